@@ -5,10 +5,42 @@
 package main
 
 import (
+	"context"
+
 	database "github.com/fluidity-money/fluidity-app/lib/databases/timescale/worker"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	libQueue "github.com/fluidity-money/fluidity-app/lib/queue"
+	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
 	queue "github.com/fluidity-money/fluidity-app/lib/queues/worker"
 )
 
 func main() {
-	queue.Emissions(database.InsertEmissions)
+	go ethQueue.ChainReorgs(func(reorg ethQueue.ChainReorg) {
+		removed := database.RollbackEmissions(reorg)
+
+		log.App(func(k *log.Log) {
+			k.Format(
+				"Removed %v emissions in %v blocks orphaned on %v!",
+				removed,
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+		})
+	})
+
+	queue.EmissionsContext(func(ctx context.Context, emission queue.Emission) {
+		// replayed blocks send emissions that were probably seen
+		// already, so they're only inserted if they're missing
+
+		if libQueue.IsReplay(ctx) && database.EmissionExists(emission) {
+			log.Debugf(
+				"Skipping a replayed emission in transaction %v that was already inserted!",
+				emission.TransactionHash,
+			)
+
+			return
+		}
+
+		database.InsertEmissions(emission)
+	})
 }
@@ -51,7 +51,7 @@ func main() {
 	for {
 		select {
 		case tweet := <-tweets:
-			queue.SendMessage(twitter.TopicTweets, tweet)
+			queue.SendEnvelope(twitter.TopicTweets, twitter.SchemaTweet, tweet)
 
 		case err := <-errors:
 			log.Fatal(func(k *log.Log) {
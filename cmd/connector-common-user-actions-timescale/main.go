@@ -1,13 +1,44 @@
 package main
 
 import (
+	"context"
+
 	database "github.com/fluidity-money/fluidity-app/lib/databases/timescale/user-actions"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	libQueue "github.com/fluidity-money/fluidity-app/lib/queue"
+	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
 	queue "github.com/fluidity-money/fluidity-app/lib/queues/user-actions"
 )
 
 func main() {
-	go queue.UserActionsEthereum(database.InsertUserAction)
+	go ethQueue.ChainReorgs(func(reorg ethQueue.ChainReorg) {
+		removed := database.RollbackUserActions(reorg)
+
+		log.App(func(k *log.Log) {
+			k.Format(
+				"Removed %v user actions in %v blocks orphaned on %v!",
+				removed,
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+		})
+	})
+
+	go queue.UserActionsEthereumContext(func(ctx context.Context, userAction queue.UserAction) {
+		// replayed blocks send user actions that were probably seen
+		// already, so they're only inserted if they're missing
+
+		if libQueue.IsReplay(ctx) && database.UserActionExists(userAction) {
+			log.Debugf(
+				"Skipping a replayed user action in transaction %v that was already inserted!",
+				userAction.TransactionHash,
+			)
+
+			return
+		}
+
+		database.InsertUserAction(userAction)
+	})
 
 	go queue.UserActionsSolana(database.InsertUserAction)
 
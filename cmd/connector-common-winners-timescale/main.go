@@ -7,10 +7,48 @@ package main
 import (
 	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/solana"
 	database "github.com/fluidity-money/fluidity-app/lib/databases/timescale/winners"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
 	queue "github.com/fluidity-money/fluidity-app/lib/queues/winners"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
 )
 
 func main() {
+	// winners are payouts that were made, so they're kept when the
+	// blocks they were seen in are orphaned. Only unpaid blocked rewards
+	// are rolled back
+
+	go ethQueue.ChainReorgs(func(reorg ethQueue.ChainReorg) {
+		removedBlocked := database.RollbackBlockedWinners(reorg)
+
+		log.App(func(k *log.Log) {
+			k.Format(
+				"Removed %v blocked winners in %v blocks orphaned on %v!",
+				removedBlocked,
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+		})
+	})
+
+	// only rewards blocked by the Ethereum contracts are tracked in
+	// ethereum_blocked_winners
+
+	go queue.BlockedWinnersEthereum(func(blockedWinner queue.BlockedWinner) {
+		switch network_ := blockedWinner.Network; network_ {
+		case network.NetworkEthereum, network.NetworkArbitrum:
+			database.InsertBlockedWinner(blockedWinner)
+
+		default:
+			log.App(func(k *log.Log) {
+				k.Format(
+					"Skipping a blocked winner on %v, which isn't tracked!",
+					network_,
+				)
+			})
+		}
+	})
+
 	go queue.WinnersEthereum(func(winner queue.Winner) {
 		database.InsertWinner(winner)
 	})
@@ -6,11 +6,14 @@ package main
 
 import (
 	"context"
+	"strconv"
 
 	ethCommon "github.com/fluidity-money/fluidity-app/common/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
 	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
@@ -18,11 +21,55 @@ import (
 	ethRpc "github.com/ethereum/go-ethereum/rpc"
 )
 
-// EnvEthereumWsUrl is the url to use to connect to the WS Geth endpoint
-const EnvEthereumWsUrl = `FLU_ETHEREUM_WS_URL`
+const (
+	// EnvEthereumWsUrl is the url to use to connect to the WS Geth endpoint
+	EnvEthereumWsUrl = `FLU_ETHEREUM_WS_URL`
+
+	// EnvNetwork to track reorganisations on (ethereum or arbitrum)
+	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvReorgWindowSize is the number of recent blocks to keep to
+	// detect reorganisations with, defaults to 128
+	EnvReorgWindowSize = `FLU_ETHEREUM_REORG_WINDOW_SIZE`
+)
+
+// defaultReorgWindowSize to use if EnvReorgWindowSize isn't set
+const defaultReorgWindowSize = "128"
 
 func main() {
-	gethWebsocketUrl := util.PickEnvOrFatal(EnvEthereumWsUrl)
+	var (
+		gethWebsocketUrl = util.PickEnvOrFatal(EnvEthereumWsUrl)
+		network__        = util.GetEnvOrFatal(EnvNetwork)
+		windowSize_      = util.GetEnvOrDefault(EnvReorgWindowSize, defaultReorgWindowSize)
+	)
+
+	network_, err := network.ParseEthereumNetwork(network__)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to parse Ethereum network (%#v) in env %v!",
+				network__,
+				EnvNetwork,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	windowSize, err := strconv.Atoi(windowSize_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to parse %#v from %#v!",
+				windowSize_,
+				EnvReorgWindowSize,
+			)
+
+			k.Payload = err
+		})
+	}
 
 	rpcClient, err := ethRpc.Dial(gethWebsocketUrl)
 
@@ -35,6 +82,23 @@ func main() {
 
 	gethClient := ethclient.NewClient(rpcClient)
 
+	window := loadWindow(network_, windowSize)
+
+	headerByHash := func(hash ethereum.Hash) (*ethereum.BlockHeader, error) {
+		header, err := gethClient.HeaderByHash(
+			context.Background(),
+			ethCommon.ConvertInternalHash(hash),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		convertedHeader := ethCommon.ConvertGethHeader(header)
+
+		return &convertedHeader, nil
+	}
+
 	headers := make(chan *ethTypes.Header)
 
 	newHeadsSubscription, err := gethClient.SubscribeNewHead(
@@ -62,11 +126,47 @@ func main() {
 		case header := <-headers:
 			newHeader := ethCommon.ConvertGethHeader(header)
 
-			log.Debug(func(k *log.Log) {
-				k.Format("Sending Block Header: %v", newHeader.BlockHash)
-			})
+			orphaned, newHeaders, err := window.Add(newHeader, headerByHash)
+
+			if err != nil {
+				log.Fatal(func(k *log.Log) {
+					k.Format(
+						"Failed to add block %v with hash %v to the reorg window!",
+						newHeader.Number.String(),
+						newHeader.BlockHash,
+					)
+
+					k.Payload = err
+				})
+			}
+
+			// the reorg is sent before the headers that replaced the
+			// orphaned blocks, so anything recorded from the orphaned
+			// blocks is rolled back first
+
+			if len(orphaned) != 0 {
+				chainReorg := getChainReorg(rpcClient, network_, orphaned)
+
+				log.App(func(k *log.Log) {
+					k.Format(
+						"Chain reorganisation at block %v orphaned %v blocks, sending it!",
+						newHeader.Number.String(),
+						len(orphaned),
+					)
+				})
+
+				queue.SendEnvelope(ethQueue.TopicChainReorgs, ethQueue.SchemaChainReorg, chainReorg)
+			}
+
+			for _, newHeader := range newHeaders {
+				log.Debug(func(k *log.Log) {
+					k.Format("Sending Block Header: %v", newHeader.BlockHash)
+				})
+
+				queue.SendEnvelope(ethQueue.TopicBlockHeaders, ethQueue.SchemaBlockHeader, newHeader)
+			}
 
-			queue.SendMessage(ethQueue.TopicBlockHeaders, newHeader)
+			storeWindow(network_, window)
 		}
 
 	}
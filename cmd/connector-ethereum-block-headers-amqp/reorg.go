@@ -0,0 +1,128 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	ethCommon "github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/reorg"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/state"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+
+	gethCommon "github.com/ethereum/go-ethereum/common"
+	ethRpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// RedisReorgWindowKey to store the window of recent blocks in, formatted
+// with the network
+const RedisReorgWindowKey = `ethereum.reorg.window.%v`
+
+// blockTransactions is the part of eth_getBlockByHash that's needed
+// without full transactions, which avoids decoding transaction types
+// that Geth doesn't know about (like Arbitrum's)
+type blockTransactions struct {
+	Transactions []gethCommon.Hash `json:"transactions"`
+}
+
+// loadWindow of recent blocks from the last time the connector ran
+func loadWindow(network_ network.BlockchainNetwork, size int) *reorg.Window {
+	key := fmt.Sprintf(RedisReorgWindowKey, network_)
+
+	windowBytes := state.Get(key)
+
+	if len(windowBytes) == 0 {
+		return reorg.NewWindow(size, nil)
+	}
+
+	var blocks []reorg.Block
+
+	if err := json.Unmarshal(windowBytes, &blocks); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to decode the reorg window stored in %#v!",
+				key,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return reorg.NewWindow(size, blocks)
+}
+
+func storeWindow(network_ network.BlockchainNetwork, window *reorg.Window) {
+	key := fmt.Sprintf(RedisReorgWindowKey, network_)
+
+	state.Set(key, window.Blocks())
+}
+
+// getChainReorg with the transactions in every orphaned block. If the
+// node no longer has a block, it's sent without any transactions
+func getChainReorg(rpcClient *ethRpc.Client, network_ network.BlockchainNetwork, orphaned []reorg.Block) ethereum.ChainReorg {
+	orphanedBlocks := make([]ethereum.OrphanedBlock, len(orphaned))
+
+	for i, block := range orphaned {
+		var (
+			blockHash = block.Hash.String()
+
+			transactions *blockTransactions
+		)
+
+		err := rpcClient.CallContext(
+			context.Background(),
+			&transactions,
+			"eth_getBlockByHash",
+			blockHash,
+			false,
+		)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Format(
+					"Failed to get the transactions in orphaned block %v!",
+					blockHash,
+				)
+
+				k.Payload = err
+			})
+		}
+
+		transactionHashes := make([]ethereum.Hash, 0)
+
+		if transactions == nil {
+			log.App(func(k *log.Log) {
+				k.Format(
+					"Orphaned block %v with hash %v couldn't be found, its transactions won't be rolled back!",
+					block.Number,
+					blockHash,
+				)
+			})
+		} else {
+			for _, transactionHash := range transactions.Transactions {
+				transactionHashes = append(
+					transactionHashes,
+					ethCommon.ConvertGethHash(transactionHash),
+				)
+			}
+		}
+
+		orphanedBlocks[i] = ethereum.OrphanedBlock{
+			BlockHash:         block.Hash,
+			BlockNumber:       misc.BigIntFromUint64(block.Number),
+			TransactionHashes: transactionHashes,
+		}
+	}
+
+	return ethereum.ChainReorg{
+		Network:        network_,
+		OrphanedBlocks: orphanedBlocks,
+	}
+}
@@ -395,7 +395,7 @@ func main() {
 				lastBlockSeen = blockNumber
 			}
 
-			queue.SendMessage(queueEth.TopicLogs, convertedLog)
+			queue.SendEnvelope(queueEth.TopicLogs, queueEth.SchemaLog, convertedLog)
 		}
 	}
 }
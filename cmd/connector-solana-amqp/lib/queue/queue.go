@@ -15,6 +15,6 @@ func SendConfirmedBlocks(slots []uint64) {
 			Slot: slot,
 		}
 
-		queue.SendMessage(solana.TopicSlots, slotContainer)
+		queue.SendEnvelope(solana.TopicSlots, solana.SchemaSlot, slotContainer)
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/fluidity-money/fluidity-app/cmd/connector-solana-amqp/lib/queue"
@@ -42,12 +43,18 @@ const (
 	// EnvTokensList to relate the received token names to a contract address
 	EnvTokensList = "FLU_SOLANA_TOKENS_LIST"
 
-	// EnvSolanaWsUrl is the URL to connect to the Solana websocket api
+	// EnvSolanaWsUrl is the URLs to connect to the Solana websocket api,
+	// separated by , - tried in turn if a websocket disconnects
 	EnvSolanaWsUrl = `FLU_SOLANA_WS_URL`
 
-	// EnvSolanaRpcUrl is the URL to make solana RPC calls to
+	// EnvSolanaRpcUrl is the URLs to make solana RPC calls to, separated
+	// by , - failing over to the next if one fails or falls behind
 	EnvSolanaRpcUrl = `FLU_SOLANA_RPC_URL`
 
+	// EnvSolanaRpcRequestsPerSecond to limit the calls made to each RPC
+	// URL to, unlimited if not set
+	EnvSolanaRpcRequestsPerSecond = `FLU_SOLANA_RPC_REQUESTS_PER_SECOND`
+
 	// EnvStartingSlot is the slot to start from if the Redis key is not set
 	EnvStartingSlot = `FLU_SOLANA_STARTING_SLOT`
 )
@@ -101,9 +108,10 @@ func updateConfirmedBlocksFrom(client *solanaRpc.Provider, from uint64) (uint64,
 
 func main() {
 	var (
-		solanaWsUrl      = util.PickEnvOrFatal(EnvSolanaWsUrl)
-		solanaRpcUrl     = util.PickEnvOrFatal(EnvSolanaRpcUrl)
-		solanaTokenList_ = util.GetEnvOrFatal(EnvTokensList)
+		solanaWsUrls               = util.GetEnvOrFatal(EnvSolanaWsUrl)
+		solanaRpcUrls              = util.GetEnvOrFatal(EnvSolanaRpcUrl)
+		solanaTokenList_           = util.GetEnvOrFatal(EnvTokensList)
+		solanaRpcRequestsPerSecond = util.GetEnvOrDefault(EnvSolanaRpcRequestsPerSecond, "0")
 	)
 
 	var (
@@ -113,20 +121,28 @@ func main() {
 
 	tokenList := solana.GetTokensListSolana(solanaTokenList_)
 
-	solanaHttp, err := solanaRpc.New(solanaRpcUrl)
+	requestsPerSecond, err := strconv.ParseFloat(solanaRpcRequestsPerSecond, 64)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to create the HTTP client!"
+			k.Format(
+				"Failed to parse %v from env!",
+				EnvSolanaRpcRequestsPerSecond,
+			)
+
 			k.Payload = err
 		})
 	}
 
-	solanaWebsocket, err := solanaRpc.NewWebsocket(solanaWsUrl)
+	solanaClient, err := solanaRpc.NewFailover(
+		strings.Split(solanaRpcUrls, ","),
+		strings.Split(solanaWsUrls, ","),
+		solanaRpc.FailoverRequestsPerSecond(requestsPerSecond),
+	)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to create the websocket client!"
+			k.Message = "Failed to create the Solana client!"
 			k.Payload = err
 		})
 	}
@@ -166,7 +182,7 @@ func main() {
 			k.Message = "Starting block not 0, updating!"
 			k.Payload = startingBlock
 		})
-		updateConfirmedBlocksFrom(solanaHttp, startingBlock)
+		updateConfirmedBlocksFrom(solanaClient, startingBlock)
 	}
 
 	// use a neverending WaitGroup to avoid exiting immediately
@@ -178,9 +194,23 @@ func main() {
 		wg.Add(1)
 		defer wg.Done()
 
-		go solanaWebsocket.SubscribeBlocks(token.FluidMintPubkey, func(b solanaRpc.BlockResponse) {
-			processSlot(tokenRedisKey, b.Value.Slot)
-		})
+		go func(token solana.TokenDetailsSolana) {
+			// blocks missed while the websocket was disconnected are
+			// caught up on by the subscription, if they mention the mint
+
+			err := solanaClient.SubscribeBlocks(token.FluidMintPubkey, func(b solanaRpc.BlockResponse) {
+				processSlot(tokenRedisKey, b.Value.Slot)
+			})
+
+			log.Fatal(func(k *log.Log) {
+				k.Format(
+					"Subscription to the blocks for token %v ended!",
+					token.TokenName,
+				)
+
+				k.Payload = err
+			})
+		}(token)
 	}
 
 	wg.Wait()
@@ -0,0 +1,81 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/checkpoints"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/state"
+)
+
+// progressStore of the checkpoints published, fencing writes with the
+// token of the connector's lease
+type progressStore struct {
+	token uint64
+}
+
+// Load the progress, starting from the last checkpoint stored before
+// progress was if there's none
+func (progressStore) Load() (checkpoints.Progress, error) {
+	var progress checkpoints.Progress
+
+	bytes := state.Get(RedisCheckpointProgress)
+
+	if len(bytes) == 0 {
+		if lastCheckpoint := GetLastBlock(RedisLastCheckpoint); lastCheckpoint != 0 {
+			progress.Started = true
+			progress.HighWaterMark = lastCheckpoint
+		}
+
+		return progress, nil
+	}
+
+	if err := json.Unmarshal(bytes, &progress); err != nil {
+		return progress, fmt.Errorf(
+			"failed to decode the checkpoint progress! %v",
+			err,
+		)
+	}
+
+	return progress, nil
+}
+
+// Save the progress unless another connector took over
+func (store progressStore) Save(progress checkpoints.Progress) error {
+	if !state.SetFenced(RedisCheckpointProgress, store.token, progress) {
+		return fmt.Errorf(
+			"another connector took over, not saving progress with fencing token %v",
+			store.token,
+		)
+	}
+
+	return nil
+}
+
+// GetLastBlock seen by the connector, or 0 if it hasn't seen any
+func GetLastBlock(key string) uint64 {
+	bytes := state.Get(key)
+
+	if len(bytes) == 0 {
+		// not found
+		return 0
+	}
+
+	var res uint64
+
+	err := json.Unmarshal(bytes, &res)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to get the last seen checkpoint from redis!"
+			k.Payload = err
+		})
+	}
+
+	return res
+}
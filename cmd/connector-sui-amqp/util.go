@@ -0,0 +1,34 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+)
+
+// uintFromEnv to parse a positive number from the env given, using the
+// default if it's not set
+func uintFromEnv(env, defaultValue string) uint64 {
+	value_ := util.GetEnvOrDefault(env, defaultValue)
+
+	value, err := strconv.ParseUint(value_, 10, 64)
+
+	if err != nil || value == 0 {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to parse a positive number from env %v, %#v!",
+				env,
+				value_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return value
+}
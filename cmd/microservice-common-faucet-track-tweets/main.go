@@ -273,7 +273,7 @@ func main() {
 			TokenName: tokenChosen,
 		}
 
-		queue.SendMessage(faucet.TopicFaucetRequest, faucetRequest)
+		queue.SendEnvelope(faucet.TopicFaucetRequest, faucet.SchemaFaucetRequest, faucetRequest)
 
 		faucetDatabase.TrackFaucetUse(address, networkChosen, tokenChosen)
 
@@ -66,6 +66,6 @@ func main() {
 			Network: network_,
 		}
 
-		queue.SendMessage(addresslinkerQueue.TopicLinkedAddresses, addrs)
+		queue.SendEnvelope(addresslinkerQueue.TopicLinkedAddresses, addresslinkerQueue.SchemaLinkedAddresses, addrs)
 	})
 }
@@ -5,12 +5,20 @@
 package main
 
 import (
+	"context"
 	"math"
 	"math/big"
+	"os"
 	"strconv"
+	"time"
 
 	libEthereum "github.com/fluidity-money/fluidity-app/common/ethereum"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/applications"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/route"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price/cache"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/prices"
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
 	user_actions "github.com/fluidity-money/fluidity-app/lib/queues/user-actions"
@@ -48,9 +56,16 @@ const (
 
 	// EnvNetwork is the network ID, used to create user actions
 	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvPriceConfig to optionally convert application fees from the
+	// underlying token to USD with, using the price as of the block. See
+	// common/ethereum/price
+	EnvPriceConfig = `FLU_ETHEREUM_PRICE_CONFIG`
 )
 
 func main() {
+	lifecycle.Start()
+
 	var (
 		publishAmqpTopic         = util.GetEnvOrFatal(EnvServerWorkQueue)
 		contractAddrString       = util.GetEnvOrFatal(EnvContractAddress)
@@ -60,6 +75,7 @@ func main() {
 		applicationContracts     = applications.AppsListFromEnvOrFatal(EnvApplicationContracts)
 		utilities                = applications.UtilityListFromEnvOrFatal(EnvUtilityContracts)
 		networkId                = util.GetEnvOrFatal(EnvNetwork)
+		priceConfig_             = os.Getenv(EnvPriceConfig)
 	)
 
 	contractAddress := ethCommon.HexToAddress(contractAddrString)
@@ -96,13 +112,74 @@ func main() {
 
 	defer gethClient.Close()
 
-	worker.GetEthereumBlockLogs(func(blockLog worker.EthereumBlockLog) {
+	// priceOracle is only set if the underlying token isn't assumed
+	// to be worth $1
+
+	var priceOracle *price.Oracle
+
+	if priceConfig_ != "" {
+		priceConfig, err := price.ParseConfig(priceConfig_)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Message = "Failed to read the price config!"
+				k.Payload = err
+			})
+		}
+
+		if _, exists := priceConfig.Tokens[tokenName]; !exists {
+			log.Fatal(func(k *log.Log) {
+				k.Format(
+					"Underlying token %v isn't in the price config!",
+					tokenName,
+				)
+			})
+		}
+
+		priceOracle = price.NewOracle(
+			gethClient,
+			dbNetwork,
+			*priceConfig,
+			cache.State{},
+			prices.History{},
+		)
+	}
+
+	worker.GetEthereumBlockLogs(func(ctx context.Context, blockLog worker.EthereumBlockLog) {
 		var (
 			logs         = blockLog.Logs
 			transactions = blockLog.Transactions
 			blockHash    = blockLog.BlockHash
+			blockNumber  = blockLog.BlockNumber
+			blockTime    = time.Unix(int64(blockLog.BlockTime), 0)
 		)
 
+		// tokenPriceUsd to convert fees with if set, as of the block
+
+		var tokenPriceUsd *big.Rat
+
+		if priceOracle != nil {
+			tokenPrice, err := priceOracle.GetPriceAtBlock(
+				tokenName,
+				blockNumber.Uint64(),
+				blockTime,
+			)
+
+			if err != nil {
+				log.FatalContext(ctx, func(k *log.Log) {
+					k.Format(
+						"Failed to get the price of %v at block %v!",
+						tokenName,
+						blockNumber.String(),
+					)
+
+					k.Payload = err
+				})
+			}
+
+			tokenPriceUsd = tokenPrice.Usd
+		}
+
 		fluidTransfers := libEthereum.GetTransfers(
 			logs,
 			transactions,
@@ -112,7 +189,7 @@ func main() {
 		)
 
 		if err != nil {
-			log.Fatal(func(k *log.Log) {
+			log.FatalContext(ctx, func(k *log.Log) {
 				k.Format(
 					"Failed to get a fluid transfer in block %#v!",
 					blockHash,
@@ -142,11 +219,18 @@ func main() {
 
 		var decoratedTransactions = make(map[ethereum.Hash]worker.EthereumDecoratedTransaction)
 
+		// the venue that touched the fluid token in each transaction with
+		// application transfers, if one did
+
+		var transactionVenues = make(map[ethereum.Hash]applications.Application)
+
+		fluidTokenAddress := ethereum.AddressFromString(contractAddress.String())
+
 		for transactionHash, transfers := range applicationTransfers {
 			transaction, exists := blockTransactions[transactionHash]
 
 			if !exists {
-				log.Fatal(func(k *log.Log) {
+				log.FatalContext(ctx, func(k *log.Log) {
 					k.Format(
 						"Transaction %s in block %s is unreferenced!",
 						transactionHash.String(),
@@ -158,7 +242,7 @@ func main() {
 			convertedReceipt, err := libEthereum.GetReceipt(gethClient, transactionHash)
 
 			if err != nil {
-				log.Fatal(func(k *log.Log) {
+				log.FatalContext(ctx, func(k *log.Log) {
 					k.Format(
 						"Failed to fetch receipt for transaction %s!",
 						transactionHash.String(),
@@ -168,9 +252,34 @@ func main() {
 				})
 			}
 
+			// only credit the application that moved the fluid token, so
+			// the fluid leg of a swap routed through several venues or an
+			// aggregator is counted once
+
+			attribution := route.Attribute(
+				*convertedReceipt,
+				fluidTokenAddress,
+				transfers,
+			)
+
+			if len(attribution.Transfers) < len(transfers) {
+				log.AppContext(ctx, func(k *log.Log) {
+					k.Format(
+						"Transaction %s moved the fluid token through venue %s (aggregator %s), keeping %d of %d application transfers",
+						transactionHash.String(),
+						attribution.Venue.String(),
+						attribution.Aggregator.String(),
+						len(attribution.Transfers),
+						len(transfers),
+					)
+				})
+			}
+
+			transactionVenues[transactionHash] = attribution.Venue
+
 			transfersWithFees := make([]worker.EthereumDecoratedTransfer, 0)
 
-			for _, transfer := range transfers {
+			for _, transfer := range attribution.Transfers {
 				// appData is arbitrary data that we propagate to be consumed later
 				feeData, appData, emission, err := applications.GetApplicationFee(
 					transfer,
@@ -187,7 +296,7 @@ func main() {
 				)
 
 				if err != nil {
-					log.Fatal(func(k *log.Log) {
+					log.FatalContext(ctx, func(k *log.Log) {
 						k.Message = "Failed to get the application fee for an application transfer!"
 						k.Payload = err
 					})
@@ -199,7 +308,7 @@ func main() {
 
 				// we set the decorator if there's an app fee
 				if fee == nil {
-					log.App(func(k *log.Log) {
+					log.AppContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Skipping an application transfer for transaction %#v and application %#v!",
 							transactionHash.String(),
@@ -210,6 +319,10 @@ func main() {
 					continue
 				}
 
+				if tokenPriceUsd != nil {
+					fee = new(big.Rat).Mul(fee, tokenPriceUsd)
+				}
+
 				decorator := &worker.EthereumWorkerDecorator{
 					Application:     transfer.Application,
 					UtilityName:     utility,
@@ -223,7 +336,7 @@ func main() {
 				)
 
 				if err != nil {
-					log.Fatal(func(k *log.Log) {
+					log.FatalContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Failed to get the sender and receiver for an application transfer with hash %s!",
 							transactionHash.String(),
@@ -273,8 +386,12 @@ func main() {
 					decorator.Application,
 				)
 
-				queue.SendMessage(
+				transferUserAction.BlockHash = blockHash.String()
+
+				queue.SendEnvelopeContext(
+					ctx,
 					user_actions.TopicUserActionsEthereum,
+					user_actions.SchemaUserAction,
 					transferUserAction,
 				)
 			}
@@ -304,7 +421,7 @@ func main() {
 				transaction, txExists := blockTransactions[transactionHash]
 
 				if !txExists {
-					log.Fatal(func(k *log.Log) {
+					log.FatalContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Transaction %s in block %s is unreferenced!",
 							transactionHash.String(),
@@ -318,7 +435,7 @@ func main() {
 				receipt, err := libEthereum.GetReceipt(gethClient, transactionHash)
 
 				if err != nil {
-					log.Fatal(func(k *log.Log) {
+					log.FatalContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Failed to fetch receipt for transaction %s!",
 							transactionHash.String(),
@@ -330,10 +447,17 @@ func main() {
 
 				decoratedTransaction.Receipt = *receipt
 			} else {
-				// fill in decorator with app
+				// fill in decorator with app, preferring the venue that
+				// touched the fluid token
 				transfers := decoratedTransaction.Transfers
 
-				if len(transfers) > 0 {
+				venue := transactionVenues[transactionHash]
+
+				switch {
+				case venue != applications.ApplicationNone:
+					decorator.Application = venue
+
+				case len(transfers) > 0:
 					app := transfers[0].Decorator.Application
 					decorator.Application = app
 				}
@@ -369,8 +493,12 @@ func main() {
 				decorator.Application,
 			)
 
-			queue.SendMessage(
+			transferUserAction.BlockHash = blockHash.String()
+
+			queue.SendEnvelopeContext(
+				ctx,
 				user_actions.TopicUserActionsEthereum,
+				user_actions.SchemaUserAction,
 				transferUserAction,
 			)
 
@@ -393,7 +521,7 @@ func main() {
 						fee = transfer.Decorator.ApplicationFee.RatString()
 					}
 
-					log.Debug(func(k *log.Log) {
+					log.DebugContext(ctx, func(k *log.Log) {
 						k.Format(
 							"For transaction hash %v, transfer with index %v had application %v, utility %v, fee %v!",
 							transactionHash,
@@ -408,6 +536,6 @@ func main() {
 		}
 
 		// send to server
-		queue.SendMessage(publishAmqpTopic, serverWork)
+		queue.SendEnvelopeContext(ctx, publishAmqpTopic, worker.SchemaEthereumHintedBlock, serverWork)
 	})
 }
@@ -5,11 +5,21 @@
 package main
 
 import (
+	"context"
+	"os"
 	"strconv"
+	"time"
 
-	"github.com/fluidity-money/fluidity-app/common/ethereum/spooler"
+	ethereumSpooler "github.com/fluidity-money/fluidity-app/common/ethereum/spooler"
+	"github.com/fluidity-money/fluidity-app/common/spooler"
+	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/transactions"
+	timescaleSpooler "github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
+	"github.com/fluidity-money/fluidity-app/lib/leader"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/queues/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/state"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
 	"github.com/fluidity-money/fluidity-app/lib/util"
@@ -27,14 +37,28 @@ const (
 
 	// EnvNetwork to differentiate between eth, arbitrum, etc
 	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvWorkerAddress of the worker-sender paying out the rewards, to
+	// hold off on releasing more while its transactions are pending.
+	// Rewards are always released if it isn't set.
+	EnvWorkerAddress = `FLU_ETHEREUM_WORKER_ADDR`
 )
 
+// RedisLeasePrefix to take a lease for each network and token with, so
+// overlapping runs don't release the same rewards twice
+const RedisLeasePrefix = `ethereum.automatic-payout-release.`
+
+// PendingTransactionMaxAge to stop waiting on pending transactions
+// after, in case the worker-sender died before it could update them
+const PendingTransactionMaxAge = time.Hour
+
 func main() {
 	var (
 		senderQueueName = util.GetEnvOrFatal(EnvPublishAmqpQueueName)
 		shortName       = util.GetEnvOrFatal(EnvTokenName)
 		decimals_       = util.GetEnvOrFatal(EnvTokenDecimals)
 		network_        = util.GetEnvOrFatal(EnvNetwork)
+		workerAddress_  = os.Getenv(EnvWorkerAddress)
 	)
 
 	decimals, err := strconv.ParseInt(decimals_, 10, 32)
@@ -60,28 +84,80 @@ func main() {
 		})
 	}
 
-	rewards, foundRewards, err := spooler.GetRewards(net, token)
+	// skip this run if the last one is still going, dry runs don't
+	// release anything so they don't need the lease
 
-	if err != nil {
-		log.Fatal(func(k *log.Log) {
+	spoolerConfig := spooler.DefaultConfig()
+
+	if !spoolerConfig.DryRun {
+		leaseName := RedisLeasePrefix + string(net) + "." + shortName
+
+		lease, isLeader := leader.TryLead(state.LeaseStore{}, leaseName)
+
+		if !isLeader {
+			log.App(func(k *log.Log) {
+				k.Format(
+					"Another release holds lease %v, not releasing rewards for token %s!",
+					leaseName,
+					shortName,
+				)
+			})
+
+			return
+		}
+
+		defer lease.Release(context.Background())
+	}
+
+	// the worker-sender's transaction manager is still bumping an
+	// earlier batch, so wait for the next run to release another
+
+	workerAddress := ethereum.AddressFromString(workerAddress_)
+
+	if workerAddress_ != "" && transactions.HasPendingAttempts(workerAddress, PendingTransactionMaxAge) {
+		log.App(func(k *log.Log) {
 			k.Format(
-				"Failed to get rewards for token %s! %+v",
+				"Transactions sent by %v are still pending, not releasing rewards for token %s!",
+				workerAddress,
 				shortName,
-				err,
 			)
 		})
+
+		return
 	}
 
-	if foundRewards {
-		log.App(func(k *log.Log) {
+	// in dry run mode, the rewards are left in the queue and the batch
+	// is published instead
+
+	backend := ethereumSpooler.BatchBackend{
+		QueueName: senderQueueName,
+		Send:      queue.SendMessage,
+	}
+
+	spoolerConfig.PublishDryRun = dry_run.Publish
+
+	tokenSpooler := spooler.New(net, timescaleSpooler.Store{}, backend, spoolerConfig)
+
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Releasing rewards for token %s",
+			shortName,
+		)
+	})
+
+	foundRewards, err := tokenSpooler.Release(token)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
 			k.Format(
-				"Sending rewards for token %s",
+				"Failed to release rewards for token %s! %+v",
 				shortName,
+				err,
 			)
 		})
+	}
 
-		queue.SendMessage(senderQueueName, rewards)
-	} else {
+	if !foundRewards {
 		log.App(func(k *log.Log) {
 			k.Format(
 				"No rewards for token %s found!",
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"strconv"
@@ -12,6 +13,7 @@ import (
 	lib "github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-block-fluid-transfers-amqp/lib"
 	ethConvert "github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-block-fluid-transfers-amqp/lib/ethereum"
 
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
 	"github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
@@ -64,6 +66,8 @@ func intFromEnvOrFatal(env string) int {
 }
 
 func main() {
+	lifecycle.Start()
+
 	var (
 		gethHttpApi = util.PickEnvOrFatal(EnvGethHttpUrl)
 
@@ -71,64 +75,78 @@ func main() {
 		delay   = intFromEnvOrFatal(EnvRetryDelay)
 	)
 
+	// headers sent again by microservice-ethereum-replay-blocks come
+	// down their own topic, and everything sent for them is tagged as a
+	// replay so nothing is paid out twice
+
+	go ethQueue.ReplayedBlockHeaders(func(ctx context.Context, header ethereum.BlockHeader) {
+		sendBlockLog(ctx, gethHttpApi, retries, delay, header)
+	})
+
 	ethQueue.BlockHeaders(func(header ethereum.BlockHeader) {
-		var (
-			blockHash   = header.BlockHash
-			blockNumber = header.Number
-			baseFee     = header.BaseFee
-		)
-
-		amqpBlock := worker.EthereumBlockLog{
-			BlockHash:    blockHash,
-			BlockBaseFee: header.BaseFee,
-			BlockTime:    header.Time,
-			BlockNumber:  blockNumber,
-			BaseFee:      baseFee,
-			Logs:         make([]types.Log, 0),
-			Transactions: make([]types.Transaction, 0),
-		}
-
-		// Block contains log with ABI hash in its topics
-		// Guaranteed to be signature - Order dependent
-
-		block, err := lib.GetBlockFromHash(gethHttpApi, blockHash.String(), retries, delay)
-
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Format(
-					"Failed to get a block with hash %#v!",
-					blockHash.String(),
-				)
-
-				k.Payload = err
-			})
-		}
-
-		newTransactions, err := ethConvert.ConvertTransactions(
-			blockHash.String(),
-			block.Transactions,
-		)
-
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Format("Could not convert transactions from block: %v", blockHash)
-				k.Payload = err
-			})
-		}
-
-		amqpBlock.Transactions = append(amqpBlock.Transactions, newTransactions...)
-
-		newFluidLogs, err := lib.GetLogsFromHash(gethHttpApi, blockHash.String())
-
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Format("Could not get logs from block: %v", blockHash)
-				k.Payload = err
-			})
-		}
-
-		amqpBlock.Logs = append(amqpBlock.Logs, newFluidLogs...)
-
-		queue.SendMessage(workerQueue.TopicEthereumBlockLogs, amqpBlock)
+		sendBlockLog(context.Background(), gethHttpApi, retries, delay, header)
 	})
 }
+
+// sendBlockLog for the block with the header given, with its
+// transactions and logs, down the topic to the application server
+func sendBlockLog(ctx context.Context, gethHttpApi string, retries, delay int, header ethereum.BlockHeader) {
+	var (
+		blockHash   = header.BlockHash
+		blockNumber = header.Number
+		baseFee     = header.BaseFee
+	)
+
+	amqpBlock := worker.EthereumBlockLog{
+		BlockHash:    blockHash,
+		BlockBaseFee: header.BaseFee,
+		BlockTime:    header.Time,
+		BlockNumber:  blockNumber,
+		BaseFee:      baseFee,
+		Logs:         make([]types.Log, 0),
+		Transactions: make([]types.Transaction, 0),
+	}
+
+	// Block contains log with ABI hash in its topics
+	// Guaranteed to be signature - Order dependent
+
+	block, err := lib.GetBlockFromHash(gethHttpApi, blockHash.String(), retries, delay)
+
+	if err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Format(
+				"Failed to get a block with hash %#v!",
+				blockHash.String(),
+			)
+
+			k.Payload = err
+		})
+	}
+
+	newTransactions, err := ethConvert.ConvertTransactions(
+		blockHash.String(),
+		block.Transactions,
+	)
+
+	if err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Format("Could not convert transactions from block: %v", blockHash)
+			k.Payload = err
+		})
+	}
+
+	amqpBlock.Transactions = append(amqpBlock.Transactions, newTransactions...)
+
+	newFluidLogs, err := lib.GetLogsFromHash(gethHttpApi, blockHash.String())
+
+	if err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Format("Could not get logs from block: %v", blockHash)
+			k.Payload = err
+		})
+	}
+
+	amqpBlock.Logs = append(amqpBlock.Logs, newFluidLogs...)
+
+	queue.SendEnvelopeContext(ctx, workerQueue.TopicEthereumBlockLogs, workerQueue.SchemaEthereumBlockLog, amqpBlock)
+}
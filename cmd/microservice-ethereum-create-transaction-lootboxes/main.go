@@ -5,9 +5,10 @@
 package main
 
 import (
+	"math/big"
+	"os"
 	"strconv"
 	"time"
-	"math/big"
 
 	database "github.com/fluidity-money/fluidity-app/lib/databases/timescale/lootboxes"
 	"github.com/fluidity-money/fluidity-app/lib/log"
@@ -18,8 +19,9 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 
-	//"github.com/fluidity-money/fluidity-app/common/ethereum/uniswap_v3"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/applications"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price/cache"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -27,19 +29,53 @@ import (
 
 const (
 	// EnvTokensList to relate the received token names to a contract address
-	// of the form ADDR1:TOKEN1:DECIMALS1:MULTIPLIER,ADDR2:TOKEN2:DECIMALS2:MULTIPLIER,<UNISWAP V3 >?...
+	// of the form ADDR1:TOKEN1:DECIMALS1:MULTIPLIER,ADDR2:TOKEN2:DECIMALS2:MULTIPLIER,...
 	EnvTokensList = "FLU_ETHEREUM_TOKENS_LIST"
 
 	// EnvGethHttpUrl to use when performing RPC requests
 	EnvGethHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+	// EnvNetwork that the Geth endpoint is on, to namespace cached prices
+	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvPriceConfig to look up the price of tokens with, see
+	// common/ethereum/price. Tokens that aren't in it are assumed to be
+	// USD priced
+	EnvPriceConfig = `FLU_ETHEREUM_PRICE_CONFIG`
 )
 
 func main() {
 	var (
 		ethereumTokensList_ = util.GetEnvOrFatal(EnvTokensList)
 		gethHttpUrl         = util.PickEnvOrFatal(EnvGethHttpUrl)
+		priceNetwork_       = util.GetEnvOrDefault(EnvNetwork, string(network.NetworkEthereum))
+		priceConfig_        = os.Getenv(EnvPriceConfig)
 	)
 
+	priceNetwork, err := network.ParseEthereumNetwork(priceNetwork_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to parse network from env"
+			k.Payload = err
+		})
+	}
+
+	var priceConfig price.Config
+
+	if priceConfig_ != "" {
+		parsedConfig, err := price.ParseConfig(priceConfig_)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Message = "Failed to read the price config!"
+				k.Payload = err
+			})
+		}
+
+		priceConfig = *parsedConfig
+	}
+
 	log.Debugf("Running with tokens list %v", ethereumTokensList_)
 
 	tokensList := util.GetTokensListBase(ethereumTokensList_)
@@ -51,10 +87,6 @@ func main() {
 	// tokensMap to look up a token's address using its short name
 	tokensMap := make(map[string]ethCommon.Address)
 
-	// oraclesMap to optionally use to look up a token price using Uniswap's TWAP oracle.
-	// Can be unset, and if is unset, then assumes the underlying volume is USD priced.
-	oraclesMap := make(map[string]ethCommon.Address)
-
 	for i, token := range tokensList {
 		var (
 			tokenAddress = token.TokenAddress
@@ -72,21 +104,20 @@ func main() {
 				)
 			})
 
-		// if the second parameter is set in the extras fields, enable a oracle token map
+		// the second parameter used to be a Uniswap pool to price the
+		// token with, which is now set in the price config instead
 		case 2:
-			oracle := token.Extras[1]
-
-			if !ethCommon.IsHexAddress(oracle) {
+			if _, exists := priceConfig.Tokens[tokenName]; !exists {
 				log.Fatal(func(k *log.Log) {
 					k.Format(
-						"Oracle not formatted being provided as an argument %v!",
-						i,
+						"Oracle %v for token %v should be moved to %v!",
+						token.Extras[1],
+						tokenName,
+						EnvPriceConfig,
 					)
 				})
 			}
 
-			oraclesMap[tokenName] = ethCommon.HexToAddress(oracle)
-
 			fallthrough
 
 		default:
@@ -120,6 +151,8 @@ func main() {
 
 	defer ethClient.Close()
 
+	priceOracle := price.NewOracle(ethClient, priceNetwork, priceConfig, cache.State{}, nil)
+
 	user_actions_queue.UserActionsEthereum(func(userAction user_actions_queue.UserAction) {
 		programFound, hasBegun, currentEpoch, _ := database.GetLootboxConfig()
 
@@ -229,18 +262,26 @@ func main() {
 
 		normalisedAmount.Quo(normalisedAmount, new(big.Rat).SetInt(tokenDecimalsExp))
 
-		// for the asset, the price 1 is assumed to be the case, unless the pool is set for a lookup
+		// for the asset, the price 1 is assumed to be the case, unless the token is in the price config
 
 		amountUsd := new(big.Rat).SetInt64(1)
 
-		_, ok := oraclesMap[tokenShortName]
+		if _, ok := priceConfig.Tokens[tokenShortName]; ok {
+			tokenPrice, err := priceOracle.GetPrice(tokenShortName)
 
-		// if we can find a pool using the oracle map, then let's look up the price instead of using the default
+			if err != nil {
+				log.Fatal(func(k *log.Log) {
+					k.Format(
+						"Failed to get the price of %v for transaction hash %v!",
+						tokenShortName,
+						transactionHash,
+					)
+
+					k.Payload = err
+				})
+			}
 
-		if ok {
-			// this will die using Fatal if the lookup fails
-			//amountUsd = uniswap_v3.GetTwrpPrice1Second(ethClient, uniswapV3PoolAddr)
-			amountUsd, _ = new(big.Rat).SetString("0.04636")
+			amountUsd.Set(tokenPrice.Usd)
 		}
 
 		// multiply the usd price with the normalisedAmount to get the USD amount that was sent
@@ -313,7 +354,7 @@ func main() {
 			application_,
 		)
 
-		queue.SendMessage(lootboxes_queue.TopicLootboxes, lootbox)
+		queue.SendEnvelope(lootboxes_queue.TopicLootboxes, lootboxes_queue.SchemaLootbox, lootbox)
 	})
 }
 
@@ -8,9 +8,13 @@ import (
 	"context"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/txmanager"
+	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/transactions"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/queues/dry-run"
 	"github.com/fluidity-money/fluidity-app/lib/queues/faucet"
 	faucetTypes "github.com/fluidity-money/fluidity-app/lib/types/faucet"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
@@ -18,6 +22,7 @@ import (
 
 	ethAbiBind "github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
@@ -44,6 +49,10 @@ const (
 	EnvUseHardhatFix = `FLU_ETHEREUM_HARDHAT_FIX`
 )
 
+// MiningTimeout to wait for a transfer to be mined, including the
+// attempts made by the transaction manager with higher fees
+const MiningTimeout = time.Minute * 30
+
 func main() {
 	var (
 		ethereumTokensList_ = util.GetEnvOrFatal(EnvTokensList)
@@ -94,11 +103,32 @@ func main() {
 		})
 	}
 
-	chainId, err := ethClient.ChainID(context.Background())
+	transactionOptions, err := ethereum.NewTransactionOptions(ethClient, privateKey)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to get the current chain id Geth!"
+			k.Message = "Failed to create the transaction options!"
+			k.Payload = err
+		})
+	}
+
+	// with FLU_DRY_RUN set, transactions are simulated and the results
+	// published instead
+
+	managerConfig := txmanager.DefaultConfig()
+
+	managerConfig.PublishDryRun = dry_run.Publish
+
+	manager, err := txmanager.New(
+		ethClient,
+		transactions.Store{},
+		transactionOptions,
+		managerConfig,
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to create the transaction manager!"
 			k.Payload = err
 		})
 	}
@@ -152,27 +182,21 @@ func main() {
 			ethAddress   = ethCommon.HexToAddress(address)
 		)
 
-		transferOpts, err := ethAbiBind.NewKeyedTransactorWithChainID(
-			privateKey,
-			chainId,
-		)
-
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Message = "Failed to create a new keyed constructor with chain id!"
-				k.Payload = err
-			})
-		}
+		ctx, cancel := context.WithTimeout(context.Background(), MiningTimeout)
+
+		receipt, err := manager.Send(ctx, func(transferOpts *ethAbiBind.TransactOpts) (*types.Transaction, error) {
+			return callTransferFunction(
+				ethClient,
+				tokenAddress,
+				ethAddress,
+				&amount.Int,
+				transferOpts,
+				useHardhatFix,
+				gasLimit,
+			)
+		})
 
-		transaction, err := callTransferFunction(
-			ethClient,
-			tokenAddress,
-			ethAddress,
-			&amount.Int,
-			transferOpts,
-			useHardhatFix,
-			gasLimit,
-		)
+		cancel()
 
 		if err != nil {
 			log.Fatal(func(k *log.Log) {
@@ -185,12 +209,12 @@ func main() {
 			})
 		}
 
-		var transactionHash string
-
-		if transaction != nil {
-			transactionHash = transaction.Hash().Hex()
+		if manager.DryRun() {
+			return
 		}
 
+		transactionHash := receipt.TxHash.Hex()
+
 		log.App(func(k *log.Log) {
 			k.Format(
 				"Sent %v to %#v! Transaction hash %#v!",
@@ -0,0 +1,172 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/fluidity"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reconciliation"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/winners"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/log/discord"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// EnvEthereumHttpUrl to read the prize pool from
+	EnvEthereumHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+	// EnvNetwork to reconcile the winnings of
+	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvContractAddress of the Fluid token to read the prize pool of
+	EnvContractAddress = `FLU_ETHEREUM_CONTRACT_ADDR`
+
+	// EnvTokenShortName of the Fluid token, as recorded in the winners
+	EnvTokenShortName = `FLU_ETHEREUM_TOKEN_SHORT_NAME`
+
+	// EnvTokenDecimals of the Fluid token
+	EnvTokenDecimals = `FLU_ETHEREUM_TOKEN_DECIMALS`
+
+	// EnvTolerance to alert when a deviation exceeds, as a fraction
+	EnvTolerance = `FLU_RECONCILIATION_TOLERANCE`
+)
+
+// DefaultTolerance of deviations if EnvTolerance isn't set
+const DefaultTolerance = "0.01"
+
+// runs as a cron service, comparing the prize pool on-chain with the
+// winnings owed and paid for a single network and token
+func main() {
+	var (
+		ethereumUrl     = util.PickEnvOrFatal(EnvEthereumHttpUrl)
+		network_        = util.GetEnvOrFatal(EnvNetwork)
+		contractAddress = util.GetEnvOrFatal(EnvContractAddress)
+		tokenShortName  = util.GetEnvOrFatal(EnvTokenShortName)
+		tokenDecimals_  = util.GetEnvOrFatal(EnvTokenDecimals)
+		tolerance_      = util.GetEnvOrDefault(EnvTolerance, DefaultTolerance)
+	)
+
+	dbNetwork, err := network.ParseEthereumNetwork(network_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to read the network from env!"
+			k.Payload = err
+		})
+	}
+
+	tokenDecimals, err := strconv.Atoi(tokenDecimals_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to read the token decimals from env!"
+			k.Payload = err
+		})
+	}
+
+	tolerance, err := strconv.ParseFloat(tolerance_, 64)
+
+	if err != nil || tolerance < 0 {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to read a positive tolerance from %v, was %#v!",
+				EnvTolerance,
+				tolerance_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	gethClient, err := ethclient.Dial(ethereumUrl)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to connect to geth!"
+			k.Payload = err
+		})
+	}
+
+	defer gethClient.Close()
+
+	prizePoolNative, err := fluidity.GetRewardPool(
+		gethClient,
+		ethCommon.HexToAddress(contractAddress),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to get the prize pool from the contract!"
+			k.Payload = err
+		})
+	}
+
+	decimalsScale := new(big.Rat).SetInt(
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals)), nil),
+	)
+
+	prizePool, _ := new(big.Rat).Quo(prizePoolNative, decimalsScale).Float64()
+
+	tokenDetails := token_details.New(tokenShortName, tokenDecimals)
+
+	// the spooler tracks winnings in USD, which the Fluid tokens are
+	// pegged to, so they're compared to amounts in the token
+
+	reconciliation_ := reconciliation.PrizePoolReconciliation{
+		Network:         dbNetwork,
+		TokenShortName:  tokenShortName,
+		PrizePool:       prizePool,
+		UnpaidWinnings:  spooler.UnpaidWinningsForCategory(dbNetwork, tokenDetails),
+		BlockedWinnings: winners.SumBlockedWinnings(dbNetwork, tokenShortName),
+		SentWinnings:    spooler.SentWinningsForCategory(dbNetwork, tokenDetails),
+		PaidWinnings:    winners.SumWinnings(dbNetwork, tokenShortName),
+		RecordedTime:    time.Now(),
+	}
+
+	reconcile(&reconciliation_, tolerance)
+
+	reconciliation.InsertPrizePoolReconciliation(reconciliation_)
+
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Reconciled the prize pool of %v on %v, shortfall deviation %v, payout deviation %v",
+			tokenShortName,
+			dbNetwork,
+			reconciliation_.ShortfallDeviation,
+			reconciliation_.PayoutDeviation,
+		)
+
+		k.Payload = reconciliation_
+	})
+
+	if reconciliation_.WithinTolerance {
+		return
+	}
+
+	discord.Notify(
+		discord.SeverityAlarm,
+		"Prize pool of %v on %v is out of tolerance %v! Prize pool %v, unpaid %v, blocked %v, sent %v, paid %v, shortfall deviation %v, payout deviation %v",
+		tokenShortName,
+		dbNetwork,
+		tolerance,
+		reconciliation_.PrizePool,
+		reconciliation_.UnpaidWinnings,
+		reconciliation_.BlockedWinnings,
+		reconciliation_.SentWinnings,
+		reconciliation_.PaidWinnings,
+		reconciliation_.ShortfallDeviation,
+		reconciliation_.PayoutDeviation,
+	)
+}
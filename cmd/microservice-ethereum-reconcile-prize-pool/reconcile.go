@@ -0,0 +1,64 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reconciliation"
+)
+
+// reconcile the amounts in the reconciliation given, filling in the
+// deviations and whether they're within the tolerance
+func reconcile(reconciliation_ *reconciliation.PrizePoolReconciliation, tolerance float64) {
+	var (
+		prizePool = reconciliation_.PrizePool
+		unpaid    = reconciliation_.UnpaidWinnings
+		blocked   = reconciliation_.BlockedWinnings
+		sent      = reconciliation_.SentWinnings
+		paid      = reconciliation_.PaidWinnings
+	)
+
+	// the winnings still owed should be covered by the prize pool, which
+	// is normally larger since it keeps earning yield
+
+	var (
+		owed      = unpaid + blocked
+		shortfall = owed - prizePool
+
+		shortfallDeviation float64
+	)
+
+	switch {
+	case shortfall <= 0:
+		shortfallDeviation = 0
+
+	case prizePool <= 0:
+		shortfallDeviation = 1
+
+	default:
+		shortfallDeviation = shortfall / prizePool
+	}
+
+	// every reward sent should have been paid or blocked by the contract
+
+	var (
+		settled = paid + blocked
+		larger  = math.Max(sent, settled)
+
+		payoutDeviation float64
+	)
+
+	if larger > 0 {
+		payoutDeviation = math.Abs(sent-settled) / larger
+	}
+
+	reconciliation_.ShortfallDeviation = shortfallDeviation
+	reconciliation_.PayoutDeviation = payoutDeviation
+	reconciliation_.Tolerance = tolerance
+
+	reconciliation_.WithinTolerance = shortfallDeviation <= tolerance &&
+		payoutDeviation <= tolerance
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reconciliation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileBalanced(t *testing.T) {
+	reconciliation_ := reconciliation.PrizePoolReconciliation{
+		PrizePool:       1000,
+		UnpaidWinnings:  100,
+		BlockedWinnings: 10,
+		SentWinnings:    510,
+		PaidWinnings:    500,
+	}
+
+	reconcile(&reconciliation_, 0.01)
+
+	assert.Equal(t, 0., reconciliation_.ShortfallDeviation)
+	assert.Equal(t, 0., reconciliation_.PayoutDeviation)
+	assert.Equal(t, 0.01, reconciliation_.Tolerance)
+	assert.True(t, reconciliation_.WithinTolerance)
+}
+
+func TestReconcileShortfall(t *testing.T) {
+	reconciliation_ := reconciliation.PrizePoolReconciliation{
+		PrizePool:      100,
+		UnpaidWinnings: 150,
+	}
+
+	reconcile(&reconciliation_, 0.01)
+
+	assert.Equal(t, 0.5, reconciliation_.ShortfallDeviation)
+	assert.False(t, reconciliation_.WithinTolerance)
+
+	// an empty pool owing anything is entirely short
+
+	reconciliation_ = reconciliation.PrizePoolReconciliation{
+		UnpaidWinnings: 1,
+	}
+
+	reconcile(&reconciliation_, 0.01)
+
+	assert.Equal(t, 1., reconciliation_.ShortfallDeviation)
+	assert.False(t, reconciliation_.WithinTolerance)
+}
+
+func TestReconcilePayoutDrift(t *testing.T) {
+	// rewards sent to the contract that weren't seen paid
+
+	reconciliation_ := reconciliation.PrizePoolReconciliation{
+		PrizePool:    1000,
+		SentWinnings: 200,
+		PaidWinnings: 150,
+	}
+
+	reconcile(&reconciliation_, 0.1)
+
+	assert.Equal(t, 0., reconciliation_.ShortfallDeviation)
+	assert.Equal(t, 0.25, reconciliation_.PayoutDeviation)
+	assert.False(t, reconciliation_.WithinTolerance)
+
+	reconcile(&reconciliation_, 0.3)
+
+	assert.True(t, reconciliation_.WithinTolerance)
+}
+
+func TestReconcileEmpty(t *testing.T) {
+	var reconciliation_ reconciliation.PrizePoolReconciliation
+
+	reconcile(&reconciliation_, 0)
+
+	assert.True(t, reconciliation_.WithinTolerance)
+}
@@ -5,12 +5,16 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
+	goEthereum "github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/fluidity"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/txmanager"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/types/winners"
 	"github.com/fluidity-money/fluidity-app/lib/util"
@@ -23,6 +27,15 @@ const (
 	// EnvShouldPayout to be set to `true` to release the payout,
 	// `false` to just acknowledge it
 	EnvShouldPayout = `FLU_ETHEREUM_PAYOUT`
+
+	// EnvEthereumHttpUrl to simulate the call with in a dry run
+	EnvEthereumHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+	// EnvContractAddress of the token to simulate the call on in a dry run
+	EnvContractAddress = `FLU_ETHEREUM_CONTRACT_ADDR`
+
+	// EnvOperatorAddress to simulate the call from in a dry run
+	EnvOperatorAddress = `FLU_ETHEREUM_OPERATOR_ADDR`
 )
 
 func main() {
@@ -93,4 +106,58 @@ func main() {
 	}
 
 	fmt.Println(hexutil.Encode(unblockCall))
+
+	if util.DryRunEnabled() {
+		simulateUnblock(unblockCall)
+	}
+}
+
+// simulateUnblock to check the call would succeed if sent by the operator
+func simulateUnblock(unblockCall []byte) {
+	var (
+		gethHttpUrl      = util.GetEnvOrFatal(EnvEthereumHttpUrl)
+		contractAddress_ = util.GetEnvOrFatal(EnvContractAddress)
+		operatorAddress_ = util.GetEnvOrFatal(EnvOperatorAddress)
+	)
+
+	var (
+		contractAddress = common.HexToAddress(contractAddress_)
+		operatorAddress = common.HexToAddress(operatorAddress_)
+	)
+
+	gethClient, err := ethclient.Dial(gethHttpUrl)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to connect to geth to simulate the call!"
+			k.Payload = err
+		})
+	}
+
+	defer gethClient.Close()
+
+	gasEstimate, err := txmanager.SimulateCall(
+		context.Background(),
+		gethClient,
+		goEthereum.CallMsg{
+			From: operatorAddress,
+			To:   &contractAddress,
+			Data: unblockCall,
+		},
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Simulating the unblockReward call failed!"
+			k.Payload = err
+		})
+	}
+
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Simulated the unblockReward call from %v, gas estimate %v!",
+			operatorAddress,
+			gasEstimate,
+		)
+	})
 }
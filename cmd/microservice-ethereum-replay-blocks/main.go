@@ -0,0 +1,275 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+
+	lib "github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-block-fluid-transfers-amqp/lib"
+	ethConvert "github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-block-fluid-transfers-amqp/lib/ethereum"
+
+	ethCommon "github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
+	workerQueue "github.com/fluidity-money/fluidity-app/lib/queues/worker"
+	types "github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	worker "github.com/fluidity-money/fluidity-app/lib/types/worker"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// EnvNetwork to replay blocks from (ethereum or arbitrum)
+	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvGethHttpUrl to fetch headers, blocks and receipts from
+	EnvGethHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+	// EnvFromBlock to start replaying from (inclusive)
+	EnvFromBlock = `FLU_ETHEREUM_REPLAY_FROM_BLOCK`
+
+	// EnvToBlock to stop replaying at (inclusive)
+	EnvToBlock = `FLU_ETHEREUM_REPLAY_TO_BLOCK`
+
+	// EnvTokenList to send the logs of down the logs topic, in the same
+	// format as connector-ethereum-reward-logs-amqp
+	EnvTokenList = `FLU_ETHEREUM_TOKENS_LIST`
+
+	// EnvReplayTopic to publish to, either "block-logs" (the default)
+	// to send blocks with their logs straight to the application server,
+	// or "headers" to send headers to microservice-ethereum-block-fluid-transfers-amqp
+	// down the replay topic for headers
+	EnvReplayTopic = `FLU_ETHEREUM_REPLAY_TOPIC`
+)
+
+const (
+	replayTopicBlockLogs = "block-logs"
+	replayTopicHeaders   = "headers"
+)
+
+// retries to use when fetching blocks, which should all exist already
+const (
+	blockRetries    = 1
+	blockRetryDelay = 0
+)
+
+// uint64FromEnvOrFatal reads a block number that must exist from the environment
+func uint64FromEnvOrFatal(env string) uint64 {
+	numString := util.GetEnvOrFatal(env)
+
+	num, err := strconv.ParseUint(numString, 10, 64)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format("Failed to read a block number from environment variable %s!", env)
+			k.Payload = err
+		})
+	}
+
+	return num
+}
+
+func main() {
+	var (
+		network_    = util.GetEnvOrFatal(EnvNetwork)
+		gethHttpApi = util.PickEnvOrFatal(EnvGethHttpUrl)
+		fromBlock   = uint64FromEnvOrFatal(EnvFromBlock)
+		toBlock     = uint64FromEnvOrFatal(EnvToBlock)
+		replayTopic = util.GetEnvOrDefault(EnvReplayTopic, replayTopicBlockLogs)
+		tokenList_  = util.GetEnvOrFatal(EnvTokenList)
+	)
+
+	tokenList := util.GetTokensListBase(tokenList_)
+
+	tokenAddresses := make(map[types.Address]bool, len(tokenList))
+
+	for _, token := range tokenList {
+		tokenAddresses[types.AddressFromString(token.TokenAddress)] = true
+	}
+
+	dbNetwork, err := network.ParseEthereumNetwork(network_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to parse Ethereum network (%#v) in env %v!",
+				network_,
+				EnvNetwork,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	if fromBlock > toBlock {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"The block to replay from (%v) is after the block to replay to (%v)!",
+				fromBlock,
+				toBlock,
+			)
+		})
+	}
+
+	if replayTopic != replayTopicBlockLogs && replayTopic != replayTopicHeaders {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Unknown replay topic %#v in env %v, expected %#v or %#v!",
+				replayTopic,
+				EnvReplayTopic,
+				replayTopicBlockLogs,
+				replayTopicHeaders,
+			)
+		})
+	}
+
+	gethClient, err := ethclient.Dial(gethHttpApi)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to connect to the Geth HTTP server!"
+			k.Payload = err
+		})
+	}
+
+	defer gethClient.Close()
+
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Replaying %v blocks %v to %v down the %v topic!",
+			dbNetwork,
+			fromBlock,
+			toBlock,
+			replayTopic,
+		)
+	})
+
+	// everything sent with this context is tagged as a replay, so the
+	// spooler and the sender won't pay anything out again
+
+	ctx := queue.WithReplay(context.Background())
+
+	for blockNumber := fromBlock; blockNumber <= toBlock; blockNumber++ {
+		replayBlock(ctx, gethClient, gethHttpApi, replayTopic, tokenAddresses, blockNumber)
+	}
+
+	queue.Finish()
+
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Finished replaying %v blocks %v to %v!",
+			dbNetwork,
+			fromBlock,
+			toBlock,
+		)
+	})
+}
+
+// replayBlock by looking up its header and sending it down the replay
+// topic for headers, or sending the block with its logs down the topic
+// that would've received it when it was mined. The logs emitted by the
+// tokens are sent down the logs topic either way, so user actions like
+// mints and burns are rebuilt as well
+func replayBlock(ctx context.Context, gethClient *ethclient.Client, gethHttpApi, replayTopic string, tokenAddresses map[types.Address]bool, blockNumber uint64) {
+	gethHeader, err := gethClient.HeaderByNumber(
+		ctx,
+		new(big.Int).SetUint64(blockNumber),
+	)
+
+	if err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Format("Failed to get the header for block %v!", blockNumber)
+			k.Payload = err
+		})
+	}
+
+	header := ethCommon.ConvertGethHeader(gethHeader)
+
+	log.DebugContext(ctx, func(k *log.Log) {
+		k.Format(
+			"Replaying block %v with hash %v!",
+			blockNumber,
+			header.BlockHash,
+		)
+	})
+
+	blockLog, err := getBlockLog(gethClient, gethHttpApi, header)
+
+	if err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Format(
+				"Failed to get the block log for block %v with hash %v!",
+				blockNumber,
+				header.BlockHash,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	for _, ethLog := range blockLog.Logs {
+		if !tokenAddresses[types.AddressFromString(ethLog.Address.String())] {
+			continue
+		}
+
+		queue.SendEnvelopeContext(ctx, ethQueue.TopicLogs, ethQueue.SchemaLog, ethLog)
+	}
+
+	if replayTopic == replayTopicHeaders {
+		queue.SendEnvelopeContext(ctx, ethQueue.TopicBlockHeadersReplay, ethQueue.SchemaBlockHeader, header)
+		return
+	}
+
+	queue.SendEnvelopeContext(ctx, workerQueue.TopicEthereumBlockLogs, workerQueue.SchemaEthereumBlockLog, *blockLog)
+}
+
+// getBlockLog the same way microservice-ethereum-block-fluid-transfers-amqp
+// would, taking the logs from the receipts of every transaction in the block
+func getBlockLog(gethClient *ethclient.Client, gethHttpApi string, header types.BlockHeader) (*worker.EthereumBlockLog, error) {
+	blockHash := header.BlockHash.String()
+
+	block, err := lib.GetBlockFromHash(gethHttpApi, blockHash, blockRetries, blockRetryDelay)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := ethConvert.ConvertTransactions(blockHash, block.Transactions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	logs := make([]types.Log, 0)
+
+	for _, transaction := range block.Transactions {
+		transactionHash := ethCommon.ConvertInternalHash(transaction.Hash)
+
+		receipt, err := gethClient.TransactionReceipt(context.Background(), transactionHash)
+
+		if err != nil {
+			return nil, err
+		}
+
+		logs = append(logs, ethCommon.ConvertGethLogs(receipt.Logs)...)
+	}
+
+	blockLog := worker.EthereumBlockLog{
+		BlockHash:    header.BlockHash,
+		BlockBaseFee: header.BaseFee,
+		BlockTime:    header.Time,
+		BlockNumber:  header.Number,
+		BaseFee:      header.BaseFee,
+		Logs:         logs,
+		Transactions: transactions,
+	}
+
+	return &blockLog, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+
+	"github.com/fluidity-money/fluidity-app/common/calculation/simulation"
+	"github.com/fluidity-money/fluidity-app/common/calculation/trf"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+)
+
+const (
+	// EnvScenario to read the scenario to simulate from, as a path to a
+	// JSON file
+	EnvScenario = `FLU_SIMULATION_SCENARIO`
+
+	// EnvOutputFormat to write the report in, either "json" (the
+	// default) or "csv"
+	EnvOutputFormat = `FLU_SIMULATION_OUTPUT_FORMAT`
+
+	// EnvReport to write if the output format is csv, either "summary"
+	// (the default) or "blocks"
+	EnvReport = `FLU_SIMULATION_REPORT`
+)
+
+const (
+	outputFormatJson = "json"
+	outputFormatCsv  = "csv"
+
+	reportSummary = "summary"
+	reportBlocks  = "blocks"
+)
+
+func main() {
+	var (
+		scenarioPath = util.GetEnvOrFatal(EnvScenario)
+		outputFormat = util.GetEnvOrDefault(EnvOutputFormat, outputFormatJson)
+		report_      = util.GetEnvOrDefault(EnvReport, reportSummary)
+	)
+
+	scenarioFile, err := os.Open(scenarioPath)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format("Failed to open the scenario at %#v!", scenarioPath)
+			k.Payload = err
+		})
+	}
+
+	var scenario simulation.Scenario
+
+	err = json.NewDecoder(scenarioFile).Decode(&scenario)
+
+	_ = scenarioFile.Close()
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format("Failed to decode the scenario at %#v!", scenarioPath)
+			k.Payload = err
+		})
+	}
+
+	// default to the constants the worker server is using
+
+	if scenario.WinningClasses == 0 {
+		scenario.WinningClasses = trf.WinningClasses
+	}
+
+	if scenario.PayoutFreq == nil {
+		scenario.PayoutFreq = big.NewRat(
+			trf.PayoutFreqNum,
+			trf.PayoutFreqDenom,
+		)
+	}
+
+	report, err := simulation.Run(scenario)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to run the simulation!"
+			k.Payload = err
+		})
+	}
+
+	switch outputFormat {
+	case outputFormatJson:
+		err = report.WriteJson(os.Stdout)
+
+	case outputFormatCsv:
+		switch report_ {
+		case reportSummary:
+			err = report.WriteSummaryCsv(os.Stdout)
+
+		case reportBlocks:
+			err = report.WriteBlocksCsv(os.Stdout)
+
+		default:
+			log.Fatal(func(k *log.Log) {
+				k.Format(
+					"Unknown report %#v, expected %#v or %#v!",
+					report_,
+					reportSummary,
+					reportBlocks,
+				)
+			})
+		}
+
+	default:
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Unknown output format %#v, expected %#v or %#v!",
+				outputFormat,
+				outputFormatJson,
+				outputFormatCsv,
+			)
+		})
+	}
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to write the report!"
+			k.Payload = err
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/calculation/simulation"
+	"github.com/fluidity-money/fluidity-app/common/calculation/trf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envRunMain to run main instead of the tests when the test binary is
+// started again by TestMainWithoutInfrastructure
+const envRunMain = `FLU_SIMULATION_TEST_RUN_MAIN`
+
+const testScenario = `{
+	"seed": 42,
+	"worker_config": {
+		"default_seconds_since_last_block": 13,
+		"default_transfers_in_block": 1,
+		"atx_buffer_size": 10,
+		"epoch_blocks": 5,
+		"spooler_instant_reward_threshold": 10,
+		"spooler_batched_reward_threshold": 1
+	},
+	"pools": [{
+		"utility_name": "FLUID",
+		"pool_size": "10000000000",
+		"token_decimals": "1000000",
+		"exchange_rate": "1",
+		"delta_weight": "31536000"
+	}],
+	"synthetic": {
+		"blocks": 50,
+		"min_transfers": 1,
+		"max_transfers": 4,
+		"min_gas_fee_usd": 0.5,
+		"max_gas_fee_usd": 3
+	}
+}`
+
+func TestMain(m *testing.M) {
+	if os.Getenv(envRunMain) != "" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// TestMainWithoutInfrastructure runs main in a new process with only the
+// scenario set, so any package that connects to Timescale, Redis or the
+// queue on startup would fail it
+func TestMainWithoutInfrastructure(t *testing.T) {
+	scenarioPath := filepath.Join(t.TempDir(), "scenario.json")
+
+	require.NoError(t, os.WriteFile(scenarioPath, []byte(testScenario), 0644))
+
+	cmd := exec.Command(os.Args[0])
+
+	cmd.Env = []string{
+		envRunMain + "=true",
+		EnvScenario + "=" + scenarioPath,
+	}
+
+	stdout, err := cmd.Output()
+
+	var stderr []byte
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		stderr = exitErr.Stderr
+	}
+
+	require.NoError(t, err, "main failed: %s", stderr)
+
+	var report simulation.Report
+
+	require.NoError(t, json.Unmarshal(stdout, &report))
+
+	assert.Len(t, report.Blocks, 50)
+
+	// the scenario doesn't set the winning classes, so the default is used
+
+	assert.Len(t, report.Summary.Tiers, trf.WinningClasses)
+}
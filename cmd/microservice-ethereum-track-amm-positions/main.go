@@ -72,7 +72,7 @@ func handleMint(log_ ethQueue.Log) {
 		})
 	}
 
-	queue.SendMessage(ammQueue.TopicPositionMint, mint)
+	queue.SendEnvelope(ammQueue.TopicPositionMint, ammQueue.SchemaPositionMint, mint)
 }
 
 func handleUpdate(log_ ethQueue.Log) {
@@ -85,5 +85,5 @@ func handleUpdate(log_ ethQueue.Log) {
 		})
 	}
 
-	queue.SendMessage(ammQueue.TopicPositionUpdate, update)
+	queue.SendEnvelope(ammQueue.TopicPositionUpdate, ammQueue.SchemaPositionUpdate, update)
 }
@@ -0,0 +1,145 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/prices"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// EnvGethHttpUrl to query Chainlink feeds and Uniswap pools with
+	EnvGethHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+	// EnvNetwork that the headers and Geth endpoint are on
+	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvPriceConfig with the tokens to record prices for, see
+	// common/ethereum/price
+	EnvPriceConfig = `FLU_ETHEREUM_PRICE_CONFIG`
+
+	// EnvRecordInterval to wait between recording prices in seconds of
+	// block time
+	EnvRecordInterval = `FLU_ETHEREUM_PRICE_RECORD_INTERVAL`
+)
+
+// defaultRecordInterval to record prices at if EnvRecordInterval isn't set
+const defaultRecordInterval = 60
+
+func main() {
+	var (
+		gethHttpUrl     = util.PickEnvOrFatal(EnvGethHttpUrl)
+		network_        = util.GetEnvOrFatal(EnvNetwork)
+		priceConfig_    = util.GetEnvOrFatal(EnvPriceConfig)
+		recordInterval_ = util.GetEnvOrDefault(EnvRecordInterval, strconv.Itoa(defaultRecordInterval))
+	)
+
+	dbNetwork, err := network.ParseEthereumNetwork(network_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to parse network from env"
+			k.Payload = err
+		})
+	}
+
+	priceConfig, err := price.ParseConfig(priceConfig_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to read the price config!"
+			k.Payload = err
+		})
+	}
+
+	recordInterval, err := strconv.Atoi(recordInterval_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to parse the record interval %#v!",
+				recordInterval_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	gethClient, err := ethclient.Dial(gethHttpUrl)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to connect to Geth!"
+			k.Payload = err
+		})
+	}
+
+	defer gethClient.Close()
+
+	// prices are always looked up fresh, so the oracle doesn't cache
+
+	priceOracle := price.NewOracle(gethClient, dbNetwork, *priceConfig, nil, nil)
+
+	var lastRecordedTime uint64
+
+	ethQueue.BlockHeaders(func(header ethQueue.BlockHeader) {
+		var (
+			blockNumber = header.Number.Uint64()
+			blockTime   = header.Time
+		)
+
+		if blockTime < lastRecordedTime+uint64(recordInterval) {
+			log.Debugf(
+				"Last recorded prices at %v, skipping block %v at %v",
+				lastRecordedTime,
+				blockNumber,
+				blockTime,
+			)
+
+			return
+		}
+
+		recordedTime := time.Unix(int64(blockTime), 0)
+
+		for token := range priceConfig.Tokens {
+			tokenPrice, err := priceOracle.GetPrice(token)
+
+			if err != nil {
+				log.App(func(k *log.Log) {
+					k.Format(
+						"Failed to get the price of %v at block %v, not recording it!",
+						token,
+						blockNumber,
+					)
+
+					k.Payload = err
+				})
+
+				continue
+			}
+
+			log.Debugf(
+				"Recording the price of %v at block %v as %v from %v",
+				token,
+				blockNumber,
+				tokenPrice.Usd.FloatString(6),
+				tokenPrice.Source,
+			)
+
+			prices.InsertPrice(dbNetwork, token, blockNumber, recordedTime, *tokenPrice)
+		}
+
+		lastRecordedTime = blockTime
+	})
+}
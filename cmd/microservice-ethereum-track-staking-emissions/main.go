@@ -5,13 +5,17 @@
 package main
 
 import (
+	"os"
+
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/chainlink"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/fluidity"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price/cache"
 	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/airdrop"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	ethLogs "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 )
 
@@ -19,16 +23,43 @@ const (
 	// EnvEthereumWsUrl is the url to use to connect to the WS Geth endpoint
 	EnvEthereumHttpUrl = `FLU_ETHEREUM_HTTP_URL`
 
-	// EnvChainlinkEthPriceFeed to get the price of eth in usd from
+	// EnvChainlinkEthPriceFeed to get the price of eth in usd from if
+	// EnvPriceConfig isn't set
 	EnvChainlinkEthPriceFeed = `FLU_ETHEREUM_CHAINLINK_ETH_FEED_ADDR`
+
+	// EnvPriceConfig to look up the price of WETH (as "WETH") with, see
+	// common/ethereum/price
+	EnvPriceConfig = `FLU_ETHEREUM_PRICE_CONFIG`
 )
 
+// priceTokenWeth to look up in the price config
+const priceTokenWeth = "WETH"
+
 func main() {
 	var (
-		gethHttpUrl          = util.PickEnvOrFatal(EnvEthereumHttpUrl)
-		wethPriceFeedAddress = mustEthereumAddressFromEnv(EnvChainlinkEthPriceFeed)
+		gethHttpUrl  = util.PickEnvOrFatal(EnvEthereumHttpUrl)
+		priceConfig_ = os.Getenv(EnvPriceConfig)
 	)
 
+	var priceConfig price.Config
+
+	if priceConfig_ == "" {
+		wethPriceFeedAddress := mustEthereumAddressFromEnv(EnvChainlinkEthPriceFeed)
+
+		priceConfig = price.ChainlinkConfig(priceTokenWeth, wethPriceFeedAddress)
+	} else {
+		parsedConfig, err := price.ParseConfig(priceConfig_)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Message = "Failed to read the price config!"
+				k.Payload = err
+			})
+		}
+
+		priceConfig = *parsedConfig
+	}
+
 	ethClient, err := ethclient.Dial(gethHttpUrl)
 
 	if err != nil {
@@ -38,18 +69,28 @@ func main() {
 		})
 	}
 
+	// staking only happens on mainnet
+
+	priceOracle := price.NewOracle(
+		ethClient,
+		network.NetworkEthereum,
+		priceConfig,
+		cache.State{},
+		nil,
+	)
+
 	ethLogs.Logs(func(l ethLogs.Log) {
 
-		wethPriceUsd, err := chainlink.GetPrice(ethClient, wethPriceFeedAddress)
+		wethPrice, err := priceOracle.GetPrice(priceTokenWeth)
 
 		if err != nil {
 			log.Fatal(func(k *log.Log) {
-				k.Message = "Failed to get the price of WETH from chainlink!"
+				k.Message = "Failed to get the price of WETH!"
 				k.Payload = err
 			})
 		}
 
-		stakingEvent, err := fluidity.TryDecodeStakingEventData(l, wethPriceUsd)
+		stakingEvent, err := fluidity.TryDecodeStakingEventData(l, wethPrice.Usd)
 
 		switch err {
 		case fluidity.ErrWrongEvent:
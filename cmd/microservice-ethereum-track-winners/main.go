@@ -8,10 +8,12 @@ package main
 // without using abigen/etc
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
 	logging "github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
 	"github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/queues/winners"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
@@ -81,7 +83,13 @@ func main() {
 		})
 	}
 
-	ethereum.Logs(func(log ethereum.Log) {
+	ethereum.LogsContext(func(ctx context.Context, log ethereum.Log) {
+		// replayed rewards were tracked the first time they were seen
+
+		if queue.IsReplay(ctx) {
+			return
+		}
+
 		var (
 			logTopics        = log.Topics
 			transactionHash  = log.TxHash
@@ -94,7 +102,7 @@ func main() {
 		logAddressString = strings.ToLower(logAddressString)
 		filterAddress = strings.ToLower(filterAddress)
 
-		logging.Debug(func(k *logging.Log) {
+		logging.DebugContext(ctx, func(k *logging.Log) {
 			k.Format(
 				"Found a log, address was %v, trying to match %v",
 				logAddress,
@@ -105,7 +113,7 @@ func main() {
 		// address doesn't match our target contract!
 
 		if filterAddress != logAddressString {
-			logging.Debug(func(k *logging.Log) {
+			logging.DebugContext(ctx, func(k *logging.Log) {
 				k.Format(
 					"Address %s doesn't match %s, skipping!",
 					logAddress,
@@ -117,7 +125,7 @@ func main() {
 		}
 
 		if len(logTopics) == 0 {
-			logging.Debug(func(k *logging.Log) {
+			logging.DebugContext(ctx, func(k *logging.Log) {
 				k.Format(
 					"Log has no topics! Skipping...",
 				)
@@ -140,7 +148,9 @@ func main() {
 		switch err {
 		case nil:
 			processReward(
+				ctx,
 				logAddress,
+				log.BlockHash,
 				transactionHash,
 				rewardData,
 				tokenDetails,
@@ -150,7 +160,7 @@ func main() {
 			return
 
 		case fluidity.ErrWrongEvent:
-			logging.Debug(func(k *logging.Log) {
+			logging.DebugContext(ctx, func(k *logging.Log) {
 				k.Format(
 					"Log signature %s didn't decode as a reward or blockedReward!",
 					logTopics[0],
@@ -158,7 +168,7 @@ func main() {
 			})
 
 		default:
-			logging.Fatal(func(k *logging.Log) {
+			logging.FatalContext(ctx, func(k *logging.Log) {
 				k.Message = "Error decoding a reward or blockedReward!"
 				k.Payload = err
 			})
@@ -172,6 +182,7 @@ func main() {
 		switch err {
 		case nil:
 			processUnblockedReward(
+				ctx,
 				transactionHash,
 				unblockedRewardData,
 				tokenDetails,
@@ -179,7 +190,7 @@ func main() {
 			)
 
 		case fluidity.ErrWrongEvent:
-			logging.Debug(func(k *logging.Log) {
+			logging.DebugContext(ctx, func(k *logging.Log) {
 				k.Format(
 					"Log signature %s didn't decode as an unblockedReward!",
 					logTopics[0],
@@ -187,7 +198,7 @@ func main() {
 			})
 
 		default:
-			logging.Fatal(func(k *logging.Log) {
+			logging.FatalContext(ctx, func(k *logging.Log) {
 				k.Message = "Error decoding a reward or blockedReward!"
 				k.Payload = err
 			})
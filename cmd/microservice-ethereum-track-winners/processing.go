@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"time"
 
 	winnersDb "github.com/fluidity-money/fluidity-app/lib/databases/timescale/winners"
@@ -15,7 +16,7 @@ import (
 	"github.com/fluidity-money/fluidity-app/common/ethereum/fluidity"
 )
 
-func processReward(contractAddress ethereum.Address, transactionHash ethereum.Hash, data fluidity.RewardData, tokenDetails token_details.TokenDetails, network network.BlockchainNetwork) {
+func processReward(ctx context.Context, contractAddress ethereum.Address, blockHash, transactionHash ethereum.Hash, data fluidity.RewardData, tokenDetails token_details.TokenDetails, network network.BlockchainNetwork) {
 	var (
 		winnerString = data.Winner.String()
 		startBlock   = *data.StartBlock
@@ -28,6 +29,7 @@ func processReward(contractAddress ethereum.Address, transactionHash ethereum.Ha
 	if blocked {
 		blockedWinner := convertBlockedWinner(
 			contractAddress,
+			blockHash,
 			transactionHash,
 			winnerAddress,
 			data,
@@ -35,7 +37,7 @@ func processReward(contractAddress ethereum.Address, transactionHash ethereum.Ha
 			network,
 		)
 
-		queue.SendMessage(winnersQueue.TopicBlockedWinnersEthereum, blockedWinner)
+		queue.SendEnvelopeContext(ctx, winnersQueue.TopicBlockedWinnersEthereum, winnersQueue.SchemaBlockedWinner, blockedWinner)
 
 		return
 	}
@@ -58,10 +60,10 @@ func processReward(contractAddress ethereum.Address, transactionHash ethereum.Ha
 		time.Now(),
 	)
 
-	sendRewards(winnersQueue.TopicWinnersEthereum, convertedWinners)
+	sendRewards(ctx, winnersQueue.TopicWinnersEthereum, convertedWinners)
 }
 
-func processUnblockedReward(transactionHash ethereum.Hash, data fluidity.UnblockedRewardData, tokenDetails token_details.TokenDetails, network network.BlockchainNetwork) {
+func processUnblockedReward(ctx context.Context, transactionHash ethereum.Hash, data fluidity.UnblockedRewardData, tokenDetails token_details.TokenDetails, network network.BlockchainNetwork) {
 	rewardData := data.RewardData
 
 	var (
@@ -90,12 +92,35 @@ func processUnblockedReward(transactionHash ethereum.Hash, data fluidity.Unblock
 		time.Now(),
 	)
 
-	sendRewards(winnersQueue.TopicWinnersEthereum, convertedWinners)
+	sendRewards(ctx, winnersQueue.TopicWinnersEthereum, convertedWinners)
+
+	// the released reward is paid out as winners, so it isn't counted as
+	// blocked anymore
+
+	released := winnersDb.ReleaseBlockedWinners(
+		network,
+		tokenDetails.TokenShortName,
+		startBlock,
+		endBlock,
+		winnerAddress,
+		transactionHash,
+	)
+
+	log.AppContext(ctx, func(k *log.Log) {
+		k.Format(
+			"Released %v blocked winners for %v in blocks %v to %v with transaction %v",
+			released,
+			winnerAddress,
+			startBlock.String(),
+			endBlock.String(),
+			transactionHash,
+		)
+	})
 }
 
-func sendRewards(topic string, rewards []winnersDb.Winner) {
+func sendRewards(ctx context.Context, topic string, rewards []winnersDb.Winner) {
 	for _, reward := range rewards {
-		queue.SendMessage(winnersQueue.TopicWinnersEthereum, reward)
+		queue.SendEnvelopeContext(ctx, winnersQueue.TopicWinnersEthereum, winnersQueue.SchemaWinner, reward)
 	}
 }
 
@@ -153,7 +178,7 @@ func convertWinners(pendingRewards []winnersDb.PendingRewardData, transactionHas
 }
 
 // Convert, returning the internal definition for a blocked winner
-func convertBlockedWinner(contractAddress ethereum.Address, transactionHash ethereum.Hash, winnerAddress ethereum.Address, data fluidity.RewardData, tokenDetails token_details.TokenDetails, network network.BlockchainNetwork) winners.BlockedWinner {
+func convertBlockedWinner(contractAddress ethereum.Address, blockHash, transactionHash ethereum.Hash, winnerAddress ethereum.Address, data fluidity.RewardData, tokenDetails token_details.TokenDetails, network network.BlockchainNetwork) winners.BlockedWinner {
 	var (
 		contractAddressString = contractAddress.String()
 		transactionHashString = transactionHash.String()
@@ -173,6 +198,7 @@ func convertBlockedWinner(contractAddress ethereum.Address, transactionHash ethe
 		WinningAmount:           *winningAmount,
 		BatchFirstBlock:         *firstBlock,
 		BatchLastBlock:          *lastBlock,
+		BlockHash:               blockHash.String(),
 	}
 
 	return blockedWinner
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"strconv"
 	"time"
 
@@ -74,8 +75,12 @@ func main() {
 		})
 	}
 
-	ethereum.Logs(func(ethLog ethereum.Log) {
+	// replayed logs are handled as well, with the user actions sent
+	// tagged as replays so they aren't inserted twice
+
+	ethereum.LogsContext(func(ctx context.Context, ethLog ethereum.Log) {
 		var (
+			blockHash       = ethLog.BlockHash
 			transactionHash = ethLog.TxHash
 			logTopics       = ethLog.Topics
 			logData         = ethLog.Data
@@ -92,7 +97,7 @@ func main() {
 			return
 		}
 
-		log.Debug(func(k *log.Log) {
+		log.DebugContext(ctx, func(k *log.Log) {
 			k.Format(
 				"The number of log topics is %v, expecting more than 2!",
 				len(logTopics),
@@ -140,7 +145,9 @@ func main() {
 			)
 
 			handleMint(
+				ctx,
 				network_,
+				blockHash,
 				transactionHash,
 				topicRemaining,
 				logData,
@@ -156,7 +163,9 @@ func main() {
 			)
 
 			handleBurn(
+				ctx,
 				network_,
+				blockHash,
 				transactionHash,
 				topicRemaining,
 				logData,
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"encoding/hex"
 	"time"
 
@@ -18,9 +19,9 @@ import (
 	"github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-user-actions/lib"
 )
 
-func handleMint(network_ network.BlockchainNetwork, transactionHash ethereum.Hash, topics []ethereum.Hash, data misc.Blob, time time.Time, tokenShortName string, tokenDecimals int) {
+func handleMint(ctx context.Context, network_ network.BlockchainNetwork, blockHash, transactionHash ethereum.Hash, topics []ethereum.Hash, data misc.Blob, time time.Time, tokenShortName string, tokenDecimals int) {
 	if lenTopics := len(topics); lenTopics != 1 {
-		log.Fatal(func(k *log.Log) {
+		log.FatalContext(ctx, func(k *log.Log) {
 			k.Format(
 				"Length of the topic for the mint is not 1! Was %v!",
 				lenTopics,
@@ -39,7 +40,7 @@ func handleMint(network_ network.BlockchainNetwork, transactionHash ethereum.Has
 	)
 
 	if err != nil {
-		log.Fatal(func(k *log.Log) {
+		log.FatalContext(ctx, func(k *log.Log) {
 			k.Message = "Failed to decide a mint event with a two log!"
 			k.Payload = err
 		})
@@ -57,8 +58,12 @@ func handleMint(network_ network.BlockchainNetwork, transactionHash ethereum.Has
 		tokenDecimals,
 	)
 
-	queue.SendMessage(
+	mint.BlockHash = blockHash.String()
+
+	queue.SendEnvelopeContext(
+		ctx,
 		user_actions.TopicUserActionsEthereum,
+		user_actions.SchemaUserAction,
 		mint,
 	)
 }
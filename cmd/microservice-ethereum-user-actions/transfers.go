@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"time"
 
 	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/worker"
@@ -22,9 +23,9 @@ import (
 // ZeroAddress is ignored when sent to by ending handleTransfer early
 const ZeroAddress = "0x0000000000000000000000000000000000000000"
 
-func handleTransfer(network_ network.BlockchainNetwork, transactionHash ethereum.Hash, logAddress ethereum.Address, logTopics []ethereum.Hash, data []byte, time time.Time, tokenShortName string, tokenDecimals int, logIndex misc.BigInt, applicationContracts map[ethereum.Address]applications.Application) {
+func handleTransfer(ctx context.Context, network_ network.BlockchainNetwork, transactionHash ethereum.Hash, logAddress ethereum.Address, logTopics []ethereum.Hash, data []byte, time time.Time, tokenShortName string, tokenDecimals int, logIndex misc.BigInt, applicationContracts map[ethereum.Address]applications.Application) {
 	if lenTopics := len(logTopics); lenTopics != 2 {
-		log.Fatal(func(k *log.Log) {
+		log.FatalContext(ctx, func(k *log.Log) {
 			k.Format(
 				"Length of the log topics for transfer is not 2! Is %v!",
 				lenTopics,
@@ -52,7 +53,7 @@ func handleTransfer(network_ network.BlockchainNetwork, transactionHash ethereum
 	)
 
 	if err != nil {
-		log.Fatal(func(k *log.Log) {
+		log.FatalContext(ctx, func(k *log.Log) {
 			k.Message = "Failed to decode a transfer event to a user action!"
 			k.Payload = err
 		})
@@ -77,8 +78,10 @@ func handleTransfer(network_ network.BlockchainNetwork, transactionHash ethereum
 	transfer.SenderAddress = senderAddress.String()
 	transfer.RecipientAddress = recipientAddress.String()
 
-	queue.SendMessage(
+	queue.SendEnvelopeContext(
+		ctx,
 		user_actions.TopicUserActionsEthereum,
+		user_actions.SchemaUserAction,
 		transfer,
 	)
 }
@@ -5,10 +5,14 @@
 package main
 
 import (
+	"context"
+
 	"github.com/fluidity-money/fluidity-app/common/calculation/probability"
 
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
+	workerQueue "github.com/fluidity-money/fluidity-app/lib/queues/worker"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
 	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
@@ -40,6 +44,8 @@ type ethereumUniqueTransfer struct {
 }
 
 func main() {
+	lifecycle.Start()
+
 	var (
 		publishAmqpQueueName = util.GetEnvOrFatal(EnvPublishAmqpQueueName)
 		rewardsAmqpQueueName = util.GetEnvOrFatal(EnvRewardsAmqpQueueName)
@@ -55,12 +61,10 @@ func main() {
 		})
 	}
 
-	queue.GetMessages(publishAmqpQueueName, func(message queue.Message) {
-
-		var announcements []worker.EthereumAnnouncement
-		message.Decode(&announcements)
+	queue.GetEnvelopesContext(publishAmqpQueueName, workerQueue.SchemaEthereumAnnouncements, func(ctx context.Context, decoded interface{}) {
+		announcements := decoded.([]worker.EthereumAnnouncement)
 
-		processAnnouncements(announcements, rewardsAmqpQueueName, network_)
+		processAnnouncements(ctx, announcements, rewardsAmqpQueueName, network_)
 	})
 }
 
@@ -82,7 +86,7 @@ func mergePayouts(source map[applications.UtilityName]worker.Payout, dest map[ap
 
 // processAnnouncements to handle fluid transfer or application-based win
 // announcements and determine their winning status
-func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAmqpQueueName string, network_ network.BlockchainNetwork) {
+func processAnnouncements(ctx context.Context, announcements []worker.EthereumAnnouncement, rewardsAmqpQueueName string, network_ network.BlockchainNetwork) {
 	winAnnouncements := make(map[ethereumUniqueTransfer]worker.EthereumWinnerAnnouncement)
 
 	for _, announcement := range announcements {
@@ -90,6 +94,7 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 		var (
 			announcementTransactionHash = announcement.TransactionHash
 			blockNumber                 = announcement.BlockNumber
+			blockHash                   = announcement.BlockHash
 			logIndex                    = announcement.LogIndex
 			fromAddress                 = announcement.FromAddress
 			toAddress                   = announcement.ToAddress
@@ -102,7 +107,7 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 		)
 
 		if fromAddress == ethereumNullAddress {
-			log.App(func(k *log.Log) {
+			log.AppContext(ctx, func(k *log.Log) {
 				k.Format(
 					"From address was nil in transaction hash %#v! To was set to %#v!",
 					announcementTransactionHash,
@@ -114,7 +119,7 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 		}
 
 		if toAddress == ethereumNullAddress {
-			log.App(func(k *log.Log) {
+			log.AppContext(ctx, func(k *log.Log) {
 				k.Format(
 					"To address was nil in transaction hash %#v! From was set to %#v!",
 					announcementTransactionHash,
@@ -130,7 +135,7 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 		winningBalls := probability.NaiveIsWinning(sourceRandom, &emission)
 
 		if winningBalls == 0 {
-			log.App(func(k *log.Log) {
+			log.AppContext(ctx, func(k *log.Log) {
 				k.Format(
 					"From %#v to %#v transaction hash %#v didn't win anything!",
 					fromAddress,
@@ -144,7 +149,7 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 
 		fromWinAmounts, toWinAmounts := probability.CalculatePayoutsSplit(sourcePayouts, winningBalls)
 
-		log.App(func(k *log.Log) {
+		log.AppContext(ctx, func(k *log.Log) {
 			k.Format(
 				"Transaction hash %#v with transaction from %#v to %#v and application %v has won: %#v won %s,%#v won %s",
 				announcementTransactionHash,
@@ -172,6 +177,7 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 				TransactionHash: announcementTransactionHash,
 				LogIndex:        logIndex,
 				BlockNumber:     blockNumber,
+				BlockHash:       blockHash,
 				FromAddress:     fromAddress,
 				FromWinAmount:   make(map[applications.UtilityName]worker.Payout),
 				ToAddress:       toAddress,
@@ -195,6 +201,6 @@ func processAnnouncements(announcements []worker.EthereumAnnouncement, rewardsAm
 			winAnnouncementsList = append(winAnnouncementsList, announcement)
 		}
 
-		queue.SendMessage(rewardsAmqpQueueName, winAnnouncementsList)
+		queue.SendEnvelopeContext(ctx, rewardsAmqpQueueName, workerQueue.SchemaEthereumWinnerAnnouncements, winAnnouncementsList)
 	}
 }
@@ -11,15 +11,18 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethAbiBind "github.com/ethereum/go-ethereum/accounts/abi/bind"
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	ethCrypto "github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 
 	"github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/txmanager"
+	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/transactions"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/queues/dry-run"
 	appTypes "github.com/fluidity-money/fluidity-app/lib/types/applications"
 	typesEth "github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
@@ -60,8 +63,9 @@ const (
 	EnvUtilityTokensMap = `FLU_ETHEREUM_UTILITY_TOKENS_LOOKUP`
 )
 
-// wait at most 5 minutes for our transactions to be mined
-const MiningTimeout = time.Minute * 5
+// wait at most 30 minutes for our transactions to be mined, including
+// the attempts made by the transaction manager with higher fees
+const MiningTimeout = time.Minute * 30
 
 // details needed to update the reward type database
 type win = struct {
@@ -177,7 +181,10 @@ func main() {
 	rewardsQueue := make(chan worker.EthereumSpooledRewards)
 	lpRewardsQueue := make(chan worker.EthereumSpooledLpRewards)
 
-	go queue.GetMessages(publishAmqpQueueName, func(message queue.Message) {
+	// replays shouldn't pay out again, though the spooler shouldn't
+	// send them either
+
+	go queue.GetMessages(publishAmqpQueueName, queue.SkipReplays(func(message queue.Message) {
 		var announcement worker.EthereumSpooledRewards
 
 		message.Decode(&announcement)
@@ -190,9 +197,12 @@ func main() {
 		}
 
 		rewardsQueue <- announcement
-	})
+	}))
+
+	// replays shouldn't pay out again, though the spooler shouldn't
+	// send them either
 
-	go queue.GetMessages(publishLpRewardsQueueName, func(message queue.Message) {
+	go queue.GetMessages(publishLpRewardsQueueName, queue.SkipReplays(func(message queue.Message) {
 		var announcement worker.EthereumSpooledLpRewards
 
 		message.Decode(&announcement)
@@ -205,71 +215,102 @@ func main() {
 		}
 
 		lpRewardsQueue <- announcement
-	})
+	}))
+
+	// with FLU_DRY_RUN set, transactions are simulated and the results
+	// published instead
+
+	managerConfig := txmanager.DefaultConfig()
+
+	managerConfig.PublishDryRun = dry_run.Publish
+
+	manager, err := txmanager.New(
+		ethClient,
+		transactions.Store{},
+		transactionOptions,
+		managerConfig,
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to create the transaction manager!"
+			k.Payload = err
+		})
+	}
 
 	for {
-		var transaction *types.Transaction
+		var transact txmanager.TransactFunc
 
-		// we need this to process single threadedly to stop nonce desyncs
+		// the transaction manager reserves nonces, but we still wait
+		// for each transaction to be mined before sending the next
 		select {
 		case announcement := <-rewardsQueue:
-			rewardTransactionArguments := callRewardArguments{
-				transactionOptions:    transactionOptions,
-				containerAnnouncement: announcement,
-				executorAddress:       executorAddress_,
-				contractAddress:       contractAddress_,
-				client:                ethClient,
-				useHardhatFix:         useHardhatFix,
-				hardcodedGasLimit:     gasLimit,
+			transact = func(transactionOptions *ethAbiBind.TransactOpts) (*types.Transaction, error) {
+				rewardTransactionArguments := callRewardArguments{
+					transactionOptions:    transactionOptions,
+					containerAnnouncement: announcement,
+					executorAddress:       executorAddress_,
+					contractAddress:       contractAddress_,
+					client:                ethClient,
+					useHardhatFix:         useHardhatFix,
+					hardcodedGasLimit:     gasLimit,
+				}
+
+				return callRewardFunction(rewardTransactionArguments)
 			}
 
-			transaction, err = callRewardFunction(rewardTransactionArguments)
-
 		case announcement := <-lpRewardsQueue:
-			lpRewardTransactionArguments := callLpRewardArguments{
-				tokens:                utilityTokensMap,
-				transactionOptions:    transactionOptions,
-				containerAnnouncement: announcement,
-				executorAddress:       executorAddress_,
-				contractAddress:       contractAddress_,
-				client:                ethClient,
+			transact = func(transactionOptions *ethAbiBind.TransactOpts) (*types.Transaction, error) {
+				lpRewardTransactionArguments := callLpRewardArguments{
+					tokens:                utilityTokensMap,
+					transactionOptions:    transactionOptions,
+					containerAnnouncement: announcement,
+					executorAddress:       executorAddress_,
+					contractAddress:       contractAddress_,
+					client:                ethClient,
+				}
+
+				return callLpRewardFunction(lpRewardTransactionArguments)
 			}
-
-			transaction, err = callLpRewardFunction(lpRewardTransactionArguments)
-		}
-
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Format(
-					"Failed to call a reward transaction with transaction hash %#v!",
-					transaction,
-				)
-
-				k.Payload = err
-			})
 		}
 
-		log.App(func(k *log.Log) {
-			k.Message = "Successfully called a contract function with hash"
-			k.Payload = transaction.Hash().Hex()
-		})
-
-		log.Debugf("Waiting for reward transaction to be mined...")
+		log.Debugf("Sending a reward transaction and waiting for it to be mined...")
 
 		ctx, cancel := context.WithTimeout(context.Background(), MiningTimeout)
 
-		receipt, err := bind.WaitMined(ctx, ethClient, transaction)
+		receipt, err := manager.Send(ctx, transact)
 
 		// done with the context now
 		cancel()
 
 		if err != nil {
 			log.Fatal(func(k *log.Log) {
-				k.Message = "Error waiting for reward transaction to be mined!"
+				k.Message = "Failed to send a reward transaction!"
 				k.Payload = err
 			})
 		}
 
+		if manager.DryRun() {
+			continue
+		}
+
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			log.App(func(k *log.Log) {
+				k.Format(
+					"Reward transaction %v reverted in block %v!",
+					receipt.TxHash.Hex(),
+					receipt.BlockNumber,
+				)
+			})
+
+			continue
+		}
+
+		log.App(func(k *log.Log) {
+			k.Message = "Successfully called a contract function with hash"
+			k.Payload = receipt.TxHash.Hex()
+		})
+
 		log.Debugf(
 			"Reward transaction mined in block %s!",
 			receipt.BlockNumber.String(),
@@ -5,10 +5,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/fluidity-money/fluidity-app/common/calculation/moving-average"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
 )
 
@@ -37,6 +39,19 @@ func computeTransactionsSumAndAverage(key string, limit int, shouldPop bool) (in
 	return average, sum
 }
 
-func createMovingAverageKey(network_ network.BlockchainNetwork, token string) string {
-	return fmt.Sprintf("%v.%v.transfer-count", network_, token)
+func createMovingAverageKey(ctx context.Context, network_ network.BlockchainNetwork, token string) string {
+	key := fmt.Sprintf("%v.%v.transfer-count", network_, token)
+
+	return replayStateKey(ctx, key)
+}
+
+// replayStateKey to use for the key given if the block is being
+// replayed, so replays track their own state in Redis and never change
+// the moving average (or block time) that live payouts are based on
+func replayStateKey(ctx context.Context, key string) string {
+	if !queue.IsReplay(ctx) {
+		return key
+	}
+
+	return key + ".replay"
 }
@@ -5,10 +5,12 @@
 package main
 
 import (
+	"context"
 	"math/big"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -16,11 +18,15 @@ import (
 	"github.com/fluidity-money/fluidity-app/common/calculation/probability"
 	commonEth "github.com/fluidity-money/fluidity-app/common/ethereum"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/applications"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/chainlink"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/fluidity"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price/cache"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/yield"
 
 	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/failsafe"
 	worker_config "github.com/fluidity-money/fluidity-app/lib/databases/postgres/worker"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/prices"
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
 	"github.com/fluidity-money/fluidity-app/lib/queues/worker"
@@ -32,11 +38,11 @@ import (
 )
 
 const (
-	// SecondsInOneYear to use for ATX calculation
-	SecondsInOneYear uint64 = 365 * 24 * 60 * 60
-
 	// EthereumDecimals to normalise values to
 	EthereumDecimals int64 = 1e18
+
+	// priceTokenEth to look up in the price config
+	priceTokenEth = "ETH"
 )
 
 const (
@@ -52,9 +58,14 @@ const (
 	// EnvEthereumHttpUrl to use to get information on the apy and atx from chainlink
 	EnvEthereumHttpUrl = `FLU_ETHEREUM_HTTP_URL`
 
-	// EnvChainlinkEthPriceFeed to get the price of eth in usd from
+	// EnvChainlinkEthPriceFeed to get the price of eth in usd from if
+	// EnvPriceConfig isn't set
 	EnvChainlinkEthPriceFeed = `FLU_ETHEREUM_CHAINLINK_ETH_FEED_ADDR`
 
+	// EnvPriceConfig to look up the price of ETH (as "ETH") with, see
+	// common/ethereum/price
+	EnvPriceConfig = `FLU_ETHEREUM_PRICE_CONFIG`
+
 	// EnvChainlinkEthPriceNetworkUrl to use if the network
 	// currently in use lacks Chainlink feeds, if set to nothing
 	// defaults to FLU_ETHEREUM_HTTP_URL
@@ -82,6 +93,10 @@ const (
 
 	// EnvGlobalUtilityRewards to always pay out based on the internal config, just utility names.
 	EnvGlobalUtilityRewards = `FLU_ETHEREUM_GLOBAL_UTILITY_REWARDS`
+
+	// EnvYieldSource to look up the prize pool of the fluid token with
+	// instead of the registry, see common/ethereum/yield
+	EnvYieldSource = `FLU_ETHEREUM_YIELD_SOURCE`
 )
 
 type PayoutDetails struct {
@@ -91,6 +106,8 @@ type PayoutDetails struct {
 }
 
 func main() {
+	lifecycle.Start()
+
 	var (
 		serverWorkAmqpTopic  = util.GetEnvOrFatal(EnvServerWorkQueue)
 		publishAmqpQueueName = util.GetEnvOrFatal(EnvPublishAmqpQueueName)
@@ -98,15 +115,17 @@ func main() {
 		networkId            = util.GetEnvOrFatal(EnvNetwork)
 		tokenDetails_        = util.GetEnvOrFatal(EnvTokenDetails)
 
-		contractAddress       = mustEthereumAddressFromEnv(EnvContractAddress)
-		registryAddress       = mustEthereumAddressFromEnv(EnvRegistryAddress)
-		chainlinkEthPriceFeed = mustEthereumAddressFromEnv(EnvChainlinkEthPriceFeed)
+		contractAddress = mustEthereumAddressFromEnv(EnvContractAddress)
+		registryAddress = mustEthereumAddressFromEnv(EnvRegistryAddress)
 
 		ammLpPoolAddr_ = mustEthereumAddressFromEnv(EnvAmmLpPoolAddress)
 
 		chainlinkEthPriceFeedUrl = os.Getenv(EnvChainlinkEthPriceNetworkUrl)
+		priceConfig_             = os.Getenv(EnvPriceConfig)
 
 		globalUtilityRewards_ = os.Getenv(EnvGlobalUtilityRewards)
+
+		yieldSourceConfig_ = os.Getenv(EnvYieldSource)
 	)
 
 	ammLpPoolAddr := commonEth.ConvertGethAddress(ammLpPoolAddr_)
@@ -205,6 +224,58 @@ func main() {
 		}
 	}
 
+	var priceConfig price.Config
+
+	if priceConfig_ == "" {
+		chainlinkEthPriceFeed := mustEthereumAddressFromEnv(EnvChainlinkEthPriceFeed)
+
+		priceConfig = price.ChainlinkConfig(priceTokenEth, chainlinkEthPriceFeed)
+	} else {
+		parsedConfig, err := price.ParseConfig(priceConfig_)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Message = "Failed to read the price config!"
+				k.Payload = err
+			})
+		}
+
+		priceConfig = *parsedConfig
+	}
+
+	priceOracle := price.NewOracle(
+		chainlinkEthPriceClient,
+		dbNetwork,
+		priceConfig,
+		cache.State{},
+		prices.History{},
+	)
+
+	// if the yield source is configured, the prize pool of the fluid
+	// token is read from wherever its underlying tokens are lent
+
+	var yieldSource yield.Source
+
+	if yieldSourceConfig_ != "" {
+		yieldSourceConfig, err := yield.ParseConfig(yieldSourceConfig_)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Message = "Failed to read the yield source config!"
+				k.Payload = err
+			})
+		}
+
+		yieldSource, err = yield.New(gethClient, contractAddress, *yieldSourceConfig)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Message = "Failed to create the yield source!"
+				k.Payload = err
+			})
+		}
+	}
+
 	// these are the fluid clients that we build off later during our
 	// lookup, they need to include the base FLUID client as well as
 	// the clients for global utility rewards
@@ -214,14 +285,12 @@ func main() {
 		globalUtilityRewards...,
 	)
 
-	queue.GetMessages(serverWorkAmqpTopic, func(message queue.Message) {
-		var hintedBlock worker.EthereumHintedBlock
-
-		message.Decode(&hintedBlock)
+	queue.GetEnvelopesContext(serverWorkAmqpTopic, worker.SchemaEthereumHintedBlock, func(ctx context.Context, decoded interface{}) {
+		hintedBlock := decoded.(worker.EthereumHintedBlock)
 
 		// set the configuration using what's in the database for the block
 
-		log.Debug(func(k *log.Log) {
+		log.DebugContext(ctx, func(k *log.Log) {
 			k.Message = "About to fetch worker config from postgres!"
 		})
 
@@ -237,18 +306,12 @@ func main() {
 			epochBlocks                  = workerConfig.EpochBlocks
 		)
 
-		var (
-			currentAtxTransactionMarginRat = new(big.Rat).SetInt64(
-				currentAtxTransactionMargin,
-			)
-
-			secondsInOneYearRat   = new(big.Rat).SetUint64(SecondsInOneYear)
-			secondsSinceLastBlock = defaultSecondsSinceLastBlock
-		)
+		secondsSinceLastBlock := defaultSecondsSinceLastBlock
 
 		var (
 			blockBaseFee      = hintedBlock.BlockBaseFee
 			blockNumber       = hintedBlock.BlockNumber
+			blockTime         = time.Unix(int64(hintedBlock.BlockTime), 0)
 			blockHash         = hintedBlock.BlockHash
 			fluidTransactions = hintedBlock.DecoratedTransactions
 
@@ -259,7 +322,10 @@ func main() {
 			transfersInBlock += len(tx.Transfers)
 		}
 
-		movingAverageKey := createMovingAverageKey(dbNetwork, tokenName)
+		// blocks that are replayed keep their own moving average so the
+		// live one isn't skewed by old transfer counts
+
+		movingAverageKey := createMovingAverageKey(ctx, dbNetwork, tokenName)
 
 		secondsSinceLastBlockRat := new(big.Rat).SetFloat64(secondsSinceLastBlock)
 
@@ -275,6 +341,8 @@ func main() {
 
 		emission.EthereumBlockNumber = blockNumber
 
+		emission.EthereumBlockHash = blockHash.String()
+
 		emission.SecondsSinceLastBlock = uint64(secondsSinceLastBlock)
 
 		// add the transaction count in the block that just came in without
@@ -353,72 +421,65 @@ func main() {
 		}
 
 		// if this block is abnormal and could be an attack, we don't use the
-		// average!
+		// average! btx should be set when the apy > averageTransfersInBlock
 
-		transfersInEpochRat := new(big.Rat).SetInt64(int64(transfersInEpoch))
-
-		epochBlocksRat := new(big.Rat).SetInt64(int64(epochBlocks))
-
-		// average transfers in block over the current epoch
-		transfersInBlockOverEpoch := new(big.Rat).Quo(
-			transfersInEpochRat,
-			epochBlocksRat,
-		)
-
-		currentAtxTransactionMarginRatCmp := new(big.Rat).Add(
-			transfersInBlockOverEpoch,
-			currentAtxTransactionMarginRat,
-		)
-
-		averageTransfersInBlockRat := new(big.Rat).SetInt64(
-			int64(averageTransfersInBlock),
+		currentAtx, btx := probability.CalculateCurrentAtx(
+			secondsSinceLastBlockRat,
+			averageTransfersInBlock,
+			transfersInEpoch,
+			epochBlocks,
+			currentAtxTransactionMargin,
 		)
 
-		// btx should be set when the apy > averageTransfersInBlock
+		// use the price as of the block, in case we're behind
 
-		var (
-			btx        int
-			currentAtx *big.Rat
+		ethPrice, err := priceOracle.GetPriceAtBlock(
+			priceTokenEth,
+			blockNumber.Uint64(),
+			blockTime,
 		)
 
-		// Sets the ATX to transfers in block if current fluid
-		// transfers in block is larger than the average, set to
-		// average otherwise example: average BTX is 20, current
-		// BTX is 30, BTX that gets passed to worker is 30 example
-		// 2: average BTX is 20, current BTX is 10, BTX that gets
-		// passed to worker is 20
+		if err != nil {
+			log.FatalContext(ctx, func(k *log.Log) {
+				k.Message = "Failed to get the price of eth!"
+				k.Payload = err
+			})
+		}
 
-		if currentAtxTransactionMarginRatCmp.Cmp(averageTransfersInBlockRat) > 0 {
+		ethPriceUsd := ethPrice.Usd
 
-			currentAtx = new(big.Rat).Mul(
-				secondsInOneYearRat,
-				transfersInBlockOverEpoch,
-			)
+		// the prize pool of the fluid token for the block, if it's
+		// looked up from the yield source
 
-			currentAtx.Quo(currentAtx, secondsSinceLastBlockRat)
+		var yieldPrizePool *big.Rat
 
-			btx = transfersInEpoch
+		if yieldSource != nil {
+			apy, err := yieldSource.Apy(emission)
 
-		} else {
+			if err != nil {
+				log.FatalContext(ctx, func(k *log.Log) {
+					k.Message = "Failed to get the apy of the yield source!"
+					k.Payload = err
+				})
+			}
 
-			currentAtx = probability.CalculateAtx(
-				secondsSinceLastBlockRat,
-				averageTransfersInBlock,
-			)
+			yieldPrizePool, err = yieldSource.PrizePool()
 
-			btx = averageTransfersInBlock * epochBlocks
-		}
+			if err != nil {
+				log.FatalContext(ctx, func(k *log.Log) {
+					k.Message = "Failed to get the prize pool from the yield source!"
+					k.Payload = err
+				})
+			}
 
-		ethPriceUsd, err := chainlink.GetPrice(
-			chainlinkEthPriceClient,
-			chainlinkEthPriceFeed,
-		)
+			emission.Payout.Apy, _ = apy.Float64()
 
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Message = "Failed to get the price of eth from chainlink!"
-				k.Payload = err
-			})
+			log.Debugf(
+				"Yield source for the block %v has apy %v and prize pool %v",
+				blockNumber,
+				apy.FloatString(6),
+				yieldPrizePool.FloatString(0),
+			)
 		}
 
 		var blockAnnouncements []worker.EthereumAnnouncement
@@ -454,7 +515,7 @@ func main() {
 				)
 
 			default:
-				log.App(func(k *log.Log) {
+				log.AppContext(ctx, func(k *log.Log) {
 					k.Format(
 						"Ignoring message with hash %#v for a unsupported fee type!",
 						transactionHash,
@@ -491,7 +552,7 @@ func main() {
 				)
 
 				if logIndex == nil {
-					log.Fatal(func(k *log.Log) {
+					log.FatalContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Log index for transaction hash %v is nil!",
 							transactionHash,
@@ -502,7 +563,7 @@ func main() {
 				// if the amount transferred was exactly 0, then we skip to the next transfer
 
 				if isDecoratedTransferZeroVolume(transfer) {
-					log.App(func(k *log.Log) {
+					log.AppContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Skipped an empty amount transferred, hash %v, log index %v",
 							transfer.TransactionHash,
@@ -524,7 +585,7 @@ func main() {
 				)
 
 				if senderAddress == recipientAddress {
-					log.App(func(k *log.Log) {
+					log.AppContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Ignoring instance of sender and receiver being the same, skipping log index %v!",
 							logIndex,
@@ -595,12 +656,20 @@ func main() {
 				)
 
 				if err != nil {
-					log.Fatal(func(k *log.Log) {
+					log.FatalContext(ctx, func(k *log.Log) {
 						k.Message = "Failed to get trf vars from chain!"
 						k.Payload = err
 					})
 				}
 
+				// use the prize pool from the yield source for the fluid token
+
+				for i, pool := range pools {
+					if yieldPrizePool != nil && pool.Name == appTypes.UtilityFluid {
+						pools[i].PoolSizeNative = yieldPrizePool
+					}
+				}
+
 				for _, pool := range pools {
 					// trigger
 					log.Debugf(
@@ -631,7 +700,7 @@ func main() {
 
 				for _, pool := range pools {
 					if pool.PoolSizeNative.Cmp(zeroRat) == 0 {
-						log.Debug(func(k *log.Log) {
+						log.DebugContext(ctx, func(k *log.Log) {
 							k.Format(
 								"Skipping empty pool %+v!",
 								pool,
@@ -695,13 +764,24 @@ func main() {
 				tokenDetails := fluidTokenDetails
 
 				// check if we've processed this before as a final failsafe before we submit
-				// the balls via a message and store an emission
+				// the balls via a message and store an emission, unless the block is
+				// being replayed, since the transfer was processed the first time
 
-				failsafe.CommitTransactionHashIndex(transactionHash, *logIndex)
+				if queue.IsReplay(ctx) {
+					log.DebugContext(ctx, func(k *log.Log) {
+						k.Format(
+							"Not committing the failsafe for the replayed transaction hash %v, log index %v",
+							transactionHash,
+							logIndex,
+						)
+					})
+				} else {
+					failsafe.CommitTransactionHashIndex(transactionHash, *logIndex)
+				}
 
 				for _, payoutDetails := range payouts {
 
-					log.Debug(func(k *log.Log) {
+					log.DebugContext(ctx, func(k *log.Log) {
 						k.Format(
 							"Transaction with hash %v, log index %v had application %v",
 							transactionHash,
@@ -713,6 +793,7 @@ func main() {
 					announcement := worker.EthereumAnnouncement{
 						TransactionHash: transactionHash,
 						BlockNumber:     &blockNumber,
+						BlockHash:       blockHash,
 						LogIndex:        logIndex,
 						FromAddress:     senderAddress,
 						ToAddress:       recipientAddress,
@@ -728,11 +809,11 @@ func main() {
 					_ = probability.NaiveIsWinning(announcement.RandomSource, emission)
 
 					if payoutDetails.customPayoutType == "" {
-						log.Debug(func(k *log.Log) {
+						log.DebugContext(ctx, func(k *log.Log) {
 							k.Format("Source payouts for normal payout: %v", payoutDetails.randomSource)
 						})
 					} else {
-						log.Debug(func(k *log.Log) {
+						log.DebugContext(ctx, func(k *log.Log) {
 							k.Format(
 								"Source payouts for special payout type %s: %v",
 								payoutDetails.customPayoutType,
@@ -749,11 +830,11 @@ func main() {
 
 					blockAnnouncements = append(blockAnnouncements, announcement)
 
-					sendEmission(emission)
+					sendEmission(ctx, emission)
 				}
 			}
 		}
 
-		queue.SendMessage(publishAmqpQueueName, blockAnnouncements)
+		queue.SendEnvelopeContext(ctx, publishAmqpQueueName, worker.SchemaEthereumAnnouncements, blockAnnouncements)
 	})
 }
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -12,7 +13,8 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/state"
 )
 
-func stateGetSetRat(key string, value *big.Rat) (retrieved *big.Rat, err error) {
+func stateGetSetRat(ctx context.Context, key string, value *big.Rat) (retrieved *big.Rat, err error) {
+	key = replayStateKey(ctx, key)
 
 	bytes := state.GetSet(key, value.String())
 
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
@@ -48,9 +49,10 @@ func bigFloatFromInt(x *big.Int) *big.Float {
 	return float.SetInt(x)
 }
 
-// getLastBlockTime by asking Redis for it
-func getLastBlockTimestamp() int64 {
-	blockTime_ := state.Get("block.time")
+// getLastBlockTime by asking Redis for it, or for the time of the last
+// block replayed if the block is being replayed
+func getLastBlockTimestamp(ctx context.Context) int64 {
+	blockTime_ := state.Get(replayStateKey(ctx, "block.time"))
 
 	if len(blockTime_) == 0 {
 		return 0
@@ -174,10 +176,10 @@ func mustEthereumAddressFromEnv(env string) ethCommon.Address {
 	return ethCommon.HexToAddress(addressString)
 }
 
-func sendEmission(emission *worker.Emission) {
+func sendEmission(ctx context.Context, emission *worker.Emission) {
 	emission.Update()
 
-	queue.SendMessage(worker.TopicEmissions, emission)
+	queue.SendEnvelopeContext(ctx, worker.TopicEmissions, worker.SchemaEmission, emission)
 
 	log.Debugf("Emission: %s", emission)
 }
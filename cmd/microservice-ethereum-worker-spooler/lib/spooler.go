@@ -0,0 +1,221 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package microservice_ethereum_worker_spooler
+
+// the spooler's message handling lives here so the pipeline tests can
+// run it in the same process as the in-memory broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/outbox"
+	workerDb "github.com/fluidity-money/fluidity-app/lib/databases/postgres/worker"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/amm"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/winners"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+	winnersQueue "github.com/fluidity-money/fluidity-app/lib/queues/winners"
+	workerQueue "github.com/fluidity-money/fluidity-app/lib/queues/worker"
+	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	commonApps "github.com/fluidity-money/fluidity-app/common/ethereum/applications"
+	ethereumSpooler "github.com/fluidity-money/fluidity-app/common/ethereum/spooler"
+	commonSpooler "github.com/fluidity-money/fluidity-app/common/spooler"
+)
+
+// Config of the worker spooler
+type Config struct {
+	Network network.BlockchainNetwork
+
+	// RewardsQueue to receive winner announcements from
+	RewardsQueue string
+
+	// BatchedRewardsQueue to send batches of rewards to the
+	// worker-sender down
+	BatchedRewardsQueue string
+
+	// TokenDetails of the token each utility pays out in
+	TokenDetails map[applications.UtilityName]token_details.TokenDetails
+
+	// OutboxEnabled to write the pending winners message to the outbox
+	// in the same transaction as the pending winners
+	OutboxEnabled bool
+}
+
+// Spool the winner announcements received, writing the pending winners
+// and paying out every token that passes the thresholds. Replayed blocks
+// were already paid out the first time they were seen, so nothing is
+// written as pending or sent for them. Blocks forever
+func Spool(config Config) {
+	backend := ethereumSpooler.BatchBackend{
+		QueueName: config.BatchedRewardsQueue,
+		Send:      queue.SendMessage,
+	}
+
+	// the pending winners are written before they're released, so
+	// the spooler doesn't support dry runs
+
+	ethSpooler := commonSpooler.New(
+		config.Network,
+		spooler.Store{},
+		backend,
+		commonSpooler.Config{DryRun: false},
+	)
+
+	queue.GetEnvelopesContext(config.RewardsQueue, workerQueue.SchemaEthereumWinnerAnnouncements, queue.SkipReplayedEnvelopes(func(ctx context.Context, decoded interface{}) {
+		announcements := decoded.([]worker.EthereumWinnerAnnouncement)
+
+		if err := spoolAnnouncements(ctx, config, ethSpooler, announcements); err != nil {
+			log.FatalContext(ctx, func(k *log.Log) {
+				k.Message = "Failed to pay out spooled winnings!"
+				k.Payload = err
+			})
+		}
+	}))
+}
+
+func spoolAnnouncements(ctx context.Context, config Config, ethSpooler *commonSpooler.Spooler, announcements []worker.EthereumWinnerAnnouncement) error {
+	var (
+		dbNetwork     = config.Network
+		tokenDetails  = config.TokenDetails
+		outboxEnabled = config.OutboxEnabled
+
+		pendingWinners []spooler.PendingWinner
+	)
+
+	workerConfig := workerDb.GetWorkerConfigEthereum(dbNetwork)
+
+	thresholds := commonSpooler.Thresholds{
+		Instant: workerConfig.SpoolerInstantRewardThreshold,
+		Batched: workerConfig.SpoolerBatchedRewardThreshold,
+	}
+
+	wins := make([]commonSpooler.Win, 0, len(announcements))
+
+	for _, announcement := range announcements {
+		pendingWinners_ := spooler.CreatePendingWinners(announcement, tokenDetails)
+
+		// store pending winners from all announcements to send to the queue later
+		pendingWinners = append(pendingWinners, pendingWinners_...)
+
+		// write the winner into the database, with the pending
+		// winners message if the outbox is enabled
+
+		if outboxEnabled {
+			insertPendingWinnersWithOutbox(ctx, pendingWinners_, pendingWinners)
+		} else {
+			spooler.InsertPendingWinners(pendingWinners_)
+		}
+
+		// if the win was an AMM win, add the LP winnings
+		if announcement.Application == commonApps.ApplicationSeawaterAmm && announcement.Decorator != nil {
+			amm.InsertAmmWinnings(announcement, tokenDetails)
+		}
+
+		var (
+			// the sender's winnings will always be higher than the recipient's
+			fromWinAmount     = announcement.FromWinAmount
+			fluidTokenDetails = announcement.TokenDetails
+			blockNumberInt    = announcement.BlockNumber
+			blockHash         = announcement.BlockHash
+			transactionHash   = announcement.TransactionHash
+			senderAddress     = announcement.FromAddress
+			recipientAddress  = announcement.ToAddress
+			toWinAmount       = announcement.ToWinAmount
+			application       = announcement.Application
+			rewardTier        = announcement.RewardTier
+			logIndex          = announcement.LogIndex
+
+			blockNumber = uint64(blockNumberInt.Int64())
+		)
+
+		log.DebugContext(ctx, func(k *log.Log) {
+			k.Format(
+				"Inserting pending reward type for transaction with hash %v and application %v",
+				transactionHash,
+				application.String(),
+			)
+		})
+
+		// write the sender and receiver to be stored once the win is paid out
+		winners.InsertPendingRewardType(
+			dbNetwork,
+			fluidTokenDetails,
+			blockNumber,
+			blockHash,
+			transactionHash,
+			senderAddress,
+			fromWinAmount,
+			recipientAddress,
+			toWinAmount,
+			application,
+			rewardTier,
+			*logIndex,
+			tokenDetails,
+		)
+
+		var totalWinAmount float64
+
+		// from will always be greater than to
+		for _, payout := range fromWinAmount {
+			totalWinAmount += payout.Usd
+		}
+
+		wins = append(wins, commonSpooler.Win{
+			Token:     fluidTokenDetails,
+			UsdAmount: totalWinAmount,
+		})
+
+		if !outboxEnabled {
+			queue.SendEnvelopeContext(ctx, winnersQueue.TopicPendingWinners, winnersQueue.SchemaPendingWinners, pendingWinners)
+		}
+	}
+
+	if err := ethSpooler.Spool(wins, thresholds); err != nil {
+		return fmt.Errorf(
+			"failed to spool %v announcements! %w",
+			len(announcements),
+			err,
+		)
+	}
+
+	return nil
+}
+
+// insertPendingWinners and the pending winners message in one
+// transaction, for the outbox relay to send once it commits
+func insertPendingWinnersWithOutbox(ctx context.Context, pendingWinners, announcedWinners []spooler.PendingWinner) {
+	transaction, err := timescale.Client().Begin()
+
+	if err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Message = "Failed to begin a transaction to insert pending winners!"
+			k.Payload = err
+		})
+	}
+
+	spooler.InsertPendingWinnersTx(transaction, pendingWinners)
+
+	outbox.InsertEnvelope(
+		ctx,
+		transaction,
+		winnersQueue.TopicPendingWinners,
+		winnersQueue.SchemaPendingWinners,
+		announcedWinners,
+	)
+
+	if err := transaction.Commit(); err != nil {
+		log.FatalContext(ctx, func(k *log.Log) {
+			k.Message = "Failed to commit pending winners and their outbox message!"
+			k.Payload = err
+		})
+	}
+}
@@ -5,24 +5,24 @@
 package main
 
 import (
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
-	workerDb "github.com/fluidity-money/fluidity-app/lib/databases/postgres/worker"
-	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/amm"
+	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/outbox"
 	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
 	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/winners"
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
-	"github.com/fluidity-money/fluidity-app/lib/queue"
-	winnersQueue "github.com/fluidity-money/fluidity-app/lib/queues/winners"
+	ethQueue "github.com/fluidity-money/fluidity-app/lib/queues/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/timescale"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
-	"github.com/fluidity-money/fluidity-app/lib/types/worker"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 
-	commonApps "github.com/fluidity-money/fluidity-app/common/ethereum/applications"
-	commonSpooler "github.com/fluidity-money/fluidity-app/common/ethereum/spooler"
+	lib "github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-worker-spooler/lib"
 )
 
 const (
@@ -37,9 +37,23 @@ const (
 
 	// EnvNetwork to differentiate between eth, arbitrum, etc
 	EnvNetwork = `FLU_ETHEREUM_NETWORK`
+
+	// EnvOutboxEnabled to write pending winner messages to the outbox in
+	// the same transaction as the pending winners if set to "true"
+	EnvOutboxEnabled = `FLU_ETHEREUM_SPOOLER_OUTBOX_ENABLED`
+)
+
+const (
+	// outboxBatchSize to relay outbox messages in
+	outboxBatchSize = 100
+
+	// outboxRelayInterval to wait for when the outbox is empty
+	outboxRelayInterval = time.Second
 )
 
 func main() {
+	lifecycle.Start()
+
 	var (
 		rewardsQueue        = util.GetEnvOrFatal(EnvRewardsAmqpQueueName)
 		batchedRewardsQueue = util.GetEnvOrFatal(EnvPublishAmqpQueueName)
@@ -47,6 +61,8 @@ func main() {
 
 		tokenDetails  = make(map[applications.UtilityName]token_details.TokenDetails)
 		tokenDetails_ = util.GetEnvOrFatal(EnvTokenDetails)
+
+		outboxEnabled = os.Getenv(EnvOutboxEnabled) == "true"
 	)
 
 	dbNetwork, err := network.ParseEthereumNetwork(network_)
@@ -85,150 +101,38 @@ func main() {
 		tokenDetails[utility] = token_details.New(shortName, int(decimals))
 	}
 
-	queue.GetMessages(rewardsQueue, func(message queue.Message) {
-		var (
-			announcements  []worker.EthereumWinnerAnnouncement
-			pendingWinners []spooler.PendingWinner
-		)
+	if outboxEnabled {
+		go outbox.Relay(timescale.Client(), outboxBatchSize, outboxRelayInterval)
+	}
 
-		message.Decode(&announcements)
+	// roll back pending winners for transactions that were orphaned,
+	// so they aren't paid out in the next batch
 
-		var (
-			workerConfig = workerDb.GetWorkerConfigEthereum(dbNetwork)
+	go ethQueue.ChainReorgs(func(reorg ethQueue.ChainReorg) {
+		if reorg.Network != dbNetwork {
+			return
+		}
 
-			instantRewardThreshold = workerConfig.SpoolerInstantRewardThreshold
-			batchedRewardThreshold = workerConfig.SpoolerBatchedRewardThreshold
+		var (
+			removedWinners     = spooler.RollbackPendingWinners(reorg)
+			removedRewardTypes = winners.RollbackPendingRewardTypes(reorg)
 		)
 
-		toSend := make(map[token_details.TokenDetails]bool)
-
-		for _, announcement := range announcements {
-			// write the winner into the database
-			pendingWinners_ := spooler.CreatePendingWinners(announcement, tokenDetails)
-			spooler.InsertPendingWinners(pendingWinners_)
-
-			// store pending winners from all announcements to send to the queue later
-			pendingWinners = append(pendingWinners, pendingWinners_...)
-
-			// if the win was an AMM win, add the LP winnings
-			if announcement.Application == commonApps.ApplicationSeawaterAmm && announcement.Decorator != nil {
-				amm.InsertAmmWinnings(announcement, tokenDetails)
-			}
-
-			var (
-				// the sender's winnings will always be higher than the recipient's
-				fromWinAmount     = announcement.FromWinAmount
-				fluidTokenDetails = announcement.TokenDetails
-				blockNumberInt    = announcement.BlockNumber
-				transactionHash   = announcement.TransactionHash
-				senderAddress     = announcement.FromAddress
-				recipientAddress  = announcement.ToAddress
-				toWinAmount       = announcement.ToWinAmount
-				application       = announcement.Application
-				rewardTier        = announcement.RewardTier
-				logIndex          = announcement.LogIndex
-
-				blockNumber = uint64(blockNumberInt.Int64())
-			)
-
-			log.Debug(func(k *log.Log) {
-				k.Format(
-					"Inserting pending reward type for transaction with hash %v and application %v",
-					transactionHash,
-					application.String(),
-				)
-			})
-
-			// write the sender and receiver to be stored once the win is paid out
-			winners.InsertPendingRewardType(
-				dbNetwork,
-				fluidTokenDetails,
-				blockNumber,
-				transactionHash,
-				senderAddress,
-				fromWinAmount,
-				recipientAddress,
-				toWinAmount,
-				application,
-				rewardTier,
-				*logIndex,
-				tokenDetails,
+		log.App(func(k *log.Log) {
+			k.Format(
+				"Removed %v pending winners and %v pending reward types in %v orphaned blocks!",
+				removedWinners,
+				removedRewardTypes,
+				len(reorg.OrphanedBlocks),
 			)
+		})
+	})
 
-			var totalWinAmount float64
-
-			// from will always be greater than to
-			for _, payout := range fromWinAmount {
-				totalWinAmount += payout.Usd
-			}
-
-			log.Debug(func(k *log.Log) {
-				k.Format(
-					"Reward value is $%f, instant send threshhold is $%f.",
-					totalWinAmount,
-					instantRewardThreshold,
-				)
-			})
-
-			totalRewards := spooler.UnpaidWinningsForCategory(dbNetwork, fluidTokenDetails)
-
-			log.Debug(func(k *log.Log) {
-				k.Format(
-					"Total pending rewards are $%f, threshhold is $%f.",
-					totalRewards,
-					batchedRewardThreshold,
-				)
-			})
-
-			if totalWinAmount > instantRewardThreshold {
-				log.Debug(func(k *log.Log) {
-					k.Message = "Transaction won more than instant send threshold, sending instantly!"
-				})
-
-				toSend[fluidTokenDetails] = true
-			} else if totalRewards > batchedRewardThreshold {
-				log.Debug(func(k *log.Log) {
-					k.Message = "Total pending rewards are greater than threshold, sending!"
-				})
-
-				toSend[fluidTokenDetails] = true
-			}
-
-			queue.SendMessage(winnersQueue.TopicPendingWinners, pendingWinners)
-		}
-
-		for token, send := range toSend {
-			if !send {
-				// should never happen
-				continue
-			}
-
-			log.Debug(func(k *log.Log) {
-				k.Format("Sending rewards for token %v", token)
-			})
-
-			rewards, found, err := commonSpooler.GetRewards(dbNetwork, token)
-
-			if err != nil {
-				log.Fatal(func(k *log.Log) {
-					k.Format(
-						"Failed to get rewards for token %s! %+v",
-						token.TokenShortName,
-						err,
-					)
-				})
-			}
-
-			if !found {
-				log.Fatal(func(k *log.Log) {
-					k.Format(
-						"Trying to send rewards for token %s but no rewards found!",
-						token.TokenShortName,
-					)
-				})
-			}
-
-			queue.SendMessage(batchedRewardsQueue, rewards)
-		}
+	lib.Spool(lib.Config{
+		Network:             dbNetwork,
+		RewardsQueue:        rewardsQueue,
+		BatchedRewardsQueue: batchedRewardsQueue,
+		TokenDetails:        tokenDetails,
+		OutboxEnabled:       outboxEnabled,
 	})
 }
@@ -1,12 +1,28 @@
 package main
 
 import (
+	"context"
 	"os"
 
+	"github.com/fluidity-money/fluidity-app/common/ethereum/txmanager"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/util"
 
+	goEthereum "github.com/ethereum/go-ethereum"
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	// EnvEthereumHttpUrl to simulate the call with in a dry run
+	EnvEthereumHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+	// EnvWorkerConfigAddress to simulate the call on in a dry run
+	EnvWorkerConfigAddress = `FLU_ETHEREUM_WORKER_CONFIG_ADDR`
+
+	// EnvOperatorAddress to simulate the call from in a dry run
+	EnvOperatorAddress = `FLU_ETHEREUM_OPERATOR_ADDR`
 )
 
 type OracleUpdate struct {
@@ -48,4 +64,58 @@ func main() {
 	log.App(func(k *log.Log) {
 		k.Format("Call the workerConfig contract with data %s", hexutil.Encode(updateCall))
 	})
+
+	if util.DryRunEnabled() {
+		simulateUpdate(updateCall)
+	}
+}
+
+// simulateUpdate to check the call would succeed if sent by the operator
+func simulateUpdate(updateCall []byte) {
+	var (
+		gethHttpUrl          = util.GetEnvOrFatal(EnvEthereumHttpUrl)
+		workerConfigAddress_ = util.GetEnvOrFatal(EnvWorkerConfigAddress)
+		operatorAddress_     = util.GetEnvOrFatal(EnvOperatorAddress)
+	)
+
+	var (
+		workerConfigAddress = ethCommon.HexToAddress(workerConfigAddress_)
+		operatorAddress     = ethCommon.HexToAddress(operatorAddress_)
+	)
+
+	gethClient, err := ethclient.Dial(gethHttpUrl)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to connect to geth to simulate the call!"
+			k.Payload = err
+		})
+	}
+
+	defer gethClient.Close()
+
+	gasEstimate, err := txmanager.SimulateCall(
+		context.Background(),
+		gethClient,
+		goEthereum.CallMsg{
+			From: operatorAddress,
+			To:   &workerConfigAddress,
+			Data: updateCall,
+		},
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Simulating the updateOracles call failed!"
+			k.Payload = err
+		})
+	}
+
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Simulated the updateOracles call from %v, gas estimate %v!",
+			operatorAddress,
+			gasEstimate,
+		)
+	})
 }
@@ -81,7 +81,7 @@ func main() {
 				Epoch:           epoch,
 			}
 
-			queue.SendMessage(lootboxes_queue.TopicLootboxes, referralLootbox)
+			queue.SendEnvelope(lootboxes_queue.TopicLootboxes, lootboxes_queue.SchemaLootbox, referralLootbox)
 		}
 	})
 }
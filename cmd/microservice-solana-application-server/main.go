@@ -122,8 +122,9 @@ func main() {
 				Transfers: transfers,
 			}
 
-			queue.SendMessage(
+			queue.SendEnvelope(
 				worker.TopicSolanaBufferedTransfers,
+				worker.SchemaSolanaBufferedTransfers,
 				bufferedTransfers,
 			)
 		}
@@ -51,6 +51,8 @@ func main() {
 		tokenDetails = make(tokenMap)
 
 		testingEnabled = os.Getenv(EnvSolanaDebugFakePayouts) == "true"
+
+		dryRun = util.DryRunEnabled()
 	)
 
 	solanaClient, err := rpc.New(solanaRpcUrl)
@@ -156,6 +158,21 @@ func main() {
 			mintAddress      = token.mintPubkey
 		)
 
+		if dryRun {
+			simulateTransfer(
+				solanaClient,
+				senderPdaAddress,
+				recipientAddress,
+				mintAddress,
+				amountInt64,
+				blockHash,
+				senderAddress,
+				senderPrivateKey,
+			)
+
+			return
+		}
+
 		signature, err := spl_token.SendTransfer(
 			solanaClient,
 			senderPdaAddress,
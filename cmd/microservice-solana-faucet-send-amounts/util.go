@@ -5,11 +5,17 @@
 package main
 
 import (
+	"encoding/base64"
 	"strings"
+	"time"
 
 	"github.com/fluidity-money/fluidity-app/common/solana"
+	"github.com/fluidity-money/fluidity-app/common/solana/rpc"
+	"github.com/fluidity-money/fluidity-app/common/solana/spl-token"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/queues/dry-run"
 	faucetTypes "github.com/fluidity-money/fluidity-app/lib/types/faucet"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
 )
 
 // addAccountDetails to parse the env PDAs and private keys and add them to the map
@@ -83,3 +89,70 @@ func (tokenDetails tokenMap) addAccountDetails(accountDetailsList_ string) {
 		tokenDetails[tokenName] = details
 	}
 }
+
+// simulateTransfer to sign the transfer that would be sent and simulate
+// it, publishing the result instead of sending it
+func simulateTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress, mintAddress solana.PublicKey, amount uint64, blockHash solana.Hash, senderAddress solana.PublicKey, senderPrivateKey solana.PrivateKey) {
+	transaction, err := spl_token.MakeTransfer(
+		solanaClient,
+		senderPdaAddress,
+		recipientAddress,
+		mintAddress,
+		amount,
+		blockHash,
+		senderAddress,
+		senderPrivateKey,
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to make a transfer to simulate!"
+			k.Payload = err
+		})
+	}
+
+	transactionBinary, err := transaction.MarshalBinary()
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to encode a transfer to simulate!"
+			k.Payload = err
+		})
+	}
+
+	result := dry_run.Result{
+		Network:     network.NetworkSolana,
+		Description: "faucet transfer",
+		Sender:      senderAddress.ToBase58(),
+		Recipient:   recipientAddress.ToBase58(),
+		Transaction: base64.StdEncoding.EncodeToString(transactionBinary),
+		Time:        time.Now(),
+	}
+
+	if len(transaction.Signatures) > 0 {
+		result.TransactionHash = transaction.Signatures[0].String()
+	}
+
+	// the transaction is signed with a real block hash, so the
+	// signatures can be verified
+
+	value, err := solanaClient.SimulateTransaction(
+		transactionBinary, // transaction
+		true,              // sigVerify
+		"finalized",       // commitment
+		false,             // replaceRecentBlockhash
+	)
+
+	if value != nil {
+		result.GasEstimate = value.UnitsConsumed
+		result.Logs = value.Logs
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Success = true
+	}
+
+	dry_run.Publish(result)
+}
@@ -5,16 +5,18 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
-	"os"
 	"strconv"
 
 	"github.com/fluidity-money/fluidity-app/common/solana"
 	"github.com/fluidity-money/fluidity-app/common/solana/payout"
 	"github.com/fluidity-money/fluidity-app/common/solana/rpc"
 	"github.com/fluidity-money/fluidity-app/common/solana/spl-token"
+	commonSpooler "github.com/fluidity-money/fluidity-app/common/spooler"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 )
 
@@ -45,17 +47,15 @@ const (
 	// EnvTokenName is the same of the token being wrapped
 	EnvTokenName = `FLU_SOLANA_TOKEN_NAME`
 
-	// EnvPubkeySender to receive the sender's prize
-	EnvPubkeySender = `FLU_SOLANA_PUBKEY_SENDER`
+	// EnvTokenDecimals is the number of decimals the token uses
+	EnvTokenDecimals = `FLU_SOLANA_TOKEN_DECIMALS`
 
-	// EnvPubkeyReceiver to receive the receiver's prize
-	EnvPubkeyReceiver = `FLU_SOLANA_PUBKEY_RECEIVER`
+	// EnvPayerPrikey is the private key of the payout authority
+	EnvPayerPrikey = `FLU_SOLANA_PAYER_PRIKEY`
 
-	// EnvPayerPubkey to use as the payer (would be the multisig)
-	EnvPayerPubkey = `FLU_SOLANA_PAYER_PUBKEY`
-
-	// EnvWinningAmount to pay out during the reward process
-	EnvWinningAmount = `FLU_SOLANA_WINNING_AMOUNT`
+	// EnvBatchedRewardThreshold in USD that the unpaid winnings for the
+	// token need to be worth more than to be released, 0 if not set
+	EnvBatchedRewardThreshold = `FLU_SOLANA_SPOOLER_BATCHED_REWARD_THRESHOLD`
 )
 
 func main() {
@@ -68,19 +68,44 @@ func main() {
 		fluidMintPubkey  = pubkeyFromEnv(EnvFluidityMintPubkey)
 		obligationPubkey = pubkeyFromEnv(EnvObligationPubkey)
 		reservePubkey    = pubkeyFromEnv(EnvReservePubkey)
-		aPubkey          = pubkeyFromEnv(EnvPubkeySender)
-		bPubkey          = pubkeyFromEnv(EnvPubkeyReceiver)
-		payerPubkey      = pubkeyFromEnv(EnvPayerPubkey)
 
-		winningAmount_ = util.GetEnvOrFatal(EnvWinningAmount)
-		tokenName      = util.GetEnvOrFatal(EnvTokenName)
+		payerPrikey       = util.GetEnvOrFatal(EnvPayerPrikey)
+		tokenName         = util.GetEnvOrFatal(EnvTokenName)
+		decimalPlaces_    = util.GetEnvOrFatal(EnvTokenDecimals)
+		batchedThreshold_ = util.GetEnvOrDefault(EnvBatchedRewardThreshold, "0")
 	)
 
-	winningAmount, err := strconv.ParseUint(winningAmount_, 10, 64)
+	decimalPlaces, err := strconv.Atoi(decimalPlaces_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Format(
+				"Failed to convert the decimals from %#v!",
+				decimalPlaces_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	batchedThreshold, err := strconv.ParseFloat(batchedThreshold_, 64)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to parse the winning amount to a uint64!"
+			k.Format(
+				"Failed to parse the batched reward threshold %#v!",
+				batchedThreshold_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	payer, err := solana.WalletFromPrivateKeyBase58(payerPrikey)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Message = "Failed to decode the payer's private key!"
 			k.Payload = err
 		})
 	}
@@ -112,57 +137,51 @@ func main() {
 		})
 	}
 
-	recentBlockHash, err := client.GetRecentBlockhash("finalized")
-
-	if err != nil {
-		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to get the recent block hash!"
-			k.Payload = err
-		})
-	}
-
-	payoutArgs := payout.PayoutArgs{
-		DataAccountPubkey: fluidDataPubkey,
-		MetaSplPubkey:     spl_token.TokenProgramAddressPubkey,
-		TokenMintPubkey:   tokenMintPubkey,
-		FluidMintPubkey:   fluidMintPubkey,
-		PdaPubkey:         pdaPubkey,
-		ObligationPubkey:  obligationPubkey,
-		ReservePubkey:     reservePubkey,
-		AccountAPubkey:    aPubkey,
-		AccountBPubkey:    bPubkey,
-		PayerPubkey:       payerPubkey,
-		WinningAmount:     winningAmount,
-		TokenName:         tokenName,
-		BumpSeed:          bumpSeed,
-		RecentBlockHash:   recentBlockHash,
+	// the winners and amounts are set by the backend for each payout
+
+	backend := payout.Backend{
+		Client: client,
+		Args: payout.PayoutArgs{
+			FluidityProgramPubkey: fluidityPubkey,
+			DataAccountPubkey:     fluidDataPubkey,
+			MetaSplPubkey:         spl_token.TokenProgramAddressPubkey,
+			TokenMintPubkey:       tokenMintPubkey,
+			FluidMintPubkey:       fluidMintPubkey,
+			PdaPubkey:             pdaPubkey,
+			ObligationPubkey:      obligationPubkey,
+			ReservePubkey:         reservePubkey,
+			PayerPubkey:           payer.PublicKey(),
+			TokenName:             tokenName,
+			BumpSeed:              bumpSeed,
+		},
+		PayerPrivateKey: payer.PrivateKey,
 	}
 
-	payoutTransaction, _, err := payout.CreatePayoutTransaction(payoutArgs)
+	solanaSpooler := commonSpooler.New(
+		network.NetworkSolana,
+		spooler.Store{},
+		backend,
+		commonSpooler.DefaultConfig(),
+	)
 
-	if err != nil {
-		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to create a payout transaction!"
-			k.Payload = err
-		})
-	}
+	tokenDetails := token_details.New(tokenName, decimalPlaces)
 
-	payoutTransactionBytes, err := payoutTransaction.MarshalBinary()
+	// release every pending winner once their total passes the batched
+	// threshold, there's no single win to check against the instant one
 
-	if err != nil {
-		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to marshal the payout transaction as bytes!"
-			k.Payload = err
-		})
+	thresholds := commonSpooler.Thresholds{
+		Instant: batchedThreshold,
+		Batched: batchedThreshold,
 	}
 
-	encoder := base64.NewEncoder(base64.StdEncoding, os.Stdout)
+	wins := []commonSpooler.Win{{
+		Token:     tokenDetails,
+		UsdAmount: 0,
+	}}
 
-	_, err = encoder.Write(payoutTransactionBytes)
-
-	if err != nil {
+	if err := solanaSpooler.Spool(wins, thresholds); err != nil {
 		log.Fatal(func(k *log.Log) {
-			k.Message = "Failed to write the payout instruction to stdout via base64!"
+			k.Message = "Failed to release the pending winners!"
 			k.Payload = err
 		})
 	}
@@ -42,17 +42,14 @@ type (
 		Error  *solana.RpcError `json:"error"`
 	}
 
-	// rpc return type for the getTransaction endpoint using jsonParsed encoding
-	rpcParsedJsonTransaction struct {
+	// rpc return type for the getTransaction endpoint, only decoding
+	// the addresses loaded from lookup tables
+	rpcTransactionLoadedAddresses struct {
 		Error  *solana.RpcError `json:"error"`
-		Result struct {
-			Transaction struct {
-				Message struct {
-					AccountKeys []struct {
-						Pubkey string `json:"pubkey"`
-					} `json:"accountKeys"`
-				} `json:"message"`
-			} `json:"transaction"`
+		Result *struct {
+			Meta struct {
+				LoadedAddresses *solana.TransactionLoadedAddresses `json:"loadedAddresses"`
+			} `json:"meta"`
 		} `json:"result"`
 	}
 )
@@ -138,7 +135,7 @@ func GetBlock(rpcUrl string, slot uint64, retries, delay int) (*solana.Block, er
 			)
 		}
 
-		if err := fetchSolanaAccountList(rpcUrl, &blockRes.Result); err != nil {
+		if err := resolveLoadedAddresses(rpcUrl, &blockRes.Result); err != nil {
 			return nil, err
 		}
 
@@ -146,7 +143,13 @@ func GetBlock(rpcUrl string, slot uint64, retries, delay int) (*solana.Block, er
 	}
 }
 
-func fetchSolanaAccountList(rpcUrl string, block *solana.Block) error {
+// resolveLoadedAddresses of the v0 transactions in the block that use
+// address lookup tables, so the accounts their instructions index past
+// the account keys in the message can be found. The RPC normally includes
+// them with the block, otherwise they're fetched with getTransaction.
+// The lookup tables aren't read, since they could have changed since the
+// transaction was made
+func resolveLoadedAddresses(rpcUrl string, block *solana.Block) error {
 	for i, txn := range block.Transactions {
 		var (
 			versionLegacyUnset = bytes.Equal(txn.Version, TransactionVersionLegacyUnset)
@@ -161,81 +164,97 @@ func fetchSolanaAccountList(rpcUrl string, block *solana.Block) error {
 
 		lookups := txn.Transaction.Message.AddressTableLookups
 
-		if len(lookups) == 0 {
-			// transaction doesn't use lookups - don't need to do anything
+		// transaction doesn't use lookups, or the rpc resolved them
+
+		if len(lookups) == 0 || txn.Meta.LoadedAddresses != nil {
 			continue
 		}
 
-		// transaction uses account lookups - fetch the parsed json format
-		var (
-			txnSignature = txn.Transaction.Signatures[0]
-
-			request = map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      1,
-				"method":  "getTransaction",
-				"params": []interface{}{
-					txnSignature,
-					map[string]interface{}{
-						"encoding":                       "jsonParsed",
-						"commitment":                     "confirmed",
-						"maxSupportedTransactionVersion": 0,
-					},
-				},
-			}
-		)
+		if len(txn.Transaction.Signatures) == 0 {
+			return fmt.Errorf(
+				"transaction %v in the block uses lookup tables but has no signature to fetch it with",
+				i,
+			)
+		}
 
-		requestBuf := new(bytes.Buffer)
-		encoder := json.NewEncoder(requestBuf)
+		txnSignature := txn.Transaction.Signatures[0]
 
-		err := encoder.Encode(request)
+		loadedAddresses, err := getLoadedAddresses(rpcUrl, txnSignature)
 
 		if err != nil {
-			return fmt.Errorf("Failed to encode RPC call: %w", err)
+			return fmt.Errorf(
+				"failed to get the loaded addresses of transaction %v: %w",
+				txnSignature,
+				err,
+			)
 		}
 
-		r, err := http.Post(
-			rpcUrl,
-			"application/json",
-			requestBuf,
-		)
+		block.Transactions[i].Meta.LoadedAddresses = loadedAddresses
+	}
 
-		if err != nil {
-			return fmt.Errorf("Failed to make an RPC call: %w", err)
-		}
+	return nil
+}
 
-		defer r.Body.Close()
+// getLoadedAddresses of a v0 transaction with getTransaction, returning
+// an error if the rpc didn't send them
+func getLoadedAddresses(rpcUrl, txnSignature string) (*solana.TransactionLoadedAddresses, error) {
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "getTransaction",
+		"params": []interface{}{
+			txnSignature,
+			map[string]interface{}{
+				"encoding":                       "json",
+				"commitment":                     "confirmed",
+				"maxSupportedTransactionVersion": 0,
+			},
+		},
+	}
 
-		reader := json.NewDecoder(r.Body)
+	requestBuf := new(bytes.Buffer)
+	encoder := json.NewEncoder(requestBuf)
 
-		var transactionRes rpcParsedJsonTransaction
+	if err := encoder.Encode(request); err != nil {
+		return nil, fmt.Errorf("Failed to encode RPC call: %w", err)
+	}
 
-		if err := reader.Decode(&transactionRes); err != nil {
-			return fmt.Errorf("Failed decoding RPC response: %w", err)
-		}
+	r, err := http.Post(
+		rpcUrl,
+		"application/json",
+		requestBuf,
+	)
 
-		if transactionRes.Error != nil {
-			return fmt.Errorf(
-				"Error getting a transaction from solana! %+v",
-				transactionRes.Error,
-			)
-		}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to make an RPC call: %w", err)
+	}
 
-		var (
-			accounts    = transactionRes.Result.Transaction.Message.AccountKeys
-			accountKeys = make([]string, len(accounts))
-		)
+	defer r.Body.Close()
 
-		for i, acc := range accounts {
-			accountKeys[i] = acc.Pubkey
-		}
+	reader := json.NewDecoder(r.Body)
 
-		newTxn := txn
+	var transactionRes rpcTransactionLoadedAddresses
 
-		newTxn.Transaction.Message.AccountKeys = accountKeys
+	if err := reader.Decode(&transactionRes); err != nil {
+		return nil, fmt.Errorf("Failed decoding RPC response: %w", err)
+	}
 
-		block.Transactions[i] = newTxn
+	if transactionRes.Error != nil {
+		return nil, fmt.Errorf(
+			"Error getting a transaction from solana! %+v",
+			transactionRes.Error,
+		)
 	}
 
-	return nil
+	if transactionRes.Result == nil {
+		return nil, fmt.Errorf("transaction wasn't found")
+	}
+
+	loadedAddresses := transactionRes.Result.Meta.LoadedAddresses
+
+	if loadedAddresses == nil {
+		return nil, fmt.Errorf("transaction uses lookup tables but the rpc didn't send its loaded addresses")
+	}
+
+	return loadedAddresses, nil
 }
@@ -0,0 +1,61 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package solana
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/solana"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func blockWithLookup() solana.Block {
+	var txn solana.TransactionResult
+
+	txn.Version = TransactionVersion0
+	txn.Transaction.Signatures = []string{"signature"}
+
+	txn.Transaction.Message.AddressTableLookups = []solana.TransactionAddressTableLookups{{
+		AccountKey:      "table",
+		WritableIndexes: []int{0},
+	}}
+
+	return solana.Block{Transactions: []solana.TransactionResult{txn}}
+}
+
+func transactionServer(t *testing.T, response string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(response))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server.URL
+}
+
+func TestResolveLoadedAddresses(t *testing.T) {
+	url := transactionServer(t, `{"result":{"meta":{"loadedAddresses":{"writable":["loaded"],"readonly":[]}}}}`)
+
+	block := blockWithLookup()
+
+	require.NoError(t, resolveLoadedAddresses(url, &block))
+
+	loadedAddresses := block.Transactions[0].Meta.LoadedAddresses
+
+	require.NotNil(t, loadedAddresses)
+	assert.Equal(t, []string{"loaded"}, loadedAddresses.Writable)
+}
+
+func TestResolveLoadedAddressesMissing(t *testing.T) {
+	url := transactionServer(t, `{"result":{"meta":{}}}`)
+
+	block := blockWithLookup()
+
+	assert.Error(t, resolveLoadedAddresses(url, &block))
+}
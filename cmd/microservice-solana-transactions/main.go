@@ -168,6 +168,6 @@ func main() {
 			Transactions: parsedTransactions,
 		}
 
-		queue.SendMessage(solanaQueue.TopicBufferedTransactions, transactions)
+		queue.SendEnvelope(solanaQueue.TopicBufferedTransactions, solanaQueue.SchemaBufferedApplicationTransactions, transactions)
 	})
 }
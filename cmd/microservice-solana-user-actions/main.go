@@ -86,7 +86,7 @@ func main() {
 
 				signature         = transaction.Signature
 				transactionResult = transaction.Result
-				accountKeys       = transactionResult.Transaction.Message.AccountKeys
+				accountKeys       = transactionResult.AccountKeys()
 				tokenBalances     = transactionResult.Meta.PostTokenBalances
 				adjustedFee       = transaction.AdjustedFee
 				applications      = transaction.Applications
@@ -196,9 +196,9 @@ func main() {
 
 					if payoutWasBlocked {
 						blockedPayout := convertBlockedPayout(winner1)
-						queue.SendMessage(winners.TopicBlockedWinnersSolana, blockedPayout)
+						queue.SendEnvelope(winners.TopicBlockedWinnersSolana, winners.SchemaBlockedWinner, blockedPayout)
 					} else {
-						queue.SendMessage(winners.TopicWinnersSolana, winner1)
+						queue.SendEnvelope(winners.TopicWinnersSolana, winners.SchemaWinner, winner1)
 					}
 				}
 
@@ -209,9 +209,9 @@ func main() {
 
 					if payoutWasBlocked {
 						blockedPayout := convertBlockedPayout(winner2)
-						queue.SendMessage(winners.TopicBlockedWinnersSolana, blockedPayout)
+						queue.SendEnvelope(winners.TopicBlockedWinnersSolana, winners.SchemaBlockedWinner, blockedPayout)
 					} else {
-						queue.SendMessage(winners.TopicWinnersSolana, winner2)
+						queue.SendEnvelope(winners.TopicWinnersSolana, winners.SchemaWinner, winner2)
 					}
 				}
 
@@ -251,7 +251,7 @@ func main() {
 				Slot:         slotNumber,
 			}
 
-			queue.SendMessage(worker.TopicSolanaParsedTransactions, bufferedTransactionsBlock)
+			queue.SendEnvelope(worker.TopicSolanaParsedTransactions, worker.SchemaSolanaBufferedParsedTransactions, bufferedTransactionsBlock)
 		}
 
 		if len(bufferedUserActions) != 0 {
@@ -259,8 +259,9 @@ func main() {
 				UserActions: bufferedUserActions,
 			}
 
-			queue.SendMessage(
+			queue.SendEnvelope(
 				user_actions.TopicBufferedUserActionsSolana,
+				user_actions.SchemaBufferedUserAction,
 				bufferedUserAction,
 			)
 		}
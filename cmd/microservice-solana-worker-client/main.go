@@ -15,6 +15,7 @@ import (
 	postgres "github.com/fluidity-money/fluidity-app/lib/databases/postgres/solana"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
+	workerQueue "github.com/fluidity-money/fluidity-app/lib/queues/worker"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 )
@@ -133,11 +134,9 @@ func main() {
 		})
 	}
 
-	queue.GetMessages(topicWinnerQueue, func(message queue.Message) {
+	queue.GetEnvelopes(topicWinnerQueue, workerQueue.SchemaSolanaWinnerAnnouncement, func(decoded interface{}) {
 
-		var winnerAnnouncement worker.SolanaWinnerAnnouncement
-
-		message.Decode(&winnerAnnouncement)
+		winnerAnnouncement := decoded.(worker.SolanaWinnerAnnouncement)
 
 		var (
 			winningTransactionHash = winnerAnnouncement.WinningTransactionHash
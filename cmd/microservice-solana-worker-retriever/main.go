@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/fluidity-money/fluidity-app/lib/leader"
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
 	"github.com/fluidity-money/fluidity-app/lib/queues/worker"
@@ -26,6 +28,10 @@ const (
 
 	// RedisTvlDuration to store the Pyth TVL calculation
 	RedisTvlDuration = time.Minute * 30
+
+	// RedisLeasePrefix to elect a single retriever for each TVL data
+	// account with, since they'd overwrite each other's TVL otherwise
+	RedisLeasePrefix = `solana.worker-retriever.`
 )
 
 const (
@@ -69,6 +75,7 @@ const (
 )
 
 func main() {
+	lifecycle.Start()
 
 	var (
 		rpcUrl = util.PickEnvOrFatal(EnvSolanaRpcUrl)
@@ -104,6 +111,10 @@ func main() {
 		})
 	}
 
+	// only the leader retrieves the tvl, standbys wait here to take over
+
+	leader.Lead(state.LeaseStore{}, RedisLeasePrefix+tvlDataPubkey.String())
+
 	worker.GetSolanaBufferedTransfers(func(transfers worker.SolanaBufferedTransfers) {
 
 		// get the entire amount of fUSDC in circulation (the amount of USDC wrapped)
@@ -187,7 +198,7 @@ func main() {
 			MintSupply:        mintSupply,
 		}
 
-		queue.SendMessage(topicWrappedActionsQueue, payableBufferedTransfers)
+		queue.SendEnvelope(topicWrappedActionsQueue, worker.SchemaSolanaWork, payableBufferedTransfers)
 
 	})
 }
@@ -83,11 +83,9 @@ func main() {
 
 	decimalPlacesRat := raiseDecimalPlaces(decimalPlaces)
 
-	queue.GetMessages(topicWrappedActionsQueue, func(message queue.Message) {
+	queue.GetEnvelopes(topicWrappedActionsQueue, worker.SchemaSolanaWork, func(decoded interface{}) {
 
-		var bufferedTransfers worker.SolanaWork
-
-		message.Decode(&bufferedTransfers)
+		bufferedTransfers := decoded.(worker.SolanaWork)
 
 		var (
 			transfers      = bufferedTransfers.BufferedTransfers
@@ -304,7 +302,7 @@ func main() {
 				FluidMintPubkey:        fluidMintPubkey.String(),
 			}
 
-			queue.SendMessage(topicWinnerQueue, winnerAnnouncement)
+			queue.SendEnvelope(topicWinnerQueue, worker.SchemaSolanaWinnerAnnouncement, winnerAnnouncement)
 
 			sendEmission(emission)
 		}
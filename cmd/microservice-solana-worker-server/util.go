@@ -53,7 +53,7 @@ func raiseDecimalPlaces(places int) *big.Rat {
 func sendEmission(emission *worker.Emission) {
 	emission.Update()
 
-	queue.SendMessage(worker.TopicEmissions, emission)
+	queue.SendEnvelope(worker.TopicEmissions, worker.SchemaEmission, emission)
 
 	log.Debugf("Emission: %s", emission)
 }
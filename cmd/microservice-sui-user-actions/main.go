@@ -9,6 +9,7 @@ import (
 	"strconv"
 
 	"github.com/fluidity-money/fluidity-app/common/sui/applications"
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
 	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
@@ -130,14 +131,22 @@ func main() {
 				transactions  = transactionBlock.Transaction.Data.Transaction.Transactions
 				inputs        = transactionBlock.Transaction.Data.Transaction.Inputs
 				gasUsed       = transactionBlock.Effects.GasUsed
+
+				balanceChanges = transactionBlock.BalanceChanges
+
+				// only application interactions moving the fluid coin are tracked
+				involvesFluid = swap.InvolvesCoin(balanceChanges, fluidToken.Type())
 			)
 
 			// process events
 			for eventIndex, event := range events {
+				// copied since decorated transfers take its address
+				event := event
+
 				transactionHash := event.Id.TxDigest
 
 				// check if event is an app type
-				if application := applications.ClassifyApplicationTransfer(event); application != applications.ApplicationNone {
+				if application := applications.ClassifyApplicationTransfer(event); involvesFluid && application != applications.ApplicationNone {
 					checkpointBig, err := misc.BigIntFromString(transactionBlock.Checkpoint)
 					if err != nil {
 						log.Fatal(func(k *log.Log) {
@@ -162,10 +171,11 @@ func main() {
 					}
 
 					decoratedTransfer := sui_queue.DecoratedTransfer{
-						Event:      &event,
-						Data:       transactionBlock.Transaction.Data,
-						Checkpoint: *checkpointBig,
-						UserAction: userAction,
+						Event:          &event,
+						Data:           transactionBlock.Transaction.Data,
+						Checkpoint:     *checkpointBig,
+						UserAction:     userAction,
+						BalanceChanges: balanceChanges,
 					}
 
 					decoratedTransfers = append(decoratedTransfers, decoratedTransfer)
@@ -191,7 +201,7 @@ func main() {
 
 					swap := userActionFromWrap(transactionHash, wrapEvent, fluidToken)
 
-					queue.SendMessage(user_actions.TopicUserActionsSui, swap)
+					queue.SendEnvelope(user_actions.TopicUserActionsSui, user_actions.SchemaUserAction, swap)
 				case fluidToken.Unwrap():
 					unwrapEvent, err := sui_types.ParseUnwrap(event.ParsedJson)
 					if err != nil {
@@ -210,7 +220,7 @@ func main() {
 
 					swap := userActionFromUnwrap(transactionHash, unwrapEvent, fluidToken)
 
-					queue.SendMessage(user_actions.TopicUserActionsSui, swap)
+					queue.SendEnvelope(user_actions.TopicUserActionsSui, user_actions.SchemaUserAction, swap)
 				case fluidToken.DistributeYield():
 					distributeYieldEvent, err := sui_types.ParseDistributeYield(event.ParsedJson)
 					if err != nil {
@@ -444,17 +454,18 @@ func main() {
 
 		// worker doesn't re-process transfers without an application, so send immediately
 		for _, userAction := range userActions {
-			queue.SendMessage(
+			queue.SendEnvelope(
 				user_actions.TopicUserActionsSui,
+				user_actions.SchemaUserAction,
 				userAction,
 			)
 		}
 		// decorated user actions are processed by the application server to add application info
-		queue.SendMessage(sui_queue.TopicDecoratedTransfers, decoratedTransfers)
+		queue.SendEnvelope(sui_queue.TopicDecoratedTransfers, sui_queue.SchemaDecoratedTransfers, decoratedTransfers)
 
 		// winners are directly processed into timescale
 		for _, winner := range winners {
-			queue.SendMessage(winnerTypes.TopicWinnersSui, winner)
+			queue.SendEnvelope(winnerTypes.TopicWinnersSui, winnerTypes.SchemaWinner, winner)
 			// keep track of the last winning checkpoint
 			writeLastWinningCheckpoint(RedisLastWinnerCheckpoint, checkpoint.SequenceNumber)
 		}
@@ -20,10 +20,11 @@ func getTransactionBlocks(client sui.ISuiAPI, digests []string) (models.SuiMulti
 		transactionBlocks models.SuiMultiGetTransactionBlocksResponse
 
 		options = models.SuiTransactionBlockOptions{
-			ShowInput:         true,
-			ShowEvents:        true,
-			ShowObjectChanges: true,
-			ShowEffects:       true,
+			ShowInput:          true,
+			ShowEvents:         true,
+			ShowObjectChanges:  true,
+			ShowEffects:        true,
+			ShowBalanceChanges: true,
 		}
 	)
 
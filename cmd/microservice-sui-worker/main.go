@@ -15,12 +15,15 @@ import (
 
 	"github.com/fluidity-money/fluidity-app/common/calculation/probability"
 	"github.com/fluidity-money/fluidity-app/common/ethereum/fluidity"
+	commonSpooler "github.com/fluidity-money/fluidity-app/common/spooler"
 	suiApps "github.com/fluidity-money/fluidity-app/common/sui/applications"
 	prize_pool "github.com/fluidity-money/fluidity-app/common/sui/applications/prize-pool"
+	"github.com/fluidity-money/fluidity-app/common/sui/payout"
 	"github.com/fluidity-money/fluidity-app/lib/databases/postgres/worker"
 	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/spooler"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/queue"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/queues/dry-run"
 	sui_queue "github.com/fluidity-money/fluidity-app/lib/queues/sui"
 	user_actions "github.com/fluidity-money/fluidity-app/lib/queues/user-actions"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
@@ -64,9 +67,6 @@ const (
 
 	// EnvCoinReserve for the coin reserve containing the fluid token
 	EnvCoinReserve = `FLU_SUI_COIN_RESERVE`
-
-	// SuiClockId for the constant address of the Sui clock
-	SuiClockId = `0x0000000000000000000000000000000000000000000000000000000000000006`
 )
 
 func main() {
@@ -84,13 +84,15 @@ func main() {
 		prizePoolVault = util.GetEnvOrFatal(EnvPrizePoolVaultId)
 		scallopVersion = util.GetEnvOrFatal(EnvScallopVersion)
 		scallopMarket  = util.GetEnvOrFatal(EnvScallopMarket)
+
+		dryRun = util.DryRunEnabled()
 	)
 
-	payoutArgs := payoutArgs{
+	payoutArgs := payout.PayoutArgs{
 		PrizePoolVault: prizePoolVault,
 		ScallopVersion: scallopVersion,
 		ScallopMarket:  scallopMarket,
-		Clock:          SuiClockId,
+		Clock:          payout.ClockId,
 	}
 
 	decimalPlaces, err := strconv.Atoi(decimalPlaces_)
@@ -133,6 +135,26 @@ func main() {
 		IsFluid:        false,
 	}
 
+	backend := payout.Backend{
+		Client:        client,
+		Signer:        *signer,
+		FluidToken:    fluidToken,
+		BaseToken:     baseToken,
+		WorkerAddress: workerAddress,
+		PayoutArgs:    payoutArgs,
+	}
+
+	spoolerConfig := commonSpooler.DefaultConfig()
+
+	spoolerConfig.PublishDryRun = dry_run.Publish
+
+	suiSpooler := commonSpooler.New(
+		network.NetworkSui,
+		spooler.Store{},
+		backend,
+		spoolerConfig,
+	)
+
 	sui_queue.DecoratedTransfers(func(decoratedTransfers []sui_queue.DecoratedTransfer) {
 		transfersWithFees := make([]worker_types.TransferWithFee, 0)
 
@@ -171,7 +193,24 @@ func main() {
 
 			// process app (set fee and application in user action)
 			// AppData is not yet used on Sui
-			feeData, _, emission, err := suiApps.GetApplicationFee(transfer, *event, *application)
+			feeData, _, emission, err := suiApps.GetApplicationFee(
+				transfer,
+				*event,
+				*application,
+				client,
+				fluidToken,
+			)
+
+			if err != nil {
+				log.Fatal(func(k *log.Log) {
+					k.Format(
+						"Failed to get the fee for application %v in transaction %v!",
+						application,
+						transactionDigest,
+					)
+					k.Payload = err
+				})
+			}
 
 			var utility applications.UtilityName
 			utilityDetails, ok := utilities[packageId]
@@ -263,8 +302,9 @@ func main() {
 				decimalPlaces,
 			)
 
-			queue.SendMessage(
+			queue.SendEnvelope(
 				user_actions.TopicUserActionsSui,
+				user_actions.SchemaUserAction,
 				transferUserAction,
 			)
 		}
@@ -446,7 +486,7 @@ func main() {
 					)
 				})
 
-				sendEmission(emission)
+				sendEmission(emission, dryRun)
 
 				continue
 			}
@@ -500,17 +540,24 @@ func main() {
 			// don't bother paying out if the unlucky winner won nothing
 
 			if winningAmount <= 0 {
-				sendEmission(emission)
+				sendEmission(emission, dryRun)
 				continue
 			}
 
-			sendEmission(emission)
+			sendEmission(emission, dryRun)
 
 			pendingWinners_ := spooler.CreatePendingWinnersSui(transfer, fromWinAmounts, toWinAmounts, utilities, matchedBalls)
 			// store pending winners from all announcements to store later
 			pendingWinners = append(pendingWinners, pendingWinners_...)
 		}
-		spooler.InsertPendingWinners(pendingWinners)
-		payoutSpooledWinnings(client, *signer, fluidToken, baseToken, workerAddress, payoutArgs, pendingWinners)
+
+		// the live worker spools the winners, so a dry run only
+		// simulates the payouts it would have made
+
+		if !dryRun {
+			spooler.InsertPendingWinners(pendingWinners)
+		}
+
+		payoutSpooledWinnings(suiSpooler, pendingWinners)
 	})
 }
@@ -11,22 +11,16 @@ import (
 	worker_types "github.com/fluidity-money/fluidity-app/lib/types/worker"
 )
 
-const (
-	FluidityModule = `fluidity_coin`
-	PayoutFunction = `distribute_yield`
-)
-
-type payoutArgs struct {
-	PrizePoolVault string
-	ScallopVersion string
-	ScallopMarket  string
-	Clock          string
-}
-
-func sendEmission(emission *worker.Emission) {
+// sendEmission to the emissions queue, only logging it in a dry run
+func sendEmission(emission *worker.Emission, dryRun bool) {
 	emission.Update()
 
-	queue.SendMessage(worker.TopicEmissions, emission)
+	if dryRun {
+		log.Debugf("Dry run emission: %s", emission)
+		return
+	}
+
+	queue.SendEnvelope(worker.TopicEmissions, worker.SchemaEmission, emission)
 
 	log.Debugf("Emission: %s", emission)
 }
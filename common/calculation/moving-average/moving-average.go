@@ -9,11 +9,11 @@ package moving_average
 
 import (
 	"fmt"
-	"math"
 	"math/big"
 	"strconv"
 
 	"github.com/fluidity-money/fluidity-app/lib/state"
+	"github.com/fluidity-money/fluidity-app/lib/util"
 )
 
 // DefaultBufferSize to use when storing the ring buffer
@@ -26,36 +26,16 @@ func StoreValue(key string, x interface{}) {
 
 // CalculateMovingAverageAndSumMaybePop with the limit given, by getting
 // a range over each item then popping anything that exceeds the limit if
-// the flag is set
+// the flag is set, the same as util.MovingAverageAndSumMaybePop
 func CalculateMovingAverageAndSumMaybePop(key string, limit int, shouldPopIfExcess bool) (average int, sum int, err error) {
-	listLength_ := state.LLen(key)
-
-	if listLength_ > math.MaxInt {
-		return 0, 0, fmt.Errorf(
-			"key list %#v length too large: %v",
-			key,
-			listLength_,
-		)
-	}
-
-	listLength := int(listLength_)
-
-	shouldPop := listLength > limit && shouldPopIfExcess
-
-	// should only pop if there's excess and the argument is set
-
-	if shouldPop {
-		state.RPopCount(key, listLength-limit)
-	}
+	// the whole list is read so the values dropped are the same as
+	// the values popped
 
-	valuesBytes := state.LRange(key, 0, int64(limit))
+	valuesBytes := state.LRange(key, 0, -1)
 
-	// avoid dividing by zero if no bytes are stored
-	if listLength == 0 {
-		return 0, 0, nil
-	}
+	values := make([]int, len(valuesBytes))
 
-	for _, valueBytes := range valuesBytes {
+	for i, valueBytes := range valuesBytes {
 		s := string(valueBytes)
 
 		value, err := strconv.Atoi(s)
@@ -68,14 +48,22 @@ func CalculateMovingAverageAndSumMaybePop(key string, limit int, shouldPopIfExce
 			)
 		}
 
-		average += value
-
-		sum += value
+		values[i] = value
 	}
 
-	amount := average / listLength
+	remaining, average, sum := util.MovingAverageAndSumMaybePop(
+		values,
+		limit,
+		shouldPopIfExcess,
+	)
+
+	// should only pop if there's excess and the argument is set
+
+	if popped := len(values) - len(remaining); popped > 0 {
+		state.RPopCount(key, popped)
+	}
 
-	return amount, sum, nil
+	return average, sum, nil
 }
 
 // CalculateMovingAverage by taking the key given and calculating the
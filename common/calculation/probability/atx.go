@@ -10,6 +10,9 @@ import (
 	"math/big"
 )
 
+// SecondsInOneYear to use for ATX calculation
+const SecondsInOneYear uint64 = 365 * 24 * 60 * 60
+
 // CalculateAtx using the duration since the last block and the number of
 // fluid transfers
 func CalculateAtx(secondsSinceLastBlock *big.Rat, fluidTransfers int) *big.Rat {
@@ -37,3 +40,54 @@ func CalculateAtx(secondsSinceLastBlock *big.Rat, fluidTransfers int) *big.Rat {
 
 	return fluidTransfersMulSeconds
 }
+
+// CalculateCurrentAtx using the average transfers in a block and the
+// transfers in the current epoch. If the epoch's transfers per block
+// plus the margin is larger than the average, the epoch is used instead
+// of the average, so a burst of transfers doesn't make the average too
+// generous. Returns the atx and the btx
+func CalculateCurrentAtx(secondsSinceLastBlock *big.Rat, averageTransfersInBlock, transfersInEpoch, epochBlocks int, currentAtxTransactionMargin int64) (currentAtx *big.Rat, btx int) {
+	var (
+		transfersInEpochRat = intToRat(transfersInEpoch)
+		epochBlocksRat      = intToRat(epochBlocks)
+
+		averageTransfersInBlockRat = intToRat(averageTransfersInBlock)
+
+		currentAtxTransactionMarginRat = new(big.Rat).SetInt64(
+			currentAtxTransactionMargin,
+		)
+	)
+
+	// average transfers in block over the current epoch
+	transfersInBlockOverEpoch := new(big.Rat).Quo(
+		transfersInEpochRat,
+		epochBlocksRat,
+	)
+
+	currentAtxTransactionMarginRatCmp := new(big.Rat).Add(
+		transfersInBlockOverEpoch,
+		currentAtxTransactionMarginRat,
+	)
+
+	// example: average BTX is 20, current BTX is 30, BTX that gets
+	// passed to worker is 30. example 2: average BTX is 20, current BTX
+	// is 10, BTX that gets passed to worker is 20
+
+	if currentAtxTransactionMarginRatCmp.Cmp(averageTransfersInBlockRat) > 0 {
+		currentAtx = new(big.Rat).Mul(
+			new(big.Rat).SetUint64(SecondsInOneYear),
+			transfersInBlockOverEpoch,
+		)
+
+		currentAtx.Quo(currentAtx, secondsSinceLastBlock)
+
+		return currentAtx, transfersInEpoch
+	}
+
+	currentAtx = CalculateAtx(
+		secondsSinceLastBlock,
+		averageTransfersInBlock,
+	)
+
+	return currentAtx, averageTransfersInBlock * epochBlocks
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this source
+// code is governed by a Creative Commons license that can be found in the
+// LICENSE_TRF.md file.
+
+package simulation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+)
+
+type (
+	// Report of a simulation, summarised and block by block
+	Report struct {
+		Summary Summary       `json:"summary"`
+		Blocks  []BlockReport `json:"blocks"`
+
+		// poolNames in the order they were given, for the CSV columns
+		poolNames []applications.UtilityName
+
+		// payoutMean and payoutM2 for the running variance
+		payoutMean float64
+		payoutM2   float64
+	}
+
+	// Summary of the payouts over every transfer
+	Summary struct {
+		Transfers             int     `json:"transfers"`
+		TransfersWithoutPools int     `json:"transfers_without_pools"`
+		Wins                  int     `json:"wins"`
+		WinRate               float64 `json:"win_rate"`
+		TotalPaidUsd          float64 `json:"total_paid_usd"`
+
+		// ExpectedPayoutUsd per transfer, including transfers that
+		// didn't win
+		ExpectedPayoutUsd float64 `json:"expected_payout_usd"`
+		PayoutVarianceUsd float64 `json:"payout_variance_usd"`
+
+		Tiers   []TierReport  `json:"tiers"`
+		Pools   []PoolReport  `json:"pools"`
+		Batches BatchesReport `json:"batches"`
+	}
+
+	// TierReport of the wins with a number of matched balls
+	TierReport struct {
+		Tier    int     `json:"tier"`
+		Wins    int     `json:"wins"`
+		WinRate float64 `json:"win_rate"`
+		PaidUsd float64 `json:"paid_usd"`
+	}
+
+	// PoolReport of how far a pool was drawn down
+	PoolReport struct {
+		Name     applications.UtilityName `json:"name"`
+		StartUsd float64                  `json:"start_usd"`
+		EndUsd   float64                  `json:"end_usd"`
+
+		// Drawdown of the pool by the end, as a fraction of its start
+		Drawdown float64 `json:"drawdown"`
+	}
+
+	// BatchesReport of the batches the spooler would've sent
+	BatchesReport struct {
+		Count         int     `json:"count"`
+		MinWinners    int     `json:"min_winners"`
+		MaxWinners    int     `json:"max_winners"`
+		MeanWinners   float64 `json:"mean_winners"`
+		MeanUsd       float64 `json:"mean_usd"`
+		UnsentWinners int     `json:"unsent_winners"`
+		UnsentUsd     float64 `json:"unsent_usd"`
+	}
+
+	// BlockReport of the state after each block
+	BlockReport struct {
+		Block      int     `json:"block"`
+		Transfers  int     `json:"transfers"`
+		Btx        int     `json:"btx"`
+		Atx        float64 `json:"atx"`
+		Wins       int     `json:"wins"`
+		PaidUsd    float64 `json:"paid_usd"`
+		PendingUsd float64 `json:"pending_usd"`
+
+		PoolSizeUsd map[applications.UtilityName]float64 `json:"pool_size_usd"`
+	}
+)
+
+func newReport(winningClasses int, pools []worker.UtilityVars) *Report {
+	report := &Report{
+		Blocks:    make([]BlockReport, 0),
+		poolNames: make([]applications.UtilityName, len(pools)),
+	}
+
+	report.Summary.Tiers = make([]TierReport, winningClasses)
+
+	for i := range report.Summary.Tiers {
+		report.Summary.Tiers[i].Tier = i + 1
+	}
+
+	report.Summary.Pools = make([]PoolReport, len(pools))
+
+	for i, pool := range pools {
+		report.poolNames[i] = pool.Name
+
+		report.Summary.Pools[i] = PoolReport{
+			Name:     pool.Name,
+			StartUsd: poolSizeUsd(pool),
+		}
+	}
+
+	return report
+}
+
+// addTransfer that won the tier given (0 if it didn't win)
+func (report *Report) addTransfer(tier int, paidUsd float64) {
+	summary := &report.Summary
+
+	summary.Transfers++
+
+	delta := paidUsd - report.payoutMean
+
+	report.payoutMean += delta / float64(summary.Transfers)
+
+	report.payoutM2 += delta * (paidUsd - report.payoutMean)
+
+	if tier == 0 {
+		return
+	}
+
+	summary.Wins++
+	summary.TotalPaidUsd += paidUsd
+
+	summary.Tiers[tier-1].Wins++
+	summary.Tiers[tier-1].PaidUsd += paidUsd
+}
+
+func (report *Report) addBatch(winners int, usd float64) {
+	batches := &report.Summary.Batches
+
+	if batches.Count == 0 || winners < batches.MinWinners {
+		batches.MinWinners = winners
+	}
+
+	if winners > batches.MaxWinners {
+		batches.MaxWinners = winners
+	}
+
+	batches.Count++
+
+	batches.MeanWinners += (float64(winners) - batches.MeanWinners) / float64(batches.Count)
+
+	batches.MeanUsd += (usd - batches.MeanUsd) / float64(batches.Count)
+}
+
+func (report *Report) addBlock(block BlockReport) {
+	report.Blocks = append(report.Blocks, block)
+}
+
+// finish the summary once every block was simulated
+func (report *Report) finish(pools []worker.UtilityVars) {
+	summary := &report.Summary
+
+	if transfers := summary.Transfers; transfers != 0 {
+		summary.WinRate = float64(summary.Wins) / float64(transfers)
+
+		summary.ExpectedPayoutUsd = report.payoutMean
+
+		summary.PayoutVarianceUsd = report.payoutM2 / float64(transfers)
+
+		for i, tier := range summary.Tiers {
+			summary.Tiers[i].WinRate = float64(tier.Wins) / float64(transfers)
+		}
+	}
+
+	for i, pool := range pools {
+		poolReport := &summary.Pools[i]
+
+		poolReport.EndUsd = poolSizeUsd(pool)
+
+		if poolReport.StartUsd != 0 {
+			poolReport.Drawdown = 1 - poolReport.EndUsd/poolReport.StartUsd
+		}
+	}
+}
+
+// WriteJson of the summary and every block
+func (report Report) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	encoder.SetIndent("", "\t")
+
+	return encoder.Encode(report)
+}
+
+// WriteSummaryCsv as rows of metric and value
+func (report Report) WriteSummaryCsv(w io.Writer) error {
+	var (
+		summary = report.Summary
+		batches = summary.Batches
+	)
+
+	rows := [][]string{
+		{"metric", "value"},
+		{"transfers", strconv.Itoa(summary.Transfers)},
+		{"transfers_without_pools", strconv.Itoa(summary.TransfersWithoutPools)},
+		{"wins", strconv.Itoa(summary.Wins)},
+		{"win_rate", formatFloat(summary.WinRate)},
+		{"total_paid_usd", formatFloat(summary.TotalPaidUsd)},
+		{"expected_payout_usd", formatFloat(summary.ExpectedPayoutUsd)},
+		{"payout_variance_usd", formatFloat(summary.PayoutVarianceUsd)},
+	}
+
+	for _, tier := range summary.Tiers {
+		rows = append(
+			rows,
+			[]string{fmt.Sprintf("tier_%v_wins", tier.Tier), strconv.Itoa(tier.Wins)},
+			[]string{fmt.Sprintf("tier_%v_win_rate", tier.Tier), formatFloat(tier.WinRate)},
+			[]string{fmt.Sprintf("tier_%v_paid_usd", tier.Tier), formatFloat(tier.PaidUsd)},
+		)
+	}
+
+	for _, pool := range summary.Pools {
+		rows = append(
+			rows,
+			[]string{fmt.Sprintf("pool_%v_start_usd", pool.Name), formatFloat(pool.StartUsd)},
+			[]string{fmt.Sprintf("pool_%v_end_usd", pool.Name), formatFloat(pool.EndUsd)},
+			[]string{fmt.Sprintf("pool_%v_drawdown", pool.Name), formatFloat(pool.Drawdown)},
+		)
+	}
+
+	rows = append(
+		rows,
+		[]string{"batches", strconv.Itoa(batches.Count)},
+		[]string{"batch_min_winners", strconv.Itoa(batches.MinWinners)},
+		[]string{"batch_max_winners", strconv.Itoa(batches.MaxWinners)},
+		[]string{"batch_mean_winners", formatFloat(batches.MeanWinners)},
+		[]string{"batch_mean_usd", formatFloat(batches.MeanUsd)},
+		[]string{"unsent_winners", strconv.Itoa(batches.UnsentWinners)},
+		[]string{"unsent_usd", formatFloat(batches.UnsentUsd)},
+	)
+
+	return writeCsv(w, rows)
+}
+
+// WriteBlocksCsv with a row for every block, and a column for the size
+// of each pool
+func (report Report) WriteBlocksCsv(w io.Writer) error {
+	header := []string{
+		"block",
+		"transfers",
+		"btx",
+		"atx",
+		"wins",
+		"paid_usd",
+		"pending_usd",
+	}
+
+	for _, name := range report.poolNames {
+		header = append(header, fmt.Sprintf("pool_%v_usd", name))
+	}
+
+	rows := [][]string{header}
+
+	for _, block := range report.Blocks {
+		row := []string{
+			strconv.Itoa(block.Block),
+			strconv.Itoa(block.Transfers),
+			strconv.Itoa(block.Btx),
+			formatFloat(block.Atx),
+			strconv.Itoa(block.Wins),
+			formatFloat(block.PaidUsd),
+			formatFloat(block.PendingUsd),
+		}
+
+		for _, name := range report.poolNames {
+			row = append(row, formatFloat(block.PoolSizeUsd[name]))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return writeCsv(w, rows)
+}
+
+func writeCsv(w io.Writer, rows [][]string) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv! %v", err)
+	}
+
+	return nil
+}
+
+func formatFloat(x float64) string {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return ""
+	}
+
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
@@ -0,0 +1,393 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this source
+// code is governed by a Creative Commons license that can be found in the
+// LICENSE_TRF.md file.
+
+package simulation
+
+// simulation runs the TRF offline over a stream of blocks of transfers,
+// using the same calculations as the Ethereum worker server and a seeded
+// RNG in place of the worker client's randomness, so the economics of a
+// parameter change can be reviewed before it's rolled out
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/fluidity-money/fluidity-app/common/calculation/probability"
+	"github.com/fluidity-money/fluidity-app/common/spooler"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+)
+
+// simulatedToken that every win is paid out in
+var simulatedToken = token_details.New("fUSDC", 6)
+
+type (
+	// Scenario to simulate, with either a recorded stream of blocks or
+	// the settings to generate a synthetic one
+	Scenario struct {
+		Seed int64 `json:"seed"`
+
+		// WorkerConfig that the worker server and spooler would read from
+		// the database
+		WorkerConfig worker.WorkerConfigEthereum `json:"worker_config"`
+
+		WinningClasses int      `json:"winning_classes"`
+		PayoutFreq     *big.Rat `json:"payout_freq"`
+
+		// Pools to pay out of, drawn down as rewards are won. Only normal
+		// pools are simulated
+		Pools []worker.UtilityVars `json:"pools"`
+
+		Blocks    []Block    `json:"blocks"`
+		Synthetic *Synthetic `json:"synthetic"`
+	}
+
+	// Block of transfers that were seen together
+	Block struct {
+		Transfers []Transfer `json:"transfers"`
+	}
+
+	// Transfer with the fees that were paid for it, in USD
+	Transfer struct {
+		GasFeeUsd         float64 `json:"gas_fee_usd"`
+		ApplicationFeeUsd float64 `json:"application_fee_usd"`
+	}
+
+	// Synthetic stream of blocks, with transfers and fees picked
+	// uniformly between the bounds given
+	Synthetic struct {
+		Blocks            int     `json:"blocks"`
+		MinTransfers      int     `json:"min_transfers"`
+		MaxTransfers      int     `json:"max_transfers"`
+		MinGasFeeUsd      float64 `json:"min_gas_fee_usd"`
+		MaxGasFeeUsd      float64 `json:"max_gas_fee_usd"`
+		ApplicationFeeUsd float64 `json:"application_fee_usd"`
+	}
+
+	// pendingStore of the wins the spooler hasn't sent, only tracking
+	// the number of winners and their total
+	pendingStore struct {
+		winners int
+		usd     float64
+	}
+)
+
+// Run the scenario, returning a report of the payouts
+func Run(scenario Scenario) (*Report, error) {
+	config := scenario.WorkerConfig
+
+	if scenario.WinningClasses <= 0 {
+		return nil, fmt.Errorf("winning classes must be positive, not %v", scenario.WinningClasses)
+	}
+
+	if scenario.PayoutFreq == nil || scenario.PayoutFreq.Sign() <= 0 {
+		return nil, fmt.Errorf("payout frequency must be positive")
+	}
+
+	if config.EpochBlocks <= 0 || config.AtxBufferSize <= 0 {
+		return nil, fmt.Errorf(
+			"epoch blocks (%v) and atx buffer size (%v) must be positive",
+			config.EpochBlocks,
+			config.AtxBufferSize,
+		)
+	}
+
+	for _, pool := range scenario.Pools {
+		if pool.PoolSizeNative == nil || pool.TokenDecimalsScale == nil || pool.ExchangeRate == nil || pool.DeltaWeight == nil {
+			return nil, fmt.Errorf(
+				"pool %v is missing its size, decimals, exchange rate or delta weight",
+				pool.Name,
+			)
+		}
+	}
+
+	rng := rand.New(rand.NewSource(scenario.Seed))
+
+	blocks := scenario.Blocks
+
+	if len(blocks) == 0 && scenario.Synthetic != nil {
+		blocks = generateBlocks(rng, *scenario.Synthetic)
+	}
+
+	pools := copyPools(scenario.Pools)
+
+	report := newReport(scenario.WinningClasses, pools)
+
+	var (
+		// transfer counts for every block, newest first, like the list
+		// the worker server keeps in Redis
+		transferCounts []int
+
+		pending pendingStore
+	)
+
+	// the spooler only reads the unpaid winnings from the store to
+	// check the thresholds, so nothing is ever paid out with it
+
+	spooler_ := spooler.New(network.NetworkEthereum, &pending, nil, spooler.Config{})
+
+	thresholds := spooler.Thresholds{
+		Instant: config.SpoolerInstantRewardThreshold,
+		Batched: config.SpoolerBatchedRewardThreshold,
+	}
+
+	for blockIndex, block := range blocks {
+		transfersInBlock := len(block.Transfers)
+
+		transferCounts = append([]int{transfersInBlock}, transferCounts...)
+
+		currentAtx, btx, secondsSinceLastEpoch := calculateAtx(config, &transferCounts)
+
+		blockReport := BlockReport{
+			Block:       blockIndex,
+			Transfers:   transfersInBlock,
+			Btx:         btx,
+			PoolSizeUsd: make(map[applications.UtilityName]float64),
+		}
+
+		blockReport.Atx, _ = currentAtx.Float64()
+
+		for _, transfer := range block.Transfers {
+			nonEmptyPools := nonEmptyPools(pools)
+
+			if len(nonEmptyPools) == 0 {
+				report.Summary.TransfersWithoutPools++
+				continue
+			}
+
+			emission := worker.NewEthereumEmission()
+
+			transferFeeNormal := new(big.Rat).SetFloat64(
+				transfer.GasFeeUsd + transfer.ApplicationFeeUsd,
+			)
+
+			randomN, randomPayouts, _ := probability.WinningChances(
+				worker.TrfModeNormal,
+				transferFeeNormal,
+				currentAtx,
+				scenario.PayoutFreq,
+				nonEmptyPools,
+				scenario.WinningClasses,
+				btx,
+				secondsSinceLastEpoch,
+				emission,
+			)
+
+			// the worker would fail to pick the balls if there are
+			// fewer numbers than winning classes
+
+			if int(randomN) < scenario.WinningClasses {
+				return nil, fmt.Errorf(
+					"block %v has %v random numbers to pick %v winning classes from",
+					blockIndex,
+					randomN,
+					scenario.WinningClasses,
+				)
+			}
+
+			balls := util.RandomIntegersFrom(rng, scenario.WinningClasses, 1, uint32(randomN))
+
+			matchedBalls := probability.NaiveIsWinning(balls, emission)
+
+			if matchedBalls == 0 {
+				report.addTransfer(0, 0)
+				continue
+			}
+
+			fromWinAmounts, toWinAmounts := probability.CalculatePayoutsSplit(
+				randomPayouts,
+				matchedBalls,
+			)
+
+			var winUsd float64
+
+			for _, winAmounts := range []map[applications.UtilityName]worker.Payout{fromWinAmounts, toWinAmounts} {
+				for utility, payout := range winAmounts {
+					winUsd += payout.Usd
+
+					drawDown(pools, utility, &payout.Native.Int)
+				}
+			}
+
+			report.addTransfer(matchedBalls, winUsd)
+
+			blockReport.Wins++
+			blockReport.PaidUsd += winUsd
+
+			// the spooler sends everything pending once a win is
+			// large enough, or the total pending is large enough
+
+			pending.winners++
+			pending.usd += winUsd
+
+			win := spooler.Win{
+				Token:     simulatedToken,
+				UsdAmount: winUsd,
+			}
+
+			if len(spooler_.TokensToSend([]spooler.Win{win}, thresholds)) > 0 {
+				report.addBatch(pending.winners, pending.usd)
+
+				pending = pendingStore{}
+			}
+		}
+
+		blockReport.PendingUsd = pending.usd
+
+		for _, pool := range pools {
+			blockReport.PoolSizeUsd[pool.Name] = poolSizeUsd(pool)
+		}
+
+		report.addBlock(blockReport)
+	}
+
+	report.finish(pools)
+
+	report.Summary.Batches.UnsentWinners = pending.winners
+	report.Summary.Batches.UnsentUsd = pending.usd
+
+	return report, nil
+}
+
+// calculateAtx the same way as the worker server, returning the atx,
+// btx and the seconds since the last epoch
+func calculateAtx(config worker.WorkerConfigEthereum, transferCounts *[]int) (currentAtx *big.Rat, btx int, secondsSinceLastEpoch uint64) {
+	var (
+		epochBlocks   = config.EpochBlocks
+		atxBufferSize = config.AtxBufferSize
+
+		secondsSinceLastBlock = config.DefaultSecondsSinceLastBlock
+	)
+
+	secondsSinceLastEpoch = uint64(secondsSinceLastBlock * float64(epochBlocks))
+
+	shouldAverageTransfersScanPopExcess := atxBufferSize > epochBlocks
+
+	var averageTransfersInBlock, transfersInEpoch int
+
+	*transferCounts, averageTransfersInBlock, _ = util.MovingAverageAndSumMaybePop(
+		*transferCounts,
+		atxBufferSize,
+		shouldAverageTransfersScanPopExcess,
+	)
+
+	*transferCounts, _, transfersInEpoch = util.MovingAverageAndSumMaybePop(
+		*transferCounts,
+		epochBlocks,
+		!shouldAverageTransfersScanPopExcess,
+	)
+
+	if averageTransfersInBlock < config.DefaultTransfersInBlock {
+		averageTransfersInBlock = config.DefaultTransfersInBlock
+	}
+
+	currentAtx, btx = probability.CalculateCurrentAtx(
+		new(big.Rat).SetFloat64(secondsSinceLastBlock),
+		averageTransfersInBlock,
+		transfersInEpoch,
+		epochBlocks,
+		config.CurrentAtxTransactionMargin,
+	)
+
+	return currentAtx, btx, secondsSinceLastEpoch
+}
+
+func generateBlocks(rng *rand.Rand, synthetic Synthetic) []Block {
+	blocks := make([]Block, synthetic.Blocks)
+
+	for i := range blocks {
+		transferCount := synthetic.MinTransfers
+
+		if spread := synthetic.MaxTransfers - synthetic.MinTransfers; spread > 0 {
+			transferCount += rng.Intn(spread + 1)
+		}
+
+		transfers := make([]Transfer, transferCount)
+
+		for j := range transfers {
+			gasFee := synthetic.MinGasFeeUsd +
+				rng.Float64()*(synthetic.MaxGasFeeUsd-synthetic.MinGasFeeUsd)
+
+			transfers[j] = Transfer{
+				GasFeeUsd:         gasFee,
+				ApplicationFeeUsd: synthetic.ApplicationFeeUsd,
+			}
+		}
+
+		blocks[i] = Block{Transfers: transfers}
+	}
+
+	return blocks
+}
+
+// copyPools so the scenario's pools aren't drawn down
+func copyPools(pools []worker.UtilityVars) []worker.UtilityVars {
+	copied := make([]worker.UtilityVars, len(pools))
+
+	for i, pool := range pools {
+		copied[i] = pool
+		copied[i].PoolSizeNative = new(big.Rat).Set(pool.PoolSizeNative)
+	}
+
+	return copied
+}
+
+// nonEmptyPools that can still pay out, the same way the worker server
+// skips empty pools
+func nonEmptyPools(pools []worker.UtilityVars) []worker.UtilityVars {
+	nonEmpty := make([]worker.UtilityVars, 0, len(pools))
+
+	for _, pool := range pools {
+		if pool.PoolSizeNative.Sign() > 0 {
+			nonEmpty = append(nonEmpty, pool)
+		}
+	}
+
+	return nonEmpty
+}
+
+func drawDown(pools []worker.UtilityVars, utility applications.UtilityName, native *big.Int) {
+	for _, pool := range pools {
+		if pool.Name != utility {
+			continue
+		}
+
+		pool.PoolSizeNative.Sub(pool.PoolSizeNative, new(big.Rat).SetInt(native))
+
+		if pool.PoolSizeNative.Sign() < 0 {
+			pool.PoolSizeNative.SetInt64(0)
+		}
+	}
+}
+
+func poolSizeUsd(pool worker.UtilityVars) float64 {
+	size := new(big.Rat).Quo(pool.PoolSizeNative, pool.TokenDecimalsScale)
+
+	size.Mul(size, pool.ExchangeRate)
+
+	sizeUsd, _ := size.Float64()
+
+	return sizeUsd
+}
+
+func (store *pendingStore) UnpaidWinningsForCategory(_ network.BlockchainNetwork, _ token_details.TokenDetails) float64 {
+	return store.usd
+}
+
+func (store *pendingStore) GetAndRemoveRewardsForCategory(_ network.BlockchainNetwork, _ token_details.TokenDetails) []worker.EthereumReward {
+	*store = pendingStore{}
+
+	return nil
+}
+
+func (store *pendingStore) GetUnsentRewardsForCategory(_ network.BlockchainNetwork, _ token_details.TokenDetails) []worker.EthereumReward {
+	return nil
+}
+
+func (store *pendingStore) RestoreRewardsForCategory(_ network.BlockchainNetwork, _ token_details.TokenDetails, _ []worker.EthereumReward) {
+}
@@ -0,0 +1,172 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this source
+// code is governed by a Creative Commons license that can be found in the
+// LICENSE_TRF.md file.
+
+package simulation
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testScenario = `{
+	"seed": 42,
+	"worker_config": {
+		"default_seconds_since_last_block": 13,
+		"current_atx_transaction_margin": 0,
+		"default_transfers_in_block": 1,
+		"atx_buffer_size": 10,
+		"epoch_blocks": 5,
+		"spooler_instant_reward_threshold": 10,
+		"spooler_batched_reward_threshold": 1
+	},
+	"winning_classes": 5,
+	"payout_freq": "1/4",
+	"pools": [{
+		"utility_name": "FLUID",
+		"pool_size": "10000000000",
+		"token_decimals": "1000000",
+		"exchange_rate": "1",
+		"delta_weight": "31536000"
+	}],
+	"synthetic": {
+		"blocks": 50,
+		"min_transfers": 1,
+		"max_transfers": 4,
+		"min_gas_fee_usd": 0.5,
+		"max_gas_fee_usd": 3
+	}
+}`
+
+func loadTestScenario(t *testing.T) Scenario {
+	var scenario Scenario
+
+	require.NoError(t, json.Unmarshal([]byte(testScenario), &scenario))
+
+	return scenario
+}
+
+func TestRunDeterministic(t *testing.T) {
+	first, err := Run(loadTestScenario(t))
+
+	require.NoError(t, err)
+
+	second, err := Run(loadTestScenario(t))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Summary, second.Summary)
+	assert.Equal(t, first.Blocks, second.Blocks)
+
+	summary := first.Summary
+
+	assert.Len(t, first.Blocks, 50)
+	assert.NotZero(t, summary.Transfers)
+
+	var tierWins int
+
+	for _, tier := range summary.Tiers {
+		tierWins += tier.Wins
+	}
+
+	assert.Equal(t, summary.Wins, tierWins)
+
+	// every win that was paid out was drawn from the pool
+
+	pool := summary.Pools[0]
+
+	assert.InDelta(t, summary.TotalPaidUsd, pool.StartUsd-pool.EndUsd, 0.01)
+
+	assert.InDelta(
+		t,
+		summary.TotalPaidUsd/float64(summary.Transfers),
+		summary.ExpectedPayoutUsd,
+		1e-9,
+	)
+}
+
+func TestRunDoesntChangeScenario(t *testing.T) {
+	scenario := loadTestScenario(t)
+
+	poolSize := new(big.Rat).Set(scenario.Pools[0].PoolSizeNative)
+
+	_, err := Run(scenario)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, poolSize, scenario.Pools[0].PoolSizeNative)
+}
+
+func TestRunEmptyPool(t *testing.T) {
+	scenario := loadTestScenario(t)
+
+	scenario.Pools[0].PoolSizeNative = new(big.Rat)
+
+	report, err := Run(scenario)
+
+	require.NoError(t, err)
+
+	assert.Zero(t, report.Summary.Transfers)
+	assert.NotZero(t, report.Summary.TransfersWithoutPools)
+}
+
+func TestRunInvalid(t *testing.T) {
+	scenario := loadTestScenario(t)
+
+	scenario.WorkerConfig.EpochBlocks = 0
+
+	_, err := Run(scenario)
+
+	assert.Error(t, err)
+}
+
+func TestWriteCsv(t *testing.T) {
+	scenario := loadTestScenario(t)
+
+	scenario.Synthetic.Blocks = 3
+
+	report, err := Run(scenario)
+
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, report.WriteBlocksCsv(&buf))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+
+	require.NoError(t, err)
+
+	assert.Len(t, rows, 4)
+	assert.Equal(t, "pool_FLUID_usd", rows[0][len(rows[0])-1])
+
+	buf.Reset()
+
+	require.NoError(t, report.WriteSummaryCsv(&buf))
+
+	rows, err = csv.NewReader(&buf).ReadAll()
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"metric", "value"}, rows[0])
+}
+
+func TestPoolSizeUsd(t *testing.T) {
+	pool := worker.UtilityVars{
+		Name:               applications.UtilityFluid,
+		PoolSizeNative:     big.NewRat(5000000, 1),
+		TokenDecimalsScale: big.NewRat(1000000, 1),
+		ExchangeRate:       big.NewRat(2, 1),
+	}
+
+	assert.Equal(t, 10., poolSizeUsd(pool))
+}
@@ -0,0 +1,22 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this source
+// code is governed by a Creative Commons license that can be found in the
+// LICENSE_TRF.md file.
+
+// trf holds the default TRF parameters used by the worker servers, with
+// no other dependencies, so that offline tools like the payout simulator
+// can use them without connecting to any infrastructure.
+package trf
+
+const (
+	// WinningClasses (balls drawn) for a normal transfer
+	WinningClasses = 5
+
+	// DeltaWeightNum over DeltaWeightDenom is the default delta weight
+	DeltaWeightNum   = int64(31536000)
+	DeltaWeightDenom = int64(1)
+
+	// PayoutFreqNum over PayoutFreqDenom is the payout frequency for a
+	// normal transfer
+	PayoutFreqNum   = int64(1)
+	PayoutFreqDenom = int64(4)
+)
@@ -29,6 +29,19 @@ const aaveLendingPoolAddressProviderAbiString = `[
 	  ],
       "stateMutability": "view",
       "type": "function"
+    },
+    {
+      "inputs": [],
+      "name": "getPool",
+      "outputs": [
+		  {
+			  "internalType": "address",
+			  "name": "",
+			  "type": "address"
+		  }
+	  ],
+      "stateMutability": "view",
+      "type": "function"
     }
 ]`
 
@@ -109,6 +122,101 @@ const aaveLendingPoolAbiString = `[
 	}
 ]`
 
+// aaveV3PoolAbiString has the ReserveData struct returned by
+// getReserveData flattened, which is encoded the same way since every
+// field is static
+const aaveV3PoolAbiString = `[
+	{
+		"inputs": [
+			{
+				"internalType": "address",
+				"name": "asset",
+				"type": "address"
+			}
+		],
+		"name": "getReserveData",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "configuration",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint128",
+				"name": "liquidityIndex",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint128",
+				"name": "currentLiquidityRate",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint128",
+				"name": "variableBorrowIndex",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint128",
+				"name": "currentVariableBorrowRate",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint128",
+				"name": "currentStableBorrowRate",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint40",
+				"name": "lastUpdateTimestamp",
+				"type": "uint40"
+			},
+			{
+				"internalType": "uint16",
+				"name": "id",
+				"type": "uint16"
+			},
+			{
+				"internalType": "address",
+				"name": "aTokenAddress",
+				"type": "address"
+			},
+			{
+				"internalType": "address",
+				"name": "stableDebtTokenAddress",
+				"type": "address"
+			},
+			{
+				"internalType": "address",
+				"name": "variableDebtTokenAddress",
+				"type": "address"
+			},
+			{
+				"internalType": "address",
+				"name": "interestRateStrategyAddress",
+				"type": "address"
+			},
+			{
+				"internalType": "uint128",
+				"name": "accruedToTreasury",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint128",
+				"name": "unbacked",
+				"type": "uint128"
+			},
+			{
+				"internalType": "uint128",
+				"name": "isolationModeTotalDebt",
+				"type": "uint128"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
 const aaveATokenAbiString = `[
 	{
 		"inputs": [
@@ -143,6 +251,38 @@ var aTokenAbi ethAbi.ABI
 // ABI code that can be used with a bound contract
 var lendingPoolAbi ethAbi.ABI
 
+// v3PoolAbi set by init.go to contain the aave v3 pool ABI code that can
+// be used with a bound contract
+var v3PoolAbi ethAbi.ABI
+
+// reserveLayout of the results of getReserveData, which moved between
+// versions
+type reserveLayout struct {
+	// poolMethod to call on the address provider to get the pool
+	poolMethod string
+
+	poolAbi *ethAbi.ABI
+
+	liquidityRate int
+	aTokenAddress int
+}
+
+var (
+	reserveLayoutV2 = reserveLayout{
+		poolMethod:    "getLendingPool",
+		poolAbi:       &lendingPoolAbi,
+		liquidityRate: 3,
+		aTokenAddress: 7,
+	}
+
+	reserveLayoutV3 = reserveLayout{
+		poolMethod:    "getPool",
+		poolAbi:       &v3PoolAbi,
+		liquidityRate: 2,
+		aTokenAddress: 8,
+	}
+)
+
 // GetBalanceOf using an atoken contract gets the user's current balance,
 // accounting for interest
 func GetBalanceOf(client *ethclient.Client, aTokenAddress, contractAddress ethCommon.Address) (*big.Rat, error) {
@@ -203,8 +343,28 @@ func getAaveAddress(client *ethclient.Client, addressProvider ethCommon.Address,
 	return address, nil
 }
 
+// GetTokenApy for depositing the underlying token into an Aave V2 lending pool
 func GetTokenApy(client *ethclient.Client, addressProvider, underlying ethCommon.Address, emission *worker.Emission) (*big.Rat, error) {
-	lendingPool, err := getAaveAddress(client, addressProvider, "getLendingPool")
+	return getTokenApy(client, reserveLayoutV2, addressProvider, underlying, emission)
+}
+
+// GetTokenApyV3 for depositing the underlying token into an Aave V3 pool
+func GetTokenApyV3(client *ethclient.Client, addressProvider, underlying ethCommon.Address, emission *worker.Emission) (*big.Rat, error) {
+	return getTokenApy(client, reserveLayoutV3, addressProvider, underlying, emission)
+}
+
+// GetATokenAddress of the underlying token in an Aave V2 lending pool
+func GetATokenAddress(client *ethclient.Client, addressProvider, underlying ethCommon.Address) (ethCommon.Address, error) {
+	return getATokenAddress(client, reserveLayoutV2, addressProvider, underlying)
+}
+
+// GetATokenAddressV3 of the underlying token in an Aave V3 pool
+func GetATokenAddressV3(client *ethclient.Client, addressProvider, underlying ethCommon.Address) (ethCommon.Address, error) {
+	return getATokenAddress(client, reserveLayoutV3, addressProvider, underlying)
+}
+
+func getReserveData(client *ethclient.Client, layout reserveLayout, addressProvider, underlying ethCommon.Address) ([]interface{}, error) {
+	lendingPool, err := getAaveAddress(client, addressProvider, layout.poolMethod)
 
 	if err != nil {
 		return nil, fmt.Errorf(
@@ -216,7 +376,7 @@ func GetTokenApy(client *ethclient.Client, addressProvider, underlying ethCommon
 	reserveResults, err := ethereum.StaticCall(
 		client,
 		lendingPool,
-		lendingPoolAbi,
+		*layout.poolAbi,
 		"getReserveData",
 		underlying,
 	)
@@ -228,7 +388,35 @@ func GetTokenApy(client *ethclient.Client, addressProvider, underlying ethCommon
 		)
 	}
 
-	liquidityRateResult, ok := reserveResults[3].(*big.Int)
+	return reserveResults, nil
+}
+
+func getATokenAddress(client *ethclient.Client, layout reserveLayout, addressProvider, underlying ethCommon.Address) (ethCommon.Address, error) {
+	var aTokenAddress ethCommon.Address
+
+	reserveResults, err := getReserveData(client, layout, addressProvider, underlying)
+
+	if err != nil {
+		return aTokenAddress, err
+	}
+
+	aTokenAddress, ok := reserveResults[layout.aTokenAddress].(ethCommon.Address)
+
+	if !ok {
+		return aTokenAddress, fmt.Errorf("could not read the atoken address as an address!")
+	}
+
+	return aTokenAddress, nil
+}
+
+func getTokenApy(client *ethclient.Client, layout reserveLayout, addressProvider, underlying ethCommon.Address, emission *worker.Emission) (*big.Rat, error) {
+	reserveResults, err := getReserveData(client, layout, addressProvider, underlying)
+
+	if err != nil {
+		return nil, err
+	}
+
+	liquidityRateResult, ok := reserveResults[layout.liquidityRate].(*big.Int)
 
 	if !ok {
 		return nil, fmt.Errorf("could not read liquidity rate as a uint128!")
@@ -30,4 +30,10 @@ func init() {
 	if lendingPoolAbi, err = ethAbi.JSON(lendingPoolAbiReader); err != nil {
 		panic(err)
 	}
+
+	v3PoolAbiReader := strings.NewReader(aaveV3PoolAbiString)
+
+	if v3PoolAbi, err = ethAbi.JSON(v3PoolAbiReader); err != nil {
+		panic(err)
+	}
 }
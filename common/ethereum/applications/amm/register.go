@@ -0,0 +1,29 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package amm
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationSeawaterAmm,
+		Name:        "seawater_amm",
+		Topics:      []string{ammSwap1LogTopic, ammSwap2LogTopic},
+		EmissionKey: "seawater_amm",
+
+		Fee: func(args registry.FeeArgs) (libApps.ApplicationFeeData, libApps.ApplicationData, error) {
+			return GetAmmFees(
+				args.Transfer,
+				args.Client,
+				args.FluidTokenContract,
+				args.TokenDecimals,
+			)
+		},
+	})
+}
@@ -12,31 +12,34 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
 	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
 	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
-	libEthereum "github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/misc"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/amm"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/apeswap"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/balancer"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/camelot"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/chronos"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/curve"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/dodo"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/gtrade"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/kyber"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/lifi"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/meson"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/odos"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/oneinch"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/paraswap"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/saddle"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/sushiswap"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/trader-joe"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/uniswap"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/wombat"
-	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/xy-finance"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+
+	// applications register themselves with the registry when imported
+
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/amm"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/apeswap"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/balancer"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/camelot"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/chronos"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/curve"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/dodo"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/gtrade"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/kyber"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/lifi"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/meson"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/odos"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/oneinch"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/paraswap"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/saddle"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/sushiswap"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/trader-joe"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/uniswap"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/wombat"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/xy-finance"
 
 	ethCommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -44,42 +47,40 @@ import (
 
 type Application = libApps.Application
 
-// Applications supported via the app. refer to
-// lib/types/applications/applications.go for the stringified
-// implementation. Tests depend on the number in this iota, and the
-// frontend/database depends on the stringified representation.
+// Applications supported via the app, shadowing lib/types/applications.
+// New applications should register themselves with the registry
+// package instead of being added here.
 const (
-	// ApplicationNone is the nil value representing a transfer.
-	ApplicationNone libApps.Application = iota
-	ApplicationUniswapV3
-	ApplicationUniswapV2
-	ApplicationBalancerV2
-	ApplicationOneInchLPV2
-	ApplicationOneInchLPV1
-	ApplicationMooniswap
-	ApplicationOneInchFixedRateSwap
-	ApplicationDodoV2
-	ApplicationCurve
-	ApplicationMultichain
-	ApplicationXyFinance
-	ApplicationApeswap
-	ApplicationSaddle
-	ApplicationGTradeV6_1
-	ApplicationMeson
-	ApplicationCamelot
-	ApplicationChronos
-	ApplicationSushiswap
-	ApplicationKyberClassic
-	ApplicationWombat
-	ApplicationSeawaterAmm
-	ApplicationTraderJoe
-	ApplicationRamses
-	ApplicationJumper
-	ApplicationCamelotV3
-	ApplicationLifi
-	ApplicationOdos
-	ApplicationBetSwirl
-	ApplicationParaswap
+	ApplicationNone                 = libApps.ApplicationNone
+	ApplicationUniswapV3            = libApps.ApplicationUniswapV3
+	ApplicationUniswapV2            = libApps.ApplicationUniswapV2
+	ApplicationBalancerV2           = libApps.ApplicationBalancerV2
+	ApplicationOneInchLPV2          = libApps.ApplicationOneInchLPV2
+	ApplicationOneInchLPV1          = libApps.ApplicationOneInchLPV1
+	ApplicationMooniswap            = libApps.ApplicationMooniswap
+	ApplicationOneInchFixedRateSwap = libApps.ApplicationOneInchFixedRateSwap
+	ApplicationDodoV2               = libApps.ApplicationDodoV2
+	ApplicationCurve                = libApps.ApplicationCurve
+	ApplicationMultichain           = libApps.ApplicationMultichain
+	ApplicationXyFinance            = libApps.ApplicationXyFinance
+	ApplicationApeswap              = libApps.ApplicationApeswap
+	ApplicationSaddle               = libApps.ApplicationSaddle
+	ApplicationGTradeV6_1           = libApps.ApplicationGTradeV6_1
+	ApplicationMeson                = libApps.ApplicationMeson
+	ApplicationCamelot              = libApps.ApplicationCamelot
+	ApplicationChronos              = libApps.ApplicationChronos
+	ApplicationSushiswap            = libApps.ApplicationSushiswap
+	ApplicationKyberClassic         = libApps.ApplicationKyberClassic
+	ApplicationWombat               = libApps.ApplicationWombat
+	ApplicationSeawaterAmm          = libApps.ApplicationSeawaterAmm
+	ApplicationTraderJoe            = libApps.ApplicationTraderJoe
+	ApplicationRamses               = libApps.ApplicationRamses
+	ApplicationJumper               = libApps.ApplicationJumper
+	ApplicationCamelotV3            = libApps.ApplicationCamelotV3
+	ApplicationLifi                 = libApps.ApplicationLifi
+	ApplicationOdos                 = libApps.ApplicationOdos
+	ApplicationBetSwirl             = libApps.ApplicationBetSwirl
+	ApplicationParaswap             = libApps.ApplicationParaswap
 )
 
 // ParseApplicationName shadows the lib types definition
@@ -96,241 +97,31 @@ func GetApplicationFee(transfer worker.EthereumApplicationTransfer, client *ethc
 		feeData  applications.ApplicationFeeData
 		appData  applications.ApplicationData
 		emission worker.EthereumAppFees
-		err      error
 	)
 
 	// returning the default feeData and no error implies that we succeeded with no fee!
 
-	switch transfer.Application {
-	case ApplicationUniswapV3:
-		feeData, err = uniswap.GetUniswapV3Fees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.UniswapV3 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationSushiswap:
-		feeData, err = sushiswap.GetSushiswapFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-		emission.Sushiswap += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationUniswapV2:
-		feeData, err = uniswap.GetUniswapV2Fees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.UniswapV2 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationBalancerV2:
-		feeData, err = balancer.GetBalancerFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.BalancerV2 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationOneInchLPV2:
-		feeData, err = oneinch.GetOneInchLPFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.OneInchV2 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationOneInchLPV1:
-		feeData, err = oneinch.GetOneInchLPFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.OneInchV1 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationMooniswap:
-		feeData, err = oneinch.GetMooniswapV1Fees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.Mooniswap += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationOneInchFixedRateSwap:
-		feeData, err = oneinch.GetFixedRateSwapFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.OneInchFixedRate += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationDodoV2:
-		feeData, err = dodo.GetDodoV2Fees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-			txReceipt,
-		)
-
-		emission.DodoV2 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationCurve:
-		feeData, err = curve.GetCurveSwapFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.Curve += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationXyFinance:
-		feeData, err = xy_finance.GetXyFinanceSwapFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-			txReceipt,
-		)
-
-		emission.XyFinance += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationApeswap:
-		feeData, err = apeswap.GetApeswapFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.Apeswap += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationSaddle:
-		feeData, err = saddle.GetSaddleFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-			txReceipt,
-		)
-
-		emission.Saddle += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationGTradeV6_1:
-		feeData, err = gtrade.GetGtradeV6_1Fees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-			txReceipt,
-		)
-
-		emission.GTradeV6_1 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationMeson:
-		feeData, err = meson.GetMesonFees(
-			transfer,
-			inputData,
-		)
-
-		emission.Meson += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationCamelot:
-		feeData, err = camelot.GetCamelotFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.Camelot += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationCamelotV3:
-		feeData, err = camelot.GetCamelotV3Fees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
+	registration, found := registry.Get(transfer.Application)
 
-		emission.CamelotV3 += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationChronos:
-		feeData, err = chronos.GetChronosFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.Chronos += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationKyberClassic:
-		feeData, err = kyberClassic.GetKyberClassicFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-
-		emission.KyberClassic += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationWombat:
-		feeData, err = wombat.GetWombatFees(
+	if !found {
+		err := fmt.Errorf(
+			"Transfer #%v did not contain an application",
 			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
 		)
 
-		emission.Wombat += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationSeawaterAmm:
-		feeData, appData, err = amm.GetAmmFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-		emission.SeawaterAmm += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationTraderJoe:
-		feeData, err = trader_joe.GetTraderJoeFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-		emission.TraderJoe += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationLifi:
-		feeData, err = lifi.GetLifiFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-		emission.Lifi += util.MaybeRatToFloat(feeData.Fee)
-	case ApplicationOdos:
-		feeData, err = odos.GetOdosFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-		emission.Odos += util.MaybeRatToFloat(feeData.Fee)
+		return feeData, appData, emission, err
+	}
 
-	case ApplicationParaswap:
-		feeData, err = paraswap.GetParaswapFees(
-			transfer,
-			client,
-			fluidTokenContract,
-			tokenDecimals,
-		)
-		emission.Paraswap += util.MaybeRatToFloat(feeData.Fee)
+	feeData, appData, err := registration.Fee(registry.FeeArgs{
+		Transfer:           transfer,
+		Client:             client,
+		FluidTokenContract: fluidTokenContract,
+		TokenDecimals:      tokenDecimals,
+		Receipt:            txReceipt,
+		InputData:          inputData,
+	})
 
-	default:
-		err = fmt.Errorf(
-			"Transfer #%v did not contain an application",
-			transfer,
-		)
-	}
+	emission.Add(registration.EmissionKey, util.MaybeRatToFloat(feeData.Fee))
 
 	return feeData, appData, emission, err
 }
@@ -339,116 +130,23 @@ func GetApplicationFee(transfer worker.EthereumApplicationTransfer, client *ethc
 // In the case of an AMM (such as Uniswap) the transaction sender receives the majority payout every time,
 // with the recipient tokens being effectively burnt (sent to the contract). In the case of a P2P swap,
 // such as a DEX, the party sending the fluid tokens receives the majority payout.
-func GetApplicationTransferParties(transaction ethereum.Transaction, transfer worker.EthereumApplicationTransfer) (libEthereum.Address, libEthereum.Address, error) {
-	var (
-		logAddress      = transfer.Log.Address
-		contractAddress = transaction.To
-		nilAddress      libEthereum.Address
-	)
+func GetApplicationTransferParties(transaction ethereum.Transaction, transfer worker.EthereumApplicationTransfer) (ethereum.Address, ethereum.Address, error) {
+	var nilAddress ethereum.Address
 
-	switch transfer.Application {
-	case ApplicationUniswapV2,
-		ApplicationUniswapV3:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and the rest to the Uniswap contract
-		return transaction.From, logAddress, nil
-	case ApplicationOneInchLPV2,
-		ApplicationOneInchLPV1,
-		ApplicationMooniswap,
-		ApplicationOneInchFixedRateSwap:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		return transaction.From, logAddress, nil
-	case ApplicationBalancerV2:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and the rest to the Balancer Vault
-		return transaction.From, logAddress, nil
-	case ApplicationDodoV2:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and the rest to the Dodo Pool
-		return transaction.From, logAddress, nil
-	case ApplicationCurve:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationXyFinance:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationApeswap:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationSaddle:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationGTradeV6_1:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationMeson:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		mesonSender, err := meson.GetInitiator(transaction.Data)
-		if err != nil {
-			return libEthereum.ZeroAddress, libEthereum.ZeroAddress, err
-		}
+	registration, found := registry.Get(transfer.Application)
 
-		mesonSenderAddress := libEthereum.AddressFromString(mesonSender)
-
-		return mesonSenderAddress, contractAddress, nil
-	case ApplicationCamelot, ApplicationCamelotV3:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationChronos:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationSushiswap:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationKyberClassic:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationWombat:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationSeawaterAmm:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool (switched to the LPs)
-		return transaction.From, logAddress, nil
-	case ApplicationTraderJoe:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationLifi:
-		// Give the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationOdos:
-		// Gave the majority payout to the swap-maker (i.e. transaction sender)
-		// and rest to pool
-		return transaction.From, logAddress, nil
-	case ApplicationParaswap:
-		// Give the majority payout to the initiator of the transaction, and the
-		// rest to the pool.
-
-		// Assuming that the initiator of the transaction is the
-		// initiator. This might not hold up in practice - the
-		// contracts are not fully open source, and the only
-		// function signature we've seen is simpleSwap.
-
-		return transaction.From, contractAddress, nil
-	default:
+	if !found {
 		return nilAddress, nilAddress, fmt.Errorf(
 			"Transfer #%v did not contain an application",
 			transfer,
 		)
 	}
+
+	if registration.Parties == nil {
+		return registry.DefaultParties(transaction, transfer)
+	}
+
+	return registration.Parties(transaction, transfer)
 }
 
 // AppsListFromEnvOrFatal parses a list of `app:address:address,app:address:address` into a map of {address => app}
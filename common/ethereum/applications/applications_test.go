@@ -13,6 +13,8 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/types/misc"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
 )
 
 func TestGetApplicationFee(t *testing.T) {
@@ -109,3 +111,60 @@ func TestGetApplicationTransferParties(t *testing.T) {
 	assert.Equal(t, transactionSender, sender)
 	assert.Equal(t, logAddress, receiver)
 }
+
+// TestRegistrations to make sure every application with a fee function
+// is registered under the name and number it's stored with
+func TestRegistrations(t *testing.T) {
+	registered := []Application{
+		ApplicationUniswapV3,
+		ApplicationUniswapV2,
+		ApplicationBalancerV2,
+		ApplicationOneInchLPV2,
+		ApplicationOneInchLPV1,
+		ApplicationMooniswap,
+		ApplicationOneInchFixedRateSwap,
+		ApplicationDodoV2,
+		ApplicationCurve,
+		ApplicationXyFinance,
+		ApplicationApeswap,
+		ApplicationSaddle,
+		ApplicationGTradeV6_1,
+		ApplicationMeson,
+		ApplicationCamelot,
+		ApplicationChronos,
+		ApplicationSushiswap,
+		ApplicationKyberClassic,
+		ApplicationWombat,
+		ApplicationSeawaterAmm,
+		ApplicationTraderJoe,
+		ApplicationCamelotV3,
+		ApplicationLifi,
+		ApplicationOdos,
+		ApplicationParaswap,
+	}
+
+	assert.Len(t, registry.All(), len(registered))
+
+	for _, app := range registered {
+		registration, found := registry.Get(app)
+
+		if !assert.True(t, found, "%v isn't registered", app) {
+			continue
+		}
+
+		assert.Equal(t, app.String(), registration.Name)
+
+		assert.True(
+			t,
+			worker.IsEthereumAppFeesField(registration.EmissionKey),
+			"%v has emission key %#v",
+			app,
+			registration.EmissionKey,
+		)
+	}
+
+	for _, app := range []Application{ApplicationNone, ApplicationMultichain, ApplicationRamses, ApplicationJumper, ApplicationBetSwirl} {
+		_, found := registry.Get(app)
+		assert.False(t, found, "%v is registered", app)
+	}
+}
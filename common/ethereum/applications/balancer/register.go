@@ -0,0 +1,21 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package balancer
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationBalancerV2,
+		Name:        "balancer_v2",
+		Topics:      []string{balancerSwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetBalancerFees),
+		EmissionKey: "balancer_v2",
+	})
+}
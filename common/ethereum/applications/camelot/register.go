@@ -0,0 +1,29 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package camelot
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationCamelot,
+		Name:        "camelot",
+		Topics:      []string{camelotSwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetCamelotFees),
+		EmissionKey: "camelot",
+	})
+
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationCamelotV3,
+		Name:        "camelot_v3",
+		Topics:      []string{camelotV3SwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetCamelotV3Fees),
+		EmissionKey: "camelot_v3",
+	})
+}
@@ -0,0 +1,21 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package dodo
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationDodoV2,
+		Name:        "dodo_v2",
+		Topics:      []string{dodoV2DODOSwapLogTopic},
+		Fee:         registry.FeeFromReceipt(GetDodoV2Fees),
+		EmissionKey: "dodo_v2",
+	})
+}
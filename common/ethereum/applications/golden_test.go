@@ -0,0 +1,322 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package applications
+
+// Golden file tests for every application with fixtures recorded in
+// testdata/golden/<application>/. Each fixture contains the log,
+// transaction, receipt and RPC responses a decoder needs, and the
+// output it produced when the fixture was last updated.
+//
+// To update the expected outputs after an intentional change, run
+//
+//     go test ./common/ethereum/applications -run TestGolden -update
+//
+// To record the fixtures again from a node (for the transaction and log
+// index in each file), set FLU_ETHEREUM_HTTP_URL and run with -record.
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	libEthereum "github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+	testUtils "github.com/fluidity-money/fluidity-app/tests/integrations/ethereum/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// EnvEthereumHttpUrl to record fixtures from with -record
+const EnvEthereumHttpUrl = `FLU_ETHEREUM_HTTP_URL`
+
+var (
+	flagUpdateGolden = flag.Bool(
+		"update",
+		false,
+		"update the expected outputs of the golden files",
+	)
+
+	flagRecordGolden = flag.Bool(
+		"record",
+		false,
+		"record the golden file fixtures from the node at "+EnvEthereumHttpUrl+", implies -update",
+	)
+)
+
+type (
+	goldenFixture struct {
+		Application        string                  `json:"application"`
+		TokenDecimals      int                     `json:"token_decimals"`
+		FluidTokenContract ethereum.Address        `json:"fluid_token_contract"`
+		Transaction        ethereum.Transaction    `json:"transaction"`
+		Log                ethereum.Log            `json:"log"`
+		Receipt            *ethereum.Receipt       `json:"receipt,omitempty"`
+		Rpc                []testUtils.RpcExchange `json:"rpc"`
+		Expected           goldenExpected          `json:"expected"`
+	}
+
+	goldenExpected struct {
+		Fee             string                  `json:"fee,omitempty"`
+		Volume          string                  `json:"volume,omitempty"`
+		ApplicationData libApps.ApplicationData `json:"application_data"`
+		Emission        worker.EthereumAppFees  `json:"emission"`
+		Sender          ethereum.Address        `json:"sender"`
+		Recipient       ethereum.Address        `json:"recipient"`
+		Error           string                  `json:"error,omitempty"`
+	}
+)
+
+// goldenFixturesMissing for applications that don't have a transaction
+// recorded yet. Record one with -record and remove the application here
+var goldenFixturesMissing = map[string]bool{
+	"curve":             true,
+	"gtrade_v6_1":       true,
+	"oneinch_fixedrate": true,
+	"oneinch_v1":        true,
+	"oneinch_v2":        true,
+	"seawater_amm":      true,
+	"xy_finance":        true,
+}
+
+// TestGoldenCoverage to fail if a registered application doesn't have a
+// golden fixture
+func TestGoldenCoverage(t *testing.T) {
+	for _, registration := range registry.All() {
+		name := registration.Name
+
+		files, err := filepath.Glob(filepath.Join("testdata", "golden", name, "*.json"))
+
+		require.NoError(t, err)
+
+		if goldenFixturesMissing[name] {
+			assert.Empty(t, files, "%v has golden fixtures, remove it from goldenFixturesMissing", name)
+			continue
+		}
+
+		assert.NotEmpty(t, files, "%v has no golden fixtures in testdata/golden/%v", name, name)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "golden", "*", "*.json"))
+
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		file := file
+
+		name := strings.TrimSuffix(
+			strings.TrimPrefix(file, filepath.Join("testdata", "golden")+string(filepath.Separator)),
+			".json",
+		)
+
+		t.Run(name, func(t *testing.T) {
+			testGoldenFile(t, file)
+		})
+	}
+}
+
+func testGoldenFile(t *testing.T, file string) {
+	fixture := readGoldenFixture(t, file)
+
+	app, err := ParseApplicationName(fixture.Application)
+
+	require.NoError(t, err)
+
+	var client *ethclient.Client
+
+	if *flagRecordGolden {
+		var recorder *testUtils.RpcRecorder
+
+		client, recorder = recordGoldenFixture(t, &fixture)
+
+		defer func() {
+			fixture.Rpc = recorder.Exchanges()
+			writeGoldenFixture(t, file, fixture)
+		}()
+	} else {
+		var closeClient func()
+
+		client, closeClient, err = testUtils.ReplayRpcClient(fixture.Rpc)
+
+		require.NoError(t, err)
+
+		t.Cleanup(closeClient)
+	}
+
+	got := runGoldenFixture(app, client, fixture)
+
+	if *flagUpdateGolden || *flagRecordGolden {
+		fixture.Expected = got
+
+		if !*flagRecordGolden {
+			writeGoldenFixture(t, file, fixture)
+		}
+
+		return
+	}
+
+	assert.Equal(t, fixture.Expected, got)
+}
+
+// runGoldenFixture to get the output of the application's decoder for
+// the fixture
+func runGoldenFixture(app Application, client *ethclient.Client, fixture goldenFixture) goldenExpected {
+	var (
+		got     goldenExpected
+		receipt ethereum.Receipt
+	)
+
+	if fixture.Receipt != nil {
+		receipt = *fixture.Receipt
+	}
+
+	transfer := worker.EthereumApplicationTransfer{
+		TransactionHash: fixture.Transaction.Hash,
+		Log:             fixture.Log,
+		Application:     app,
+	}
+
+	fluidTokenContract := libEthereum.ConvertInternalAddress(fixture.FluidTokenContract)
+
+	feeData, appData, emission, err := GetApplicationFee(
+		transfer,
+		client,
+		fluidTokenContract,
+		fixture.TokenDecimals,
+		receipt,
+		fixture.Transaction.Data,
+	)
+
+	if err != nil {
+		got.Error = err.Error()
+		return got
+	}
+
+	got.Fee = ratString(feeData.Fee)
+	got.Volume = ratString(feeData.Volume)
+	got.ApplicationData = appData
+	got.Emission = emission
+
+	got.Sender, got.Recipient, err = GetApplicationTransferParties(
+		fixture.Transaction,
+		transfer,
+	)
+
+	if err != nil {
+		got.Error = err.Error()
+	}
+
+	return got
+}
+
+// recordGoldenFixture to fetch the transaction, log and receipt of the
+// fixture again, returning a client to record the decoder's requests with
+func recordGoldenFixture(t *testing.T, fixture *goldenFixture) (*ethclient.Client, *testUtils.RpcRecorder) {
+	url := os.Getenv(EnvEthereumHttpUrl)
+
+	require.NotEmpty(t, url, "%v must be set to record fixtures", EnvEthereumHttpUrl)
+
+	node, err := ethclient.Dial(url)
+
+	require.NoError(t, err)
+
+	defer node.Close()
+
+	var (
+		ctx             = context.Background()
+		transactionHash = libEthereum.ConvertInternalHash(fixture.Transaction.Hash)
+	)
+
+	gethReceipt, err := node.TransactionReceipt(ctx, transactionHash)
+
+	require.NoError(t, err)
+
+	gethTransaction, _, err := node.TransactionByHash(ctx, transactionHash)
+
+	require.NoError(t, err)
+
+	signer := ethTypes.LatestSignerForChainID(gethTransaction.ChainId())
+
+	sender, err := ethTypes.Sender(signer, gethTransaction)
+
+	require.NoError(t, err)
+
+	receipt := libEthereum.ConvertGethReceipt(*gethReceipt)
+
+	var found bool
+
+	for _, log := range receipt.Logs {
+		if log.Index.Cmp(&fixture.Log.Index.Int) == 0 {
+			fixture.Log = log
+			found = true
+		}
+	}
+
+	require.True(t, found, "log %v not in the receipt", fixture.Log.Index.String())
+
+	fixture.Receipt = &receipt
+
+	fixture.Transaction.From = libEthereum.ConvertGethAddress(sender)
+	fixture.Transaction.Data = gethTransaction.Data()
+
+	if to := gethTransaction.To(); to != nil {
+		fixture.Transaction.To = libEthereum.ConvertGethAddress(*to)
+	}
+
+	client, recorder, closeClient, err := testUtils.RecordRpcClient(url)
+
+	require.NoError(t, err)
+
+	t.Cleanup(closeClient)
+
+	return client, recorder
+}
+
+func readGoldenFixture(t *testing.T, file string) goldenFixture {
+	var fixture goldenFixture
+
+	content, err := os.ReadFile(file)
+
+	require.NoError(t, err)
+
+	err = json.Unmarshal(content, &fixture)
+
+	require.NoError(t, err, "decoding %v", file)
+
+	return fixture
+}
+
+func writeGoldenFixture(t *testing.T, file string, fixture goldenFixture) {
+	content, err := json.MarshalIndent(fixture, "", "\t")
+
+	require.NoError(t, err)
+
+	err = os.WriteFile(file, append(content, '\n'), 0644)
+
+	require.NoError(t, err)
+}
+
+func ratString(rat *big.Rat) string {
+	if rat == nil {
+		return ""
+	}
+
+	return fmt.Sprint(rat)
+}
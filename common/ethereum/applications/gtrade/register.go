@@ -0,0 +1,21 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package gtrade
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationGTradeV6_1,
+		Name:        "gtrade_v6_1",
+		Topics:      []string{gtradeV6_1FeesChargedLogTopic},
+		Fee:         registry.FeeFromReceipt(GetGtradeV6_1Fees),
+		EmissionKey: "gtrade_v6_1",
+	})
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package meson
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationMeson,
+		Name:        "meson",
+		EmissionKey: "meson",
+
+		Fee: func(args registry.FeeArgs) (libApps.ApplicationFeeData, libApps.ApplicationData, error) {
+			var appData libApps.ApplicationData
+
+			feeData, err := GetMesonFees(args.Transfer, args.InputData)
+
+			return feeData, appData, err
+		},
+
+		// Give the majority payout to the initiator of the swap (from
+		// the input data) and the rest to the contract
+		Parties: func(transaction ethereum.Transaction, transfer worker.EthereumApplicationTransfer) (ethereum.Address, ethereum.Address, error) {
+			mesonSender, err := GetInitiator(transaction.Data)
+
+			if err != nil {
+				return ethereum.ZeroAddress, ethereum.ZeroAddress, err
+			}
+
+			mesonSenderAddress := ethereum.AddressFromString(mesonSender)
+
+			return mesonSenderAddress, transaction.To, nil
+		},
+	})
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package oneinch
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationOneInchLPV2,
+		Name:        "oneinch_v2",
+		Topics:      []string{oneInchLPV2SwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetOneInchLPFees),
+		EmissionKey: "oneinch_v2",
+	})
+
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationOneInchLPV1,
+		Name:        "oneinch_v1",
+		Topics:      []string{oneInchLPV2SwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetOneInchLPFees),
+		EmissionKey: "oneinch_v1",
+	})
+
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationMooniswap,
+		Name:        "mooniswap",
+		Topics:      []string{mooniswapSwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetMooniswapV1Fees),
+		EmissionKey: "mooniswap",
+	})
+
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationOneInchFixedRateSwap,
+		Name:        "oneinch_fixedrate",
+		Topics:      []string{oneInchFixedRateSwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetFixedRateSwapFees),
+		EmissionKey: "oneinch_fixedrate",
+	})
+}
@@ -0,0 +1,32 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package paraswap
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationParaswap,
+		Name:        "paraswap",
+		Topics:      []string{paraswapSwappedV3Topic, paraswapBoughtV3Topic},
+		Fee:         registry.FeeFromTransfer(GetParaswapFees),
+		EmissionKey: "paraswap",
+		Aggregator:  true,
+
+		// Assuming that the initiator of the transaction is the
+		// initiator. This might not hold up in practice - the
+		// contracts are not fully open source, and the only
+		// function signature we've seen is simpleSwap.
+		Parties: func(transaction ethereum.Transaction, _ worker.EthereumApplicationTransfer) (ethereum.Address, ethereum.Address, error) {
+			return transaction.From, transaction.To, nil
+		},
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// registry contains the applications supported on Ethereum, each
+// registering itself from an init function in its own package so that
+// integrating a new protocol only needs the new package.
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type (
+	// FeeArgs passed to an application to compute the fee paid by the
+	// user in an application transfer
+	FeeArgs struct {
+		Transfer           worker.EthereumApplicationTransfer
+		Client             *ethclient.Client
+		FluidTokenContract ethCommon.Address
+		TokenDecimals      int
+		Receipt            ethereum.Receipt
+		InputData          misc.Blob
+	}
+
+	// FeeFunc returns the fee paid by the user, returning a nil fee if
+	// the log is legitimate but doesn't involve the fluid token
+	FeeFunc func(args FeeArgs) (libApps.ApplicationFeeData, libApps.ApplicationData, error)
+
+	// PartiesFunc returns the sender and recipient to be considered for
+	// payout from an application transfer
+	PartiesFunc func(transaction ethereum.Transaction, transfer worker.EthereumApplicationTransfer) (sender ethereum.Address, recipient ethereum.Address, err error)
+
+	// Registration of an application
+	Registration struct {
+		// Application number, which is stored in the database and
+		// should never change
+		Application libApps.Application
+
+		// Name of the application, used in the environment and the
+		// database
+		Name string
+
+		// Topics of the events handled by Fee, logs with a different
+		// first topic are skipped. If empty, every log is passed on
+		Topics []string
+
+		// Fee to compute the fee paid by the user
+		Fee FeeFunc
+
+		// Parties to find the sender and the recipient, if nil the
+		// transaction sender gets the majority payout and the
+		// contract emitting the log the rest
+		Parties PartiesFunc
+
+		// EmissionKey to track the fee with in worker.EthereumAppFees,
+		// either the json name of one of its fields or a new key
+		EmissionKey string
+
+		// Aggregator if the application routes swaps through other
+		// venues in the same transaction, so it's only credited when
+		// no venue we support touched the fluid token
+		Aggregator bool
+	}
+)
+
+var registrations = make(map[libApps.Application]Registration)
+
+// Register an application, panicking if it's malformed or its number
+// or name are already taken. Should only be called from init
+func Register(registration Registration) {
+	var (
+		app  = registration.Application
+		name = registration.Name
+	)
+
+	if registration.Fee == nil {
+		panic(fmt.Sprintf("application %#v registered without a fee function", name))
+	}
+
+	if registration.EmissionKey == "" {
+		panic(fmt.Sprintf("application %#v registered without an emission key", name))
+	}
+
+	if _, exists := registrations[app]; exists {
+		panic(fmt.Sprintf("application %d (%#v) registered twice", app, name))
+	}
+
+	libApps.Register(app, name)
+
+	topics := make([]string, len(registration.Topics))
+
+	for i, topic := range registration.Topics {
+		topics[i] = strings.ToLower(topic)
+	}
+
+	registration.Topics = topics
+
+	registrations[app] = registration
+}
+
+// Get the registration of an application, returning false if the
+// application wasn't registered
+func Get(app libApps.Application) (Registration, bool) {
+	registration, found := registrations[app]
+
+	return registration, found
+}
+
+// All the applications registered
+func All() []Registration {
+	all := make([]Registration, 0, len(registrations))
+
+	for _, registration := range registrations {
+		all = append(all, registration)
+	}
+
+	return all
+}
+
+// HandlesTopic returns whether the application should be passed logs
+// with the first topic given, true if the application isn't registered
+func HandlesTopic(app libApps.Application, topic string) bool {
+	registration, found := registrations[app]
+
+	if !found || len(registration.Topics) == 0 {
+		return true
+	}
+
+	topic = strings.ToLower(topic)
+
+	for _, handled := range registration.Topics {
+		if handled == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAggregator returns whether the application was registered as an
+// aggregator
+func IsAggregator(app libApps.Application) bool {
+	registration, found := registrations[app]
+
+	return found && registration.Aggregator
+}
+
+// DefaultParties gives the majority payout to the swap-maker (the
+// transaction sender) and the rest to the contract emitting the log
+func DefaultParties(transaction ethereum.Transaction, transfer worker.EthereumApplicationTransfer) (ethereum.Address, ethereum.Address, error) {
+	return transaction.From, transfer.Log.Address, nil
+}
+
+// FeeFromTransfer adapts the common fee function signature taking only
+// the transfer, client, fluid token and its decimals
+func FeeFromTransfer(f func(transfer worker.EthereumApplicationTransfer, client *ethclient.Client, fluidTokenContract ethCommon.Address, tokenDecimals int) (libApps.ApplicationFeeData, error)) FeeFunc {
+	return func(args FeeArgs) (libApps.ApplicationFeeData, libApps.ApplicationData, error) {
+		var appData libApps.ApplicationData
+
+		feeData, err := f(
+			args.Transfer,
+			args.Client,
+			args.FluidTokenContract,
+			args.TokenDecimals,
+		)
+
+		return feeData, appData, err
+	}
+}
+
+// FeeFromReceipt adapts the fee function signature of applications that
+// need the receipt of the transaction
+func FeeFromReceipt(f func(transfer worker.EthereumApplicationTransfer, client *ethclient.Client, fluidTokenContract ethCommon.Address, tokenDecimals int, txReceipt ethereum.Receipt) (libApps.ApplicationFeeData, error)) FeeFunc {
+	return func(args FeeArgs) (libApps.ApplicationFeeData, libApps.ApplicationData, error) {
+		var appData libApps.ApplicationData
+
+		feeData, err := f(
+			args.Transfer,
+			args.Client,
+			args.FluidTokenContract,
+			args.TokenDecimals,
+			args.Receipt,
+		)
+
+		return feeData, appData, err
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// route reconstructs the token movements in a transaction from its
+// Transfer logs, to attribute swaps routed through aggregators to the
+// venue that actually touched the fluid token.
+package route
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+
+	libEthereum "github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+type (
+	// Hop of a token between two addresses, from a Transfer log
+	Hop struct {
+		LogIndex *big.Int
+		Token    ethereum.Address
+		From     ethereum.Address
+		To       ethereum.Address
+		Amount   *big.Int
+	}
+
+	// Route of the tokens moved in a transaction, in log order
+	Route struct {
+		Hops []Hop
+	}
+
+	// Attribution of the application transfers in a transaction
+	Attribution struct {
+		Route Route
+
+		// Transfers to compute fees for in log order, only the
+		// application credited if one moved the fluid token
+		Transfers []worker.EthereumApplicationTransfer
+
+		// Venue that touched the fluid token first,
+		// ApplicationNone if no application did
+		Venue applications.Application
+
+		// Aggregator the swap was routed through, ApplicationNone if
+		// there wasn't one
+		Aggregator applications.Application
+	}
+)
+
+// Reconstruct the route of the tokens moved in a transaction from the
+// Transfer logs in its receipt, skipping logs that aren't ERC20 transfers
+func Reconstruct(receipt ethereum.Receipt) Route {
+	hops := make([]Hop, 0)
+
+	for _, log := range receipt.Logs {
+		topics := log.Topics
+
+		// erc721 transfers have the amount indexed as a fourth topic
+
+		if len(topics) != 3 || len(log.Data) != 32 {
+			continue
+		}
+
+		if !libEthereum.IsTransferLogTopic(strings.ToLower(topics[0].String())) {
+			continue
+		}
+
+		var (
+			from = ethCommon.HexToAddress(topics[1].String())
+			to   = ethCommon.HexToAddress(topics[2].String())
+		)
+
+		hops = append(hops, Hop{
+			LogIndex: new(big.Int).Set(&log.Index.Int),
+			Token:    normaliseAddress(log.Address),
+			From:     libEthereum.ConvertGethAddress(from),
+			To:       libEthereum.ConvertGethAddress(to),
+			Amount:   new(big.Int).SetBytes(log.Data),
+		})
+	}
+
+	sort.SliceStable(hops, func(i, j int) bool {
+		return hops[i].LogIndex.Cmp(hops[j].LogIndex) < 0
+	})
+
+	return Route{Hops: hops}
+}
+
+// TokenHops that moved the token given
+func (route Route) TokenHops(token ethereum.Address) []Hop {
+	var (
+		hops       = make([]Hop, 0)
+		normalised = normaliseAddress(token)
+	)
+
+	for _, hop := range route.Hops {
+		if hop.Token == normalised {
+			hops = append(hops, hop)
+		}
+	}
+
+	return hops
+}
+
+// firstTouch returns the position of the first hop of the token that
+// sent from or to the address, or -1 if none did
+func (route Route) firstTouch(token, address ethereum.Address) int {
+	var (
+		normalisedToken   = normaliseAddress(token)
+		normalisedAddress = normaliseAddress(address)
+	)
+
+	for i, hop := range route.Hops {
+		if hop.Token != normalisedToken {
+			continue
+		}
+
+		if hop.From == normalisedAddress || hop.To == normalisedAddress {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Attribute the application transfers found in a transaction, so the
+// fluid token leg of a swap is credited once. The venue we track that
+// moved the fluid token first is the only transfer kept. Otherwise, an
+// aggregator that moved the fluid token is kept, since the venue it
+// routed through isn't one we support. If no application moved the
+// fluid token, the venues' decoders decide, and an aggregator is only
+// kept if there aren't any venues.
+func Attribute(receipt ethereum.Receipt, fluidToken ethereum.Address, transfers []worker.EthereumApplicationTransfer) Attribution {
+	attribution := Attribution{
+		Route:      Reconstruct(receipt),
+		Transfers:  make([]worker.EthereumApplicationTransfer, 0, len(transfers)),
+		Venue:      applications.ApplicationNone,
+		Aggregator: applications.ApplicationNone,
+	}
+
+	// sorted so the earliest event is kept if a venue emitted more than
+	// one, and so the transfers are in log order on every path
+
+	transfers = sortTransfers(transfers)
+
+	var (
+		route = attribution.Route
+
+		venues = make([]worker.EthereumApplicationTransfer, 0)
+
+		// the venue and aggregator that touched the fluid token first,
+		// with the position they touched it at, or -1
+		venue, aggregator               worker.EthereumApplicationTransfer
+		venueTouched, aggregatorTouched = -1, -1
+
+		// the first aggregator event, if none touched the fluid token
+		aggregatorFound = false
+	)
+
+	for _, transfer := range transfers {
+		touched := route.firstTouch(fluidToken, transfer.Log.Address)
+
+		if registry.IsAggregator(transfer.Application) {
+			if !aggregatorFound {
+				aggregator = transfer
+				aggregatorFound = true
+			}
+
+			// prefer the aggregator that touched the fluid token first
+
+			if touched >= 0 && (aggregatorTouched < 0 || touched < aggregatorTouched) {
+				aggregator = transfer
+				aggregatorTouched = touched
+			}
+
+			continue
+		}
+
+		venues = append(venues, transfer)
+
+		if touched >= 0 && (venueTouched < 0 || touched < venueTouched) {
+			venue = transfer
+			venueTouched = touched
+		}
+	}
+
+	if aggregatorFound {
+		attribution.Aggregator = aggregator.Application
+	}
+
+	switch {
+	case venueTouched >= 0:
+		attribution.Venue = venue.Application
+		attribution.Transfers = append(attribution.Transfers, venue)
+
+	case aggregatorTouched >= 0:
+		attribution.Venue = aggregator.Application
+		attribution.Transfers = append(attribution.Transfers, aggregator)
+
+	case len(venues) > 0:
+		attribution.Transfers = append(attribution.Transfers, venues...)
+
+	case aggregatorFound:
+		attribution.Transfers = append(attribution.Transfers, aggregator)
+	}
+
+	return attribution
+}
+
+// sortTransfers by their log index, without changing the slice given
+func sortTransfers(transfers []worker.EthereumApplicationTransfer) []worker.EthereumApplicationTransfer {
+	sorted := make([]worker.EthereumApplicationTransfer, len(transfers))
+
+	copy(sorted, transfers)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		var (
+			first  = sorted[i].Log.Index
+			second = sorted[j].Log.Index
+		)
+
+		return first.Cmp(&second.Int) < 0
+	})
+
+	return sorted
+}
+
+func normaliseAddress(address ethereum.Address) ethereum.Address {
+	return ethereum.AddressFromString(address.String())
+}
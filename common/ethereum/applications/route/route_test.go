@@ -0,0 +1,238 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package route
+
+import (
+	"math/big"
+	"testing"
+
+	libEthereum "github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/odos"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/paraswap"
+	_ "github.com/fluidity-money/fluidity-app/common/ethereum/applications/uniswap"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	fluidToken = ethereum.AddressFromString("0x4cfa50b7ce747e2d61724fcac57f24b748ff2b2a")
+	otherToken = ethereum.AddressFromString("0xfd086bc7cd5c481dcc9c85ebe478a1c0b69fcbb9")
+
+	user    = ethereum.AddressFromString("0x0000000000000000000000000000000000000001")
+	router  = ethereum.AddressFromString("0x0000000000000000000000000000000000000002")
+	pool    = ethereum.AddressFromString("0x0000000000000000000000000000000000000003")
+	router2 = ethereum.AddressFromString("0x0000000000000000000000000000000000000004")
+)
+
+func addressTopic(address ethereum.Address) ethereum.Hash {
+	return libEthereum.ConvertGethHash(
+		libEthereum.ConvertInternalAddress(address).Hash(),
+	)
+}
+
+func transferLog(index int64, token, from, to ethereum.Address, amount int64) ethereum.Log {
+	return ethereum.Log{
+		Address: token,
+		Topics: []ethereum.Hash{
+			ethereum.HashFromString(libEthereum.TransferLogTopic),
+			addressTopic(from),
+			addressTopic(to),
+		},
+		Data:  big.NewInt(amount).FillBytes(make([]byte, 32)),
+		Index: misc.BigIntFromInt64(index),
+	}
+}
+
+func applicationTransfer(index int64, app applications.Application, address ethereum.Address) worker.EthereumApplicationTransfer {
+	return worker.EthereumApplicationTransfer{
+		Application: app,
+		Log: ethereum.Log{
+			Address: address,
+			Index:   misc.BigIntFromInt64(index),
+		},
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	nft := transferLog(2, otherToken, user, pool, 1)
+	nft.Topics = append(nft.Topics, ethereum.HashFromString("0x01"))
+
+	notTransfer := transferLog(3, otherToken, user, pool, 1)
+	notTransfer.Topics[0] = ethereum.HashFromString("0x02")
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(5, otherToken, pool, user, 99),
+			nft,
+			notTransfer,
+			transferLog(1, fluidToken, user, pool, 100),
+		},
+	}
+
+	route := Reconstruct(receipt)
+
+	assert.Len(t, route.Hops, 2)
+
+	first := route.Hops[0]
+
+	assert.Equal(t, big.NewInt(1), first.LogIndex)
+	assert.Equal(t, fluidToken, first.Token)
+	assert.Equal(t, user, first.From)
+	assert.Equal(t, pool, first.To)
+	assert.Equal(t, big.NewInt(100), first.Amount)
+
+	assert.Equal(t, big.NewInt(5), route.Hops[1].LogIndex)
+
+	assert.Len(t, route.TokenHops(fluidToken), 1)
+	assert.Len(t, route.TokenHops(otherToken), 1)
+}
+
+func TestAttributeAggregatorThroughVenue(t *testing.T) {
+	// user -> paraswap router -> uniswap pool -> user
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(0, fluidToken, user, router, 100),
+			transferLog(1, fluidToken, router, pool, 100),
+			transferLog(2, otherToken, pool, user, 99),
+		},
+	}
+
+	transfers := []worker.EthereumApplicationTransfer{
+		applicationTransfer(3, applications.ApplicationUniswapV3, pool),
+		applicationTransfer(4, applications.ApplicationParaswap, router),
+	}
+
+	attribution := Attribute(receipt, fluidToken, transfers)
+
+	assert.Equal(t, applications.ApplicationUniswapV3, attribution.Venue)
+	assert.Equal(t, applications.ApplicationParaswap, attribution.Aggregator)
+
+	assert.Equal(t, transfers[:1], attribution.Transfers)
+}
+
+func TestAttributeAggregatorThroughUnknownVenue(t *testing.T) {
+	// user -> odos router -> paraswap router -> untracked pool -> user
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(0, fluidToken, user, router2, 100),
+			transferLog(1, fluidToken, router2, router, 100),
+			transferLog(2, fluidToken, router, pool, 100),
+			transferLog(3, otherToken, pool, user, 99),
+		},
+	}
+
+	transfers := []worker.EthereumApplicationTransfer{
+		applicationTransfer(4, applications.ApplicationParaswap, router),
+		applicationTransfer(5, applications.ApplicationOdos, router2),
+	}
+
+	attribution := Attribute(receipt, fluidToken, transfers)
+
+	assert.Equal(t, applications.ApplicationOdos, attribution.Venue)
+	assert.Equal(t, applications.ApplicationOdos, attribution.Aggregator)
+
+	assert.Equal(t, transfers[1:], attribution.Transfers)
+}
+
+func TestAttributeAggregatorWithoutFluid(t *testing.T) {
+	// the router never held the fluid token, so the venue isn't known
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(0, fluidToken, user, pool, 100),
+		},
+	}
+
+	transfers := []worker.EthereumApplicationTransfer{
+		applicationTransfer(1, applications.ApplicationParaswap, router),
+	}
+
+	attribution := Attribute(receipt, fluidToken, transfers)
+
+	assert.Equal(t, applications.ApplicationNone, attribution.Venue)
+	assert.Equal(t, applications.ApplicationParaswap, attribution.Aggregator)
+	assert.Equal(t, transfers, attribution.Transfers)
+}
+
+func TestAttributeWithoutAggregator(t *testing.T) {
+	// only the venue that moved the fluid token is kept
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(0, fluidToken, user, pool, 100),
+			transferLog(1, otherToken, pool, user, 99),
+		},
+	}
+
+	transfers := []worker.EthereumApplicationTransfer{
+		applicationTransfer(3, applications.ApplicationUniswapV3, pool),
+		applicationTransfer(2, applications.ApplicationUniswapV2, router),
+	}
+
+	attribution := Attribute(receipt, fluidToken, transfers)
+
+	assert.Equal(t, applications.ApplicationUniswapV3, attribution.Venue)
+	assert.Equal(t, applications.ApplicationNone, attribution.Aggregator)
+	assert.Equal(t, transfers[:1], attribution.Transfers)
+}
+
+func TestAttributeSplitBetweenVenues(t *testing.T) {
+	// the fluid token is split between two pools, but is only
+	// credited once, to the pool that moved it first
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(0, fluidToken, user, router, 100),
+			transferLog(1, fluidToken, user, pool, 100),
+			transferLog(2, otherToken, pool, user, 99),
+			transferLog(3, otherToken, router, user, 99),
+		},
+	}
+
+	transfers := []worker.EthereumApplicationTransfer{
+		applicationTransfer(5, applications.ApplicationUniswapV3, pool),
+		applicationTransfer(4, applications.ApplicationUniswapV2, router),
+	}
+
+	attribution := Attribute(receipt, fluidToken, transfers)
+
+	assert.Equal(t, applications.ApplicationUniswapV2, attribution.Venue)
+	assert.Equal(t, transfers[1:], attribution.Transfers)
+}
+
+func TestAttributeWithoutFluid(t *testing.T) {
+	// no application moved the fluid token, so the venues are kept in
+	// log order for their decoders to decide
+
+	receipt := ethereum.Receipt{
+		Logs: []ethereum.Log{
+			transferLog(0, fluidToken, user, router2, 100),
+		},
+	}
+
+	transfers := []worker.EthereumApplicationTransfer{
+		applicationTransfer(3, applications.ApplicationUniswapV3, pool),
+		applicationTransfer(4, applications.ApplicationParaswap, router),
+		applicationTransfer(2, applications.ApplicationUniswapV2, router),
+	}
+
+	attribution := Attribute(receipt, fluidToken, transfers)
+
+	assert.Equal(t, applications.ApplicationNone, attribution.Venue)
+	assert.Equal(t, applications.ApplicationParaswap, attribution.Aggregator)
+
+	assert.Equal(
+		t,
+		[]worker.EthereumApplicationTransfer{transfers[2], transfers[0]},
+		attribution.Transfers,
+	)
+}
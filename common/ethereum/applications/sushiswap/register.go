@@ -0,0 +1,22 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package sushiswap
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/uniswap"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationSushiswap,
+		Name:        "sushiswap",
+		Topics:      []string{uniswap.UniswapV2SwapLogTopic, sushiswapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetSushiswapFees),
+		EmissionKey: "sushiswap",
+	})
+}
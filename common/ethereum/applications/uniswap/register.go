@@ -0,0 +1,29 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package uniswap
+
+import (
+	libApps "github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
+)
+
+func init() {
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationUniswapV3,
+		Name:        "uniswap_v3",
+		Topics:      []string{uniswapV3SwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetUniswapV3Fees),
+		EmissionKey: "uniswap_v3",
+	})
+
+	registry.Register(registry.Registration{
+		Application: libApps.ApplicationUniswapV2,
+		Name:        "uniswap_v2",
+		Topics:      []string{UniswapV2SwapLogTopic},
+		Fee:         registry.FeeFromTransfer(GetUniswapV2Fees),
+		EmissionKey: "uniswap_v2",
+	})
+}
@@ -7,6 +7,7 @@ package chainlink
 import (
 	"fmt"
 	"math/big"
+	"time"
 
 	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/fluidity-money/fluidity-app/common/ethereum"
@@ -73,6 +74,14 @@ var priceFeedAbi ethAbi.ABI
 // GetPrice using a Chainlink feed, caching the results in an internal
 // server to make it thread safe
 func GetPrice(client *ethclient.Client, priceFeedAddress ethCommon.Address) (*big.Rat, error) {
+	price, _, err := GetPriceAndUpdatedAt(client, priceFeedAddress)
+
+	return price, err
+}
+
+// GetPriceAndUpdatedAt using a Chainlink feed, returning the time the
+// round was last updated so the caller can check if the feed is stale
+func GetPriceAndUpdatedAt(client *ethclient.Client, priceFeedAddress ethCommon.Address) (*big.Rat, time.Time, error) {
 	log.Debug(func(k *log.Log) {
 		k.Context = Context
 
@@ -100,14 +109,14 @@ func GetPrice(client *ethclient.Client, priceFeedAddress ethCommon.Address) (*bi
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, time.Time{}, fmt.Errorf(
 			"Failed to get latestRoundData! %w",
 			err,
 		)
 	}
 
 	if count := len(priceRes); count != 5 {
-		return nil, fmt.Errorf(
+		return nil, time.Time{}, fmt.Errorf(
 			"returned results for latestRoundData did not have length of 5! was %v",
 			count,
 		)
@@ -116,14 +125,22 @@ func GetPrice(client *ethclient.Client, priceFeedAddress ethCommon.Address) (*bi
 	price, ok := priceRes[1].(*big.Int)
 
 	if !ok {
-		return nil, fmt.Errorf(
+		return nil, time.Time{}, fmt.Errorf(
 			"failed to read a *big.Int from the result of latestRoundData!",
 		)
 	}
 
+	updatedAt, ok := priceRes[3].(*big.Int)
+
+	if !ok {
+		return nil, time.Time{}, fmt.Errorf(
+			"failed to read the updated timestamp from the result of latestRoundData!",
+		)
+	}
+
 	priceRat := new(big.Rat).SetInt(price)
 
 	priceRat.Quo(priceRat, decimals)
 
-	return priceRat, nil
+	return priceRat, time.Unix(updatedAt.Int64(), 0), nil
 }
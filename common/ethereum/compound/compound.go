@@ -5,7 +5,15 @@
 package compound
 
 import (
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
 	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 const cTokenContractAbiString = `[
@@ -52,3 +60,259 @@ const cTokenContractAbiString = `[
 // cTokenContractAbi set by init.go to contain the CToken ABI code that
 // can be used with a bound contract
 var cTokenContractAbi ethAbi.ABI
+
+const cometContractAbiString = `[
+    {
+        "inputs": [],
+        "name": "getUtilization",
+        "outputs": [
+            {
+                "internalType": "uint256",
+                "name": "",
+                "type": "uint256"
+            }
+        ],
+        "stateMutability": "view",
+        "type": "function"
+    },
+    {
+        "inputs": [
+            {
+                "internalType": "uint256",
+                "name": "utilization",
+                "type": "uint256"
+            }
+        ],
+        "name": "getSupplyRate",
+        "outputs": [
+            {
+                "internalType": "uint64",
+                "name": "",
+                "type": "uint64"
+            }
+        ],
+        "stateMutability": "view",
+        "type": "function"
+    },
+    {
+        "inputs": [
+            {
+                "internalType": "address",
+                "name": "account",
+                "type": "address"
+            }
+        ],
+        "name": "balanceOf",
+        "outputs": [
+            {
+                "internalType": "uint256",
+                "name": "",
+                "type": "uint256"
+            }
+        ],
+        "stateMutability": "view",
+        "type": "function"
+    }
+]`
+
+// cometContractAbi set by init.go to contain the Compound V3 (Comet) ABI
+// code that can be used with a bound contract
+var cometContractAbi ethAbi.ABI
+
+// compoundMantissa that rates are scaled by
+var compoundMantissa = new(big.Rat).SetInt(
+	new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil),
+)
+
+// daysPerYear to compound the daily rate over
+const daysPerYear = 365
+
+// secondsPerDay to convert Comet's per second rates with
+const secondsPerDay = 24 * 60 * 60
+
+// GetTokenApy for supplying to a Compound V2 cToken, compounding the
+// rate per block daily
+func GetTokenApy(client *ethclient.Client, cTokenAddress ethCommon.Address, blocksPerDay uint64, emission *worker.Emission) (*big.Rat, error) {
+	results, err := ethereum.StaticCall(
+		client,
+		cTokenAddress,
+		cTokenContractAbi,
+		"supplyRatePerBlock",
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the supply rate of the ctoken! %w",
+			err,
+		)
+	}
+
+	supplyRatePerBlock, err := ethereum.CoerceBoundContractResultsToRat(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the supply rate of the ctoken to a rat! %w",
+			err,
+		)
+	}
+
+	var (
+		blocksPerDayRat = new(big.Rat).SetUint64(blocksPerDay)
+		one             = big.NewRat(1, 1)
+	)
+
+	supplyRatePerBlockDivEthMantissa := new(big.Rat).Quo(
+		supplyRatePerBlock,
+		compoundMantissa,
+	)
+
+	supplyRatePerBlockMulBlocksPerDay := new(big.Rat).Mul(
+		supplyRatePerBlockDivEthMantissa,
+		blocksPerDayRat,
+	)
+
+	powLeftSide := new(big.Rat).Add(supplyRatePerBlockMulBlocksPerDay, one)
+
+	powLeftSideDaysPerYear := ethereum.BigPow(powLeftSide, daysPerYear)
+
+	supplyApy := new(big.Rat).Sub(powLeftSideDaysPerYear, one)
+
+	emission.CompoundGetTokenApy.BlocksPerDay = blocksPerDay
+	emission.CompoundGetTokenApy.SupplyRatePerBlockDivEthMantissa, _ = supplyRatePerBlockDivEthMantissa.Float64()
+	emission.CompoundGetTokenApy.SupplyRatePerBlockMulBlocksPerDay, _ = supplyRatePerBlockMulBlocksPerDay.Float64()
+	emission.CompoundGetTokenApy.PowLeftSide, _ = powLeftSide.Float64()
+	emission.CompoundGetTokenApy.PowLeftSideDaysPerYear, _ = powLeftSideDaysPerYear.Float64()
+	emission.CompoundGetTokenApy.SupplyApy, _ = supplyApy.Float64()
+
+	return supplyApy, nil
+}
+
+// GetBalanceOfUnderlying held by the owner in a Compound V2 cToken,
+// accounting for interest
+func GetBalanceOfUnderlying(client *ethclient.Client, cTokenAddress, owner ethCommon.Address) (*big.Rat, error) {
+	results, err := ethereum.StaticCall(
+		client,
+		cTokenAddress,
+		cTokenContractAbi,
+		"balanceOfUnderlying",
+		owner,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to call balanceOfUnderlying on the ctoken! %w",
+			err,
+		)
+	}
+
+	balance, err := ethereum.CoerceBoundContractResultsToRat(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the underlying balance of the ctoken to a rat! %w",
+			err,
+		)
+	}
+
+	return balance, nil
+}
+
+// GetCometApy for supplying the base asset to a Compound V3 (Comet)
+// market at its current utilisation, compounding the rate daily
+func GetCometApy(client *ethclient.Client, cometAddress ethCommon.Address) (*big.Rat, error) {
+	results, err := ethereum.StaticCall(
+		client,
+		cometAddress,
+		cometContractAbi,
+		"getUtilization",
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the utilisation of the comet market! %w",
+			err,
+		)
+	}
+
+	utilization, err := ethereum.CoerceBoundContractResultsToInt(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the utilisation of the comet market to an int! %w",
+			err,
+		)
+	}
+
+	results, err = ethereum.StaticCall(
+		client,
+		cometAddress,
+		cometContractAbi,
+		"getSupplyRate",
+		utilization,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the supply rate of the comet market! %w",
+			err,
+		)
+	}
+
+	if len(results) != 1 {
+		return nil, fmt.Errorf(
+			"supply rate returned %v results, expected 1!",
+			len(results),
+		)
+	}
+
+	supplyRatePerSecond, ok := results[0].(uint64)
+
+	if !ok {
+		return nil, fmt.Errorf("could not read the supply rate as a uint64!")
+	}
+
+	var (
+		supplyRatePerDay = new(big.Rat).SetUint64(supplyRatePerSecond)
+		one              = big.NewRat(1, 1)
+	)
+
+	supplyRatePerDay.Mul(supplyRatePerDay, big.NewRat(secondsPerDay, 1))
+
+	supplyRatePerDay.Quo(supplyRatePerDay, compoundMantissa)
+
+	powLeftSide := new(big.Rat).Add(supplyRatePerDay, one)
+
+	supplyApy := ethereum.BigPow(powLeftSide, daysPerYear)
+
+	return supplyApy.Sub(supplyApy, one), nil
+}
+
+// GetCometBalanceOf the base asset held by the owner in a Compound V3
+// (Comet) market, accounting for interest
+func GetCometBalanceOf(client *ethclient.Client, cometAddress, owner ethCommon.Address) (*big.Rat, error) {
+	results, err := ethereum.StaticCall(
+		client,
+		cometAddress,
+		cometContractAbi,
+		"balanceOf",
+		owner,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to call balanceOf on the comet market! %w",
+			err,
+		)
+	}
+
+	balance, err := ethereum.CoerceBoundContractResultsToRat(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the comet balance to a rat! %w",
+			err,
+		)
+	}
+
+	return balance, nil
+}
@@ -18,4 +18,10 @@ func init() {
 	if cTokenContractAbi, err = ethAbi.JSON(reader); err != nil {
 		panic(err)
 	}
+
+	cometReader := strings.NewReader(cometContractAbiString)
+
+	if cometContractAbi, err = ethAbi.JSON(cometReader); err != nil {
+		panic(err)
+	}
 }
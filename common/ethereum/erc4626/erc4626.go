@@ -0,0 +1,277 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package erc4626
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum"
+
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const vaultAbiString = `[
+	{
+		"inputs": [],
+		"name": "decimals",
+		"outputs": [
+			{
+				"internalType": "uint8",
+				"name": "",
+				"type": "uint8"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{
+				"internalType": "address",
+				"name": "owner",
+				"type": "address"
+			}
+		],
+		"name": "balanceOf",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{
+				"internalType": "uint256",
+				"name": "shares",
+				"type": "uint256"
+			}
+		],
+		"name": "convertToAssets",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "assets",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// vaultAbi set by init.go to contain the ERC4626 ABI code that can be
+// used with a bound contract
+var vaultAbi ethAbi.ABI
+
+// daysPerYear to compound the daily rate over
+const daysPerYear = 365
+
+// secondsPerYear to annualise the growth of the share price with
+const secondsPerYear = daysPerYear * 24 * 60 * 60
+
+// GetSharePrice of the vault in assets per share at the block given, or
+// the latest block if it's nil
+func GetSharePrice(client *ethclient.Client, vaultAddress ethCommon.Address, blockNumber *big.Int) (*big.Rat, error) {
+	results, err := ethereum.StaticCallAtBlock(
+		client,
+		vaultAddress,
+		vaultAbi,
+		"decimals",
+		blockNumber,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the decimals of the vault! %w",
+			err,
+		)
+	}
+
+	decimals, err := ethereum.CoerceBoundContractResultsToUint8(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the decimals of the vault to a uint8! %w",
+			err,
+		)
+	}
+
+	oneShare := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+
+	assets, err := convertToAssets(client, vaultAddress, oneShare, blockNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return assets.Quo(assets, new(big.Rat).SetInt(oneShare)), nil
+}
+
+// GetTokenApy of the vault from the growth of its share price over the
+// last lookbackBlocks blocks, compounded daily
+func GetTokenApy(client *ethclient.Client, vaultAddress ethCommon.Address, lookbackBlocks uint64) (*big.Rat, error) {
+	ctx := context.Background()
+
+	latestHeader, err := client.HeaderByNumber(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the latest block header! %w",
+			err,
+		)
+	}
+
+	latestNumber := latestHeader.Number
+
+	if latestNumber.Cmp(new(big.Int).SetUint64(lookbackBlocks)) <= 0 {
+		return nil, fmt.Errorf(
+			"chain at block %v is shorter than the lookback of %v blocks!",
+			latestNumber,
+			lookbackBlocks,
+		)
+	}
+
+	pastNumber := new(big.Int).Sub(
+		latestNumber,
+		new(big.Int).SetUint64(lookbackBlocks),
+	)
+
+	pastHeader, err := client.HeaderByNumber(ctx, pastNumber)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the header of block %v! %w",
+			pastNumber,
+			err,
+		)
+	}
+
+	if latestHeader.Time <= pastHeader.Time {
+		return nil, fmt.Errorf(
+			"no time passed between blocks %v and %v!",
+			pastNumber,
+			latestNumber,
+		)
+	}
+
+	latestPrice, err := GetSharePrice(client, vaultAddress, latestNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pastPrice, err := GetSharePrice(client, vaultAddress, pastNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	zero := new(big.Rat)
+
+	if pastPrice.Cmp(zero) == 0 {
+		return nil, fmt.Errorf(
+			"share price of the vault was 0 at block %v!",
+			pastNumber,
+		)
+	}
+
+	var (
+		one = big.NewRat(1, 1)
+
+		elapsed = new(big.Rat).SetUint64(latestHeader.Time - pastHeader.Time)
+	)
+
+	// growth of the share price over the lookback, annualised
+
+	apr := new(big.Rat).Quo(latestPrice, pastPrice)
+
+	apr.Sub(apr, one)
+
+	apr.Mul(apr, big.NewRat(secondsPerYear, 1))
+
+	apr.Quo(apr, elapsed)
+
+	// a loss shouldn't be reported as a negative yield
+
+	if apr.Cmp(zero) < 0 {
+		return zero, nil
+	}
+
+	aprPerDay := new(big.Rat).Quo(apr, big.NewRat(daysPerYear, 1))
+
+	onePlusAprPerDay := new(big.Rat).Add(one, aprPerDay)
+
+	apy := ethereum.BigPow(onePlusAprPerDay, daysPerYear)
+
+	return apy.Sub(apy, one), nil
+}
+
+// GetBalanceOfUnderlying held by the owner in the vault, by converting
+// their shares to assets
+func GetBalanceOfUnderlying(client *ethclient.Client, vaultAddress, owner ethCommon.Address) (*big.Rat, error) {
+	results, err := ethereum.StaticCall(
+		client,
+		vaultAddress,
+		vaultAbi,
+		"balanceOf",
+		owner,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the shares held in the vault! %w",
+			err,
+		)
+	}
+
+	shares, err := ethereum.CoerceBoundContractResultsToInt(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the shares held in the vault to an int! %w",
+			err,
+		)
+	}
+
+	return convertToAssets(client, vaultAddress, shares, nil)
+}
+
+func convertToAssets(client *ethclient.Client, vaultAddress ethCommon.Address, shares, blockNumber *big.Int) (*big.Rat, error) {
+	results, err := ethereum.StaticCallAtBlock(
+		client,
+		vaultAddress,
+		vaultAbi,
+		"convertToAssets",
+		blockNumber,
+		shares,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to convert shares to assets with the vault! %w",
+			err,
+		)
+	}
+
+	assets, err := ethereum.CoerceBoundContractResultsToRat(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the assets of the vault to a rat! %w",
+			err,
+		)
+	}
+
+	return assets, nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package erc4626
+
+import (
+	"strings"
+
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func init() {
+	reader := strings.NewReader(vaultAbiString)
+
+	var err error
+
+	if vaultAbi, err = ethAbi.JSON(reader); err != nil {
+		panic(err)
+	}
+}
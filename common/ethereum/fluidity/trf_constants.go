@@ -4,10 +4,12 @@
 
 package fluidity
 
+import "github.com/fluidity-money/fluidity-app/common/calculation/trf"
+
 const (
-	WinningClasses   = 5
-	DeltaWeightNum   = int64(31536000)
-	DeltaWeightDenom = int64(1)
-	PayoutFreqNum    = int64(1)
-	PayoutFreqDenom  = int64(4)
+	WinningClasses   = trf.WinningClasses
+	DeltaWeightNum   = trf.DeltaWeightNum
+	DeltaWeightDenom = trf.DeltaWeightDenom
+	PayoutFreqNum    = trf.PayoutFreqNum
+	PayoutFreqDenom  = trf.PayoutFreqDenom
 )
@@ -0,0 +1,23 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// cache keeps prices resolved by the price package in Redis, kept apart
+// so the price package doesn't need Redis to start
+
+package cache
+
+import "github.com/fluidity-money/fluidity-app/lib/state"
+
+// State to pass to price.NewOracle to cache prices using lib/state
+type State struct{}
+
+// Get a cached price, or nothing if it's expired
+func (State) Get(key string) []byte {
+	return state.Get(key)
+}
+
+// SetTimed a price to expire in seconds
+func (State) SetTimed(key string, seconds uint64, content interface{}) {
+	state.SetTimed(key, seconds, content)
+}
@@ -0,0 +1,231 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package price
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// SourceChainlink to read the price from a Chainlink feed
+	SourceChainlink = "chainlink"
+
+	// SourceUniswapV3Twap to read the price from the time weighted
+	// average tick of a Uniswap V3 pool
+	SourceUniswapV3Twap = "uniswap_v3_twap"
+
+	// SourceRatio to price a token as another token's price times a ratio
+	SourceRatio = "ratio"
+
+	// SourceStatic to use a hardcoded price
+	SourceStatic = "static"
+)
+
+type (
+	// Config for every token that can be priced, with the sources to
+	// try for each in order
+	Config struct {
+		// CacheTtl to keep resolved prices in Redis for in seconds, 0
+		// to always look up prices
+		CacheTtl uint64 `json:"cache_ttl"`
+
+		// MaxStaleness of a price in seconds before it's ignored, 0 for
+		// no limit. Overridden per token
+		MaxStaleness uint64 `json:"max_staleness"`
+
+		// MaxDeviation between the price used and the next live source as
+		// a fraction of the price used, 0 to skip the check. Overridden
+		// per token
+		MaxDeviation float64 `json:"max_deviation"`
+
+		Tokens map[string]TokenConfig `json:"tokens"`
+	}
+
+	// TokenConfig with the sources to try to price a token, in order
+	TokenConfig struct {
+		MaxStaleness uint64         `json:"max_staleness"`
+		MaxDeviation float64        `json:"max_deviation"`
+		Sources      []SourceConfig `json:"sources"`
+	}
+
+	// SourceConfig for a single source, with only the fields for its
+	// type set
+	SourceConfig struct {
+		Type string `json:"type"`
+
+		// Address of the Chainlink feed or the Uniswap V3 pool
+		Address ethCommon.Address `json:"address"`
+
+		// Seconds to average the Uniswap V3 tick over
+		Seconds uint32 `json:"seconds"`
+
+		// Token0Decimals and Token1Decimals of the Uniswap V3 pool
+		Token0Decimals int `json:"token0_decimals"`
+		Token1Decimals int `json:"token1_decimals"`
+
+		// Invert the Uniswap V3 price if the token is token1 in the pool
+		Invert bool `json:"invert"`
+
+		// Token to multiply by the ratio if the type is ratio, or the
+		// token that the Uniswap V3 pool is quoted in if that isn't USD
+		Token string `json:"token"`
+
+		Ratio *big.Rat `json:"ratio"`
+
+		Price *big.Rat `json:"price"`
+	}
+)
+
+// ParseConfig from JSON, checking that every source is complete and that
+// tokens priced with other tokens don't depend on each other
+func ParseConfig(configJson string) (*Config, error) {
+	var config Config
+
+	if err := json.Unmarshal([]byte(configJson), &config); err != nil {
+		return nil, fmt.Errorf("failed to decode the price config! %v", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (config Config) validate() error {
+	for token, tokenConfig := range config.Tokens {
+		if len(tokenConfig.Sources) == 0 {
+			return fmt.Errorf("token %v has no price sources", token)
+		}
+
+		for i, source := range tokenConfig.Sources {
+			if err := config.validateSource(source); err != nil {
+				return fmt.Errorf(
+					"source %v of token %v is invalid! %v",
+					i,
+					token,
+					err,
+				)
+			}
+		}
+
+		if err := config.checkCycle(token, map[string]bool{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (config Config) validateSource(source SourceConfig) error {
+	var emptyAddress ethCommon.Address
+
+	switch source.Type {
+	case SourceChainlink:
+		if source.Address == emptyAddress {
+			return fmt.Errorf("chainlink feed address not set")
+		}
+
+	case SourceUniswapV3Twap:
+		if source.Address == emptyAddress {
+			return fmt.Errorf("uniswap v3 pool address not set")
+		}
+
+		if source.Seconds == 0 {
+			return fmt.Errorf("uniswap v3 twap seconds not set")
+		}
+
+		if err := config.checkTokenExists(source.Token); source.Token != "" && err != nil {
+			return err
+		}
+
+	case SourceRatio:
+		if err := config.checkTokenExists(source.Token); err != nil {
+			return err
+		}
+
+		if source.Ratio == nil {
+			return fmt.Errorf("ratio not set")
+		}
+
+	case SourceStatic:
+		if source.Price == nil {
+			return fmt.Errorf("static price not set")
+		}
+
+	default:
+		return fmt.Errorf("unknown source type %#v", source.Type)
+	}
+
+	return nil
+}
+
+func (config Config) checkTokenExists(token string) error {
+	if _, exists := config.Tokens[token]; !exists {
+		return fmt.Errorf("token %#v isn't configured", token)
+	}
+
+	return nil
+}
+
+// checkCycle of tokens priced by other tokens, starting with token
+func (config Config) checkCycle(token string, seen map[string]bool) error {
+	if seen[token] {
+		return fmt.Errorf("token %v is priced using itself", token)
+	}
+
+	seen[token] = true
+
+	for _, source := range config.Tokens[token].Sources {
+		if source.Token == "" {
+			continue
+		}
+
+		if err := config.checkCycle(source.Token, seen); err != nil {
+			return err
+		}
+	}
+
+	delete(seen, token)
+
+	return nil
+}
+
+// maxStaleness for the token, falling back to the global config
+func (config Config) maxStaleness(token string) uint64 {
+	if staleness := config.Tokens[token].MaxStaleness; staleness != 0 {
+		return staleness
+	}
+
+	return config.MaxStaleness
+}
+
+// maxDeviation for the token, falling back to the global config
+func (config Config) maxDeviation(token string) float64 {
+	if deviation := config.Tokens[token].MaxDeviation; deviation != 0 {
+		return deviation
+	}
+
+	return config.MaxDeviation
+}
+
+// ChainlinkConfig to price a single token with a Chainlink feed, for
+// services that were configured with just a feed address
+func ChainlinkConfig(token string, feedAddress ethCommon.Address) Config {
+	source := SourceConfig{
+		Type:    SourceChainlink,
+		Address: feedAddress,
+	}
+
+	return Config{
+		Tokens: map[string]TokenConfig{
+			token: {Sources: []SourceConfig{source}},
+		},
+	}
+}
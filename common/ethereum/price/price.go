@@ -0,0 +1,341 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// price resolves the USD price of a token through an ordered list of
+// sources (Chainlink feeds, Uniswap V3 TWAPs, other tokens and
+// static prices), caching results in Redis. Services read the config
+// as JSON from FLU_ETHEREUM_PRICE_CONFIG, for example:
+//
+//	{
+//	  "cache_ttl": 30,
+//	  "max_staleness": 3600,
+//	  "max_deviation": 0.05,
+//	  "tokens": {
+//	    "ETH": {"sources": [
+//	      {"type": "chainlink", "address": "0x5f4e..."},
+//	      {"type": "uniswap_v3_twap", "address": "0x88e6...", "seconds": 600,
+//	       "token0_decimals": 6, "token1_decimals": 18, "invert": true},
+//	      {"type": "static", "price": "2000"}
+//	    ]},
+//	    "WETH": {"sources": [{"type": "ratio", "token": "ETH", "ratio": "1"}]}
+//	  }
+//	}
+//
+// The first source that returns a price newer than the staleness limit
+// is used. If a deviation limit is set, it's checked against the next
+// source that isn't static.
+//
+// Prices recorded by microservice-ethereum-track-prices can be looked up
+// as of a block with GetPriceAtBlock, falling back to the current price
+// if nothing fresh enough was recorded.
+package price
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Context to use for logging
+const Context = "PRICE"
+
+var (
+	// ErrStale if a source's price is older than the staleness limit
+	ErrStale = fmt.Errorf("price is stale")
+
+	// ErrDeviation if the price used deviates too much from the next
+	// live source
+	ErrDeviation = fmt.Errorf("price deviates too much between sources")
+
+	// ErrUnknownToken if the token isn't in the config
+	ErrUnknownToken = fmt.Errorf("token isn't configured")
+)
+
+type (
+	// Cache that resolved prices are kept in, normally lib/state
+	Cache interface {
+		Get(key string) []byte
+		SetTimed(key string, seconds uint64, content interface{})
+	}
+
+	// History of recorded prices, normally in Timescale. Returns nil if
+	// nothing was recorded before the block or time
+	History interface {
+		GetPriceAtBlock(network network.BlockchainNetwork, token string, blockNumber uint64) *Price
+		GetPriceAtTime(network network.BlockchainNetwork, token string, time time.Time) *Price
+	}
+
+	// Price of a token in USD, with the time it was last updated and
+	// the type of source it came from
+	Price struct {
+		Usd *big.Rat `json:"usd"`
+
+		// UpdatedAt is unset for static prices, which are never stale
+		UpdatedAt time.Time `json:"updated_at"`
+
+		Source string `json:"source"`
+	}
+
+	// Oracle to look up prices with
+	Oracle struct {
+		client  *ethclient.Client
+		network network.BlockchainNetwork
+		config  Config
+		cache   Cache
+		history History
+
+		now func() time.Time
+	}
+)
+
+// NewOracle using the client to query Chainlink and Uniswap. Cache and
+// history can be nil to disable caching and historical lookups
+func NewOracle(client *ethclient.Client, network_ network.BlockchainNetwork, config Config, cache Cache, history History) *Oracle {
+	return &Oracle{
+		client:  client,
+		network: network_,
+		config:  config,
+		cache:   cache,
+		history: history,
+		now:     time.Now,
+	}
+}
+
+// GetPrice of the token in USD, using the cache if it's set
+func (oracle *Oracle) GetPrice(token string) (*Price, error) {
+	if _, exists := oracle.config.Tokens[token]; !exists {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownToken, token)
+	}
+
+	cacheKey := fmt.Sprintf("price.%v.%v", oracle.network, token)
+
+	useCache := oracle.cache != nil && oracle.config.CacheTtl != 0
+
+	if useCache {
+		if price := oracle.getCached(cacheKey); price != nil {
+			return price, nil
+		}
+	}
+
+	price, err := oracle.resolve(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if useCache {
+		oracle.cache.SetTimed(cacheKey, oracle.config.CacheTtl, price)
+	}
+
+	return price, nil
+}
+
+// GetPriceAtBlock of the token in USD, using the price recorded at or
+// before the block if it isn't stale by the block's time, or the
+// current price if it is
+func (oracle *Oracle) GetPriceAtBlock(token string, blockNumber uint64, blockTime time.Time) (*Price, error) {
+	if _, exists := oracle.config.Tokens[token]; !exists {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownToken, token)
+	}
+
+	if oracle.history != nil {
+		price := oracle.history.GetPriceAtBlock(oracle.network, token, blockNumber)
+
+		if oracle.isFreshAt(token, price, blockTime) {
+			return price, nil
+		}
+
+		price = oracle.history.GetPriceAtTime(oracle.network, token, blockTime)
+
+		if oracle.isFreshAt(token, price, blockTime) {
+			return price, nil
+		}
+	}
+
+	log.Debugf(
+		"No recorded price for %v at block %v, using the current price",
+		token,
+		blockNumber,
+	)
+
+	return oracle.GetPrice(token)
+}
+
+// isFreshAt checks if a recorded price can be used for the time given
+func (oracle *Oracle) isFreshAt(token string, price *Price, at time.Time) bool {
+	if price == nil || price.Usd == nil {
+		return false
+	}
+
+	return !isStale(price, oracle.config.maxStaleness(token), at)
+}
+
+// ToUsd converts a normalised amount of the token (with decimals
+// already applied) to USD
+func (oracle *Oracle) ToUsd(token string, amount *big.Rat) (*big.Rat, error) {
+	price, err := oracle.GetPrice(token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Rat).Mul(price.Usd, amount), nil
+}
+
+func (oracle *Oracle) getCached(cacheKey string) *Price {
+	priceBytes := oracle.cache.Get(cacheKey)
+
+	if len(priceBytes) == 0 {
+		return nil
+	}
+
+	var price Price
+
+	if err := json.Unmarshal(priceBytes, &price); err != nil || price.Usd == nil {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to decode the cached price in %v, looking it up again!",
+				cacheKey,
+			)
+
+			k.Payload = err
+		})
+
+		return nil
+	}
+
+	return &price
+}
+
+// resolve the price with the first source that returns a fresh price,
+// checking it against the next live source if a deviation limit is set
+func (oracle *Oracle) resolve(token string) (*Price, error) {
+	var (
+		sources      = oracle.config.Tokens[token].Sources
+		maxDeviation = oracle.config.maxDeviation(token)
+	)
+
+	price, i, err := oracle.firstFresh(token, sources, false)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"no source for %v returned a fresh price! %w",
+			token,
+			err,
+		)
+	}
+
+	if maxDeviation == 0 {
+		return price, nil
+	}
+
+	// static prices are a last resort, so they're never used to check
+
+	checkPrice, _, err := oracle.firstFresh(token, sources[i+1:], true)
+
+	if err != nil {
+		log.Debugf(
+			"No second source to check the price of %v against, using %v from %v",
+			token,
+			price.Usd.FloatString(6),
+			price.Source,
+		)
+
+		return price, nil
+	}
+
+	deviation := calculateDeviation(price.Usd, checkPrice.Usd)
+
+	if deviation.Cmp(new(big.Rat).SetFloat64(maxDeviation)) > 0 {
+		return nil, fmt.Errorf(
+			"%w: %v was %v from %v but %v from %v",
+			ErrDeviation,
+			token,
+			price.Usd.FloatString(6),
+			price.Source,
+			checkPrice.Usd.FloatString(6),
+			checkPrice.Source,
+		)
+	}
+
+	return price, nil
+}
+
+// firstFresh price returned by the sources, with its index, logging any
+// sources that failed
+func (oracle *Oracle) firstFresh(token string, sources []SourceConfig, skipStatic bool) (*Price, int, error) {
+	maxStaleness := oracle.config.maxStaleness(token)
+
+	lastErr := fmt.Errorf("no sources")
+
+	for i, source := range sources {
+		if skipStatic && source.Type == SourceStatic {
+			continue
+		}
+
+		price, err := oracle.lookup(source)
+
+		if err == nil && isStale(price, maxStaleness, oracle.now()) {
+			err = fmt.Errorf(
+				"%w: updated at %v",
+				ErrStale,
+				price.UpdatedAt,
+			)
+		}
+
+		if err != nil {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Source %v (%v) for the price of %v failed, trying the next one!",
+					i,
+					source.Type,
+					token,
+				)
+
+				k.Payload = err
+			})
+
+			lastErr = err
+
+			continue
+		}
+
+		return price, i, nil
+	}
+
+	return nil, 0, lastErr
+}
+
+func isStale(price *Price, maxStaleness uint64, now time.Time) bool {
+	if maxStaleness == 0 || price.UpdatedAt.IsZero() {
+		return false
+	}
+
+	limit := time.Duration(maxStaleness) * time.Second
+
+	return now.Sub(price.UpdatedAt) > limit
+}
+
+// calculateDeviation of b from a, as a fraction of a
+func calculateDeviation(a, b *big.Rat) *big.Rat {
+	if a.Sign() == 0 {
+		return new(big.Rat).Abs(b)
+	}
+
+	deviation := new(big.Rat).Sub(a, b)
+
+	deviation.Abs(deviation)
+
+	return deviation.Quo(deviation, a)
+}
@@ -0,0 +1,362 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package price
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `{
+	"cache_ttl": 30,
+	"max_staleness": 3600,
+	"max_deviation": 0.05,
+	"tokens": {
+		"ETH": {
+			"sources": [
+				{"type": "chainlink", "address": "0x0000000000000000000000000000000000000001"},
+				{"type": "uniswap_v3_twap", "address": "0x0000000000000000000000000000000000000002", "seconds": 600, "token0_decimals": 18, "token1_decimals": 6},
+				{"type": "static", "price": "2000"}
+			]
+		},
+		"WETH": {
+			"sources": [{"type": "ratio", "token": "ETH", "ratio": "1"}]
+		},
+		"ARB": {
+			"max_deviation": 0.5,
+			"sources": [
+				{"type": "uniswap_v3_twap", "address": "0x0000000000000000000000000000000000000003", "seconds": 600, "token0_decimals": 18, "token1_decimals": 18, "invert": true, "token": "ETH"},
+				{"type": "static", "price": "0.04636"}
+			]
+		}
+	}
+}`
+
+var testNow = time.Unix(1700000000, 0)
+
+type testCache map[string][]byte
+
+func (cache testCache) Get(key string) []byte {
+	return cache[key]
+}
+
+func (cache testCache) SetTimed(key string, _ uint64, content interface{}) {
+	bytes, _ := json.Marshal(content)
+	cache[key] = bytes
+}
+
+// testHistory of prices recorded at blocks, looked up by time as well
+type testHistory []struct {
+	blockNumber uint64
+	price       Price
+}
+
+func (history testHistory) GetPriceAtBlock(_ network.BlockchainNetwork, _ string, blockNumber uint64) *Price {
+	var found *Price
+
+	for i, recorded := range history {
+		if recorded.blockNumber <= blockNumber {
+			found = &history[i].price
+		}
+	}
+
+	return found
+}
+
+func (history testHistory) GetPriceAtTime(_ network.BlockchainNetwork, _ string, at time.Time) *Price {
+	var found *Price
+
+	for i, recorded := range history {
+		if !recorded.price.UpdatedAt.After(at) {
+			found = &history[i].price
+		}
+	}
+
+	return found
+}
+
+// fakeSources to replace the on-chain lookups, returning errors for
+// addresses that aren't set
+type fakeSources struct {
+	chainlink     map[ethCommon.Address]*big.Rat
+	chainlinkAt   time.Time
+	ticks         map[ethCommon.Address]int64
+	chainlinkHits int
+}
+
+func (sources *fakeSources) install(t *testing.T) {
+	oldChainlink, oldTick := getChainlinkPrice, getUniswapV3TwapTick
+
+	getChainlinkPrice = func(_ *ethclient.Client, address ethCommon.Address) (*big.Rat, time.Time, error) {
+		sources.chainlinkHits++
+
+		price, ok := sources.chainlink[address]
+
+		if !ok {
+			return nil, time.Time{}, fmt.Errorf("no feed")
+		}
+
+		return price, sources.chainlinkAt, nil
+	}
+
+	getUniswapV3TwapTick = func(_ *ethclient.Client, address ethCommon.Address, _ uint32) (*big.Int, error) {
+		tick, ok := sources.ticks[address]
+
+		if !ok {
+			return nil, fmt.Errorf("no pool")
+		}
+
+		return big.NewInt(tick), nil
+	}
+
+	t.Cleanup(func() {
+		getChainlinkPrice, getUniswapV3TwapTick = oldChainlink, oldTick
+	})
+}
+
+var (
+	ethFeed = ethCommon.HexToAddress("0x0000000000000000000000000000000000000001")
+	ethPool = ethCommon.HexToAddress("0x0000000000000000000000000000000000000002")
+	arbPool = ethCommon.HexToAddress("0x0000000000000000000000000000000000000003")
+)
+
+// ethTick is about 2000 USDC per ETH with 18 and 6 decimals
+const ethTick = -200311
+
+func newTestOracle(t *testing.T, cache Cache, history History) *Oracle {
+	config, err := ParseConfig(testConfig)
+
+	require.NoError(t, err)
+
+	oracle := NewOracle(nil, network.NetworkEthereum, *config, cache, history)
+
+	oracle.now = func() time.Time { return testNow }
+
+	return oracle
+}
+
+func priceFloat(t *testing.T, price *Price) float64 {
+	require.NotNil(t, price)
+
+	f, _ := price.Usd.Float64()
+
+	return f
+}
+
+func TestGetPriceChainlink(t *testing.T) {
+	sources := fakeSources{
+		chainlink:   map[ethCommon.Address]*big.Rat{ethFeed: big.NewRat(2010, 1)},
+		chainlinkAt: testNow.Add(-time.Minute),
+		ticks:       map[ethCommon.Address]int64{ethPool: ethTick},
+	}
+
+	sources.install(t)
+
+	price, err := newTestOracle(t, nil, nil).GetPrice("ETH")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2010.0, priceFloat(t, price))
+	assert.Equal(t, SourceChainlink, price.Source)
+}
+
+func TestGetPriceStaleFallsBack(t *testing.T) {
+	sources := fakeSources{
+		chainlink:   map[ethCommon.Address]*big.Rat{ethFeed: big.NewRat(1500, 1)},
+		chainlinkAt: testNow.Add(-2 * time.Hour),
+		ticks:       map[ethCommon.Address]int64{ethPool: ethTick},
+	}
+
+	sources.install(t)
+
+	price, err := newTestOracle(t, nil, nil).GetPrice("ETH")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, SourceUniswapV3Twap, price.Source)
+	assert.InDelta(t, 2000, priceFloat(t, price), 1)
+}
+
+func TestGetPriceStatic(t *testing.T) {
+	sources := fakeSources{}
+
+	sources.install(t)
+
+	price, err := newTestOracle(t, nil, nil).GetPrice("ETH")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, SourceStatic, price.Source)
+	assert.Equal(t, 2000.0, priceFloat(t, price))
+	assert.True(t, price.UpdatedAt.IsZero())
+}
+
+func TestGetPriceDeviation(t *testing.T) {
+	sources := fakeSources{
+		chainlink:   map[ethCommon.Address]*big.Rat{ethFeed: big.NewRat(2500, 1)},
+		chainlinkAt: testNow,
+		ticks:       map[ethCommon.Address]int64{ethPool: ethTick},
+	}
+
+	sources.install(t)
+
+	_, err := newTestOracle(t, nil, nil).GetPrice("ETH")
+
+	assert.ErrorIs(t, err, ErrDeviation)
+}
+
+func TestGetPriceRatioAndQuote(t *testing.T) {
+	sources := fakeSources{
+		chainlink:   map[ethCommon.Address]*big.Rat{ethFeed: big.NewRat(2000, 1)},
+		chainlinkAt: testNow.Add(-time.Minute),
+		ticks: map[ethCommon.Address]int64{
+			ethPool: ethTick,
+
+			// 1 ETH is ~43000 ARB, so inverted ARB is ~0.0000232 ETH
+			arbPool: 106690,
+		},
+	}
+
+	sources.install(t)
+
+	oracle := newTestOracle(t, nil, nil)
+
+	weth, err := oracle.GetPrice("WETH")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2000.0, priceFloat(t, weth))
+	assert.Equal(t, testNow.Add(-time.Minute), weth.UpdatedAt)
+
+	arb, err := oracle.GetPrice("ARB")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, SourceUniswapV3Twap, arb.Source)
+	assert.InDelta(t, 0.04636, priceFloat(t, arb), 0.001)
+
+	amountUsd, err := oracle.ToUsd("ARB", big.NewRat(100, 1))
+
+	require.NoError(t, err)
+
+	f, _ := amountUsd.Float64()
+
+	assert.InDelta(t, 4.636, f, 0.1)
+}
+
+func TestGetPriceCached(t *testing.T) {
+	sources := fakeSources{
+		chainlink:   map[ethCommon.Address]*big.Rat{ethFeed: big.NewRat(2000, 1)},
+		chainlinkAt: testNow,
+		ticks:       map[ethCommon.Address]int64{ethPool: ethTick},
+	}
+
+	sources.install(t)
+
+	cache := make(testCache)
+
+	oracle := newTestOracle(t, cache, nil)
+
+	first, err := oracle.GetPrice("ETH")
+
+	require.NoError(t, err)
+
+	hits := sources.chainlinkHits
+
+	second, err := oracle.GetPrice("ETH")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, hits, sources.chainlinkHits)
+	assert.Equal(t, first.Usd, second.Usd)
+	assert.Contains(t, cache, "price.ethereum.ETH")
+}
+
+func TestGetPriceUnknown(t *testing.T) {
+	_, err := newTestOracle(t, nil, nil).GetPrice("USDC")
+
+	assert.ErrorIs(t, err, ErrUnknownToken)
+}
+
+func TestParseConfigInvalid(t *testing.T) {
+	configs := map[string]string{
+		"no sources":     `{"tokens": {"ETH": {"sources": []}}}`,
+		"unknown type":   `{"tokens": {"ETH": {"sources": [{"type": "coingecko"}]}}}`,
+		"missing price":  `{"tokens": {"ETH": {"sources": [{"type": "static"}]}}}`,
+		"missing token":  `{"tokens": {"ETH": {"sources": [{"type": "ratio", "token": "WETH", "ratio": "1"}]}}}`,
+		"missing feed":   `{"tokens": {"ETH": {"sources": [{"type": "chainlink"}]}}}`,
+		"missing window": `{"tokens": {"ETH": {"sources": [{"type": "uniswap_v3_twap", "address": "0x0000000000000000000000000000000000000002"}]}}}`,
+		"cycle": `{"tokens": {
+			"A": {"sources": [{"type": "ratio", "token": "B", "ratio": "1"}]},
+			"B": {"sources": [{"type": "ratio", "token": "A", "ratio": "1"}]}
+		}}`,
+	}
+
+	for name, config := range configs {
+		_, err := ParseConfig(config)
+
+		assert.Error(t, err, name)
+	}
+}
+
+func TestCalculateDeviation(t *testing.T) {
+	deviation := calculateDeviation(big.NewRat(100, 1), big.NewRat(95, 1))
+
+	assert.Equal(t, big.NewRat(1, 20), deviation)
+}
+
+func TestGetPriceAtBlock(t *testing.T) {
+	sources := fakeSources{
+		chainlink:   map[ethCommon.Address]*big.Rat{ethFeed: big.NewRat(2000, 1)},
+		chainlinkAt: testNow,
+		ticks:       map[ethCommon.Address]int64{ethPool: ethTick},
+	}
+
+	sources.install(t)
+
+	history := testHistory{
+		{100, Price{Usd: big.NewRat(1800, 1), UpdatedAt: testNow.Add(-3 * time.Hour), Source: SourceChainlink}},
+		{200, Price{Usd: big.NewRat(1900, 1), UpdatedAt: testNow.Add(-2 * time.Hour), Source: SourceChainlink}},
+	}
+
+	oracle := newTestOracle(t, nil, history)
+
+	// the price recorded at block 100 was fresh for block 150
+
+	price, err := oracle.GetPriceAtBlock("ETH", 150, testNow.Add(-150*time.Minute))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 1800.0, priceFloat(t, price))
+	assert.Equal(t, 0, sources.chainlinkHits)
+
+	// nothing was recorded for long enough after block 200, so the
+	// current price is used
+
+	price, err = oracle.GetPriceAtBlock("ETH", 300, testNow)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2000.0, priceFloat(t, price))
+
+	// nothing was recorded before block 50, but the time lookup is
+	// still used
+
+	price, err = oracle.GetPriceAtBlock("ETH", 50, testNow.Add(-90*time.Minute))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 1900.0, priceFloat(t, price))
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package price
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/chainlink"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/uniswap_v3"
+)
+
+// lookups for the on-chain sources, replaced in tests
+var (
+	getChainlinkPrice    = chainlink.GetPriceAndUpdatedAt
+	getUniswapV3TwapTick = uniswap_v3.GetTwapTick
+)
+
+// lookup the price from a single source
+func (oracle *Oracle) lookup(source SourceConfig) (*Price, error) {
+	switch source.Type {
+	case SourceChainlink:
+		usd, updatedAt, err := getChainlinkPrice(oracle.client, source.Address)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if usd.Sign() <= 0 {
+			return nil, fmt.Errorf("chainlink feed %v returned %v", source.Address, usd)
+		}
+
+		return &Price{Usd: usd, UpdatedAt: updatedAt, Source: source.Type}, nil
+
+	case SourceUniswapV3Twap:
+		return oracle.lookupUniswapV3Twap(source)
+
+	case SourceRatio:
+		underlying, err := oracle.GetPrice(source.Token)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get the price of %v! %w",
+				source.Token,
+				err,
+			)
+		}
+
+		usd := new(big.Rat).Mul(underlying.Usd, source.Ratio)
+
+		return &Price{Usd: usd, UpdatedAt: underlying.UpdatedAt, Source: source.Type}, nil
+
+	case SourceStatic:
+		usd := new(big.Rat).Set(source.Price)
+
+		return &Price{Usd: usd, Source: source.Type}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown source type %#v", source.Type)
+	}
+}
+
+func (oracle *Oracle) lookupUniswapV3Twap(source SourceConfig) (*Price, error) {
+	tick, err := getUniswapV3TwapTick(oracle.client, source.Address, source.Seconds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		usd = uniswap_v3.TickToPrice(tick)
+		ten = big.NewRat(10, 1)
+	)
+
+	// the tick is the price of token0 in token1 with their decimals,
+	// so scale it by 10^(token0 decimals - token1 decimals)
+
+	usd.Mul(usd, ethereum.BigPow(ten, source.Token0Decimals))
+	usd.Quo(usd, ethereum.BigPow(ten, source.Token1Decimals))
+
+	if usd.Sign() == 0 {
+		return nil, fmt.Errorf("uniswap v3 pool %v returned a price of 0", source.Address)
+	}
+
+	if source.Invert {
+		usd.Inv(usd)
+	}
+
+	updatedAt := oracle.now()
+
+	// if the pool isn't quoted in USD, then convert with the quote token
+
+	if source.Token != "" {
+		quote, err := oracle.GetPrice(source.Token)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get the price of the quote token %v! %w",
+				source.Token,
+				err,
+			)
+		}
+
+		usd.Mul(usd, quote.Usd)
+
+		if !quote.UpdatedAt.IsZero() && quote.UpdatedAt.Before(updatedAt) {
+			updatedAt = quote.UpdatedAt
+		}
+	}
+
+	return &Price{Usd: usd, UpdatedAt: updatedAt, Source: source.Type}, nil
+}
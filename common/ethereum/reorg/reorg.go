@@ -0,0 +1,185 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package reorg
+
+// reorg detects chain reorganisations by keeping a window of the most
+// recent blocks seen, checking that every new header's parent is the
+// block that was seen at the height before it
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+)
+
+type (
+	// Block in the window, kept small so the window is cheap to store
+	Block struct {
+		Number     uint64        `json:"number"`
+		Hash       ethereum.Hash `json:"hash"`
+		ParentHash ethereum.Hash `json:"parent_hash"`
+	}
+
+	// HeaderByHash looks up a header that was missed, to walk back along
+	// the new chain until it meets a block in the window
+	HeaderByHash func(hash ethereum.Hash) (*ethereum.BlockHeader, error)
+
+	// Window of recent blocks, ordered by block number
+	Window struct {
+		size   int
+		blocks []Block
+	}
+)
+
+// NewWindow keeping up to size blocks, starting with the blocks given
+// (usually restored from the last time the window was stored)
+func NewWindow(size int, blocks []Block) *Window {
+	window := &Window{size: size}
+
+	for _, block := range blocks {
+		window.insert(block)
+	}
+
+	window.trim()
+
+	return window
+}
+
+// Blocks in the window, ordered by block number, to store
+func (window *Window) Blocks() []Block {
+	blocks := make([]Block, len(window.blocks))
+
+	copy(blocks, window.blocks)
+
+	return blocks
+}
+
+// Add a new header to the window, returning the blocks that were
+// orphaned if the header is on a different chain to the window and the
+// headers that should be sent downstream, oldest first. If the header's
+// parent wasn't seen, headers are looked up with headerByHash back to
+// the last block both chains have in common, so the new chain is sent
+// in full. Headers that were already seen are ignored.
+func (window *Window) Add(header ethereum.BlockHeader, headerByHash HeaderByHash) (orphaned []Block, headers []ethereum.BlockHeader, err error) {
+	block := blockFromHeader(header)
+
+	if existing, ok := window.get(block.Number); ok && existing.Hash == block.Hash {
+		return nil, nil, nil
+	}
+
+	headers = []ethereum.BlockHeader{header}
+
+	// walk back along the new chain until the parent is the block in
+	// the window at that height, or the window doesn't go back that far
+
+	oldest := block
+
+	for oldest.Number > 0 {
+		parent, ok := window.get(oldest.Number - 1)
+
+		if !ok || parent.Hash == oldest.ParentHash {
+			break
+		}
+
+		parentHeader, err := headerByHash(oldest.ParentHash)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf(
+				"failed to look up the header for %v, the parent of block %v! %v",
+				oldest.ParentHash,
+				oldest.Number,
+				err,
+			)
+		}
+
+		if parentHeader == nil {
+			return nil, nil, fmt.Errorf(
+				"the header for %v, the parent of block %v, doesn't exist!",
+				oldest.ParentHash,
+				oldest.Number,
+			)
+		}
+
+		headers = append(headers, *parentHeader)
+
+		oldest = blockFromHeader(*parentHeader)
+	}
+
+	// every block from the oldest block on the new chain onwards was
+	// replaced, including any after the new header if the chain got
+	// shorter
+
+	kept := make([]Block, 0, len(window.blocks))
+
+	for _, existing := range window.blocks {
+		if existing.Number >= oldest.Number {
+			orphaned = append(orphaned, existing)
+		} else {
+			kept = append(kept, existing)
+		}
+	}
+
+	window.blocks = kept
+
+	for i := len(headers) - 1; i >= 0; i-- {
+		window.insert(blockFromHeader(headers[i]))
+	}
+
+	window.trim()
+
+	// reverse the headers so the oldest is sent first
+
+	for i, j := 0, len(headers)-1; i < j; i, j = i+1, j-1 {
+		headers[i], headers[j] = headers[j], headers[i]
+	}
+
+	return orphaned, headers, nil
+}
+
+func (window *Window) get(number uint64) (Block, bool) {
+	i := sort.Search(len(window.blocks), func(i int) bool {
+		return window.blocks[i].Number >= number
+	})
+
+	if i < len(window.blocks) && window.blocks[i].Number == number {
+		return window.blocks[i], true
+	}
+
+	return Block{}, false
+}
+
+// insert a block in order, replacing any block at the same height
+func (window *Window) insert(block Block) {
+	i := sort.Search(len(window.blocks), func(i int) bool {
+		return window.blocks[i].Number >= block.Number
+	})
+
+	if i < len(window.blocks) && window.blocks[i].Number == block.Number {
+		window.blocks[i] = block
+		return
+	}
+
+	window.blocks = append(window.blocks, Block{})
+
+	copy(window.blocks[i+1:], window.blocks[i:])
+
+	window.blocks[i] = block
+}
+
+// trim the oldest blocks until the window is no larger than its size
+func (window *Window) trim() {
+	if excess := len(window.blocks) - window.size; excess > 0 {
+		window.blocks = window.blocks[excess:]
+	}
+}
+
+func blockFromHeader(header ethereum.BlockHeader) Block {
+	return Block{
+		Number:     header.Number.Uint64(),
+		Hash:       header.BlockHash,
+		ParentHash: header.ParentHash,
+	}
+}
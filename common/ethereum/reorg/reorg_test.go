@@ -0,0 +1,259 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package reorg
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// simulatedChain of headers, forking from other chains
+type simulatedChain struct {
+	name    string
+	headers map[uint64]ethereum.BlockHeader
+}
+
+// simulatedHeaders that the node knows about, looked up by hash
+type simulatedHeaders map[ethereum.Hash]ethereum.BlockHeader
+
+func simulatedHash(chain string, number uint64) ethereum.Hash {
+	return ethereum.HashFromString(fmt.Sprintf("0x%s%062x", chain, number))
+}
+
+// newSimulatedChain of blocks from..to, with from's parent taken from
+// the parent chain (or a genesis hash if there isn't one)
+func newSimulatedChain(node simulatedHeaders, name string, parent *simulatedChain, from, to uint64) *simulatedChain {
+	chain := &simulatedChain{
+		name:    name,
+		headers: make(map[uint64]ethereum.BlockHeader),
+	}
+
+	for number := from; number <= to; number++ {
+		var parentHash ethereum.Hash
+
+		switch {
+		case number > from:
+			parentHash = chain.headers[number-1].BlockHash
+
+		case parent != nil:
+			parentHash = parent.headers[number-1].BlockHash
+
+		default:
+			parentHash = simulatedHash("ff", number-1)
+		}
+
+		header := ethereum.BlockHeader{
+			BlockHash:  simulatedHash(name, number),
+			ParentHash: parentHash,
+			Number:     misc.BigIntFromUint64(number),
+		}
+
+		chain.headers[number] = header
+
+		node[header.BlockHash] = header
+	}
+
+	return chain
+}
+
+func (node simulatedHeaders) headerByHash(hash ethereum.Hash) (*ethereum.BlockHeader, error) {
+	header, ok := node[hash]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown block %v", hash)
+	}
+
+	return &header, nil
+}
+
+func blockNumbers(blocks []Block) []uint64 {
+	numbers := make([]uint64, len(blocks))
+
+	for i, block := range blocks {
+		numbers[i] = block.Number
+	}
+
+	return numbers
+}
+
+func headerHashes(headers []ethereum.BlockHeader) []ethereum.Hash {
+	hashes := make([]ethereum.Hash, len(headers))
+
+	for i, header := range headers {
+		hashes[i] = header.BlockHash
+	}
+
+	return hashes
+}
+
+// stream headers from..to of the chain into the window, failing if any
+// of them orphaned a block
+func stream(t *testing.T, window *Window, node simulatedHeaders, chain *simulatedChain, from, to uint64) {
+	for number := from; number <= to; number++ {
+		header := chain.headers[number]
+
+		orphaned, headers, err := window.Add(header, node.headerByHash)
+
+		require.NoError(t, err)
+		assert.Empty(t, orphaned)
+		assert.Equal(t, []ethereum.Hash{header.BlockHash}, headerHashes(headers))
+	}
+}
+
+func TestWindowNoReorg(t *testing.T) {
+	node := make(simulatedHeaders)
+
+	chain := newSimulatedChain(node, "aa", nil, 1, 10)
+
+	window := NewWindow(5, nil)
+
+	stream(t, window, node, chain, 1, 10)
+
+	assert.Equal(t, []uint64{6, 7, 8, 9, 10}, blockNumbers(window.Blocks()))
+
+	// a header that was already seen is ignored
+
+	orphaned, headers, err := window.Add(chain.headers[10], node.headerByHash)
+
+	require.NoError(t, err)
+	assert.Empty(t, orphaned)
+	assert.Empty(t, headers)
+}
+
+func TestWindowReorg(t *testing.T) {
+	node := make(simulatedHeaders)
+
+	var (
+		chain = newSimulatedChain(node, "aa", nil, 1, 5)
+		fork  = newSimulatedChain(node, "bb", chain, 4, 7)
+	)
+
+	window := NewWindow(10, nil)
+
+	stream(t, window, node, chain, 1, 5)
+
+	// block 4 on the fork replaces 4 and 5 on the old chain
+
+	orphaned, headers, err := window.Add(fork.headers[4], node.headerByHash)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []Block{
+		blockFromHeader(chain.headers[4]),
+		blockFromHeader(chain.headers[5]),
+	}, orphaned)
+
+	assert.Equal(t, []ethereum.Hash{fork.headers[4].BlockHash}, headerHashes(headers))
+
+	stream(t, window, node, fork, 5, 7)
+
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7}, blockNumbers(window.Blocks()))
+}
+
+func TestWindowReorgMissedHeaders(t *testing.T) {
+	node := make(simulatedHeaders)
+
+	var (
+		chain = newSimulatedChain(node, "aa", nil, 1, 6)
+		fork  = newSimulatedChain(node, "bb", chain, 3, 7)
+	)
+
+	window := NewWindow(10, nil)
+
+	stream(t, window, node, chain, 1, 6)
+
+	// only the tip of the fork was seen, so the headers before it are
+	// looked up back to block 2, which both chains have
+
+	orphaned, headers, err := window.Add(fork.headers[7], node.headerByHash)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint64{3, 4, 5, 6}, blockNumbers(orphaned))
+
+	assert.Equal(t, []ethereum.Hash{
+		fork.headers[3].BlockHash,
+		fork.headers[4].BlockHash,
+		fork.headers[5].BlockHash,
+		fork.headers[6].BlockHash,
+		fork.headers[7].BlockHash,
+	}, headerHashes(headers))
+
+	assert.Equal(t, fork.headers[7].BlockHash, window.Blocks()[6].Hash)
+	assert.Equal(t, chain.headers[2].BlockHash, window.Blocks()[1].Hash)
+}
+
+func TestWindowReorgDeeperThanWindow(t *testing.T) {
+	node := make(simulatedHeaders)
+
+	var (
+		chain = newSimulatedChain(node, "aa", nil, 1, 10)
+		fork  = newSimulatedChain(node, "bb", chain, 5, 10)
+	)
+
+	window := NewWindow(3, nil)
+
+	stream(t, window, node, chain, 1, 10)
+
+	// the window only goes back to block 8, so everything in it is
+	// orphaned and the walk stops there
+
+	orphaned, headers, err := window.Add(fork.headers[10], node.headerByHash)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint64{8, 9, 10}, blockNumbers(orphaned))
+	assert.Len(t, headers, 3)
+}
+
+func TestWindowRestored(t *testing.T) {
+	node := make(simulatedHeaders)
+
+	var (
+		chain = newSimulatedChain(node, "aa", nil, 1, 5)
+		fork  = newSimulatedChain(node, "bb", chain, 5, 5)
+	)
+
+	window := NewWindow(10, nil)
+
+	stream(t, window, node, chain, 1, 5)
+
+	restored := NewWindow(10, window.Blocks())
+
+	orphaned, _, err := restored.Add(fork.headers[5], node.headerByHash)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []uint64{5}, blockNumbers(orphaned))
+}
+
+func TestWindowHeaderLookupFails(t *testing.T) {
+	node := make(simulatedHeaders)
+
+	var (
+		chain = newSimulatedChain(node, "aa", nil, 1, 4)
+		fork  = newSimulatedChain(node, "bb", chain, 3, 4)
+	)
+
+	window := NewWindow(10, nil)
+
+	stream(t, window, node, chain, 1, 4)
+
+	delete(node, fork.headers[3].BlockHash)
+
+	_, _, err := window.Add(fork.headers[4], node.headerByHash)
+
+	assert.Error(t, err)
+
+	// the window is untouched so the header can be tried again
+
+	assert.Equal(t, chain.headers[4].BlockHash, window.Blocks()[3].Hash)
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package spooler
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+)
+
+// BatchBackend pays out batches of rewards by sending them to the
+// worker-sender, which calls batchReward on the token contract
+type BatchBackend struct {
+	// QueueName the worker-sender receives batches on
+	QueueName string
+
+	// Send the batch down the queue, usually queue.SendMessage
+	Send func(queueName string, content interface{})
+}
+
+// Payout the batch by sending it to the worker-sender
+func (backend BatchBackend) Payout(rewards worker.EthereumSpooledRewards) error {
+	backend.Send(backend.QueueName, rewards)
+
+	return nil
+}
+
+// Simulate returns the batch that would be sent to the worker-sender,
+// which simulates the transaction itself if it's in a dry run
+func (backend BatchBackend) Simulate(rewards worker.EthereumSpooledRewards) ([]dry_run.Result, error) {
+	message, err := json.Marshal(rewards)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode the rewards for token %s! %v",
+			rewards.Token.TokenShortName,
+			err,
+		)
+	}
+
+	result := dry_run.Result{
+		Network:     rewards.Network,
+		Description: "release batched rewards",
+		Recipient:   backend.QueueName,
+		Message:     message,
+		Success:     true,
+		Time:        time.Now(),
+	}
+
+	return []dry_run.Result{result}, nil
+}
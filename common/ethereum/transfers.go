@@ -16,6 +16,8 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
 	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/applications/registry"
 )
 
 // TransferLogTopic is the signature of token transfers
@@ -165,7 +167,8 @@ func GetTransfers(logs []ethereum.Log, transactions []ethereum.Transaction, bloc
 
 // GetApplicationTransfers to use the passed function to classify
 // individual logs and their respective transactions as generated by
-// an application we support, to be processed later
+// an application we support, to be processed later. Logs with a topic
+// not handled by a registered application are skipped
 func GetApplicationTransfers(logs []ethereum.Log, transactions []ethereum.Transaction, blockHash ethereum.Hash, applicationContracts map[ethereum.Address]applications.Application) map[ethereum.Hash][]worker.EthereumApplicationTransfer {
 	transfers := make(map[ethereum.Hash][]worker.EthereumApplicationTransfer)
 
@@ -201,6 +204,20 @@ func GetApplicationTransfers(logs []ethereum.Log, transactions []ethereum.Transa
 			continue
 		}
 
+		firstTopic := topics[0].String()
+
+		if !registry.HandlesTopic(app, firstTopic) {
+			log.Debugf(
+				"For transaction hash %#v, index %v, topic %v isn't handled by %v!",
+				transactionHash,
+				index.String(),
+				firstTopic,
+				app,
+			)
+
+			continue
+		}
+
 		transfer := worker.EthereumApplicationTransfer{
 			TransactionHash: transactionHash,
 			Log:             transferLog,
@@ -0,0 +1,28 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package txmanager
+
+import (
+	"context"
+	"math/big"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+)
+
+// Simulator to simulate calls with, satisfied by *ethclient.Client
+type Simulator interface {
+	CallContract(ctx context.Context, call goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	EstimateGas(ctx context.Context, call goEthereum.CallMsg) (uint64, error)
+}
+
+// SimulateCall with eth_call against the latest block, then estimate the
+// gas it would use, returning the error the call reverted with
+func SimulateCall(ctx context.Context, client Simulator, call goEthereum.CallMsg) (gasEstimate uint64, err error) {
+	if _, err := client.CallContract(ctx, call, nil); err != nil {
+		return 0, err
+	}
+
+	return client.EstimateGas(ctx, call)
+}
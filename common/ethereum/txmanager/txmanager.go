@@ -0,0 +1,891 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// txmanager sends transactions and follows them until they're mined,
+// reserving nonces so several can be in flight at once, bumping their
+// fees if they aren't mined in time, and recording every attempt made.
+// Nonces and attempts are kept in a Store, usually transactions.Store
+// (Postgres). With FLU_DRY_RUN set, transactions are signed and simulated
+// instead, and the results published.
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	libEthereum "github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	ethAbiBind "github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// Context to use for logging
+const Context = "ETHEREUM/TXMANAGER"
+
+// MinFeeBumpPercent is the smallest bump nodes accept to replace a
+// transaction in their mempool
+const MinFeeBumpPercent = 10
+
+var (
+	// ErrReplacedExternally when the nonce of a transaction was used by
+	// a transaction the manager didn't send
+	ErrReplacedExternally = errors.New("the nonce was used by another transaction")
+
+	// ErrMaxAttempts when a transaction wasn't mined after being sent
+	// the maximum number of times
+	ErrMaxAttempts = errors.New("the transaction wasn't mined after the maximum number of attempts")
+)
+
+type (
+	// Client to send and follow transactions with, satisfied by
+	// *ethclient.Client
+	Client interface {
+		ChainID(ctx context.Context) (*big.Int, error)
+		PendingNonceAt(ctx context.Context, account ethCommon.Address) (uint64, error)
+		NonceAt(ctx context.Context, account ethCommon.Address, blockNumber *big.Int) (uint64, error)
+		HeaderByNumber(ctx context.Context, number *big.Int) (*ethTypes.Header, error)
+		SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+		SuggestGasPrice(ctx context.Context) (*big.Int, error)
+		SendTransaction(ctx context.Context, transaction *ethTypes.Transaction) error
+		TransactionByHash(ctx context.Context, hash ethCommon.Hash) (*ethTypes.Transaction, bool, error)
+		TransactionReceipt(ctx context.Context, hash ethCommon.Hash) (*ethTypes.Receipt, error)
+		CallContract(ctx context.Context, call goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+		EstimateGas(ctx context.Context, call goEthereum.CallMsg) (uint64, error)
+	}
+
+	// Store of the nonces reserved and the attempts made
+	Store interface {
+		// ReserveNonce to use, which is the highest of the nonce
+		// stored and the pending nonce given
+		ReserveNonce(chainId uint64, address ethereum.Address, pendingNonce uint64) uint64
+
+		// ReleaseNonce that was reserved but never sent
+		ReleaseNonce(chainId uint64, address ethereum.Address, nonce uint64)
+
+		// ResetNonce to reserve next to the nonce given, if the nonce
+		// stored is past it
+		ResetNonce(chainId uint64, address ethereum.Address, nonce uint64)
+
+		InsertAttempt(attempt ethereum.TransactionAttempt)
+
+		UpdateAttemptStatus(chainId uint64, transactionHash ethereum.Hash, status ethereum.TransactionAttemptStatus)
+	}
+
+	// Config of how transactions are followed and bumped
+	Config struct {
+		// ResubmitTimeout to wait for a transaction to be mined before
+		// sending it again with higher fees
+		ResubmitTimeout time.Duration
+
+		// PollInterval to check for receipts with
+		PollInterval time.Duration
+
+		// FeeBumpPercent to raise the fees by each time, at least
+		// MinFeeBumpPercent
+		FeeBumpPercent int64
+
+		// MaxAttempts to send a transaction before giving up
+		MaxAttempts int
+
+		// MaxGasFeeCap to never bump the fee cap (or gas price) past,
+		// no limit if nil
+		MaxGasFeeCap *big.Int
+
+		// DryRun to simulate transactions instead of sending them,
+		// without reserving nonces or recording attempts
+		DryRun bool
+
+		// PublishDryRun to call with the result of each simulation,
+		// usually dry_run.Publish
+		PublishDryRun func(dry_run.Result)
+	}
+
+	// TransactFunc to build and sign a transaction with the options
+	// given, without sending it (they have NoSend set)
+	TransactFunc func(transactionOptions *ethAbiBind.TransactOpts) (*ethTypes.Transaction, error)
+
+	// Manager of the transactions sent by a single account
+	Manager struct {
+		client             Client
+		store              Store
+		transactionOptions *ethAbiBind.TransactOpts
+		config             Config
+		chainId            uint64
+		address            ethereum.Address
+
+		// mu is held while a nonce is reserved and signed with
+		mu sync.Mutex
+
+		// inFlight nonces of the transactions being followed, guarded
+		// by mu
+		inFlight map[uint64]bool
+	}
+)
+
+// DefaultConfig to use for the manager
+func DefaultConfig() Config {
+	return Config{
+		ResubmitTimeout: 2 * time.Minute,
+		PollInterval:    5 * time.Second,
+		FeeBumpPercent:  12,
+		MaxAttempts:     10,
+		MaxGasFeeCap:    nil,
+		DryRun:          util.DryRunEnabled(),
+		PublishDryRun:   nil,
+	}
+}
+
+// New manager sending transactions signed with the options given,
+// usually from NewTransactionOptions
+func New(client Client, store Store, transactionOptions *ethAbiBind.TransactOpts, config Config) (*Manager, error) {
+	if config.FeeBumpPercent < MinFeeBumpPercent {
+		return nil, fmt.Errorf(
+			"fee bump percent %v is less than the minimum of %v",
+			config.FeeBumpPercent,
+			MinFeeBumpPercent,
+		)
+	}
+
+	if config.MaxAttempts < 1 {
+		return nil, fmt.Errorf(
+			"max attempts %v should be at least 1",
+			config.MaxAttempts,
+		)
+	}
+
+	chainId, err := client.ChainID(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the chain id! %v",
+			err,
+		)
+	}
+
+	manager := &Manager{
+		client:             client,
+		store:              store,
+		transactionOptions: transactionOptions,
+		config:             config,
+		chainId:            chainId.Uint64(),
+		address:            libEthereum.ConvertGethAddress(transactionOptions.From),
+		inFlight:           make(map[uint64]bool),
+	}
+
+	return manager, nil
+}
+
+// Send a transaction built by transact, with a nonce reserved for it,
+// waiting until it's mined and bumping its fees if it takes too long.
+// transact should update the gas amounts as usual, which are used for
+// the first attempt. Returns the receipt of the attempt that was mined,
+// which could have reverted. In dry run mode, returns a receipt with the
+// result of the simulation and the gas estimate, without a block.
+func (manager *Manager) Send(ctx context.Context, transact TransactFunc) (*ethTypes.Receipt, error) {
+	if manager.config.DryRun {
+		return manager.simulate(ctx, transact)
+	}
+
+	transaction, err := manager.sign(ctx, transact)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer manager.land(transaction.Nonce())
+
+	return manager.follow(ctx, transaction)
+}
+
+// DryRun is true if transactions are simulated instead of sent
+func (manager *Manager) DryRun() bool {
+	return manager.config.DryRun
+}
+
+// sign a transaction using the next nonce, releasing it if that fails
+func (manager *Manager) sign(ctx context.Context, transact TransactFunc) (*ethTypes.Transaction, error) {
+	manager.mu.Lock()
+
+	defer manager.mu.Unlock()
+
+	var (
+		chainId = manager.chainId
+		address = manager.address
+	)
+
+	pendingNonce, err := manager.client.PendingNonceAt(
+		ctx,
+		manager.transactionOptions.From,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the pending nonce! %v",
+			err,
+		)
+	}
+
+	nonce := manager.nextNonce(pendingNonce)
+
+	// copy the options so the caller can change the gas amounts freely
+
+	transactionOptions := *manager.transactionOptions
+
+	transactionOptions.Context = ctx
+	transactionOptions.Nonce = new(big.Int).SetUint64(nonce)
+	transactionOptions.NoSend = true
+
+	transaction, err := transact(&transactionOptions)
+
+	if err == nil && transaction.Nonce() != nonce {
+		err = fmt.Errorf(
+			"transaction was signed with nonce %v, not %v",
+			transaction.Nonce(),
+			nonce,
+		)
+	}
+
+	if err != nil {
+		delete(manager.inFlight, nonce)
+
+		manager.store.ReleaseNonce(chainId, address, nonce)
+
+		return nil, fmt.Errorf(
+			"failed to make the transaction with nonce %v! %w",
+			nonce,
+			err,
+		)
+	}
+
+	return transaction, nil
+}
+
+// nextNonce to sign with, marking it as in flight. Transactions that
+// were abandoned or lost leave a gap at the node's pending nonce that
+// every later transaction waits on, so the gap is filled by the next
+// transaction if it's below a nonce in flight that isn't being followed,
+// or the stored nonce is reset to it if nothing is in flight. Assumes
+// that one manager sends for the address at a time. Called with mu held.
+func (manager *Manager) nextNonce(pendingNonce uint64) uint64 {
+	var (
+		chainId = manager.chainId
+		address = manager.address
+
+		nonce uint64
+	)
+
+	switch {
+	case len(manager.inFlight) == 0:
+		manager.store.ResetNonce(chainId, address, pendingNonce)
+
+		nonce = manager.store.ReserveNonce(chainId, address, pendingNonce)
+
+	case !manager.inFlight[pendingNonce] && pendingNonce < maxNonce(manager.inFlight):
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Nonce %v isn't being followed but later nonces are in flight, filling the gap!",
+				pendingNonce,
+			)
+		})
+
+		nonce = pendingNonce
+
+	default:
+		nonce = manager.store.ReserveNonce(chainId, address, pendingNonce)
+	}
+
+	manager.inFlight[nonce] = true
+
+	return nonce
+}
+
+// land the nonce once its transaction stops being followed, whether it
+// was mined or not
+func (manager *Manager) land(nonce uint64) {
+	manager.mu.Lock()
+
+	defer manager.mu.Unlock()
+
+	delete(manager.inFlight, nonce)
+}
+
+// follow the transaction until it's mined, sending it again with higher
+// fees each time it isn't mined in time
+func (manager *Manager) follow(ctx context.Context, transaction *ethTypes.Transaction) (*ethTypes.Receipt, error) {
+	var (
+		nonce    = transaction.Nonce()
+		attempts = make([]*ethTypes.Transaction, 0, manager.config.MaxAttempts)
+		statuses = make(map[ethCommon.Hash]ethereum.TransactionAttemptStatus)
+	)
+
+	setStatus := func(hash ethCommon.Hash, status ethereum.TransactionAttemptStatus) {
+		statuses[hash] = status
+
+		manager.store.UpdateAttemptStatus(
+			manager.chainId,
+			libEthereum.ConvertGethHash(hash),
+			status,
+		)
+	}
+
+	// finish by setting the status of every attempt still pending
+
+	finish := func(minedHash *ethCommon.Hash, minedStatus, otherStatus ethereum.TransactionAttemptStatus) {
+		for _, attempt := range attempts {
+			hash := attempt.Hash()
+
+			switch {
+			case minedHash != nil && hash == *minedHash:
+				setStatus(hash, minedStatus)
+
+			case statuses[hash] == ethereum.TransactionAttemptPending:
+				setStatus(hash, otherStatus)
+			}
+		}
+	}
+
+	for attemptNumber := 1; ; attemptNumber++ {
+		hash := transaction.Hash()
+
+		// a transaction that couldn't be bumped is sent again as is
+
+		if _, seen := statuses[hash]; !seen {
+			manager.recordAttempt(transaction, attemptNumber)
+
+			statuses[hash] = ethereum.TransactionAttemptPending
+		}
+
+		err := manager.client.SendTransaction(ctx, transaction)
+
+		switch {
+		case err == nil || isAlreadyKnown(err):
+			if len(attempts) == 0 || attempts[len(attempts)-1].Hash() != hash {
+				attempts = append(attempts, transaction)
+			}
+
+		case isNonceTooLow(err) || isUnderpriced(err):
+			// an earlier attempt could have been mined, or a bump
+			// wasn't enough, both of which are handled below
+
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Transaction %v with nonce %v wasn't accepted, following earlier attempts: %v",
+					hash.Hex(),
+					nonce,
+					err,
+				)
+			})
+
+			setStatus(hash, ethereum.TransactionAttemptAbandoned)
+
+		case len(attempts) == 0:
+			// the first attempt was never sent, so the nonce can be used again
+
+			setStatus(hash, ethereum.TransactionAttemptAbandoned)
+
+			manager.store.ReleaseNonce(manager.chainId, manager.address, nonce)
+
+			return nil, fmt.Errorf(
+				"failed to send transaction %v with nonce %v! %w",
+				hash.Hex(),
+				nonce,
+				err,
+			)
+
+		default:
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Failed to send attempt %v of transaction %v with nonce %v, following earlier attempts: %v",
+					attemptNumber,
+					hash.Hex(),
+					nonce,
+					err,
+				)
+			})
+
+			setStatus(hash, ethereum.TransactionAttemptAbandoned)
+		}
+
+		if len(attempts) == 0 {
+			return nil, fmt.Errorf(
+				"no attempt of the transaction with nonce %v was sent! %w",
+				nonce,
+				err,
+			)
+		}
+
+		receipt, err := manager.waitMined(ctx, attempts)
+
+		if err != nil {
+			finish(nil, "", ethereum.TransactionAttemptAbandoned)
+
+			return nil, fmt.Errorf(
+				"stopped waiting for nonce %v to be mined! %w",
+				nonce,
+				err,
+			)
+		}
+
+		if receipt != nil {
+			finish(&receipt.TxHash, receiptStatus(receipt), ethereum.TransactionAttemptReplaced)
+
+			return receipt, nil
+		}
+
+		// if the nonce was used and none of our attempts were mined,
+		// then something else replaced them
+
+		minedNonce, err := manager.client.NonceAt(ctx, manager.transactionOptions.From, nil)
+
+		if err != nil {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+				k.Message = "Failed to get the mined nonce, assuming it wasn't used!"
+				k.Payload = err
+			})
+		}
+
+		if err == nil && minedNonce > nonce {
+			// the receipt could've been written since we last checked
+
+			receipt, err := manager.findReceipt(ctx, attempts)
+
+			if err != nil {
+				finish(nil, "", ethereum.TransactionAttemptAbandoned)
+
+				return nil, err
+			}
+
+			if receipt != nil {
+				finish(&receipt.TxHash, receiptStatus(receipt), ethereum.TransactionAttemptReplaced)
+
+				return receipt, nil
+			}
+
+			finish(nil, "", ethereum.TransactionAttemptReplacedExternally)
+
+			return nil, fmt.Errorf(
+				"nonce %v was mined without any of our %v attempts! %w",
+				nonce,
+				len(attempts),
+				ErrReplacedExternally,
+			)
+		}
+
+		latest := attempts[len(attempts)-1]
+
+		_, _, err = manager.client.TransactionByHash(ctx, latest.Hash())
+
+		if errors.Is(err, goEthereum.NotFound) {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Transaction %v with nonce %v was dropped by the node!",
+					latest.Hash().Hex(),
+					nonce,
+				)
+			})
+
+			setStatus(latest.Hash(), ethereum.TransactionAttemptDropped)
+		}
+
+		if attemptNumber >= manager.config.MaxAttempts {
+			finish(nil, "", ethereum.TransactionAttemptAbandoned)
+
+			return nil, fmt.Errorf(
+				"nonce %v wasn't mined after %v attempts! %w",
+				nonce,
+				attemptNumber,
+				ErrMaxAttempts,
+			)
+		}
+
+		transaction, err = manager.bump(ctx, latest)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to bump the fees of transaction %v! %v",
+				latest.Hash().Hex(),
+				err,
+			)
+		}
+
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Transaction %v with nonce %v wasn't mined in %v, sending %v with tip cap %v and fee cap %v",
+				latest.Hash().Hex(),
+				nonce,
+				manager.config.ResubmitTimeout,
+				transaction.Hash().Hex(),
+				transaction.GasTipCap(),
+				transaction.GasFeeCap(),
+			)
+		})
+	}
+}
+
+// simulate the transaction built by transact with eth_call and
+// eth_estimateGas, using the pending nonce and publishing the result
+func (manager *Manager) simulate(ctx context.Context, transact TransactFunc) (*ethTypes.Receipt, error) {
+	from := manager.transactionOptions.From
+
+	pendingNonce, err := manager.client.PendingNonceAt(ctx, from)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the pending nonce! %v",
+			err,
+		)
+	}
+
+	transactionOptions := *manager.transactionOptions
+
+	transactionOptions.Context = ctx
+	transactionOptions.Nonce = new(big.Int).SetUint64(pendingNonce)
+	transactionOptions.NoSend = true
+
+	result := dry_run.Result{
+		Network: network.NetworkEthereum,
+		ChainId: manager.chainId,
+		Sender:  manager.address.String(),
+		Time:    time.Now(),
+	}
+
+	transaction, err := transact(&transactionOptions)
+
+	if err != nil {
+		result.Error = err.Error()
+
+		manager.publishDryRun(result)
+
+		return nil, fmt.Errorf(
+			"failed to make the transaction to simulate! %w",
+			err,
+		)
+	}
+
+	encoded, err := transaction.MarshalBinary()
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode transaction %v! %v",
+			transaction.Hash().Hex(),
+			err,
+		)
+	}
+
+	call := goEthereum.CallMsg{
+		From:       from,
+		To:         transaction.To(),
+		Value:      transaction.Value(),
+		Data:       transaction.Data(),
+		AccessList: transaction.AccessList(),
+	}
+
+	if transaction.Type() == ethTypes.DynamicFeeTxType {
+		call.GasFeeCap = transaction.GasFeeCap()
+		call.GasTipCap = transaction.GasTipCap()
+	} else {
+		call.GasPrice = transaction.GasPrice()
+	}
+
+	if to := transaction.To(); to != nil {
+		result.Recipient = libEthereum.ConvertGethAddress(*to).String()
+	}
+
+	result.TransactionHash = transaction.Hash().Hex()
+	result.Transaction = hexutil.Encode(encoded)
+
+	result.GasEstimate, err = SimulateCall(ctx, manager.client, call)
+
+	receipt := &ethTypes.Receipt{
+		Type:    transaction.Type(),
+		Status:  ethTypes.ReceiptStatusSuccessful,
+		TxHash:  transaction.Hash(),
+		GasUsed: result.GasEstimate,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		receipt.Status = ethTypes.ReceiptStatusFailed
+	} else {
+		result.Success = true
+	}
+
+	manager.publishDryRun(result)
+
+	return receipt, nil
+}
+
+func (manager *Manager) publishDryRun(result dry_run.Result) {
+	if publish := manager.config.PublishDryRun; publish != nil {
+		publish(result)
+		return
+	}
+
+	log.App(func(k *log.Log) {
+		k.Context = Context
+		k.Message = "Simulated a transaction in dry run mode!"
+		k.Payload = result
+	})
+}
+
+// waitMined polls for the receipt of any of the attempts until the
+// resubmit timeout, returning nil if none were mined in time
+func (manager *Manager) waitMined(ctx context.Context, attempts []*ethTypes.Transaction) (*ethTypes.Receipt, error) {
+	var (
+		timeout = time.NewTimer(manager.config.ResubmitTimeout)
+		ticker  = time.NewTicker(manager.config.PollInterval)
+	)
+
+	defer timeout.Stop()
+	defer ticker.Stop()
+
+	for {
+		receipt, err := manager.findReceipt(ctx, attempts)
+
+		if receipt != nil || err != nil {
+			return receipt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-timeout.C:
+			return nil, nil
+
+		case <-ticker.C:
+		}
+	}
+}
+
+// findReceipt of any of the attempts, starting with the latest,
+// returning nil if none of them were mined
+func (manager *Manager) findReceipt(ctx context.Context, attempts []*ethTypes.Transaction) (*ethTypes.Receipt, error) {
+	for i := len(attempts) - 1; i >= 0; i-- {
+		hash := attempts[i].Hash()
+
+		receipt, err := manager.client.TransactionReceipt(ctx, hash)
+
+		switch {
+		case err == nil && receipt != nil:
+			return receipt, nil
+
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+
+		case err != nil && !errors.Is(err, goEthereum.NotFound):
+			log.App(func(k *log.Log) {
+				k.Context = Context
+				k.Format("Failed to get the receipt of transaction %v!", hash.Hex())
+				k.Payload = err
+			})
+		}
+	}
+
+	return nil, nil
+}
+
+// bump the fees of the transaction, signing it again with the same
+// nonce. Returns the same transaction if the fees are at the maximum.
+func (manager *Manager) bump(ctx context.Context, transaction *ethTypes.Transaction) (*ethTypes.Transaction, error) {
+	var (
+		maxGasFeeCap = manager.config.MaxGasFeeCap
+		unsigned     ethTypes.TxData
+	)
+
+	if transaction.Type() == ethTypes.DynamicFeeTxType {
+		header, err := manager.client.HeaderByNumber(ctx, nil)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get the latest header! %v",
+				err,
+			)
+		}
+
+		suggestedTip, err := manager.client.SuggestGasTipCap(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to suggest the gas tip cap! %v",
+				err,
+			)
+		}
+
+		gasTipCap := maxBig(
+			manager.bumpAmount(transaction.GasTipCap()),
+			suggestedTip,
+		)
+
+		// same as go-ethereum's default, the tip with twice the base fee
+
+		minGasFeeCap := new(big.Int).Set(gasTipCap)
+
+		if header.BaseFee != nil {
+			doubleBaseFee := new(big.Int).Mul(header.BaseFee, big.NewInt(2))
+			minGasFeeCap.Add(minGasFeeCap, doubleBaseFee)
+		}
+
+		gasFeeCap := maxBig(
+			manager.bumpAmount(transaction.GasFeeCap()),
+			minGasFeeCap,
+		)
+
+		if maxGasFeeCap != nil && gasFeeCap.Cmp(maxGasFeeCap) > 0 {
+			gasFeeCap = new(big.Int).Set(maxGasFeeCap)
+		}
+
+		if gasTipCap.Cmp(gasFeeCap) > 0 {
+			gasTipCap = new(big.Int).Set(gasFeeCap)
+		}
+
+		if gasFeeCap.Cmp(transaction.GasFeeCap()) <= 0 {
+			return transaction, nil
+		}
+
+		unsigned = &ethTypes.DynamicFeeTx{
+			ChainID:    transaction.ChainId(),
+			Nonce:      transaction.Nonce(),
+			GasTipCap:  gasTipCap,
+			GasFeeCap:  gasFeeCap,
+			Gas:        transaction.Gas(),
+			To:         transaction.To(),
+			Value:      transaction.Value(),
+			Data:       transaction.Data(),
+			AccessList: transaction.AccessList(),
+		}
+	} else {
+		suggestedGasPrice, err := manager.client.SuggestGasPrice(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to suggest the gas price! %v",
+				err,
+			)
+		}
+
+		gasPrice := maxBig(
+			manager.bumpAmount(transaction.GasPrice()),
+			suggestedGasPrice,
+		)
+
+		if maxGasFeeCap != nil && gasPrice.Cmp(maxGasFeeCap) > 0 {
+			gasPrice = new(big.Int).Set(maxGasFeeCap)
+		}
+
+		if gasPrice.Cmp(transaction.GasPrice()) <= 0 {
+			return transaction, nil
+		}
+
+		unsigned = &ethTypes.LegacyTx{
+			Nonce:    transaction.Nonce(),
+			GasPrice: gasPrice,
+			Gas:      transaction.Gas(),
+			To:       transaction.To(),
+			Value:    transaction.Value(),
+			Data:     transaction.Data(),
+		}
+	}
+
+	return manager.transactionOptions.Signer(
+		manager.transactionOptions.From,
+		ethTypes.NewTx(unsigned),
+	)
+}
+
+// bumpAmount by the fee bump percent
+func (manager *Manager) bumpAmount(amount *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(
+		amount,
+		big.NewInt(100+manager.config.FeeBumpPercent),
+	)
+
+	return bumped.Quo(bumped, big.NewInt(100))
+}
+
+func (manager *Manager) recordAttempt(transaction *ethTypes.Transaction, attemptNumber int) {
+	manager.store.InsertAttempt(ethereum.TransactionAttempt{
+		ChainId:         manager.chainId,
+		Address:         manager.address,
+		Nonce:           transaction.Nonce(),
+		Attempt:         attemptNumber,
+		TransactionHash: libEthereum.ConvertGethHash(transaction.Hash()),
+		GasTipCap:       misc.NewBigIntFromInt(*transaction.GasTipCap()),
+		GasFeeCap:       misc.NewBigIntFromInt(*transaction.GasFeeCap()),
+		Status:          ethereum.TransactionAttemptPending,
+		CreatedTime:     time.Now(),
+	})
+}
+
+// receiptStatus to record for the attempt that was mined
+func receiptStatus(receipt *ethTypes.Receipt) ethereum.TransactionAttemptStatus {
+	if receipt.Status != ethTypes.ReceiptStatusSuccessful {
+		return ethereum.TransactionAttemptReverted
+	}
+
+	return ethereum.TransactionAttemptMined
+}
+
+func maxNonce(nonces map[uint64]bool) uint64 {
+	var max uint64
+
+	for nonce := range nonces {
+		if nonce > max {
+			max = nonce
+		}
+	}
+
+	return max
+}
+
+func maxBig(x, y *big.Int) *big.Int {
+	if x.Cmp(y) >= 0 {
+		return x
+	}
+
+	return y
+}
+
+// errors returned by nodes when sending a transaction, which aren't
+// exported by go-ethereum's client
+
+func isAlreadyKnown(err error) bool {
+	message := strings.ToLower(err.Error())
+
+	return strings.Contains(message, "already known") ||
+		strings.Contains(message, "known transaction")
+}
+
+func isNonceTooLow(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "nonce too low")
+}
+
+func isUnderpriced(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "underpriced")
+}
@@ -0,0 +1,493 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package txmanager
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+
+	goEthereum "github.com/ethereum/go-ethereum"
+	ethAbiBind "github.com/ethereum/go-ethereum/accounts/abi/bind"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	ethTypes "github.com/ethereum/go-ethereum/core/types"
+	ethCrypto "github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testChainId = big.NewInt(1)
+
+type fakeClient struct {
+	mu sync.Mutex
+
+	pendingNonce uint64
+	minedNonce   uint64
+	baseFee      *big.Int
+
+	sent     []*ethTypes.Transaction
+	receipts map[ethCommon.Hash]*ethTypes.Receipt
+
+	// mineAt the attempt with this index (starting at 1), 0 for never
+	mineAt int
+
+	// callErr to return when simulating transactions
+	callErr error
+}
+
+func (client *fakeClient) ChainID(ctx context.Context) (*big.Int, error) {
+	return testChainId, nil
+}
+
+func (client *fakeClient) PendingNonceAt(ctx context.Context, account ethCommon.Address) (uint64, error) {
+	return client.pendingNonce, nil
+}
+
+func (client *fakeClient) NonceAt(ctx context.Context, account ethCommon.Address, blockNumber *big.Int) (uint64, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	return client.minedNonce, nil
+}
+
+func (client *fakeClient) HeaderByNumber(ctx context.Context, number *big.Int) (*ethTypes.Header, error) {
+	return &ethTypes.Header{BaseFee: client.baseFee}, nil
+}
+
+func (client *fakeClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (client *fakeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1), nil
+}
+
+func (client *fakeClient) SendTransaction(ctx context.Context, transaction *ethTypes.Transaction) error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.sent = append(client.sent, transaction)
+
+	if client.mineAt == len(client.sent) {
+		client.receipts[transaction.Hash()] = &ethTypes.Receipt{
+			Status: ethTypes.ReceiptStatusSuccessful,
+			TxHash: transaction.Hash(),
+		}
+
+		client.minedNonce = transaction.Nonce() + 1
+	}
+
+	return nil
+}
+
+func (client *fakeClient) TransactionByHash(ctx context.Context, hash ethCommon.Hash) (*ethTypes.Transaction, bool, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	for _, transaction := range client.sent {
+		if transaction.Hash() == hash {
+			return transaction, true, nil
+		}
+	}
+
+	return nil, false, goEthereum.NotFound
+}
+
+func (client *fakeClient) TransactionReceipt(ctx context.Context, hash ethCommon.Hash) (*ethTypes.Receipt, error) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	receipt, found := client.receipts[hash]
+
+	if !found {
+		return nil, goEthereum.NotFound
+	}
+
+	return receipt, nil
+}
+
+func (client *fakeClient) CallContract(ctx context.Context, call goEthereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return nil, client.callErr
+}
+
+func (client *fakeClient) EstimateGas(ctx context.Context, call goEthereum.CallMsg) (uint64, error) {
+	return 21000, nil
+}
+
+type memoryStore struct {
+	nextNonce uint64
+	attempts  []ethereum.TransactionAttempt
+	statuses  map[ethereum.Hash]ethereum.TransactionAttemptStatus
+}
+
+func (store *memoryStore) ReserveNonce(chainId uint64, address ethereum.Address, pendingNonce uint64) uint64 {
+	if pendingNonce > store.nextNonce {
+		store.nextNonce = pendingNonce
+	}
+
+	store.nextNonce++
+
+	return store.nextNonce - 1
+}
+
+func (store *memoryStore) ReleaseNonce(chainId uint64, address ethereum.Address, nonce uint64) {
+	if store.nextNonce == nonce+1 {
+		store.nextNonce = nonce
+	}
+}
+
+func (store *memoryStore) ResetNonce(chainId uint64, address ethereum.Address, nonce uint64) {
+	if store.nextNonce > nonce {
+		store.nextNonce = nonce
+	}
+}
+
+func (store *memoryStore) InsertAttempt(attempt ethereum.TransactionAttempt) {
+	store.attempts = append(store.attempts, attempt)
+	store.statuses[attempt.TransactionHash] = attempt.Status
+}
+
+func (store *memoryStore) UpdateAttemptStatus(chainId uint64, transactionHash ethereum.Hash, status ethereum.TransactionAttemptStatus) {
+	store.statuses[transactionHash] = status
+}
+
+// statuses of the attempts in the order they were made
+func (store *memoryStore) orderedStatuses() []ethereum.TransactionAttemptStatus {
+	statuses := make([]ethereum.TransactionAttemptStatus, len(store.attempts))
+
+	for i, attempt := range store.attempts {
+		statuses[i] = store.statuses[attempt.TransactionHash]
+	}
+
+	return statuses
+}
+
+func newTestManager(t *testing.T, client *fakeClient) (*Manager, *memoryStore) {
+	privateKey, err := ethCrypto.GenerateKey()
+
+	require.NoError(t, err)
+
+	transactionOptions, err := ethAbiBind.NewKeyedTransactorWithChainID(privateKey, testChainId)
+
+	require.NoError(t, err)
+
+	client.receipts = make(map[ethCommon.Hash]*ethTypes.Receipt)
+
+	if client.baseFee == nil {
+		client.baseFee = big.NewInt(100)
+	}
+
+	store := &memoryStore{
+		statuses: make(map[ethereum.Hash]ethereum.TransactionAttemptStatus),
+	}
+
+	config := Config{
+		ResubmitTimeout: 20 * time.Millisecond,
+		PollInterval:    2 * time.Millisecond,
+		FeeBumpPercent:  12,
+		MaxAttempts:     3,
+	}
+
+	manager, err := New(client, store, transactionOptions, config)
+
+	require.NoError(t, err)
+
+	return manager, store
+}
+
+// transactTest signs a transfer with the options given
+func transactTest(transactionOptions *ethAbiBind.TransactOpts) (*ethTypes.Transaction, error) {
+	to := ethCommon.HexToAddress("0x0000000000000000000000000000000000000001")
+
+	transaction := ethTypes.NewTx(&ethTypes.DynamicFeeTx{
+		ChainID:   testChainId,
+		Nonce:     transactionOptions.Nonce.Uint64(),
+		GasTipCap: big.NewInt(10),
+		GasFeeCap: big.NewInt(300),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(0),
+	})
+
+	return transactionOptions.Signer(transactionOptions.From, transaction)
+}
+
+func TestSendMinedFirstAttempt(t *testing.T) {
+	client := &fakeClient{pendingNonce: 4, minedNonce: 4, mineAt: 1}
+
+	manager, store := newTestManager(t, client)
+
+	receipt, err := manager.Send(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, client.sent[0].Hash(), receipt.TxHash)
+	assert.Equal(t, uint64(4), client.sent[0].Nonce())
+	assert.Equal(t, uint64(5), store.nextNonce)
+
+	assert.Equal(
+		t,
+		[]ethereum.TransactionAttemptStatus{ethereum.TransactionAttemptMined},
+		store.orderedStatuses(),
+	)
+}
+
+func TestSendReservesNoncesInOrder(t *testing.T) {
+	client := &fakeClient{pendingNonce: 0, mineAt: 0}
+
+	manager, _ := newTestManager(t, client)
+
+	first, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	second, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(0), first.Nonce())
+	assert.Equal(t, uint64(1), second.Nonce())
+}
+
+func TestSendResetsNonceAfterAbandoning(t *testing.T) {
+	client := &fakeClient{mineAt: 0}
+
+	manager, store := newTestManager(t, client)
+
+	_, err := manager.Send(context.Background(), transactTest)
+
+	assert.True(t, errors.Is(err, ErrMaxAttempts))
+	assert.Equal(t, uint64(1), store.nextNonce)
+
+	// nonce 0 was never mined and nothing is in flight, so it's used again
+
+	transaction, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(0), transaction.Nonce())
+	assert.Equal(t, uint64(1), store.nextNonce)
+}
+
+func TestSendFillsNonceGap(t *testing.T) {
+	client := &fakeClient{pendingNonce: 0, mineAt: 0}
+
+	manager, store := newTestManager(t, client)
+
+	first, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	second, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	// nonce 0 was abandoned while nonce 1 is still being followed
+
+	manager.land(first.Nonce())
+
+	third, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(1), second.Nonce())
+	assert.Equal(t, uint64(0), third.Nonce())
+	assert.Equal(t, uint64(2), store.nextNonce)
+
+	// with nonce 0 in flight again, the next transaction goes after 1
+
+	fourth, err := manager.sign(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(2), fourth.Nonce())
+}
+
+func TestSendBumpsFees(t *testing.T) {
+	client := &fakeClient{mineAt: 2}
+
+	manager, store := newTestManager(t, client)
+
+	receipt, err := manager.Send(context.Background(), transactTest)
+
+	require.NoError(t, err)
+	require.Len(t, client.sent, 2)
+
+	var (
+		first  = client.sent[0]
+		second = client.sent[1]
+	)
+
+	assert.Equal(t, second.Hash(), receipt.TxHash)
+	assert.Equal(t, first.Nonce(), second.Nonce())
+
+	// 10 * 1.12, and the larger of 300 * 1.12 and 2 * 100 + 11
+
+	assert.Equal(t, big.NewInt(11), second.GasTipCap())
+	assert.Equal(t, big.NewInt(336), second.GasFeeCap())
+
+	assert.Equal(
+		t,
+		[]ethereum.TransactionAttemptStatus{
+			ethereum.TransactionAttemptReplaced,
+			ethereum.TransactionAttemptMined,
+		},
+		store.orderedStatuses(),
+	)
+}
+
+func TestSendBumpsToTheBaseFee(t *testing.T) {
+	client := &fakeClient{mineAt: 2, baseFee: big.NewInt(1000)}
+
+	manager, _ := newTestManager(t, client)
+
+	_, err := manager.Send(context.Background(), transactTest)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewInt(2011), client.sent[1].GasFeeCap())
+}
+
+func TestSendMaxGasFeeCap(t *testing.T) {
+	client := &fakeClient{mineAt: 0}
+
+	manager, store := newTestManager(t, client)
+
+	manager.config.MaxGasFeeCap = big.NewInt(310)
+
+	_, err := manager.Send(context.Background(), transactTest)
+
+	assert.True(t, errors.Is(err, ErrMaxAttempts))
+
+	// the second attempt is capped, and the third can't be bumped
+
+	require.Len(t, store.attempts, 2)
+
+	assert.Equal(t, "310", store.attempts[1].GasFeeCap.String())
+}
+
+func TestSendReplacedExternally(t *testing.T) {
+	client := &fakeClient{mineAt: 0, minedNonce: 1}
+
+	manager, store := newTestManager(t, client)
+
+	// the node reports nonce 0 as pending, but something else mines it
+
+	_, err := manager.Send(context.Background(), transactTest)
+
+	assert.True(t, errors.Is(err, ErrReplacedExternally))
+
+	assert.Equal(
+		t,
+		[]ethereum.TransactionAttemptStatus{ethereum.TransactionAttemptReplacedExternally},
+		store.orderedStatuses(),
+	)
+}
+
+func TestSendMaxAttempts(t *testing.T) {
+	client := &fakeClient{mineAt: 0}
+
+	manager, store := newTestManager(t, client)
+
+	_, err := manager.Send(context.Background(), transactTest)
+
+	assert.True(t, errors.Is(err, ErrMaxAttempts))
+
+	assert.Equal(
+		t,
+		[]ethereum.TransactionAttemptStatus{
+			ethereum.TransactionAttemptAbandoned,
+			ethereum.TransactionAttemptAbandoned,
+			ethereum.TransactionAttemptAbandoned,
+		},
+		store.orderedStatuses(),
+	)
+
+	// the nonce is still reserved, since an attempt could be mined later
+
+	assert.Equal(t, uint64(1), store.nextNonce)
+}
+
+func TestSendReleasesNonceOnFailure(t *testing.T) {
+	client := &fakeClient{}
+
+	manager, store := newTestManager(t, client)
+
+	_, err := manager.Send(context.Background(), func(*ethAbiBind.TransactOpts) (*ethTypes.Transaction, error) {
+		return nil, errors.New("simulation failed")
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, uint64(0), store.nextNonce)
+	assert.Empty(t, client.sent)
+}
+
+func TestSendDryRun(t *testing.T) {
+	client := &fakeClient{pendingNonce: 7}
+
+	manager, store := newTestManager(t, client)
+
+	var results []dry_run.Result
+
+	manager.config.DryRun = true
+
+	manager.config.PublishDryRun = func(result dry_run.Result) {
+		results = append(results, result)
+	}
+
+	receipt, err := manager.Send(context.Background(), transactTest)
+
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	result := results[0]
+
+	assert.Empty(t, client.sent)
+	assert.Empty(t, store.attempts)
+	assert.Equal(t, uint64(0), store.nextNonce)
+
+	assert.True(t, result.Success)
+	assert.Equal(t, uint64(21000), result.GasEstimate)
+	assert.Equal(t, receipt.TxHash.Hex(), result.TransactionHash)
+	assert.Equal(t, "0x0000000000000000000000000000000000000001", result.Recipient)
+	assert.Equal(t, ethTypes.ReceiptStatusSuccessful, receipt.Status)
+	assert.Nil(t, receipt.BlockNumber)
+
+	client.callErr = errors.New("execution reverted")
+
+	receipt, err = manager.Send(context.Background(), transactTest)
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.False(t, results[1].Success)
+	assert.Equal(t, "execution reverted", results[1].Error)
+	assert.Equal(t, ethTypes.ReceiptStatusFailed, receipt.Status)
+}
+
+func TestNewFeeBumpTooSmall(t *testing.T) {
+	privateKey, err := ethCrypto.GenerateKey()
+
+	require.NoError(t, err)
+
+	transactionOptions, err := ethAbiBind.NewKeyedTransactorWithChainID(privateKey, testChainId)
+
+	require.NoError(t, err)
+
+	config := DefaultConfig()
+
+	config.FeeBumpPercent = 5
+
+	_, err = New(&fakeClient{}, &memoryStore{}, transactionOptions, config)
+
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ package uniswap_v3
 import (
 	_ "embed"
 	"fmt"
+	"math"
 	"math/big"
 
 	"github.com/fluidity-money/fluidity-app/common/ethereum"
@@ -25,17 +26,75 @@ var uniswapV3AbiBytes []byte
 
 var uniswapV3PoolAbi ethAbi.ABI
 
-func calculatePrice(seconds int, firstTick, secondTick *big.Int) (price *big.Rat) {
-	tickDifference := new(big.Int).Sub(new(big.Int).Set(secondTick), new(big.Int).Set(firstTick))
+// calculateAverageTick between two cumulative ticks, rounding towards
+// negative infinity like Uniswap's OracleLibrary
+func calculateAverageTick(seconds uint32, firstTick, secondTick *big.Int) *big.Int {
+	var (
+		tickDifference = new(big.Int).Sub(secondTick, firstTick)
+		secondsInt     = new(big.Int).SetUint64(uint64(seconds))
+	)
 
-	averageTick := new(big.Int).Quo(tickDifference, new(big.Int).SetInt64(int64(seconds)))
-	log.Debugf("average tick before %v", averageTick.String())
+	// Div is euclidean, so it already rounds down for a positive divisor
 
-	averageTick.Exp(averageTick, new(big.Int).SetInt64(10001), nil)
+	return new(big.Int).Div(tickDifference, secondsInt)
+}
 
-	log.Debugf("average tick %v", averageTick.String())
+// TickToPrice of token0 in token1 (1.0001^tick), without taking the
+// decimals of either token into account
+func TickToPrice(tick *big.Int) *big.Rat {
+	price := math.Pow(1.0001, float64(tick.Int64()))
 
-	return
+	return new(big.Rat).SetFloat64(price)
+}
+
+// GetTwapTick from the given seconds in the past til now
+func GetTwapTick(client *ethclient.Client, poolAddress ethCommon.Address, seconds uint32) (*big.Int, error) {
+	if seconds == 0 {
+		return nil, fmt.Errorf("seconds to average over must be positive")
+	}
+
+	secondsAgo := []uint32{seconds, 0}
+
+	resp, err := ethereum.StaticCall(client, poolAddress, uniswapV3PoolAbi, "observe", secondsAgo)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to do a static call to observe with seconds ago %v! %w",
+			secondsAgo,
+			err,
+		)
+	}
+
+	if l := len(resp); l != 2 {
+		return nil, fmt.Errorf(
+			"observe returned %v results, expected 2",
+			l,
+		)
+	}
+
+	tickCumulatives, ok := resp[0].([]*big.Int)
+
+	if !ok {
+		return nil, fmt.Errorf(
+			"expected type []*big.Int from observe, got %T",
+			resp[0],
+		)
+	}
+
+	if l := len(tickCumulatives); l != 2 {
+		return nil, fmt.Errorf(
+			"observe returned %v tick cumulatives, expected 2",
+			l,
+		)
+	}
+
+	averageTick := calculateAverageTick(
+		seconds,
+		tickCumulatives[0],
+		tickCumulatives[1],
+	)
+
+	return averageTick, nil
 }
 
 // GetTwrpPrice from given seconds in the past til now. Using the Uniswap
@@ -43,6 +102,8 @@ func calculatePrice(seconds int, firstTick, secondTick *big.Int) (price *big.Rat
 // the price for, but should be avoided in general. It's safer to use
 // Chainlink where possible. This will only work for the current block, when
 // normally several blocks could be scanned, so be careful when there's low liquidity.
+// Returns the price of token0 in token1 without adjusting for decimals,
+// and dies using Fatal if the lookup fails.
 func GetTwrpPrice(client *ethclient.Client, poolAddress ethCommon.Address, seconds int) *big.Rat {
 	log.Debug(func(k *log.Log) {
 		k.Context = Context
@@ -53,73 +114,25 @@ func GetTwrpPrice(client *ethclient.Client, poolAddress ethCommon.Address, secon
 		)
 	})
 
-	secondsAgo := []uint32{uint32(seconds),0}
-
-	resp, err := ethereum.StaticCall(client, poolAddress, uniswapV3PoolAbi, "observe", secondsAgo)
+	averageTick, err := GetTwapTick(client, poolAddress, uint32(seconds))
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
 			k.Context = Context
 
 			k.Format(
-				"Failed to do a static call to observe! Pool address %v, secondsAgo %v",
-				poolAddress,
-				secondsAgo,
-			)
-
-			k.Payload = err
-		})
-	}
-
-	if l := len(resp); l != 2 {
-		log.Fatal(func(k *log.Log) {
-			k.Context = Context
-
-			k.Format(
-				"Failed to decode a observe call! Pool address %v, seconds ago %v",
-				poolAddress,
-				seconds,
-			)
-
-			k.Payload = fmt.Errorf("length is %v", l)
-		})
-	}
-
-	tickCumulatives, ok := resp[0].([]*big.Int)
-
-	if !ok {
-		log.Fatal(func(k *log.Log) {
-			k.Context = Context
-
-			k.Format(
-				"Failed to decode a observe call's results! Pool address %v, seconds ago %v",
+				"Failed to get the average tick! Pool address %v, seconds ago %v",
 				poolAddress,
 				seconds,
 			)
 
-			k.Payload = fmt.Sprintf("expected type []*big.Int, got %T", resp[0])
-		})
-	}
-
-	if l := len(tickCumulatives); l != 2 {
-		log.Fatal(func(k *log.Log) {
-			k.Context = Context
-
-			k.Format(
-				"Failed to decode a observe call! Pool address %v, seconds ago %v",
-				poolAddress,
-				seconds,
-			)
-
-			k.Payload = fmt.Errorf("not enough to responses %v", l)
+			k.Payload = err
 		})
 	}
 
-	price := calculatePrice(seconds, tickCumulatives[0], tickCumulatives[1])
-
-	return price
+	return TickToPrice(averageTick)
 }
 
-func GetTwrpPrice1Second(client *ethclient.Client, poolAddress ethCommon.Address)  *big.Rat {
+func GetTwrpPrice1Second(client *ethclient.Client, poolAddress ethCommon.Address) *big.Rat {
 	return GetTwrpPrice(client, poolAddress, 1)
 }
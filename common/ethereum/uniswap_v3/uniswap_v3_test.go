@@ -0,0 +1,44 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package uniswap_v3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateAverageTick(t *testing.T) {
+	averageTick := calculateAverageTick(
+		10,
+		big.NewInt(1000),
+		big.NewInt(2005),
+	)
+
+	assert.Equal(t, int64(100), averageTick.Int64())
+
+	// negative ticks round down, not towards zero
+
+	averageTick = calculateAverageTick(
+		10,
+		big.NewInt(2005),
+		big.NewInt(1000),
+	)
+
+	assert.Equal(t, int64(-101), averageTick.Int64())
+}
+
+func TestTickToPrice(t *testing.T) {
+	price, _ := TickToPrice(big.NewInt(0)).Float64()
+
+	assert.Equal(t, 1.0, price)
+
+	// a tick of about 200000 is ~2000 USDC (6 decimals) per WETH (18 decimals)
+
+	price, _ = TickToPrice(big.NewInt(-200000)).Float64()
+
+	assert.InDelta(t, 2.06e-9, price, 0.01e-9)
+}
@@ -309,6 +309,12 @@ func BigPow(left *big.Rat, count int) *big.Rat {
 }
 
 func StaticCall(client *ethClient.Client, address ethCommon.Address, abi ethAbi.ABI, method string, args ...interface{}) ([]interface{}, error) {
+	return StaticCallAtBlock(client, address, abi, method, nil, args...)
+}
+
+// StaticCallAtBlock to call a contract with the state as of the block
+// given, or the latest block if it's nil
+func StaticCallAtBlock(client *ethClient.Client, address ethCommon.Address, abi ethAbi.ABI, method string, blockNumber *big.Int, args ...interface{}) ([]interface{}, error) {
 	boundContract := ethBind.NewBoundContract(
 		address,
 		abi,
@@ -318,8 +324,9 @@ func StaticCall(client *ethClient.Client, address ethCommon.Address, abi ethAbi.
 	)
 
 	opts := ethBind.CallOpts{
-		Pending: false,
-		Context: context.Background(),
+		Pending:     false,
+		Context:     context.Background(),
+		BlockNumber: blockNumber,
 	}
 
 	var results []interface{}
@@ -0,0 +1,124 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package yield
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// SourceAaveV2 to lend the underlying token in an Aave V2 lending pool
+	SourceAaveV2 = "aave_v2"
+
+	// SourceAaveV3 to lend the underlying token in an Aave V3 pool
+	SourceAaveV3 = "aave_v3"
+
+	// SourceCompoundV2 to lend the underlying token with a Compound V2 cToken
+	SourceCompoundV2 = "compound_v2"
+
+	// SourceCompoundV3 to supply the underlying token as the base asset
+	// of a Compound V3 (Comet) market
+	SourceCompoundV3 = "compound_v3"
+
+	// SourceErc4626 to deposit the underlying token into an ERC4626 vault
+	SourceErc4626 = "erc4626"
+)
+
+// DefaultBlocksPerDay to use for Compound V2 if it isn't configured,
+// for 12 second blocks
+const DefaultBlocksPerDay = 7200
+
+// DefaultLookbackBlocks to measure an ERC4626 vault's share price
+// growth over if it isn't configured, a day of 12 second blocks
+const DefaultLookbackBlocks = 7200
+
+// Config for the source of yield of a Fluid token, with only the fields
+// for its type set
+type Config struct {
+	Type string `json:"type"`
+
+	// Address of the Aave addresses provider, the Compound V2 cToken,
+	// the Compound V3 Comet market or the ERC4626 vault
+	Address ethCommon.Address `json:"address"`
+
+	// Underlying token that's lent, needed for Aave
+	Underlying ethCommon.Address `json:"underlying"`
+
+	// BlocksPerDay to compound the Compound V2 rate per block with
+	BlocksPerDay uint64 `json:"blocks_per_day"`
+
+	// LookbackBlocks to measure an ERC4626 vault's yield over
+	LookbackBlocks uint64 `json:"lookback_blocks"`
+}
+
+// ParseConfig from JSON, checking that the fields the type needs are set
+// and filling in defaults
+func ParseConfig(configJson string) (*Config, error) {
+	var config Config
+
+	if err := json.Unmarshal([]byte(configJson), &config); err != nil {
+		return nil, fmt.Errorf("failed to decode the yield source config! %v", err)
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf(
+			"yield source config of type %#v is invalid! %v",
+			config.Type,
+			err,
+		)
+	}
+
+	switch config.Type {
+	case SourceCompoundV2:
+		if config.BlocksPerDay == 0 {
+			config.BlocksPerDay = DefaultBlocksPerDay
+		}
+
+	case SourceErc4626:
+		if config.LookbackBlocks == 0 {
+			config.LookbackBlocks = DefaultLookbackBlocks
+		}
+	}
+
+	return &config, nil
+}
+
+func (config Config) validate() error {
+	var emptyAddress ethCommon.Address
+
+	switch config.Type {
+	case SourceAaveV2, SourceAaveV3:
+		if config.Address == emptyAddress {
+			return fmt.Errorf("aave addresses provider not set")
+		}
+
+		if config.Underlying == emptyAddress {
+			return fmt.Errorf("underlying token not set")
+		}
+
+	case SourceCompoundV2:
+		if config.Address == emptyAddress {
+			return fmt.Errorf("ctoken address not set")
+		}
+
+	case SourceCompoundV3:
+		if config.Address == emptyAddress {
+			return fmt.Errorf("comet address not set")
+		}
+
+	case SourceErc4626:
+		if config.Address == emptyAddress {
+			return fmt.Errorf("vault address not set")
+		}
+
+	default:
+		return fmt.Errorf("unknown source type")
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package yield
+
+import (
+	"strings"
+
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func init() {
+	reader := strings.NewReader(erc20AbiString)
+
+	var err error
+
+	if erc20Abi, err = ethAbi.JSON(reader); err != nil {
+		panic(err)
+	}
+}
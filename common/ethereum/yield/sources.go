@@ -0,0 +1,102 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package yield
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/aave"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/compound"
+	"github.com/fluidity-money/fluidity-app/common/ethereum/erc4626"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+type (
+	aaveV2 struct {
+		addressProvider ethCommon.Address
+		underlying      ethCommon.Address
+	}
+
+	aaveV3 struct {
+		addressProvider ethCommon.Address
+		underlying      ethCommon.Address
+	}
+
+	compoundV2 struct {
+		cToken       ethCommon.Address
+		blocksPerDay uint64
+	}
+
+	compoundV3 struct {
+		comet ethCommon.Address
+	}
+
+	erc4626Vault struct {
+		vault          ethCommon.Address
+		lookbackBlocks uint64
+	}
+)
+
+func (lender aaveV2) apy(client *ethclient.Client, emission *worker.Emission) (*big.Rat, error) {
+	return aave.GetTokenApy(client, lender.addressProvider, lender.underlying, emission)
+}
+
+func (lender aaveV2) underlyingBalance(client *ethclient.Client, holder ethCommon.Address) (*big.Rat, error) {
+	aToken, err := aave.GetATokenAddress(client, lender.addressProvider, lender.underlying)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to look up the aave v2 atoken! %w",
+			err,
+		)
+	}
+
+	return aave.GetBalanceOf(client, aToken, holder)
+}
+
+func (lender aaveV3) apy(client *ethclient.Client, emission *worker.Emission) (*big.Rat, error) {
+	return aave.GetTokenApyV3(client, lender.addressProvider, lender.underlying, emission)
+}
+
+func (lender aaveV3) underlyingBalance(client *ethclient.Client, holder ethCommon.Address) (*big.Rat, error) {
+	aToken, err := aave.GetATokenAddressV3(client, lender.addressProvider, lender.underlying)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to look up the aave v3 atoken! %w",
+			err,
+		)
+	}
+
+	return aave.GetBalanceOf(client, aToken, holder)
+}
+
+func (lender compoundV2) apy(client *ethclient.Client, emission *worker.Emission) (*big.Rat, error) {
+	return compound.GetTokenApy(client, lender.cToken, lender.blocksPerDay, emission)
+}
+
+func (lender compoundV2) underlyingBalance(client *ethclient.Client, holder ethCommon.Address) (*big.Rat, error) {
+	return compound.GetBalanceOfUnderlying(client, lender.cToken, holder)
+}
+
+func (lender compoundV3) apy(client *ethclient.Client, _ *worker.Emission) (*big.Rat, error) {
+	return compound.GetCometApy(client, lender.comet)
+}
+
+func (lender compoundV3) underlyingBalance(client *ethclient.Client, holder ethCommon.Address) (*big.Rat, error) {
+	return compound.GetCometBalanceOf(client, lender.comet, holder)
+}
+
+func (lender erc4626Vault) apy(client *ethclient.Client, _ *worker.Emission) (*big.Rat, error) {
+	return erc4626.GetTokenApy(client, lender.vault, lender.lookbackBlocks)
+}
+
+func (lender erc4626Vault) underlyingBalance(client *ethclient.Client, holder ethCommon.Address) (*big.Rat, error) {
+	return erc4626.GetBalanceOfUnderlying(client, lender.vault, holder)
+}
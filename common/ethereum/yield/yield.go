@@ -0,0 +1,175 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// yield looks up the yield earned by the underlying tokens of a Fluid
+// token, from whichever money market or vault its config says they're
+// deposited in
+package yield
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	ethAbi "github.com/ethereum/go-ethereum/accounts/abi"
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const erc20AbiString = `[
+	{
+		"inputs": [],
+		"name": "totalSupply",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// erc20Abi set by init.go to read the supply of the Fluid token with
+var erc20Abi ethAbi.ABI
+
+type (
+	// Source of the yield earned by the underlying tokens held by a
+	// Fluid token
+	Source interface {
+		// Apy currently earned, as a fraction, filling in the emission
+		// with the working if the source has fields for it
+		Apy(emission *worker.Emission) (*big.Rat, error)
+
+		// UnderlyingBalance held by the Fluid token including the interest
+		// earned, in the underlying token's smallest unit
+		UnderlyingBalance() (*big.Rat, error)
+
+		// PrizePool available to reward, the underlying balance less the
+		// Fluid tokens in circulation, in the underlying token's smallest unit
+		PrizePool() (*big.Rat, error)
+	}
+
+	// lender implemented for each money market
+	lender interface {
+		apy(client *ethclient.Client, emission *worker.Emission) (*big.Rat, error)
+		underlyingBalance(client *ethclient.Client, holder ethCommon.Address) (*big.Rat, error)
+	}
+
+	source struct {
+		client     *ethclient.Client
+		fluidToken ethCommon.Address
+		lender     lender
+	}
+)
+
+// New source of yield for the Fluid token given using its config
+func New(client *ethclient.Client, fluidToken ethCommon.Address, config Config) (Source, error) {
+	var lender lender
+
+	switch config.Type {
+	case SourceAaveV2:
+		lender = aaveV2{
+			addressProvider: config.Address,
+			underlying:      config.Underlying,
+		}
+
+	case SourceAaveV3:
+		lender = aaveV3{
+			addressProvider: config.Address,
+			underlying:      config.Underlying,
+		}
+
+	case SourceCompoundV2:
+		lender = compoundV2{
+			cToken:       config.Address,
+			blocksPerDay: config.BlocksPerDay,
+		}
+
+	case SourceCompoundV3:
+		lender = compoundV3{
+			comet: config.Address,
+		}
+
+	case SourceErc4626:
+		lender = erc4626Vault{
+			vault:          config.Address,
+			lookbackBlocks: config.LookbackBlocks,
+		}
+
+	default:
+		return nil, fmt.Errorf(
+			"unknown yield source type %#v!",
+			config.Type,
+		)
+	}
+
+	source := source{
+		client:     client,
+		fluidToken: fluidToken,
+		lender:     lender,
+	}
+
+	return source, nil
+}
+
+func (source source) Apy(emission *worker.Emission) (*big.Rat, error) {
+	return source.lender.apy(source.client, emission)
+}
+
+func (source source) UnderlyingBalance() (*big.Rat, error) {
+	return source.lender.underlyingBalance(source.client, source.fluidToken)
+}
+
+func (source source) PrizePool() (*big.Rat, error) {
+	balance, err := source.UnderlyingBalance()
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the underlying balance of the fluid token! %w",
+			err,
+		)
+	}
+
+	results, err := ethereum.StaticCall(
+		source.client,
+		source.fluidToken,
+		erc20Abi,
+		"totalSupply",
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the total supply of the fluid token! %w",
+			err,
+		)
+	}
+
+	totalSupply, err := ethereum.CoerceBoundContractResultsToRat(results)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to coerce the total supply of the fluid token to a rat! %w",
+			err,
+		)
+	}
+
+	return prizePool(balance, totalSupply), nil
+}
+
+// prizePool from the underlying balance and the supply of the Fluid
+// token, which is 0 if the balance is somehow less than the supply
+func prizePool(balance, totalSupply *big.Rat) *big.Rat {
+	pool := new(big.Rat).Sub(balance, totalSupply)
+
+	if pool.Sign() < 0 {
+		return new(big.Rat)
+	}
+
+	return pool
+}
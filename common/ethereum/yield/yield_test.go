@@ -0,0 +1,187 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package yield
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+	testUtils "github.com/fluidity-money/fluidity-app/tests/integrations/ethereum/util"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	fluidToken = ethCommon.HexToAddress("0x4cfa50b7ce747e2d61724fcac57f24b748ff2b2a")
+	market     = ethCommon.HexToAddress("0xc3d688b66703497daa19211eedff47f25384cdc3")
+)
+
+// word to respond to an eth_call with
+func word(x uint64) string {
+	return fmt.Sprintf("0x%064x", x)
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(`{"type": "compound_v2", "address": "0xc3d688b66703497daa19211eedff47f25384cdc3"}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, market, config.Address)
+	assert.Equal(t, uint64(DefaultBlocksPerDay), config.BlocksPerDay)
+
+	config, err = ParseConfig(`{"type": "erc4626", "address": "0xc3d688b66703497daa19211eedff47f25384cdc3", "lookback_blocks": 10}`)
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), config.LookbackBlocks)
+
+	_, err = ParseConfig(`{"type": "aave_v3", "address": "0xc3d688b66703497daa19211eedff47f25384cdc3"}`)
+
+	assert.Error(t, err, "aave without the underlying token")
+
+	_, err = ParseConfig(`{"type": "erc4626"}`)
+
+	assert.Error(t, err, "vault without an address")
+
+	_, err = ParseConfig(`{"type": "yearn"}`)
+
+	assert.Error(t, err, "unknown type")
+}
+
+func TestPrizePool(t *testing.T) {
+	assert.Equal(t, big.NewRat(500, 1), prizePool(big.NewRat(1500, 1), big.NewRat(1000, 1)))
+
+	assert.Equal(t, new(big.Rat), prizePool(big.NewRat(999, 1), big.NewRat(1000, 1)))
+}
+
+func TestCompoundV2(t *testing.T) {
+	client, err := testUtils.MockRpcClient(nil, map[string]map[string]interface{}{
+		"supplyRatePerBlock()": {
+			// 0.0001 a day over 1000 blocks
+			"": word(1e11),
+		},
+		"balanceOfUnderlying(address)": {
+			"": word(1500e6),
+		},
+		"totalSupply()": {
+			"": word(1000e6),
+		},
+	})
+
+	require.NoError(t, err)
+
+	source, err := New(client, fluidToken, Config{
+		Type:         SourceCompoundV2,
+		Address:      market,
+		BlocksPerDay: 1000,
+	})
+
+	require.NoError(t, err)
+
+	var emission worker.Emission
+
+	apy, err := source.Apy(&emission)
+
+	require.NoError(t, err)
+
+	apyFloat, _ := apy.Float64()
+
+	assert.InDelta(t, math.Pow(1.0001, 365)-1, apyFloat, 1e-9)
+	assert.InDelta(t, apyFloat, emission.CompoundGetTokenApy.SupplyApy, 1e-9)
+	assert.Equal(t, uint64(1000), emission.CompoundGetTokenApy.BlocksPerDay)
+
+	balance, err := source.UnderlyingBalance()
+
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(1500e6, 1), balance)
+
+	pool, err := source.PrizePool()
+
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(500e6, 1), pool)
+}
+
+func TestCompoundV3(t *testing.T) {
+	client, err := testUtils.MockRpcClient(nil, map[string]map[string]interface{}{
+		"getUtilization()": {
+			"": word(8e17),
+		},
+		"getSupplyRate(uint256)": {
+			"": word(1e9),
+		},
+		"balanceOf(address)": {
+			"": word(1200e6),
+		},
+		"totalSupply()": {
+			"": word(1000e6),
+		},
+	})
+
+	require.NoError(t, err)
+
+	source, err := New(client, fluidToken, Config{
+		Type:    SourceCompoundV3,
+		Address: market,
+	})
+
+	require.NoError(t, err)
+
+	apy, err := source.Apy(&worker.Emission{})
+
+	require.NoError(t, err)
+
+	apyFloat, _ := apy.Float64()
+
+	assert.InDelta(t, math.Pow(1+1e9*86400/1e18, 365)-1, apyFloat, 1e-9)
+
+	pool, err := source.PrizePool()
+
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(200e6, 1), pool)
+}
+
+func TestErc4626Balance(t *testing.T) {
+	client, err := testUtils.MockRpcClient(nil, map[string]map[string]interface{}{
+		"balanceOf(address)": {
+			"": word(900e6),
+		},
+		// every share is worth 1.2 assets
+		"convertToAssets(uint256)": {
+			"": word(1080e6),
+		},
+		"totalSupply()": {
+			"": word(1000e6),
+		},
+	})
+
+	require.NoError(t, err)
+
+	source, err := New(client, fluidToken, Config{
+		Type:           SourceErc4626,
+		Address:        market,
+		LookbackBlocks: DefaultLookbackBlocks,
+	})
+
+	require.NoError(t, err)
+
+	balance, err := source.UnderlyingBalance()
+
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(1080e6, 1), balance)
+
+	pool, err := source.PrizePool()
+
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(80e6, 1), pool)
+}
+
+func TestNewUnknown(t *testing.T) {
+	_, err := New(nil, fluidToken, Config{Type: "yearn"})
+
+	assert.Error(t, err)
+}
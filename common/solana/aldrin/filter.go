@@ -39,7 +39,7 @@ func GetAldrinFees(solanaClient *rpc.Provider, transaction types.TransactionResu
 
 	var (
 		transactionSignature = transaction.Transaction.Signatures[0]
-		accountKeys          = transaction.Transaction.Message.AccountKeys
+		accountKeys          = transaction.AccountKeys()
 	)
 
 	allInstructions := solana.GetAllInstructions(transaction)
@@ -92,7 +92,7 @@ func GetLifinityFees(solanaClient *rpc.Provider, transaction types.TransactionRe
 
 	var (
 		transactionSignature = transaction.Transaction.Signatures[0]
-		accountKeys          = transaction.Transaction.Message.AccountKeys
+		accountKeys          = transaction.AccountKeys()
 		adjustedPrices       []*big.Rat
 	)
 
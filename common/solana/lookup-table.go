@@ -0,0 +1,165 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package solana
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	solTypes "github.com/fluidity-money/fluidity-app/lib/types/solana"
+)
+
+const (
+	// AddressLookupTableProgramAddress that owns every lookup table
+	AddressLookupTableProgramAddress = "AddressLookupTab1e1111111111111111111111111"
+
+	// lookupTableMetaSize of the state before the addresses in a lookup
+	// table account, including the padding after it
+	lookupTableMetaSize = 56
+
+	// lookupTableDiscriminator for an initialised lookup table
+	lookupTableDiscriminator = 1
+)
+
+// AddressLookupTable that a v0 message loads accounts from by index
+type AddressLookupTable struct {
+	// Key of the lookup table account, not part of its state
+	Key PublicKey
+
+	// DeactivationSlot of the table, the max u64 if it's active
+	DeactivationSlot uint64
+
+	LastExtendedSlot           uint64
+	LastExtendedSlotStartIndex uint8
+
+	// Authority of the table, nil if it's frozen
+	Authority *PublicKey
+
+	Addresses []PublicKey
+}
+
+// DecodeAddressLookupTable from the data of the lookup table account
+func DecodeAddressLookupTable(key PublicKey, data []byte) (*AddressLookupTable, error) {
+	if dataLen := len(data); dataLen < lookupTableMetaSize {
+		return nil, fmt.Errorf(
+			"lookup table %v data was too short, was %v bytes",
+			key,
+			dataLen,
+		)
+	}
+
+	if discriminator := binary.LittleEndian.Uint32(data[0:4]); discriminator != lookupTableDiscriminator {
+		return nil, fmt.Errorf(
+			"lookup table %v is not initialised, discriminator was %v",
+			key,
+			discriminator,
+		)
+	}
+
+	table := AddressLookupTable{
+		Key:                        key,
+		DeactivationSlot:           binary.LittleEndian.Uint64(data[4:12]),
+		LastExtendedSlot:           binary.LittleEndian.Uint64(data[12:20]),
+		LastExtendedSlotStartIndex: data[20],
+	}
+
+	if hasAuthority := data[21] != 0; hasAuthority {
+		authority := PublicKeyFromBytes(data[22:54])
+		table.Authority = &authority
+	}
+
+	addresses := data[lookupTableMetaSize:]
+
+	if len(addresses)%32 != 0 {
+		return nil, fmt.Errorf(
+			"lookup table %v addresses weren't a multiple of 32 bytes, was %v",
+			key,
+			len(addresses),
+		)
+	}
+
+	table.Addresses = make([]PublicKey, len(addresses)/32)
+
+	for i := range table.Addresses {
+		table.Addresses[i] = PublicKeyFromBytes(addresses[i*32 : (i+1)*32])
+	}
+
+	return &table, nil
+}
+
+// IsActive if the table hasn't been deactivated
+func (table AddressLookupTable) IsActive() bool {
+	return table.DeactivationSlot == math.MaxUint64
+}
+
+// lookup the addresses at the indexes given
+func (table AddressLookupTable) lookup(indexes []int) ([]string, error) {
+	addresses := make([]string, len(indexes))
+
+	for i, index := range indexes {
+		if index < 0 || index >= len(table.Addresses) {
+			return nil, fmt.Errorf(
+				"index %v is out of range of lookup table %v with %v addresses",
+				index,
+				table.Key,
+				len(table.Addresses),
+			)
+		}
+
+		addresses[i] = table.Addresses[index].ToBase58()
+	}
+
+	return addresses, nil
+}
+
+// ResolveAddressTableLookups of a v0 message to the addresses they load,
+// the writable addresses from every table then the readonly ones, using
+// getTable to get each table
+func ResolveAddressTableLookups(lookups []solTypes.TransactionAddressTableLookups, getTable func(PublicKey) (*AddressLookupTable, error)) (*solTypes.TransactionLoadedAddresses, error) {
+	loadedAddresses := solTypes.TransactionLoadedAddresses{
+		Writable: make([]string, 0),
+		Readonly: make([]string, 0),
+	}
+
+	for _, lookup := range lookups {
+		tableKey, err := PublicKeyFromBase58(lookup.AccountKey)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to decode lookup table address %#v! %v",
+				lookup.AccountKey,
+				err,
+			)
+		}
+
+		table, err := getTable(tableKey)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get lookup table %v! %v",
+				tableKey,
+				err,
+			)
+		}
+
+		writable, err := table.lookup(lookup.WritableIndexes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		readonly, err := table.lookup(lookup.ReadonlyIndexes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		loadedAddresses.Writable = append(loadedAddresses.Writable, writable...)
+		loadedAddresses.Readonly = append(loadedAddresses.Readonly, readonly...)
+	}
+
+	return &loadedAddresses, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package solana
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	solTypes "github.com/fluidity-money/fluidity-app/lib/types/solana"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeLookupTable(deactivationSlot uint64, authority *PublicKey, addresses ...PublicKey) []byte {
+	data := make([]byte, lookupTableMetaSize)
+
+	binary.LittleEndian.PutUint32(data[0:4], lookupTableDiscriminator)
+	binary.LittleEndian.PutUint64(data[4:12], deactivationSlot)
+	binary.LittleEndian.PutUint64(data[12:20], 1234)
+
+	data[20] = 2
+
+	if authority != nil {
+		data[21] = 1
+		copy(data[22:54], authority[:])
+	}
+
+	for _, address := range addresses {
+		data = append(data, address[:]...)
+	}
+
+	return data
+}
+
+func TestDecodeAddressLookupTable(t *testing.T) {
+	authority := testKey(9)
+
+	table, err := DecodeAddressLookupTable(
+		testKey(6),
+		encodeLookupTable(math.MaxUint64, &authority, testKey(1), testKey(2)),
+	)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, testKey(6), table.Key)
+	assert.True(t, table.IsActive())
+	assert.Equal(t, uint64(1234), table.LastExtendedSlot)
+	assert.Equal(t, uint8(2), table.LastExtendedSlotStartIndex)
+	assert.Equal(t, &authority, table.Authority)
+	assert.Equal(t, []PublicKey{testKey(1), testKey(2)}, table.Addresses)
+
+	// frozen and deactivated
+
+	table, err = DecodeAddressLookupTable(testKey(6), encodeLookupTable(100, nil))
+
+	require.NoError(t, err)
+
+	assert.False(t, table.IsActive())
+	assert.Nil(t, table.Authority)
+	assert.Empty(t, table.Addresses)
+
+	_, err = DecodeAddressLookupTable(testKey(6), make([]byte, 10))
+
+	assert.Error(t, err)
+
+	uninitialised := encodeLookupTable(math.MaxUint64, nil)
+
+	uninitialised[0] = 0
+
+	_, err = DecodeAddressLookupTable(testKey(6), uninitialised)
+
+	assert.Error(t, err)
+
+	_, err = DecodeAddressLookupTable(testKey(6), append(encodeLookupTable(math.MaxUint64, nil), 1))
+
+	assert.Error(t, err)
+}
+
+func TestResolveAddressTableLookups(t *testing.T) {
+	tables := map[PublicKey]*AddressLookupTable{
+		testKey(6): {Key: testKey(6), Addresses: []PublicKey{testKey(1), testKey(2), testKey(3)}},
+		testKey(7): {Key: testKey(7), Addresses: []PublicKey{testKey(4), testKey(5)}},
+	}
+
+	getTable := func(key PublicKey) (*AddressLookupTable, error) {
+		table, found := tables[key]
+
+		if !found {
+			return nil, errors.New("not found")
+		}
+
+		return table, nil
+	}
+
+	lookups := []solTypes.TransactionAddressTableLookups{
+		{AccountKey: testKey(6).String(), WritableIndexes: []int{2}, ReadonlyIndexes: []int{0}},
+		{AccountKey: testKey(7).String(), WritableIndexes: []int{1, 0}, ReadonlyIndexes: []int{}},
+	}
+
+	loadedAddresses, err := ResolveAddressTableLookups(lookups, getTable)
+
+	require.NoError(t, err)
+
+	// every writable address comes before the readonly ones
+
+	assert.Equal(
+		t,
+		[]string{testKey(3).String(), testKey(5).String(), testKey(4).String()},
+		loadedAddresses.Writable,
+	)
+
+	assert.Equal(t, []string{testKey(1).String()}, loadedAddresses.Readonly)
+
+	lookups[1].WritableIndexes = []int{2}
+
+	_, err = ResolveAddressTableLookups(lookups, getTable)
+
+	assert.Error(t, err)
+
+	lookups[1].AccountKey = testKey(8).String()
+
+	_, err = ResolveAddressTableLookups(lookups, getTable)
+
+	assert.Error(t, err)
+}
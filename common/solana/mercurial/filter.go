@@ -20,7 +20,7 @@ func GetMercurialFees(solanaClient *rpc.Provider, transaction types.TransactionR
 
 	var (
 		transactionSignature = transaction.Transaction.Signatures[0]
-		accountKeys          = transaction.Transaction.Message.AccountKeys
+		accountKeys          = transaction.AccountKeys()
 	)
 
 	allInstructions := solana.GetAllInstructions(transaction)
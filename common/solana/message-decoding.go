@@ -0,0 +1,333 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package solana
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrShortMessage if a message or transaction ended before being decoded
+var ErrShortMessage = errors.New("message ended before it was decoded")
+
+// binaryDecoder reading the wire format of messages and transactions
+type binaryDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (decoder *binaryDecoder) remaining() int {
+	return len(decoder.data) - decoder.pos
+}
+
+func (decoder *binaryDecoder) readBytes(n int) ([]byte, error) {
+	if n < 0 || decoder.remaining() < n {
+		return nil, ErrShortMessage
+	}
+
+	b := decoder.data[decoder.pos : decoder.pos+n]
+
+	decoder.pos += n
+
+	return b, nil
+}
+
+func (decoder *binaryDecoder) readByte() (byte, error) {
+	b, err := decoder.readBytes(1)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+func (decoder *binaryDecoder) readCompactU16Length() (int, error) {
+	length, read, err := DecodeCompactU16Length(decoder.data[decoder.pos:])
+
+	if err != nil {
+		return 0, err
+	}
+
+	decoder.pos += read
+
+	return length, nil
+}
+
+func (decoder *binaryDecoder) readPublicKey() (PublicKey, error) {
+	b, err := decoder.readBytes(32)
+
+	if err != nil {
+		return PublicKey{}, err
+	}
+
+	return PublicKeyFromBytes(b), nil
+}
+
+// readIndexes prefixed with their length, copied so they don't share
+// the decoded buffer
+func (decoder *binaryDecoder) readIndexes() ([]byte, error) {
+	length, err := decoder.readCompactU16Length()
+
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := decoder.readBytes(length)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(make([]byte, 0, length), b...), nil
+}
+
+// DecodeCompactU16Length from the start of the bytes given, returning the
+// length and the number of bytes it was encoded in
+func DecodeCompactU16Length(bytes []byte) (int, int, error) {
+	length := 0
+
+	for i := 0; i < 3; i++ {
+		if i >= len(bytes) {
+			return 0, 0, ErrShortMessage
+		}
+
+		elem := int(bytes[i])
+
+		length |= (elem & 0x7f) << (i * 7)
+
+		if elem&0x80 == 0 {
+			if length > 0xffff {
+				return 0, 0, fmt.Errorf("compact-u16 length %v overflows", length)
+			}
+
+			return length, i + 1, nil
+		}
+	}
+
+	return 0, 0, errors.New("compact-u16 length is longer than 3 bytes")
+}
+
+// UnmarshalBinary a legacy or v0 message
+func (mx *Message) UnmarshalBinary(data []byte) error {
+	decoder := binaryDecoder{data: data}
+
+	if err := mx.decode(&decoder); err != nil {
+		return err
+	}
+
+	if remaining := decoder.remaining(); remaining != 0 {
+		return fmt.Errorf(
+			"message had %v bytes left after it was decoded",
+			remaining,
+		)
+	}
+
+	return nil
+}
+
+func (mx *Message) decode(decoder *binaryDecoder) error {
+	*mx = Message{}
+
+	first, err := decoder.readByte()
+
+	if err != nil {
+		return err
+	}
+
+	// legacy messages start with the number of signatures, which can't
+	// have the top bit set
+
+	if first&messageVersionPrefix == 0 {
+		mx.Version = MessageVersionLegacy
+		mx.Header.NumRequiredSignatures = first
+	} else {
+		if version := first &^ messageVersionPrefix; version != 0 {
+			return fmt.Errorf("unsupported message version %v", version)
+		}
+
+		mx.Version = MessageVersionV0
+
+		if mx.Header.NumRequiredSignatures, err = decoder.readByte(); err != nil {
+			return err
+		}
+	}
+
+	if mx.Header.NumReadonlySignedAccounts, err = decoder.readByte(); err != nil {
+		return err
+	}
+
+	if mx.Header.NumReadonlyUnsignedAccounts, err = decoder.readByte(); err != nil {
+		return err
+	}
+
+	accountKeysLen, err := decoder.readCompactU16Length()
+
+	if err != nil {
+		return err
+	}
+
+	mx.AccountKeys = make([]PublicKey, accountKeysLen)
+
+	for i := range mx.AccountKeys {
+		if mx.AccountKeys[i], err = decoder.readPublicKey(); err != nil {
+			return err
+		}
+	}
+
+	recentBlockhash, err := decoder.readPublicKey()
+
+	if err != nil {
+		return err
+	}
+
+	mx.RecentBlockhash = Hash(recentBlockhash)
+
+	instructionsLen, err := decoder.readCompactU16Length()
+
+	if err != nil {
+		return err
+	}
+
+	mx.Instructions = make([]CompiledInstruction, instructionsLen)
+
+	for i := range mx.Instructions {
+		instruction := &mx.Instructions[i]
+
+		programIdIndex, err := decoder.readByte()
+
+		if err != nil {
+			return err
+		}
+
+		instruction.ProgramIDIndex = uint16(programIdIndex)
+
+		accounts, err := decoder.readIndexes()
+
+		if err != nil {
+			return err
+		}
+
+		instruction.Accounts = make([]uint16, len(accounts))
+
+		for j, account := range accounts {
+			instruction.Accounts[j] = uint16(account)
+		}
+
+		if instruction.Data, err = decoder.readIndexes(); err != nil {
+			return err
+		}
+	}
+
+	if mx.Version == MessageVersionLegacy {
+		return nil
+	}
+
+	lookupsLen, err := decoder.readCompactU16Length()
+
+	if err != nil {
+		return err
+	}
+
+	mx.AddressTableLookups = make([]MessageAddressTableLookup, lookupsLen)
+
+	for i := range mx.AddressTableLookups {
+		lookup := &mx.AddressTableLookups[i]
+
+		if lookup.AccountKey, err = decoder.readPublicKey(); err != nil {
+			return err
+		}
+
+		if lookup.WritableIndexes, err = decoder.readIndexes(); err != nil {
+			return err
+		}
+
+		if lookup.ReadonlyIndexes, err = decoder.readIndexes(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalBinary a transaction with a legacy or v0 message
+func (tx *Transaction) UnmarshalBinary(data []byte) error {
+	decoder := binaryDecoder{data: data}
+
+	signaturesLen, err := decoder.readCompactU16Length()
+
+	if err != nil {
+		return err
+	}
+
+	tx.Signatures = make([]Signature, signaturesLen)
+
+	for i := range tx.Signatures {
+		signature, err := decoder.readBytes(64)
+
+		if err != nil {
+			return err
+		}
+
+		copy(tx.Signatures[i][:], signature)
+	}
+
+	if err := tx.Message.decode(&decoder); err != nil {
+		return err
+	}
+
+	if remaining := decoder.remaining(); remaining != 0 {
+		return fmt.Errorf(
+			"transaction had %v bytes left after it was decoded",
+			remaining,
+		)
+	}
+
+	return nil
+}
+
+// ResolveAccountKeys that instructions in the message index, including
+// those loaded from the lookup tables given
+func (mx Message) ResolveAccountKeys(getTable func(PublicKey) (*AddressLookupTable, error)) ([]PublicKey, error) {
+	var (
+		accountKeys = append([]PublicKey{}, mx.AccountKeys...)
+		readonly    []PublicKey
+	)
+
+	for _, lookup := range mx.AddressTableLookups {
+		table, err := getTable(lookup.AccountKey)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to get lookup table %v! %v",
+				lookup.AccountKey,
+				err,
+			)
+		}
+
+		for _, indexes := range [][]uint8{lookup.WritableIndexes, lookup.ReadonlyIndexes} {
+			for _, index := range indexes {
+				if int(index) >= len(table.Addresses) {
+					return nil, fmt.Errorf(
+						"index %v is out of range of lookup table %v with %v addresses",
+						index,
+						lookup.AccountKey,
+						len(table.Addresses),
+					)
+				}
+			}
+		}
+
+		for _, index := range lookup.WritableIndexes {
+			accountKeys = append(accountKeys, table.Addresses[index])
+		}
+
+		for _, index := range lookup.ReadonlyIndexes {
+			readonly = append(readonly, table.Addresses[index])
+		}
+	}
+
+	return append(accountKeys, readonly...), nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package solana
+
+import (
+	"crypto/ed25519"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) PublicKey {
+	var key PublicKey
+
+	for i := range key {
+		key[i] = b
+	}
+
+	return key
+}
+
+// testSwap with a payer, a program and pool accounts, and a lookup table
+// holding the pool accounts
+func testSwap(t *testing.T) (PrivateKey, Instruction, AddressLookupTable) {
+	_, privateKey, err := ed25519.GenerateKey(nil)
+
+	require.NoError(t, err)
+
+	var (
+		payer    = PrivateKey(privateKey)
+		program  = testKey(1)
+		pool     = testKey(2)
+		vault    = testKey(3)
+		oracle   = testKey(4)
+		unlisted = testKey(5)
+	)
+
+	instruction := NewInstruction(
+		program,
+		AccountMetaSlice{
+			NewAccountMeta(payer.PublicKey(), true, true),
+			NewAccountMeta(pool, true, false),
+			NewAccountMeta(oracle, false, false),
+			NewAccountMeta(vault, true, false),
+			NewAccountMeta(unlisted, false, false),
+		},
+		[]byte{9, 8, 7},
+	)
+
+	table := AddressLookupTable{
+		Key:              testKey(6),
+		DeactivationSlot: math.MaxUint64,
+		Addresses:        []PublicKey{oracle, vault, program, pool},
+	}
+
+	return payer, instruction, table
+}
+
+func TestDecodeCompactU16Length(t *testing.T) {
+	for _, length := range []int{0, 1, 0x7f, 0x80, 0x3fff, 0x4000, 0xffff} {
+		var buf []byte
+
+		EncodeCompactU16Length(&buf, length)
+
+		decoded, read, err := DecodeCompactU16Length(append(buf, 0xaa))
+
+		require.NoError(t, err)
+
+		assert.Equal(t, length, decoded)
+		assert.Equal(t, len(buf), read)
+	}
+
+	_, _, err := DecodeCompactU16Length([]byte{0x80})
+
+	assert.ErrorIs(t, err, ErrShortMessage)
+
+	_, _, err = DecodeCompactU16Length([]byte{0xff, 0xff, 0xff})
+
+	assert.Error(t, err)
+}
+
+func TestLegacyTransactionRoundTrip(t *testing.T) {
+	payer, instruction, _ := testSwap(t)
+
+	transaction, err := NewTransaction([]Instruction{instruction}, Hash(testKey(7)))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, MessageVersionLegacy, transaction.Message.Version)
+	assert.Empty(t, transaction.Message.AddressTableLookups)
+
+	_, err = transaction.Sign(func(PublicKey) *PrivateKey { return &payer })
+
+	require.NoError(t, err)
+
+	encoded, err := transaction.MarshalBinary()
+
+	require.NoError(t, err)
+
+	var decoded Transaction
+
+	require.NoError(t, decoded.UnmarshalBinary(encoded))
+
+	assert.Equal(t, *transaction, decoded)
+}
+
+func TestV0TransactionRoundTrip(t *testing.T) {
+	payer, instruction, table := testSwap(t)
+
+	transaction, err := NewTransaction(
+		[]Instruction{instruction},
+		Hash(testKey(7)),
+		TransactionAddressTables(table),
+	)
+
+	require.NoError(t, err)
+
+	message := transaction.Message
+
+	assert.Equal(t, MessageVersionV0, message.Version)
+
+	// the payer and program can't be loaded, nor the account that isn't
+	// in the table
+
+	assert.Equal(
+		t,
+		[]PublicKey{payer.PublicKey(), testKey(5), testKey(1)},
+		message.AccountKeys,
+	)
+
+	assert.Equal(t, MessageHeader{1, 0, 2}, message.Header)
+
+	assert.Equal(
+		t,
+		[]MessageAddressTableLookup{{
+			AccountKey:      table.Key,
+			WritableIndexes: []uint8{3, 1},
+			ReadonlyIndexes: []uint8{0},
+		}},
+		message.AddressTableLookups,
+	)
+
+	// payer, pool, oracle, vault, unlisted, indexing the loaded accounts
+	// after the static ones
+
+	require.Len(t, message.Instructions, 1)
+
+	assert.Equal(t, uint16(2), message.Instructions[0].ProgramIDIndex)
+	assert.Equal(t, []uint16{0, 3, 5, 4, 1}, message.Instructions[0].Accounts)
+
+	_, err = transaction.Sign(func(PublicKey) *PrivateKey { return &payer })
+
+	require.NoError(t, err)
+
+	encoded, err := transaction.MarshalBinary()
+
+	require.NoError(t, err)
+
+	var decoded Transaction
+
+	require.NoError(t, decoded.UnmarshalBinary(encoded))
+
+	assert.Equal(t, *transaction, decoded)
+
+	accountKeys, err := decoded.Message.ResolveAccountKeys(func(key PublicKey) (*AddressLookupTable, error) {
+		assert.Equal(t, table.Key, key)
+		return &table, nil
+	})
+
+	require.NoError(t, err)
+
+	assert.Equal(
+		t,
+		[]PublicKey{payer.PublicKey(), testKey(5), testKey(1), testKey(2), testKey(3), testKey(4)},
+		accountKeys,
+	)
+
+	// the resolved accounts are the ones the instruction was built with
+
+	for i, account := range instruction.Accounts() {
+		index := decoded.Message.Instructions[0].Accounts[i]
+		assert.Equal(t, account.PublicKey, accountKeys[index])
+	}
+}
+
+func TestUnmarshalMessageInvalid(t *testing.T) {
+	var message Message
+
+	// version 1 isn't supported
+
+	assert.Error(t, message.UnmarshalBinary([]byte{0x81, 1, 0, 0}))
+
+	assert.ErrorIs(t, message.UnmarshalBinary([]byte{1, 0, 0, 2}), ErrShortMessage)
+
+	legacy := Message{
+		AccountKeys:  []PublicKey{testKey(1)},
+		Header:       MessageHeader{NumRequiredSignatures: 1},
+		Instructions: []CompiledInstruction{{ProgramIDIndex: 0, Data: []byte{}}},
+	}
+
+	encoded, err := legacy.MarshalBinary()
+
+	require.NoError(t, err)
+
+	assert.Error(t, message.UnmarshalBinary(append(encoded, 0)))
+
+	legacy.AddressTableLookups = []MessageAddressTableLookup{{AccountKey: testKey(2)}}
+
+	_, err = legacy.MarshalBinary()
+
+	assert.Error(t, err)
+}
@@ -48,7 +48,7 @@ func GetOrcaFees(solanaClient *rpc.Provider, transaction types.TransactionResult
 
 	var (
 		transactionSignature = transaction.Transaction.Signatures[0]
-		accountKeys          = transaction.Transaction.Message.AccountKeys
+		accountKeys          = transaction.AccountKeys()
 	)
 
 	feesPaid = big.NewRat(0, 1)
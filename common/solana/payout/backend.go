@@ -0,0 +1,282 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package payout
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/common/solana"
+	"github.com/fluidity-money/fluidity-app/common/solana/rpc"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+)
+
+// Backend for the spooler (common/spooler) to pay out batches of rewards
+// with the payout authority, one transaction per winner. The payout instruction splits the amount
+// between two accounts, so each winner's fluid token account is given
+// as both to receive all of it.
+type Backend struct {
+	Client *rpc.Provider
+
+	// Args to pay out with, the accounts, amount and block hash are
+	// set for each winner
+	Args PayoutArgs
+
+	// PayerPrivateKey to sign with, the key of Args.PayerPubkey
+	PayerPrivateKey solana.PrivateKey
+}
+
+type (
+	payoutTransaction struct {
+		winner      solana.PublicKey
+		transaction *solana.Transaction
+	}
+
+	// PartialPayoutError when some of the winners in a batch were paid
+	// out before a payout failed, so the batch can't be sent again
+	PartialPayoutError struct {
+		// Paid winners before the payout failed
+		Paid int
+
+		// Winners in the batch
+		Winners int
+
+		// Winner whose payout failed
+		Winner solana.PublicKey
+
+		Err error
+	}
+)
+
+func (err PartialPayoutError) Error() string {
+	return fmt.Sprintf(
+		"paid out %v of %v winners, failed to send the payout to %v! %v",
+		err.Paid,
+		err.Winners,
+		err.Winner,
+		err.Err,
+	)
+}
+
+func (err PartialPayoutError) Unwrap() error {
+	return err.Err
+}
+
+// PartialPayout is always true, for the spooler to leave the batch
+// marked as sent
+func (err PartialPayoutError) PartialPayout() bool {
+	return true
+}
+
+// Payout every winner in the batch. Every payout is signed before any
+// are sent, so if a payout fails after others were sent a
+// PartialPayoutError is returned
+func (backend Backend) Payout(rewards worker.EthereumSpooledRewards) error {
+	transactions, err := backend.createPayoutTransactions(rewards)
+
+	if err != nil {
+		return err
+	}
+
+	for i, payout := range transactions {
+		signature, err := backend.Client.SendTransaction(payout.transaction)
+
+		switch {
+		case err != nil && i == 0:
+			return fmt.Errorf(
+				"failed to send the payout to %v! %v",
+				payout.winner,
+				err,
+			)
+
+		case err != nil:
+			return PartialPayoutError{
+				Paid:    i,
+				Winners: len(transactions),
+				Winner:  payout.winner,
+				Err:     err,
+			}
+		}
+
+		log.Debug(func(k *log.Log) {
+			k.Context = solana.ContextLogging
+
+			k.Format(
+				"Paid out %v with signature %v",
+				payout.winner,
+				signature,
+			)
+		})
+	}
+
+	return nil
+}
+
+// Simulate the payout to every winner in the batch
+func (backend Backend) Simulate(rewards worker.EthereumSpooledRewards) ([]dry_run.Result, error) {
+	transactions, err := backend.createPayoutTransactions(rewards)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]dry_run.Result, 0, len(transactions))
+
+	for _, payout := range transactions {
+		transactionBinary, err := payout.transaction.MarshalBinary()
+
+		if err != nil {
+			return results, fmt.Errorf(
+				"failed to encode the payout to %v! %v",
+				payout.winner,
+				err,
+			)
+		}
+
+		result := dry_run.Result{
+			Network:     network.NetworkSolana,
+			Description: "payout",
+			Sender:      backend.Args.PayerPubkey.ToBase58(),
+			Recipient:   payout.winner.ToBase58(),
+			Transaction: base64.StdEncoding.EncodeToString(transactionBinary),
+			Time:        time.Now(),
+		}
+
+		if len(payout.transaction.Signatures) > 0 {
+			result.TransactionHash = payout.transaction.Signatures[0].String()
+		}
+
+		value, err := backend.Client.SimulateTransaction(
+			transactionBinary, // transaction
+			true,              // sigVerify
+			"finalized",       // commitment
+			false,             // replaceRecentBlockhash
+		)
+
+		if value != nil {
+			result.GasEstimate = value.UnitsConsumed
+			result.Logs = value.Logs
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Success = true
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// createPayoutTransactions to sign a payout for each winner, sorted by
+// their address
+func (backend Backend) createPayoutTransactions(rewards worker.EthereumSpooledRewards) ([]payoutTransaction, error) {
+	type winning struct {
+		winner string
+		amount uint64
+	}
+
+	winnings := make([]winning, 0)
+
+	for utility, utilityRewards := range rewards.Rewards {
+		if utility != applications.UtilityFluid {
+			return nil, fmt.Errorf(
+				"can't pay out rewards for utility %v",
+				utility,
+			)
+		}
+
+		for address, amount := range utilityRewards {
+			if !amount.IsUint64() {
+				return nil, fmt.Errorf(
+					"winning amount %v for %v is too large to be a u64",
+					amount.String(),
+					address,
+				)
+			}
+
+			winnings = append(winnings, winning{
+				winner: address.String(),
+				amount: amount.Uint64(),
+			})
+		}
+	}
+
+	sort.Slice(winnings, func(i, j int) bool {
+		return winnings[i].winner < winnings[j].winner
+	})
+
+	recentBlockHash, err := backend.Client.GetRecentBlockhash("finalized")
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the recent block hash! %v",
+			err,
+		)
+	}
+
+	transactions := make([]payoutTransaction, 0, len(winnings))
+
+	for _, winning := range winnings {
+		var (
+			winner_ = winning.winner
+			amount  = winning.amount
+		)
+
+		winner, err := solana.PublicKeyFromBase58(winner_)
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to decode winner %v! %v",
+				winner_,
+				err,
+			)
+		}
+
+		args := backend.Args
+
+		args.AccountAPubkey = winner
+		args.AccountBPubkey = winner
+		args.WinningAmount = amount
+		args.RecentBlockHash = recentBlockHash
+
+		transaction, _, err := CreatePayoutTransaction(args)
+
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = transaction.Sign(func(publicKey solana.PublicKey) *solana.PrivateKey {
+			if args.PayerPubkey.Equals(publicKey) {
+				return &backend.PayerPrivateKey
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to sign the payout to %v! %v",
+				winner_,
+				err,
+			)
+		}
+
+		transactions = append(transactions, payoutTransaction{
+			winner:      winner,
+			transaction: transaction,
+		})
+	}
+
+	return transactions, nil
+}
@@ -40,7 +40,7 @@ func GetRaydiumFees(solanaClient *rpc.Provider, transaction types.TransactionRes
 
 	var (
 		transactionSignature = transaction.Transaction.Signatures[0]
-		accountKeys          = transaction.Transaction.Message.AccountKeys
+		accountKeys          = transaction.AccountKeys()
 	)
 
 	allInstructions := solana.GetAllInstructions(transaction)
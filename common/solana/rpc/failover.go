@@ -0,0 +1,527 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+// failover spreads calls over several RPC endpoints, preferring them in
+// the order they were given, skipping endpoints that are lagging behind
+// the others and retrying idempotent calls on the next endpoint if one
+// fails.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+)
+
+// LogContextFailover to use for logging endpoints failing
+const LogContextFailover = "SOLANA/RPC/FAILOVER"
+
+const (
+	// DefaultMaxSlotLag behind the most recent endpoint before an
+	// endpoint is unhealthy, the same distance the Solana node uses to
+	// call itself behind
+	DefaultMaxSlotLag = 150
+
+	// DefaultHealthCheckInterval to get the latest slot from every
+	// endpoint with
+	DefaultHealthCheckInterval = 10 * time.Second
+
+	// DefaultResubscribeDelay to wait before subscribing again after a
+	// disconnect, doubled after every failure up to a minute
+	DefaultResubscribeDelay = time.Second
+
+	// maxResubscribeDelay to back off up to when subscribing fails
+	maxResubscribeDelay = time.Minute
+)
+
+// retryableRpcErrorCodes returned by a node that doesn't have the slot or
+// block asked for at the commitment asked for yet, which another node
+// further ahead might
+var retryableRpcErrorCodes = map[int]bool{
+	-32004: true, // block not available for slot
+	-32005: true, // node is unhealthy or behind
+	-32007: true, // slot skipped or missing due to a ledger jump
+	-32014: true, // block status not yet available
+	-32016: true, // minimum context slot has not been reached
+}
+
+// nonIdempotentMethods that can't be retried on another endpoint since
+// they might have succeeded on the first
+var nonIdempotentMethods = map[string]bool{
+	"sendTransaction": true,
+	"requestAirdrop":  true,
+}
+
+type (
+	// FailoverOption to configure a provider with multiple endpoints
+	FailoverOption interface {
+		apply(opts *failoverOptions)
+	}
+
+	failoverOptions struct {
+		requestsPerSecond   float64
+		maxSlotLag          uint64
+		healthCheckInterval time.Duration
+		resubscribeDelay    time.Duration
+	}
+
+	failoverOptionFunc func(opts *failoverOptions)
+
+	// endpoint to invoke methods with, and its health
+	endpoint struct {
+		url     string
+		limiter *rateLimiter
+
+		// dial to connect to the endpoint again if a websocket closed
+		dial func() (providerUnderlying, error)
+
+		mu         sync.Mutex
+		underlying providerUnderlying
+		slot       uint64
+		healthy    bool
+	}
+
+	failover struct {
+		endpoints []*endpoint
+	}
+)
+
+func (f failoverOptionFunc) apply(opts *failoverOptions) {
+	f(opts)
+}
+
+// FailoverRequestsPerSecond to limit the requests made to each endpoint
+// to, unlimited if 0
+func FailoverRequestsPerSecond(requestsPerSecond float64) FailoverOption {
+	return failoverOptionFunc(func(opts *failoverOptions) {
+		opts.requestsPerSecond = requestsPerSecond
+	})
+}
+
+// FailoverMaxSlotLag behind the endpoint with the latest slot that an
+// endpoint can be before it's skipped
+func FailoverMaxSlotLag(maxSlotLag uint64) FailoverOption {
+	return failoverOptionFunc(func(opts *failoverOptions) {
+		opts.maxSlotLag = maxSlotLag
+	})
+}
+
+// FailoverHealthCheckInterval to get the latest slot of every endpoint
+// with
+func FailoverHealthCheckInterval(interval time.Duration) FailoverOption {
+	return failoverOptionFunc(func(opts *failoverOptions) {
+		opts.healthCheckInterval = interval
+	})
+}
+
+// FailoverResubscribeDelay to wait before the first attempt to subscribe
+// again after a disconnect
+func FailoverResubscribeDelay(delay time.Duration) FailoverOption {
+	return failoverOptionFunc(func(opts *failoverOptions) {
+		opts.resubscribeDelay = delay
+	})
+}
+
+// NewFailover provider that invokes methods with the http endpoints given,
+// or the websocket endpoints if there are none, and subscribes with the
+// websocket endpoints. Endpoints are preferred in the order given
+func NewFailover(httpUrls, websocketUrls []string, opts ...FailoverOption) (*Provider, error) {
+	options := failoverOptions{
+		maxSlotLag:          DefaultMaxSlotLag,
+		healthCheckInterval: DefaultHealthCheckInterval,
+		resubscribeDelay:    DefaultResubscribeDelay,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&options)
+	}
+
+	httpUrls, err := cleanUrls(httpUrls, "http", "https")
+
+	if err != nil {
+		return nil, err
+	}
+
+	websocketUrls, err = cleanUrls(websocketUrls, "ws", "wss")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var failover failover
+
+	switch {
+	case len(httpUrls) != 0:
+		for _, url_ := range httpUrls {
+			url_ := url_
+
+			endpoint := newEndpoint(url_, options, func() (providerUnderlying, error) {
+				return NewHttp(url_)
+			})
+
+			failover.endpoints = append(failover.endpoints, endpoint)
+		}
+
+	case len(websocketUrls) != 0:
+		for _, url_ := range websocketUrls {
+			url_ := url_
+
+			endpoint := newEndpoint(url_, options, func() (providerUnderlying, error) {
+				return NewWebsocket(url_)
+			})
+
+			failover.endpoints = append(failover.endpoints, endpoint)
+		}
+
+	default:
+		return nil, fmt.Errorf("no rpc urls were given")
+	}
+
+	// connect to the endpoints now so a bad url is found early, as the
+	// single rpc providers did
+
+	for _, endpoint := range failover.endpoints {
+		_, err := endpoint.connect()
+
+		switch {
+		case err != nil && len(failover.endpoints) == 1:
+			return nil, err
+
+		case err != nil:
+			endpoint.setUnhealthy()
+
+			log.App(func(k *log.Log) {
+				k.Context = LogContextFailover
+				k.Message = "Failed to connect to an rpc endpoint, skipping it for now!"
+				k.Payload = err
+			})
+		}
+	}
+
+	// there's no point checking the health of a single endpoint, since
+	// there's nothing to fail over to
+
+	if len(failover.endpoints) > 1 {
+		go failover.checkHealth(options)
+	}
+
+	provider := Provider{
+		providerUnderlying: &failover,
+		websocketUrls:      websocketUrls,
+		options:            options,
+	}
+
+	return &provider, nil
+}
+
+// urlScheme of the url given, if it's one an rpc provider supports
+func urlScheme(url_ string) (string, error) {
+	url, err := url.Parse(url_)
+
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to parse the url for the new rpc provider: %v",
+			err,
+		)
+	}
+
+	switch scheme := url.Scheme; scheme {
+	case "http", "https", "ws", "wss":
+		return scheme, nil
+
+	default:
+		return "", fmt.Errorf(
+			"unknown scheme, was %#v, was expecting (http?|wss?)",
+			scheme,
+		)
+	}
+}
+
+// cleanUrls by trimming them and dropping empty urls, checking they have
+// one of the schemes given
+func cleanUrls(urls []string, schemes ...string) ([]string, error) {
+	cleaned := make([]string, 0, len(urls))
+
+	for _, url_ := range urls {
+		url_ = strings.TrimSpace(url_)
+
+		if url_ == "" {
+			continue
+		}
+
+		scheme, err := urlScheme(url_)
+
+		if err != nil {
+			return nil, err
+		}
+
+		hasScheme := false
+
+		for _, scheme_ := range schemes {
+			hasScheme = hasScheme || scheme == scheme_
+		}
+
+		if !hasScheme {
+			return nil, fmt.Errorf(
+				"url %#v has scheme %#v, was expecting one of %v",
+				url_,
+				scheme,
+				schemes,
+			)
+		}
+
+		cleaned = append(cleaned, url_)
+	}
+
+	return cleaned, nil
+}
+
+func newEndpoint(url_ string, options failoverOptions, dial func() (providerUnderlying, error)) *endpoint {
+	return &endpoint{
+		url:     url_,
+		limiter: newRateLimiter(options.requestsPerSecond),
+		dial:    dial,
+		healthy: true,
+	}
+}
+
+// connect to the endpoint if it isn't connected, or its websocket closed
+func (endpoint *endpoint) connect() (providerUnderlying, error) {
+	endpoint.mu.Lock()
+
+	defer endpoint.mu.Unlock()
+
+	if websocket, ok := endpoint.underlying.(*Websocket); ok && websocket.isClosed() {
+		endpoint.underlying = nil
+	}
+
+	if endpoint.underlying != nil {
+		return endpoint.underlying, nil
+	}
+
+	underlying, err := endpoint.dial()
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to connect to rpc endpoint %v: %w",
+			endpoint.url,
+			err,
+		)
+	}
+
+	endpoint.underlying = underlying
+
+	return underlying, nil
+}
+
+// RawInvoke on the endpoint, waiting for its rate limit
+func (endpoint *endpoint) RawInvoke(method string, params interface{}) (json.RawMessage, error) {
+	underlying, err := endpoint.connect()
+
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.limiter.wait()
+
+	return underlying.RawInvoke(method, params)
+}
+
+func (endpoint *endpoint) isHealthy() bool {
+	endpoint.mu.Lock()
+
+	defer endpoint.mu.Unlock()
+
+	return endpoint.healthy
+}
+
+func (endpoint *endpoint) setHealth(slot uint64, healthy bool) {
+	endpoint.mu.Lock()
+
+	defer endpoint.mu.Unlock()
+
+	endpoint.slot = slot
+	endpoint.healthy = healthy
+}
+
+func (endpoint *endpoint) setUnhealthy() {
+	endpoint.mu.Lock()
+
+	defer endpoint.mu.Unlock()
+
+	endpoint.healthy = false
+}
+
+// RawInvoke the method on the first healthy endpoint, retrying idempotent
+// methods on the next endpoint if it fails in a way another endpoint
+// might not
+func (failover *failover) RawInvoke(method string, params interface{}) (json.RawMessage, error) {
+	endpoints := failover.ordered()
+
+	if nonIdempotentMethods[method] {
+		endpoints = endpoints[:1]
+	}
+
+	var err error
+
+	for _, endpoint := range endpoints {
+		var result json.RawMessage
+
+		result, err = endpoint.RawInvoke(method, params)
+
+		if err == nil {
+			return result, nil
+		}
+
+		var rpcErr *rpcError
+
+		isRpcError := errors.As(err, &rpcErr)
+
+		if isRpcError && !retryableRpcErrorCodes[rpcErr.Code] {
+			return nil, err
+		}
+
+		// the endpoint couldn't be reached or it's behind, so skip it
+		// until the next health check says otherwise
+
+		if !isRpcError || rpcErr.Code == -32005 {
+			endpoint.setUnhealthy()
+		}
+
+		log.Debug(func(k *log.Log) {
+			k.Context = LogContextFailover
+
+			k.Format(
+				"Failed to invoke %v with endpoint %v, trying the next endpoint! %v",
+				method,
+				endpoint.url,
+				err,
+			)
+		})
+	}
+
+	if len(endpoints) == 1 {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf(
+		"failed to invoke %v with %v endpoints, last error: %w",
+		method,
+		len(endpoints),
+		err,
+	)
+}
+
+// ordered endpoints to try, the healthy endpoints in the order given then
+// the unhealthy endpoints in case they've recovered
+func (failover *failover) ordered() []*endpoint {
+	var (
+		healthy   = make([]*endpoint, 0, len(failover.endpoints))
+		unhealthy = make([]*endpoint, 0)
+	)
+
+	for _, endpoint := range failover.endpoints {
+		if endpoint.isHealthy() {
+			healthy = append(healthy, endpoint)
+		} else {
+			unhealthy = append(unhealthy, endpoint)
+		}
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+// checkHealth of every endpoint every interval, forever. Endpoints start
+// healthy and are skipped as soon as a call to them fails, so the first
+// check waits for the interval too
+func (failover *failover) checkHealth(options failoverOptions) {
+	for {
+		time.Sleep(options.healthCheckInterval)
+
+		failover.checkHealthOnce(options.maxSlotLag)
+	}
+}
+
+// checkHealthOnce by getting the latest slot of every endpoint, marking
+// endpoints that fail or lag too far behind the latest unhealthy
+func (failover *failover) checkHealthOnce(maxSlotLag uint64) {
+	var (
+		slots  = make([]uint64, len(failover.endpoints))
+		errs   = make([]error, len(failover.endpoints))
+		latest uint64
+		wg     sync.WaitGroup
+	)
+
+	for i, endpoint_ := range failover.endpoints {
+		wg.Add(1)
+
+		go func(i int, endpoint_ *endpoint) {
+			defer wg.Done()
+
+			slots[i], errs[i] = Provider{providerUnderlying: endpoint_}.GetLatestSlot()
+		}(i, endpoint_)
+	}
+
+	wg.Wait()
+
+	for i, slot := range slots {
+		if errs[i] == nil && slot > latest {
+			latest = slot
+		}
+	}
+
+	for i, endpoint := range failover.endpoints {
+		var (
+			slot = slots[i]
+			err  = errs[i]
+		)
+
+		healthy := err == nil && latest-slot <= maxSlotLag
+
+		wasHealthy := endpoint.isHealthy()
+
+		endpoint.setHealth(slot, healthy)
+
+		if healthy == wasHealthy {
+			continue
+		}
+
+		log.App(func(k *log.Log) {
+			k.Context = LogContextFailover
+
+			switch {
+			case healthy:
+				k.Format(
+					"Endpoint %v is healthy again at slot %v",
+					endpoint.url,
+					slot,
+				)
+
+			case err != nil:
+				k.Format(
+					"Endpoint %v failed to get the latest slot, skipping it!",
+					endpoint.url,
+				)
+
+				k.Payload = err
+
+			default:
+				k.Format(
+					"Endpoint %v is at slot %v, %v behind the latest slot %v, skipping it!",
+					endpoint.url,
+					slot,
+					latest-slot,
+					latest,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEndpoint that replies to every call with the function given,
+// counting the calls made to it
+type testEndpoint struct {
+	*httptest.Server
+
+	calls int32
+}
+
+func newTestEndpoint(t *testing.T, reply func(method string) (interface{}, *rpcError, int)) *testEndpoint {
+	var endpoint testEndpoint
+
+	endpoint.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&endpoint.calls, 1)
+
+		var request rpcRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		result, rpcErr, status := reply(request.Method)
+
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+
+		resultBytes, _ := json.Marshal(result)
+
+		_ = json.NewEncoder(w).Encode(rpcResponse{
+			Id:      request.Id,
+			JsonRpc: "2.0",
+			Result:  resultBytes,
+			Err:     rpcErr,
+		})
+	}))
+
+	t.Cleanup(endpoint.Close)
+
+	return &endpoint
+}
+
+func (endpoint *testEndpoint) callCount() int {
+	return int(atomic.LoadInt32(&endpoint.calls))
+}
+
+func replyWith(result interface{}) func(string) (interface{}, *rpcError, int) {
+	return func(string) (interface{}, *rpcError, int) {
+		return result, nil, http.StatusOK
+	}
+}
+
+func replyWithError(code int) func(string) (interface{}, *rpcError, int) {
+	return func(string) (interface{}, *rpcError, int) {
+		return nil, &rpcError{Code: code, Message: "test"}, http.StatusOK
+	}
+}
+
+func replyWithStatus(status int) func(string) (interface{}, *rpcError, int) {
+	return func(string) (interface{}, *rpcError, int) {
+		return nil, nil, status
+	}
+}
+
+// newTestFailover without the background health checks
+func newTestFailover(t *testing.T, endpoints ...*testEndpoint) (*Provider, *failover) {
+	urls := make([]string, len(endpoints))
+
+	for i, endpoint := range endpoints {
+		urls[i] = endpoint.URL
+	}
+
+	provider, err := NewFailover(urls, nil, FailoverHealthCheckInterval(time.Hour))
+
+	require.NoError(t, err)
+
+	return provider, provider.providerUnderlying.(*failover)
+}
+
+func TestNewFailoverNoUrls(t *testing.T) {
+	_, err := NewFailover([]string{" ", ""}, nil)
+
+	assert.Error(t, err)
+}
+
+func TestNewFailoverWrongScheme(t *testing.T) {
+	_, err := NewFailover([]string{"wss://example.com"}, nil)
+
+	assert.Error(t, err)
+}
+
+func TestFailoverRetriesOnAnotherEndpoint(t *testing.T) {
+	var (
+		down = newTestEndpoint(t, replyWithStatus(http.StatusBadGateway))
+		up   = newTestEndpoint(t, replyWith(100))
+	)
+
+	provider, failover := newTestFailover(t, down, up)
+
+	slot, err := provider.GetLatestSlot()
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), slot)
+
+	// the endpoint that failed is skipped until the next health check
+
+	assert.False(t, failover.endpoints[0].isHealthy())
+	assert.Equal(t, up.URL, failover.ordered()[0].url)
+
+	_, err = provider.GetLatestSlot()
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, down.callCount())
+	assert.Equal(t, 2, up.callCount())
+}
+
+func TestFailoverRetriesBlockNotAvailable(t *testing.T) {
+	var (
+		behind = newTestEndpoint(t, replyWithError(-32004))
+		ahead  = newTestEndpoint(t, replyWith([]uint64{10, 11}))
+	)
+
+	provider, failover := newTestFailover(t, behind, ahead)
+
+	blocks, err := provider.GetConfirmedBlocks(10, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{10, 11}, blocks)
+
+	// a node without the block yet is still healthy
+
+	assert.True(t, failover.endpoints[0].isHealthy())
+}
+
+func TestFailoverDoesntRetryInvalidRequests(t *testing.T) {
+	var (
+		first  = newTestEndpoint(t, replyWithError(-32602))
+		second = newTestEndpoint(t, replyWith(100))
+	)
+
+	provider, _ := newTestFailover(t, first, second)
+
+	_, err := provider.GetLatestSlot()
+
+	assert.ErrorContains(t, err, "-32602")
+	assert.Equal(t, 0, second.callCount())
+}
+
+func TestFailoverDoesntRetrySendTransaction(t *testing.T) {
+	var (
+		first  = newTestEndpoint(t, replyWithStatus(http.StatusInternalServerError))
+		second = newTestEndpoint(t, replyWith("signature"))
+	)
+
+	provider, _ := newTestFailover(t, first, second)
+
+	_, err := provider.RawInvoke("sendTransaction", []interface{}{"transaction"})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, first.callCount())
+	assert.Equal(t, 0, second.callCount())
+}
+
+func TestFailoverAllEndpointsFail(t *testing.T) {
+	var (
+		first  = newTestEndpoint(t, replyWithStatus(http.StatusTooManyRequests))
+		second = newTestEndpoint(t, replyWithStatus(http.StatusServiceUnavailable))
+	)
+
+	provider, _ := newTestFailover(t, first, second)
+
+	_, err := provider.GetLatestSlot()
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, first.callCount())
+	assert.Equal(t, 1, second.callCount())
+}
+
+func TestFailoverCheckHealth(t *testing.T) {
+	var (
+		lagging = newTestEndpoint(t, replyWith(1000))
+		latest  = newTestEndpoint(t, replyWith(1200))
+		down    = newTestEndpoint(t, replyWithStatus(http.StatusBadGateway))
+	)
+
+	_, failover := newTestFailover(t, lagging, latest, down)
+
+	failover.checkHealthOnce(150)
+
+	assert.False(t, failover.endpoints[0].isHealthy())
+	assert.True(t, failover.endpoints[1].isHealthy())
+	assert.False(t, failover.endpoints[2].isHealthy())
+
+	ordered := failover.ordered()
+
+	assert.Equal(t, latest.URL, ordered[0].url)
+	assert.Equal(t, lagging.URL, ordered[1].url)
+	assert.Equal(t, down.URL, ordered[2].url)
+
+	// within the lag allowed, so preferred in the order given again
+
+	failover.checkHealthOnce(500)
+
+	assert.True(t, failover.endpoints[0].isHealthy())
+	assert.Equal(t, lagging.URL, failover.ordered()[0].url)
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(20)
+
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		limiter.wait()
+	}
+
+	// the first request is immediate, then one every 50ms
+
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	assert.Nil(t, newRateLimiter(0))
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/common/solana"
+)
+
+// GetAddressLookupTable to get and decode the lookup table account given
+func (s Provider) GetAddressLookupTable(tableKey solana.PublicKey) (*solana.AddressLookupTable, error) {
+	account, err := s.GetAccountInfo(tableKey)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get lookup table account %v: %v",
+			tableKey,
+			err,
+		)
+	}
+
+	if owner := account.Owner; owner != solana.AddressLookupTableProgramAddress {
+		return nil, fmt.Errorf(
+			"account %v is not a lookup table, owner was %v",
+			tableKey,
+			owner,
+		)
+	}
+
+	data, err := account.GetBinary()
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to decode lookup table account %v: %v",
+			tableKey,
+			err,
+		)
+	}
+
+	return solana.DecodeAddressLookupTable(tableKey, data)
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type (
+	// blockAccounts is a block with only the accounts of each
+	// transaction, including the addresses loaded from lookup tables
+	blockAccounts struct {
+		Blockhash    string                     `json:"blockhash"`
+		Transactions []blockAccountsTransaction `json:"transactions"`
+	}
+
+	blockAccountsTransaction struct {
+		Transaction struct {
+			AccountKeys []struct {
+				Pubkey string `json:"pubkey"`
+			} `json:"accountKeys"`
+		} `json:"transaction"`
+	}
+)
+
+// getBlockAccounts to get the block at the slot given with the accounts
+// of each transaction, with the commitment given
+func (s Provider) getBlockAccounts(slot uint64, commitment string) (*blockAccounts, error) {
+	res, err := s.RawInvoke("getBlock", []interface{}{
+		slot,
+		map[string]interface{}{
+			"encoding":                       "jsonParsed",
+			"transactionDetails":             "accounts",
+			"maxSupportedTransactionVersion": 0,
+			"rewards":                        false,
+			"commitment":                     commitment,
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to getBlock: %v",
+			err,
+		)
+	}
+
+	var block *blockAccounts
+
+	if err := json.Unmarshal(res, &block); err != nil {
+		return nil, fmt.Errorf(
+			"failed to decode getBlock, message %#v: %v",
+			string(res),
+			err,
+		)
+	}
+
+	if block == nil {
+		return nil, fmt.Errorf(
+			"getBlock returned no block for slot %v",
+			slot,
+		)
+	}
+
+	return block, nil
+}
+
+// mentions the account given in any of its transactions, the same as
+// blockSubscribe's mentionsAccountOrProgram filter
+func (block blockAccounts) mentions(account string) bool {
+	for _, transaction := range block.Transactions {
+		for _, accountKey := range transaction.Transaction.AccountKeys {
+			if accountKey.Pubkey == account {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -14,12 +14,19 @@ type getBlocksResult struct {
 	Error  map[string]interface{} `json:"error"`
 }
 
-func (s Provider) GetConfirmedBlocks(from, to uint64) ([]uint64, error) {
+// GetConfirmedBlocks to get up to limit finalized blocks from the slot given
+func (s Provider) GetConfirmedBlocks(from, limit uint64) ([]uint64, error) {
+	return s.GetBlocksWithLimit(from, limit, CommitmentFinalized)
+}
+
+// GetBlocksWithLimit to get up to limit blocks from the slot given, with the
+// commitment given (confirmed or finalized)
+func (s Provider) GetBlocksWithLimit(from, limit uint64, commitment string) ([]uint64, error) {
 	res, err := s.RawInvoke("getBlocksWithLimit", []interface{}{
 		from,
-		to,
+		limit,
 		map[string]string{
-			"commitment": "finalized",
+			"commitment": commitment,
 		},
 	})
 
@@ -105,6 +105,17 @@ func (client Http) RawInvoke(method string, params interface{}) (json.RawMessage
 		)
 	}
 
+	// the rpc is rate limiting or failing, so don't try to decode the
+	// body as a response
+
+	if code := resp.StatusCode; code == http.StatusTooManyRequests || code >= 500 {
+		return nil, fmt.Errorf(
+			"rpc returned status %v: %#v",
+			resp.Status,
+			bodyBuf.String(),
+		)
+	}
+
 	log.Debug(func(k *log.Log) {
 		bodyBuf2 := bodyBuf
 
@@ -129,7 +140,7 @@ func (client Http) RawInvoke(method string, params interface{}) (json.RawMessage
 
 	if err := response.Err; err != nil {
 		return nil, fmt.Errorf(
-			"rpc error was not nil: %v, %+v",
+			"rpc error was not nil: %w, %+v",
 			err,
 			response,
 		)
@@ -0,0 +1,53 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter to space out requests to an endpoint evenly
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// newRateLimiter allowing the requests per second given, nil (allowing
+// any number of requests) if 0
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+
+	interval := time.Duration(float64(time.Second) / requestsPerSecond)
+
+	return &rateLimiter{interval: interval}
+}
+
+// wait until the next request can be made
+func (limiter *rateLimiter) wait() {
+	if limiter == nil {
+		return
+	}
+
+	limiter.mu.Lock()
+
+	now := time.Now()
+
+	if limiter.next.Before(now) {
+		limiter.next = now
+	}
+
+	delay := limiter.next.Sub(now)
+
+	limiter.next = limiter.next.Add(limiter.interval)
+
+	limiter.mu.Unlock()
+
+	time.Sleep(delay)
+}
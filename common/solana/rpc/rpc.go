@@ -7,11 +7,20 @@ package rpc
 import (
 	"encoding/json"
 	"fmt"
-	"net/url"
 )
 
-// LogContextWebsocket to use for following errors
-const LogContextWebsocket = "SOLANA/RPC/WEBSOCKET"
+const (
+	// LogContextWebsocket to use for following errors
+	LogContextWebsocket = "SOLANA/RPC/WEBSOCKET"
+
+	// CommitmentFinalized for blocks confirmed by a supermajority with
+	// the maximum lockout
+	CommitmentFinalized = "finalized"
+
+	// CommitmentConfirmed for blocks voted on by a supermajority, the
+	// lowest commitment getBlocks supports
+	CommitmentConfirmed = "confirmed"
+)
 
 type (
 	// RpcContext used for tracing the outcome of different uses of the RPC
@@ -42,6 +51,10 @@ type (
 	}
 )
 
+func (err rpcError) Error() string {
+	return fmt.Sprintf("rpc error %v: %v", err.Code, err.Message)
+}
+
 type (
 	providerUnderlying interface {
 		RawInvoke(string, interface{}) (json.RawMessage, error)
@@ -50,54 +63,29 @@ type (
 	// Provider supporting
 	Provider struct {
 		providerUnderlying
+
+		// websocketUrls to subscribe with, tried in turn after a
+		// disconnect
+		websocketUrls []string
+
+		options failoverOptions
 	}
 )
 
 // New rpc provider - wss? will use Websocket, https? will use Http
 func New(url_ string) (*Provider, error) {
-	url, err := url.Parse(url_)
+	scheme, err := urlScheme(url_)
 
 	if err != nil {
-		return nil, fmt.Errorf(
-			"failed to parse the url for the new rpc provider: %v",
-			err,
-		)
+		return nil, err
 	}
 
-	switch scheme := url.Scheme; scheme {
-	case "http":
-		fallthrough
-
-	case "https":
-		r, err := NewHttp(url_)
-
-		if err != nil {
-			return nil, err
-		}
-
-		p := Provider{r}
-
-		return &p, nil
-
-	case "ws":
-		fallthrough
-
-	case "wss":
-		r, err := NewWebsocket(url_)
-
-		if err != nil {
-			return nil, err
-		}
-
-		p := Provider{r}
-
-		return &p, nil
+	switch scheme {
+	case "http", "https":
+		return NewFailover([]string{url_}, nil)
 
 	default:
-		return nil, fmt.Errorf(
-			"unknown scheme, was %#v, was expecting (http?|wss?)",
-			scheme,
-		)
+		return NewFailover(nil, []string{url_})
 	}
 }
 
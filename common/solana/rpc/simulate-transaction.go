@@ -39,13 +39,16 @@ func (s Provider) SimulateTransaction(transaction []byte, signatureVerify bool,
 
 	options := map[string]interface{}{
 		"sigVerify":              signatureVerify,
-		"commitment":             "finalized",
+		"commitment":             commitment,
 		"encoding":               "base64",
-		"replaceRecentBlockhash": true,
-		"accounts": map[string]interface{}{
+		"replaceRecentBlockhash": replaceRecentBlockHash,
+	}
+
+	if len(accounts) > 0 {
+		options["accounts"] = map[string]interface{}{
 			"encoding":  "base64",
 			"addresses": accountsStrings,
-		},
+		}
 	}
 
 	params := []interface{}{
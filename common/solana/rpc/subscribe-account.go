@@ -6,9 +6,9 @@ package rpc
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/fluidity-money/fluidity-app/common/solana"
-	"github.com/fluidity-money/fluidity-app/lib/log"
 	types "github.com/fluidity-money/fluidity-app/lib/types/solana"
 )
 
@@ -16,47 +16,51 @@ type accountNotification struct {
 	Value types.Account `json:"value"`
 }
 
-// SubscribeAccount subscribes to changes to account and dies with log.Fatal
-// if something goes wrong
-func (websocket Websocket) SubscribeAccount(publicKey solana.PublicKey, f func(types.Account)) {
+// SubscribeAccount subscribes to changes to account, subscribing again
+// after a disconnect and calling f with the account as it is then, in
+// case it changed while disconnected. Returns if a change couldn't be
+// decoded
+func (s Provider) SubscribeAccount(publicKey solana.PublicKey, f func(types.Account)) error {
 	programId := publicKey.ToBase58()
 
-	replies := websocket.subscribe("accountSubscribe", []interface{}{
+	params := []interface{}{
 		programId,
 		map[string]string{
 			"encoding":   "base64",
-			"commitment": "finalized",
+			"commitment": CommitmentFinalized,
 		},
-	})
+	}
 
-	for reply := range replies {
-		result := reply.result
+	onResubscribe := func() error {
+		account, err := s.GetAccountInfo(publicKey)
 
-		if err := reply.err; err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Context = LogContextWebsocket
-				k.Message = "Received error off queue for accountSubscribe!"
-				k.Payload = err
-			})
+		if err != nil {
+			return fmt.Errorf(
+				"failed to catch up on account %v: %v",
+				programId,
+				err,
+			)
 		}
 
-		var accountNotification accountNotification
-
-		err := json.Unmarshal(result, &accountNotification)
+		f(*account)
 
-		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Context = LogContextWebsocket
+		return nil
+	}
 
-				k.Format(
-					"Failed to decode the message %#v off the accountSubscribe websocket!",
-					string(result),
-				)
+	return s.resubscribe("accountSubscribe", params, onResubscribe, func(result json.RawMessage) error {
+		var accountNotification accountNotification
 
-				k.Payload = err
-			})
+		if err := json.Unmarshal(result, &accountNotification); err != nil {
+			return fmt.Errorf(
+				"%w: accountSubscribe message %#v: %v",
+				errDecodingNotification,
+				string(result),
+				err,
+			)
 		}
 
 		f(accountNotification.Value)
-	}
+
+		return nil
+	})
 }
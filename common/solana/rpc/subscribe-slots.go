@@ -6,58 +6,60 @@ package rpc
 
 import (
 	"encoding/json"
+	"fmt"
 
 	solCommon "github.com/fluidity-money/fluidity-app/common/solana"
-	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/types/solana"
 )
 
-// SubscribeSlots subscribes to new slots
-func (websocket Websocket) SubscribeSlots(f func(solana.Slot)) {
-	replies := websocket.subscribe("slotSubscribe", nil)
+// SubscribeSlots subscribes to new slots, subscribing again after a
+// disconnect and calling f with the slots with blocks that were missed.
+// Returns if a slot couldn't be decoded
+func (s Provider) SubscribeSlots(f func(solana.Slot)) error {
+	// slotSubscribe notifies slots as they're processed, so the slots
+	// missed are caught up on with the lowest commitment getBlocks has
 
-	for reply := range replies {
-		result := reply.result
-
-		if err := reply.err; err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Context = LogContextWebsocket
-				k.Message = "Received error off queue for slotsSubscribe!"
-				k.Payload = err
-			})
-		}
+	tracker := slotTracker{
+		provider:   s,
+		commitment: CommitmentConfirmed,
+	}
 
+	return s.resubscribe("slotSubscribe", nil, tracker.resubscribed, func(result json.RawMessage) error {
 		var slot solana.Slot
 
-		isEmptyMessage := len(result) == 0
-
-		if isEmptyMessage {
-			continue
+		if err := json.Unmarshal(result, &slot); err != nil {
+			return fmt.Errorf(
+				"%w: slotSubscribe message %#v: %v",
+				errDecodingNotification,
+				string(result),
+				err,
+			)
 		}
 
-		// assume that the message was empty for keepalive!
+		isNew, err := tracker.track(slot.Slot, func(missed uint64) error {
+			f(solana.Slot{Slot: missed})
 
-		err := json.Unmarshal(result, &slot)
+			return nil
+		})
 
 		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Context = LogContextWebsocket
-
-				k.Format(
-					"Failed to decode the message %#v off the slotsSubscribe websocket!",
-					string(result),
-				)
+			return err
+		}
 
-				k.Payload = err
-			})
+		if isNew {
+			f(slot)
 		}
 
-		f(slot)
-	}
+		return nil
+	})
 }
 
-// SubscribeBlocks to subscribe to new blocks with the given filter, if provided
-func (websocket Websocket) SubscribeBlocks(accountOrProgram solCommon.PublicKey, f func(BlockResponse)) {
+// SubscribeBlocks to subscribe to new blocks with the given filter, if
+// provided, subscribing again after a disconnect. Blocks missed while
+// disconnected are fetched and checked against the filter, then sent with
+// only their slot and blockhash set. Returns if a block couldn't be
+// decoded or fetched
+func (s Provider) SubscribeBlocks(accountOrProgram solCommon.PublicKey, f func(BlockResponse)) error {
 	var firstParam interface{}
 
 	if accountOrProgram.IsZero() {
@@ -76,52 +78,81 @@ func (websocket Websocket) SubscribeBlocks(accountOrProgram solCommon.PublicKey,
 			Encoding                       string `json:"encoding"`
 			TransactionDetails             string `json:"transactionDetails"`
 			MaxSupportedTransactionVersion int    `json:"maxSupportedTransactionVersion"`
+			Commitment                     string `json:"commitment"`
 		}{
 			MaxSupportedTransactionVersion: 0,
 			Encoding:                       "jsonParsed",
 			TransactionDetails:             "none",
+			Commitment:                     CommitmentFinalized,
 		},
 	}
 
-	replies := websocket.subscribe("blockSubscribe", params)
+	tracker := slotTracker{
+		provider:   s,
+		commitment: CommitmentFinalized,
+	}
 
-	for reply := range replies {
-		result := reply.result
+	return s.resubscribe("blockSubscribe", params, tracker.resubscribed, func(result json.RawMessage) error {
+		var block BlockResponse
 
-		if err := reply.err; err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Context = LogContextWebsocket
-				k.Message = "Received error off queue for blocksSubscribe!"
-				k.Payload = err
-			})
+		if err := json.Unmarshal(result, &block); err != nil {
+			return fmt.Errorf(
+				"%w: blockSubscribe message %#v: %v",
+				errDecodingNotification,
+				string(result),
+				err,
+			)
 		}
 
-		var block BlockResponse
+		isNew, err := tracker.track(block.Value.Slot, func(missed uint64) error {
+			missedBlock, err := s.missedBlock(missed, accountOrProgram, tracker.commitment)
 
-		isEmptyMessage := len(result) == 0
+			if err != nil {
+				return err
+			}
 
-		if isEmptyMessage {
-			continue
-		}
+			if missedBlock != nil {
+				f(*missedBlock)
+			}
 
-		// assume that the message was empty for keepalive!
-		err := json.Unmarshal(result, &block)
+			return nil
+		})
 
 		if err != nil {
-			log.Fatal(func(k *log.Log) {
-				k.Context = LogContextWebsocket
-
-				k.Format(
-					"Failed to decode the message %#v off the blocksSubscribe websocket!",
-					string(result),
-				)
+			return err
+		}
 
-				k.Payload = err
-			})
+		if isNew {
+			f(block)
 		}
 
-		f(block)
+		return nil
+	})
+}
+
+// missedBlock at the slot given, returning nil if the filter is set and
+// none of its transactions mention the account or program
+func (s Provider) missedBlock(slot uint64, accountOrProgram solCommon.PublicKey, commitment string) (*BlockResponse, error) {
+	block, err := s.getBlockAccounts(slot, commitment)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get the missed block at slot %v: %v",
+			slot,
+			err,
+		)
 	}
+
+	if !accountOrProgram.IsZero() && !block.mentions(accountOrProgram.String()) {
+		return nil, nil
+	}
+
+	var missedBlock BlockResponse
+
+	missedBlock.Value.Slot = slot
+	missedBlock.Value.Block.Blockhash = block.Blockhash
+
+	return &missedBlock, nil
 }
 
 type BlockResponse struct {
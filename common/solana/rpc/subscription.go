@@ -0,0 +1,219 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+)
+
+// catchUpPageLength of the blocks to get at once when catching up on the
+// slots missed while disconnected
+const catchUpPageLength = 1000
+
+// errDecodingNotification is wrapped by the errors of notifications that
+// couldn't be decoded, which end the subscription instead of subscribing
+// again
+var errDecodingNotification = errors.New("failed to decode a notification")
+
+// slotTracker of the last slot seen by a subscription, to catch up on the
+// slots missed while it was disconnected
+type slotTracker struct {
+	provider Provider
+
+	// commitment to get the blocks missed with
+	commitment string
+
+	lastSlot uint64
+	seen     bool
+
+	// catchingUp since the subscription was made again
+	catchingUp bool
+}
+
+// resubscribe to the method given with the websocket urls in turn until a
+// notification can't be decoded, calling handle with every notification.
+// onResubscribe is called every time the subscription is made again
+// after a disconnect, before any notifications are handled
+func (s Provider) resubscribe(method string, params interface{}, onResubscribe func() error, handle func(json.RawMessage) error) error {
+	if len(s.websocketUrls) == 0 {
+		return fmt.Errorf(
+			"no websocket urls to subscribe to %v with",
+			method,
+		)
+	}
+
+	var (
+		delay        = s.options.resubscribeDelay
+		resubscribed = false
+	)
+
+	for attempt := 0; ; attempt++ {
+		url := s.websocketUrls[attempt%len(s.websocketUrls)]
+
+		var onResubscribe_ func() error
+
+		if resubscribed {
+			onResubscribe_ = onResubscribe
+		}
+
+		subscribed, err := subscribeOnce(url, method, params, onResubscribe_, handle)
+
+		if errors.Is(err, errDecodingNotification) {
+			return err
+		}
+
+		// start backing off again if the subscription was made, since
+		// it was working until the disconnect
+
+		if subscribed {
+			delay = s.options.resubscribeDelay
+			resubscribed = true
+		}
+
+		log.App(func(k *log.Log) {
+			k.Context = LogContextWebsocket
+
+			k.Format(
+				"Subscription to %v with %v ended, subscribing again in %v!",
+				method,
+				url,
+				delay,
+			)
+
+			k.Payload = err
+		})
+
+		time.Sleep(delay)
+
+		if delay *= 2; delay > maxResubscribeDelay {
+			delay = maxResubscribeDelay
+		}
+	}
+}
+
+// subscribeOnce to the method with the websocket url given, handling
+// notifications until the connection is lost or handle fails. Returns
+// whether the subscription was made
+func subscribeOnce(url, method string, params interface{}, onResubscribe func() error, handle func(json.RawMessage) error) (bool, error) {
+	websocket, err := NewWebsocket(url)
+
+	if err != nil {
+		return false, err
+	}
+
+	notifications, err := websocket.subscribe(method, params)
+
+	if err != nil {
+		websocket.Close()
+
+		return false, err
+	}
+
+	// drain the notifications after closing so the websocket isn't
+	// stuck sending one
+
+	defer func() {
+		websocket.Close()
+
+		for range notifications {
+		}
+	}()
+
+	if onResubscribe != nil {
+		if err := onResubscribe(); err != nil {
+			return true, err
+		}
+	}
+
+	for notification := range notifications {
+		if err := notification.err; err != nil {
+			return true, err
+		}
+
+		// assume that the message was empty for keepalive!
+
+		if len(notification.result) == 0 {
+			continue
+		}
+
+		if err := handle(notification.result); err != nil {
+			return true, err
+		}
+	}
+
+	return true, ErrWebsocketClosed
+}
+
+// resubscribed to start catching up from the last slot seen on the next
+// notification
+func (tracker *slotTracker) resubscribed() error {
+	tracker.catchingUp = tracker.seen
+
+	return nil
+}
+
+// track the slot of a notification, first calling missed with every slot
+// with a block since the last slot seen if the subscription was made
+// again. Returns false if the slot was already seen, or if missed failed
+func (tracker *slotTracker) track(slot uint64, missed func(uint64) error) (bool, error) {
+	if tracker.seen && slot <= tracker.lastSlot {
+		return false, nil
+	}
+
+	for tracker.catchingUp {
+		from := tracker.lastSlot + 1
+
+		if from >= slot {
+			tracker.catchingUp = false
+
+			break
+		}
+
+		blocks, err := tracker.provider.GetBlocksWithLimit(
+			from,
+			catchUpPageLength,
+			tracker.commitment,
+		)
+
+		if err != nil {
+			return false, fmt.Errorf(
+				"failed to catch up on the slots from %v: %v",
+				from,
+				err,
+			)
+		}
+
+		for _, block := range blocks {
+			if block >= slot {
+				tracker.catchingUp = false
+
+				break
+			}
+
+			if err := missed(block); err != nil {
+				return false, err
+			}
+
+			tracker.lastSlot = block
+		}
+
+		// the endpoint has nothing past the last block, so either
+		// the slots were skipped or it's behind the subscription
+
+		if len(blocks) < catchUpPageLength {
+			tracker.catchingUp = false
+		}
+	}
+
+	tracker.lastSlot = slot
+	tracker.seen = true
+
+	return true, nil
+}
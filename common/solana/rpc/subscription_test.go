@@ -0,0 +1,238 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	solCommon "github.com/fluidity-money/fluidity-app/common/solana"
+	"github.com/fluidity-money/fluidity-app/lib/types/solana"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSubscriptionId = 7
+
+// newTestWebsocket that confirms the subscription made to it then sends
+// the slots given for each connection in turn, closing every connection
+// but the last once it's sent them
+func newTestWebsocket(t *testing.T, slotsPerConnection ...[]uint64) string {
+	var (
+		upgrader    websocket.Upgrader
+		connections int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		connection := int(atomic.AddInt32(&connections, 1)) - 1
+
+		if connection >= len(slotsPerConnection) {
+			return
+		}
+
+		var request rpcRequest
+
+		if err := conn.ReadJSON(&request); err != nil {
+			return
+		}
+
+		reply := fmt.Sprintf(
+			`{"jsonrpc":"2.0","id":%d,"result":%d}`,
+			request.Id,
+			testSubscriptionId,
+		)
+
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(reply)); err != nil {
+			return
+		}
+
+		for _, slot := range slotsPerConnection[connection] {
+			notification := fmt.Sprintf(
+				`{"jsonrpc":"2.0","method":"slotNotification","params":{"subscription":%d,"result":{"slot":%d,"parent":%d,"root":0}}}`,
+				testSubscriptionId,
+				slot,
+				slot-1,
+			)
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(notification)); err != nil {
+				return
+			}
+		}
+
+		// keep the last connection open until the client goes away
+
+		if connection == len(slotsPerConnection)-1 {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscribeSlotsCatchesUp(t *testing.T) {
+	websocketUrl := newTestWebsocket(
+		t,
+		[]uint64{100, 101},
+		[]uint64{101, 105},
+	)
+
+	var catchUps uint64
+
+	// the blocks produced while disconnected, past the slot the
+	// subscription picks up from too
+
+	httpEndpoint := newTestEndpoint(t, func(method string) (interface{}, *rpcError, int) {
+		assert.Equal(t, "getBlocksWithLimit", method)
+
+		atomic.AddUint64(&catchUps, 1)
+
+		return []uint64{102, 104, 105, 106}, nil, http.StatusOK
+	})
+
+	provider, err := NewFailover(
+		[]string{httpEndpoint.URL},
+		[]string{websocketUrl},
+		FailoverResubscribeDelay(time.Millisecond),
+	)
+
+	require.NoError(t, err)
+
+	slots := make(chan uint64)
+
+	go provider.SubscribeSlots(func(slot solana.Slot) {
+		slots <- slot.Slot
+	})
+
+	expected := []uint64{100, 101, 102, 104, 105}
+
+	for _, expectedSlot := range expected {
+		select {
+		case slot := <-slots:
+			assert.Equal(t, expectedSlot, slot)
+
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for slot %v", expectedSlot)
+		}
+	}
+
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&catchUps))
+}
+
+func TestSubscribeWithoutWebsockets(t *testing.T) {
+	httpEndpoint := newTestEndpoint(t, replyWith(0))
+
+	provider, err := NewFailover([]string{httpEndpoint.URL}, nil, FailoverHealthCheckInterval(time.Hour))
+
+	require.NoError(t, err)
+
+	err = provider.SubscribeSlots(func(solana.Slot) {})
+
+	assert.Error(t, err)
+}
+
+func TestSlotTrackerSkipsSeenSlots(t *testing.T) {
+	var tracker slotTracker
+
+	isNew, err := tracker.track(10, nil)
+
+	require.NoError(t, err)
+	assert.True(t, isNew)
+
+	isNew, err = tracker.track(10, nil)
+
+	require.NoError(t, err)
+	assert.False(t, isNew)
+
+	isNew, err = tracker.track(11, nil)
+
+	require.NoError(t, err)
+	assert.True(t, isNew)
+}
+
+func TestMissedBlockFiltered(t *testing.T) {
+	var mentioned, other solCommon.PublicKey
+
+	mentioned[0] = 1
+	other[0] = 2
+
+	// the only transaction in the block loads the account from a
+	// lookup table
+
+	block := map[string]interface{}{
+		"blockhash": "hash",
+		"transactions": []interface{}{
+			map[string]interface{}{
+				"transaction": map[string]interface{}{
+					"accountKeys": []interface{}{
+						map[string]interface{}{"pubkey": "payer", "source": "transaction"},
+						map[string]interface{}{"pubkey": mentioned.String(), "source": "lookupTable"},
+					},
+				},
+			},
+		},
+	}
+
+	httpEndpoint := newTestEndpoint(t, func(method string) (interface{}, *rpcError, int) {
+		assert.Equal(t, "getBlock", method)
+
+		return block, nil, http.StatusOK
+	})
+
+	provider, err := NewFailover([]string{httpEndpoint.URL}, nil, FailoverHealthCheckInterval(time.Hour))
+
+	require.NoError(t, err)
+
+	missedBlock, err := provider.missedBlock(102, mentioned, CommitmentFinalized)
+
+	require.NoError(t, err)
+	require.NotNil(t, missedBlock)
+	assert.Equal(t, uint64(102), missedBlock.Value.Slot)
+	assert.Equal(t, "hash", missedBlock.Value.Block.Blockhash)
+
+	missedBlock, err = provider.missedBlock(102, other, CommitmentFinalized)
+
+	require.NoError(t, err)
+	assert.Nil(t, missedBlock)
+
+	// without a filter every block is sent
+
+	missedBlock, err = provider.missedBlock(102, solCommon.PublicKey{}, CommitmentFinalized)
+
+	require.NoError(t, err)
+	assert.NotNil(t, missedBlock)
+}
+
+func TestMissedBlockSkipped(t *testing.T) {
+	httpEndpoint := newTestEndpoint(t, replyWith(nil))
+
+	provider, err := NewFailover([]string{httpEndpoint.URL}, nil, FailoverHealthCheckInterval(time.Hour))
+
+	require.NoError(t, err)
+
+	_, err = provider.missedBlock(102, solCommon.PublicKey{}, CommitmentFinalized)
+
+	assert.Error(t, err)
+}
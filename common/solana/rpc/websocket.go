@@ -8,10 +8,14 @@ package rpc
 // around logging.
 
 // websocket first sends the connected user a "ticket" with their identifier
-// and then sends them what it received from upstream
+// and then sends them what it received from upstream. notifications are
+// sent to the caller that subscribed with the subscription id they have.
+// if the connection is lost, every caller is sent the error and their
+// channel is closed.
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/fluidity-money/fluidity-app/lib/log"
@@ -19,10 +23,17 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// ErrWebsocketClosed is returned when invoking a method with a websocket
+// that has lost its connection
+var ErrWebsocketClosed = errors.New("solana websocket closed")
+
 type (
 	Websocket struct {
-		invokeChan  chan websocketOutgoing
-		shouldClose chan bool
+		invokeChan chan websocketOutgoing
+		conn       *websocket.Conn
+
+		// closed once the connection is lost
+		closed chan struct{}
 	}
 
 	// websocketOutgoingResponse to use to track the reply for the
@@ -31,7 +42,7 @@ type (
 	websocketOutgoingResponse struct {
 		id     int
 		result json.RawMessage
-		err    *rpcError
+		err    error
 	}
 
 	websocketResponseParams struct {
@@ -40,11 +51,14 @@ type (
 	}
 
 	websocketResponse struct {
-		Id      int                     `json:"id"`
-		JsonRpc string                  `json:"jsonrpc"`
-		Params  websocketResponseParams `json:"params"`
-		// TODO where is this actually located? is it on params? does it exist at all?
-		Err *rpcError `json:"error"`
+		Id      int    `json:"id"`
+		JsonRpc string `json:"jsonrpc"`
+
+		// Method is set for notifications, which don't have an id
+		Method string                  `json:"method"`
+		Result json.RawMessage         `json:"result"`
+		Params websocketResponseParams `json:"params"`
+		Err    *rpcError               `json:"error"`
 	}
 
 	// WebsocketOutgoing to send messages down with and to receive
@@ -53,6 +67,10 @@ type (
 		returnChannel chan websocketOutgoingResponse
 		method        string
 		params        interface{}
+
+		// subscription to send the notifications for to the return
+		// channel after the reply
+		subscription bool
 	}
 
 	SubscriptionResponse struct {
@@ -75,13 +93,20 @@ func NewWebsocket(url string) (*Websocket, error) {
 		// sent from the underlying websocket to any connected users
 		incomingMessagesChan = make(chan websocketResponse)
 
-		// sent down when the connection needs to close prematurely
-		closeChannel = make(chan bool)
+		// sent down when the connection is lost
+		readErrChan = make(chan error)
+
+		closedChan = make(chan struct{})
 
 		// all messages with their reply queue are stored in this map
 		// to connect the response to it
 
-		responses = make(map[int]chan websocketOutgoingResponse, 0)
+		responses = make(map[int]websocketOutgoing, 0)
+
+		// subscriptions with their notification queue, by the
+		// subscription id from the reply to the subscribe call
+
+		subscriptions = make(map[int]chan websocketOutgoingResponse, 0)
 	)
 
 	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
@@ -94,62 +119,53 @@ func NewWebsocket(url string) (*Websocket, error) {
 	}
 
 	go func() {
-	L:
 		for {
 			var response websocketResponse
 
 			messageType, message, err := conn.ReadMessage()
 
-			isUnexpectedErr := websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway)
-
-			switch true {
-			case err != nil && isUnexpectedErr:
-				log.Fatal(func(k *log.Log) {
-					k.Context = LogContextWebsocket
-					k.Message = "Failed to read a message from the Solana websocket!"
-					k.Payload = err
-				})
+			if err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway) {
+					log.App(func(k *log.Log) {
+						k.Context = LogContextWebsocket
+						k.Message = "Failed to read a message from the Solana websocket!"
+						k.Payload = err
+					})
+				}
 
-			case err != nil:
-				log.Fatal(func(k *log.Log) {
-					k.Context = LogContextWebsocket
-					k.Message = "Solana websocket went away!"
-					k.Payload = err
-				})
+				readErrChan <- fmt.Errorf("%w: %v", ErrWebsocketClosed, err)
 
-			default:
+				return
 			}
 
-			switch messageType {
-			case websocket.TextMessage:
-
-			case websocket.BinaryMessage:
-				log.Fatal(func(k *log.Log) {
+			if messageType != websocket.TextMessage {
+				log.Debug(func(k *log.Log) {
 					k.Context = LogContextWebsocket
-					k.Message = "Received binary message from the websocket!"
-				})
 
-			case websocket.CloseMessage:
-				log.Fatal(func(k *log.Log) {
-					k.Context = LogContextWebsocket
-					k.Message = "Received a close message from the websocket!"
+					k.Format(
+						"Skipping a message of type %v from the websocket!",
+						messageType,
+					)
 				})
 
-			default:
-				continue L
+				continue
 			}
 
 			err = json.Unmarshal(message, &response)
 
 			if err != nil {
-				log.Fatal(func(k *log.Log) {
+				log.App(func(k *log.Log) {
 					k.Context = LogContextWebsocket
 
 					k.Format(
-						"Failed to decode message (%#v) off the websocket!",
+						"Failed to decode message (%#v) off the websocket, skipping it!",
 						string(message),
 					)
+
+					k.Payload = err
 				})
+
+				continue
 			}
 
 			log.Debug(func(k *log.Log) {
@@ -171,15 +187,46 @@ func NewWebsocket(url string) (*Websocket, error) {
 	callIdCount := 0
 
 	go func() {
+		var closedErr error
+
 		for {
 			select {
 			case response := <-incomingMessagesChan:
+				// notifications are sent to the subscriber, replies to
+				// the caller
+
+				if response.Method != "" {
+					subscriptionId := response.Params.Subscription
+
+					notifications, ok := subscriptions[subscriptionId]
+
+					if !ok {
+						log.Debug(func(k *log.Log) {
+							k.Context = LogContextWebsocket
+
+							k.Format(
+								"Got a notification for subscription %v that does not exist!",
+								subscriptionId,
+							)
+						})
+
+						continue
+					}
+
+					notifications <- websocketOutgoingResponse{
+						id:     subscriptionId,
+						result: response.Params.Result,
+					}
+
+					continue
+				}
+
 				id := response.Id
 
-				responses, ok := responses[id]
+				rpcCall, ok := responses[id]
 
 				if !ok {
-					log.Fatal(func(k *log.Log) {
+					log.App(func(k *log.Log) {
 						k.Context = LogContextWebsocket
 
 						k.Format(
@@ -187,8 +234,12 @@ func NewWebsocket(url string) (*Websocket, error) {
 							id,
 						)
 					})
+
+					continue
 				}
 
+				delete(responses, id)
+
 				log.Debug(func(k *log.Log) {
 					k.Context = LogContextWebsocket
 
@@ -198,12 +249,34 @@ func NewWebsocket(url string) (*Websocket, error) {
 					)
 				})
 
-				responses <- websocketOutgoingResponse{
+				reply := websocketOutgoingResponse{
 					id:     id,
-					result: response.Params.Result,
-					err:    response.Err,
+					result: response.Result,
 				}
 
+				if response.Err != nil {
+					reply.err = response.Err
+				}
+
+				// the reply to a subscription is its id, that the
+				// notifications will be sent with
+
+				if rpcCall.subscription && reply.err == nil {
+					var subscriptionId int
+
+					if err := json.Unmarshal(response.Result, &subscriptionId); err != nil {
+						reply.err = fmt.Errorf(
+							"failed to decode the subscription id %#v: %v",
+							string(response.Result),
+							err,
+						)
+					} else {
+						subscriptions[subscriptionId] = rpcCall.returnChannel
+					}
+				}
+
+				rpcCall.returnChannel <- reply
+
 				log.Debug(func(k *log.Log) {
 					k.Context = LogContextWebsocket
 
@@ -220,6 +293,13 @@ func NewWebsocket(url string) (*Websocket, error) {
 					returnChannel = rpcCall.returnChannel
 				)
 
+				if closedErr != nil {
+					returnChannel <- websocketOutgoingResponse{err: closedErr}
+					close(returnChannel)
+
+					continue
+				}
+
 				callId := callIdCount
 
 				callIdCount += 1
@@ -234,26 +314,32 @@ func NewWebsocket(url string) (*Websocket, error) {
 				bytes, err := json.Marshal(rpcRequest)
 
 				if err != nil {
-					log.Fatal(func(k *log.Log) {
-						k.Context = LogContextWebsocket
-						k.Message = "Failed to encode a RPC request for sending down Solana socket"
-						k.Payload = err
-					})
+					returnChannel <- websocketOutgoingResponse{
+						id: callId,
+						err: fmt.Errorf(
+							"failed to encode a rpc request for sending down the solana socket: %v",
+							err,
+						),
+					}
+
+					continue
 				}
 
 				err = conn.WriteMessage(websocket.TextMessage, bytes)
 
-				if err != nil {
-					log.Fatal(func(k *log.Log) {
-						k.Context = LogContextWebsocket
-
-						k.Format(
-							"Failed to write the rpc body to the Solana socket %v!",
-							rpcRequest,
-						)
+				// the reader will find the connection is lost if the
+				// write failed because of it
 
-						k.Payload = err
-					})
+				if err != nil {
+					returnChannel <- websocketOutgoingResponse{
+						id: callId,
+						err: fmt.Errorf(
+							"failed to write the rpc body to the solana socket: %v",
+							err,
+						),
+					}
+
+					continue
 				}
 
 				log.Debug(func(k *log.Log) {
@@ -268,33 +354,77 @@ func NewWebsocket(url string) (*Websocket, error) {
 
 				// remember the request so we can send them the response later
 
-				responses[callId] = returnChannel
+				responses[callId] = rpcCall
+
+			case err := <-readErrChan:
+				// tell every caller and subscriber the connection is
+				// gone, then reply to any later calls with the error
+
+				closedErr = err
+
+				for id, rpcCall := range responses {
+					rpcCall.returnChannel <- websocketOutgoingResponse{id: id, err: err}
+					close(rpcCall.returnChannel)
+				}
+
+				for id, notifications := range subscriptions {
+					notifications <- websocketOutgoingResponse{id: id, err: err}
+					close(notifications)
+				}
+
+				responses = nil
+				subscriptions = nil
+
+				close(closedChan)
 			}
 		}
 	}()
 
 	websocket := Websocket{
-		invokeChan:  outgoingMessagesChan,
-		shouldClose: closeChannel,
+		invokeChan: outgoingMessagesChan,
+		conn:       conn,
+		closed:     closedChan,
 	}
 
 	return &websocket, nil
 }
 
-func (websocket Websocket) subscribe(method string, params interface{}) chan websocketOutgoingResponse {
-	replies := make(chan websocketOutgoingResponse)
+// subscribe to the method given, returning the channel the notifications
+// are sent to once the subscription is confirmed. The channel is closed
+// after an error if the connection is lost
+func (websocket *Websocket) subscribe(method string, params interface{}) (chan websocketOutgoingResponse, error) {
+	// buffered so the reply can be sent before the caller reads it
+
+	replies := make(chan websocketOutgoingResponse, 1)
 
 	websocket.invokeChan <- websocketOutgoing{
 		method:        method,
 		params:        params,
 		returnChannel: replies,
+		subscription:  true,
 	}
 
-	return replies
+	reply := <-replies
+
+	if err := reply.err; err != nil {
+		return nil, fmt.Errorf(
+			"failed to subscribe with %v: %w",
+			method,
+			err,
+		)
+	}
+
+	return replies, nil
 }
 
-func (websocket Websocket) RawInvoke(method string, params interface{}) (json.RawMessage, error) {
-	replies := websocket.subscribe(method, params)
+func (websocket *Websocket) RawInvoke(method string, params interface{}) (json.RawMessage, error) {
+	replies := make(chan websocketOutgoingResponse, 1)
+
+	websocket.invokeChan <- websocketOutgoing{
+		method:        method,
+		params:        params,
+		returnChannel: replies,
+	}
 
 	response := <-replies
 
@@ -302,7 +432,7 @@ func (websocket Websocket) RawInvoke(method string, params interface{}) (json.Ra
 
 	if err := response.err; err != nil {
 		return result, fmt.Errorf(
-			"rpc method %v returned error: %v",
+			"rpc method %v returned error: %w",
 			method,
 			err,
 		)
@@ -310,3 +440,18 @@ func (websocket Websocket) RawInvoke(method string, params interface{}) (json.Ra
 
 	return result, nil
 }
+
+// Close the connection, sending an error to every subscriber
+func (websocket *Websocket) Close() error {
+	return websocket.conn.Close()
+}
+
+func (websocket *Websocket) isClosed() bool {
+	select {
+	case <-websocket.closed:
+		return true
+
+	default:
+		return false
+	}
+}
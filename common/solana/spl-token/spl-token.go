@@ -69,6 +69,38 @@ type (
 // SendTransfer using the token address given, the sender address, returning
 // the signature or an error
 func SendTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress, tokenMintAddress solLib.PublicKey, amount uint64, recentBlockHash solLib.Hash, ownerPublicKey solLib.PublicKey, ownerPrivateKey solLib.PrivateKey) (string, error) {
+	transaction, err := MakeTransfer(
+		solanaClient,
+		senderPdaAddress,
+		recipientAddress,
+		tokenMintAddress,
+		amount,
+		recentBlockHash,
+		ownerPublicKey,
+		ownerPrivateKey,
+	)
+
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := solanaClient.SendTransaction(transaction)
+
+	if err != nil {
+		return "", fmt.Errorf(
+			"failed to send the transaction to Solana! %v",
+			err,
+		)
+	}
+
+	signatureString := fmt.Sprintf("%x", string(signature[:]))
+
+	return signatureString, nil
+}
+
+// MakeTransfer to build and sign the transaction SendTransfer sends,
+// creating the recipient's associated token account if it doesn't exist
+func MakeTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress, tokenMintAddress solLib.PublicKey, amount uint64, recentBlockHash solLib.Hash, ownerPublicKey solLib.PublicKey, ownerPrivateKey solLib.PrivateKey) (*solLib.Transaction, error) {
 
 	var (
 		senderAccountMeta = solLib.NewAccountMeta(senderPdaAddress, true, false)
@@ -88,7 +120,7 @@ func SendTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress
 	)
 
 	if err != nil {
-		return "", fmt.Errorf(
+		return nil, fmt.Errorf(
 			"unable to derive the user's ATA key! %v",
 			err,
 		)
@@ -134,7 +166,7 @@ func SendTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress
 	dataSerialised, err := borsh.Serialize(data)
 
 	if err != nil {
-		return "", fmt.Errorf(
+		return nil, fmt.Errorf(
 			"Failed to serialise the data content to send out! %v",
 			err,
 		)
@@ -151,7 +183,7 @@ func SendTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress
 	transaction, err := solLib.NewTransaction(instructions, recentBlockHash)
 
 	if err != nil {
-		return "", fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to make a new transaction with the instructions given and the block hash! %v",
 			err,
 		)
@@ -167,22 +199,11 @@ func SendTransfer(solanaClient *rpc.Provider, senderPdaAddress, recipientAddress
 	})
 
 	if err != nil {
-		return "", fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to sign the transaction using the user's private key! %v",
 			err,
 		)
 	}
 
-	signature, err := solanaClient.SendTransaction(transaction)
-
-	if err != nil {
-		return "", fmt.Errorf(
-			"failed to send the transaction to Solana! %v",
-			err,
-		)
-	}
-
-	signatureString := fmt.Sprintf("%x", string(signature[:]))
-
-	return signatureString, nil
+	return transaction, nil
 }
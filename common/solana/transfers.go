@@ -10,8 +10,10 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/types/solana"
 )
 
+// ClassifyApplication by the accounts used in the transaction, including
+// those loaded from lookup tables by v0 transactions
 func ClassifyApplication(transaction solana.TransactionResult, apps map[string]applications.Application) []applications.Application {
-	accounts := transaction.Transaction.Message.AccountKeys
+	accounts := transaction.AccountKeys()
 
 	foundApps := make(map[applications.Application]struct{}, 0)
 
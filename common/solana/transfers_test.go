@@ -0,0 +1,43 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package solana
+
+import (
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/solana/applications"
+	solTypes "github.com/fluidity-money/fluidity-app/lib/types/solana"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyApplicationLoadedAddresses(t *testing.T) {
+	var (
+		orcaProgram = testKey(1).String()
+		orcaPool    = testKey(2).String()
+	)
+
+	apps := map[string]applications.Application{
+		orcaPool: applications.ApplicationOrca,
+	}
+
+	var transaction solTypes.TransactionResult
+
+	transaction.Transaction.Message.AccountKeys = []string{testKey(9).String(), orcaProgram}
+
+	assert.Empty(t, ClassifyApplication(transaction, apps))
+
+	// a v0 swap loading the pool from a lookup table
+
+	transaction.Meta.LoadedAddresses = &solTypes.TransactionLoadedAddresses{
+		Writable: []string{orcaPool},
+	}
+
+	assert.Equal(
+		t,
+		[]applications.Application{applications.ApplicationOrca},
+		ClassifyApplication(transaction, apps),
+	)
+}
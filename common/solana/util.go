@@ -8,6 +8,7 @@ import (
 	crypto_rand "crypto/rand"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 
 	"github.com/btcsuite/btcutil/base58"
@@ -157,7 +158,30 @@ type CompiledInstruction struct {
 	Data []byte `json:"data"`
 }
 
+// MessageVersion of a message, legacy messages aren't prefixed with one
+type MessageVersion int
+
+const (
+	MessageVersionLegacy MessageVersion = iota
+	MessageVersionV0
+)
+
+// messageVersionPrefix set on the first byte of a versioned message, with
+// the version in the rest of the byte
+const messageVersionPrefix = 0x80
+
+// MessageAddressTableLookup loading accounts from a lookup table into a
+// v0 message by their index in the table
+type MessageAddressTableLookup struct {
+	AccountKey      PublicKey `json:"accountKey"`
+	WritableIndexes []uint8   `json:"writableIndexes"`
+	ReadonlyIndexes []uint8   `json:"readonlyIndexes"`
+}
+
 type Message struct {
+	// Version of the message, legacy unless lookup tables are used
+	Version MessageVersion `json:"-"`
+
 	// List of base-58 encoded public keys used by the transaction,
 	// including by the instructions and for signatures.
 	// The first `message.header.numRequiredSignatures` public keys must sign the transaction.
@@ -173,6 +197,11 @@ type Message struct {
 	// List of program instructions that will be executed in sequence
 	// and committed in one atomic transaction if all succeed.
 	Instructions []CompiledInstruction `json:"instructions"`
+
+	// Lookups of the accounts loaded from lookup tables, indexed by
+	// instructions after the account keys, the writable accounts of
+	// every lookup first. Only in v0 messages.
+	AddressTableLookups []MessageAddressTableLookup `json:"addressTableLookups"`
 }
 
 type Signature [64]byte
@@ -215,7 +244,8 @@ type TransactionOption interface {
 }
 
 type transactionOptions struct {
-	payer PublicKey
+	payer         PublicKey
+	addressTables []AddressLookupTable
 }
 
 type Instruction interface {
@@ -366,14 +396,71 @@ func NewTransaction(instructions []Instruction, recentBlockHash Hash, opts ...Tr
 		finalAccounts[0] = feePayerAccount
 	}
 
+	message, err := compileMessage(
+		finalAccounts,
+		programIDs,
+		instructions,
+		recentBlockHash,
+		options.addressTables,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transaction{
+		Message: *message,
+	}, nil
+}
+
+// compileMessage with the accounts given, sorted with the fee payer
+// first, loading the accounts that can be from the lookup tables given
+// and making the message v0 if there are any
+func compileMessage(accounts []*AccountMeta, programIDs PublicKeySlice, instructions []Instruction, recentBlockHash Hash, addressTables []AddressLookupTable) (*Message, error) {
 	message := Message{
 		RecentBlockhash: recentBlockHash,
 	}
+
+	if len(addressTables) > 0 {
+		message.Version = MessageVersionV0
+	}
+
+	type loadedAccount struct {
+		table int
+		index uint8
+	}
+
+	var (
+		// writable then readonly accounts loaded from each table
+		loadedWritable = make([][]*AccountMeta, len(addressTables))
+		loadedReadonly = make([][]*AccountMeta, len(addressTables))
+
+		loaded = map[PublicKey]loadedAccount{}
+	)
+
 	accountKeyIndex := map[string]uint16{}
-	for idx, acc := range finalAccounts {
+
+	for _, acc := range accounts {
+		// signers and invoked programs can't be loaded from tables
+
+		if !acc.IsSigner && !programIDs.Has(acc.PublicKey) {
+			table, index, found := findInAddressTables(addressTables, acc.PublicKey)
+
+			if found {
+				loaded[acc.PublicKey] = loadedAccount{table, index}
+
+				if acc.IsWritable {
+					loadedWritable[table] = append(loadedWritable[table], acc)
+				} else {
+					loadedReadonly[table] = append(loadedReadonly[table], acc)
+				}
+
+				continue
+			}
+		}
 
 		message.AccountKeys = append(message.AccountKeys, acc.PublicKey)
-		accountKeyIndex[acc.PublicKey.String()] = uint16(idx)
+		accountKeyIndex[acc.PublicKey.String()] = uint16(len(message.AccountKeys) - 1)
 		if acc.IsSigner {
 			message.Header.NumRequiredSignatures++
 			if !acc.IsWritable {
@@ -387,8 +474,51 @@ func NewTransaction(instructions []Instruction, recentBlockHash Hash, opts ...Tr
 		}
 	}
 
+	// loaded accounts are indexed after the account keys, the writable
+	// accounts of every table before the readonly accounts
+
+	nextIndex := len(message.AccountKeys)
+
+	for _, loadedAccounts := range [][][]*AccountMeta{loadedWritable, loadedReadonly} {
+		for _, tableAccounts := range loadedAccounts {
+			for _, acc := range tableAccounts {
+				accountKeyIndex[acc.PublicKey.String()] = uint16(nextIndex)
+				nextIndex++
+			}
+		}
+	}
+
+	if nextIndex > 256 {
+		return nil, fmt.Errorf(
+			"transaction uses %v accounts, more than 256",
+			nextIndex,
+		)
+	}
+
+	for table, addressTable := range addressTables {
+		lookup := MessageAddressTableLookup{
+			AccountKey:      addressTable.Key,
+			WritableIndexes: make([]uint8, 0),
+			ReadonlyIndexes: make([]uint8, 0),
+		}
+
+		for _, acc := range loadedWritable[table] {
+			lookup.WritableIndexes = append(lookup.WritableIndexes, loaded[acc.PublicKey].index)
+		}
+
+		for _, acc := range loadedReadonly[table] {
+			lookup.ReadonlyIndexes = append(lookup.ReadonlyIndexes, loaded[acc.PublicKey].index)
+		}
+
+		if len(lookup.WritableIndexes)+len(lookup.ReadonlyIndexes) == 0 {
+			continue
+		}
+
+		message.AddressTableLookups = append(message.AddressTableLookups, lookup)
+	}
+
 	for txIdx, instruction := range instructions {
-		accounts = instruction.Accounts()
+		accounts := instruction.Accounts()
 		accountIndex := make([]uint16, len(accounts))
 		for idx, acc := range accounts {
 			accountIndex[idx] = accountKeyIndex[acc.PublicKey.String()]
@@ -404,9 +534,30 @@ func NewTransaction(instructions []Instruction, recentBlockHash Hash, opts ...Tr
 		})
 	}
 
-	return &Transaction{
-		Message: message,
-	}, nil
+	return &message, nil
+}
+
+// findInAddressTables to find the first active table containing the key,
+// and its index in the table
+func findInAddressTables(addressTables []AddressLookupTable, key PublicKey) (int, uint8, bool) {
+	for table, addressTable := range addressTables {
+		if !addressTable.IsActive() {
+			continue
+		}
+
+		for index, address := range addressTable.Addresses {
+			// only the first 256 addresses can be indexed
+			if index > math.MaxUint8 {
+				break
+			}
+
+			if address.Equals(key) {
+				return table, uint8(index), true
+			}
+		}
+	}
+
+	return 0, 0, false
 }
 
 type privateKeyGetter func(key PublicKey) *PrivateKey
@@ -428,11 +579,24 @@ func EncodeCompactU16Length(bytes *[]byte, ln int) {
 }
 
 func (mx *Message) MarshalBinary() ([]byte, error) {
-	buf := []byte{
+	var buf []byte
+
+	switch mx.Version {
+	case MessageVersionLegacy:
+
+	case MessageVersionV0:
+		buf = append(buf, messageVersionPrefix)
+
+	default:
+		return nil, fmt.Errorf("unknown message version %v", mx.Version)
+	}
+
+	buf = append(
+		buf,
 		mx.Header.NumRequiredSignatures,
 		mx.Header.NumReadonlySignedAccounts,
 		mx.Header.NumReadonlyUnsignedAccounts,
-	}
+	)
 
 	EncodeCompactU16Length(&buf, len(mx.AccountKeys))
 	for _, key := range mx.AccountKeys {
@@ -452,6 +616,26 @@ func (mx *Message) MarshalBinary() ([]byte, error) {
 		EncodeCompactU16Length(&buf, len(instruction.Data))
 		buf = append(buf, instruction.Data...)
 	}
+
+	if mx.Version == MessageVersionLegacy {
+		if len(mx.AddressTableLookups) != 0 {
+			return nil, errors.New("legacy messages can't use address table lookups")
+		}
+
+		return buf, nil
+	}
+
+	EncodeCompactU16Length(&buf, len(mx.AddressTableLookups))
+	for _, lookup := range mx.AddressTableLookups {
+		buf = append(buf, lookup.AccountKey[:]...)
+
+		EncodeCompactU16Length(&buf, len(lookup.WritableIndexes))
+		buf = append(buf, lookup.WritableIndexes...)
+
+		EncodeCompactU16Length(&buf, len(lookup.ReadonlyIndexes))
+		buf = append(buf, lookup.ReadonlyIndexes...)
+	}
+
 	return buf, nil
 }
 
@@ -506,6 +690,14 @@ func TransactionPayer(payer PublicKey) TransactionOption {
 	return transactionOptionFunc(func(opts *transactionOptions) { opts.payer = payer })
 }
 
+// TransactionAddressTables to load the accounts that aren't signers or
+// programs from the lookup tables given, making the message v0
+func TransactionAddressTables(tables ...AddressLookupTable) TransactionOption {
+	return transactionOptionFunc(func(opts *transactionOptions) {
+		opts.addressTables = append(opts.addressTables, tables...)
+	})
+}
+
 func (tx *Transaction) MarshalBinary() ([]byte, error) {
 	if len(tx.Signatures) == 0 || len(tx.Signatures) != int(tx.Message.Header.NumRequiredSignatures) {
 		return nil, errors.New("signature verification failed")
@@ -11,6 +11,8 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
 	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
 )
 
@@ -79,3 +81,30 @@ func BatchWinnings(winnings []worker.EthereumReward, expectedCategory string) (m
 
 	return firstBlock, lastBlock, rewards, nil
 }
+
+// Batch the rewards read from the store into the batch to pay out,
+// returning false if there weren't any
+func Batch(dbNetwork network.BlockchainNetwork, token token_details.TokenDetails, transactions []worker.EthereumReward) (worker.EthereumSpooledRewards, bool, error) {
+	if len(transactions) == 0 {
+		return worker.EthereumSpooledRewards{}, false, nil
+	}
+
+	firstBlock, lastBlock, spooledRewards, err := BatchWinnings(transactions, token.TokenShortName)
+
+	if err != nil {
+		return worker.EthereumSpooledRewards{}, false, fmt.Errorf(
+			"Failed to batch rewards! %w",
+			err,
+		)
+	}
+
+	rewards := worker.EthereumSpooledRewards{
+		Network:    dbNetwork,
+		Token:      token,
+		FirstBlock: &firstBlock,
+		LastBlock:  &lastBlock,
+		Rewards:    spooledRewards,
+	}
+
+	return rewards, true, nil
+}
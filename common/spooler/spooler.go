@@ -0,0 +1,294 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// spooler holds winnings until a single win is worth sending instantly,
+// or the total unpaid for a token passes the batched threshold, then
+// batches the pending winners for the token and pays them out with the
+// Backend for the chain. Pending winners are read from a Store, usually
+// spooler.Store (Timescale). With FLU_DRY_RUN set, the batch is left in
+// the store and the payout simulated instead.
+package spooler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+)
+
+// Context to use for logging
+const Context = "SPOOLER"
+
+type (
+	// Thresholds in USD to pay out winnings at, from the worker config
+	Thresholds struct {
+		// Instant to pay out a single win worth more than this immediately
+		Instant float64
+
+		// Batched to pay out every unpaid win for a token once their
+		// total is worth more than this
+		Batched float64
+	}
+
+	// Win to check against the thresholds, usually the sender's
+	// winnings in a transaction (which are always the largest)
+	Win struct {
+		Token     token_details.TokenDetails
+		UsdAmount float64
+	}
+
+	// Store of the pending winners to pay out
+	Store interface {
+		// UnpaidWinningsForCategory in USD
+		UnpaidWinningsForCategory(network network.BlockchainNetwork, token token_details.TokenDetails) float64
+
+		// GetAndRemoveRewardsForCategory to mark the pending winners
+		// as sent and return them
+		GetAndRemoveRewardsForCategory(network network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward
+
+		// GetUnsentRewardsForCategory without marking them as sent
+		GetUnsentRewardsForCategory(network network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward
+
+		// RestoreRewardsForCategory that were marked as sent but
+		// couldn't be paid out, so they're sent with the next batch
+		RestoreRewardsForCategory(network network.BlockchainNetwork, token token_details.TokenDetails, rewards []worker.EthereumReward)
+	}
+
+	// Backend to pay out batches of rewards on a chain with
+	Backend interface {
+		// Payout the batch of rewards, returning an error implementing
+		// PartialPayoutError if some of the batch was sent before it
+		// failed
+		Payout(rewards worker.EthereumSpooledRewards) error
+
+		// Simulate paying out the batch without sending anything,
+		// returning the result of each transaction that would be sent
+		Simulate(rewards worker.EthereumSpooledRewards) ([]dry_run.Result, error)
+	}
+
+	// PartialPayoutError from a Backend that paid out some of a batch
+	// before failing. The batch isn't restored to the store, since
+	// that would pay those winners again with the next batch
+	PartialPayoutError interface {
+		error
+
+		PartialPayout() bool
+	}
+
+	// Config of the spooler
+	Config struct {
+		// DryRun to simulate payouts, leaving the pending winners
+		// in the store
+		DryRun bool
+
+		// PublishDryRun to call with the result of each simulation,
+		// usually dry_run.Publish
+		PublishDryRun func(dry_run.Result)
+	}
+
+	// Spooler of the pending winners on a network
+	Spooler struct {
+		network network.BlockchainNetwork
+		store   Store
+		backend Backend
+		config  Config
+	}
+)
+
+// DefaultConfig to use for the spooler
+func DefaultConfig() Config {
+	return Config{
+		DryRun:        util.DryRunEnabled(),
+		PublishDryRun: nil,
+	}
+}
+
+// New spooler for the network given, paying out with the backend
+func New(network network.BlockchainNetwork, store Store, backend Backend, config Config) *Spooler {
+	return &Spooler{
+		network: network,
+		store:   store,
+		backend: backend,
+		config:  config,
+	}
+}
+
+// DryRun if the spooler simulates payouts instead of sending them
+func (spooler *Spooler) DryRun() bool {
+	return spooler.config.DryRun
+}
+
+// TokensToSend with the wins given, in the order they were first seen.
+// A token is sent if any of its wins are worth more than the instant
+// threshold, or the total unpaid for it is worth more than the batched
+// threshold. Wins should be in the store already.
+func (spooler *Spooler) TokensToSend(wins []Win, thresholds Thresholds) []token_details.TokenDetails {
+	var (
+		tokens = make([]token_details.TokenDetails, 0)
+		seen   = make(map[token_details.TokenDetails]bool)
+	)
+
+	for _, win := range wins {
+		token := win.Token
+
+		if seen[token] {
+			continue
+		}
+
+		log.Debug(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Reward value is $%f, instant send threshhold is $%f.",
+				win.UsdAmount,
+				thresholds.Instant,
+			)
+		})
+
+		if win.UsdAmount > thresholds.Instant {
+			log.Debug(func(k *log.Log) {
+				k.Context = Context
+				k.Message = "Transaction won more than instant send threshold, sending instantly!"
+			})
+
+			seen[token] = true
+			tokens = append(tokens, token)
+
+			continue
+		}
+
+		totalRewards := spooler.store.UnpaidWinningsForCategory(spooler.network, token)
+
+		log.Debug(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Total pending rewards are $%f, threshhold is $%f.",
+				totalRewards,
+				thresholds.Batched,
+			)
+		})
+
+		if totalRewards > thresholds.Batched {
+			log.Debug(func(k *log.Log) {
+				k.Context = Context
+				k.Message = "Total pending rewards are greater than threshold, sending!"
+			})
+
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// Spool the wins given, paying out every token that passed the
+// thresholds. Wins should be in the store already.
+func (spooler *Spooler) Spool(wins []Win, thresholds Thresholds) error {
+	for _, token := range spooler.TokensToSend(wins, thresholds) {
+		log.Debug(func(k *log.Log) {
+			k.Context = Context
+			k.Format("Sending rewards for token %v", token)
+		})
+
+		found, err := spooler.Release(token)
+
+		if err != nil {
+			return err
+		}
+
+		// in a dry run the wins might not have been written to the
+		// store, so there could be nothing to send
+
+		if !found && !spooler.config.DryRun {
+			return fmt.Errorf(
+				"trying to send rewards for token %s but no rewards found",
+				token.TokenShortName,
+			)
+		}
+	}
+
+	return nil
+}
+
+// Release every pending winner for the token, returning false if there
+// weren't any. The pending winners are marked as sent before they're
+// paid out, so another spooler can't pay them out too, and they're
+// restored if the payout fails without sending any of the batch
+func (spooler *Spooler) Release(token token_details.TokenDetails) (bool, error) {
+	var transactions []worker.EthereumReward
+
+	if spooler.config.DryRun {
+		transactions = spooler.store.GetUnsentRewardsForCategory(spooler.network, token)
+	} else {
+		transactions = spooler.store.GetAndRemoveRewardsForCategory(spooler.network, token)
+	}
+
+	rewards, found, err := Batch(spooler.network, token, transactions)
+
+	if err != nil {
+		return false, fmt.Errorf(
+			"failed to get rewards for token %s! %w",
+			token.TokenShortName,
+			err,
+		)
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	if spooler.config.DryRun {
+		results, err := spooler.backend.Simulate(rewards)
+
+		for _, result := range results {
+			spooler.publishDryRun(result)
+		}
+
+		if err != nil {
+			return true, fmt.Errorf(
+				"failed to simulate paying out rewards for token %s! %w",
+				token.TokenShortName,
+				err,
+			)
+		}
+
+		return true, nil
+	}
+
+	if err := spooler.backend.Payout(rewards); err != nil {
+		var partialErr PartialPayoutError
+
+		if !errors.As(err, &partialErr) || !partialErr.PartialPayout() {
+			spooler.store.RestoreRewardsForCategory(spooler.network, token, transactions)
+		}
+
+		return true, fmt.Errorf(
+			"failed to pay out rewards for token %s! %w",
+			token.TokenShortName,
+			err,
+		)
+	}
+
+	return true, nil
+}
+
+func (spooler *Spooler) publishDryRun(result dry_run.Result) {
+	if publish := spooler.config.PublishDryRun; publish != nil {
+		publish(result)
+		return
+	}
+
+	log.App(func(k *log.Log) {
+		k.Context = Context
+		k.Message = "Simulated a payout in dry run mode!"
+		k.Payload = result
+	})
+}
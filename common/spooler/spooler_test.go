@@ -0,0 +1,643 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package spooler
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/solana"
+	"github.com/fluidity-money/fluidity-app/common/solana/payout"
+	"github.com/fluidity-money/fluidity-app/common/solana/rpc"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
+	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	"github.com/near/borsh-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// memoryStore of pending winners by token short name
+	memoryStore struct {
+		unpaid   map[string]float64
+		rewards  map[string][]worker.EthereumReward
+		removed  int
+		restored int
+	}
+
+	// fakeBackend records the batches paid out and simulated
+	fakeBackend struct {
+		paid      []worker.EthereumSpooledRewards
+		simulated []worker.EthereumSpooledRewards
+		err       error
+	}
+
+	// solanaRpc that the Solana payout backend sends to, recording the
+	// amount paid to each winner and the transactions simulated
+	solanaRpc struct {
+		*httptest.Server
+
+		// paid winners in the order their payouts were sent
+		paid []solanaPayout
+
+		simulated int
+
+		// failAt the payout with this index, if it's not negative
+		failAt int
+	}
+
+	solanaPayout struct {
+		winner string
+		amount uint64
+	}
+)
+
+var (
+	testNetwork = network.NetworkSui
+
+	fusdc = token_details.New("USDC", 6)
+	fusdt = token_details.New("USDT", 6)
+
+	thresholds = Thresholds{
+		Instant: 10,
+		Batched: 100,
+	}
+)
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		unpaid:  make(map[string]float64),
+		rewards: make(map[string][]worker.EthereumReward),
+	}
+}
+
+func (store *memoryStore) add(token token_details.TokenDetails, winner string, amount int64, usd float64, blockNumber int64) {
+	var (
+		winAmount = misc.BigIntFromInt64(amount)
+		block     = misc.BigIntFromInt64(blockNumber)
+		address   ethereum.Address
+	)
+
+	// keep the address as it was given, like it's read from the store,
+	// since Solana addresses are case sensitive
+
+	_ = address.Scan(winner)
+
+	store.rewards[token.TokenShortName] = append(store.rewards[token.TokenShortName], worker.EthereumReward{
+		Category:     token.TokenShortName,
+		Winner:       address,
+		WinAmount:    &winAmount,
+		Utilityname:  applications.UtilityFluid,
+		BlockNumber:  &block,
+		TokenDetails: token,
+		Network:      testNetwork,
+	})
+
+	store.unpaid[token.TokenShortName] += usd
+}
+
+func (store *memoryStore) UnpaidWinningsForCategory(_ network.BlockchainNetwork, token token_details.TokenDetails) float64 {
+	return store.unpaid[token.TokenShortName]
+}
+
+func (store *memoryStore) GetAndRemoveRewardsForCategory(_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+	rewards := store.rewards[token.TokenShortName]
+
+	delete(store.rewards, token.TokenShortName)
+	delete(store.unpaid, token.TokenShortName)
+
+	store.removed += len(rewards)
+
+	return rewards
+}
+
+func (store *memoryStore) GetUnsentRewardsForCategory(_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+	return store.rewards[token.TokenShortName]
+}
+
+func (store *memoryStore) RestoreRewardsForCategory(_ network.BlockchainNetwork, token token_details.TokenDetails, rewards []worker.EthereumReward) {
+	store.rewards[token.TokenShortName] = append(store.rewards[token.TokenShortName], rewards...)
+
+	store.restored += len(rewards)
+}
+
+func (backend *fakeBackend) Payout(rewards worker.EthereumSpooledRewards) error {
+	backend.paid = append(backend.paid, rewards)
+
+	return backend.err
+}
+
+func (backend *fakeBackend) Simulate(rewards worker.EthereumSpooledRewards) ([]dry_run.Result, error) {
+	backend.simulated = append(backend.simulated, rewards)
+
+	result := dry_run.Result{
+		Network: rewards.Network,
+		Success: backend.err == nil,
+	}
+
+	return []dry_run.Result{result}, backend.err
+}
+
+func TestTokensToSendInstant(t *testing.T) {
+	store := newMemoryStore()
+
+	spooler := New(testNetwork, store, new(fakeBackend), Config{})
+
+	wins := []Win{
+		{Token: fusdc, UsdAmount: 5},
+		{Token: fusdt, UsdAmount: 11},
+	}
+
+	assert.Equal(
+		t,
+		[]token_details.TokenDetails{fusdt},
+		spooler.TokensToSend(wins, thresholds),
+	)
+}
+
+func TestTokensToSendBatched(t *testing.T) {
+	store := newMemoryStore()
+
+	store.add(fusdc, "0x1", 1, 99, 1)
+
+	spooler := New(testNetwork, store, new(fakeBackend), Config{})
+
+	wins := []Win{{Token: fusdc, UsdAmount: 1}}
+
+	assert.Empty(t, spooler.TokensToSend(wins, thresholds))
+
+	store.add(fusdc, "0x2", 1, 2, 2)
+
+	assert.Equal(
+		t,
+		[]token_details.TokenDetails{fusdc},
+		spooler.TokensToSend(wins, thresholds),
+	)
+}
+
+func TestTokensToSendOrderAndDuplicates(t *testing.T) {
+	store := newMemoryStore()
+
+	spooler := New(testNetwork, store, new(fakeBackend), Config{})
+
+	wins := []Win{
+		{Token: fusdt, UsdAmount: 20},
+		{Token: fusdc, UsdAmount: 20},
+		{Token: fusdt, UsdAmount: 30},
+	}
+
+	assert.Equal(
+		t,
+		[]token_details.TokenDetails{fusdt, fusdc},
+		spooler.TokensToSend(wins, thresholds),
+	)
+}
+
+func TestSpoolPaysOutBatch(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		backend = new(fakeBackend)
+	)
+
+	store.add(fusdc, "0x1", 10, 1, 5)
+	store.add(fusdc, "0x2", 20, 1, 3)
+	store.add(fusdc, "0x1", 30, 20, 9)
+
+	spooler := New(testNetwork, store, backend, Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	require.NoError(t, err)
+	require.Len(t, backend.paid, 1)
+
+	rewards := backend.paid[0]
+
+	assert.Equal(t, testNetwork, rewards.Network)
+	assert.Equal(t, fusdc, rewards.Token)
+	assert.Equal(t, "3", rewards.FirstBlock.String())
+	assert.Equal(t, "9", rewards.LastBlock.String())
+
+	fluidRewards := rewards.Rewards[applications.UtilityFluid]
+
+	var (
+		firstWinnings  = fluidRewards[ethereum.AddressFromString("0x1")]
+		secondWinnings = fluidRewards[ethereum.AddressFromString("0x2")]
+	)
+
+	assert.Len(t, fluidRewards, 2)
+	assert.Equal(t, "40", firstWinnings.String())
+	assert.Equal(t, "20", secondWinnings.String())
+
+	assert.Equal(t, 3, store.removed)
+	assert.Empty(t, backend.simulated)
+}
+
+func TestSpoolNothingToSend(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		backend = new(fakeBackend)
+	)
+
+	store.add(fusdc, "0x1", 10, 1, 1)
+
+	spooler := New(testNetwork, store, backend, Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 1}}, thresholds)
+
+	require.NoError(t, err)
+
+	assert.Empty(t, backend.paid)
+	assert.Zero(t, store.removed)
+}
+
+func TestSpoolNoRewardsFound(t *testing.T) {
+	spooler := New(testNetwork, newMemoryStore(), new(fakeBackend), Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	assert.Error(t, err)
+}
+
+func TestSpoolPayoutFailed(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		backend = &fakeBackend{err: errors.New("reverted")}
+	)
+
+	store.add(fusdc, "0x1", 10, 20, 1)
+
+	spooler := New(testNetwork, store, backend, Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	assert.ErrorIs(t, err, backend.err)
+
+	// the winners that couldn't be paid out are sent with the next batch
+
+	assert.Equal(t, 1, store.removed)
+	assert.Equal(t, 1, store.restored)
+	assert.Len(t, store.rewards[fusdc.TokenShortName], 1)
+}
+
+func TestSpoolDryRun(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		backend = new(fakeBackend)
+		results []dry_run.Result
+	)
+
+	store.add(fusdc, "0x1", 10, 20, 1)
+
+	spooler := New(testNetwork, store, backend, Config{
+		DryRun: true,
+		PublishDryRun: func(result dry_run.Result) {
+			results = append(results, result)
+		},
+	})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	require.NoError(t, err)
+
+	assert.Empty(t, backend.paid)
+	assert.Len(t, backend.simulated, 1)
+	assert.Len(t, results, 1)
+
+	// the pending winners are left for the live spooler
+
+	assert.Zero(t, store.removed)
+	assert.Len(t, store.rewards[fusdc.TokenShortName], 1)
+
+	// wins that weren't written to the store aren't an error
+
+	err = spooler.Spool([]Win{{Token: fusdt, UsdAmount: 20}}, thresholds)
+
+	assert.NoError(t, err)
+}
+
+func TestBatchWinningsCategoryMismatch(t *testing.T) {
+	store := newMemoryStore()
+
+	store.add(fusdc, "0x1", 10, 1, 1)
+
+	_, _, _, err := BatchWinnings(store.rewards[fusdc.TokenShortName], fusdt.TokenShortName)
+
+	assert.Error(t, err)
+}
+
+func newSolanaRpc(t *testing.T) *solanaRpc {
+	server := solanaRpc{failAt: -1}
+
+	var blockHash solana.PublicKey
+
+	blockHash[0] = 1
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Id     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		var (
+			result interface{}
+			rpcErr interface{}
+		)
+
+		switch request.Method {
+		case "getRecentBlockhash":
+			result = map[string]interface{}{
+				"context": map[string]int{"slot": 1},
+				"value":   map[string]string{"blockhash": blockHash.ToBase58()},
+			}
+
+		case "simulateTransaction":
+			server.simulated++
+
+			result = map[string]interface{}{
+				"context": map[string]int{"slot": 1},
+				"value":   map[string]interface{}{"err": nil, "unitsConsumed": 1},
+			}
+
+		case "sendTransaction":
+			if len(server.paid) == server.failAt {
+				rpcErr = map[string]interface{}{"code": -32002, "message": "failed"}
+				break
+			}
+
+			transaction := decodeSolanaTransaction(t, request.Params[0])
+
+			server.paid = append(server.paid, decodeSolanaPayout(t, transaction))
+
+			result = transaction.Signatures[0].String()
+
+		default:
+			t.Fatalf("unexpected rpc call %v", request.Method)
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      request.Id,
+			"jsonrpc": "2.0",
+			"result":  result,
+			"error":   rpcErr,
+		})
+	}))
+
+	t.Cleanup(server.Close)
+
+	return &server
+}
+
+func decodeSolanaTransaction(t *testing.T, param json.RawMessage) solana.Transaction {
+	var transactionBase64 string
+
+	require.NoError(t, json.Unmarshal(param, &transactionBase64))
+
+	transactionBinary, err := base64.StdEncoding.DecodeString(transactionBase64)
+
+	require.NoError(t, err)
+
+	var transaction solana.Transaction
+
+	require.NoError(t, transaction.UnmarshalBinary(transactionBinary))
+
+	return transaction
+}
+
+// decodeSolanaPayout to get the winner (account a) and the amount paid
+func decodeSolanaPayout(t *testing.T, transaction solana.Transaction) solanaPayout {
+	message := transaction.Message
+
+	require.Len(t, message.Instructions, 1)
+
+	instruction := message.Instructions[0]
+
+	var payoutInstruction payout.InstructionPayout
+
+	require.NoError(t, borsh.Deserialize(&payoutInstruction, instruction.Data))
+
+	winner := message.AccountKeys[instruction.Accounts[7]]
+
+	return solanaPayout{
+		winner: winner.ToBase58(),
+		amount: payoutInstruction.Amount,
+	}
+}
+
+func newSolanaBackend(t *testing.T, server *solanaRpc) payout.Backend {
+	client, err := rpc.New(server.URL)
+
+	require.NoError(t, err)
+
+	_, payerPrivateKey, err := ed25519.GenerateKey(nil)
+
+	require.NoError(t, err)
+
+	payerKey := solana.PrivateKey(payerPrivateKey)
+
+	testKey := func(b byte) solana.PublicKey {
+		var key solana.PublicKey
+
+		key[0] = b
+
+		return key
+	}
+
+	return payout.Backend{
+		Client: client,
+		Args: payout.PayoutArgs{
+			FluidityProgramPubkey: testKey(1),
+			DataAccountPubkey:     testKey(2),
+			MetaSplPubkey:         testKey(3),
+			TokenMintPubkey:       testKey(4),
+			FluidMintPubkey:       testKey(5),
+			PdaPubkey:             testKey(6),
+			ObligationPubkey:      testKey(7),
+			ReservePubkey:         testKey(8),
+			PayerPubkey:           payerKey.PublicKey(),
+			TokenName:             "USDC",
+		},
+		PayerPrivateKey: payerKey,
+	}
+}
+
+// solanaWinner to use as the address of a pending winner
+func solanaWinner(b byte) string {
+	var key solana.PublicKey
+
+	key[31] = b
+
+	return key.ToBase58()
+}
+
+func TestSolanaSpoolThresholds(t *testing.T) {
+	var (
+		store  = newMemoryStore()
+		server = newSolanaRpc(t)
+	)
+
+	spooler := New(network.NetworkSolana, store, newSolanaBackend(t, server), Config{})
+
+	store.add(fusdc, solanaWinner(1), 10, 99, 1)
+
+	// under both thresholds
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 1}}, thresholds)
+
+	require.NoError(t, err)
+	assert.Empty(t, server.paid)
+
+	// over the batched threshold
+
+	store.add(fusdc, solanaWinner(2), 20, 2, 2)
+
+	err = spooler.Spool([]Win{{Token: fusdc, UsdAmount: 2}}, thresholds)
+
+	require.NoError(t, err)
+	assert.Len(t, server.paid, 2)
+
+	// over the instant threshold
+
+	store.add(fusdt, solanaWinner(3), 30, 11, 3)
+
+	err = spooler.Spool([]Win{{Token: fusdt, UsdAmount: 11}}, thresholds)
+
+	require.NoError(t, err)
+	assert.Len(t, server.paid, 3)
+	assert.Zero(t, server.simulated)
+}
+
+func TestSolanaSpoolPaysOutBatch(t *testing.T) {
+	var (
+		store  = newMemoryStore()
+		server = newSolanaRpc(t)
+	)
+
+	store.add(fusdc, solanaWinner(2), 10, 1, 5)
+	store.add(fusdc, solanaWinner(1), 20, 1, 3)
+	store.add(fusdc, solanaWinner(2), 30, 20, 9)
+
+	spooler := New(network.NetworkSolana, store, newSolanaBackend(t, server), Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	require.NoError(t, err)
+
+	// a payout per winner with their winnings summed, sorted by address
+
+	expected := []solanaPayout{
+		{winner: solanaWinner(1), amount: 20},
+		{winner: solanaWinner(2), amount: 40},
+	}
+
+	sort.Slice(expected, func(i, j int) bool {
+		return expected[i].winner < expected[j].winner
+	})
+
+	assert.Equal(t, expected, server.paid)
+	assert.Equal(t, 3, store.removed)
+	assert.Zero(t, store.restored)
+}
+
+func TestSolanaSpoolPayoutFailed(t *testing.T) {
+	var (
+		store  = newMemoryStore()
+		server = newSolanaRpc(t)
+	)
+
+	server.failAt = 0
+
+	store.add(fusdc, solanaWinner(1), 10, 20, 1)
+	store.add(fusdc, solanaWinner(2), 10, 20, 1)
+
+	spooler := New(network.NetworkSolana, store, newSolanaBackend(t, server), Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	assert.Error(t, err)
+
+	// nothing was sent, so the winners are sent with the next batch
+
+	assert.Empty(t, server.paid)
+	assert.Equal(t, 2, store.restored)
+	assert.Len(t, store.rewards[fusdc.TokenShortName], 2)
+}
+
+func TestSolanaSpoolPartialPayout(t *testing.T) {
+	var (
+		store  = newMemoryStore()
+		server = newSolanaRpc(t)
+	)
+
+	server.failAt = 1
+
+	store.add(fusdc, solanaWinner(1), 10, 20, 1)
+	store.add(fusdc, solanaWinner(2), 10, 20, 1)
+
+	spooler := New(network.NetworkSolana, store, newSolanaBackend(t, server), Config{})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	var partialErr payout.PartialPayoutError
+
+	require.ErrorAs(t, err, &partialErr)
+
+	assert.Equal(t, 1, partialErr.Paid)
+	assert.Equal(t, 2, partialErr.Winners)
+
+	// the first winner was paid, so the batch isn't restored to be sent
+	// again
+
+	assert.Len(t, server.paid, 1)
+	assert.Zero(t, store.restored)
+	assert.Empty(t, store.rewards[fusdc.TokenShortName])
+}
+
+func TestSolanaSpoolDryRun(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		server  = newSolanaRpc(t)
+		results []dry_run.Result
+	)
+
+	store.add(fusdc, solanaWinner(1), 10, 20, 1)
+	store.add(fusdc, solanaWinner(2), 10, 20, 1)
+
+	spooler := New(network.NetworkSolana, store, newSolanaBackend(t, server), Config{
+		DryRun: true,
+		PublishDryRun: func(result dry_run.Result) {
+			results = append(results, result)
+		},
+	})
+
+	err := spooler.Spool([]Win{{Token: fusdc, UsdAmount: 20}}, thresholds)
+
+	require.NoError(t, err)
+
+	assert.Empty(t, server.paid)
+	assert.Equal(t, 2, server.simulated)
+	assert.Len(t, results, 2)
+
+	for _, result := range results {
+		assert.True(t, result.Success)
+		assert.Equal(t, network.NetworkSolana, result.Network)
+	}
+
+	assert.Zero(t, store.removed)
+}
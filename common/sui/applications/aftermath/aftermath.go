@@ -0,0 +1,145 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package aftermath
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+)
+
+const (
+	// PackageId of the Aftermath AMM package that emits swap events on mainnet
+	PackageId = "0xefe170ec0be4d762196bedecd7a065816576198a6527c99282a2551aaa7da38c"
+
+	// FeeDecimals that the fees in a pool are scaled by
+	FeeDecimals = 18
+)
+
+// IsSwapEvent if the event type is an Aftermath events::SwapEvent or
+// the SwapEventV2 that replaced it
+func IsSwapEvent(eventType swap.EventType) bool {
+	return eventType.Is(PackageId, "events", "SwapEvent") ||
+		eventType.Is(PackageId, "events", "SwapEventV2")
+}
+
+// GetAftermathFees to find the fee paid in a swap in an Aftermath pool,
+// which can swap several coins at once and charges a fee on every coin
+// in and every coin out. Only the fee charged on the fluid coin is
+// counted. Returns a nil Fee if the fluid coin wasn't swapped
+func GetAftermathFees(args swap.Args) (applications.ApplicationFeeData, error) {
+	var (
+		feeData    applications.ApplicationFeeData
+		parsedJson = args.Event.ParsedJson
+	)
+
+	poolId, err := swap.String(parsedJson, "pool_id")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	fluidAmount, fluidIn, found, err := fluidAmountSwapped(parsedJson, args.FluidCoinType)
+
+	if err != nil {
+		return feeData, err
+	}
+
+	if !found {
+		return feeData, nil
+	}
+
+	content, err := swap.GetObjectContent(args.Client, poolId)
+
+	if err != nil {
+		return feeData, fmt.Errorf(
+			"failed to get Aftermath pool %v! %v",
+			poolId,
+			err,
+		)
+	}
+
+	feesKey := "fees_swap_out"
+
+	if fluidIn {
+		feesKey = "fees_swap_in"
+	}
+
+	typeNames, err := swap.Strings(content.Fields, "type_names")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	fees, err := swap.Ints(content.Fields, feesKey)
+
+	if err != nil {
+		return feeData, err
+	}
+
+	if len(fees) != len(typeNames) {
+		return feeData, fmt.Errorf(
+			"aftermath pool %v had %v coins and %v fees",
+			poolId,
+			len(typeNames),
+			len(fees),
+		)
+	}
+
+	for i, typeName := range typeNames {
+		if !swap.SameCoin(typeName, args.FluidCoinType) {
+			continue
+		}
+
+		feeRate := new(big.Rat).SetFrac(
+			fees[i],
+			new(big.Int).Exp(big.NewInt(10), big.NewInt(FeeDecimals), nil),
+		)
+
+		return swap.Fee(fluidAmount, fluidIn, feeRate, args.TokenDecimals), nil
+	}
+
+	return feeData, fmt.Errorf(
+		"fluid coin was swapped in Aftermath pool %v without being in it",
+		poolId,
+	)
+}
+
+// fluidAmountSwapped to find the amount of the fluid coin swapped in or
+// out, and whether it was swapped in
+func fluidAmountSwapped(parsedJson map[string]interface{}, fluidCoinType string) (amount *big.Int, fluidIn bool, found bool, err error) {
+	for _, direction := range []string{"in", "out"} {
+		types, err := swap.Strings(parsedJson, "types_"+direction)
+
+		if err != nil {
+			return nil, false, false, err
+		}
+
+		amounts, err := swap.Ints(parsedJson, "amounts_"+direction)
+
+		if err != nil {
+			return nil, false, false, err
+		}
+
+		if len(types) != len(amounts) {
+			return nil, false, false, fmt.Errorf(
+				"swap had %v types and %v amounts %v",
+				len(types),
+				len(amounts),
+				direction,
+			)
+		}
+
+		for i, type_ := range types {
+			if swap.SameCoin(type_, fluidCoinType) {
+				return amounts[i], direction == "in", true, nil
+			}
+		}
+	}
+
+	return nil, false, false, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package aftermath
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/sui"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// type names in Move are without the 0x prefix
+	fluidTypeName = "000000000000000000000000000000000000000000000000000000000000ab12::fluid_coin::FLUID_COIN"
+	otherTypeName = "000000000000000000000000000000000000000000000000000000000000cd34::coin::COIN"
+
+	fluidCoinType = "0xab12::fluid_coin::FLUID_COIN"
+)
+
+// fakeSuiClient serving the fields of pools, embedding the interface so
+// only the methods used need to be implemented
+type fakeSuiClient struct {
+	sui.ISuiAPI
+
+	pools map[string]map[string]interface{}
+}
+
+func (client fakeSuiClient) SuiGetObject(_ context.Context, request models.SuiGetObjectRequest) (models.SuiObjectResponse, error) {
+	fields, found := client.pools[request.ObjectId]
+
+	if !found {
+		return models.SuiObjectResponse{}, errors.New("object not found")
+	}
+
+	return models.SuiObjectResponse{
+		Data: &models.SuiObjectData{
+			ObjectId: request.ObjectId,
+			Content: &models.SuiParsedData{
+				DataType: "moveObject",
+				Type:     PackageId + "::pool::Pool<0xef::af_lp::AF_LP>",
+				Fields:   fields,
+			},
+		},
+	}, nil
+}
+
+func swapEvent(pool string, typeIn, typeOut string) models.SuiEventResponse {
+	return models.SuiEventResponse{
+		Type: PackageId + "::events::SwapEventV2",
+		ParsedJson: map[string]interface{}{
+			"pool_id":     pool,
+			"types_in":    []interface{}{typeIn},
+			"amounts_in":  []interface{}{"1000000000"},
+			"types_out":   []interface{}{typeOut},
+			"amounts_out": []interface{}{"990000000"},
+		},
+	}
+}
+
+func TestIsSwapEvent(t *testing.T) {
+	for _, name := range []string{"SwapEvent", "SwapEventV2"} {
+		eventType, err := swap.ParseEventType(PackageId + "::events::" + name)
+
+		require.NoError(t, err)
+
+		assert.True(t, IsSwapEvent(eventType))
+	}
+
+	eventType, err := swap.ParseEventType(PackageId + "::events::DepositEvent")
+
+	require.NoError(t, err)
+
+	assert.False(t, IsSwapEvent(eventType))
+}
+
+func TestGetAftermathFees(t *testing.T) {
+	var (
+		fluidPool     = swap.NormaliseAddress("0xf1")
+		malformedPool = swap.NormaliseAddress("0xf2")
+	)
+
+	client := fakeSuiClient{
+		pools: map[string]map[string]interface{}{
+			// 0.2% on the fluid coin in, 1% on it out
+			fluidPool: {
+				"type_names":    []interface{}{otherTypeName, fluidTypeName},
+				"fees_swap_in":  []interface{}{"3000000000000000", "2000000000000000"},
+				"fees_swap_out": []interface{}{"0", "10000000000000000"},
+			},
+			malformedPool: {
+				"type_names":   []interface{}{otherTypeName, fluidTypeName},
+				"fees_swap_in": []interface{}{"3000000000000000"},
+			},
+		},
+	}
+
+	args := swap.Args{
+		Client:        client,
+		Event:         swapEvent(fluidPool, fluidTypeName, otherTypeName),
+		FluidCoinType: fluidCoinType,
+		TokenDecimals: 6,
+	}
+
+	feeData, err := GetAftermathFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(2, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(1000, 1), feeData.Volume)
+
+	// 990 fluid out means 1000 before the 1% fee
+
+	args.Event = swapEvent(fluidPool, otherTypeName, fluidTypeName)
+
+	feeData, err = GetAftermathFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(10, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(990, 1), feeData.Volume)
+
+	// the fluid coin wasn't swapped, so the pool isn't looked up
+
+	args.Event = swapEvent(swap.NormaliseAddress("0xf3"), otherTypeName, "0x2::sui::SUI")
+
+	feeData, err = GetAftermathFees(args)
+
+	require.NoError(t, err)
+
+	assert.Nil(t, feeData.Fee)
+
+	args.Event = swapEvent(malformedPool, fluidTypeName, otherTypeName)
+
+	_, err = GetAftermathFees(args)
+
+	assert.Error(t, err)
+}
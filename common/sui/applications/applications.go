@@ -21,11 +21,19 @@ type Application int64
 const (
 	// ApplicationNone is the default application, representing a transfer
 	ApplicationNone Application = iota
+	ApplicationCetus
+	ApplicationTurbos
+	ApplicationDeepBook
+	ApplicationAftermath
 )
 
 // applicationNames is used to map human readable names to their enum varients
 var applicationNames = []string{
 	"none",
+	"cetus",
+	"turbos",
+	"deepbook",
+	"aftermath",
 }
 
 func (app Application) String() string {
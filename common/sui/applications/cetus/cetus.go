@@ -0,0 +1,88 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package cetus
+
+import (
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+)
+
+// PackageId of the Cetus CLMM package that emits swap events on mainnet
+const PackageId = "0x1eabed72c53feb3805120a081dc15963c204dc8d091542592abaf7a35689b2fb"
+
+// IsSwapEvent if the event type is a Cetus pool::SwapEvent
+func IsSwapEvent(eventType swap.EventType) bool {
+	return eventType.Is(PackageId, "pool", "SwapEvent")
+}
+
+// GetCetusFees to find the fee paid in a swap in a Cetus pool, with the
+// fee taken from the input coin. Returns a nil Fee if the fluid coin
+// isn't in the pool
+func GetCetusFees(args swap.Args) (applications.ApplicationFeeData, error) {
+	var (
+		feeData    applications.ApplicationFeeData
+		parsedJson = args.Event.ParsedJson
+	)
+
+	poolId, err := swap.String(parsedJson, "pool")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	aToB, err := swap.Bool(parsedJson, "atob")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	amountIn, err := swap.Int(parsedJson, "amount_in")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	amountOut, err := swap.Int(parsedJson, "amount_out")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	feeAmount, err := swap.Int(parsedJson, "fee_amount")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	coinTypes, err := swap.PoolCoinTypes(args.Client, poolId)
+
+	if err != nil {
+		return feeData, fmt.Errorf(
+			"failed to get the coins in Cetus pool %v! %v",
+			poolId,
+			err,
+		)
+	}
+
+	if len(coinTypes) != 2 {
+		return feeData, fmt.Errorf(
+			"cetus pool %v had %v coin types, expected 2",
+			poolId,
+			len(coinTypes),
+		)
+	}
+
+	return swap.InputFee(
+		args,
+		coinTypes[0],
+		coinTypes[1],
+		aToB,
+		amountIn,
+		amountOut,
+		feeAmount,
+	)
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package cetus
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/sui"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fluidCoinType = "0xab12::fluid_coin::FLUID_COIN"
+	otherCoinType = "0xcd34::coin::COIN"
+)
+
+// fakeSuiClient serving the types of pools, embedding the interface so
+// only the methods used need to be implemented
+type fakeSuiClient struct {
+	sui.ISuiAPI
+
+	pools map[string]string
+}
+
+func (client fakeSuiClient) SuiGetObject(_ context.Context, request models.SuiGetObjectRequest) (models.SuiObjectResponse, error) {
+	type_, found := client.pools[request.ObjectId]
+
+	if !found {
+		return models.SuiObjectResponse{}, errors.New("object not found")
+	}
+
+	return models.SuiObjectResponse{
+		Data: &models.SuiObjectData{
+			ObjectId: request.ObjectId,
+			Content:  &models.SuiParsedData{DataType: "moveObject", Type: type_},
+		},
+	}, nil
+}
+
+func swapEvent(pool string, aToB bool) models.SuiEventResponse {
+	return models.SuiEventResponse{
+		Type: PackageId + "::pool::SwapEvent",
+		ParsedJson: map[string]interface{}{
+			"atob":       aToB,
+			"pool":       pool,
+			"amount_in":  "1000000000",
+			"amount_out": "2000000000",
+			"fee_amount": "2500000",
+		},
+	}
+}
+
+func TestIsSwapEvent(t *testing.T) {
+	eventType, err := swap.ParseEventType(PackageId + "::pool::SwapEvent")
+
+	require.NoError(t, err)
+
+	assert.True(t, IsSwapEvent(eventType))
+
+	eventType, err = swap.ParseEventType(PackageId + "::pool::AddLiquidityEvent")
+
+	require.NoError(t, err)
+
+	assert.False(t, IsSwapEvent(eventType))
+}
+
+func TestGetCetusFees(t *testing.T) {
+	var (
+		fluidPool = swap.NormaliseAddress("0xc1")
+		otherPool = swap.NormaliseAddress("0xc2")
+	)
+
+	client := fakeSuiClient{
+		pools: map[string]string{
+			fluidPool: PackageId + "::pool::Pool<" + fluidCoinType + ", " + otherCoinType + ">",
+			otherPool: PackageId + "::pool::Pool<0x2::sui::SUI, " + otherCoinType + ">",
+		},
+	}
+
+	args := swap.Args{
+		Client:        client,
+		Event:         swapEvent(fluidPool, true),
+		FluidCoinType: fluidCoinType,
+		TokenDecimals: 6,
+	}
+
+	// 1000 fluid in paying a 2.5 fluid fee
+
+	feeData, err := GetCetusFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(5, 2), feeData.Fee)
+	assert.Equal(t, big.NewRat(1000, 1), feeData.Volume)
+
+	// 2000 fluid out at a 0.25% fee, so 2000 * 0.0025 / 0.9975
+
+	args.Event = swapEvent(fluidPool, false)
+
+	feeData, err = GetCetusFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(2000*25, 9975), feeData.Fee)
+	assert.Equal(t, big.NewRat(2000, 1), feeData.Volume)
+
+	args.Event = swapEvent(otherPool, true)
+
+	feeData, err = GetCetusFees(args)
+
+	require.NoError(t, err)
+
+	assert.Nil(t, feeData.Fee)
+
+	args.Event = swapEvent(swap.NormaliseAddress("0xc3"), true)
+
+	_, err = GetCetusFees(args)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package deepbook
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+)
+
+const (
+	// PackageId of the DeepBook (v2) package
+	PackageId = "0xdee9"
+
+	// FloatScaling that prices are multiplied by
+	FloatScaling = 1_000_000_000
+)
+
+// IsSwapEvent if the event type is a DeepBook clob_v2::OrderFilled,
+// emitted for every maker order filled by a taker
+func IsSwapEvent(eventType swap.EventType) bool {
+	return eventType.Is(PackageId, "clob_v2", "OrderFilled")
+}
+
+// GetDeepBookFees to find the commission paid by the taker of an order,
+// typed OrderFilled<Base, Quote>, with the commission paid in the quote
+// coin. Returns a nil Fee if neither coin is the fluid coin
+func GetDeepBookFees(args swap.Args) (applications.ApplicationFeeData, error) {
+	var (
+		feeData    applications.ApplicationFeeData
+		parsedJson = args.Event.ParsedJson
+	)
+
+	eventType, err := swap.ParseEventType(args.Event.Type)
+
+	if err != nil {
+		return feeData, err
+	}
+
+	if len(eventType.TypeArguments) != 2 {
+		return feeData, fmt.Errorf(
+			"deepbook event %v had %v type arguments, expected 2",
+			args.Event.Type,
+			len(eventType.TypeArguments),
+		)
+	}
+
+	var (
+		fluidIsBase  = swap.SameCoin(eventType.TypeArguments[0], args.FluidCoinType)
+		fluidIsQuote = swap.SameCoin(eventType.TypeArguments[1], args.FluidCoinType)
+	)
+
+	if !fluidIsBase && !fluidIsQuote {
+		return feeData, nil
+	}
+
+	baseFilled, err := swap.Int(parsedJson, "base_asset_quantity_filled")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	price, err := swap.Int(parsedJson, "price")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	takerCommission, err := swap.Int(parsedJson, "taker_commission")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	quoteFilled := new(big.Rat).SetFrac(
+		new(big.Int).Mul(baseFilled, price),
+		big.NewInt(FloatScaling),
+	)
+
+	commission := new(big.Rat).SetInt(takerCommission)
+
+	if fluidIsQuote {
+		return swap.FeeData(commission, quoteFilled, args.TokenDecimals), nil
+	}
+
+	// the fluid coin is the base, so convert the commission to it at the
+	// rate it was charged
+
+	if quoteFilled.Sign() == 0 {
+		return feeData, fmt.Errorf(
+			"deepbook order filled for nothing at price %v",
+			price,
+		)
+	}
+
+	volume := new(big.Rat).SetInt(baseFilled)
+
+	fee := new(big.Rat).Mul(volume, commission)
+
+	fee.Quo(fee, quoteFilled)
+
+	return swap.FeeData(fee, volume, args.TokenDecimals), nil
+}
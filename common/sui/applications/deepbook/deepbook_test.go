@@ -0,0 +1,95 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package deepbook
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fluidCoinType = "0xab12::fluid_coin::FLUID_COIN"
+	otherCoinType = "0xcd34::coin::COIN"
+)
+
+func orderFilled(base, quote string) models.SuiEventResponse {
+	// 10 base filled at 2.5 quote each, with a 0.1 quote commission
+
+	return models.SuiEventResponse{
+		Type: "0xdee9::clob_v2::OrderFilled<" + base + ", " + quote + ">",
+		ParsedJson: map[string]interface{}{
+			"pool_id":                    swap.NormaliseAddress("0xe1"),
+			"is_bid":                     false,
+			"base_asset_quantity_filled": "10000000",
+			"price":                      "2500000000",
+			"taker_commission":           "100000",
+		},
+	}
+}
+
+func TestIsSwapEvent(t *testing.T) {
+	eventType, err := swap.ParseEventType(orderFilled(fluidCoinType, otherCoinType).Type)
+
+	require.NoError(t, err)
+
+	assert.True(t, IsSwapEvent(eventType))
+
+	eventType, err = swap.ParseEventType("0xdee9::clob_v2::OrderPlaced<0x2::sui::SUI, " + otherCoinType + ">")
+
+	require.NoError(t, err)
+
+	assert.False(t, IsSwapEvent(eventType))
+}
+
+func TestGetDeepBookFees(t *testing.T) {
+	args := swap.Args{
+		Event:         orderFilled(otherCoinType, fluidCoinType),
+		FluidCoinType: fluidCoinType,
+		TokenDecimals: 6,
+	}
+
+	// fluid is the quote, so the commission was paid in it
+
+	feeData, err := GetDeepBookFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(1, 10), feeData.Fee)
+	assert.Equal(t, big.NewRat(25, 1), feeData.Volume)
+
+	// fluid is the base, so the commission is 0.4% of it
+
+	args.Event = orderFilled(fluidCoinType, otherCoinType)
+
+	feeData, err = GetDeepBookFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(1, 25), feeData.Fee)
+	assert.Equal(t, big.NewRat(10, 1), feeData.Volume)
+
+	args.Event = orderFilled("0x2::sui::SUI", otherCoinType)
+
+	feeData, err = GetDeepBookFees(args)
+
+	require.NoError(t, err)
+
+	assert.Nil(t, feeData.Fee)
+
+	args.Event = orderFilled(fluidCoinType, otherCoinType)
+
+	args.Event.ParsedJson["price"] = 2.5
+
+	_, err = GetDeepBookFees(args)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,443 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package swap
+
+// swap contains the code shared by the decoders for Sui DEXes, to parse
+// the types of the events they emit, find the coins in their pools and
+// compute the fee paid in USD from the amount of the fluid coin swapped.
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/sui"
+)
+
+type (
+	// Args passed to a decoder to compute the fee paid in a swap
+	Args struct {
+		Client sui.ISuiAPI
+
+		// Event emitted by the DEX
+		Event models.SuiEventResponse
+
+		// BalanceChanges of the transaction containing the event
+		BalanceChanges []models.BalanceChanges
+
+		// FluidCoinType of the fluid coin, eg 0x...::fluid_coin::FLUID_COIN
+		FluidCoinType string
+
+		// TokenDecimals of the fluid coin
+		TokenDecimals int
+	}
+
+	// EventType parsed from a type like 0x2::module::Name<A, B>
+	EventType struct {
+		Package       string
+		Module        string
+		Name          string
+		TypeArguments []string
+	}
+)
+
+// poolCoinTypes cached by the ID of the pool, since a pool's coins
+// never change
+var (
+	poolCoinTypes   = make(map[string][]string)
+	poolCoinTypesMu sync.Mutex
+)
+
+// NormaliseAddress to be lowercase and padded to 32 bytes with a 0x
+// prefix, so 0x2 and 0x0...02 are the same
+func NormaliseAddress(address string) string {
+	address = strings.ToLower(strings.TrimPrefix(address, "0x"))
+
+	if len(address) < 64 {
+		address = strings.Repeat("0", 64-len(address)) + address
+	}
+
+	return "0x" + address
+}
+
+// NormaliseType to normalise every address in the type given
+func NormaliseType(type_ string) string {
+	eventType, err := ParseEventType(type_)
+
+	if err != nil {
+		return strings.TrimSpace(type_)
+	}
+
+	return eventType.String()
+}
+
+// ParseEventType given in an event or an object, like
+// 0xdee9::clob_v2::OrderFilled<0x2::sui::SUI, 0x...::coin::COIN>
+func ParseEventType(type_ string) (EventType, error) {
+	var eventType EventType
+
+	type_ = strings.TrimSpace(type_)
+
+	base := type_
+
+	if i := strings.Index(type_, "<"); i != -1 {
+		if !strings.HasSuffix(type_, ">") {
+			return eventType, fmt.Errorf(
+				"type %#v has unbalanced type arguments",
+				type_,
+			)
+		}
+
+		base = type_[:i]
+
+		typeArguments, err := splitTypeArguments(type_[i+1 : len(type_)-1])
+
+		if err != nil {
+			return eventType, fmt.Errorf(
+				"failed to split the type arguments of %#v! %v",
+				type_,
+				err,
+			)
+		}
+
+		for _, typeArgument := range typeArguments {
+			eventType.TypeArguments = append(eventType.TypeArguments, NormaliseType(typeArgument))
+		}
+	}
+
+	parts := strings.Split(base, "::")
+
+	if len(parts) != 3 {
+		return eventType, fmt.Errorf(
+			"type %#v isn't package::module::name",
+			type_,
+		)
+	}
+
+	eventType.Package = NormaliseAddress(parts[0])
+	eventType.Module = parts[1]
+	eventType.Name = parts[2]
+
+	return eventType, nil
+}
+
+// Is the type the one given, with any type arguments
+func (eventType EventType) Is(packageId, module, name string) bool {
+	return eventType.Package == NormaliseAddress(packageId) &&
+		eventType.Module == module &&
+		eventType.Name == name
+}
+
+// String the type back with its addresses normalised
+func (eventType EventType) String() string {
+	type_ := eventType.Package + "::" + eventType.Module + "::" + eventType.Name
+
+	if len(eventType.TypeArguments) == 0 {
+		return type_
+	}
+
+	return type_ + "<" + strings.Join(eventType.TypeArguments, ", ") + ">"
+}
+
+// splitTypeArguments on the commas between them, ignoring the commas in
+// their own type arguments
+func splitTypeArguments(typeArguments string) ([]string, error) {
+	var (
+		split = make([]string, 0)
+		depth = 0
+		start = 0
+	)
+
+	for i, c := range typeArguments {
+		switch c {
+		case '<':
+			depth++
+
+		case '>':
+			depth--
+
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced type arguments")
+			}
+
+		case ',':
+			if depth == 0 {
+				split = append(split, typeArguments[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced type arguments")
+	}
+
+	return append(split, typeArguments[start:]), nil
+}
+
+// InvolvesCoin if any of the balance changes are in the coin given
+func InvolvesCoin(balanceChanges []models.BalanceChanges, coinType string) bool {
+	coinType = NormaliseType(coinType)
+
+	for _, balanceChange := range balanceChanges {
+		if NormaliseType(balanceChange.CoinType) == coinType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PoolCoinTypes to get the type arguments of the pool with the ID given,
+// the coins in it for most DEXes
+func PoolCoinTypes(client sui.ISuiAPI, poolId string) ([]string, error) {
+	poolId = NormaliseAddress(poolId)
+
+	poolCoinTypesMu.Lock()
+
+	coinTypes, found := poolCoinTypes[poolId]
+
+	poolCoinTypesMu.Unlock()
+
+	if found {
+		return coinTypes, nil
+	}
+
+	content, err := GetObjectContent(client, poolId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	poolType, err := ParseEventType(content.Type)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to parse the type of pool %v! %v",
+			poolId,
+			err,
+		)
+	}
+
+	coinTypes = poolType.TypeArguments
+
+	poolCoinTypesMu.Lock()
+
+	poolCoinTypes[poolId] = coinTypes
+
+	poolCoinTypesMu.Unlock()
+
+	return coinTypes, nil
+}
+
+// GetObjectContent to get the type and the fields of an object
+func GetObjectContent(client sui.ISuiAPI, objectId string) (*models.SuiParsedData, error) {
+	response, err := client.SuiGetObject(context.Background(), models.SuiGetObjectRequest{
+		ObjectId: objectId,
+		Options: models.SuiObjectDataOptions{
+			ShowType:    true,
+			ShowContent: true,
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to get object %v! %v",
+			objectId,
+			err,
+		)
+	}
+
+	if response.Data == nil || response.Data.Content == nil {
+		return nil, fmt.Errorf(
+			"object %v has no content",
+			objectId,
+		)
+	}
+
+	return response.Data.Content, nil
+}
+
+// Fee paid in a swap in USD, and the volume of the fluid coin swapped,
+// from the amount of the fluid coin swapped and the fee rate charged on
+// the input. If the fluid coin was swapped in, the fee is a fraction of
+// it, otherwise the fee is taken from the input before the fluid coin
+// is paid out, so it's amount * rate / (1 - rate) in the fluid coin
+func Fee(fluidAmount *big.Int, fluidIn bool, feeRate *big.Rat, tokenDecimals int) applications.ApplicationFeeData {
+	var (
+		volume = new(big.Rat).SetInt(fluidAmount)
+		fee    = new(big.Rat).Mul(volume, feeRate)
+	)
+
+	one := big.NewRat(1, 1)
+
+	if !fluidIn && feeRate.Cmp(one) < 0 {
+		fee.Quo(fee, new(big.Rat).Sub(one, feeRate))
+	}
+
+	return FeeData(fee, volume, tokenDecimals)
+}
+
+// FeeData in USD from the fee and volume in the fluid coin's units
+func FeeData(fee, volume *big.Rat, tokenDecimals int) applications.ApplicationFeeData {
+	decimalsRat := new(big.Rat).SetInt(
+		new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(tokenDecimals)), nil),
+	)
+
+	return applications.ApplicationFeeData{
+		Fee:    new(big.Rat).Quo(fee, decimalsRat),
+		Volume: new(big.Rat).Quo(volume, decimalsRat),
+	}
+}
+
+// String field of the event's json
+func String(parsedJson map[string]interface{}, key string) (string, error) {
+	value, ok := parsedJson[key].(string)
+
+	if !ok {
+		return "", fmt.Errorf(
+			"event field %#v isn't a string, was %v",
+			key,
+			parsedJson[key],
+		)
+	}
+
+	return value, nil
+}
+
+// Bool field of the event's json
+func Bool(parsedJson map[string]interface{}, key string) (bool, error) {
+	value, ok := parsedJson[key].(bool)
+
+	if !ok {
+		return false, fmt.Errorf(
+			"event field %#v isn't a bool, was %v",
+			key,
+			parsedJson[key],
+		)
+	}
+
+	return value, nil
+}
+
+// Int field of the event's json, u64 and larger are strings in json
+func Int(parsedJson map[string]interface{}, key string) (*big.Int, error) {
+	value, err := String(parsedJson, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parseInt(key, value)
+}
+
+// Strings field of the event's json
+func Strings(parsedJson map[string]interface{}, key string) ([]string, error) {
+	values, ok := parsedJson[key].([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf(
+			"event field %#v isn't a list, was %v",
+			key,
+			parsedJson[key],
+		)
+	}
+
+	strings_ := make([]string, len(values))
+
+	for i, value := range values {
+		string_, ok := value.(string)
+
+		if !ok {
+			return nil, fmt.Errorf(
+				"event field %#v has a value that isn't a string, was %v",
+				key,
+				value,
+			)
+		}
+
+		strings_[i] = string_
+	}
+
+	return strings_, nil
+}
+
+// Ints field of the event's json
+func Ints(parsedJson map[string]interface{}, key string) ([]*big.Int, error) {
+	values, err := Strings(parsedJson, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ints := make([]*big.Int, len(values))
+
+	for i, value := range values {
+		if ints[i], err = parseInt(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	return ints, nil
+}
+
+func parseInt(key, value string) (*big.Int, error) {
+	int_, ok := new(big.Int).SetString(value, 10)
+
+	if !ok {
+		return nil, fmt.Errorf(
+			"event field %#v isn't a number, was %#v",
+			key,
+			value,
+		)
+	}
+
+	return int_, nil
+}
+
+// SameCoin if the coin types are the same once normalised
+func SameCoin(a, b string) bool {
+	return NormaliseType(a) == NormaliseType(b)
+}
+
+// InputFee for a swap in a pool of two coins that takes its fee from the
+// input, as the concentrated liquidity DEXes do. amountIn includes the
+// fee. Returns a nil Fee if the fluid coin isn't in the pool
+func InputFee(args Args, coinA, coinB string, aToB bool, amountIn, amountOut, feeAmount *big.Int) (applications.ApplicationFeeData, error) {
+	var (
+		fluidIsA = SameCoin(coinA, args.FluidCoinType)
+		fluidIsB = SameCoin(coinB, args.FluidCoinType)
+	)
+
+	if !fluidIsA && !fluidIsB {
+		return applications.ApplicationFeeData{}, nil
+	}
+
+	if amountIn.Sign() <= 0 {
+		return applications.ApplicationFeeData{}, fmt.Errorf(
+			"swap had an amount in of %v",
+			amountIn,
+		)
+	}
+
+	// the input is the fluid coin, so the fee was paid in it
+
+	if fluidIn := fluidIsA == aToB; fluidIn {
+		return FeeData(
+			new(big.Rat).SetInt(feeAmount),
+			new(big.Rat).SetInt(amountIn),
+			args.TokenDecimals,
+		), nil
+	}
+
+	feeRate := new(big.Rat).SetFrac(feeAmount, amountIn)
+
+	return Fee(amountOut, false, feeRate, args.TokenDecimals), nil
+}
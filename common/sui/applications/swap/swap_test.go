@@ -0,0 +1,187 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package swap
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sui0x2 = "0x0000000000000000000000000000000000000000000000000000000000000002"
+
+	fluidCoinType = "0xab12::fluid_coin::FLUID_COIN"
+	otherCoinType = "0xcd34::coin::COIN"
+)
+
+func TestNormaliseAddress(t *testing.T) {
+	assert.Equal(t, sui0x2, NormaliseAddress("0x2"))
+	assert.Equal(t, sui0x2, NormaliseAddress("2"))
+	assert.Equal(t, sui0x2, NormaliseAddress(sui0x2))
+
+	assert.Equal(t, NormaliseAddress("0xABCD"), NormaliseAddress("0xabcd"))
+}
+
+func TestParseEventType(t *testing.T) {
+	eventType, err := ParseEventType(
+		"0xdee9::clob_v2::OrderFilled<0x2::sui::SUI, 0xab::lp::LP<0x2::sui::SUI, 0xcd::coin::COIN>>",
+	)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, NormaliseAddress("0xdee9"), eventType.Package)
+	assert.Equal(t, "clob_v2", eventType.Module)
+	assert.Equal(t, "OrderFilled", eventType.Name)
+
+	assert.True(t, eventType.Is("0xdee9", "clob_v2", "OrderFilled"))
+	assert.False(t, eventType.Is("0xdee9", "clob", "OrderFilled"))
+
+	require.Len(t, eventType.TypeArguments, 2)
+
+	assert.Equal(t, sui0x2+"::sui::SUI", eventType.TypeArguments[0])
+
+	assert.Equal(
+		t,
+		NormaliseAddress("0xab")+"::lp::LP<"+sui0x2+"::sui::SUI, "+NormaliseAddress("0xcd")+"::coin::COIN>",
+		eventType.TypeArguments[1],
+	)
+
+	_, err = ParseEventType("0x2::sui")
+
+	assert.Error(t, err)
+
+	_, err = ParseEventType("0x2::pool::Pool<0x2::sui::SUI")
+
+	assert.Error(t, err)
+}
+
+func TestSameCoin(t *testing.T) {
+	assert.True(t, SameCoin("0x2::sui::SUI", sui0x2+"::sui::SUI"))
+
+	// type names in Move don't have a prefix
+
+	assert.True(t, SameCoin("0x2::sui::SUI", sui0x2[2:]+"::sui::SUI"))
+
+	assert.False(t, SameCoin(fluidCoinType, otherCoinType))
+}
+
+func TestInvolvesCoin(t *testing.T) {
+	balanceChanges := []models.BalanceChanges{
+		{CoinType: "0x2::sui::SUI", Amount: "-100"},
+		{CoinType: NormaliseType(fluidCoinType), Amount: "100"},
+	}
+
+	assert.True(t, InvolvesCoin(balanceChanges, fluidCoinType))
+	assert.False(t, InvolvesCoin(balanceChanges, otherCoinType))
+	assert.False(t, InvolvesCoin(nil, fluidCoinType))
+}
+
+func TestFee(t *testing.T) {
+	// 0.3% of 1000 fluid swapped in, with 6 decimals
+
+	feeData := Fee(big.NewInt(1000_000_000), true, big.NewRat(3, 1000), 6)
+
+	assert.Equal(t, big.NewRat(3, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(1000, 1), feeData.Volume)
+
+	// 997 fluid out means 1000 in before the 0.3% fee
+
+	feeData = Fee(big.NewInt(997_000_000), false, big.NewRat(3, 1000), 6)
+
+	assert.Equal(t, big.NewRat(3, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(997, 1), feeData.Volume)
+}
+
+func TestInputFee(t *testing.T) {
+	args := Args{FluidCoinType: fluidCoinType, TokenDecimals: 6}
+
+	var (
+		amountIn  = big.NewInt(1000_000_000)
+		amountOut = big.NewInt(997_000_000)
+		feeAmount = big.NewInt(3_000_000)
+	)
+
+	// fluid in as coin a, so the fee was paid in it
+
+	feeData, err := InputFee(args, fluidCoinType, otherCoinType, true, amountIn, amountOut, feeAmount)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(3, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(1000, 1), feeData.Volume)
+
+	// fluid out as coin b
+
+	feeData, err = InputFee(args, otherCoinType, fluidCoinType, true, amountIn, amountOut, feeAmount)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(3, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(997, 1), feeData.Volume)
+
+	// fluid in as coin b
+
+	feeData, err = InputFee(args, otherCoinType, fluidCoinType, false, amountIn, amountOut, feeAmount)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(1000, 1), feeData.Volume)
+
+	// the fluid coin isn't in the pool
+
+	feeData, err = InputFee(args, otherCoinType, "0x2::sui::SUI", true, amountIn, amountOut, feeAmount)
+
+	require.NoError(t, err)
+
+	assert.Nil(t, feeData.Fee)
+
+	_, err = InputFee(args, fluidCoinType, otherCoinType, true, big.NewInt(0), amountOut, feeAmount)
+
+	assert.Error(t, err)
+}
+
+func TestFields(t *testing.T) {
+	parsedJson := map[string]interface{}{
+		"amount":  "18446744073709551615",
+		"atob":    true,
+		"types":   []interface{}{"0x2::sui::SUI"},
+		"amounts": []interface{}{"1", "x"},
+	}
+
+	amount, err := Int(parsedJson, "amount")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, "18446744073709551615", amount.String())
+
+	aToB, err := Bool(parsedJson, "atob")
+
+	require.NoError(t, err)
+
+	assert.True(t, aToB)
+
+	types, err := Strings(parsedJson, "types")
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"0x2::sui::SUI"}, types)
+
+	_, err = Ints(parsedJson, "amounts")
+
+	assert.Error(t, err)
+
+	_, err = Int(parsedJson, "atob")
+
+	assert.Error(t, err)
+
+	_, err = Bool(parsedJson, "missing")
+
+	assert.Error(t, err)
+}
@@ -3,25 +3,50 @@ package applications
 import (
 	"fmt"
 
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/aftermath"
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/cetus"
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/deepbook"
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/turbos"
 	"github.com/fluidity-money/fluidity-app/lib/queues/sui"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	sui_types "github.com/fluidity-money/fluidity-app/lib/types/sui"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+
 	"github.com/fluidity-money/sui-go-sdk/models"
+	suiSdk "github.com/fluidity-money/sui-go-sdk/sui"
 )
 
 // ClassifyApplicationTransfer to determine the application used in a transfer based on the event type
 func ClassifyApplicationTransfer(event models.SuiEventResponse) Application {
-	switch event.Type {
+	eventType, err := swap.ParseEventType(event.Type)
+
+	if err != nil {
+		return ApplicationNone
+	}
+
+	switch {
+	case cetus.IsSwapEvent(eventType):
+		return ApplicationCetus
+
+	case turbos.IsSwapEvent(eventType):
+		return ApplicationTurbos
+
+	case deepbook.IsSwapEvent(eventType):
+		return ApplicationDeepBook
+
+	case aftermath.IsSwapEvent(eventType):
+		return ApplicationAftermath
+
 	default:
 		return ApplicationNone
 	}
 }
 
-// currently passes the entire block since no apps are currently implemented
 // GetApplicationFee to find the fee (in USD) paid by a user for the application interaction
 // returns (feeData with Fee set to nil, nil) in the case where the application event is legitimate, but doesn't involve
 // the fluid asset we're tracking, e.g. in a multi-token pool where two other tokens are swapped
-// if a receipt is passed, will be passed to the application if it can use it
-func GetApplicationFee(transfer sui.DecoratedTransfer, event models.SuiEventResponse, application Application) (applications.ApplicationFeeData, applications.ApplicationData, sui.SuiAppFees, error) {
+func GetApplicationFee(transfer sui.DecoratedTransfer, event models.SuiEventResponse, application Application, client suiSdk.ISuiAPI, fluidToken sui_types.SuiToken) (applications.ApplicationFeeData, applications.ApplicationData, sui.SuiAppFees, error) {
 	var (
 		feeData  applications.ApplicationFeeData
 		appData  applications.ApplicationData
@@ -29,9 +54,46 @@ func GetApplicationFee(transfer sui.DecoratedTransfer, event models.SuiEventResp
 		err      error
 	)
 
+	args := swap.Args{
+		Client:         client,
+		Event:          event,
+		BalanceChanges: transfer.BalanceChanges,
+		FluidCoinType:  fluidToken.Type(),
+		TokenDecimals:  fluidToken.TokenDecimals,
+	}
+
+	// the balance changes are only missing if the transfer was
+	// decorated before they were tracked, so the swap is decoded anyway
+
+	if len(args.BalanceChanges) != 0 && !swap.InvolvesCoin(args.BalanceChanges, args.FluidCoinType) {
+		return feeData, appData, emission, nil
+	}
+
 	switch application {
 	case ApplicationNone:
 		return feeData, appData, emission, nil
+
+	case ApplicationCetus:
+		feeData, err = cetus.GetCetusFees(args)
+		emission.Cetus = util.MaybeRatToFloat(feeData.Fee)
+
+	case ApplicationTurbos:
+		feeData, err = turbos.GetTurbosFees(args)
+		emission.Turbos = util.MaybeRatToFloat(feeData.Fee)
+
+	case ApplicationDeepBook:
+		feeData, err = deepbook.GetDeepBookFees(args)
+		emission.DeepBook = util.MaybeRatToFloat(feeData.Fee)
+
+	case ApplicationAftermath:
+		feeData, err = aftermath.GetAftermathFees(args)
+		emission.Aftermath = util.MaybeRatToFloat(feeData.Fee)
+
+	default:
+		err = fmt.Errorf(
+			"unknown application %v",
+			application,
+		)
 	}
 
 	return feeData, appData, emission, err
@@ -42,7 +104,31 @@ func GetApplicationFee(transfer sui.DecoratedTransfer, event models.SuiEventResp
 // with the recipient tokens being effectively burnt (sent to the contract). In the case of a P2P swap,
 // such as a DEX, the party sending the fluid tokens receives the majority payout.
 func GetApplicationTransferParties(transfer sui.DecoratedTransfer, application Application) (string, string, error) {
+	if transfer.Event == nil {
+		return "", "", fmt.Errorf(
+			"Transfer #%v did not contain an event",
+			transfer,
+		)
+	}
+
+	var (
+		sender     = transfer.Data.Sender
+		parsedJson = transfer.Event.ParsedJson
+	)
+
+	// the pool is the recipient, its coins are effectively burnt
+
 	switch application {
+	case ApplicationCetus, ApplicationTurbos:
+		pool, err := swap.String(parsedJson, "pool")
+
+		return sender, pool, err
+
+	case ApplicationDeepBook, ApplicationAftermath:
+		pool, err := swap.String(parsedJson, "pool_id")
+
+		return sender, pool, err
+
 	default:
 		return "", "", fmt.Errorf(
 			"Transfer #%v did not contain an application",
@@ -0,0 +1,96 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package turbos
+
+import (
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+)
+
+// PackageId of the Turbos CLMM package that emits swap events on mainnet
+const PackageId = "0x91bfbc386a41afcfd9b2533058d7e915a1d3829089cc268ff4333d54d6339ca1"
+
+// IsSwapEvent if the event type is a Turbos pool::SwapEvent
+func IsSwapEvent(eventType swap.EventType) bool {
+	return eventType.Is(PackageId, "pool", "SwapEvent")
+}
+
+// GetTurbosFees to find the fee paid in a swap in a Turbos pool, typed
+// Pool<A, B, Fee>, with the fee taken from the input coin. Returns a nil
+// Fee if the fluid coin isn't in the pool
+func GetTurbosFees(args swap.Args) (applications.ApplicationFeeData, error) {
+	var (
+		feeData    applications.ApplicationFeeData
+		parsedJson = args.Event.ParsedJson
+	)
+
+	poolId, err := swap.String(parsedJson, "pool")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	aToB, err := swap.Bool(parsedJson, "a_to_b")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	amountA, err := swap.Int(parsedJson, "amount_a")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	amountB, err := swap.Int(parsedJson, "amount_b")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	feeAmount, err := swap.Int(parsedJson, "fee_amount")
+
+	if err != nil {
+		return feeData, err
+	}
+
+	coinTypes, err := swap.PoolCoinTypes(args.Client, poolId)
+
+	if err != nil {
+		return feeData, fmt.Errorf(
+			"failed to get the coins in Turbos pool %v! %v",
+			poolId,
+			err,
+		)
+	}
+
+	// the last type argument is the fee tier
+
+	if len(coinTypes) != 3 {
+		return feeData, fmt.Errorf(
+			"turbos pool %v had %v type arguments, expected 3",
+			poolId,
+			len(coinTypes),
+		)
+	}
+
+	amountIn, amountOut := amountA, amountB
+
+	if !aToB {
+		amountIn, amountOut = amountB, amountA
+	}
+
+	return swap.InputFee(
+		args,
+		coinTypes[0],
+		coinTypes[1],
+		aToB,
+		amountIn,
+		amountOut,
+		feeAmount,
+	)
+}
@@ -0,0 +1,119 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package turbos
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/common/sui/applications/swap"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/sui"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fluidCoinType = "0xab12::fluid_coin::FLUID_COIN"
+	otherCoinType = "0xcd34::coin::COIN"
+	feeType       = PackageId + "::fee3000bps::FEE3000BPS"
+)
+
+// fakeSuiClient serving the types of pools, embedding the interface so
+// only the methods used need to be implemented
+type fakeSuiClient struct {
+	sui.ISuiAPI
+
+	pools map[string]string
+}
+
+func (client fakeSuiClient) SuiGetObject(_ context.Context, request models.SuiGetObjectRequest) (models.SuiObjectResponse, error) {
+	type_, found := client.pools[request.ObjectId]
+
+	if !found {
+		return models.SuiObjectResponse{}, errors.New("object not found")
+	}
+
+	return models.SuiObjectResponse{
+		Data: &models.SuiObjectData{
+			ObjectId: request.ObjectId,
+			Content:  &models.SuiParsedData{DataType: "moveObject", Type: type_},
+		},
+	}, nil
+}
+
+func swapEvent(pool string, aToB bool) models.SuiEventResponse {
+	return models.SuiEventResponse{
+		Type: PackageId + "::pool::SwapEvent",
+		ParsedJson: map[string]interface{}{
+			"a_to_b":     aToB,
+			"pool":       pool,
+			"amount_a":   "1000000000",
+			"amount_b":   "500000000",
+			"fee_amount": "3000000",
+		},
+	}
+}
+
+func TestGetTurbosFees(t *testing.T) {
+	var (
+		fluidPool     = swap.NormaliseAddress("0xd1")
+		otherPool     = swap.NormaliseAddress("0xd2")
+		malformedPool = swap.NormaliseAddress("0xd3")
+	)
+
+	client := fakeSuiClient{
+		pools: map[string]string{
+			fluidPool:     PackageId + "::pool::Pool<" + otherCoinType + ", " + fluidCoinType + ", " + feeType + ">",
+			otherPool:     PackageId + "::pool::Pool<0x2::sui::SUI, " + otherCoinType + ", " + feeType + ">",
+			malformedPool: PackageId + "::pool::Pool<0x2::sui::SUI, " + otherCoinType + ">",
+		},
+	}
+
+	args := swap.Args{
+		Client:        client,
+		Event:         swapEvent(fluidPool, true),
+		FluidCoinType: fluidCoinType,
+		TokenDecimals: 6,
+	}
+
+	// 1000 of coin a in for 500 fluid out at a 0.3% fee
+
+	feeData, err := GetTurbosFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(500*3, 997), feeData.Fee)
+	assert.Equal(t, big.NewRat(500, 1), feeData.Volume)
+
+	// 500 fluid in paying a 3 fluid fee
+
+	args.Event = swapEvent(fluidPool, false)
+
+	feeData, err = GetTurbosFees(args)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, big.NewRat(3, 1), feeData.Fee)
+	assert.Equal(t, big.NewRat(500, 1), feeData.Volume)
+
+	args.Event = swapEvent(otherPool, true)
+
+	feeData, err = GetTurbosFees(args)
+
+	require.NoError(t, err)
+
+	assert.Nil(t, feeData.Fee)
+
+	args.Event = swapEvent(malformedPool, true)
+
+	_, err = GetTurbosFees(args)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,512 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// checkpoints ingests Sui checkpoints in order and without gaps. Pages
+// of checkpoints are fetched from several RPC endpoints in parallel and
+// published in order once every page before them was. Progress is saved
+// after every checkpoint is published, so a restart carries on after the
+// last one. RPC failures are retried on the next endpoint with a backoff
+// that doubles up to a cap.
+package checkpoints
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	sui_types "github.com/fluidity-money/fluidity-app/lib/types/sui"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/sui"
+)
+
+const (
+	// Context to use for logging
+	Context = `SUI/CHECKPOINTS`
+
+	// MaxPageSize to fetch checkpoints with, a hard limit in the Go SDK
+	// (the RPC limit is either 50 or 100)
+	MaxPageSize = 50
+)
+
+type (
+	// Store of the progress, shared with any replica that takes over
+	Store interface {
+		// Load the progress, or an empty progress if there's none
+		Load() (Progress, error)
+
+		// Save the progress, returning an error if it wasn't saved
+		Save(progress Progress) error
+	}
+
+	// Publisher of checkpoints, called in order
+	Publisher func(checkpoint sui_types.Checkpoint)
+
+	// Config of the ingester
+	Config struct {
+		// PageSize of checkpoints to fetch at once, at most MaxPageSize
+		PageSize uint64
+
+		// Window of pages to fetch at once, spread over the endpoints
+		Window int
+
+		// MinBackoff to wait before retrying a failed request, doubled
+		// every failure up to MaxBackoff
+		MinBackoff time.Duration
+
+		// MaxBackoff to wait between retries
+		MaxBackoff time.Duration
+
+		// PollInterval to wait for new checkpoints at once caught up
+		PollInterval time.Duration
+	}
+
+	// Ingester of checkpoints
+	Ingester struct {
+		clients []sui.ISuiAPI
+		store   Store
+		publish Publisher
+		config  Config
+
+		// offset of the endpoint to use for the next request
+		offset int
+
+		mu sync.Mutex
+	}
+)
+
+// DefaultConfig to ingest checkpoints with
+func DefaultConfig() Config {
+	return Config{
+		PageSize:     MaxPageSize,
+		Window:       4,
+		MinBackoff:   time.Second,
+		MaxBackoff:   time.Minute,
+		PollInterval: 5 * time.Second,
+	}
+}
+
+// New ingester fetching from the clients given, publishing in order with
+// publish
+func New(clients []sui.ISuiAPI, store Store, publish Publisher, config Config) (*Ingester, error) {
+	switch {
+	case len(clients) == 0:
+		return nil, fmt.Errorf("no endpoints to fetch checkpoints from")
+
+	case config.PageSize == 0 || config.PageSize > MaxPageSize:
+		return nil, fmt.Errorf(
+			"page size %v should be between 1 and %v",
+			config.PageSize,
+			MaxPageSize,
+		)
+
+	case config.Window <= 0:
+		return nil, fmt.Errorf("window %v should be positive", config.Window)
+
+	case config.MinBackoff <= 0 || config.MaxBackoff < config.MinBackoff:
+		return nil, fmt.Errorf(
+			"backoff should be positive and capped above the minimum, min %v max %v",
+			config.MinBackoff,
+			config.MaxBackoff,
+		)
+	}
+
+	ingester := &Ingester{
+		clients: clients,
+		store:   store,
+		publish: publish,
+		config:  config,
+	}
+
+	return ingester, nil
+}
+
+// Run the ingester from the checkpoint given, or the first after the
+// high-water mark that wasn't published if that's later, until the
+// context is done. Starting past the high-water mark leaves a gap that's
+// filled by running from the mark again later
+func (ingester *Ingester) Run(ctx context.Context, first uint64) error {
+	progress, err := ingester.store.Load()
+
+	if err != nil {
+		return fmt.Errorf("failed to load the progress! %v", err)
+	}
+
+	next := progress.NextFrom(first)
+
+	log.App(func(k *log.Log) {
+		k.Context = Context
+
+		k.Format(
+			"Ingesting checkpoints from %v, high-water mark %v (started %v), %v ranges completed above it",
+			next,
+			progress.HighWaterMark,
+			progress.Started,
+			len(progress.Completed),
+		)
+	})
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		latest, err := ingester.latestCheckpoint(ctx)
+
+		if err != nil {
+			return err
+		}
+
+		if next > latest {
+			log.Debug(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Up to date at %v, waiting %v!",
+					latest,
+					ingester.config.PollInterval,
+				)
+			})
+
+			if err := sleep(ctx, ingester.config.PollInterval); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		pages := ingester.planPages(progress, next, latest)
+
+		results := ingester.fetchPages(ctx, pages)
+
+		// publish the pages in order as they arrive
+
+		for i, page := range pages {
+			var result pageResult
+
+			select {
+			case result = <-results[i]:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			if result.err != nil {
+				return result.err
+			}
+
+			for _, checkpoint := range result.checkpoints {
+				if err := ingester.publishCheckpoint(&progress, checkpoint); err != nil {
+					return err
+				}
+			}
+
+			next = progress.NextFrom(page.To + 1)
+		}
+	}
+}
+
+type pageResult struct {
+	checkpoints []models.CheckpointResponse
+	err         error
+}
+
+// planPages from next up to the latest checkpoint, skipping the ranges
+// that were published already
+func (ingester *Ingester) planPages(progress Progress, next, latest uint64) []Range {
+	var (
+		pageSize = ingester.config.PageSize
+		pages    = make([]Range, 0, ingester.config.Window)
+	)
+
+	for cursor := progress.NextFrom(next); cursor <= latest && len(pages) < ingester.config.Window; {
+		to := cursor + pageSize - 1
+
+		if to > latest {
+			to = latest
+		}
+
+		pages = append(pages, Range{From: cursor, To: to})
+
+		cursor = progress.NextFrom(to + 1)
+	}
+
+	return pages
+}
+
+// fetchPages in parallel, returning a channel for each page that the
+// result is sent down
+func (ingester *Ingester) fetchPages(ctx context.Context, pages []Range) []chan pageResult {
+	results := make([]chan pageResult, len(pages))
+
+	for i, page := range pages {
+		results[i] = make(chan pageResult, 1)
+
+		go func(page Range, result chan pageResult) {
+			checkpoints, err := ingester.fetchPage(ctx, page)
+
+			result <- pageResult{checkpoints, err}
+		}(page, results[i])
+	}
+
+	return results
+}
+
+// fetchPage of checkpoints, retrying on the next endpoint until every
+// checkpoint in the page was fetched or the context is done
+func (ingester *Ingester) fetchPage(ctx context.Context, page Range) ([]models.CheckpointResponse, error) {
+	var checkpoints []models.CheckpointResponse
+
+	description := fmt.Sprintf("get checkpoints %v-%v", page.From, page.To)
+
+	err := ingester.retry(ctx, description, func(client sui.ISuiAPI) (err error) {
+		checkpoints, err = getCheckpoints(ctx, client, page, ingester.config.PageSize)
+
+		return err
+	})
+
+	return checkpoints, err
+}
+
+// latestCheckpoint that any endpoint knows of, retrying until the
+// context is done
+func (ingester *Ingester) latestCheckpoint(ctx context.Context) (latest uint64, err error) {
+	err = ingester.retry(ctx, "get the latest checkpoint", func(client sui.ISuiAPI) (err error) {
+		latest, err = client.SuiGetLatestCheckpointSequenceNumber(ctx)
+
+		return err
+	})
+
+	return latest, err
+}
+
+// publishCheckpoint unless it was published already, saving the
+// progress after
+func (ingester *Ingester) publishCheckpoint(progress *Progress, response models.CheckpointResponse) error {
+	checkpoint, sequenceNumber, err := ToCheckpoint(response)
+
+	if err != nil {
+		return err
+	}
+
+	if progress.Contains(sequenceNumber) {
+		return nil
+	}
+
+	ingester.publish(checkpoint)
+
+	log.Debug(func(k *log.Log) {
+		k.Context = Context
+		k.Format("Published checkpoint %v", sequenceNumber)
+	})
+
+	if err := progress.Complete(Range{sequenceNumber, sequenceNumber}); err != nil {
+		return err
+	}
+
+	if err := ingester.store.Save(*progress); err != nil {
+		return fmt.Errorf(
+			"failed to save the progress after checkpoint %v! %v",
+			sequenceNumber,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// retry f with each endpoint in turn, waiting with a backoff capped at
+// the max backoff after every failure, until it succeeds or the context
+// is done
+func (ingester *Ingester) retry(ctx context.Context, description string, f func(client sui.ISuiAPI) error) error {
+	backoff := ingester.config.MinBackoff
+
+	for {
+		offset, client := ingester.nextClient()
+
+		err := f(client)
+
+		if err == nil {
+			return nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to %v with endpoint %v, retrying in %v!",
+				description,
+				offset,
+				backoff,
+			)
+
+			k.Payload = err
+		})
+
+		if err := sleep(ctx, backoff); err != nil {
+			return err
+		}
+
+		backoff = nextBackoff(backoff, ingester.config.MaxBackoff)
+	}
+}
+
+// nextClient to make a request with, taking turns between the endpoints
+func (ingester *Ingester) nextClient() (int, sui.ISuiAPI) {
+	ingester.mu.Lock()
+	defer ingester.mu.Unlock()
+
+	offset := ingester.offset
+
+	ingester.offset = (offset + 1) % len(ingester.clients)
+
+	return offset, ingester.clients[offset]
+}
+
+// getCheckpoints in the page from the client, returning an error if the
+// endpoint skipped any or doesn't have them all yet
+func getCheckpoints(ctx context.Context, client sui.ISuiAPI, page Range, pageSize uint64) ([]models.CheckpointResponse, error) {
+	checkpoints := make([]models.CheckpointResponse, 0, page.To-page.From+1)
+
+	for next := page.From; next <= page.To; {
+		// the cursor is the checkpoint before the first returned
+
+		var cursor interface{}
+
+		if next > 0 {
+			cursor = strconv.FormatUint(next-1, 10)
+		}
+
+		limit := page.To - next + 1
+
+		if limit > pageSize {
+			limit = pageSize
+		}
+
+		response, err := client.SuiGetCheckpoints(ctx, models.SuiGetCheckpointsRequest{
+			Cursor:          cursor,
+			Limit:           limit,
+			DescendingOrder: false,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(response.Data) == 0 {
+			return nil, fmt.Errorf(
+				"endpoint doesn't have checkpoint %v yet",
+				next,
+			)
+		}
+
+		for _, checkpoint := range response.Data {
+			sequenceNumber, err := strconv.ParseUint(checkpoint.SequenceNumber, 10, 64)
+
+			if err != nil {
+				return nil, fmt.Errorf(
+					"failed to convert sequence number %v to a number! %v",
+					checkpoint.SequenceNumber,
+					err,
+				)
+			}
+
+			if sequenceNumber != next {
+				return nil, fmt.Errorf(
+					"expected checkpoint %v, got %v",
+					next,
+					sequenceNumber,
+				)
+			}
+
+			checkpoints = append(checkpoints, checkpoint)
+
+			next++
+
+			if next > page.To {
+				break
+			}
+		}
+	}
+
+	return checkpoints, nil
+}
+
+// ToCheckpoint to convert the checkpoint from the RPC to the summary
+// that's published, with its sequence number
+func ToCheckpoint(response models.CheckpointResponse) (sui_types.Checkpoint, uint64, error) {
+	var (
+		sequenceNumberString = response.SequenceNumber
+		timestampMs          = response.TimestampMs
+		transactions         = response.Transactions
+	)
+
+	sequenceNumber, err := strconv.ParseUint(sequenceNumberString, 10, 64)
+
+	if err != nil {
+		return sui_types.Checkpoint{}, 0, fmt.Errorf(
+			"failed to convert sequence number %v to a number! %v",
+			sequenceNumberString,
+			err,
+		)
+	}
+
+	// convert Unix MS timestamp to Go time
+
+	timestamp_, err := strconv.ParseInt(timestampMs, 10, 64)
+
+	if err != nil {
+		return sui_types.Checkpoint{}, 0, fmt.Errorf(
+			"failed to convert checkpoint timestamp %v to a number! %v",
+			timestampMs,
+			err,
+		)
+	}
+
+	timestamp := time.Unix(timestamp_/1000, 0).UTC()
+
+	sequenceNumberInt := new(big.Int).SetUint64(sequenceNumber)
+
+	checkpoint := sui_types.Checkpoint{
+		SequenceNumber: misc.NewBigIntFromInt(*sequenceNumberInt),
+		Timestamp:      timestamp,
+		Transactions:   transactions,
+	}
+
+	return checkpoint, sequenceNumber, nil
+}
+
+// nextBackoff to wait after the one given, doubling it up to the max
+func nextBackoff(backoff, max time.Duration) time.Duration {
+	backoff *= 2
+
+	if backoff > max {
+		return max
+	}
+
+	return backoff
+}
+
+// sleep for the duration given, returning early if the context is done
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,330 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package checkpoints
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	sui_types "github.com/fluidity-money/fluidity-app/lib/types/sui"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/sui"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// fakeSuiClient serving checkpoints up to latest, embedding the
+	// interface so only the methods used need to be implemented
+	fakeSuiClient struct {
+		sui.ISuiAPI
+
+		mu sync.Mutex
+
+		latest uint64
+
+		// failures to fail requests with before succeeding, forever
+		// if negative
+		failures int
+
+		// maxPage to return at most, ignoring larger limits if set
+		maxPage uint64
+
+		// missing checkpoint to leave out of pages if set
+		missing *uint64
+
+		// delay before responding to each page
+		delay time.Duration
+	}
+
+	memoryStore struct {
+		mu       sync.Mutex
+		progress Progress
+		saves    int
+	}
+)
+
+var testConfig = Config{
+	PageSize:     10,
+	Window:       4,
+	MinBackoff:   time.Millisecond,
+	MaxBackoff:   4 * time.Millisecond,
+	PollInterval: time.Millisecond,
+}
+
+func (client *fakeSuiClient) fail() error {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	switch {
+	case client.failures < 0:
+		return errors.New("connection refused")
+
+	case client.failures > 0:
+		client.failures--
+		return errors.New("too many requests")
+	}
+
+	return nil
+}
+
+func (client *fakeSuiClient) SuiGetLatestCheckpointSequenceNumber(_ context.Context) (uint64, error) {
+	if err := client.fail(); err != nil {
+		return 0, err
+	}
+
+	return client.latest, nil
+}
+
+func (client *fakeSuiClient) SuiGetCheckpoints(ctx context.Context, request models.SuiGetCheckpointsRequest) (models.PaginatedCheckpointsResponse, error) {
+	if err := client.fail(); err != nil {
+		return models.PaginatedCheckpointsResponse{}, err
+	}
+
+	if err := sleep(ctx, client.delay); err != nil {
+		return models.PaginatedCheckpointsResponse{}, err
+	}
+
+	var first uint64
+
+	if cursor, ok := request.Cursor.(string); ok {
+		last, err := strconv.ParseUint(cursor, 10, 64)
+
+		if err != nil {
+			return models.PaginatedCheckpointsResponse{}, err
+		}
+
+		first = last + 1
+	}
+
+	limit := request.Limit
+
+	if client.maxPage != 0 && limit > client.maxPage {
+		limit = client.maxPage
+	}
+
+	var response models.PaginatedCheckpointsResponse
+
+	for sequenceNumber := first; sequenceNumber <= client.latest && uint64(len(response.Data)) < limit; sequenceNumber++ {
+		if client.missing != nil && *client.missing == sequenceNumber {
+			continue
+		}
+
+		response.Data = append(response.Data, models.CheckpointResponse{
+			SequenceNumber: strconv.FormatUint(sequenceNumber, 10),
+			TimestampMs:    strconv.FormatUint(1700000000000+sequenceNumber*1000, 10),
+			Transactions:   []string{"digest-" + strconv.FormatUint(sequenceNumber, 10)},
+		})
+	}
+
+	response.HasNextPage = first+uint64(len(response.Data)) <= client.latest
+
+	return response, nil
+}
+
+func (store *memoryStore) Load() (Progress, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	return store.progress, nil
+}
+
+func (store *memoryStore) Save(progress Progress) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.progress = progress
+	store.saves++
+
+	return nil
+}
+
+// runUntil the checkpoint given is published, returning the sequence
+// numbers published in the order they were
+func runUntil(t *testing.T, clients []sui.ISuiAPI, store Store, first, last uint64) []uint64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+	defer cancel()
+
+	var published []uint64
+
+	publish := func(checkpoint sui_types.Checkpoint) {
+		sequenceNumber := checkpoint.SequenceNumber.Uint64()
+
+		published = append(published, sequenceNumber)
+
+		if sequenceNumber == last {
+			cancel()
+		}
+	}
+
+	ingester, err := New(clients, store, publish, testConfig)
+
+	require.NoError(t, err)
+
+	err = ingester.Run(ctx, first)
+
+	require.ErrorIs(t, err, context.Canceled, "ingester didn't publish %v", last)
+
+	return published
+}
+
+func sequence(from, to uint64) []uint64 {
+	numbers := make([]uint64, 0, to-from+1)
+
+	for i := from; i <= to; i++ {
+		numbers = append(numbers, i)
+	}
+
+	return numbers
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	clients := []sui.ISuiAPI{new(fakeSuiClient)}
+
+	_, err := New(nil, new(memoryStore), nil, testConfig)
+
+	assert.Error(t, err)
+
+	config := testConfig
+
+	config.PageSize = MaxPageSize + 1
+
+	_, err = New(clients, new(memoryStore), nil, config)
+
+	assert.Error(t, err)
+
+	config = testConfig
+
+	config.MaxBackoff = config.MinBackoff / 2
+
+	_, err = New(clients, new(memoryStore), nil, config)
+
+	assert.Error(t, err)
+}
+
+func TestIngesterPublishesInOrder(t *testing.T) {
+	// the slow endpoint's pages arrive after the later pages
+
+	clients := []sui.ISuiAPI{
+		&fakeSuiClient{latest: 137, delay: 5 * time.Millisecond},
+		&fakeSuiClient{latest: 137},
+		&fakeSuiClient{latest: 137, delay: time.Millisecond},
+	}
+
+	store := new(memoryStore)
+
+	published := runUntil(t, clients, store, 0, 137)
+
+	assert.Equal(t, sequence(0, 137), published)
+
+	assert.True(t, store.progress.Started)
+	assert.Equal(t, uint64(137), store.progress.HighWaterMark)
+	assert.Empty(t, store.progress.Completed)
+	assert.Equal(t, 138, store.saves)
+}
+
+func TestIngesterRetriesFailures(t *testing.T) {
+	clients := []sui.ISuiAPI{
+		&fakeSuiClient{latest: 57, failures: -1},
+		&fakeSuiClient{latest: 57, failures: 3, maxPage: 3},
+	}
+
+	published := runUntil(t, clients, new(memoryStore), 0, 57)
+
+	assert.Equal(t, sequence(0, 57), published)
+}
+
+func TestIngesterRetriesMissingCheckpoints(t *testing.T) {
+	missing := uint64(15)
+
+	// an endpoint that's behind or skips a checkpoint shouldn't leave
+	// a gap
+
+	clients := []sui.ISuiAPI{
+		&fakeSuiClient{latest: 29, missing: &missing},
+		&fakeSuiClient{latest: 29},
+		&fakeSuiClient{latest: 20},
+	}
+
+	published := runUntil(t, clients, new(memoryStore), 0, 29)
+
+	assert.Equal(t, sequence(0, 29), published)
+}
+
+func TestIngesterResumes(t *testing.T) {
+	clients := []sui.ISuiAPI{&fakeSuiClient{latest: 39}}
+
+	store := &memoryStore{
+		progress: Progress{
+			Started:       true,
+			HighWaterMark: 9,
+			Completed:     []Range{{20, 29}},
+		},
+	}
+
+	published := runUntil(t, clients, store, 0, 39)
+
+	expected := append(sequence(10, 19), sequence(30, 39)...)
+
+	assert.Equal(t, expected, published)
+
+	assert.Equal(t, uint64(39), store.progress.HighWaterMark)
+	assert.Empty(t, store.progress.Completed)
+}
+
+func TestIngesterStartsPastMark(t *testing.T) {
+	clients := []sui.ISuiAPI{&fakeSuiClient{latest: 39}}
+
+	store := &memoryStore{
+		progress: Progress{Started: true, HighWaterMark: 9},
+	}
+
+	published := runUntil(t, clients, store, 30, 39)
+
+	assert.Equal(t, sequence(30, 39), published)
+
+	// the gap is left to be filled later
+
+	assert.Equal(t, uint64(9), store.progress.HighWaterMark)
+	assert.Equal(t, []Range{{30, 39}}, store.progress.Completed)
+}
+
+func TestNextBackoffCapped(t *testing.T) {
+	backoff := time.Second
+
+	for i := 0; i < 10; i++ {
+		backoff = nextBackoff(backoff, time.Minute)
+	}
+
+	assert.Equal(t, time.Minute, backoff)
+
+	assert.Equal(t, 2*time.Second, nextBackoff(time.Second, time.Minute))
+}
+
+func TestToCheckpoint(t *testing.T) {
+	checkpoint, sequenceNumber, err := ToCheckpoint(models.CheckpointResponse{
+		SequenceNumber: "42",
+		TimestampMs:    "1700000000500",
+		Transactions:   []string{"a", "b"},
+	})
+
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(42), sequenceNumber)
+	assert.Equal(t, "42", checkpoint.SequenceNumber.String())
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), checkpoint.Timestamp)
+	assert.Equal(t, []string{"a", "b"}, checkpoint.Transactions)
+
+	_, _, err = ToCheckpoint(models.CheckpointResponse{SequenceNumber: "x"})
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package checkpoints
+
+import (
+	"fmt"
+	"sort"
+)
+
+type (
+	// Range of checkpoints, inclusive of both ends
+	Range struct {
+		From uint64 `json:"from"`
+		To   uint64 `json:"to"`
+	}
+
+	// Progress of the checkpoints published, every checkpoint up to the
+	// high-water mark and the ranges published above it. Ranges are left
+	// above the mark when the ingester was started past it, and merge
+	// into the mark once the gap before them is filled
+	Progress struct {
+		// Started once any checkpoint was published, so checkpoint 0
+		// can be told apart from nothing
+		Started bool `json:"started"`
+
+		// HighWaterMark that every checkpoint up to was published
+		HighWaterMark uint64 `json:"high_water_mark"`
+
+		// Completed ranges above the high-water mark, sorted and
+		// not touching each other or the mark
+		Completed []Range `json:"completed"`
+	}
+)
+
+// Next checkpoint after the high-water mark
+func (progress Progress) Next() uint64 {
+	if !progress.Started {
+		return 0
+	}
+
+	return progress.HighWaterMark + 1
+}
+
+// Contains the checkpoint if it was published
+func (progress Progress) Contains(checkpoint uint64) bool {
+	if progress.Started && checkpoint <= progress.HighWaterMark {
+		return true
+	}
+
+	for _, completed := range progress.Completed {
+		if completed.From <= checkpoint && checkpoint <= completed.To {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NextFrom to get the first checkpoint from the one given that wasn't
+// published yet
+func (progress Progress) NextFrom(checkpoint uint64) uint64 {
+	if next := progress.Next(); checkpoint < next {
+		checkpoint = next
+	}
+
+	for _, completed := range progress.Completed {
+		if completed.From <= checkpoint && checkpoint <= completed.To {
+			checkpoint = completed.To + 1
+		}
+	}
+
+	return checkpoint
+}
+
+// Complete the range, merging it with the ranges it touches and into
+// the high-water mark if there's no gap before it
+func (progress *Progress) Complete(range_ Range) error {
+	if range_.From > range_.To {
+		return fmt.Errorf(
+			"range %v-%v ends before it starts",
+			range_.From,
+			range_.To,
+		)
+	}
+
+	completed := append(append([]Range{}, progress.Completed...), range_)
+
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].From < completed[j].From
+	})
+
+	merged := make([]Range, 0, len(completed))
+
+	for _, next := range completed {
+		last := len(merged) - 1
+
+		if last >= 0 && next.From <= merged[last].To+1 {
+			if next.To > merged[last].To {
+				merged[last].To = next.To
+			}
+
+			continue
+		}
+
+		merged = append(merged, next)
+	}
+
+	// fold the ranges that start at the next checkpoint into the mark
+
+	for len(merged) > 0 && merged[0].From <= progress.Next() {
+		if !progress.Started || merged[0].To > progress.HighWaterMark {
+			progress.HighWaterMark = merged[0].To
+		}
+
+		progress.Started = true
+
+		merged = merged[1:]
+	}
+
+	progress.Completed = merged
+
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package checkpoints
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressEmpty(t *testing.T) {
+	var progress Progress
+
+	assert.Equal(t, uint64(0), progress.Next())
+	assert.False(t, progress.Contains(0))
+	assert.Equal(t, uint64(5), progress.NextFrom(5))
+
+	require.NoError(t, progress.Complete(Range{0, 0}))
+
+	assert.True(t, progress.Started)
+	assert.Equal(t, uint64(0), progress.HighWaterMark)
+	assert.Equal(t, uint64(1), progress.Next())
+	assert.True(t, progress.Contains(0))
+}
+
+func TestProgressCompleteAboveMark(t *testing.T) {
+	progress := Progress{Started: true, HighWaterMark: 10}
+
+	require.NoError(t, progress.Complete(Range{20, 25}))
+	require.NoError(t, progress.Complete(Range{30, 30}))
+
+	assert.Equal(t, uint64(10), progress.HighWaterMark)
+	assert.Equal(t, []Range{{20, 25}, {30, 30}}, progress.Completed)
+
+	assert.True(t, progress.Contains(22))
+	assert.False(t, progress.Contains(26))
+
+	assert.Equal(t, uint64(11), progress.NextFrom(0))
+	assert.Equal(t, uint64(26), progress.NextFrom(20))
+	assert.Equal(t, uint64(31), progress.NextFrom(30))
+
+	// touching ranges merge
+
+	require.NoError(t, progress.Complete(Range{26, 29}))
+
+	assert.Equal(t, []Range{{20, 30}}, progress.Completed)
+
+	// filling the gap merges the ranges into the mark
+
+	require.NoError(t, progress.Complete(Range{11, 19}))
+
+	assert.Equal(t, uint64(30), progress.HighWaterMark)
+	assert.Empty(t, progress.Completed)
+}
+
+func TestProgressCompleteOverlapping(t *testing.T) {
+	progress := Progress{Started: true, HighWaterMark: 10}
+
+	require.NoError(t, progress.Complete(Range{5, 12}))
+
+	assert.Equal(t, uint64(12), progress.HighWaterMark)
+
+	require.NoError(t, progress.Complete(Range{3, 4}))
+
+	assert.Equal(t, uint64(12), progress.HighWaterMark)
+	assert.Empty(t, progress.Completed)
+
+	assert.Error(t, progress.Complete(Range{20, 19}))
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// payout pays out batches of rewards on Sui by calling distribute_yield
+// on the fluid token package in a programmable transaction block.
+package payout
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	dry_run "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/fluidity-money/fluidity-app/lib/types/sui"
+	worker_types "github.com/fluidity-money/fluidity-app/lib/types/worker"
+
+	"github.com/fluidity-money/sui-go-sdk/models"
+	"github.com/fluidity-money/sui-go-sdk/signer"
+	suiSdk "github.com/fluidity-money/sui-go-sdk/sui"
+)
+
+const (
+	// Context to use for logging
+	Context = "SUI/PAYOUT"
+
+	// FluidityModule containing the payout function
+	FluidityModule = `fluidity_coin`
+
+	// PayoutFunction to call to pay out winners
+	PayoutFunction = `distribute_yield`
+
+	// ClockId for the constant address of the Sui clock
+	ClockId = `0x0000000000000000000000000000000000000000000000000000000000000006`
+)
+
+type (
+	// PayoutArgs of the objects distribute_yield is called with
+	PayoutArgs struct {
+		PrizePoolVault string
+		ScallopVersion string
+		ScallopMarket  string
+		Clock          string
+	}
+
+	// Backend to pay out batches of rewards with the worker's key
+	Backend struct {
+		Client        suiSdk.ISuiAPI
+		Signer        signer.Signer
+		FluidToken    sui.SuiToken
+		BaseToken     sui.SuiToken
+		WorkerAddress string
+		PayoutArgs    PayoutArgs
+	}
+)
+
+// Payout the batch of rewards in a single transaction
+func (backend Backend) Payout(rewards worker_types.EthereumSpooledRewards) error {
+	txnMetaData, err := backend.createPayoutTransaction(rewards)
+
+	if err != nil {
+		return err
+	}
+
+	return backend.makePayouts(txnMetaData)
+}
+
+// Simulate the transaction paying out the batch with a dry run
+func (backend Backend) Simulate(rewards worker_types.EthereumSpooledRewards) ([]dry_run.Result, error) {
+	txnMetaData, err := backend.createPayoutTransaction(rewards)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signed := txnMetaData.SignSerializedSigWith(backend.Signer.PriKey)
+
+	result := dry_run.Result{
+		Network:     network.NetworkSui,
+		Description: "distribute yield",
+		Sender:      backend.WorkerAddress,
+		Recipient:   backend.FluidToken.PackageId,
+		Transaction: signed.TxBytes,
+		Time:        time.Now(),
+	}
+
+	response, err := backend.Client.SuiDryRunTransactionBlock(context.Background(), models.SuiDryRunTransactionBlockRequest{
+		TxBytes: signed.TxBytes,
+	})
+
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+
+	case response.Effects.Status.Status != "success":
+		result.Error = response.Effects.Status.Error
+
+	default:
+		result.Success = true
+	}
+
+	if err == nil {
+		result.GasEstimate = gasEstimate(response.Effects.GasUsed)
+	}
+
+	return []dry_run.Result{result}, nil
+}
+
+// TODO utility in EthereumSpooledRewards has to be the address of the utility token
+// createPayoutTransaction to call distribute_yield on the fluid token contract
+func (backend Backend) createPayoutTransaction(rewards worker_types.EthereumSpooledRewards) (models.TxnMetaData, error) {
+	var (
+		client        = backend.Client
+		fluidToken    = backend.FluidToken
+		baseToken     = backend.BaseToken
+		workerAddress = backend.WorkerAddress
+
+		prizePoolVault = backend.PayoutArgs.PrizePoolVault
+		scallopVersion = backend.PayoutArgs.ScallopVersion
+		scallopMarket  = backend.PayoutArgs.ScallopMarket
+		clock          = backend.PayoutArgs.Clock
+	)
+
+	var winners []string
+	var amounts []uint64
+
+	for _, r := range rewards.Rewards {
+		for address, win := range r {
+			winners = append(winners, address.String())
+			amounts = append(amounts, win.Uint64())
+		}
+	}
+
+	// manually find the largest SUI token to use for gas payment
+	// assumes that the worker has at least one coin with enough balance to pay gas
+	// and doesn't perform gas smashing
+	coinsResponse, err := client.SuiXGetCoins(context.Background(), models.SuiXGetCoinsRequest{Owner: workerAddress})
+	if err != nil {
+		return models.TxnMetaData{}, fmt.Errorf(
+			"failed to get SUI coins owned by worker %v - %v",
+			workerAddress,
+			err,
+		)
+	}
+	largestCoin := struct {
+		balance  *big.Int
+		objectId string
+	}{
+		balance: new(big.Int),
+	}
+	for _, coin := range coinsResponse.Data {
+		balance, _ := new(big.Int).SetString(coin.Balance, 10)
+
+		if balance != nil && largestCoin.balance.Cmp(balance) == -1 {
+			largestCoin.balance = balance
+			largestCoin.objectId = coin.CoinObjectId
+		}
+	}
+
+	moveCall, err := client.MoveCall(context.Background(), models.MoveCallRequest{
+		Signer:          workerAddress,
+		PackageObjectId: fluidToken.PackageId,
+		Module:          FluidityModule,
+		Function:        PayoutFunction,
+		TypeArguments:   []interface{}{baseToken.Type()},
+		Arguments: []interface{}{
+			prizePoolVault,
+			scallopVersion,
+			scallopMarket,
+			clock,
+			winners,
+			amounts,
+		},
+		Gas: largestCoin.objectId,
+		// 1 SUI
+		// TODO determine this properly like the ts sdk, see gasEstimate
+		GasBudget: "1000000000",
+	})
+
+	if err != nil {
+		return models.TxnMetaData{}, fmt.Errorf(
+			"failed to create move call to distribute yield %v",
+			err,
+		)
+	}
+
+	return moveCall, nil
+}
+
+// makePayouts to post the batched payouts transaction on chain
+func (backend Backend) makePayouts(txnMetaData models.TxnMetaData) error {
+	var (
+		client = backend.Client
+		signer = backend.Signer
+	)
+
+	// TODO probably don't need to dry run unless required for gas calculation
+	signed := txnMetaData.SignSerializedSigWith(signer.PriKey)
+	_, err := client.SuiDryRunTransactionBlock(context.Background(), models.SuiDryRunTransactionBlockRequest{
+		TxBytes: signed.TxBytes,
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"failed to dry run winners payout transaction! %v",
+			err,
+		)
+	}
+
+	response, err := client.SignAndExecuteTransactionBlock(context.Background(), models.SignAndExecuteTransactionBlockRequest{
+		TxnMetaData: txnMetaData,
+		PriKey:      signer.PriKey,
+		Options:     models.SuiTransactionBlockOptions{},
+		RequestType: "WaitForEffectsCert",
+	})
+
+	if err != nil {
+		return fmt.Errorf(
+			"failed to execute winners payout transaction! %v",
+			err,
+		)
+	}
+
+	log.Debug(func(k *log.Log) {
+		k.Context = Context
+		k.Message = "Executed payout transaction with digest"
+		k.Payload = response.Digest
+	})
+	return nil
+}
+
+// gasEstimate to get the gas a transaction would use from its dry run,
+// the computation cost or the computation and storage cost less the
+// rebate if that's larger
+func gasEstimate(gasUsed models.GasCostSummary) uint64 {
+	var (
+		computationCost, _ = strconv.ParseUint(gasUsed.ComputationCost, 10, 64)
+		storageCost, _     = strconv.ParseUint(gasUsed.StorageCost, 10, 64)
+		storageRebate, _   = strconv.ParseUint(gasUsed.StorageRebate, 10, 64)
+	)
+
+	gas := computationCost + storageCost
+
+	if gas < storageRebate || gas-storageRebate < computationCost {
+		return computationCost
+	}
+
+	return gas - storageRebate
+}
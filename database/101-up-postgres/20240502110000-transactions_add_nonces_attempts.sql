@@ -0,0 +1,59 @@
+-- migrate:up
+
+-- the next nonce to use for each address sending transactions through
+-- the transaction manager, per chain
+
+CREATE TABLE ethereum_transaction_nonces (
+	chain_id BIGINT NOT NULL,
+	address VARCHAR NOT NULL,
+	next_nonce BIGINT NOT NULL,
+	updated_time TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+	PRIMARY KEY (chain_id, address)
+);
+
+CREATE TYPE ethereum_transaction_attempt_status AS ENUM (
+	-- sent, and not yet mined, replaced or given up on
+	'pending',
+
+	-- mined successfully
+	'mined',
+
+	-- mined, but the transaction reverted
+	'reverted',
+
+	-- replaced by a later attempt of ours that was mined
+	'replaced',
+
+	-- the nonce was used by a transaction that wasn't ours
+	'replaced_externally',
+
+	-- dropped by the node from its mempool
+	'dropped',
+
+	-- the transaction manager stopped bumping it
+	'abandoned'
+);
+
+-- every transaction sent for a nonce, including fee bumps
+
+CREATE TABLE ethereum_transaction_attempts (
+	chain_id BIGINT NOT NULL,
+	address VARCHAR NOT NULL,
+	nonce BIGINT NOT NULL,
+	attempt INT NOT NULL,
+	transaction_hash VARCHAR NOT NULL,
+	gas_tip_cap NUMERIC NOT NULL,
+	gas_fee_cap NUMERIC NOT NULL,
+	status ethereum_transaction_attempt_status NOT NULL DEFAULT 'pending',
+	created_time TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+	updated_time TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc'),
+	PRIMARY KEY (chain_id, transaction_hash)
+);
+
+CREATE INDEX ON ethereum_transaction_attempts (address, status);
+
+-- migrate:down
+
+DROP TABLE ethereum_transaction_attempts;
+DROP TYPE ethereum_transaction_attempt_status;
+DROP TABLE ethereum_transaction_nonces;
@@ -0,0 +1,15 @@
+-- migrate:up
+
+-- messages written in the same transaction as the rows that they
+-- describe, to be relayed to AMQP by outbox.Relay
+
+CREATE TABLE outbox_messages (
+	id BIGSERIAL PRIMARY KEY,
+	topic VARCHAR NOT NULL,
+	content BYTEA NOT NULL,
+	created_time TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (NOW() AT TIME ZONE 'utc')
+);
+
+-- migrate:down
+
+DROP TABLE outbox_messages;
@@ -0,0 +1,28 @@
+-- migrate:up
+
+-- prices recorded by microservice-ethereum-track-prices to convert to
+-- USD as of a block when processing is delayed or a block is replayed
+
+CREATE TABLE price_history (
+	network network_blockchain NOT NULL,
+	token_short_name VARCHAR NOT NULL,
+	source VARCHAR NOT NULL,
+	price_usd NUMERIC NOT NULL,
+	block_number BIGINT NOT NULL,
+
+	-- updated_time is the time the source last updated the price, or
+	-- null for static prices
+	updated_time TIMESTAMP WITHOUT TIME ZONE,
+
+	recorded_time TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+SELECT create_hypertable('price_history', 'recorded_time', if_not_exists => TRUE);
+
+CREATE INDEX ON price_history (network, token_short_name, block_number DESC);
+
+CREATE INDEX ON price_history (network, token_short_name, recorded_time DESC);
+
+-- migrate:down
+
+DROP TABLE price_history;
@@ -0,0 +1,12 @@
+-- migrate:up
+
+-- fees for applications registered without their own column, keyed by
+-- the emission key of the application
+
+ALTER TABLE worker_emissions
+	ADD COLUMN ethereum_app_fees_other JSONB;
+
+-- migrate:down
+
+ALTER TABLE worker_emissions
+	DROP COLUMN ethereum_app_fees_other;
@@ -0,0 +1,48 @@
+-- migrate:up
+
+-- rewards blocked by the contract, tracked by
+-- microservice-ethereum-track-winners and written by
+-- connector-common-winners-timescale
+
+CREATE TABLE ethereum_blocked_winners (
+	network network_blockchain NOT NULL,
+	token_short_name VARCHAR NOT NULL,
+	token_decimals INT NOT NULL,
+	contract_address VARCHAR NOT NULL,
+	reward_transaction_hash VARCHAR NOT NULL,
+	winner_address VARCHAR NOT NULL,
+	winning_amount uint256 NOT NULL,
+	batch_first_block uint256 NOT NULL,
+	batch_last_block uint256 NOT NULL,
+	recorded_time TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX ON ethereum_blocked_winners (network, token_short_name);
+
+-- the prize pool held on-chain compared to the winnings promised by the
+-- spooler, written by microservice-ethereum-reconcile-prize-pool
+
+CREATE TABLE prize_pool_reconciliations (
+	network network_blockchain NOT NULL,
+	token_short_name VARCHAR NOT NULL,
+	prize_pool DOUBLE PRECISION NOT NULL,
+	unpaid_winnings DOUBLE PRECISION NOT NULL,
+	blocked_winnings DOUBLE PRECISION NOT NULL,
+	sent_winnings DOUBLE PRECISION NOT NULL,
+	paid_winnings DOUBLE PRECISION NOT NULL,
+	shortfall_deviation DOUBLE PRECISION NOT NULL,
+	payout_deviation DOUBLE PRECISION NOT NULL,
+	tolerance DOUBLE PRECISION NOT NULL,
+	within_tolerance BOOLEAN NOT NULL,
+	recorded_time TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+SELECT create_hypertable('prize_pool_reconciliations', 'recorded_time', if_not_exists => TRUE);
+
+CREATE INDEX ON prize_pool_reconciliations (network, token_short_name, recorded_time DESC);
+
+-- migrate:down
+
+DROP TABLE prize_pool_reconciliations;
+
+DROP TABLE ethereum_blocked_winners;
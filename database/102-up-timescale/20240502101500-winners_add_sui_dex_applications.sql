@@ -0,0 +1,31 @@
+-- migrate:up
+
+-- must mirror the `String()` method of common/sui/applications/applications.go
+
+DO $$ BEGIN
+	ALTER TYPE sui_application ADD VALUE 'cetus';
+EXCEPTION
+	WHEN duplicate_object THEN null;
+END $$;
+
+DO $$ BEGIN
+	ALTER TYPE sui_application ADD VALUE 'turbos';
+EXCEPTION
+	WHEN duplicate_object THEN null;
+END $$;
+
+DO $$ BEGIN
+	ALTER TYPE sui_application ADD VALUE 'deepbook';
+EXCEPTION
+	WHEN duplicate_object THEN null;
+END $$;
+
+DO $$ BEGIN
+	ALTER TYPE sui_application ADD VALUE 'aftermath';
+EXCEPTION
+	WHEN duplicate_object THEN null;
+END $$;
+
+-- migrate:down
+
+-- we can't remove the applications from the type properly
@@ -0,0 +1,20 @@
+-- migrate:up
+
+-- user actions and emissions sent again when blocks are replayed are
+-- looked up by their transaction before they're inserted
+
+CREATE INDEX user_actions_network_transaction_hash_index ON user_actions (
+	network,
+	transaction_hash
+);
+
+CREATE INDEX worker_emissions_network_transaction_hash_index ON worker_emissions (
+	network,
+	transaction_hash
+);
+
+-- migrate:down
+
+DROP INDEX worker_emissions_network_transaction_hash_index;
+
+DROP INDEX user_actions_network_transaction_hash_index;
@@ -0,0 +1,10 @@
+-- migrate:up
+
+-- headers (like the trace context and the replay tag) that messages are
+-- sent with, taken from the context they were written in
+
+ALTER TABLE outbox_messages ADD COLUMN headers JSONB;
+
+-- migrate:down
+
+ALTER TABLE outbox_messages DROP COLUMN headers;
@@ -0,0 +1,26 @@
+-- migrate:up
+
+-- rows made from Ethereum logs are rolled back by the hash of their
+-- block when it's orphaned by a chain reorganisation, so transactions
+-- included again in the new chain aren't rolled back too
+
+ALTER TABLE user_actions
+	ADD COLUMN block_hash VARCHAR;
+
+CREATE INDEX user_actions_network_block_hash_index ON user_actions (
+	network,
+	block_hash
+);
+
+ALTER TABLE ethereum_blocked_winners
+	ADD COLUMN block_hash VARCHAR;
+
+-- migrate:down
+
+ALTER TABLE ethereum_blocked_winners
+	DROP COLUMN block_hash;
+
+DROP INDEX user_actions_network_block_hash_index;
+
+ALTER TABLE user_actions
+	DROP COLUMN block_hash;
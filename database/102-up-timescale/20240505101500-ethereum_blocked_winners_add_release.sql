@@ -0,0 +1,15 @@
+-- migrate:up
+
+-- blocked rewards released by hand are paid out as winners, so they're
+-- marked with the transaction that released them and aren't counted as
+-- blocked anymore
+
+ALTER TABLE ethereum_blocked_winners
+	ADD COLUMN release_transaction_hash VARCHAR,
+	ADD COLUMN released_time TIMESTAMP WITHOUT TIME ZONE;
+
+-- migrate:down
+
+ALTER TABLE ethereum_blocked_winners
+	DROP COLUMN released_time,
+	DROP COLUMN release_transaction_hash;
@@ -0,0 +1,54 @@
+-- migrate:up
+
+-- emissions and unpaid winners are rolled back by the hash of their block
+-- when it's orphaned, like user actions, so rows written by the block
+-- that replaced it at the same height are kept
+
+ALTER TABLE worker_emissions
+	ADD COLUMN ethereum_block_hash VARCHAR;
+
+CREATE INDEX worker_emissions_network_ethereum_block_hash_index ON worker_emissions (
+	network,
+	ethereum_block_hash
+);
+
+ALTER TABLE ethereum_pending_winners
+	ADD COLUMN block_hash VARCHAR;
+
+CREATE INDEX ethereum_pending_winners_network_block_hash_index ON ethereum_pending_winners (
+	network,
+	block_hash
+);
+
+ALTER TABLE ethereum_pending_reward_type
+	ADD COLUMN block_hash VARCHAR;
+
+-- blocks orphaned by a chain reorganisation, recorded when they're rolled
+-- back so rows from them that are still moving through the worker
+-- pipeline are rejected when they arrive after the rollback
+
+CREATE TABLE ethereum_orphaned_blocks (
+	network network_blockchain NOT NULL,
+	block_hash VARCHAR NOT NULL,
+	block_number uint256 NOT NULL,
+	recorded_time TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+	PRIMARY KEY (network, block_hash)
+);
+
+-- migrate:down
+
+DROP TABLE ethereum_orphaned_blocks;
+
+ALTER TABLE ethereum_pending_reward_type
+	DROP COLUMN block_hash;
+
+DROP INDEX ethereum_pending_winners_network_block_hash_index;
+
+ALTER TABLE ethereum_pending_winners
+	DROP COLUMN block_hash;
+
+DROP INDEX worker_emissions_network_ethereum_block_hash_index;
+
+ALTER TABLE worker_emissions
+	DROP COLUMN ethereum_block_hash;
@@ -0,0 +1,112 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package outbox
+
+// outbox stores messages in the same database transaction as the rows
+// they describe, so they can be relayed to AMQP with at-least-once
+// delivery once the transaction commits. The table exists in both
+// Postgres and Timescale, so the database to use is passed in.
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+)
+
+const (
+	// Context to use for logging
+	Context = "POSTGRES/OUTBOX"
+
+	// TableOutboxMessages to store messages waiting to be relayed in
+	TableOutboxMessages = "outbox_messages"
+)
+
+// InsertMessage into the outbox using the transaction given, encoding
+// the content as JSON. The message is sent once the transaction commits
+// and Relay picks it up, continuing the trace and the replay in the
+// context given.
+func InsertMessage(ctx context.Context, transaction *sql.Tx, topic string, content interface{}) {
+	contentBytes, err := json.Marshal(content)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Failed to encode an outbox message!"
+			k.Payload = err
+		})
+	}
+
+	InsertMessageBytes(ctx, transaction, topic, contentBytes)
+}
+
+// InsertEnvelope into the outbox using the transaction given, wrapping
+// the content in an envelope with the current version of the schema
+func InsertEnvelope(ctx context.Context, transaction *sql.Tx, topic string, schema *queue.Schema, content interface{}) {
+	envelope, err := queue.NewEnvelope(schema, content)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to wrap an outbox message for topic %#v in an envelope!",
+				topic,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	InsertMessage(ctx, transaction, topic, envelope)
+}
+
+// InsertMessageBytes into the outbox using the transaction given, with
+// the headers the message would be sent with now
+func InsertMessageBytes(ctx context.Context, transaction *sql.Tx, topic string, content []byte) {
+	headers, err := json.Marshal(queue.Headers(ctx))
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Failed to encode the headers of an outbox message!"
+			k.Payload = err
+		})
+	}
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %s (
+			topic,
+			content,
+			headers
+		)
+
+		VALUES (
+			$1,
+			$2,
+			$3
+		);`,
+
+		TableOutboxMessages,
+	)
+
+	_, err = transaction.Exec(statementText, topic, content, string(headers))
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to insert an outbox message for topic %#v!",
+				topic,
+			)
+
+			k.Payload = err
+		})
+	}
+}
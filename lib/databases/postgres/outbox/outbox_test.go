@@ -0,0 +1,205 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package outbox
+
+// these tests write to the outbox table in the database at
+// FLU_POSTGRES_URI, clearing it first
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/postgres"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher that keeps the topic of every message it sends,
+// failing to send the message at failAt if it's set
+type recordingPublisher struct {
+	topics  []string
+	replays []bool
+	failAt  int
+}
+
+func (publisher *recordingPublisher) publish(ctx context.Context, topic string, content []byte) error {
+	if publisher.failAt > 0 && len(publisher.topics)+1 == publisher.failAt {
+		return errors.New("failed to publish")
+	}
+
+	publisher.topics = append(publisher.topics, topic)
+	publisher.replays = append(publisher.replays, queue.IsReplay(ctx))
+
+	return nil
+}
+
+func newTestOutbox(t *testing.T) *sql.DB {
+	database := postgres.Client()
+
+	clearText := fmt.Sprintf(`DELETE FROM %s`, TableOutboxMessages)
+
+	_, err := database.Exec(clearText)
+
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_, _ = database.Exec(clearText)
+	})
+
+	return database
+}
+
+// insertTestMessages in one transaction, with the topics given
+func insertTestMessages(t *testing.T, database *sql.DB, ctx context.Context, topics ...string) {
+	transaction, err := database.Begin()
+
+	require.NoError(t, err)
+
+	for _, topic := range topics {
+		InsertMessage(ctx, transaction, topic, topic)
+	}
+
+	require.NoError(t, transaction.Commit())
+}
+
+func countTestMessages(t *testing.T, database *sql.DB) int {
+	var count int
+
+	err := database.QueryRow(
+		fmt.Sprintf(`SELECT COUNT(*) FROM %s`, TableOutboxMessages),
+	).Scan(&count)
+
+	require.NoError(t, err)
+
+	return count
+}
+
+func TestRelayInOrder(t *testing.T) {
+	database := newTestOutbox(t)
+
+	insertTestMessages(t, database, context.Background(), "test.a", "test.b", "test.c")
+
+	var publisher recordingPublisher
+
+	relayed, err := relayBatch(database, 10, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, relayed)
+	assert.Equal(t, []string{"test.a", "test.b", "test.c"}, publisher.topics)
+	assert.Equal(t, 0, countTestMessages(t, database))
+}
+
+func TestRelayBatchSize(t *testing.T) {
+	database := newTestOutbox(t)
+
+	insertTestMessages(t, database, context.Background(), "test.a", "test.b", "test.c")
+
+	var publisher recordingPublisher
+
+	relayed, err := relayBatch(database, 2, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, 1, countTestMessages(t, database))
+
+	relayed, err = relayBatch(database, 2, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, []string{"test.a", "test.b", "test.c"}, publisher.topics)
+}
+
+func TestRelaySkipsLockedMessages(t *testing.T) {
+	database := newTestOutbox(t)
+
+	insertTestMessages(t, database, context.Background(), "test.a", "test.b", "test.c", "test.d")
+
+	// another relay holding the first two messages
+
+	transaction, err := database.Begin()
+
+	require.NoError(t, err)
+
+	defer transaction.Rollback()
+
+	rows, err := transaction.Query(fmt.Sprintf(
+		`SELECT id FROM %s ORDER BY id LIMIT 2 FOR UPDATE`,
+		TableOutboxMessages,
+	))
+
+	require.NoError(t, err)
+
+	rows.Close()
+
+	var publisher recordingPublisher
+
+	relayed, err := relayBatch(database, 10, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, []string{"test.c", "test.d"}, publisher.topics)
+
+	// the other relay died without sending them
+
+	require.NoError(t, transaction.Rollback())
+
+	relayed, err = relayBatch(database, 10, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, []string{"test.c", "test.d", "test.a", "test.b"}, publisher.topics)
+}
+
+func TestRelayFailedPublishKeepsMessage(t *testing.T) {
+	database := newTestOutbox(t)
+
+	insertTestMessages(t, database, context.Background(), "test.a", "test.b", "test.c")
+
+	failing := recordingPublisher{failAt: 2}
+
+	relayed, err := relayBatch(database, 10, failing.publish)
+
+	assert.Error(t, err)
+
+	// the message that was sent is deleted, the rest are kept
+
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, 2, countTestMessages(t, database))
+
+	var publisher recordingPublisher
+
+	relayed, err = relayBatch(database, 10, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, relayed)
+	assert.Equal(t, []string{"test.b", "test.c"}, publisher.topics)
+}
+
+func TestRelayHeaders(t *testing.T) {
+	database := newTestOutbox(t)
+
+	insertTestMessages(t, database, queue.WithReplay(context.Background()), "test.replay")
+	insertTestMessages(t, database, context.Background(), "test.live")
+
+	var publisher recordingPublisher
+
+	_, err := relayBatch(database, 10, publisher.publish)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"test.replay", "test.live"}, publisher.topics)
+	assert.Equal(t, []bool{true, false}, publisher.replays)
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+)
+
+// publishFunc to send a relayed message with, continuing the trace and
+// the replay in the context
+type publishFunc func(ctx context.Context, topic string, content []byte) error
+
+// Relay messages from the outbox to the queue forever, sending up to
+// batchSize messages at a time in the order they were written (with the
+// headers they were written with) and sleeping for interval when the
+// outbox is empty. Messages are only
+// deleted once the server confirms them, so they can be sent more than
+// once if the process dies in between. Multiple relays can safely run
+// against the same database.
+func Relay(database *sql.DB, batchSize int, interval time.Duration) {
+	for {
+		relayed, err := relayBatch(database, batchSize, queue.TrySendMessageBytesContext)
+
+		if err != nil {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+				k.Message = "Failed to relay a batch of outbox messages, retrying!"
+				k.Payload = err
+			})
+		}
+
+		if relayed > 0 {
+			log.Debug(func(k *log.Log) {
+				k.Context = Context
+				k.Format("Relayed %v outbox messages!", relayed)
+			})
+		}
+
+		// keep going if the batch was full, as there could be more
+
+		if err == nil && relayed == batchSize {
+			continue
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// relayBatch of messages, locking them so other relays skip them and
+// deleting them once they're confirmed, returning the number sent
+func relayBatch(database *sql.DB, batchSize int, publish publishFunc) (int, error) {
+	transaction, err := database.Begin()
+
+	if err != nil {
+		return 0, fmt.Errorf(
+			"failed to begin a transaction! %v",
+			err,
+		)
+	}
+
+	defer transaction.Rollback()
+
+	selectText := fmt.Sprintf(
+		`SELECT id, topic, content, headers
+		FROM %s
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`,
+
+		TableOutboxMessages,
+	)
+
+	rows, err := transaction.Query(selectText, batchSize)
+
+	if err != nil {
+		return 0, fmt.Errorf(
+			"failed to select outbox messages! %v",
+			err,
+		)
+	}
+
+	type message struct {
+		id      int64
+		topic   string
+		content []byte
+		headers map[string]string
+	}
+
+	var messages []message
+
+	for rows.Next() {
+		var (
+			message message
+			headers []byte
+		)
+
+		err := rows.Scan(&message.id, &message.topic, &message.content, &headers)
+
+		if err != nil {
+			rows.Close()
+
+			return 0, fmt.Errorf(
+				"failed to scan an outbox message! %v",
+				err,
+			)
+		}
+
+		// messages written before headers were stored have none
+
+		if headers != nil {
+			if err := json.Unmarshal(headers, &message.headers); err != nil {
+				rows.Close()
+
+				return 0, fmt.Errorf(
+					"failed to decode the headers of outbox message %v! %v",
+					message.id,
+					err,
+				)
+			}
+		}
+
+		messages = append(messages, message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf(
+			"failed to read outbox messages! %v",
+			err,
+		)
+	}
+
+	deleteText := fmt.Sprintf(
+		`DELETE FROM %s WHERE id = $1`,
+
+		TableOutboxMessages,
+	)
+
+	relayed := 0
+
+	for _, message := range messages {
+		ctx := queue.ContextFromHeaders(message.headers)
+
+		if err := publish(ctx, message.topic, message.content); err != nil {
+			// commit what was sent so far so it isn't sent again
+
+			if err_ := transaction.Commit(); err_ != nil {
+				return 0, fmt.Errorf(
+					"failed to commit relayed outbox messages after a publish error %v! %v",
+					err,
+					err_,
+				)
+			}
+
+			return relayed, fmt.Errorf(
+				"failed to publish outbox message %v on topic %#v! %v",
+				message.id,
+				message.topic,
+				err,
+			)
+		}
+
+		if _, err := transaction.Exec(deleteText, message.id); err != nil {
+			return 0, fmt.Errorf(
+				"failed to delete relayed outbox message %v! %v",
+				message.id,
+				err,
+			)
+		}
+
+		relayed++
+	}
+
+	if err := transaction.Commit(); err != nil {
+		return 0, fmt.Errorf(
+			"failed to commit relayed outbox messages! %v",
+			err,
+		)
+	}
+
+	return relayed, nil
+}
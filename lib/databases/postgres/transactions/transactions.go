@@ -0,0 +1,319 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package transactions
+
+// transactions stores the nonces reserved by the Ethereum transaction
+// manager and every attempt it made to get a transaction mined.
+// Store can be given to the manager to use these.
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/postgres"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+)
+
+const (
+	// Context to use for logging
+	Context = "POSTGRES/TRANSACTIONS"
+
+	// TableNonces to store the next nonce for each sender in
+	TableNonces = "ethereum_transaction_nonces"
+
+	// TableAttempts to record each transaction sent in
+	TableAttempts = "ethereum_transaction_attempts"
+)
+
+// ReserveNonce for the address, using the highest of the nonce stored
+// and the pending nonce given by the node, and storing the one after it
+// for the next call. Safe to call from multiple processes.
+func ReserveNonce(chainId uint64, address ethereum.Address, pendingNonce uint64) uint64 {
+	postgresClient := postgres.Client()
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %[1]s (
+			chain_id,
+			address,
+			next_nonce
+		)
+
+		VALUES (
+			$1,
+			$2,
+			$3 + 1
+		)
+
+		ON CONFLICT (chain_id, address) DO UPDATE SET
+			next_nonce = GREATEST(%[1]s.next_nonce, $3) + 1,
+			updated_time = NOW() AT TIME ZONE 'utc'
+
+		RETURNING next_nonce - 1`,
+
+		TableNonces,
+	)
+
+	var nonce uint64
+
+	err := postgresClient.
+		QueryRow(statementText, chainId, address, pendingNonce).
+		Scan(&nonce)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to reserve a nonce for %v on chain %v!",
+				address,
+				chainId,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return nonce
+}
+
+// ReleaseNonce that was reserved but never sent, if no nonce was
+// reserved after it, so the next transaction doesn't leave a gap
+func ReleaseNonce(chainId uint64, address ethereum.Address, nonce uint64) {
+	postgresClient := postgres.Client()
+
+	statementText := fmt.Sprintf(
+		`UPDATE %s
+		SET
+			next_nonce = $3,
+			updated_time = NOW() AT TIME ZONE 'utc'
+		WHERE chain_id = $1 AND address = $2 AND next_nonce = $3 + 1`,
+
+		TableNonces,
+	)
+
+	_, err := postgresClient.Exec(statementText, chainId, address, nonce)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to release nonce %v for %v on chain %v!",
+				nonce,
+				address,
+				chainId,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+// ResetNonce to reserve next for the address to the nonce given, if the
+// nonce stored is past it, once the transactions that used the nonces
+// after it were abandoned or lost
+func ResetNonce(chainId uint64, address ethereum.Address, nonce uint64) {
+	postgresClient := postgres.Client()
+
+	statementText := fmt.Sprintf(
+		`UPDATE %s
+		SET
+			next_nonce = $3,
+			updated_time = NOW() AT TIME ZONE 'utc'
+		WHERE chain_id = $1 AND address = $2 AND next_nonce > $3`,
+
+		TableNonces,
+	)
+
+	result, err := postgresClient.Exec(statementText, chainId, address, nonce)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to reset the nonce for %v on chain %v to %v!",
+				address,
+				chainId,
+				nonce,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	if reset, _ := result.RowsAffected(); reset > 0 {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Reset the nonce for %v on chain %v to %v, the nonces after it were never mined!",
+				address,
+				chainId,
+				nonce,
+			)
+		})
+	}
+}
+
+// InsertAttempt made to send a transaction
+func InsertAttempt(attempt ethereum.TransactionAttempt) {
+	postgresClient := postgres.Client()
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %s (
+			chain_id,
+			address,
+			nonce,
+			attempt,
+			transaction_hash,
+			gas_tip_cap,
+			gas_fee_cap,
+			status
+		)
+
+		VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8
+		)`,
+
+		TableAttempts,
+	)
+
+	_, err := postgresClient.Exec(
+		statementText,
+		attempt.ChainId,
+		attempt.Address,
+		attempt.Nonce,
+		attempt.Attempt,
+		attempt.TransactionHash,
+		attempt.GasTipCap,
+		attempt.GasFeeCap,
+		attempt.Status,
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to insert attempt %v for nonce %v from %v, transaction hash %v!",
+				attempt.Attempt,
+				attempt.Nonce,
+				attempt.Address,
+				attempt.TransactionHash,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+// UpdateAttemptStatus of the attempt with the transaction hash given
+func UpdateAttemptStatus(chainId uint64, transactionHash ethereum.Hash, status ethereum.TransactionAttemptStatus) {
+	postgresClient := postgres.Client()
+
+	statementText := fmt.Sprintf(
+		`UPDATE %s
+		SET
+			status = $3,
+			updated_time = NOW() AT TIME ZONE 'utc'
+		WHERE chain_id = $1 AND transaction_hash = $2`,
+
+		TableAttempts,
+	)
+
+	_, err := postgresClient.Exec(statementText, chainId, transactionHash, status)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to set the status of transaction %v to %v!",
+				transactionHash,
+				status,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+// HasPendingAttempts for transactions sent by the address on any chain,
+// made in the last maxAge, so attempts left pending by a process that
+// died are eventually ignored
+func HasPendingAttempts(address ethereum.Address, maxAge time.Duration) bool {
+	postgresClient := postgres.Client()
+
+	statementText := fmt.Sprintf(
+		`SELECT EXISTS (
+			SELECT 1
+			FROM %s
+			WHERE
+				address = $1
+				AND status = $2
+				AND created_time > (NOW() AT TIME ZONE 'utc') - $3::FLOAT8 * INTERVAL '1 second'
+		)`,
+
+		TableAttempts,
+	)
+
+	var hasPending bool
+
+	err := postgresClient.
+		QueryRow(statementText, address, ethereum.TransactionAttemptPending, maxAge.Seconds()).
+		Scan(&hasPending)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to look up the pending transactions sent by %v!",
+				address,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return hasPending
+}
+
+// Store of the nonces and attempts in Postgres, for the transaction manager
+type Store struct{}
+
+// ReserveNonce using ReserveNonce
+func (Store) ReserveNonce(chainId uint64, address ethereum.Address, pendingNonce uint64) uint64 {
+	return ReserveNonce(chainId, address, pendingNonce)
+}
+
+// ReleaseNonce using ReleaseNonce
+func (Store) ReleaseNonce(chainId uint64, address ethereum.Address, nonce uint64) {
+	ReleaseNonce(chainId, address, nonce)
+}
+
+// ResetNonce using ResetNonce
+func (Store) ResetNonce(chainId uint64, address ethereum.Address, nonce uint64) {
+	ResetNonce(chainId, address, nonce)
+}
+
+// InsertAttempt using InsertAttempt
+func (Store) InsertAttempt(attempt ethereum.TransactionAttempt) {
+	InsertAttempt(attempt)
+}
+
+// UpdateAttemptStatus using UpdateAttemptStatus
+func (Store) UpdateAttemptStatus(chainId uint64, transactionHash ethereum.Hash, status ethereum.TransactionAttemptStatus) {
+	UpdateAttemptStatus(chainId, transactionHash, status)
+}
@@ -0,0 +1,212 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package prices
+
+// prices records the prices resolved by common/ethereum/price for each
+// block, so USD conversions can use the price as of a block
+
+import (
+	"database/sql"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/common/ethereum/price"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+)
+
+const (
+	// Context to use for logging
+	Context = `TIMESCALE/PRICES`
+
+	// TablePriceHistory to record prices in
+	TablePriceHistory = `price_history`
+)
+
+// History to pass to price.NewOracle to look up recorded prices
+type History struct{}
+
+// GetPriceAtBlock calls GetPriceAtBlock
+func (History) GetPriceAtBlock(network_ network.BlockchainNetwork, token string, blockNumber uint64) *price.Price {
+	return GetPriceAtBlock(network_, token, blockNumber)
+}
+
+// GetPriceAtTime calls GetPriceAtTime
+func (History) GetPriceAtTime(network_ network.BlockchainNetwork, token string, time_ time.Time) *price.Price {
+	return GetPriceAtTime(network_, token, time_)
+}
+
+// InsertPrice recorded for a token at a block
+func InsertPrice(network_ network.BlockchainNetwork, token string, blockNumber uint64, recordedTime time.Time, price_ price.Price) {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %s (
+			network,
+			token_short_name,
+			source,
+			price_usd,
+			block_number,
+			updated_time,
+			recorded_time
+		) VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7
+		);`,
+
+		TablePriceHistory,
+	)
+
+	var updatedTime sql.NullTime
+
+	if !price_.UpdatedAt.IsZero() {
+		updatedTime = sql.NullTime{Time: price_.UpdatedAt.UTC(), Valid: true}
+	}
+
+	_, err := timescaleClient.Exec(
+		statementText,
+		network_,
+		token,
+		price_.Source,
+		price_.Usd.FloatString(18),
+		blockNumber,
+		updatedTime,
+		recordedTime.UTC(),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to insert the price of %v at block %v!",
+				token,
+				blockNumber,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+// GetPriceAtBlock returns the last price recorded at or before the block,
+// or nil if there isn't one
+func GetPriceAtBlock(network_ network.BlockchainNetwork, token string, blockNumber uint64) *price.Price {
+	statementText := fmt.Sprintf(
+		`SELECT
+			source,
+			price_usd,
+			updated_time
+
+		FROM %s
+		WHERE
+			network = $1
+			AND token_short_name = $2
+			AND block_number <= $3
+		ORDER BY block_number DESC
+		LIMIT 1`,
+
+		TablePriceHistory,
+	)
+
+	return getPrice(statementText, network_, token, blockNumber)
+}
+
+// GetPriceAtTime returns the last price recorded at or before the time,
+// or nil if there isn't one
+func GetPriceAtTime(network_ network.BlockchainNetwork, token string, time_ time.Time) *price.Price {
+	statementText := fmt.Sprintf(
+		`SELECT
+			source,
+			price_usd,
+			updated_time
+
+		FROM %s
+		WHERE
+			network = $1
+			AND token_short_name = $2
+			AND recorded_time <= $3
+		ORDER BY recorded_time DESC
+		LIMIT 1`,
+
+		TablePriceHistory,
+	)
+
+	return getPrice(statementText, network_, token, time_.UTC())
+}
+
+func getPrice(statementText string, network_ network.BlockchainNetwork, token string, at interface{}) *price.Price {
+	timescaleClient := timescale.Client()
+
+	row := timescaleClient.QueryRow(
+		statementText,
+		network_,
+		token,
+		at,
+	)
+
+	var (
+		source      string
+		priceUsd    string
+		updatedTime sql.NullTime
+	)
+
+	err := row.Scan(&source, &priceUsd, &updatedTime)
+
+	switch err {
+	case sql.ErrNoRows:
+		return nil
+
+	case nil:
+		// nothing
+
+	default:
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to get the price of %v at %v!",
+				token,
+				at,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	usd, ok := new(big.Rat).SetString(priceUsd)
+
+	if !ok {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to decode the recorded price %#v of %v!",
+				priceUsd,
+				token,
+			)
+		})
+	}
+
+	recorded := price.Price{
+		Usd:    usd,
+		Source: source,
+	}
+
+	// times are stored without a time zone in UTC
+
+	if updatedTime.Valid {
+		recorded.UpdatedAt = updatedTime.Time.UTC()
+	}
+
+	return &recorded
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package reconciliation
+
+// reconciliation records the prize pool held on-chain against the
+// winnings promised off-chain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+)
+
+const (
+	// Context to use for logging
+	Context = `TIMESCALE/RECONCILIATION`
+
+	// TablePrizePoolReconciliations to record reconciliations in
+	TablePrizePoolReconciliations = `prize_pool_reconciliations`
+)
+
+// PrizePoolReconciliation of a token, with amounts in the token's units
+type PrizePoolReconciliation struct {
+	Network        network.BlockchainNetwork `json:"network"`
+	TokenShortName string                    `json:"token_short_name"`
+
+	// PrizePool held by the contract
+	PrizePool float64 `json:"prize_pool"`
+
+	// UnpaidWinnings spooled but not yet sent to the contract
+	UnpaidWinnings float64 `json:"unpaid_winnings"`
+
+	// BlockedWinnings the contract refused to pay out
+	BlockedWinnings float64 `json:"blocked_winnings"`
+
+	// SentWinnings the spooler sent to the contract to pay out
+	SentWinnings float64 `json:"sent_winnings"`
+
+	// PaidWinnings the contract paid out
+	PaidWinnings float64 `json:"paid_winnings"`
+
+	// ShortfallDeviation of the winnings owed over the prize pool, as a
+	// fraction of the prize pool
+	ShortfallDeviation float64 `json:"shortfall_deviation"`
+
+	// PayoutDeviation between the winnings sent and the winnings paid
+	// or blocked, as a fraction of the larger
+	PayoutDeviation float64 `json:"payout_deviation"`
+
+	Tolerance       float64 `json:"tolerance"`
+	WithinTolerance bool    `json:"within_tolerance"`
+
+	RecordedTime time.Time `json:"recorded_time"`
+}
+
+// InsertPrizePoolReconciliation that was just made
+func InsertPrizePoolReconciliation(reconciliation PrizePoolReconciliation) {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %s (
+			network,
+			token_short_name,
+			prize_pool,
+			unpaid_winnings,
+			blocked_winnings,
+			sent_winnings,
+			paid_winnings,
+			shortfall_deviation,
+			payout_deviation,
+			tolerance,
+			within_tolerance,
+			recorded_time
+		)
+
+		VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			$10,
+			$11,
+			$12
+		);`,
+
+		TablePrizePoolReconciliations,
+	)
+
+	_, err := timescaleClient.Exec(
+		statementText,
+		reconciliation.Network,
+		reconciliation.TokenShortName,
+		reconciliation.PrizePool,
+		reconciliation.UnpaidWinnings,
+		reconciliation.BlockedWinnings,
+		reconciliation.SentWinnings,
+		reconciliation.PaidWinnings,
+		reconciliation.ShortfallDeviation,
+		reconciliation.PayoutDeviation,
+		reconciliation.Tolerance,
+		reconciliation.WithinTolerance,
+		reconciliation.RecordedTime.UTC(),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to insert the prize pool reconciliation %+v!",
+				reconciliation,
+			)
+
+			k.Payload = err
+		})
+	}
+}
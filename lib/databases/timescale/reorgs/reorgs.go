@@ -0,0 +1,116 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+// reorgs records the blocks orphaned by chain reorganisations, so rows
+// from them that arrive after they were rolled back (still moving through
+// the worker pipeline when the reorg was seen) are rejected
+package reorgs
+
+import (
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+)
+
+const (
+	// Context to use for logging
+	Context = `TIMESCALE/REORGS`
+
+	// TableOrphanedBlocks to record the orphaned blocks in
+	TableOrphanedBlocks = `ethereum_orphaned_blocks`
+)
+
+// InsertOrphanedBlocks in the reorg given, ignoring blocks that were
+// already recorded (every writer rolling back the reorg records them)
+func InsertOrphanedBlocks(reorg ethereum.ChainReorg) {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %s (
+			network,
+			block_hash,
+			block_number
+		)
+
+		VALUES (
+			$1,
+			$2,
+			$3
+		)
+
+		ON CONFLICT DO NOTHING`,
+
+		TableOrphanedBlocks,
+	)
+
+	for _, block := range reorg.OrphanedBlocks {
+		_, err := timescaleClient.Exec(
+			statementText,
+			reorg.Network,
+			block.BlockHash.String(),
+			block.BlockNumber,
+		)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Failed to record the orphaned block %v with hash %v on network %v!",
+					block.BlockNumber.String(),
+					block.BlockHash,
+					reorg.Network,
+				)
+
+				k.Payload = err
+			})
+		}
+	}
+}
+
+// IsBlockOrphaned if the block with the hash given was recorded as
+// orphaned on the network. Rows without the hash of their block (from
+// chains other than Ethereum) are never orphaned
+func IsBlockOrphaned(network_ network.BlockchainNetwork, blockHash string) bool {
+	if blockHash == "" {
+		return false
+	}
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`SELECT EXISTS (
+			SELECT 1
+			FROM %s
+			WHERE
+				network = $1
+				AND block_hash = $2
+		)`,
+
+		TableOrphanedBlocks,
+	)
+
+	row := timescaleClient.QueryRow(statementText, network_, blockHash)
+
+	var orphaned bool
+
+	if err := row.Scan(&orphaned); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to check if the block with hash %v on network %v was orphaned!",
+				blockHash,
+				network_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return orphaned
+}
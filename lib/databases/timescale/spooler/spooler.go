@@ -9,14 +9,17 @@ import (
 	"fmt"
 
 	suiApps "github.com/fluidity-money/fluidity-app/common/sui/applications"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reorgs"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/timescale"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/misc"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
 	"github.com/fluidity-money/fluidity-app/lib/types/winners"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+	"github.com/lib/pq"
 )
 
 const (
@@ -30,6 +33,11 @@ const (
 
 type PendingWinner = winners.PendingWinner
 
+// execer is either the Timescale client or a transaction
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
 // CreatePendingWinners to aggregate a list of pending winners in the given announcement
 func CreatePendingWinners(winner worker.EthereumWinnerAnnouncement, tokenDetails map[applications.UtilityName]token_details.TokenDetails) []PendingWinner {
 	var (
@@ -41,6 +49,7 @@ func CreatePendingWinners(winner worker.EthereumWinnerAnnouncement, tokenDetails
 		network_           = winner.Network
 		hash               = winner.TransactionHash.String()
 		blockNumber        = winner.BlockNumber
+		blockHash          = winner.BlockHash.String()
 		senderAddress      = winner.FromAddress.String()
 		senderWinAmount    = winner.FromWinAmount
 		recipientAddress   = winner.ToAddress.String()
@@ -83,6 +92,7 @@ func CreatePendingWinners(winner worker.EthereumWinnerAnnouncement, tokenDetails
 			UsdWinAmount:    usdWinAmount,
 			Utility:         utility,
 			BlockNumber:     blockNumber,
+			BlockHash:       blockHash,
 			Network:         network_,
 			RewardType:      "send",
 			LogIndex:        logIndex,
@@ -125,6 +135,7 @@ func CreatePendingWinners(winner worker.EthereumWinnerAnnouncement, tokenDetails
 			UsdWinAmount:    usdWinAmount,
 			Utility:         utility,
 			BlockNumber:     blockNumber,
+			BlockHash:       blockHash,
 			Network:         network_,
 			RewardType:      "receive",
 			LogIndex:        logIndex,
@@ -253,6 +264,16 @@ func CreatePendingWinnersSui(transfer worker.TransferWithFee, senderPayouts map[
 func InsertPendingWinners(pendingWinners []PendingWinner) {
 	timescaleClient := timescale.Client()
 
+	insertPendingWinners(timescaleClient, pendingWinners)
+}
+
+// InsertPendingWinnersTx using the transaction given, so the winners can
+// be committed alongside an outbox message
+func InsertPendingWinnersTx(transaction *sql.Tx, pendingWinners []PendingWinner) {
+	insertPendingWinners(transaction, pendingWinners)
+}
+
+func insertPendingWinners(timescaleClient execer, pendingWinners []PendingWinner) {
 	statementText := fmt.Sprintf(
 		`INSERT INTO %s (
 			category,
@@ -268,7 +289,8 @@ func InsertPendingWinners(pendingWinners []PendingWinner) {
 			reward_type,
 			log_index,
 			application,
-			reward_tier
+			reward_tier,
+			block_hash
 		)
 
 		VALUES (
@@ -285,7 +307,8 @@ func InsertPendingWinners(pendingWinners []PendingWinner) {
 			$11,
 			$12,
 			$13,
-			$14
+			$14,
+			NULLIF($15, '')
 		);`,
 
 		TablePendingWinners,
@@ -308,8 +331,25 @@ func InsertPendingWinners(pendingWinners []PendingWinner) {
 			logIndex        = pendingWinner.LogIndex
 			application     = pendingWinner.Application
 			rewardTier      = pendingWinner.RewardTier
+			blockHash       = pendingWinner.BlockHash
 		)
 
+		// the block was rolled back before the winner got here
+
+		if reorgs.IsBlockOrphaned(network_, blockHash) {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Not inserting a pending winner in transaction %v from the orphaned block %v!",
+					hash,
+					blockHash,
+				)
+			})
+
+			continue
+		}
+
 		_, err := timescaleClient.Exec(
 			statementText,
 			category,
@@ -326,6 +366,7 @@ func InsertPendingWinners(pendingWinners []PendingWinner) {
 			logIndex,
 			application,
 			rewardTier,
+			blockHash,
 		)
 
 		if err != nil {
@@ -397,11 +438,50 @@ func UnpaidWinningsForCategory(network_ network.BlockchainNetwork, token token_d
 	}
 }
 
-func GetAndRemoveRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+// SentWinningsForCategory to sum the USD value of pending winners that
+// were sent to the contract to be paid out
+func SentWinningsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) float64 {
 	timescaleClient := timescale.Client()
 
-	shortName := token.TokenShortName
+	statementText := fmt.Sprintf(
+		`SELECT
+			SUM(usd_win_amount)
+
+		FROM %s
+		WHERE
+			network = $1
+			AND category = $2
+			AND reward_sent = true
+		`,
+
+		TablePendingWinners,
+	)
+
+	row := timescaleClient.QueryRow(
+		statementText,
+		network_,
+		token.TokenShortName,
+	)
+
+	var total sql.NullFloat64
 
+	if err := row.Scan(&total); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to get sent winnings for token %s!",
+				token.TokenShortName,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return total.Float64
+}
+
+func GetAndRemoveRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
 	statementText := fmt.Sprintf(
 		`UPDATE %s
 			SET reward_sent = true
@@ -424,6 +504,107 @@ func GetAndRemoveRewardsForCategory(network_ network.BlockchainNetwork, token to
 		TablePendingWinners,
 	)
 
+	return queryRewardsForCategory(statementText, network_, token)
+}
+
+// GetUnsentRewardsForCategory without marking them as sent, for dry runs
+// to see what GetAndRemoveRewardsForCategory would return
+func GetUnsentRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+	statementText := fmt.Sprintf(
+		`SELECT
+			network,
+			token_short_name,
+			token_decimals,
+			transaction_hash,
+			address,
+			win_amount,
+			block_number,
+			utility_name,
+			category
+		FROM %s
+		WHERE
+			reward_sent = false
+			AND network = $1
+			AND category = $2
+		;`,
+
+		TablePendingWinners,
+	)
+
+	return queryRewardsForCategory(statementText, network_, token)
+}
+
+// RestoreRewardsForCategory that were marked as sent by
+// GetAndRemoveRewardsForCategory but couldn't be paid out
+func RestoreRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails, rewards []worker.EthereumReward) {
+	timescaleClient := timescale.Client()
+
+	var (
+		shortName = token.TokenShortName
+
+		transactionHashes = make([]string, len(rewards))
+		addresses         = make([]string, len(rewards))
+		utilities         = make([]string, len(rewards))
+		blockNumbers      = make([]string, len(rewards))
+	)
+
+	for i, reward := range rewards {
+		transactionHashes[i] = reward.TransactionHash.String()
+		addresses[i] = reward.Winner.String()
+		utilities[i] = string(reward.Utilityname)
+		blockNumbers[i] = reward.BlockNumber.String()
+	}
+
+	statementText := fmt.Sprintf(
+		`UPDATE %s AS pending
+			SET reward_sent = false
+		FROM UNNEST($3::VARCHAR[], $4::VARCHAR[], $5::VARCHAR[], $6::NUMERIC[])
+			AS restored (transaction_hash, address, utility_name, block_number)
+		WHERE
+			pending.reward_sent = true
+			AND pending.network = $1
+			AND pending.category = $2
+			AND pending.transaction_hash = restored.transaction_hash
+			AND pending.address = restored.address
+			AND pending.utility_name = restored.utility_name
+			AND pending.block_number = restored.block_number
+		;`,
+
+		TablePendingWinners,
+	)
+
+	_, err := timescaleClient.Exec(
+		statementText,
+		network_,
+		shortName,
+		pq.Array(transactionHashes),
+		pq.Array(addresses),
+		pq.Array(utilities),
+		pq.Array(blockNumbers),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to restore %v unpaid winners for token %s!",
+				len(rewards),
+				shortName,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+// queryRewardsForCategory using the statement given, which should
+// return the columns of a reward
+func queryRewardsForCategory(statementText string, network_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+	timescaleClient := timescale.Client()
+
+	shortName := token.TokenShortName
+
 	rows, err := timescaleClient.Query(
 		statementText,
 		network_,
@@ -435,7 +616,7 @@ func GetAndRemoveRewardsForCategory(network_ network.BlockchainNetwork, token to
 			k.Context = Context
 
 			k.Format(
-				"Failed to fetch and mark winners as sent for token %s!",
+				"Failed to fetch the unsent winners for token %s!",
 				shortName,
 			)
 
@@ -635,3 +816,84 @@ func GetPendingRecipientBySend(network_ network.BlockchainNetwork, sendTransacti
 
 	return pendingWinner
 }
+
+// RollbackPendingWinners for transactions in blocks that were orphaned by a chain
+// reorganisation, returning the number removed. Winners that were
+// already paid out are kept, since the payout can't be undone. Winners
+// recorded without the hash of their block are matched by the block
+// number and transaction hash instead. The orphaned blocks are recorded
+// so winners from them that arrive later are rejected
+func RollbackPendingWinners(reorg ethereum.ChainReorg) int64 {
+	reorgs.InsertOrphanedBlocks(reorg)
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`DELETE FROM %s
+		WHERE
+			network = $1
+			AND reward_sent = false
+			AND (
+				block_hash = ANY($2)
+				OR (
+					block_hash IS NULL
+					AND (block_number, transaction_hash) IN (
+						SELECT * FROM UNNEST($3::NUMERIC[], $4::VARCHAR[])
+					)
+				)
+			)`,
+
+		TablePendingWinners,
+	)
+
+	blockNumbers, transactionHashes := reorg.OrphanedTransactions()
+
+	result, err := timescaleClient.Exec(
+		statementText,
+		reorg.Network,
+		pq.Array(reorg.BlockHashes()),
+		pq.Array(blockNumbers),
+		pq.Array(transactionHashes),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to remove pending winners in %v orphaned blocks on network %v!",
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed
+}
+
+// Store of the pending winners in Timescale, for the common spooler
+type Store struct{}
+
+// UnpaidWinningsForCategory using UnpaidWinningsForCategory
+func (Store) UnpaidWinningsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) float64 {
+	return UnpaidWinningsForCategory(network_, token)
+}
+
+// GetAndRemoveRewardsForCategory using GetAndRemoveRewardsForCategory
+func (Store) GetAndRemoveRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+	return GetAndRemoveRewardsForCategory(network_, token)
+}
+
+// GetUnsentRewardsForCategory using GetUnsentRewardsForCategory
+func (Store) GetUnsentRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails) []worker.EthereumReward {
+	return GetUnsentRewardsForCategory(network_, token)
+}
+
+// RestoreRewardsForCategory using RestoreRewardsForCategory
+func (Store) RestoreRewardsForCategory(network_ network.BlockchainNetwork, token token_details.TokenDetails, rewards []worker.EthereumReward) {
+	RestoreRewardsForCategory(network_, token, rewards)
+}
@@ -8,11 +8,14 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reorgs"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 	"github.com/fluidity-money/fluidity-app/lib/types/misc"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
 	"github.com/fluidity-money/fluidity-app/lib/types/user-actions"
+	"github.com/lib/pq"
 )
 
 const (
@@ -25,8 +28,23 @@ const (
 
 type UserAction = user_actions.UserAction
 
-// InsertUserAction to the database, setting time to the current timestamp
+// InsertUserAction to the database, setting time to the current
+// timestamp, unless its block was already rolled back as orphaned
 func InsertUserAction(userAction UserAction) {
+	if reorgs.IsBlockOrphaned(userAction.Network, userAction.BlockHash) {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Not inserting a user action in transaction %v from the orphaned block %v!",
+				userAction.TransactionHash,
+				userAction.BlockHash,
+			)
+		})
+
+		return
+	}
+
 	timescaleClient := timescale.Client()
 
 	var (
@@ -50,7 +68,8 @@ func InsertUserAction(userAction UserAction) {
 			token_decimals,
 			solana_sender_owner_address,
 			solana_recipient_owner_address,
-			application
+			application,
+			block_hash
 		)
 
 		VALUES (
@@ -68,7 +87,8 @@ func InsertUserAction(userAction UserAction) {
 			$12,
 			$13,
 			$14,
-			$15
+			$15,
+			NULLIF($16, '')
 		)`,
 
 		TableUserActions,
@@ -91,6 +111,7 @@ func InsertUserAction(userAction UserAction) {
 		userAction.SolanaSenderOwnerAddress,
 		userAction.SolanaRecipientOwnerAddress,
 		userAction.Application,
+		userAction.BlockHash,
 	)
 
 	if err != nil {
@@ -102,6 +123,61 @@ func InsertUserAction(userAction UserAction) {
 	}
 }
 
+// UserActionExists if a user action with the same type, transaction,
+// log, addresses and amount was already inserted, so user actions sent
+// again when blocks are replayed can be skipped
+func UserActionExists(userAction UserAction) bool {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`SELECT EXISTS (
+			SELECT 1
+			FROM %s
+			WHERE
+				network = $1
+				AND type = $2
+				AND transaction_hash = $3
+				AND log_index = $4
+				AND swap_in = $5
+				AND sender_address = $6
+				AND recipient_address = $7
+				AND amount = $8
+		)`,
+
+		TableUserActions,
+	)
+
+	row := timescaleClient.QueryRow(
+		statementText,
+		userAction.Network,
+		userAction.Type,
+		userAction.TransactionHash,
+		userAction.LogIndex,
+		userAction.SwapIn,
+		userAction.SenderAddress,
+		userAction.RecipientAddress,
+		userAction.Amount,
+	)
+
+	var exists bool
+
+	if err := row.Scan(&exists); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to check if a user action in transaction %v on network %v exists!",
+				userAction.TransactionHash,
+				userAction.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return exists
+}
+
 // GetUserActionsWithSenderAddressOrRecipientAddress, returning results
 // that either contain the address specified as the recipient or the sender
 func GetUserActionsWithSenderAddressOrRecipientAddress(network network.BlockchainNetwork, address string, limit int) []UserAction {
@@ -472,3 +548,54 @@ func GetUserActionByLogIndex(network network.BlockchainNetwork, transactionHash
 
 	return &userAction
 }
+
+// RollbackUserActions made in blocks that were orphaned by a chain reorganisation,
+// returning the number of user actions removed. User actions for
+// transactions included again in the new chain are kept. User actions
+// recorded without the hash of their block are matched by the hashes of
+// the transactions in the orphaned blocks instead. The orphaned blocks
+// are recorded so user actions from them that arrive later are rejected
+func RollbackUserActions(reorg ethereum.ChainReorg) int64 {
+	reorgs.InsertOrphanedBlocks(reorg)
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`DELETE FROM %s
+		WHERE
+			network = $1
+			AND (
+				block_hash = ANY($2)
+				OR (block_hash IS NULL AND transaction_hash = ANY($3))
+			)`,
+
+		TableUserActions,
+	)
+
+	_, transactionHashes := reorg.OrphanedTransactions()
+
+	result, err := timescaleClient.Exec(
+		statementText,
+		reorg.Network,
+		pq.Array(reorg.BlockHashes()),
+		pq.Array(transactionHashes),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to remove user actions in %v orphaned blocks on network %v!",
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed
+}
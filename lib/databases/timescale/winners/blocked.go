@@ -0,0 +1,252 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package winners
+
+// blocked winners are rewards that the contract refused to pay out
+// until they're released by hand, when they're paid out as winners
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reorgs"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/fluidity-money/fluidity-app/lib/types/winners"
+	"github.com/lib/pq"
+)
+
+// TableBlockedWinners to record rewards blocked by the contract
+const TableBlockedWinners = `ethereum_blocked_winners`
+
+type BlockedWinner = winners.BlockedWinner
+
+// InsertBlockedWinner seen on-chain, unless its block was already rolled
+// back as orphaned
+func InsertBlockedWinner(blockedWinner BlockedWinner) {
+	if reorgs.IsBlockOrphaned(blockedWinner.Network, blockedWinner.BlockHash) {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Not inserting a blocked winner in transaction %v from the orphaned block %v!",
+				blockedWinner.RewardTransactionHash,
+				blockedWinner.BlockHash,
+			)
+		})
+
+		return
+	}
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`INSERT INTO %s (
+			network,
+			token_short_name,
+			token_decimals,
+			contract_address,
+			reward_transaction_hash,
+			winner_address,
+			winning_amount,
+			batch_first_block,
+			batch_last_block,
+			block_hash
+		)
+
+		VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8,
+			$9,
+			NULLIF($10, '')
+		);`,
+
+		TableBlockedWinners,
+	)
+
+	_, err := timescaleClient.Exec(
+		statementText,
+		blockedWinner.Network,
+		blockedWinner.Token.TokenShortName,
+		blockedWinner.Token.TokenDecimals,
+		blockedWinner.EthereumContractAddress,
+		blockedWinner.RewardTransactionHash,
+		blockedWinner.WinnerAddress,
+		blockedWinner.WinningAmount,
+		blockedWinner.BatchFirstBlock,
+		blockedWinner.BatchLastBlock,
+		blockedWinner.BlockHash,
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to insert blocked winner %+v!",
+				blockedWinner,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+// ReleaseBlockedWinners that were released by the transaction given,
+// returning the number of blocked winners marked released
+func ReleaseBlockedWinners(network_ network.BlockchainNetwork, tokenShortName string, firstBlock, lastBlock misc.BigInt, winnerAddress ethereum.Address, releaseTransactionHash ethereum.Hash) int64 {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`UPDATE %s
+		SET
+			release_transaction_hash = $6,
+			released_time = NOW() AT TIME ZONE 'utc'
+		WHERE
+			network = $1
+			AND token_short_name = $2
+			AND batch_first_block = $3
+			AND batch_last_block = $4
+			AND winner_address = $5
+			AND release_transaction_hash IS NULL`,
+
+		TableBlockedWinners,
+	)
+
+	result, err := timescaleClient.Exec(
+		statementText,
+		network_,
+		tokenShortName,
+		firstBlock,
+		lastBlock,
+		winnerAddress,
+		releaseTransactionHash,
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to release the blocked winners for %v of token %v on network %v!",
+				winnerAddress,
+				tokenShortName,
+				network_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	released, _ := result.RowsAffected()
+
+	return released
+}
+
+// SumBlockedWinnings for a token in its units (scaled by its decimals),
+// excluding the winnings that were released since
+func SumBlockedWinnings(network_ network.BlockchainNetwork, tokenShortName string) float64 {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`SELECT
+			SUM(winning_amount / 10 ^ token_decimals)
+
+		FROM %s
+		WHERE
+			network = $1
+			AND token_short_name = $2
+			AND release_transaction_hash IS NULL
+		`,
+
+		TableBlockedWinners,
+	)
+
+	row := timescaleClient.QueryRow(
+		statementText,
+		network_,
+		tokenShortName,
+	)
+
+	var total sql.NullFloat64
+
+	if err := row.Scan(&total); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to sum the blocked winnings for token %v on network %v!",
+				tokenShortName,
+				network_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return total.Float64
+}
+
+// RollbackBlockedWinners with rewards blocked in blocks that were orphaned
+// by a chain reorganisation, returning the number of blocked winners
+// removed. Blocked winners released since aren't rolled back. Blocked
+// winners recorded without the hash of their block are matched by the
+// hashes of the transactions in the orphaned blocks instead. The orphaned
+// blocks are recorded so blocked winners from them that arrive later are
+// rejected
+func RollbackBlockedWinners(reorg ethereum.ChainReorg) int64 {
+	reorgs.InsertOrphanedBlocks(reorg)
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`DELETE FROM %s
+		WHERE
+			network = $1
+			AND release_transaction_hash IS NULL
+			AND (
+				block_hash = ANY($2)
+				OR (block_hash IS NULL AND reward_transaction_hash = ANY($3))
+			)`,
+
+		TableBlockedWinners,
+	)
+
+	_, transactionHashes := reorg.OrphanedTransactions()
+
+	result, err := timescaleClient.Exec(
+		statementText,
+		reorg.Network,
+		pq.Array(reorg.BlockHashes()),
+		pq.Array(transactionHashes),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to remove blocked winners in %v orphaned blocks on network %v!",
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed
+}
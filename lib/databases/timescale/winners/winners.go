@@ -13,6 +13,7 @@ import (
 
 	solApps "github.com/fluidity-money/fluidity-app/common/solana/applications"
 	suiApps "github.com/fluidity-money/fluidity-app/common/sui/applications"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reorgs"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/timescale"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
@@ -23,6 +24,7 @@ import (
 	token_details "github.com/fluidity-money/fluidity-app/lib/types/token-details"
 	"github.com/fluidity-money/fluidity-app/lib/types/winners"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
+	"github.com/lib/pq"
 )
 
 const (
@@ -439,8 +441,23 @@ func GetAndRemovePendingRewardData(net network.BlockchainNetwork, token token_de
 }
 
 // Ethereum Specific
-// InsertPendingRewardType to store the reward type and application of a pending win
-func InsertPendingRewardType(net network.BlockchainNetwork, token token_details.TokenDetails, blockNumber uint64, sendTransactionHash ethereum.Hash, senderAddress ethereum.Address, senderWinAmount map[ethApps.UtilityName]worker.Payout, recipientAddress ethereum.Address, recipientWinAmount map[ethApps.UtilityName]worker.Payout, application Application, rewardTier int, logIndex misc.BigInt, tokenDetails map[applications.UtilityName]token_details.TokenDetails) {
+// InsertPendingRewardType to store the reward type and application of a
+// pending win, unless its block was already rolled back as orphaned
+func InsertPendingRewardType(net network.BlockchainNetwork, token token_details.TokenDetails, blockNumber uint64, blockHash ethereum.Hash, sendTransactionHash ethereum.Hash, senderAddress ethereum.Address, senderWinAmount map[ethApps.UtilityName]worker.Payout, recipientAddress ethereum.Address, recipientWinAmount map[ethApps.UtilityName]worker.Payout, application Application, rewardTier int, logIndex misc.BigInt, tokenDetails map[applications.UtilityName]token_details.TokenDetails) {
+
+	if reorgs.IsBlockOrphaned(net, blockHash.String()) {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Not inserting the pending reward type with hash %v from the orphaned block %v!",
+				sendTransactionHash,
+				blockHash,
+			)
+		})
+
+		return
+	}
 
 	timescaleClient := timescale.Client()
 
@@ -456,7 +473,8 @@ func InsertPendingRewardType(net network.BlockchainNetwork, token token_details.
 			win_amount,
 			utility_name,
 			reward_tier,
-			log_index
+			log_index,
+			block_hash
 		)
 
 		VALUES (
@@ -470,7 +488,8 @@ func InsertPendingRewardType(net network.BlockchainNetwork, token token_details.
 			$8,
 			$9,
 			$10,
-			$11
+			$11,
+			NULLIF($12, '')
 		);`,
 
 		TablePendingRewardType,
@@ -512,6 +531,7 @@ func InsertPendingRewardType(net network.BlockchainNetwork, token token_details.
 			utility,
 			rewardTier,
 			logIndex,
+			blockHash.String(),
 		)
 
 		if err != nil {
@@ -564,6 +584,7 @@ func InsertPendingRewardType(net network.BlockchainNetwork, token token_details.
 			utility,
 			rewardTier,
 			logIndex,
+			blockHash.String(),
 		)
 
 		if err != nil {
@@ -641,3 +662,102 @@ func CountWinnersForDateAndWinningAmount(network network.BlockchainNetwork, toke
 
 	return winnersCount, awardedAmount
 }
+
+// SumWinnings paid out for a token in its units (scaled by its decimals)
+func SumWinnings(network_ network.BlockchainNetwork, tokenShortName string) float64 {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`SELECT
+			SUM(winning_amount / 10 ^ token_decimals)
+
+		FROM %s
+		WHERE
+			network = $1
+			AND token_short_name = $2
+		`,
+
+		TableWinners,
+	)
+
+	row := timescaleClient.QueryRow(
+		statementText,
+		network_,
+		tokenShortName,
+	)
+
+	var total sql.NullFloat64
+
+	if err := row.Scan(&total); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to sum the winnings for token %v on network %v!",
+				tokenShortName,
+				network_,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return total.Float64
+}
+
+// RollbackPendingRewardTypes for sends in blocks that were orphaned by a chain
+// reorganisation, returning the number of rows removed. Reward types are
+// removed once they're paid out, so only unpaid reward types are touched.
+// Reward types recorded without the hash of their block are matched by
+// the block number and send transaction hash instead. The orphaned blocks
+// are recorded so reward types from them that arrive later are rejected
+func RollbackPendingRewardTypes(reorg ethereum.ChainReorg) int64 {
+	reorgs.InsertOrphanedBlocks(reorg)
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`DELETE FROM %s
+		WHERE
+			network = $1
+			AND (
+				block_hash = ANY($2)
+				OR (
+					block_hash IS NULL
+					AND (block_number, send_transaction_hash) IN (
+						SELECT * FROM UNNEST($3::NUMERIC[], $4::VARCHAR[])
+					)
+				)
+			)`,
+
+		TablePendingRewardType,
+	)
+
+	blockNumbers, transactionHashes := reorg.OrphanedTransactions()
+
+	result, err := timescaleClient.Exec(
+		statementText,
+		reorg.Network,
+		pq.Array(reorg.BlockHashes()),
+		pq.Array(blockNumbers),
+		pq.Array(transactionHashes),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to remove pending reward types in %v orphaned blocks on network %v!",
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed
+}
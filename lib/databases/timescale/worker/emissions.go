@@ -5,15 +5,38 @@
 package worker
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reorgs"
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/lib/pq"
 )
 
+// InsertEmissions to the database, unless the block of the emission was
+// already rolled back as orphaned
 func InsertEmissions(emission Emission) {
+	network_ := network.BlockchainNetwork(emission.Network)
+
+	if reorgs.IsBlockOrphaned(network_, emission.EthereumBlockHash) {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Not inserting an emission in transaction %v from the orphaned block %v!",
+				emission.TransactionHash,
+				emission.EthereumBlockHash,
+			)
+		})
+
+		return
+	}
+
 	timescaleClient := timescale.Client()
 
 	var (
@@ -57,6 +80,27 @@ func InsertEmissions(emission Emission) {
 		specialPoolOptions         = emission.SpecialPoolOptions
 	)
 
+	// fees tracked by applications without their own column, stored
+	// as json if there are any
+
+	var otherEthAppFees *string
+
+	if len(ethAppFees.Other) > 0 {
+		otherEthAppFees_, err := json.Marshal(ethAppFees.Other)
+
+		if err != nil {
+			log.Fatal(func(k *log.Log) {
+				k.Context = Context
+				k.Message = "Failed to encode the other application fees!"
+				k.Payload = err
+			})
+		}
+
+		otherEthAppFeesString := string(otherEthAppFees_)
+
+		otherEthAppFees = &otherEthAppFeesString
+	}
+
 	var testingBallsString strings.Builder
 
 	for i, ball := range naiveIsWinning.TestingBalls {
@@ -207,7 +251,12 @@ func InsertEmissions(emission Emission) {
 
 			special_pool_options_payout_freq_override,
 			special_pool_options_delta_weight_override,
-			special_pool_options_winning_classes_override
+			special_pool_options_winning_classes_override,
+
+			ethereum_app_fees_other,
+
+			ethereum_block_number,
+			ethereum_block_hash
 		)
 
 		VALUES (
@@ -347,7 +396,12 @@ func InsertEmissions(emission Emission) {
 
 			$111,
 			$112,
-			$113
+			$113,
+
+			$114,
+
+			$115,
+			NULLIF($116, '')
 		);`,
 
 		TableEmissions,
@@ -493,6 +547,11 @@ func InsertEmissions(emission Emission) {
 		specialPoolOptions.PayoutFreqOverride,
 		specialPoolOptions.DeltaWeightOverride,
 		specialPoolOptions.WinningClassesOverride,
+
+		otherEthAppFees,
+
+		emission.EthereumBlockNumber,
+		emission.EthereumBlockHash,
 	)
 
 	if err != nil {
@@ -503,3 +562,108 @@ func InsertEmissions(emission Emission) {
 		})
 	}
 }
+
+// EmissionExists if an emission for a transfer of the same token between
+// the same addresses in the transaction was already inserted, so
+// emissions sent again when blocks are replayed can be skipped
+func EmissionExists(emission Emission) bool {
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`SELECT EXISTS (
+			SELECT 1
+			FROM %s
+			WHERE
+				network = $1
+				AND transaction_hash = $2
+				AND token_short_name = $3
+				AND sender_address = $4
+				AND recipient_address = $5
+		)`,
+
+		TableEmissions,
+	)
+
+	row := timescaleClient.QueryRow(
+		statementText,
+		emission.Network,
+		emission.TransactionHash,
+		emission.TokenDetails.TokenShortName,
+		emission.SenderAddress,
+		emission.RecipientAddress,
+	)
+
+	var exists bool
+
+	if err := row.Scan(&exists); err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to check if an emission in transaction %v on network %v exists!",
+				emission.TransactionHash,
+				emission.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return exists
+}
+
+// RollbackEmissions for transfers in blocks that were orphaned by a chain
+// reorganisation, returning the number of emissions removed. Emissions
+// recorded without the hash of their block are matched by the block
+// number and transaction hash instead. The orphaned blocks are recorded
+// so emissions from them that arrive later are rejected
+func RollbackEmissions(reorg ethereum.ChainReorg) int64 {
+	reorgs.InsertOrphanedBlocks(reorg)
+
+	timescaleClient := timescale.Client()
+
+	statementText := fmt.Sprintf(
+		`DELETE FROM %s
+		WHERE
+			network = $1
+			AND (
+				ethereum_block_hash = ANY($2)
+				OR (
+					ethereum_block_hash IS NULL
+					AND (ethereum_block_number, transaction_hash) IN (
+						SELECT * FROM UNNEST($3::NUMERIC[], $4::VARCHAR[])
+					)
+				)
+			)`,
+
+		TableEmissions,
+	)
+
+	blockNumbers, transactionHashes := reorg.OrphanedTransactions()
+
+	result, err := timescaleClient.Exec(
+		statementText,
+		reorg.Network,
+		pq.Array(reorg.BlockHashes()),
+		pq.Array(blockNumbers),
+		pq.Array(transactionHashes),
+	)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to remove emissions in %v orphaned blocks on network %v!",
+				len(reorg.OrphanedBlocks),
+				reorg.Network,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	removed, _ := result.RowsAffected()
+
+	return removed
+}
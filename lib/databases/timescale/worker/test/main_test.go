@@ -11,7 +11,10 @@ import (
 	"testing"
 
 	"github.com/fluidity-money/fluidity-app/lib/timescale"
+	"github.com/fluidity-money/fluidity-app/lib/types/ethereum"
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
 	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/reorgs"
 	"github.com/fluidity-money/fluidity-app/lib/databases/timescale/worker"
 )
 
@@ -39,3 +42,85 @@ func TestEmptyInsertion(t *testing.T) {
 		t.Fatalf("failed to exec deletion transaction: %v", err)
 	}
 }
+
+func TestRollbackEmissionsByBlockHash(t *testing.T) {
+	var (
+		orphanedHash    = "0xorphaned123123"
+		replacementHash = "0xreplacement123123"
+		testingHash     = "0xtestingrollback123123"
+		network_        = network.NetworkArbitrum
+		blockNumber     = misc.BigIntFromInt64(10)
+	)
+
+	timescale := timescale.Client()
+
+	defer func() {
+		_, _ = timescale.Exec(
+			fmt.Sprintf("DELETE FROM %s WHERE transaction_hash = $1", worker.TableEmissions),
+			testingHash,
+		)
+
+		_, _ = timescale.Exec(
+			fmt.Sprintf("DELETE FROM %s WHERE block_hash = $1", reorgs.TableOrphanedBlocks),
+			orphanedHash,
+		)
+	}()
+
+	emission := func(blockHash string) worker.Emission {
+		return worker.Emission{
+			Network:             string(network_),
+			TransactionHash:     testingHash,
+			EthereumBlockNumber: blockNumber,
+			EthereumBlockHash:   blockHash,
+		}
+	}
+
+	countEmissions := func(blockHash string) int {
+		row := timescale.QueryRow(
+			fmt.Sprintf(
+				"SELECT COUNT(*) FROM %s WHERE transaction_hash = $1 AND ethereum_block_hash = $2",
+				worker.TableEmissions,
+			),
+			testingHash,
+			blockHash,
+		)
+
+		var count int
+
+		if err := row.Scan(&count); err != nil {
+			t.Fatalf("failed to count the emissions: %v", err)
+		}
+
+		return count
+	}
+
+	// the orphaned block and the block that replaced it at the same height
+
+	worker.InsertEmissions(emission(orphanedHash))
+	worker.InsertEmissions(emission(replacementHash))
+
+	removed := worker.RollbackEmissions(ethereum.ChainReorg{
+		Network: network_,
+		OrphanedBlocks: []ethereum.OrphanedBlock{{
+			BlockHash:   ethereum.HashFromString(orphanedHash),
+			BlockNumber: blockNumber,
+		}},
+	})
+
+	if removed != 1 {
+		t.Fatalf("expected 1 emission rolled back, got %v", removed)
+	}
+
+	if count := countEmissions(replacementHash); count != 1 {
+		t.Fatalf("expected the emission from the new block to be kept, got %v", count)
+	}
+
+	// an emission from the orphaned block arriving after the rollback is
+	// rejected
+
+	worker.InsertEmissions(emission(orphanedHash))
+
+	if count := countEmissions(orphanedHash); count != 0 {
+		t.Fatalf("expected the late emission to be rejected, got %v", count)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package leader
+
+import (
+	"context"
+	"errors"
+
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+)
+
+// Lead blocks until this replica holds the lease with the name given,
+// releasing it when shutting down so a standby takes over straight
+// away. If the lease is lost, the process exits so it restarts as a
+// standby, since whatever it was doing isn't safe anymore
+func Lead(store Store, name string) *Lease {
+	elector := newElector(store, name)
+
+	lease, err := elector.Acquire(lifecycle.ShutdownContext())
+
+	// shutting down before taking the lease, wait for the process to exit
+
+	if errors.Is(err, context.Canceled) && lifecycle.ShuttingDown() {
+		lifecycle.Wait()
+	}
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Format("Failed to acquire lease %v!", name)
+			k.Payload = err
+		})
+	}
+
+	follow(lease)
+
+	return lease
+}
+
+// TryLead takes the lease with the name given if nobody else holds it,
+// for cron jobs that should skip a run if another replica is still
+// running. Returns false if another replica holds the lease. The lease
+// should be released once the work is done
+func TryLead(store Store, name string) (*Lease, bool) {
+	elector := newElector(store, name)
+
+	lease, err := elector.TryAcquire(context.Background())
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Format("Failed to acquire lease %v!", name)
+			k.Payload = err
+		})
+	}
+
+	if lease == nil {
+		return nil, false
+	}
+
+	follow(lease)
+
+	return lease, true
+}
+
+func newElector(store Store, name string) *Elector {
+	elector, err := New(store, name, DefaultConfig())
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Format("Failed to create the elector for lease %v!", name)
+			k.Payload = err
+		})
+	}
+
+	return elector
+}
+
+// follow the lease by releasing it when shutting down, and exiting if
+// it's lost
+func follow(lease *Lease) {
+	lifecycle.RegisterDrain("lease "+lease.Name(), lease.Release)
+
+	go func() {
+		<-lease.Done()
+
+		if errors.Is(lease.Err(), ErrLeaseLost) {
+			log.Fatal(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Lost lease %v, exiting to stand by!",
+					lease.Name(),
+				)
+			})
+		}
+	}()
+}
@@ -0,0 +1,476 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package leader
+
+// leader elects a single replica of a microservice that's only safe to
+// run once, with the other replicas standing by to take over. The
+// leader holds a lease that's renewed in the background and released
+// when shutting down, so a standby takes over without waiting for it to
+// expire. Every lease comes with a fencing token larger than any given
+// out before for the same name, so writes made by a leader that lost its
+// lease without noticing (a long GC pause, a partition) can be rejected
+// (see state.SetFenced). Leases are usually stored in Redis with
+// state.LeaseStore.
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+)
+
+const (
+	// Context to use for logging
+	Context = `LEADER`
+
+	// EnvLeaseSeconds to hold a lease for before it expires if it isn't
+	// renewed. Leases are renewed every third of this
+	EnvLeaseSeconds = `FLU_LEADER_LEASE_SECONDS`
+)
+
+// defaultLeaseSeconds to hold leases for if EnvLeaseSeconds isn't set
+const defaultLeaseSeconds = "30"
+
+var (
+	// ErrLeaseLost if the lease couldn't be renewed before it expired,
+	// or somebody else took it
+	ErrLeaseLost = errors.New("lease lost")
+
+	// ErrLeaseReleased once the lease was handed off with Release
+	ErrLeaseReleased = errors.New("lease released")
+)
+
+type (
+	// Store of leases, shared between every replica
+	Store interface {
+		// Acquire the lease for the holder for ttl, returning false if
+		// somebody else holds it
+		Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+
+		// Renew the lease for another ttl, returning false if the
+		// holder doesn't hold it anymore
+		Renew(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+
+		// Release the lease, returning false if the holder didn't
+		// hold it
+		Release(ctx context.Context, name, holder string) (bool, error)
+
+		// NextToken to fence writes with, larger than every token
+		// returned before for the name
+		NextToken(ctx context.Context, name string) (uint64, error)
+	}
+
+	// Config of the lease timings
+	Config struct {
+		// TTL of the lease if it isn't renewed
+		TTL time.Duration
+
+		// RenewInterval to renew the lease at, less than the TTL
+		RenewInterval time.Duration
+
+		// RetryInterval to try to take the lease at while standing by
+		RetryInterval time.Duration
+	}
+
+	// Elector campaigns for the lease with the name given
+	Elector struct {
+		store  Store
+		name   string
+		holder string
+		config Config
+	}
+
+	// Lease held by this replica, renewed until it's lost or released
+	Lease struct {
+		name   string
+		holder string
+		token  uint64
+		store  Store
+		config Config
+
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		mu  sync.Mutex
+		err error
+
+		stopOnce sync.Once
+
+		// stop is closed to stop renewing the lease
+		stop chan struct{}
+
+		// stopped is closed once the lease isn't being renewed
+		stopped chan struct{}
+	}
+)
+
+// DefaultConfig to hold leases with, using FLU_LEADER_LEASE_SECONDS
+func DefaultConfig() Config {
+	leaseSeconds_ := util.GetEnvOrDefault(EnvLeaseSeconds, defaultLeaseSeconds)
+
+	leaseSeconds, err := strconv.Atoi(leaseSeconds_)
+
+	if err != nil || leaseSeconds < 3 {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to parse %#v from %#v, it should be at least 3!",
+				leaseSeconds_,
+				EnvLeaseSeconds,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	ttl := time.Duration(leaseSeconds) * time.Second
+
+	return Config{
+		TTL:           ttl,
+		RenewInterval: ttl / 3,
+		RetryInterval: ttl / 6,
+	}
+}
+
+// New elector for the lease with the name given, holding it as the
+// hostname and a random suffix
+func New(store Store, name string, config Config) (*Elector, error) {
+	if config.TTL <= 0 || config.RenewInterval <= 0 || config.RetryInterval <= 0 {
+		return nil, fmt.Errorf(
+			"lease timings for %v must be positive! %+v",
+			name,
+			config,
+		)
+	}
+
+	if config.RenewInterval >= config.TTL {
+		return nil, fmt.Errorf(
+			"lease %v would expire before being renewed, renew interval %v >= ttl %v",
+			name,
+			config.RenewInterval,
+			config.TTL,
+		)
+	}
+
+	holder, err := newHolder()
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to create the holder for lease %v! %v",
+			name,
+			err,
+		)
+	}
+
+	elector := &Elector{
+		store:  store,
+		name:   name,
+		holder: holder,
+		config: config,
+	}
+
+	return elector, nil
+}
+
+// Holder this replica holds leases as
+func (elector *Elector) Holder() string {
+	return elector.holder
+}
+
+// TryAcquire the lease, returning nil if another replica holds it
+func (elector *Elector) TryAcquire(ctx context.Context) (*Lease, error) {
+	var (
+		store  = elector.store
+		name   = elector.name
+		holder = elector.holder
+		config = elector.config
+	)
+
+	// the lease expires a ttl after it was requested at the earliest
+
+	acquiredAt := time.Now()
+
+	acquired, err := store.Acquire(ctx, name, holder, config.TTL)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to acquire lease %v! %v",
+			name,
+			err,
+		)
+	}
+
+	if !acquired {
+		return nil, nil
+	}
+
+	token, err := store.NextToken(ctx, name)
+
+	if err != nil {
+		_, _ = store.Release(context.Background(), name, holder)
+
+		return nil, fmt.Errorf(
+			"failed to get the fencing token for lease %v! %v",
+			name,
+			err,
+		)
+	}
+
+	leaseCtx, cancel := context.WithCancel(context.Background())
+
+	lease := &Lease{
+		name:    name,
+		holder:  holder,
+		token:   token,
+		store:   store,
+		config:  config,
+		ctx:     leaseCtx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go lease.renew(acquiredAt.Add(config.TTL))
+
+	log.App(func(k *log.Log) {
+		k.Context = Context
+
+		k.Format(
+			"Acquired lease %v as %v with fencing token %v!",
+			name,
+			holder,
+			token,
+		)
+	})
+
+	return lease, nil
+}
+
+// Acquire the lease, standing by until the replica holding it gives
+// it up or the context is done
+func (elector *Elector) Acquire(ctx context.Context) (*Lease, error) {
+	loggedStandby := false
+
+	for {
+		lease, err := elector.TryAcquire(ctx)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if lease != nil {
+			return lease, nil
+		}
+
+		if !loggedStandby {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Lease %v is held by another replica, standing by as %v!",
+					elector.name,
+					elector.holder,
+				)
+			})
+
+			loggedStandby = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-time.After(elector.config.RetryInterval):
+		}
+	}
+}
+
+// Name of the lease
+func (lease *Lease) Name() string {
+	return lease.name
+}
+
+// Token to fence writes made while holding the lease with
+func (lease *Lease) Token() uint64 {
+	return lease.token
+}
+
+// Context that's cancelled once the lease is lost or released
+func (lease *Lease) Context() context.Context {
+	return lease.ctx
+}
+
+// Done is closed once the lease is lost or released
+func (lease *Lease) Done() <-chan struct{} {
+	return lease.ctx.Done()
+}
+
+// Err is ErrLeaseLost or ErrLeaseReleased once the lease ended, or nil
+// if it's still held
+func (lease *Lease) Err() error {
+	lease.mu.Lock()
+	defer lease.mu.Unlock()
+
+	return lease.err
+}
+
+// Release the lease so a standby can take over straight away. Work done
+// under the lease should be finished first. Does nothing if the lease
+// was already lost or released
+func (lease *Lease) Release(ctx context.Context) error {
+	lease.stopOnce.Do(func() {
+		close(lease.stop)
+	})
+
+	<-lease.stopped
+
+	if !lease.end(ErrLeaseReleased) {
+		return nil
+	}
+
+	released, err := lease.store.Release(ctx, lease.name, lease.holder)
+
+	if err != nil {
+		return fmt.Errorf(
+			"failed to release lease %v! %v",
+			lease.name,
+			err,
+		)
+	}
+
+	log.App(func(k *log.Log) {
+		k.Context = Context
+
+		if released {
+			k.Format("Released lease %v!", lease.name)
+		} else {
+			k.Format("Lease %v expired before it was released!", lease.name)
+		}
+	})
+
+	return nil
+}
+
+// renew the lease every renew interval until it's released, or it
+// expires without being renewed
+func (lease *Lease) renew(expiry time.Time) {
+	defer close(lease.stopped)
+
+	var (
+		store  = lease.store
+		name   = lease.name
+		holder = lease.holder
+		config = lease.config
+	)
+
+	ticker := time.NewTicker(config.RenewInterval)
+
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lease.stop:
+			return
+
+		case <-ticker.C:
+		}
+
+		renewedAt := time.Now()
+
+		if !renewedAt.Before(expiry) {
+			lease.end(ErrLeaseLost)
+			return
+		}
+
+		ctx, cancel := context.WithDeadline(context.Background(), expiry)
+
+		renewed, err := store.Renew(ctx, name, holder, config.TTL)
+
+		cancel()
+
+		switch {
+		case err != nil:
+			// keep trying until the lease expires, the store might
+			// come back in time
+
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Failed to renew lease %v, it expires at %v!",
+					name,
+					expiry,
+				)
+
+				k.Payload = err
+			})
+
+		case !renewed:
+			lease.end(ErrLeaseLost)
+			return
+
+		default:
+			expiry = renewedAt.Add(config.TTL)
+		}
+	}
+}
+
+// end the lease with the error given, cancelling its context. Returns
+// false if it had already ended
+func (lease *Lease) end(err error) bool {
+	lease.mu.Lock()
+
+	if lease.err != nil {
+		lease.mu.Unlock()
+		return false
+	}
+
+	lease.err = err
+
+	lease.mu.Unlock()
+
+	lease.cancel()
+
+	if err == ErrLeaseLost {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Lost lease %v with fencing token %v!",
+				lease.name,
+				lease.token,
+			)
+		})
+	}
+
+	return true
+}
+
+// newHolder to identify this replica with, the hostname with a random
+// suffix so replicas sharing a hostname don't share leases
+func newHolder() (string, error) {
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	suffix := make([]byte, 8)
+
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	return hostname + "-" + hex.EncodeToString(suffix), nil
+}
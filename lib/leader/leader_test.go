@@ -0,0 +1,314 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore of leases, expiring them like Redis would
+type memoryStore struct {
+	mu       sync.Mutex
+	holders  map[string]string
+	expiries map[string]time.Time
+	tokens   map[string]uint64
+
+	// renewErr to fail renewals with
+	renewErr error
+}
+
+var testConfig = Config{
+	TTL:           200 * time.Millisecond,
+	RenewInterval: 20 * time.Millisecond,
+	RetryInterval: 10 * time.Millisecond,
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		holders:  make(map[string]string),
+		expiries: make(map[string]time.Time),
+		tokens:   make(map[string]uint64),
+	}
+}
+
+func (store *memoryStore) holder(name string) string {
+	if time.Now().After(store.expiries[name]) {
+		delete(store.holders, name)
+	}
+
+	return store.holders[name]
+}
+
+func (store *memoryStore) Acquire(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.holder(name) != "" {
+		return false, nil
+	}
+
+	store.holders[name] = holder
+	store.expiries[name] = time.Now().Add(ttl)
+
+	return true, nil
+}
+
+func (store *memoryStore) Renew(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.renewErr != nil {
+		return false, store.renewErr
+	}
+
+	if store.holder(name) != holder {
+		return false, nil
+	}
+
+	store.expiries[name] = time.Now().Add(ttl)
+
+	return true, nil
+}
+
+func (store *memoryStore) Release(_ context.Context, name, holder string) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.holder(name) != holder {
+		return false, nil
+	}
+
+	delete(store.holders, name)
+
+	return true, nil
+}
+
+func (store *memoryStore) NextToken(_ context.Context, name string) (uint64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.tokens[name]++
+
+	return store.tokens[name], nil
+}
+
+// steal the lease for another holder, as if it expired and was taken
+func (store *memoryStore) steal(name string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.holders[name] = "thief"
+	store.expiries[name] = time.Now().Add(time.Hour)
+}
+
+func (store *memoryStore) failRenewals(err error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.renewErr = err
+}
+
+func newTestElector(t *testing.T, store Store) *Elector {
+	elector, err := New(store, "test", testConfig)
+
+	require.NoError(t, err)
+
+	return elector
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	config := testConfig
+
+	config.RenewInterval = config.TTL
+
+	_, err := New(newMemoryStore(), "test", config)
+
+	assert.Error(t, err)
+
+	_, err = New(newMemoryStore(), "test", Config{})
+
+	assert.Error(t, err)
+}
+
+func TestTryAcquireHeld(t *testing.T) {
+	store := newMemoryStore()
+
+	leader, err := newTestElector(t, store).TryAcquire(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, leader)
+
+	defer leader.Release(context.Background())
+
+	standby, err := newTestElector(t, store).TryAcquire(context.Background())
+
+	require.NoError(t, err)
+	assert.Nil(t, standby)
+}
+
+func TestLeaseRenewed(t *testing.T) {
+	store := newMemoryStore()
+
+	lease, err := newTestElector(t, store).Acquire(context.Background())
+
+	require.NoError(t, err)
+
+	defer lease.Release(context.Background())
+
+	// outlive a few ttls, the lease should still be held
+
+	time.Sleep(3 * testConfig.TTL)
+
+	assert.NoError(t, lease.Err())
+
+	standby, err := newTestElector(t, store).TryAcquire(context.Background())
+
+	require.NoError(t, err)
+	assert.Nil(t, standby)
+}
+
+func TestStandbyTakesOverOnRelease(t *testing.T) {
+	store := newMemoryStore()
+
+	leader, err := newTestElector(t, store).Acquire(context.Background())
+
+	require.NoError(t, err)
+
+	standbyLeases := make(chan *Lease, 1)
+
+	go func() {
+		lease, err := newTestElector(t, store).Acquire(context.Background())
+
+		assert.NoError(t, err)
+
+		standbyLeases <- lease
+	}()
+
+	select {
+	case <-standbyLeases:
+		t.Fatal("standby took the lease while it was held")
+
+	case <-time.After(5 * testConfig.RetryInterval):
+	}
+
+	require.NoError(t, leader.Release(context.Background()))
+
+	assert.ErrorIs(t, leader.Err(), ErrLeaseReleased)
+
+	select {
+	case <-leader.Done():
+	default:
+		t.Fatal("lease context wasn't cancelled on release")
+	}
+
+	// the standby shouldn't have to wait for the lease to expire
+
+	select {
+	case standby := <-standbyLeases:
+		assert.Greater(t, standby.Token(), leader.Token())
+
+		standby.Release(context.Background())
+
+	case <-time.After(testConfig.TTL / 2):
+		t.Fatal("standby didn't take over after the lease was released")
+	}
+}
+
+func TestLeaseLostToAnotherHolder(t *testing.T) {
+	store := newMemoryStore()
+
+	lease, err := newTestElector(t, store).Acquire(context.Background())
+
+	require.NoError(t, err)
+
+	store.steal("test")
+
+	select {
+	case <-lease.Done():
+	case <-time.After(testConfig.TTL):
+		t.Fatal("lease wasn't lost")
+	}
+
+	assert.ErrorIs(t, lease.Err(), ErrLeaseLost)
+
+	// releasing a lost lease shouldn't take it from the new holder
+
+	require.NoError(t, lease.Release(context.Background()))
+
+	assert.Equal(t, "thief", store.holder("test"))
+}
+
+func TestLeaseLostWhenRenewalsFail(t *testing.T) {
+	store := newMemoryStore()
+
+	lease, err := newTestElector(t, store).Acquire(context.Background())
+
+	require.NoError(t, err)
+
+	store.failRenewals(errors.New("connection refused"))
+
+	// renewals failing shouldn't end the lease straight away
+
+	time.Sleep(testConfig.TTL / 2)
+
+	assert.NoError(t, lease.Err())
+
+	select {
+	case <-lease.Done():
+	case <-time.After(2 * testConfig.TTL):
+		t.Fatal("lease wasn't lost once it expired")
+	}
+
+	assert.ErrorIs(t, lease.Err(), ErrLeaseLost)
+}
+
+func TestAcquireContextDone(t *testing.T) {
+	store := newMemoryStore()
+
+	leader, err := newTestElector(t, store).Acquire(context.Background())
+
+	require.NoError(t, err)
+
+	defer leader.Release(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*testConfig.RetryInterval)
+
+	defer cancel()
+
+	_, err = newTestElector(t, store).Acquire(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFencingTokensIncrease(t *testing.T) {
+	var (
+		store   = newMemoryStore()
+		elector = newTestElector(t, store)
+		last    uint64
+	)
+
+	for i := 0; i < 3; i++ {
+		lease, err := elector.Acquire(context.Background())
+
+		require.NoError(t, err)
+
+		assert.Greater(t, lease.Token(), last)
+
+		last = lease.Token()
+
+		require.NoError(t, lease.Release(context.Background()))
+
+		// releasing twice does nothing
+
+		require.NoError(t, lease.Release(context.Background()))
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package lifecycle
+
+// lifecycle shuts microservices down gracefully and answers health
+// probes. Library packages register readiness checks, liveness checks
+// and drain functions in their init, and a microservice opts in by
+// calling Start at the top of main. On SIGTERM, the process stops being
+// ready, every drain function is run within FLU_LIFECYCLE_DRAIN_SECONDS,
+// the logging server is flushed and the process exits.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+)
+
+const (
+	// Context to use for logging
+	Context = `LIFECYCLE`
+
+	// EnvListenAddr to serve the readiness and liveness probes on. The
+	// probes aren't served if it isn't set
+	EnvListenAddr = `FLU_LIFECYCLE_LISTEN_ADDR`
+
+	// EnvDrainSeconds to wait for drain functions to finish when
+	// shutting down before exiting anyway
+	EnvDrainSeconds = `FLU_LIFECYCLE_DRAIN_SECONDS`
+
+	// PathReadiness to serve the readiness probe on
+	PathReadiness = `/readyz`
+
+	// PathLiveness to serve the liveness probe on
+	PathLiveness = `/livez`
+)
+
+// defaultDrainSeconds to wait for drain functions if EnvDrainSeconds
+// isn't set
+const defaultDrainSeconds = "30"
+
+type (
+	// Check returns an error if a dependency isn't healthy
+	Check func() error
+
+	// Drain stops taking new work and waits for work in progress to
+	// finish, returning early if the context is done
+	Drain func(ctx context.Context) error
+
+	check struct {
+		name  string
+		check Check
+	}
+
+	drain struct {
+		name  string
+		drain Drain
+	}
+)
+
+var (
+	mu sync.Mutex
+
+	readinessChecks []check
+	livenessChecks  []check
+	drains          []drain
+
+	started      bool
+	shuttingDown bool
+
+	// shutdownCtx is cancelled when shutdown begins
+	shutdownCtx, cancelShutdown = context.WithCancel(context.Background())
+
+	// exit is replaced in tests
+	exit = os.Exit
+)
+
+// RegisterReadiness check, failing the readiness probe if it returns an
+// error. Readiness checks should be cheap, they're run on every probe
+func RegisterReadiness(name string, check_ Check) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	readinessChecks = append(readinessChecks, check{name, check_})
+}
+
+// RegisterLiveness check, failing the liveness probe if it returns an
+// error, which should only happen if the process needs to be restarted
+func RegisterLiveness(name string, check_ Check) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	livenessChecks = append(livenessChecks, check{name, check_})
+}
+
+// RegisterDrain function to run when shutting down, in the order
+// they're registered
+func RegisterDrain(name string, drain_ Drain) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	drains = append(drains, drain{name, drain_})
+}
+
+// Start handling SIGTERM and SIGINT by shutting down gracefully, and
+// serve the probes if FLU_LIFECYCLE_LISTEN_ADDR is set. Does nothing if
+// it was already called
+func Start() {
+	mu.Lock()
+
+	if started {
+		mu.Unlock()
+		return
+	}
+
+	started = true
+
+	mu.Unlock()
+
+	var (
+		listenAddr    = os.Getenv(EnvListenAddr)
+		drainSeconds_ = util.GetEnvOrDefault(EnvDrainSeconds, defaultDrainSeconds)
+	)
+
+	drainSeconds, err := strconv.Atoi(drainSeconds_)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to parse %#v from %#v!",
+				drainSeconds_,
+				EnvDrainSeconds,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	drainTimeout := time.Duration(drainSeconds) * time.Second
+
+	signals := make(chan os.Signal, 1)
+
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	go func() {
+		signal_ := <-signals
+
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Received %v, shutting down within %v!",
+				signal_,
+				drainTimeout,
+			)
+		})
+
+		Shutdown(drainTimeout)
+	}()
+
+	if listenAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(PathReadiness, probeHandler(Ready))
+	mux.HandleFunc(PathLiveness, probeHandler(Live))
+
+	go func() {
+		err := http.ListenAndServe(listenAddr, mux)
+
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to serve probes on %#v!",
+				listenAddr,
+			)
+
+			k.Payload = err
+		})
+	}()
+}
+
+// ShuttingDown is true once shutdown has begun
+func ShuttingDown() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return shuttingDown
+}
+
+// ShutdownContext that's cancelled once shutdown begins
+func ShutdownContext() context.Context {
+	return shutdownCtx
+}
+
+// Wait for the process to exit if it's shutting down, so callers that
+// stopped consuming don't return from main before draining is done.
+// Returns immediately if the process isn't shutting down
+func Wait() {
+	if !ShuttingDown() {
+		return
+	}
+
+	select {}
+}
+
+// Ready returns an error if the service is shutting down or any
+// readiness check fails
+func Ready() error {
+	if ShuttingDown() {
+		return fmt.Errorf("shutting down")
+	}
+
+	mu.Lock()
+
+	checks := readinessChecks
+
+	mu.Unlock()
+
+	return runChecks(checks)
+}
+
+// Live returns an error if any liveness check fails
+func Live() error {
+	mu.Lock()
+
+	checks := livenessChecks
+
+	mu.Unlock()
+
+	return runChecks(checks)
+}
+
+// Shutdown by running every drain function with the timeout given,
+// running the callbacks registered with log.RegisterShutdown, then
+// exiting. Exits with an error if draining didn't finish in time
+func Shutdown(timeout time.Duration) {
+	err := runDrains(timeout)
+
+	exitCode := 0
+
+	if err != nil {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Failed to drain cleanly, exiting anyway!"
+			k.Payload = err
+		})
+
+		exitCode = 1
+	} else {
+		log.App(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Drained, exiting!"
+		})
+	}
+
+	log.Shutdown()
+
+	exit(exitCode)
+}
+
+// runDrains after marking the service as shutting down, returning an
+// error if any failed or didn't finish before the timeout
+func runDrains(timeout time.Duration) error {
+	mu.Lock()
+
+	shuttingDown = true
+
+	drains_ := drains
+
+	mu.Unlock()
+
+	cancelShutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	defer cancel()
+
+	var failures []string
+
+	for _, drain := range drains_ {
+		done := make(chan error, 1)
+
+		go func(drain Drain) {
+			done <- drain(ctx)
+		}(drain.drain)
+
+		select {
+		case err := <-done:
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%v: %v", drain.name, err))
+			}
+
+		case <-ctx.Done():
+			failures = append(failures, fmt.Sprintf("%v: %v", drain.name, ctx.Err()))
+		}
+	}
+
+	if len(failures) != 0 {
+		return fmt.Errorf(
+			"failed to drain! %v",
+			strings.Join(failures, ", "),
+		)
+	}
+
+	return nil
+}
+
+func runChecks(checks []check) error {
+	var failures []string
+
+	for _, check := range checks {
+		if err := check.check(); err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v", check.name, err))
+		}
+	}
+
+	if len(failures) != 0 {
+		return fmt.Errorf("%v", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
+
+func probeHandler(probe func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := probe(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			_, _ = fmt.Fprintln(w, err)
+
+			return
+		}
+
+		_, _ = fmt.Fprintln(w, "ok")
+	}
+}
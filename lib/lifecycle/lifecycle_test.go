@@ -0,0 +1,127 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reset every check and drain that was registered, and stop shutting
+// down, once the test finishes
+func reset(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		readinessChecks = nil
+		livenessChecks = nil
+		drains = nil
+		shuttingDown = false
+
+		shutdownCtx, cancelShutdown = context.WithCancel(context.Background())
+	})
+}
+
+func TestReady(t *testing.T) {
+	reset(t)
+
+	RegisterReadiness("redis", func() error { return nil })
+
+	assert.NoError(t, Ready())
+
+	RegisterReadiness("postgres", func() error {
+		return fmt.Errorf("connection refused")
+	})
+
+	assert.EqualError(t, Ready(), "postgres: connection refused")
+
+	// liveness is separate to readiness
+
+	assert.NoError(t, Live())
+}
+
+func TestRunDrains(t *testing.T) {
+	reset(t)
+
+	var drained []string
+
+	RegisterDrain("first", func(ctx context.Context) error {
+		drained = append(drained, "first")
+		return nil
+	})
+
+	RegisterDrain("second", func(ctx context.Context) error {
+		drained = append(drained, "second")
+		return nil
+	})
+
+	assert.NoError(t, runDrains(time.Second))
+
+	assert.Equal(t, []string{"first", "second"}, drained)
+
+	assert.True(t, ShuttingDown())
+	assert.Error(t, Ready())
+	assert.Error(t, ShutdownContext().Err())
+}
+
+func TestRunDrainsTimeout(t *testing.T) {
+	reset(t)
+
+	RegisterDrain("stuck", func(ctx context.Context) error {
+		select {}
+	})
+
+	err := runDrains(10 * time.Millisecond)
+
+	assert.ErrorContains(t, err, "stuck")
+}
+
+func TestShutdownExitCode(t *testing.T) {
+	reset(t)
+
+	exitCode := make(chan int, 1)
+
+	previousExit := exit
+
+	exit = func(code int) {
+		exitCode <- code
+	}
+
+	defer func() {
+		exit = previousExit
+	}()
+
+	RegisterDrain("failing", func(ctx context.Context) error {
+		return fmt.Errorf("failed to cancel")
+	})
+
+	Shutdown(time.Second)
+
+	assert.Equal(t, 1, <-exitCode)
+}
+
+func TestProbeHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+
+	probeHandler(func() error { return nil })(recorder, httptest.NewRequest("GET", PathLiveness, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+
+	probeHandler(func() error {
+		return fmt.Errorf("amqp handlers: stalled")
+	})(recorder, httptest.NewRequest("GET", PathLiveness, nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "stalled")
+}
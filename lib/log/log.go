@@ -4,7 +4,13 @@
 
 package log
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/metrics"
+	"github.com/fluidity-money/fluidity-app/lib/trace"
+)
 
 const (
 	// EnvDebug is the environment variable that's tested to see if debugging
@@ -24,10 +30,22 @@ const (
 	SentryExitTime = 2 * time.Second
 )
 
+// logMessagesCount of app and fatal messages, debug messages aren't
+// counted
+var logMessagesCount = metrics.NewCounterVec(
+	"log_messages_total",
+	"Messages logged with log.App and log.Fatal, by level.",
+	"level",
+)
+
 type Log struct {
 	Context string      `json:"context"`
 	Message string      `json:"message"`
 	Payload interface{} `json:"payload"`
+
+	// Span to tag the message with, usually taken from the context of
+	// the message being handled with trace.SpanFromContext
+	Span *trace.Span `json:"-"`
 }
 
 func DebugEnabled() bool {
@@ -49,9 +67,43 @@ func Debugf(message string, format ...interface{}) {
 }
 
 func App(k func(k *Log)) {
+	logMessagesCount.Inc(LoggingLevelApp)
+
 	logCooking(loggingLevelApp, k)
 }
 
 func Fatal(k func(k *Log)) {
+	logMessagesCount.Inc(LoggingLevelFatal)
+
 	logCooking(loggingLevelFatal, k)
 }
+
+// DebugContext to log with Debug, tagging the message with the span in
+// the context of the message being handled
+func DebugContext(ctx context.Context, k func(k *Log)) {
+	Debug(withSpan(ctx, k))
+}
+
+// AppContext to log with App, tagging the message with the span in the
+// context of the message being handled
+func AppContext(ctx context.Context, k func(k *Log)) {
+	App(withSpan(ctx, k))
+}
+
+// FatalContext to log with Fatal, tagging the message with the span in
+// the context of the message being handled
+func FatalContext(ctx context.Context, k func(k *Log)) {
+	Fatal(withSpan(ctx, k))
+}
+
+// withSpan from the context set on the log before k is called, so k can
+// still set it itself
+func withSpan(ctx context.Context, k func(k *Log)) func(k *Log) {
+	span := trace.SpanFromContext(ctx)
+
+	return func(log *Log) {
+		log.Span = span
+
+		k(log)
+	}
+}
@@ -5,9 +5,14 @@
 package log
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/trace"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // TestFatal to see if the process exits, running in an `exec` subprocess
@@ -27,3 +32,25 @@ func TestFatal(t *testing.T) {
 
 	t.Fatalf("process ran with err %v, want exit status 1", err)
 }
+
+func TestWithSpan(t *testing.T) {
+	span := trace.RemoteSpan(trace.SpanContext{})
+
+	ctx := trace.ContextWithSpan(context.Background(), span)
+
+	var log Log
+
+	withSpan(ctx, func(k *Log) {
+		assert.Equal(t, span, k.Span)
+	})(&log)
+
+	assert.Equal(t, span, log.Span)
+
+	// the span can still be set by the caller
+
+	withSpan(ctx, func(k *Log) {
+		k.Span = nil
+	})(&log)
+
+	assert.Nil(t, log.Span)
+}
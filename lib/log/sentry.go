@@ -51,5 +51,10 @@ func sentryLogError(invocation, workerId string, log *log) {
 		"invocation": invocation,
 	}
 
+	if log.traceId != "" {
+		event.Tags["trace-id"] = log.traceId
+		event.Tags["span-id"] = log.spanId
+	}
+
 	_ = sentry.CaptureEvent(event)
 }
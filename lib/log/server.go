@@ -10,6 +10,8 @@ import (
 	"math/rand"
 	"os"
 	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/trace"
 )
 
 // LoggingServerContext is the context that we use in the error messages
@@ -33,12 +35,17 @@ type log struct {
 	context, message string
 	payload          interface{}
 	reply            chan error
+
+	// traceId and spanId of the span the message was logged with, empty
+	// if there wasn't one
+	traceId, spanId string
 }
 
 var (
 	loggingServer               = make(chan *log)
 	loggingAreWeDebuggingServer = make(chan bool)
 	shutdownChan                = make(chan func())
+	shutdownRequestChan         = make(chan chan bool)
 
 	loggingStream = os.Stderr
 )
@@ -76,7 +83,7 @@ func processExit(dieFast bool) {
 	os.Exit(1)
 }
 
-func printLoggingMessage(stream io.WriteCloser, time time.Time, workerId, level, context, message string, payload interface{}) {
+func printLoggingMessage(stream io.WriteCloser, time time.Time, workerId, traceId, spanId, level, context, message string, payload interface{}) {
 	var payload_ string
 
 	if context == "" {
@@ -87,11 +94,21 @@ func printLoggingMessage(stream io.WriteCloser, time time.Time, workerId, level,
 		payload_ = fmt.Sprintf("%v", payload)
 	}
 
+	// messages logged while handling a traced message include the ids
+	// after the worker id
+
+	var traceTag string
+
+	if traceId != "" {
+		traceTag = fmt.Sprintf(" [%v:%v]", traceId, spanId)
+	}
+
 	fmt.Fprintf(
 		stream,
-		"[%v] [%v] [%s:%s] %s %v\n",
+		"[%v] [%v]%s [%s:%s] %s %v\n",
 		time,
 		workerId,
+		traceTag,
 		level,
 		context,
 		message,
@@ -122,6 +139,22 @@ func startLoggingServer(debugEnabled, dieFast, silentEnabled bool, processInvoca
 		case shutdownFunc := <-shutdownChan:
 			shutdownCallbacks = append(shutdownCallbacks, shutdownFunc)
 
+		case reply := <-shutdownRequestChan:
+			// the process is exiting cleanly, so every callback is run
+			// once and nothing else should be logged
+
+			for _, shutdown := range shutdownCallbacks {
+				shutdown()
+			}
+
+			shutdownCallbacks = nil
+
+			trace.Flush()
+
+			sentryExit()
+
+			reply <- true
+
 		case log := <-loggingServer:
 			var (
 				context = log.context
@@ -129,6 +162,8 @@ func startLoggingServer(debugEnabled, dieFast, silentEnabled bool, processInvoca
 				payload = log.payload
 				reply   = log.reply
 				level   = log.level
+				traceId = log.traceId
+				spanId  = log.spanId
 
 				logString string
 
@@ -160,6 +195,8 @@ func startLoggingServer(debugEnabled, dieFast, silentEnabled bool, processInvoca
 					loggingStream,
 					now,
 					workerId,
+					traceId,
+					spanId,
 					logString,
 					context,
 					message,
@@ -176,6 +213,8 @@ func startLoggingServer(debugEnabled, dieFast, silentEnabled bool, processInvoca
 					shutdown()
 				}
 
+				trace.Flush()
+
 				processExit(dieFast)
 			}
 
@@ -188,9 +227,22 @@ func (k *Log) Format(message string, format ...interface{}) {
 	k.Message = fmt.Sprintf(message, format...)
 }
 
-func logMessage(level int, context, message string, payload interface{}) {
+func logMessage(level int, context, message string, payload interface{}, span *trace.Span) {
 	reply := make(chan error)
-	log := log{level, context, message, payload, reply}
+
+	log := log{
+		level:   level,
+		context: context,
+		message: message,
+		payload: payload,
+		reply:   reply,
+	}
+
+	if span != nil {
+		log.traceId = span.Context.TraceId.String()
+		log.spanId = span.Context.SpanId.String()
+	}
+
 	loggingServer <- &log
 	_ = <-reply
 }
@@ -198,10 +250,22 @@ func logMessage(level int, context, message string, payload interface{}) {
 func logCooking(level int, k func(k *Log)) {
 	log := new(Log)
 	k(log)
-	logMessage(level, log.Context, log.Message, log.Payload)
+	logMessage(level, log.Context, log.Message, log.Payload, log.Span)
 }
 
 // RegisterShutdown to register a callback to occur when a process exits fatally
+// or is shut down with Shutdown
 func RegisterShutdown(callback func()) {
 	shutdownChan <- callback
 }
+
+// Shutdown by running every callback registered with RegisterShutdown
+// and flushing anything waiting to be sent to Sentry or the trace
+// exporter, for a process that's about to exit without an error
+func Shutdown() {
+	reply := make(chan bool)
+
+	shutdownRequestChan <- reply
+
+	<-reply
+}
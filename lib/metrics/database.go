@@ -0,0 +1,175 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package metrics
+
+// database times queries made with database/sql by wrapping the
+// driver's connections, so callers of lib/postgres and lib/timescale
+// don't need to change
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+)
+
+const (
+	operationQuery = "query"
+	operationExec  = "exec"
+)
+
+var databaseQueryDuration = NewHistogramVec(
+	"database_query_duration_seconds",
+	"Time taken by queries and statements sent to the database.",
+	DefaultBuckets,
+	"database",
+	"operation",
+)
+
+type (
+	connector struct {
+		driver.Connector
+		database string
+	}
+
+	conn struct {
+		driver.Conn
+		database string
+	}
+
+	stmt struct {
+		driver.Stmt
+		database string
+	}
+)
+
+// WrapConnector to time every query made with connections from the
+// connector, labelled with the database name given. Returns the
+// connector unchanged if metrics are disabled
+func WrapConnector(connector_ driver.Connector, database string) driver.Connector {
+	if !Enabled() {
+		return connector_
+	}
+
+	return &connector{connector_, database}
+}
+
+func (connector *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn_, err := connector.Connector.Connect(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &conn{conn_, connector.database}, nil
+}
+
+func (conn *conn) Prepare(query string) (driver.Stmt, error) {
+	return conn.PrepareContext(context.Background(), query)
+}
+
+func (conn *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var (
+		stmt_ driver.Stmt
+		err   error
+	)
+
+	if preparer, ok := conn.Conn.(driver.ConnPrepareContext); ok {
+		stmt_, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt_, err = conn.Conn.Prepare(query)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt{stmt_, conn.database}, nil
+}
+
+func (conn *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := conn.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+
+	return conn.Conn.Begin()
+}
+
+func (conn *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := conn.Conn.(driver.QueryerContext)
+
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	defer databaseQueryDuration.ObserveSince(time.Now(), conn.database, operationQuery)
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (conn *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := conn.Conn.(driver.ExecerContext)
+
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	defer databaseQueryDuration.ObserveSince(time.Now(), conn.database, operationExec)
+
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (conn *conn) Ping(ctx context.Context) error {
+	if pinger, ok := conn.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (stmt *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	defer databaseQueryDuration.ObserveSince(time.Now(), stmt.database, operationQuery)
+
+	if queryer, ok := stmt.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+
+	values, err := namedValuesToValues(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Stmt.Query(values)
+}
+
+func (stmt *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	defer databaseQueryDuration.ObserveSince(time.Now(), stmt.database, operationExec)
+
+	if execer, ok := stmt.Stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+
+	values, err := namedValuesToValues(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stmt.Stmt.Exec(values)
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, driver.ErrSkip
+		}
+
+		values[i] = arg.Value
+	}
+
+	return values, nil
+}
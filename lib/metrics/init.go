@@ -0,0 +1,65 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// contentType of the Prometheus text format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// enabled if a listen address was set
+var enabled bool
+
+func init() {
+	listenAddr := os.Getenv(EnvListenAddr)
+
+	if listenAddr == "" {
+		return
+	}
+
+	enabled = true
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/metrics", Handler)
+
+	go func() {
+		err := http.ListenAndServe(listenAddr, mux)
+
+		fmt.Fprintf(
+			os.Stderr,
+			"[%v] [%s] Failed to serve metrics on %#v! %v\n",
+			time.Now(),
+			Context,
+			listenAddr,
+			err,
+		)
+
+		os.Exit(1)
+	}()
+}
+
+// Enabled if metrics are being collected
+func Enabled() bool {
+	return enabled
+}
+
+// Handler that serves every registered metric, for services that
+// already have a web server
+func Handler(w http.ResponseWriter, r *http.Request) {
+	var builder strings.Builder
+
+	Write(&builder)
+
+	w.Header().Set("Content-Type", contentType)
+
+	_, _ = w.Write([]byte(builder.String()))
+}
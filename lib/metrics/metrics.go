@@ -0,0 +1,363 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package metrics
+
+// metrics collects counters and histograms from the microservice
+// library and serves them in the Prometheus text format on /metrics.
+// Metrics are only collected if FLU_METRICS_LISTEN_ADDR is set, so
+// everything here is a no-op by default. lib/log depends on this
+// package, so errors are written to stderr directly.
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Context to use for logging
+	Context = `METRICS`
+
+	// EnvListenAddr to serve /metrics on, eg :9100. Metrics are
+	// disabled if it isn't set
+	EnvListenAddr = `FLU_METRICS_LISTEN_ADDR`
+
+	// Namespace prefixed to every metric name
+	Namespace = `fluidity`
+)
+
+// DefaultBuckets for histograms of durations in seconds, the same as the
+// Prometheus client's defaults
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type (
+	// metric that can be written in the text format
+	metric interface {
+		write(builder *strings.Builder)
+	}
+
+	// CounterVec of counters that only go up, partitioned by labels
+	CounterVec struct {
+		name, help string
+		labels     []string
+
+		mu     sync.Mutex
+		values map[string]float64
+	}
+
+	// HistogramVec of histograms, partitioned by labels
+	HistogramVec struct {
+		name, help string
+		labels     []string
+		buckets    []float64
+
+		mu         sync.Mutex
+		histograms map[string]*histogram
+	}
+
+	histogram struct {
+		// counts of observations in each bucket, not cumulative
+		counts []uint64
+		count  uint64
+		sum    float64
+	}
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]metric)
+)
+
+// register the metric with the name given, panicking if it was already
+// registered, since metrics are created when packages are initialised
+func register(name string, metric metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("metric %#v registered twice!", name))
+	}
+
+	registry[name] = metric
+}
+
+// NewCounterVec with the name given (prefixed with the namespace) and
+// the names of the labels that each value is partitioned by
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{
+		name:   Namespace + "_" + name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]float64),
+	}
+
+	register(counter.name, counter)
+
+	return counter
+}
+
+// NewHistogramVec with the name given (prefixed with the namespace), the
+// upper bounds of its buckets in ascending order and the names of the
+// labels that each histogram is partitioned by
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	histogram := &HistogramVec{
+		name:       Namespace + "_" + name,
+		help:       help,
+		labels:     labels,
+		buckets:    buckets,
+		histograms: make(map[string]*histogram),
+	}
+
+	register(histogram.name, histogram)
+
+	return histogram
+}
+
+// Inc the counter with the label values given
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add to the counter with the label values given
+func (counter *CounterVec) Add(value float64, labelValues ...string) {
+	if !Enabled() {
+		return
+	}
+
+	key := labelKey(counter.labels, labelValues)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	counter.values[key] += value
+}
+
+// Value of the counter with the label values given
+func (counter *CounterVec) Value(labelValues ...string) float64 {
+	key := labelKey(counter.labels, labelValues)
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	return counter.values[key]
+}
+
+// Observe a value in the histogram with the label values given
+func (histogram_ *HistogramVec) Observe(value float64, labelValues ...string) {
+	if !Enabled() {
+		return
+	}
+
+	key := labelKey(histogram_.labels, labelValues)
+
+	histogram_.mu.Lock()
+	defer histogram_.mu.Unlock()
+
+	h, exists := histogram_.histograms[key]
+
+	if !exists {
+		h = &histogram{
+			counts: make([]uint64, len(histogram_.buckets)+1),
+		}
+
+		histogram_.histograms[key] = h
+	}
+
+	// the last count is for values larger than every bucket
+
+	i := sort.SearchFloat64s(histogram_.buckets, value)
+
+	h.counts[i]++
+	h.count++
+	h.sum += value
+}
+
+// ObserveSince the time given in seconds, for timing calls with defer
+func (histogram *HistogramVec) ObserveSince(start time.Time, labelValues ...string) {
+	histogram.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count of observations in the histogram with the label values given
+func (histogram *HistogramVec) Count(labelValues ...string) uint64 {
+	key := labelKey(histogram.labels, labelValues)
+
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	h, exists := histogram.histograms[key]
+
+	if !exists {
+		return 0
+	}
+
+	return h.count
+}
+
+func (counter *CounterVec) write(builder *strings.Builder) {
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+
+	writeHeader(builder, counter.name, counter.help, "counter")
+
+	for _, key := range sortedKeys(counter.values) {
+		fmt.Fprintf(
+			builder,
+			"%s%s %s\n",
+			counter.name,
+			wrapLabels(key),
+			formatFloat(counter.values[key]),
+		)
+	}
+}
+
+func (histogram *HistogramVec) write(builder *strings.Builder) {
+	histogram.mu.Lock()
+	defer histogram.mu.Unlock()
+
+	writeHeader(builder, histogram.name, histogram.help, "histogram")
+
+	keys := make([]string, 0, len(histogram.histograms))
+
+	for key := range histogram.histograms {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var (
+			h          = histogram.histograms[key]
+			cumulative uint64
+		)
+
+		for i, upperBound := range histogram.buckets {
+			cumulative += h.counts[i]
+
+			fmt.Fprintf(
+				builder,
+				"%s_bucket%s %d\n",
+				histogram.name,
+				wrapLabels(joinLabels(key, "le", formatFloat(upperBound))),
+				cumulative,
+			)
+		}
+
+		fmt.Fprintf(
+			builder,
+			"%s_bucket%s %d\n",
+			histogram.name,
+			wrapLabels(joinLabels(key, "le", "+Inf")),
+			h.count,
+		)
+
+		fmt.Fprintf(builder, "%s_sum%s %s\n", histogram.name, wrapLabels(key), formatFloat(h.sum))
+		fmt.Fprintf(builder, "%s_count%s %d\n", histogram.name, wrapLabels(key), h.count)
+	}
+}
+
+// Write every registered metric in the Prometheus text format
+func Write(builder *strings.Builder) {
+	registryMu.Lock()
+
+	names := make([]string, 0, len(registry))
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	metrics := make([]metric, len(names))
+
+	sort.Strings(names)
+
+	for i, name := range names {
+		metrics[i] = registry[name]
+	}
+
+	registryMu.Unlock()
+
+	for _, metric := range metrics {
+		metric.write(builder)
+	}
+}
+
+func writeHeader(builder *strings.Builder, name, help, type_ string) {
+	fmt.Fprintf(builder, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(builder, "# TYPE %s %s\n", name, type_)
+}
+
+// labelKey of the label names and values formatted as they're written,
+// panicking if the number of values is wrong since that's a bug
+func labelKey(labels, labelValues []string) string {
+	if len(labels) != len(labelValues) {
+		panic(fmt.Sprintf(
+			"expected %v label values for labels %v, got %v!",
+			len(labels),
+			labels,
+			len(labelValues),
+		))
+	}
+
+	pairs := make([]string, len(labels))
+
+	for i, label := range labels {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", label, escapeLabelValue(labelValues[i]))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(key, label, value string) string {
+	pair := fmt.Sprintf("%s=\"%s\"", label, value)
+
+	if key == "" {
+		return pair
+	}
+
+	return key + "," + pair
+}
+
+func wrapLabels(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	return "{" + key + "}"
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+
+	case math.IsInf(value, -1):
+		return "-Inf"
+
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// enableMetrics for the duration of the test
+func enableMetrics(t *testing.T) {
+	previous := enabled
+
+	enabled = true
+
+	t.Cleanup(func() {
+		enabled = previous
+	})
+}
+
+func TestCounterDisabled(t *testing.T) {
+	counter := NewCounterVec("test_disabled_total", "Test counter.", "topic")
+
+	counter.Inc("winners.ethereum")
+
+	assert.Equal(t, 0., counter.Value("winners.ethereum"))
+}
+
+func TestCounter(t *testing.T) {
+	enableMetrics(t)
+
+	counter := NewCounterVec("test_counter_total", "Test \"counter\".", "topic")
+
+	counter.Inc("winners.ethereum")
+	counter.Add(2, "winners.ethereum")
+	counter.Inc(`bad"topic`)
+
+	assert.Equal(t, 3., counter.Value("winners.ethereum"))
+
+	var builder strings.Builder
+
+	counter.write(&builder)
+
+	expected := `# HELP fluidity_test_counter_total Test "counter".
+# TYPE fluidity_test_counter_total counter
+fluidity_test_counter_total{topic="bad\"topic"} 1
+fluidity_test_counter_total{topic="winners.ethereum"} 3
+`
+
+	assert.Equal(t, expected, builder.String())
+}
+
+func TestCounterWrongLabels(t *testing.T) {
+	enableMetrics(t)
+
+	counter := NewCounterVec("test_wrong_labels_total", "Test counter.", "topic")
+
+	assert.Panics(t, func() {
+		counter.Inc()
+	})
+}
+
+func TestRegisterTwice(t *testing.T) {
+	NewCounterVec("test_twice_total", "Test counter.")
+
+	assert.Panics(t, func() {
+		NewCounterVec("test_twice_total", "Test counter.")
+	})
+}
+
+func TestHistogram(t *testing.T) {
+	enableMetrics(t)
+
+	histogram := NewHistogramVec(
+		"test_duration_seconds",
+		"Test histogram.",
+		[]float64{0.1, 1},
+		"command",
+	)
+
+	histogram.Observe(0.05, "get")
+	histogram.Observe(0.1, "get")
+	histogram.Observe(0.5, "get")
+	histogram.Observe(5, "get")
+
+	assert.Equal(t, uint64(4), histogram.Count("get"))
+	assert.Equal(t, uint64(0), histogram.Count("set"))
+
+	var builder strings.Builder
+
+	histogram.write(&builder)
+
+	expected := `# HELP fluidity_test_duration_seconds Test histogram.
+# TYPE fluidity_test_duration_seconds histogram
+fluidity_test_duration_seconds_bucket{command="get",le="0.1"} 2
+fluidity_test_duration_seconds_bucket{command="get",le="1"} 3
+fluidity_test_duration_seconds_bucket{command="get",le="+Inf"} 4
+fluidity_test_duration_seconds_sum{command="get"} 5.65
+fluidity_test_duration_seconds_count{command="get"} 4
+`
+
+	assert.Equal(t, expected, builder.String())
+}
+
+func TestHandler(t *testing.T) {
+	enableMetrics(t)
+
+	counter := NewCounterVec("test_handler_total", "Test counter.")
+
+	counter.Inc()
+
+	recorder := httptest.NewRecorder()
+
+	Handler(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, contentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "fluidity_test_handler_total 1\n")
+}
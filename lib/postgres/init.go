@@ -5,14 +5,22 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"regexp"
+	"time"
 
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/metrics"
 	"github.com/fluidity-money/fluidity-app/lib/util"
-	_ "github.com/lib/pq"
+
+	"github.com/lib/pq"
 )
 
+// pingTimeout to wait for the database to respond to the readiness probe
+const pingTimeout = 5 * time.Second
+
 func init() {
 	databaseUri := util.GetEnvOrFatal(EnvDatabaseUri)
 
@@ -21,9 +29,7 @@ func init() {
 		k.Message = "Connecting to the Postgres database..."
 	})
 
-	var err error
-
-	client, err = sql.Open("postgres", databaseUri)
+	connector, err := pq.NewConnector(databaseUri)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
@@ -33,6 +39,10 @@ func init() {
 		})
 	}
 
+	// time queries if metrics are enabled
+
+	client = sql.OpenDB(metrics.WrapConnector(connector, "postgres"))
+
 	if _, err := client.Exec("SELECT 1"); err != nil {
 		// hide sensitive uri components in log output
 		userPassRegex := regexp.MustCompile(`:\/\/(.*?):(.*?)@`)
@@ -49,6 +59,14 @@ func init() {
 		k.Message = "Connected to the Postgres database!"
 	})
 
+	lifecycle.RegisterReadiness("postgres", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+
+		defer cancel()
+
+		return client.PingContext(ctx)
+	})
+
 	go func() {
 		for {
 			readyChan <- true
@@ -0,0 +1,343 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/trace"
+)
+
+// TryDecode the message's JSON content, returning a PermanentError if
+// the message can't be decoded instead of exiting
+func (message Message) TryDecode(decoded interface{}) error {
+	payload, err := readPayload(message.Content)
+
+	if err == nil {
+		err = json.Unmarshal(payload, decoded)
+	}
+
+	if err != nil {
+		return Permanentf(
+			"failed to decode a JSON message on topic %#v! %v",
+			message.Topic,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// GetMessagesContext from the queue, calling the handler each time a
+// message is received until the context is cancelled. Messages are acked
+// if the handler returns nil, dead lettered if it returns a
+// PermanentError and requeued if it returns any other error until
+// FLU_AMQP_QUEUE_MESSAGE_RETRIES is reached. The handler is passed the
+// context so long running handlers can stop when it's cancelled. Returns
+// once every message being handled is finished after cancellation, or if
+// the transport fails.
+func GetMessagesContext(ctx context.Context, topic string, f func(ctx context.Context, message Message) error) error {
+	amqpDetails := <-chanAmqpDetails
+
+	return consumeContext(ctx, amqpDetails, topic, f)
+}
+
+// consumeContext from the transport given, implementing GetMessagesContext
+func consumeContext(ctx context.Context, amqpDetails amqpDetails, topic string, f func(ctx context.Context, message Message) error) error {
+	var (
+		transport             = amqpDetails.transport
+		workerId              = amqpDetails.workerId
+		deadLetterEnabled     = amqpDetails.deadLetterEnabled
+		messageRetries        = amqpDetails.messageRetries
+		goroutines            = amqpDetails.goroutines
+		messageLoggingEnabled = amqpDetails.messageLoggingEnabled
+	)
+
+	var (
+		consumerId = generateRandomConsumerId(workerId)
+		queueName  = fmt.Sprintf("%v.%v", topic, workerId)
+	)
+
+	messages, err := transport.consume(
+		queueName,
+		topic,
+		consumerId,
+		deadLetterEnabled,
+	)
+
+	if err != nil {
+		return fmt.Errorf(
+			"failed to start to consume queue %#v topic %#v, consumer id %#v! %v",
+			queueName,
+			topic,
+			consumerId,
+			err,
+		)
+	}
+
+	handlers.addConsumer(consumerId, transport)
+
+	defer handlers.removeConsumer(consumerId)
+
+	var (
+		internalQueue = make(chan delivery)
+		errChan       = make(chan error, goroutines)
+
+		wg sync.WaitGroup
+	)
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for message := range internalQueue {
+				handlerId := handlers.start()
+
+				err := handleDelivery(
+					ctx,
+					transport,
+					workerId,
+					topic,
+					deadLetterEnabled,
+					messageRetries,
+					messageLoggingEnabled,
+					message,
+					f,
+				)
+
+				handlers.finish(handlerId)
+
+				if err != nil {
+					errChan <- err
+					return
+				}
+			}
+		}()
+	}
+
+	var consumeErr error
+
+	// send incoming messages to the internal queue until the context is
+	// cancelled, the transport stops or a message can't be acked
+
+consumeLoop:
+	for {
+		select {
+		case <-ctx.Done():
+			break consumeLoop
+
+		case consumeErr = <-errChan:
+			break consumeLoop
+
+		case message, ok := <-messages:
+			if !ok {
+				// the consumer is cancelled when shutting down,
+				// otherwise the transport failed
+
+				if !lifecycle.ShuttingDown() {
+					consumeErr = fmt.Errorf(
+						"consumer %#v on queue %#v closed by the transport",
+						consumerId,
+						queueName,
+					)
+				}
+
+				messages = nil
+
+				break consumeLoop
+			}
+
+			select {
+			case internalQueue <- message:
+
+			case consumeErr = <-errChan:
+				_ = message.nack(true)
+				break consumeLoop
+
+			case <-ctx.Done():
+				_ = message.nack(true)
+				break consumeLoop
+			}
+		}
+	}
+
+	log.Debug(func(k *log.Log) {
+		k.Context = Context
+
+		k.Format(
+			"Stopping consumer %#v on queue %#v, waiting for handlers to finish!",
+			consumerId,
+			queueName,
+		)
+	})
+
+	close(internalQueue)
+
+	wg.Wait()
+
+	// stop receiving new messages and return anything sent to us in the
+	// meantime back to the queue
+
+	if messages != nil {
+		if err := transport.cancel(consumerId); err != nil && consumeErr == nil {
+			consumeErr = fmt.Errorf(
+				"failed to cancel consumer %#v! %v",
+				consumerId,
+				err,
+			)
+		}
+
+		for message := range messages {
+			_ = message.nack(true)
+		}
+	}
+
+	if consumeErr == nil {
+		select {
+		case consumeErr = <-errChan:
+		default:
+		}
+	}
+
+	return consumeErr
+}
+
+// handleDelivery by calling the handler and acking, requeueing or dead
+// lettering the message depending on the result, only returning an
+// error if the transport failed
+func handleDelivery(ctx context.Context, transport transport, workerId, topic string, deadLetterEnabled bool, messageRetries int, messageLoggingEnabled bool, message delivery, f func(ctx context.Context, message Message) error) error {
+	var (
+		deliveryTag = message.deliveryTag
+		body        = message.body
+		routingKey  = message.routingKey
+	)
+
+	messagesConsumedCount.Inc(topic)
+
+	retryKey := getRetryKey(workerId, topic, body)
+
+	if deadLetterEnabled {
+		retryCount := transport.countAttempt(retryKey, message)
+
+		// the number of retries exceeds the max retry count! giving up!
+
+		if retryCount >= messageRetries {
+			log.App(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Message on topic %v has been retried %v times, dead lettering it!",
+					routingKey,
+					retryCount,
+				)
+			})
+
+			transport.clearAttempts(retryKey)
+
+			countNack(topic, false)
+
+			return message.nack(false)
+		}
+	}
+
+	if messageLoggingEnabled {
+		log.Debug(func(k *log.Log) {
+			k.Context = Context
+			k.Format(
+				"Message received on topic %v, content %#v!",
+				routingKey,
+				body,
+			)
+		})
+	}
+
+	span := startConsumeSpan(topic, message)
+
+	defer span.Finish()
+
+	ctx = trace.ContextWithSpan(ctx, span)
+
+	ctx = contextWithDelivery(ctx, message)
+
+	handlerStart := time.Now()
+
+	err := f(ctx, Message{
+		Topic:   routingKey,
+		Content: bytes.NewBuffer(body),
+		ctx:     ctx,
+	})
+
+	handlerDuration.ObserveSince(handlerStart, topic)
+
+	if err != nil {
+		span.Fail(err)
+	}
+
+	switch {
+	case err == nil:
+		if err := message.ack(); err != nil {
+			return fmt.Errorf(
+				"failed to ack a message with tag %#v! %v",
+				deliveryTag,
+				err,
+			)
+		}
+
+		if deadLetterEnabled {
+			transport.clearAttempts(retryKey)
+		}
+
+		messagesAckedCount.Inc(topic)
+
+		return nil
+
+	case IsPermanent(err) || !deadLetterEnabled:
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Handler for topic %v failed permanently, dead lettering the message!",
+				routingKey,
+			)
+
+			k.Payload = err
+			k.Span = span
+		})
+
+		if deadLetterEnabled {
+			transport.clearAttempts(retryKey)
+		}
+
+		countNack(topic, false)
+
+		return message.nack(false)
+
+	default:
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Handler for topic %v failed, requeueing the message!",
+				routingKey,
+			)
+
+			k.Payload = err
+			k.Span = span
+		})
+
+		countNack(topic, true)
+
+		return message.nack(true)
+	}
+}
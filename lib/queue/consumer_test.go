@@ -0,0 +1,295 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+// these tests consume with an in-memory broker, so the package's init
+// needs FLU_WORKER_ID and FLU_AMQP_QUEUE_TRANSPORT=memory to be set
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWorkerId = "test-worker"
+
+	testTimeout = 5 * time.Second
+)
+
+// testConsumer consuming a topic with an in-memory broker until the test
+// cancels it
+type testConsumer struct {
+	transport *memoryTransport
+	queueName string
+	cancel    func()
+	done      chan error
+}
+
+func newTestDetails(t *testing.T, messageRetries int) amqpDetails {
+	transport := newMemoryTransport(messageRetries)
+
+	t.Cleanup(func() {
+		_ = transport.close()
+	})
+
+	return amqpDetails{
+		transport:         transport,
+		workerId:          testWorkerId,
+		deadLetterEnabled: true,
+		messageRetries:    messageRetries,
+		goroutines:        1,
+	}
+}
+
+func startTestConsumer(t *testing.T, details amqpDetails, topic string, f func(ctx context.Context, message Message) error) *testConsumer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	consumer := testConsumer{
+		transport: details.transport.(*memoryTransport),
+		queueName: fmt.Sprintf("%v.%v", topic, details.workerId),
+		cancel:    cancel,
+		done:      make(chan error, 1),
+	}
+
+	go func() {
+		consumer.done <- consumeContext(ctx, details, topic, f)
+	}()
+
+	// wait for the queue to be declared so messages published aren't
+	// dropped
+
+	require.Eventually(t, func() bool {
+		return consumer.transport.broker.Pending(consumer.queueName) == 0 &&
+			consumer.transport.broker.DeadLetters(consumer.queueName) != nil
+	}, testTimeout, time.Millisecond)
+
+	t.Cleanup(cancel)
+
+	return &consumer
+}
+
+// stop the consumer, returning the error it returned
+func (consumer *testConsumer) stop(t *testing.T) error {
+	consumer.cancel()
+
+	select {
+	case err := <-consumer.done:
+		return err
+
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the consumer to stop!")
+	}
+
+	return nil
+}
+
+func (consumer *testConsumer) publish(t *testing.T, topic, content string) {
+	err := consumer.transport.publish(topic, []byte(content), nil)
+
+	require.NoError(t, err)
+}
+
+func (consumer *testConsumer) deadLetters() int {
+	return len(consumer.transport.broker.DeadLetters(consumer.queueName))
+}
+
+func (consumer *testConsumer) pending() int {
+	return consumer.transport.broker.Pending(consumer.queueName)
+}
+
+func TestConsumeAcks(t *testing.T) {
+	var (
+		details  = newTestDetails(t, 5)
+		received = make(chan string, 1)
+	)
+
+	consumer := startTestConsumer(t, details, "test.ack", func(_ context.Context, message Message) error {
+		var content string
+
+		if err := message.TryDecode(&content); err != nil {
+			return err
+		}
+
+		received <- content
+
+		return nil
+	})
+
+	consumer.publish(t, "test.ack", `"hello"`)
+
+	select {
+	case content := <-received:
+		assert.Equal(t, "hello", content)
+
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the message!")
+	}
+
+	assert.NoError(t, consumer.stop(t))
+
+	assert.Equal(t, 0, consumer.pending())
+	assert.Equal(t, 0, consumer.deadLetters())
+}
+
+func TestConsumeRequeuesUntilSuccess(t *testing.T) {
+	var (
+		details = newTestDetails(t, 5)
+		calls   int32
+		done    = make(chan struct{})
+	)
+
+	consumer := startTestConsumer(t, details, "test.retry", func(_ context.Context, message Message) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("failed for now")
+		}
+
+		close(done)
+
+		return nil
+	})
+
+	consumer.publish(t, "test.retry", `"hello"`)
+
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the message to succeed!")
+	}
+
+	assert.NoError(t, consumer.stop(t))
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Equal(t, 0, consumer.deadLetters())
+}
+
+func TestConsumeDeadLettersAfterRetries(t *testing.T) {
+	var (
+		details = newTestDetails(t, 3)
+		calls   int32
+	)
+
+	consumer := startTestConsumer(t, details, "test.retries", func(_ context.Context, message Message) error {
+		atomic.AddInt32(&calls, 1)
+
+		return Retryablef("always failing")
+	})
+
+	consumer.publish(t, "test.retries", `"hello"`)
+
+	assert.Eventually(t, func() bool {
+		return consumer.deadLetters() == 1
+	}, testTimeout, time.Millisecond)
+
+	assert.NoError(t, consumer.stop(t))
+
+	// the third attempt reaches the limit, so it's dead lettered without
+	// calling the handler
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, 0, consumer.pending())
+}
+
+func TestConsumeDeadLettersPermanentErrors(t *testing.T) {
+	var (
+		details = newTestDetails(t, 5)
+		calls   int32
+	)
+
+	consumer := startTestConsumer(t, details, "test.permanent", func(_ context.Context, message Message) error {
+		atomic.AddInt32(&calls, 1)
+
+		var content int
+
+		// the content isn't a number, so decoding fails permanently
+
+		return message.TryDecode(&content)
+	})
+
+	consumer.publish(t, "test.permanent", `"not a number"`)
+
+	assert.Eventually(t, func() bool {
+		return consumer.deadLetters() == 1
+	}, testTimeout, time.Millisecond)
+
+	assert.NoError(t, consumer.stop(t))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestConsumeCancelReachesHandler(t *testing.T) {
+	var (
+		details = newTestDetails(t, 5)
+		started = make(chan struct{})
+		stopped = make(chan error, 1)
+	)
+
+	consumer := startTestConsumer(t, details, "test.cancel", func(ctx context.Context, message Message) error {
+		close(started)
+
+		// a long running handler that stops when cancelled
+
+		<-ctx.Done()
+
+		stopped <- ctx.Err()
+
+		return ctx.Err()
+	})
+
+	consumer.publish(t, "test.cancel", `"hello"`)
+
+	select {
+	case <-started:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the handler to start!")
+	}
+
+	assert.NoError(t, consumer.stop(t))
+
+	assert.ErrorIs(t, <-stopped, context.Canceled)
+
+	// the message is returned to the queue for the next consumer
+
+	assert.Eventually(t, func() bool {
+		return consumer.pending() == 1
+	}, testTimeout, time.Millisecond)
+
+	assert.Equal(t, 0, consumer.deadLetters())
+}
+
+func TestConsumeMessageContext(t *testing.T) {
+	var (
+		details  = newTestDetails(t, 5)
+		contexts = make(chan [2]context.Context, 1)
+	)
+
+	consumer := startTestConsumer(t, details, "test.context", func(ctx context.Context, message Message) error {
+		contexts <- [2]context.Context{ctx, message.Context()}
+
+		return nil
+	})
+
+	consumer.publish(t, "test.context", `"hello"`)
+
+	select {
+	case received := <-contexts:
+		assert.Equal(t, received[0], received[1])
+
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the message!")
+	}
+
+	assert.NoError(t, consumer.stop(t))
+
+	// messages made outside a handler still have a context
+
+	assert.NotNil(t, Message{}.Context())
+}
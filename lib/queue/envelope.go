@@ -0,0 +1,338 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+// envelope wraps messages with the name and version of the schema used
+// to encode them, so producers and consumers can be deployed
+// independently. Messages sent without an envelope are treated as
+// LegacyVersion of whatever schema the consumer expects, and
+// Message.Decode unwraps envelopes for consumers that don't use a
+// schema.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/trace"
+)
+
+// LegacyVersion to decode messages that were sent without an envelope as
+const LegacyVersion = 1
+
+type (
+	// Envelope that's sent down the queue containing the payload
+	Envelope struct {
+		Schema   string          `json:"schema"`
+		Version  int             `json:"version"`
+		WorkerId string          `json:"worker_id"`
+		Time     time.Time       `json:"time"`
+		Payload  json.RawMessage `json:"payload"`
+	}
+
+	// Decoder to decode a payload encoded with a version of a schema
+	Decoder func(payload []byte) (interface{}, error)
+
+	// Upgrade a decoded payload to the next version of the schema
+	Upgrade func(decoded interface{}) (interface{}, error)
+
+	// Schema that's used to encode and decode a kind of message, with
+	// decoders for every version that's understood
+	Schema struct {
+		name    string
+		version int
+
+		decoders map[int]Decoder
+		upgrades map[int]Upgrade
+	}
+)
+
+// NewSchema with the name given, encoding and decoding messages at the
+// version given
+func NewSchema(name string, version int, decoder Decoder) *Schema {
+	schema := &Schema{
+		name:     name,
+		version:  version,
+		decoders: map[int]Decoder{version: decoder},
+		upgrades: make(map[int]Upgrade),
+	}
+
+	return schema
+}
+
+// Older version of the schema that's still understood, with a function
+// to upgrade payloads decoded at that version to version+1
+func (schema *Schema) Older(version int, decoder Decoder, upgrade Upgrade) *Schema {
+	if version >= schema.version {
+		panic(fmt.Sprintf(
+			"older version %v of schema %#v isn't older than %v!",
+			version,
+			schema.name,
+			schema.version,
+		))
+	}
+
+	schema.decoders[version] = decoder
+	schema.upgrades[version] = upgrade
+
+	return schema
+}
+
+// JsonDecoder to decode a JSON payload into a new value with the same
+// type as the example given
+func JsonDecoder(example interface{}) Decoder {
+	type_ := reflect.TypeOf(example)
+
+	return func(payload []byte) (interface{}, error) {
+		decoded := reflect.New(type_)
+
+		if err := json.Unmarshal(payload, decoded.Interface()); err != nil {
+			return nil, err
+		}
+
+		return decoded.Elem().Interface(), nil
+	}
+}
+
+// Name of the schema
+func (schema *Schema) Name() string {
+	return schema.name
+}
+
+// Version of the schema that's used to encode new messages
+func (schema *Schema) Version() int {
+	return schema.version
+}
+
+// Wrap the content in an envelope with the current version
+func (schema *Schema) Wrap(workerId string, content interface{}) (*Envelope, error) {
+	payload, err := json.Marshal(content)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to encode a payload for schema %#v! %v",
+			schema.name,
+			err,
+		)
+	}
+
+	envelope := &Envelope{
+		Schema:   schema.name,
+		Version:  schema.version,
+		WorkerId: workerId,
+		Time:     time.Now(),
+		Payload:  payload,
+	}
+
+	return envelope, nil
+}
+
+// Decode the content of a message, which can be enveloped at any known
+// version or sent without an envelope at LegacyVersion, upgrading it to
+// the current version. Returns a PermanentError if the schema or
+// version isn't understood.
+func (schema *Schema) Decode(content []byte) (interface{}, error) {
+	var (
+		version = LegacyVersion
+		payload = content
+	)
+
+	if envelope, ok := decodeEnvelope(content); ok {
+		if envelope.Schema != schema.name {
+			return nil, Permanentf(
+				"message has schema %#v, expected %#v!",
+				envelope.Schema,
+				schema.name,
+			)
+		}
+
+		version = envelope.Version
+		payload = envelope.Payload
+	}
+
+	decoder, ok := schema.decoders[version]
+
+	if !ok {
+		return nil, Permanentf(
+			"unknown version %v of schema %#v!",
+			version,
+			schema.name,
+		)
+	}
+
+	decoded, err := decoder(payload)
+
+	if err != nil {
+		return nil, Permanentf(
+			"failed to decode version %v of schema %#v! %v",
+			version,
+			schema.name,
+			err,
+		)
+	}
+
+	for ; version < schema.version; version++ {
+		upgrade, ok := schema.upgrades[version]
+
+		if !ok {
+			return nil, Permanentf(
+				"no upgrade from version %v of schema %#v!",
+				version,
+				schema.name,
+			)
+		}
+
+		decoded, err = upgrade(decoded)
+
+		if err != nil {
+			return nil, Permanentf(
+				"failed to upgrade version %v of schema %#v! %v",
+				version,
+				schema.name,
+				err,
+			)
+		}
+	}
+
+	return decoded, nil
+}
+
+// decodeEnvelope from the content, returning false if the content
+// wasn't sent in an envelope
+func decodeEnvelope(content []byte) (*Envelope, bool) {
+	trimmed := bytes.TrimSpace(content)
+
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, false
+	}
+
+	var envelope Envelope
+
+	if err := json.Unmarshal(trimmed, &envelope); err != nil {
+		return nil, false
+	}
+
+	if envelope.Schema == "" || envelope.Version == 0 || envelope.Payload == nil {
+		return nil, false
+	}
+
+	return &envelope, true
+}
+
+// readPayload from the content of a message, unwrapping it if it was
+// sent in an envelope, ignoring the schema and version
+func readPayload(content io.Reader) ([]byte, error) {
+	body, err := io.ReadAll(content)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if envelope, ok := decodeEnvelope(body); ok {
+		return envelope.Payload, nil
+	}
+
+	return body, nil
+}
+
+// NewEnvelope containing the content at the current version of the
+// schema, with the worker id of this worker
+func NewEnvelope(schema *Schema, content interface{}) (*Envelope, error) {
+	amqpDetails := <-chanAmqpDetails
+
+	return schema.Wrap(amqpDetails.workerId, content)
+}
+
+// SendEnvelope down a topic, wrapping the content in an envelope with
+// the current version of the schema
+func SendEnvelope(topic string, schema *Schema, content interface{}) {
+	SendEnvelopeContext(context.Background(), topic, schema, content)
+}
+
+// SendEnvelopeContext down a topic, wrapping the content in an envelope
+// and continuing the trace in the context given
+func SendEnvelopeContext(ctx context.Context, topic string, schema *Schema, content interface{}) {
+	envelope, err := NewEnvelope(schema, content)
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to wrap a message for topic %#v in an envelope!",
+				topic,
+			)
+
+			k.Payload = err
+			k.Span = trace.SpanFromContext(ctx)
+		})
+	}
+
+	SendMessageContext(ctx, topic, envelope)
+}
+
+// GetEnvelopes from a topic, decoding them with the schema and calling
+// the function with the decoded payload. Messages that can't be decoded
+// (including unknown versions) are dead lettered.
+func GetEnvelopes(topic string, schema *Schema, f func(decoded interface{})) {
+	GetEnvelopesContext(topic, schema, func(_ context.Context, decoded interface{}) {
+		f(decoded)
+	})
+}
+
+// GetEnvelopesContext from a topic like GetEnvelopes, calling the
+// function with the context the message is handled with so messages it
+// sends continue the trace
+func GetEnvelopesContext(topic string, schema *Schema, f func(ctx context.Context, decoded interface{})) {
+	err := GetMessagesContext(context.Background(), topic, envelopeHandler(schema, f))
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to consume envelopes for schema %#v on topic %#v!",
+				schema.name,
+				topic,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	lifecycle.Wait()
+}
+
+// envelopeHandler to consume messages with, decoding them with the
+// schema before calling the function
+func envelopeHandler(schema *Schema, f func(ctx context.Context, decoded interface{})) func(context.Context, Message) error {
+	return func(ctx context.Context, message Message) error {
+		body, err := io.ReadAll(message.Content)
+
+		if err != nil {
+			return Permanentf(
+				"failed to read a message on topic %#v! %v",
+				message.Topic,
+				err,
+			)
+		}
+
+		decoded, err := schema.Decode(body)
+
+		if err != nil {
+			return err
+		}
+
+		f(ctx, decoded)
+
+		return nil
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// testPayloadV1 sent the amount as a string
+	testPayloadV1 struct {
+		Amount string `json:"amount"`
+	}
+
+	// testPayloadV2 sent the amount as a number
+	testPayloadV2 struct {
+		Amount int `json:"amount"`
+	}
+
+	// testPayloadV3 added the token
+	testPayloadV3 struct {
+		Amount int    `json:"amount"`
+		Token  string `json:"token"`
+	}
+)
+
+// newTestSchema at version 3, understanding versions 1 and 2
+func newTestSchema() *Schema {
+	return NewSchema(`test.payload`, 3, JsonDecoder(testPayloadV3{})).
+		Older(1, JsonDecoder(testPayloadV1{}), func(decoded interface{}) (interface{}, error) {
+			amount, err := strconv.Atoi(decoded.(testPayloadV1).Amount)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return testPayloadV2{Amount: amount}, nil
+		}).
+		Older(2, JsonDecoder(testPayloadV2{}), func(decoded interface{}) (interface{}, error) {
+			payload := testPayloadV3{
+				Amount: decoded.(testPayloadV2).Amount,
+				Token:  "fUSDC",
+			}
+
+			return payload, nil
+		})
+}
+
+// testEnvelope containing the payload at the schema and version given
+func testEnvelope(t *testing.T, schema string, version int, payload string) []byte {
+	envelope := Envelope{
+		Schema:   schema,
+		Version:  version,
+		WorkerId: testWorkerId,
+		Time:     time.Now(),
+		Payload:  json.RawMessage(payload),
+	}
+
+	content, err := json.Marshal(envelope)
+
+	require.NoError(t, err)
+
+	return content
+}
+
+func TestSchemaCurrentVersion(t *testing.T) {
+	schema := newTestSchema()
+
+	envelope, err := schema.Wrap(testWorkerId, testPayloadV3{Amount: 10, Token: "fDAI"})
+
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, envelope.Version)
+
+	content, err := json.Marshal(envelope)
+
+	require.NoError(t, err)
+
+	decoded, err := schema.Decode(content)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, testPayloadV3{Amount: 10, Token: "fDAI"}, decoded)
+}
+
+func TestSchemaUpgradeChain(t *testing.T) {
+	schema := newTestSchema()
+
+	decoded, err := schema.Decode(testEnvelope(t, `test.payload`, 2, `{"amount":20}`))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, testPayloadV3{Amount: 20, Token: "fUSDC"}, decoded)
+
+	// version 1 is upgraded to 2, then 3
+
+	decoded, err = schema.Decode(testEnvelope(t, `test.payload`, 1, `{"amount":"30"}`))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, testPayloadV3{Amount: 30, Token: "fUSDC"}, decoded)
+}
+
+func TestSchemaFailedUpgrade(t *testing.T) {
+	schema := newTestSchema()
+
+	_, err := schema.Decode(testEnvelope(t, `test.payload`, 1, `{"amount":"thirty"}`))
+
+	assert.True(t, IsPermanent(err))
+}
+
+func TestSchemaMissingUpgrade(t *testing.T) {
+	schema := NewSchema(`test.payload`, 3, JsonDecoder(testPayloadV3{})).
+		Older(1, JsonDecoder(testPayloadV1{}), func(decoded interface{}) (interface{}, error) {
+			return testPayloadV2{}, nil
+		})
+
+	_, err := schema.Decode(testEnvelope(t, `test.payload`, 1, `{"amount":"30"}`))
+
+	assert.True(t, IsPermanent(err))
+}
+
+func TestSchemaLegacyDecoding(t *testing.T) {
+	schema := newTestSchema()
+
+	// messages sent without an envelope are LegacyVersion
+
+	decoded, err := schema.Decode([]byte(`{"amount":"40"}`))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, testPayloadV3{Amount: 40, Token: "fUSDC"}, decoded)
+
+	// payloads that aren't objects can't be envelopes
+
+	list := NewSchema(`test.list`, LegacyVersion, JsonDecoder([]int{}))
+
+	decoded, err = list.Decode([]byte(`[1, 2, 3]`))
+
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{1, 2, 3}, decoded)
+}
+
+func TestSchemaUnknownVersion(t *testing.T) {
+	schema := newTestSchema()
+
+	_, err := schema.Decode(testEnvelope(t, `test.payload`, 4, `{"amount":50}`))
+
+	assert.True(t, IsPermanent(err))
+}
+
+func TestSchemaWrongName(t *testing.T) {
+	schema := newTestSchema()
+
+	_, err := schema.Decode(testEnvelope(t, `test.other`, 3, `{"amount":60}`))
+
+	assert.True(t, IsPermanent(err))
+}
+
+func TestSchemaOlderPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		newTestSchema().Older(3, JsonDecoder(testPayloadV3{}), nil)
+	})
+}
+
+func TestConsumeEnvelopesDeadLettersUnknownVersions(t *testing.T) {
+	var (
+		details  = newTestDetails(t, 5)
+		calls    int32
+		received = make(chan interface{}, 1)
+	)
+
+	handler := envelopeHandler(newTestSchema(), func(_ context.Context, decoded interface{}) {
+		atomic.AddInt32(&calls, 1)
+
+		received <- decoded
+	})
+
+	consumer := startTestConsumer(t, details, "test.envelopes", handler)
+
+	unknown := testEnvelope(t, `test.payload`, 4, `{"amount":70}`)
+
+	require.NoError(t, consumer.transport.publish("test.envelopes", unknown, nil))
+
+	assert.Eventually(t, func() bool {
+		return consumer.deadLetters() == 1
+	}, testTimeout, time.Millisecond)
+
+	// messages at a known version are still handled afterwards
+
+	consumer.publish(t, "test.envelopes", `{"amount":"80"}`)
+
+	select {
+	case decoded := <-received:
+		assert.Equal(t, testPayloadV3{Amount: 80, Token: "fUSDC"}, decoded)
+
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the message!")
+	}
+
+	assert.NoError(t, consumer.stop(t))
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, 1, consumer.deadLetters())
+}
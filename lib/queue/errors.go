@@ -0,0 +1,68 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// RetryableError to return from a handler when the message should
+	// be requeued and tried again, up to FLU_AMQP_QUEUE_MESSAGE_RETRIES
+	RetryableError struct {
+		Err error
+	}
+
+	// PermanentError to return from a handler when the message can
+	// never be processed and should be dead lettered immediately
+	PermanentError struct {
+		Err error
+	}
+)
+
+// Retryable wraps an error so the message is requeued
+func Retryable(err error) error {
+	return RetryableError{err}
+}
+
+// Retryablef formats an error so the message is requeued
+func Retryablef(format string, args ...interface{}) error {
+	return Retryable(fmt.Errorf(format, args...))
+}
+
+// Permanent wraps an error so the message is dead lettered
+func Permanent(err error) error {
+	return PermanentError{err}
+}
+
+// Permanentf formats an error so the message is dead lettered
+func Permanentf(format string, args ...interface{}) error {
+	return Permanent(fmt.Errorf(format, args...))
+}
+
+func (err RetryableError) Error() string {
+	return fmt.Sprintf("retryable: %v", err.Err)
+}
+
+func (err RetryableError) Unwrap() error {
+	return err.Err
+}
+
+func (err PermanentError) Error() string {
+	return fmt.Sprintf("permanent: %v", err.Err)
+}
+
+func (err PermanentError) Unwrap() error {
+	return err.Err
+}
+
+// IsPermanent is true if the error or anything it wraps is a
+// PermanentError. Errors that aren't marked as either kind are retried.
+func IsPermanent(err error) bool {
+	var permanentError PermanentError
+
+	return errors.As(err, &permanentError)
+}
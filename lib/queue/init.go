@@ -7,11 +7,10 @@ package queue
 import (
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/fluidity-money/fluidity-app/lib/log"
 	"github.com/fluidity-money/fluidity-app/lib/util"
-
-	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 func init() {
@@ -19,13 +18,16 @@ func init() {
 		workerId = util.GetWorkerId()
 
 		deadLetterEnabled     = os.Getenv(EnvDeadLetterEnabled) != "false"
+		confirmsEnabled       = os.Getenv(EnvPublisherConfirmsEnabled) != "false"
 		messageLoggingEnabled = os.Getenv(EnvMessageLoggingEnabled) != ""
 
 		messageRetries_ = util.GetEnvOrDefault(EnvMessageRetries, "5")
 
 		goroutines_ = util.GetEnvOrDefault(EnvGoroutinesPerQueue, "1")
 
-		queueAddr = util.GetEnvOrFatal(EnvQueueAddr)
+		handlerStallSeconds_ = util.GetEnvOrDefault(EnvHandlerStallSeconds, "300")
+
+		transportName = util.GetEnvOrDefault(EnvQueueTransport, TransportAmqp)
 	)
 
 	messageRetries, err := strconv.Atoi(messageRetries_)
@@ -65,82 +67,83 @@ func init() {
 		k.Format("Number of goroutines in use is %v", goroutines)
 	})
 
-	client, err := amqp.Dial(queueAddr)
+	handlerStallSeconds, err := strconv.Atoi(handlerStallSeconds_)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
 			k.Context = Context
-			k.Message = "Failed to connect to AMQP server!"
-			k.Payload = err
-		})
-	}
 
-	channel, err := client.Channel()
+			k.Format(
+				"Failed to set %#v: Can't convert '%#v' to an integer!",
+				EnvHandlerStallSeconds,
+				handlerStallSeconds_,
+			)
 
-	if err != nil {
-		log.Fatal(func(k *log.Log) {
-			k.Context = Context
-			k.Message = "Failed to open a channel on AMQP!"
 			k.Payload = err
 		})
 	}
 
-	go func() {
-		errors := make(chan *amqp.Error)
+	handlerStallTimeout := time.Duration(handlerStallSeconds) * time.Second
 
-		err, closed := <-channel.NotifyClose(errors)
+	var transport transport
+
+	switch transportName {
+	case TransportAmqp:
+		var (
+			queueAddr     = util.GetEnvOrFatal(EnvQueueAddr)
+			redisAddr     = util.GetEnvOrFatal(EnvRedisAddr)
+			redisPassword = os.Getenv(EnvRedisPassword)
+		)
+
+		transport, err = newAmqpTransport(
+			queueAddr,
+			redisAddr,
+			redisPassword,
+			ExchangeName,
+			ExchangeType,
+			confirmsEnabled,
+		)
 
-		if closed && err == nil {
+		if err != nil {
 			log.Fatal(func(k *log.Log) {
 				k.Context = Context
-				k.Message = "AMQP closed on its own without an error!!!"
+				k.Message = "Failed to set up the AMQP transport!"
+				k.Payload = err
 			})
 		}
 
-		log.Fatal(func(k *log.Log) {
+	case TransportMemory:
+		log.App(func(k *log.Log) {
 			k.Context = Context
-			k.Message = "Channel to AMQP closed prematurely with error!"
-			k.Payload = err
+			k.Message = "Using the in-memory broker, messages won't leave this process!"
 		})
-	}()
 
-	err = channel.ExchangeDeclare(
-		ExchangeName,
-		ExchangeType,
-		true,  // durable
-		false, // autoDelete,
-		false, // internal
-		false, // noWait
-		nil,   // args
-	)
+		transport = newMemoryTransport(messageRetries)
 
-	if err != nil {
+	default:
 		log.Fatal(func(k *log.Log) {
 			k.Context = Context
-			k.Message = "Failed to declare an exchange!"
-			k.Payload = err
+
+			k.Format(
+				"Unknown transport %#v set with %#v! Options are %#v or %#v.",
+				transportName,
+				EnvQueueTransport,
+				TransportAmqp,
+				TransportMemory,
+			)
 		})
 	}
 
-	log.Debug(func(k *log.Log) {
-		k.Context = Context
-
-		k.Format(
-			"Declared a queue with name %#v type %#v!",
-			ExchangeName,
-			ExchangeType,
-		)
-	})
-
 	log.RegisterShutdown(func() {
-		_ = channel.Close()
+		_ = transport.close()
 	})
 
+	registerLifecycle(transport, handlerStallTimeout)
+
 	go func() {
 		for {
 			chanAmqpDetails <- amqpDetails{
-				channel:               channel,
-				exchangeName:          ExchangeName,
+				transport:             transport,
 				workerId:              workerId,
 				deadLetterEnabled:     deadLetterEnabled,
 				messageRetries:        messageRetries,
@@ -0,0 +1,169 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+// lifecycle tracks consumers and the handlers they're running, so
+// consuming can be stopped and drained when shutting down and stalled
+// handlers can fail the liveness probe
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
+	"github.com/fluidity-money/fluidity-app/lib/log"
+)
+
+// drainPollInterval to check whether consumers have finished draining
+const drainPollInterval = 100 * time.Millisecond
+
+// handlerTracker of every consumer that's running and every handler
+// that's processing a message
+type handlerTracker struct {
+	mu sync.Mutex
+
+	// consumers that are running, by consumer id
+	consumers map[string]transport
+
+	// inFlight handlers by id, with the time they started
+	inFlight map[uint64]time.Time
+
+	nextId uint64
+}
+
+var handlers = handlerTracker{
+	consumers: make(map[string]transport),
+	inFlight:  make(map[uint64]time.Time),
+}
+
+// registerLifecycle checks and drains with the lifecycle package
+func registerLifecycle(transport transport, handlerStallTimeout time.Duration) {
+	lifecycle.RegisterReadiness("amqp", transport.healthy)
+
+	lifecycle.RegisterLiveness("amqp handlers", func() error {
+		return handlers.stalled(handlerStallTimeout)
+	})
+
+	lifecycle.RegisterDrain("amqp consumers", handlers.drain)
+}
+
+func (tracker *handlerTracker) addConsumer(consumerId string, transport transport) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.consumers[consumerId] = transport
+}
+
+// removeConsumer once it's stopped and its handlers are finished
+func (tracker *handlerTracker) removeConsumer(consumerId string) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	delete(tracker.consumers, consumerId)
+}
+
+// start tracking a handler, returning the id to finish it with
+func (tracker *handlerTracker) start() uint64 {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	id := tracker.nextId
+
+	tracker.nextId++
+
+	tracker.inFlight[id] = time.Now()
+
+	return id
+}
+
+func (tracker *handlerTracker) finish(id uint64) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	delete(tracker.inFlight, id)
+}
+
+// stalled returns an error if any handler has been running for longer
+// than the timeout
+func (tracker *handlerTracker) stalled(timeout time.Duration) error {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	now := time.Now()
+
+	for _, started := range tracker.inFlight {
+		if running := now.Sub(started); running > timeout {
+			return fmt.Errorf(
+				"a handler has been running for %v, longer than %v",
+				running.Truncate(time.Second),
+				timeout,
+			)
+		}
+	}
+
+	return nil
+}
+
+// drain by cancelling every consumer, then waiting for them to finish
+// handling the messages they were sent
+func (tracker *handlerTracker) drain(ctx context.Context) error {
+	tracker.mu.Lock()
+
+	consumers := make(map[string]transport, len(tracker.consumers))
+
+	for consumerId, transport := range tracker.consumers {
+		consumers[consumerId] = transport
+	}
+
+	tracker.mu.Unlock()
+
+	for consumerId, transport := range consumers {
+		log.Debug(func(k *log.Log) {
+			k.Context = Context
+			k.Format("Cancelling consumer %#v to shut down!", consumerId)
+		})
+
+		if err := transport.cancel(consumerId); err != nil {
+			return fmt.Errorf(
+				"failed to cancel consumer %#v! %v",
+				consumerId,
+				err,
+			)
+		}
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+
+	defer ticker.Stop()
+
+	for {
+		tracker.mu.Lock()
+
+		var (
+			consumersLeft = len(tracker.consumers)
+			inFlightLeft  = len(tracker.inFlight)
+		)
+
+		tracker.mu.Unlock()
+
+		if consumersLeft == 0 && inFlightLeft == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf(
+				"%v consumers and %v handlers still running! %v",
+				consumersLeft,
+				inFlightLeft,
+				ctx.Err(),
+			)
+
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,418 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package memory
+
+// memory implements an in-process message broker that mimics the
+// behaviour of the RabbitMQ topic exchange that lib/queue uses, so
+// microservices can be tested in a single process without AMQP.
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type (
+	// Broker that routes messages published to a topic to every queue
+	// bound with a matching binding key
+	Broker struct {
+		mu sync.Mutex
+
+		queues map[string]*queue
+
+		// messageRetries before a message that's requeued is dead
+		// lettered instead
+		messageRetries int
+
+		closed bool
+	}
+
+	// Delivery of a message to a consumer, must be acked or nacked
+	Delivery struct {
+		Topic string
+		Body  []byte
+
+		// Headers sent with the message, nil if there weren't any
+		Headers map[string]string
+
+		// Attempts that were made to deliver this message, including
+		// this one
+		Attempts int
+
+		queue *queue
+	}
+
+	queue struct {
+		bindingKey string
+
+		// deadLetterEnabled to store nacked messages in the dead letter
+		// queue instead of dropping them
+		deadLetterEnabled bool
+
+		messageRetries int
+
+		pending    []Delivery
+		deadLetter []Delivery
+
+		cond *sync.Cond
+
+		consumers map[string]*consumer
+	}
+
+	consumer struct {
+		deliveries chan Delivery
+
+		// done is closed when the consumer is cancelled
+		done chan struct{}
+
+		cancelled bool
+	}
+)
+
+// New broker, with messages that are requeued more than messageRetries
+// times being dead lettered
+func New(messageRetries int) *Broker {
+	broker := &Broker{
+		queues:         make(map[string]*queue),
+		messageRetries: messageRetries,
+	}
+
+	return broker
+}
+
+// Consume messages from the queue with the name given, declaring it and
+// binding it to the binding key if it doesn't exist. Consumers sharing
+// the same queue name have messages distributed between them.
+func (broker *Broker) Consume(queueName, consumerId, bindingKey string, deadLetterEnabled bool) (<-chan Delivery, error) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if broker.closed {
+		return nil, fmt.Errorf(
+			"broker is closed, can't consume from %#v",
+			queueName,
+		)
+	}
+
+	q, exists := broker.queues[queueName]
+
+	switch {
+	case !exists:
+		q = &queue{
+			bindingKey:        bindingKey,
+			deadLetterEnabled: deadLetterEnabled,
+			messageRetries:    broker.messageRetries,
+			consumers:         make(map[string]*consumer),
+		}
+
+		q.cond = sync.NewCond(&broker.mu)
+
+		broker.queues[queueName] = q
+
+	case q.bindingKey != bindingKey:
+		return nil, fmt.Errorf(
+			"queue %#v is bound to %#v, not %#v",
+			queueName,
+			q.bindingKey,
+			bindingKey,
+		)
+	}
+
+	if _, exists := q.consumers[consumerId]; exists {
+		return nil, fmt.Errorf(
+			"consumer %#v is already consuming from %#v",
+			consumerId,
+			queueName,
+		)
+	}
+
+	c := &consumer{
+		deliveries: make(chan Delivery),
+		done:       make(chan struct{}),
+	}
+
+	q.consumers[consumerId] = c
+
+	go broker.dispatch(q, c)
+
+	return c.deliveries, nil
+}
+
+// Cancel the consumer with the id given, closing its channel once any
+// message that's being handed to it is returned to the queue
+func (broker *Broker) Cancel(consumerId string) error {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for _, q := range broker.queues {
+		c, exists := q.consumers[consumerId]
+
+		if !exists {
+			continue
+		}
+
+		delete(q.consumers, consumerId)
+
+		c.cancel()
+
+		q.cond.Broadcast()
+
+		return nil
+	}
+
+	return fmt.Errorf(
+		"no consumer with id %#v",
+		consumerId,
+	)
+}
+
+// Publish a message to every queue with a binding key that matches the
+// topic. Messages published to a topic without any queues are dropped
+// as they would be with RabbitMQ.
+func (broker *Broker) Publish(topic string, body []byte) error {
+	return broker.PublishWithHeaders(topic, body, nil)
+}
+
+// PublishWithHeaders to every queue with a binding key that matches the
+// topic, like Publish
+func (broker *Broker) PublishWithHeaders(topic string, body []byte, headers map[string]string) error {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if broker.closed {
+		return fmt.Errorf(
+			"broker is closed, can't publish to %#v",
+			topic,
+		)
+	}
+
+	for _, q := range broker.queues {
+		if !TopicMatches(q.bindingKey, topic) {
+			continue
+		}
+
+		// copy the body so consumers can't modify each other's messages
+
+		bodyCopy := make([]byte, len(body))
+
+		copy(bodyCopy, body)
+
+		q.pending = append(q.pending, Delivery{
+			Topic:   topic,
+			Body:    bodyCopy,
+			Headers: headers,
+			queue:   q,
+		})
+
+		q.cond.Signal()
+	}
+
+	return nil
+}
+
+// DeadLetters that were stored in the queue's dead letter queue
+func (broker *Broker) DeadLetters(queueName string) []Delivery {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	q, exists := broker.queues[queueName]
+
+	if !exists {
+		return nil
+	}
+
+	deadLetters := make([]Delivery, len(q.deadLetter))
+
+	copy(deadLetters, q.deadLetter)
+
+	return deadLetters
+}
+
+// Pending returns the number of messages waiting to be delivered from a queue
+func (broker *Broker) Pending(queueName string) int {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	q, exists := broker.queues[queueName]
+
+	if !exists {
+		return 0
+	}
+
+	return len(q.pending)
+}
+
+// Closed is true if the broker was closed
+func (broker *Broker) Closed() bool {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	return broker.closed
+}
+
+// Close the broker, closing every consumer's channel
+func (broker *Broker) Close() error {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	if broker.closed {
+		return nil
+	}
+
+	broker.closed = true
+
+	for _, q := range broker.queues {
+		for _, c := range q.consumers {
+			c.cancel()
+		}
+
+		q.cond.Broadcast()
+	}
+
+	return nil
+}
+
+// Ack the delivery, removing it from the queue
+func (delivery Delivery) Ack() error {
+	if delivery.queue == nil {
+		return fmt.Errorf("delivery was not received from a broker")
+	}
+
+	return nil
+}
+
+// Retry the delivery by requeueing it, dead lettering it instead if
+// the number of attempts has reached the broker's retry limit
+func (delivery Delivery) Retry() error {
+	return delivery.nack(true)
+}
+
+// Reject the delivery, dead lettering it immediately
+func (delivery Delivery) Reject() error {
+	return delivery.nack(false)
+}
+
+func (delivery Delivery) nack(requeue bool) error {
+	q := delivery.queue
+
+	if q == nil {
+		return fmt.Errorf("delivery was not received from a broker")
+	}
+
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+
+	if requeue && delivery.Attempts < q.messageRetries {
+		q.pending = append(q.pending, delivery)
+		q.cond.Signal()
+
+		return nil
+	}
+
+	if q.deadLetterEnabled {
+		q.deadLetter = append(q.deadLetter, delivery)
+	}
+
+	return nil
+}
+
+// dispatch messages from the queue's pending list to a consumer
+func (broker *Broker) dispatch(q *queue, c *consumer) {
+	defer close(c.deliveries)
+
+	for {
+		broker.mu.Lock()
+
+		for len(q.pending) == 0 && !c.cancelled {
+			q.cond.Wait()
+		}
+
+		if c.cancelled {
+			broker.mu.Unlock()
+			return
+		}
+
+		delivery := q.pending[0]
+
+		q.pending = q.pending[1:]
+
+		delivery.Attempts++
+
+		broker.mu.Unlock()
+
+		select {
+		case c.deliveries <- delivery:
+
+		case <-c.done:
+			// return the message to the front of the queue, as it was
+			// never received by the consumer
+
+			broker.mu.Lock()
+
+			delivery.Attempts--
+
+			q.pending = append([]Delivery{delivery}, q.pending...)
+
+			q.cond.Signal()
+
+			broker.mu.Unlock()
+
+			return
+		}
+	}
+}
+
+func (c *consumer) cancel() {
+	if c.cancelled {
+		return
+	}
+
+	c.cancelled = true
+
+	close(c.done)
+}
+
+// TopicMatches using the rules of a RabbitMQ topic exchange, where * in
+// the binding key matches exactly one word and # matches zero or more
+// words
+func TopicMatches(bindingKey, topic string) bool {
+	return wordsMatch(
+		strings.Split(bindingKey, "."),
+		strings.Split(topic, "."),
+	)
+}
+
+func wordsMatch(bindingWords, topicWords []string) bool {
+	if len(bindingWords) == 0 {
+		return len(topicWords) == 0
+	}
+
+	switch bindingWord := bindingWords[0]; bindingWord {
+	case "#":
+		// # can consume any number of words, including zero
+
+		for i := 0; i <= len(topicWords); i++ {
+			if wordsMatch(bindingWords[1:], topicWords[i:]) {
+				return true
+			}
+		}
+
+		return false
+
+	case "*":
+		if len(topicWords) == 0 {
+			return false
+		}
+
+		return wordsMatch(bindingWords[1:], topicWords[1:])
+
+	default:
+		if len(topicWords) == 0 || topicWords[0] != bindingWord {
+			return false
+		}
+
+		return wordsMatch(bindingWords[1:], topicWords[1:])
+	}
+}
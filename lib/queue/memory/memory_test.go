@@ -0,0 +1,215 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTimeout = 5 * time.Second
+
+func receive(t *testing.T, deliveries <-chan Delivery) Delivery {
+	select {
+	case delivery := <-deliveries:
+		return delivery
+
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for a delivery!")
+	}
+
+	return Delivery{}
+}
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		bindingKey, topic string
+		matches           bool
+	}{
+		{"ethereum.block.header", "ethereum.block.header", true},
+		{"ethereum.block.header", "ethereum.block.log", false},
+		{"ethereum.*.header", "ethereum.block.header", true},
+		{"ethereum.*", "ethereum.block.header", false},
+		{"ethereum.#", "ethereum.block.header", true},
+		{"ethereum.#", "ethereum", true},
+		{"#", "ethereum.block.header", true},
+		{"#.header", "ethereum.block.header", true},
+		{"*.block.#", "ethereum.block.header.fusdc", true},
+		{"*.block.#", "block.header", false},
+		{"solana.#", "ethereum.block.header", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(
+			t,
+			test.matches,
+			TopicMatches(test.bindingKey, test.topic),
+			"TopicMatches(%#v, %#v)",
+			test.bindingKey,
+			test.topic,
+		)
+	}
+}
+
+func TestPublishRoutesToEveryBoundQueue(t *testing.T) {
+	broker := New(5)
+
+	defer broker.Close()
+
+	headers, err := broker.Consume("ethereum.block.header.worker-a", "worker-a.1", "ethereum.block.header", true)
+
+	require.NoError(t, err)
+
+	everything, err := broker.Consume("ethereum.#.logger", "logger.1", "ethereum.#", true)
+
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Publish("ethereum.block.header", []byte("header")))
+	require.NoError(t, broker.Publish("ethereum.block.log", []byte("log")))
+
+	delivery := receive(t, headers)
+
+	assert.Equal(t, "ethereum.block.header", delivery.Topic)
+	assert.Equal(t, []byte("header"), delivery.Body)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.NoError(t, delivery.Ack())
+
+	assert.Equal(t, []byte("header"), receive(t, everything).Body)
+	assert.Equal(t, []byte("log"), receive(t, everything).Body)
+
+	assert.Zero(t, broker.Pending("ethereum.block.header.worker-a"))
+}
+
+func TestConsumersSharingAQueue(t *testing.T) {
+	const queueName = "topic.worker"
+
+	broker := New(5)
+
+	defer broker.Close()
+
+	first, err := broker.Consume(queueName, "worker.1", "topic", true)
+
+	require.NoError(t, err)
+
+	second, err := broker.Consume(queueName, "worker.2", "topic", true)
+
+	require.NoError(t, err)
+
+	_, err = broker.Consume(queueName, "worker.2", "topic", true)
+
+	assert.Error(t, err, "consumer ids should be unique")
+
+	_, err = broker.Consume(queueName, "worker.3", "other-topic", true)
+
+	assert.Error(t, err, "queues can't be rebound")
+
+	// consumers sharing a queue should only receive the message once
+
+	require.NoError(t, broker.Publish("topic", []byte("once")))
+
+	var delivery Delivery
+
+	select {
+	case delivery = <-first:
+	case delivery = <-second:
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for a delivery!")
+	}
+
+	assert.Equal(t, []byte("once"), delivery.Body)
+
+	require.NoError(t, broker.Cancel("worker.1"))
+
+	assert.Error(t, broker.Cancel("worker.1"))
+
+	// the first consumer's channel should be closed, with the second
+	// receiving everything
+
+	for range first {
+	}
+
+	require.NoError(t, broker.Publish("topic", []byte("second")))
+
+	assert.Equal(t, []byte("second"), receive(t, second).Body)
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	const (
+		queueName      = "topic.worker"
+		messageRetries = 3
+	)
+
+	broker := New(messageRetries)
+
+	defer broker.Close()
+
+	deliveries, err := broker.Consume(queueName, "worker.1", "topic", true)
+
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Publish("topic", []byte("poison")))
+
+	for i := 1; i <= messageRetries; i++ {
+		delivery := receive(t, deliveries)
+
+		assert.Equal(t, i, delivery.Attempts)
+
+		require.NoError(t, delivery.Retry())
+	}
+
+	deadLetters := broker.DeadLetters(queueName)
+
+	require.Len(t, deadLetters, 1)
+
+	assert.Equal(t, []byte("poison"), deadLetters[0].Body)
+	assert.Equal(t, messageRetries, deadLetters[0].Attempts)
+
+	require.NoError(t, broker.Publish("topic", []byte("rejected")))
+
+	require.NoError(t, receive(t, deliveries).Reject())
+
+	assert.Len(t, broker.DeadLetters(queueName), 2)
+}
+
+func TestDeadLetterDisabled(t *testing.T) {
+	const queueName = "topic.worker"
+
+	broker := New(1)
+
+	defer broker.Close()
+
+	deliveries, err := broker.Consume(queueName, "worker.1", "topic", false)
+
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Publish("topic", []byte("dropped")))
+
+	require.NoError(t, receive(t, deliveries).Reject())
+
+	assert.Empty(t, broker.DeadLetters(queueName))
+}
+
+func TestClose(t *testing.T) {
+	broker := New(5)
+
+	deliveries, err := broker.Consume("topic.worker", "worker.1", "topic", true)
+
+	require.NoError(t, err)
+
+	require.NoError(t, broker.Close())
+
+	select {
+	case _, open := <-deliveries:
+		assert.False(t, open, "deliveries should be closed!")
+
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for the deliveries to close!")
+	}
+
+	assert.Error(t, broker.Publish("topic", nil))
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import "github.com/fluidity-money/fluidity-app/lib/metrics"
+
+// metrics are labelled with the topic that was subscribed to, not the
+// routing key of each message, to keep the number of series small
+
+var (
+	messagesConsumedCount = metrics.NewCounterVec(
+		"queue_messages_consumed_total",
+		"Messages received from the queue, by topic.",
+		"topic",
+	)
+
+	messagesAckedCount = metrics.NewCounterVec(
+		"queue_messages_acked_total",
+		"Messages that were handled and acked, by topic.",
+		"topic",
+	)
+
+	messagesNackedCount = metrics.NewCounterVec(
+		"queue_messages_nacked_total",
+		"Messages that were nacked, whether requeued or dead lettered, by topic.",
+		"topic",
+	)
+
+	messagesRetriedCount = metrics.NewCounterVec(
+		"queue_messages_retried_total",
+		"Messages that failed and were requeued to be tried again, by topic.",
+		"topic",
+	)
+
+	messagesDeadLetteredCount = metrics.NewCounterVec(
+		"queue_messages_dead_lettered_total",
+		"Messages that were dead lettered, by topic.",
+		"topic",
+	)
+
+	handlerDuration = metrics.NewHistogramVec(
+		"queue_handler_duration_seconds",
+		"Time taken by handlers to process a message, by topic.",
+		metrics.DefaultBuckets,
+		"topic",
+	)
+)
+
+// countNack of a message, counting it as retried or dead lettered
+func countNack(topic string, requeue bool) {
+	messagesNackedCount.Inc(topic)
+
+	if requeue {
+		messagesRetriedCount.Inc(topic)
+	} else {
+		messagesDeadLetteredCount.Inc(topic)
+	}
+}
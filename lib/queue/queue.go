@@ -4,19 +4,21 @@
 
 package queue
 
-// queue implements code that talks to RabbitMQ over AMQP.
+// queue implements code that talks to RabbitMQ over AMQP, or an
+// in-process broker if FLU_AMQP_QUEUE_TRANSPORT is set to "memory".
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sync"
+	"time"
 
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
-	"github.com/fluidity-money/fluidity-app/lib/state"
-	"github.com/fluidity-money/fluidity-app/lib/util"
-
-	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/fluidity-money/fluidity-app/lib/trace"
 
 	"github.com/getsentry/sentry-go"
 )
@@ -34,10 +36,25 @@ const (
 	// EnvQueueAddr is the address to access RabbitMQ.
 	EnvQueueAddr = `FLU_AMQP_QUEUE_ADDR`
 
+	// EnvRedisAddr to track retries in when using the AMQP transport,
+	// the same variable that lib/state uses
+	EnvRedisAddr = `FLU_REDIS_ADDR`
+
+	// EnvRedisPassword to authenticate to Redis with, if set
+	EnvRedisPassword = `FLU_REDIS_PASSWORD`
+
+	// EnvQueueTransport to use to send and receive messages, either
+	// "amqp" (the default) or "memory" for an in-process broker
+	EnvQueueTransport = `FLU_AMQP_QUEUE_TRANSPORT`
+
 	// EnvDeadLetterEnabled to disable the use of dead letter queues
 	// (disabling with "false" implies disabling retries)
 	EnvDeadLetterEnabled = `FLU_AMQP_QUEUE_DEAD_LETTER_ENABLED`
 
+	// EnvPublisherConfirmsEnabled to disable waiting for the server to
+	// confirm each message that's sent if set to "false"
+	EnvPublisherConfirmsEnabled = `FLU_AMQP_QUEUE_PUBLISHER_CONFIRMS_ENABLED`
+
 	// EnvMessageRetries to attempt until giving up - defaults to
 	// 5 if not set!
 	EnvMessageRetries = `FLU_AMQP_QUEUE_MESSAGE_RETRIES`
@@ -49,17 +66,43 @@ const (
 	// EnvMessageLoggingEnabled to log all incoming messages using a log
 	// debug if set to anything other than ""
 	EnvMessageLoggingEnabled = `FLU_DEBUG_MESSAGE_LOGGING_ENABLED`
+
+	// EnvHandlerStallSeconds to fail the liveness probe after if a
+	// handler is still running, defaults to 300
+	EnvHandlerStallSeconds = `FLU_AMQP_QUEUE_HANDLER_STALL_SECONDS`
+
+	// PublishConfirmTimeout to wait for the server to confirm a message
+	// before giving up
+	PublishConfirmTimeout = 30 * time.Second
 )
 
 type Message struct {
 	Topic   string    `json:"topic"`
 	Content io.Reader `json:"content"`
+
+	// ctx the message is being handled with
+	ctx context.Context
 }
 
+// Context the message is being handled with, containing the span it's
+// handled in. Messages sent with it (using SendMessageContext) continue
+// the same trace
+func (message Message) Context() context.Context {
+	if message.ctx == nil {
+		return context.Background()
+	}
+
+	return message.ctx
+}
+
+// Decode the message's JSON content, unwrapping it if it was sent in an
+// envelope
 func (message Message) Decode(decoded interface{}) {
-	content := message.Content
+	payload, err := readPayload(message.Content)
 
-	err := json.NewDecoder(content).Decode(decoded)
+	if err == nil {
+		err = json.Unmarshal(payload, decoded)
+	}
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
@@ -86,12 +129,11 @@ func GetMessages(topic string, f func(message Message)) {
 	amqpDetails := <-chanAmqpDetails
 
 	var (
-		channel           = amqpDetails.channel
-		exchangeName      = amqpDetails.exchangeName
-		workerId          = amqpDetails.workerId
-		deadLetterEnabled = amqpDetails.deadLetterEnabled
-		messageRetries    = amqpDetails.messageRetries
-		goroutines        = amqpDetails.goroutines
+		transport             = amqpDetails.transport
+		workerId              = amqpDetails.workerId
+		deadLetterEnabled     = amqpDetails.deadLetterEnabled
+		messageRetries        = amqpDetails.messageRetries
+		goroutines            = amqpDetails.goroutines
 		messageLoggingEnabled = amqpDetails.messageLoggingEnabled
 	)
 
@@ -100,12 +142,10 @@ func GetMessages(topic string, f func(message Message)) {
 		queueName  = fmt.Sprintf("%v.%v", topic, workerId)
 	)
 
-	messages, err := queueConsume(
+	messages, err := transport.consume(
 		queueName,
 		topic,
-		exchangeName,
 		consumerId,
-		channel,
 		deadLetterEnabled,
 	)
 
@@ -114,54 +154,58 @@ func GetMessages(topic string, f func(message Message)) {
 			k.Context = Context
 
 			k.Format(
-				"Failed to start to consume queue %#v topic %#v, consumer id %#v, exchange %#v!",
+				"Failed to start to consume queue %#v topic %#v, consumer id %#v!",
 				queueName,
 				topic,
 				consumerId,
-				exchangeName,
 			)
 
 			k.Payload = err
 		})
 	}
 
+	handlers.addConsumer(consumerId, transport)
+
 	// have an internalQueue that processes messages to support
 	// incoming ones with the concurrency optionally enabled
 
-	internalQueue := make(chan amqp.Delivery)
+	var (
+		internalQueue = make(chan delivery)
+
+		wg sync.WaitGroup
+	)
 
 	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
 		go func() {
+			defer wg.Done()
+
 			for message := range internalQueue {
 				var (
-					deliveryTag = message.DeliveryTag
-					body        = message.Body
-					routingKey  = message.RoutingKey
+					deliveryTag = message.deliveryTag
+					body        = message.body
+					routingKey  = message.routingKey
 				)
 
 				bodyBuf := bytes.NewBuffer(body)
 
-				// risk of a collision is near 0 enough to be ignored.
+				messagesConsumedCount.Inc(topic)
 
-				retryKey := fmt.Sprintf(
-					"worker.%#v.retry.%#v.%#v",
-					workerId,
-					topic,
-					util.GetB16Hash(body),
-				)
+				retryKey := getRetryKey(workerId, topic, body)
 
 				if deadLetterEnabled {
-					retryCount := state.Incr(retryKey)
-
-					state.Expire(retryKey, 86400) // 24 hours
+					retryCount := transport.countAttempt(retryKey, message)
 
 					// the number of retries exceeds the max retry count! giving up!
 
-					if int(retryCount) >= messageRetries {
+					if retryCount >= messageRetries {
+
+						countNack(topic, false)
 
-						queueNackDeliveryTag(channel, deliveryTag)
+						_ = message.nack(false)
 
-						state.Del(retryKey)
+						transport.clearAttempts(retryKey)
 
 						continue
 					}
@@ -178,11 +222,28 @@ func GetMessages(topic string, f func(message Message)) {
 					})
 				}
 
+				span := startConsumeSpan(topic, message)
+
+				ctx := trace.ContextWithSpan(context.Background(), span)
+
+				ctx = contextWithDelivery(ctx, message)
+
+				handlerId := handlers.start()
+
+				handlerStart := time.Now()
+
 				f(Message{
 					Topic:   routingKey,
 					Content: bodyBuf,
+					ctx:     ctx,
 				})
 
+				handlerDuration.ObserveSince(handlerStart, topic)
+
+				handlers.finish(handlerId)
+
+				span.Finish()
+
 				log.Debug(func(k *log.Log) {
 					k.Context = Context
 
@@ -192,7 +253,7 @@ func GetMessages(topic string, f func(message Message)) {
 					)
 				})
 
-				if err := queueAckDeliveryTag(channel, deliveryTag); err != nil {
+				if err := message.ack(); err != nil {
 					log.Fatal(func(k *log.Log) {
 						k.Context = Context
 
@@ -210,10 +271,12 @@ func GetMessages(topic string, f func(message Message)) {
 					k.Format("Server acked the reply for %v!", deliveryTag)
 				})
 
+				messagesAckedCount.Inc(topic)
+
 				// clean up the retry key
 
 				if deadLetterEnabled {
-					state.Del(retryKey)
+					transport.clearAttempts(retryKey)
 				}
 			}
 		}()
@@ -224,61 +287,128 @@ func GetMessages(topic string, f func(message Message)) {
 	for message := range messages {
 		internalQueue <- message
 	}
+
+	// the consumer was cancelled or the transport closed, so wait for
+	// the handlers to finish
+
+	close(internalQueue)
+
+	wg.Wait()
+
+	handlers.removeConsumer(consumerId)
+
+	// if we're shutting down, let the lifecycle package exit once
+	// everything's drained instead of returning to main
+
+	lifecycle.Wait()
 }
 
-// SendMessage down a topic, with the JSON form of the content.
+// SendMessage down a topic, with the JSON form of the content. Blocks
+// until the server confirms the message unless publisher confirms are
+// disabled.
 func SendMessage(topic string, content interface{}) {
+	SendMessageContext(context.Background(), topic, content)
+}
+
+// SendMessageContext down a topic with the JSON form of the content,
+// continuing the trace in the context given
+func SendMessageContext(ctx context.Context, topic string, content interface{}) {
 	contentBytes, err := json.Marshal(content)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
 			k.Message = "Failed to encode a JSON structure!"
 			k.Payload = err
+			k.Span = trace.SpanFromContext(ctx)
 		})
 	}
 
-	SendMessageBytes(topic, contentBytes)
+	SendMessageBytesContext(ctx, topic, contentBytes)
 }
 
 // SendMessageBytes down a topic, with bytes as content
 func SendMessageBytes(topic string, content []byte) {
-	log.Debug(func(k *log.Log) {
-		k.Context = Context
-		k.Message = "Starting to send a publish request to the sending goroutine."
-	})
-
-	amqpDetails := <-chanAmqpDetails
-
-	var (
-		channel      = amqpDetails.channel
-		exchangeName = amqpDetails.exchangeName
-	)
+	SendMessageBytesContext(context.Background(), topic, content)
+}
 
-	err := queuePublish(topic, exchangeName, content, channel)
+// SendMessageBytesContext down a topic with bytes as content,
+// continuing the trace in the context given
+func SendMessageBytesContext(ctx context.Context, topic string, content []byte) {
+	err := TrySendMessageBytesContext(ctx, topic, content)
 
 	if err != nil {
 		log.Fatal(func(k *log.Log) {
 			k.Context = Context
 
 			k.Format(
-				"Failed to publish to the queue with topic %#v, exchange %#v! Error %#v",
+				"Failed to publish to the queue with topic %#v! Error %#v",
 				topic,
-				exchangeName,
 				err,
 			)
 
 			k.Payload = string(content)
+			k.Span = trace.SpanFromContext(ctx)
 		})
 	}
+}
+
+// TrySendMessage down a topic with the JSON form of the content,
+// returning an error instead of exiting if it isn't confirmed
+func TrySendMessage(topic string, content interface{}) error {
+	contentBytes, err := json.Marshal(content)
+
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode a JSON structure! %v",
+			err,
+		)
+	}
+
+	return TrySendMessageBytes(topic, contentBytes)
+}
+
+// TrySendMessageBytes down a topic with bytes as content, returning an
+// error instead of exiting if it isn't confirmed
+func TrySendMessageBytes(topic string, content []byte) error {
+	return TrySendMessageBytesContext(context.Background(), topic, content)
+}
+
+// TrySendMessageBytesContext down a topic with bytes as content,
+// continuing the trace in the context given and returning an error
+// instead of exiting if it isn't confirmed
+func TrySendMessageBytesContext(ctx context.Context, topic string, content []byte) error {
+	span, headers := startPublishSpan(ctx, topic)
+
+	defer span.Finish()
+
+	log.Debug(func(k *log.Log) {
+		k.Context = Context
+		k.Message = "Starting to send a publish request to the sending goroutine."
+		k.Span = span
+	})
+
+	amqpDetails := <-chanAmqpDetails
+
+	transport := amqpDetails.transport
+
+	headers = addReplayHeader(ctx, headers)
+
+	if err := transport.publish(topic, content, headers); err != nil {
+		span.Fail(err)
+		return err
+	}
 
 	log.Debug(func(k *log.Log) {
 		k.Context = Context
 		k.Message = "Sending goroutine has received the request!"
+		k.Span = span
 	})
+
+	return nil
 }
 
 // Finish up, by clearing the buffer
 func Finish() {
 	amqpDetails := <-chanAmqpDetails
-	amqpDetails.channel.Close()
+	_ = amqpDetails.transport.close()
 }
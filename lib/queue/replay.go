@@ -0,0 +1,119 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+// replay tags messages that were sent to backfill old blocks, so
+// services can skip side effects (like paying out rewards) that already
+// happened the first time the blocks were seen. Like the trace context,
+// the tag is passed between services in message headers and carried in
+// the context a message is handled with, so anything sent with that
+// context is tagged as a replay as well
+
+import (
+	"context"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/trace"
+)
+
+// HeaderReplay is set to "true" on messages sent as part of a replay
+const HeaderReplay = `x-fluidity-replay`
+
+// replayContextKey to mark a context as replaying with
+type replayContextKey struct{}
+
+// WithReplay returns a copy of the context that tags every message sent
+// with it as a replay
+func WithReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayContextKey{}, true)
+}
+
+// IsReplay is true if the context was made with WithReplay, or is the
+// context of a message that was sent as part of a replay
+func IsReplay(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+
+	replay, _ := ctx.Value(replayContextKey{}).(bool)
+
+	return replay
+}
+
+// IsReplay is true if the message was sent as part of a replay
+func (message Message) IsReplay() bool {
+	return IsReplay(message.Context())
+}
+
+// SkipReplays wraps a handler so messages sent as part of a replay are
+// acked without calling it, for handlers with side effects (like paying
+// out rewards) that already happened the first time the blocks were seen
+func SkipReplays(f func(Message)) func(Message) {
+	return func(message Message) {
+		if !message.IsReplay() {
+			f(message)
+			return
+		}
+
+		log.App(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Skipping a message on topic %v that was replayed!",
+				message.Topic,
+			)
+
+			k.Span = trace.SpanFromContext(message.Context())
+		})
+	}
+}
+
+// SkipReplayedEnvelopes wraps an envelope handler like SkipReplays, for
+// consumers that use GetEnvelopesContext
+func SkipReplayedEnvelopes(f func(ctx context.Context, decoded interface{})) func(context.Context, interface{}) {
+	return func(ctx context.Context, decoded interface{}) {
+		if !IsReplay(ctx) {
+			f(ctx, decoded)
+			return
+		}
+
+		log.App(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Skipping an envelope that was replayed!"
+			k.Span = trace.SpanFromContext(ctx)
+		})
+	}
+}
+
+// isReplayDelivery if the message was tagged as a replay when it was sent
+func isReplayDelivery(message delivery) bool {
+	return message.headers[HeaderReplay] == "true"
+}
+
+// contextWithDelivery to handle a message with, marking it as a replay
+// if it was sent as one
+func contextWithDelivery(ctx context.Context, message delivery) context.Context {
+	if !isReplayDelivery(message) {
+		return ctx
+	}
+
+	return WithReplay(ctx)
+}
+
+// addReplayHeader to the headers of a message being sent if the context
+// is replaying
+func addReplayHeader(ctx context.Context, headers map[string]string) map[string]string {
+	if !IsReplay(ctx) {
+		return headers
+	}
+
+	if headers == nil {
+		headers = make(map[string]string, 1)
+	}
+
+	headers[HeaderReplay] = "true"
+
+	return headers
+}
@@ -0,0 +1,148 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayContext(t *testing.T) {
+	ctx := context.Background()
+
+	assert.False(t, IsReplay(ctx))
+	assert.Nil(t, addReplayHeader(ctx, nil))
+
+	ctx = WithReplay(ctx)
+
+	assert.True(t, IsReplay(ctx))
+	assert.Equal(t, "true", addReplayHeader(ctx, nil)[HeaderReplay])
+
+	// messages made outside a handler aren't replays
+
+	assert.False(t, Message{}.IsReplay())
+}
+
+func TestConsumeTagsReplays(t *testing.T) {
+	type received struct {
+		isReplay bool
+		headers  map[string]string
+	}
+
+	var (
+		details  = newTestDetails(t, 5)
+		messages = make(chan received, 2)
+	)
+
+	consumer := startTestConsumer(t, details, "test.replay", func(ctx context.Context, message Message) error {
+		messages <- received{
+			isReplay: message.IsReplay(),
+
+			// the headers anything sent while handling it would have
+
+			headers: addReplayHeader(ctx, nil),
+		}
+
+		return nil
+	})
+
+	replayHeaders := addReplayHeader(WithReplay(context.Background()), nil)
+
+	require.NoError(t, consumer.transport.publish("test.replay", []byte(`1`), replayHeaders))
+	require.NoError(t, consumer.transport.publish("test.replay", []byte(`2`), nil))
+
+	for _, expected := range []bool{true, false} {
+		select {
+		case message := <-messages:
+			assert.Equal(t, expected, message.isReplay)
+			assert.Equal(t, expected, message.headers[HeaderReplay] == "true")
+
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for the message!")
+		}
+	}
+
+	assert.NoError(t, consumer.stop(t))
+}
+
+func TestSkipReplays(t *testing.T) {
+	var (
+		details = newTestDetails(t, 5)
+		handled = make(chan string, 2)
+		acked   = make(chan struct{}, 2)
+	)
+
+	// the handler that would pay out, skipped for replays
+
+	payOut := SkipReplays(func(message Message) {
+		var content string
+
+		message.Decode(&content)
+
+		handled <- content
+	})
+
+	consumer := startTestConsumer(t, details, "test.skip", func(_ context.Context, message Message) error {
+		payOut(message)
+
+		acked <- struct{}{}
+
+		return nil
+	})
+
+	replayHeaders := addReplayHeader(WithReplay(context.Background()), nil)
+
+	require.NoError(t, consumer.transport.publish("test.skip", []byte(`"replayed"`), replayHeaders))
+	require.NoError(t, consumer.transport.publish("test.skip", []byte(`"live"`), nil))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-acked:
+		case <-time.After(testTimeout):
+			t.Fatal("timed out waiting for the messages!")
+		}
+	}
+
+	assert.NoError(t, consumer.stop(t))
+
+	close(handled)
+
+	var contents []string
+
+	for content := range handled {
+		contents = append(contents, content)
+	}
+
+	assert.Equal(t, []string{"live"}, contents)
+	assert.Equal(t, 0, consumer.deadLetters())
+}
+
+func TestHeadersRoundTrip(t *testing.T) {
+	headers := Headers(WithReplay(context.Background()))
+
+	assert.Equal(t, "true", headers[HeaderReplay])
+
+	assert.True(t, IsReplay(ContextFromHeaders(headers)))
+
+	assert.False(t, IsReplay(ContextFromHeaders(Headers(context.Background()))))
+	assert.False(t, IsReplay(ContextFromHeaders(nil)))
+}
+
+func TestSkipReplayedEnvelopes(t *testing.T) {
+	var handled []interface{}
+
+	payOut := SkipReplayedEnvelopes(func(_ context.Context, decoded interface{}) {
+		handled = append(handled, decoded)
+	})
+
+	payOut(WithReplay(context.Background()), "replayed")
+	payOut(context.Background(), "live")
+
+	assert.Equal(t, []interface{}{"live"}, handled)
+}
@@ -5,6 +5,7 @@
 package queue
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -14,12 +15,11 @@ import (
 )
 
 type amqpDetails struct {
-	channel           *amqp.Channel
-	exchangeName      string
-	workerId          string
-	deadLetterEnabled bool
-	messageRetries    int
-	goroutines        int
+	transport             transport
+	workerId              string
+	deadLetterEnabled     bool
+	messageRetries        int
+	goroutines            int
 	messageLoggingEnabled bool
 }
 
@@ -162,7 +162,7 @@ func queueConsume(queueName, topic, exchangeName, consumerId string, channel *am
 	return messageChan, nil
 }
 
-func queuePublish(topic, exchangeName string, content []byte, channel *amqp.Channel) error {
+func queuePublish(topic, exchangeName string, content []byte, headers map[string]string, channel *amqp.Channel, confirmsEnabled bool) error {
 
 	publishing := amqp.Publishing{
 		DeliveryMode: amqp.Persistent,
@@ -171,6 +171,14 @@ func queuePublish(topic, exchangeName string, content []byte, channel *amqp.Chan
 		Body:         content,
 	}
 
+	if len(headers) != 0 {
+		publishing.Headers = make(amqp.Table, len(headers))
+
+		for key, value := range headers {
+			publishing.Headers[key] = value
+		}
+	}
+
 	log.Debug(func(k *log.Log) {
 		k.Context = Context
 
@@ -181,7 +189,8 @@ func queuePublish(topic, exchangeName string, content []byte, channel *amqp.Chan
 		)
 	})
 
-	err := channel.Publish(
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(
+		context.Background(),
 		exchangeName,
 		topic,
 		true,  // mandatory
@@ -199,13 +208,53 @@ func queuePublish(topic, exchangeName string, content []byte, channel *amqp.Chan
 		)
 	}
 
-	return nil
+	// confirmation is nil if the channel isn't in confirm mode
+
+	if !confirmsEnabled || confirmation == nil {
+		return nil
+	}
+
+	acked := make(chan bool, 1)
+
+	go func() {
+		acked <- confirmation.Wait()
+	}()
+
+	select {
+	case ack := <-acked:
+		if !ack {
+			return fmt.Errorf(
+				"server nacked the message published to %#v with delivery tag %v!",
+				topic,
+				confirmation.DeliveryTag,
+			)
+		}
+
+		log.Debug(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Server confirmed the message published to %#v with delivery tag %v!",
+				topic,
+				confirmation.DeliveryTag,
+			)
+		})
+
+		return nil
+
+	case <-time.After(PublishConfirmTimeout):
+		return fmt.Errorf(
+			"timed out after %v waiting for the server to confirm the message published to %#v!",
+			PublishConfirmTimeout,
+			topic,
+		)
+	}
 }
 
 func queueAckDeliveryTag(channel *amqp.Channel, deliveryTag uint64) error {
 	return channel.Ack(deliveryTag, false)
 }
 
-func queueNackDeliveryTag(channel *amqp.Channel, deliveryTag uint64) error {
-	return channel.Nack(deliveryTag, false, false)
+func queueNackDeliveryTag(channel *amqp.Channel, deliveryTag uint64, requeue bool) error {
+	return channel.Nack(deliveryTag, false, requeue)
 }
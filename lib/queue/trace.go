@@ -0,0 +1,101 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+// trace passes the trace context between services in message headers,
+// handing each handler a context with the span the message is handled
+// in, so anything it sends or logs with it is part of the same trace
+
+import (
+	"context"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/trace"
+)
+
+// startPublishSpan for a message being sent, as a child of the span in
+// the context, returning the headers to send the message with
+func startPublishSpan(ctx context.Context, topic string) (*trace.Span, map[string]string) {
+	span := trace.StartChild(ctx, "publish "+topic, trace.SpanKindProducer)
+
+	if span == nil {
+		return nil, nil
+	}
+
+	span.SetAttribute("messaging.destination", topic)
+
+	headers := map[string]string{
+		trace.HeaderTraceparent: span.Context.Traceparent(),
+	}
+
+	return span, headers
+}
+
+// startConsumeSpan for a message that was received, continuing the
+// trace in its headers if there is one
+func startConsumeSpan(topic string, message delivery) *trace.Span {
+	if !trace.Enabled() {
+		return nil
+	}
+
+	var parent trace.SpanContext
+
+	if traceparent, ok := message.headers[trace.HeaderTraceparent]; ok {
+		spanContext, err := trace.ParseTraceparent(traceparent)
+
+		if err != nil {
+			log.Debug(func(k *log.Log) {
+				k.Context = Context
+
+				k.Format(
+					"Ignoring a bad traceparent on topic %v!",
+					message.routingKey,
+				)
+
+				k.Payload = err
+			})
+		}
+
+		parent = spanContext
+	}
+
+	span := trace.StartSpan("consume "+topic, trace.SpanKindConsumer, parent)
+
+	span.SetAttribute("messaging.destination", message.routingKey)
+
+	return span
+}
+
+// Headers that a message sent with the context would be sent with, for
+// messages that are stored to be sent later with ContextFromHeaders
+func Headers(ctx context.Context) map[string]string {
+	headers := make(map[string]string)
+
+	if span := trace.SpanFromContext(ctx); span != nil {
+		headers[trace.HeaderTraceparent] = span.Context.Traceparent()
+	}
+
+	return addReplayHeader(ctx, headers)
+}
+
+// ContextFromHeaders that were returned by Headers, so messages sent
+// with it continue the trace and the replay they were stored in
+func ContextFromHeaders(headers map[string]string) context.Context {
+	ctx := context.Background()
+
+	if traceparent, ok := headers[trace.HeaderTraceparent]; ok {
+		spanContext, err := trace.ParseTraceparent(traceparent)
+
+		if err == nil {
+			ctx = trace.ContextWithSpan(ctx, trace.RemoteSpan(spanContext))
+		}
+	}
+
+	if headers[HeaderReplay] == "true" {
+		ctx = WithReplay(ctx)
+	}
+
+	return ctx
+}
@@ -0,0 +1,283 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+
+	"github.com/go-redis/redis/v8"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// retryKeyExpiry to expire retry keys in Redis after
+	retryKeyExpiry = 24 * time.Hour
+
+	// redisPingTimeout to wait for Redis to respond when checking the
+	// transport is healthy
+	redisPingTimeout = 5 * time.Second
+)
+
+// amqpTransport sends and receives messages using RabbitMQ, with
+// retries being tracked in Redis. Redis is connected to here rather than
+// with lib/state so the package can be used without it
+type amqpTransport struct {
+	channel      *amqp.Channel
+	exchangeName string
+
+	redisClient *redis.Client
+
+	// confirmsEnabled to wait for the server to confirm every message
+	// that's published
+	confirmsEnabled bool
+}
+
+// newAmqpTransport by connecting to the AMQP server and Redis, opening a
+// channel and declaring the exchange, putting the channel in confirm mode
+// if confirmsEnabled is set
+func newAmqpTransport(queueAddr, redisAddr, redisPassword, exchangeName, exchangeType string, confirmsEnabled bool) (*amqpTransport, error) {
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: redisPassword,
+		DB:       0,
+	})
+
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to connect to the Redis server to track retries with! %v",
+			err,
+		)
+	}
+
+	client, err := amqp.Dial(queueAddr)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to connect to AMQP server! %v",
+			err,
+		)
+	}
+
+	channel, err := client.Channel()
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to open a channel on AMQP! %v",
+			err,
+		)
+	}
+
+	go func() {
+		errors := make(chan *amqp.Error)
+
+		err, closed := <-channel.NotifyClose(errors)
+
+		if closed && err == nil {
+			log.Fatal(func(k *log.Log) {
+				k.Context = Context
+				k.Message = "AMQP closed on its own without an error!!!"
+			})
+		}
+
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Channel to AMQP closed prematurely with error!"
+			k.Payload = err
+		})
+	}()
+
+	err = channel.ExchangeDeclare(
+		exchangeName,
+		exchangeType,
+		true,  // durable
+		false, // autoDelete,
+		false, // internal
+		false, // noWait
+		nil,   // args
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to declare an exchange! %v",
+			err,
+		)
+	}
+
+	log.Debug(func(k *log.Log) {
+		k.Context = Context
+
+		k.Format(
+			"Declared a queue with name %#v type %#v!",
+			exchangeName,
+			exchangeType,
+		)
+	})
+
+	if confirmsEnabled {
+		if err := channel.Confirm(false); err != nil {
+			return nil, fmt.Errorf(
+				"failed to put the channel into confirm mode! %v",
+				err,
+			)
+		}
+	}
+
+	transport := &amqpTransport{
+		channel:         channel,
+		exchangeName:    exchangeName,
+		redisClient:     redisClient,
+		confirmsEnabled: confirmsEnabled,
+	}
+
+	return transport, nil
+}
+
+func (transport *amqpTransport) consume(queueName, topic, consumerId string, deadLetterEnabled bool) (<-chan delivery, error) {
+	channel := transport.channel
+
+	messages, err := queueConsume(
+		queueName,
+		topic,
+		transport.exchangeName,
+		consumerId,
+		channel,
+		deadLetterEnabled,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make(chan delivery)
+
+	go func() {
+		defer close(deliveries)
+
+		for message := range messages {
+			deliveryTag := message.DeliveryTag
+
+			deliveries <- delivery{
+				routingKey:  message.RoutingKey,
+				body:        message.Body,
+				deliveryTag: deliveryTag,
+				headers:     stringHeaders(message.Headers),
+
+				ack: func() error {
+					return queueAckDeliveryTag(channel, deliveryTag)
+				},
+
+				nack: func(requeue bool) error {
+					return queueNackDeliveryTag(channel, deliveryTag, requeue)
+				},
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+func (transport *amqpTransport) cancel(consumerId string) error {
+	return transport.channel.Cancel(consumerId, false)
+}
+
+func (transport *amqpTransport) publish(topic string, content []byte, headers map[string]string) error {
+	return queuePublish(
+		topic,
+		transport.exchangeName,
+		content,
+		headers,
+		transport.channel,
+		transport.confirmsEnabled,
+	)
+}
+
+func (transport *amqpTransport) countAttempt(retryKey string, _ delivery) int {
+	var (
+		redisClient = transport.redisClient
+		ctx         = context.Background()
+	)
+
+	retryCount, err := redisClient.Incr(ctx, retryKey).Result()
+
+	if err == nil {
+		err = redisClient.Expire(ctx, retryKey, retryKeyExpiry).Err()
+	}
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to count an attempt with retry key %#v!",
+				retryKey,
+			)
+
+			k.Payload = err
+		})
+	}
+
+	return int(retryCount)
+}
+
+func (transport *amqpTransport) clearAttempts(retryKey string) {
+	err := transport.redisClient.Del(context.Background(), retryKey).Err()
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+
+			k.Format(
+				"Failed to clear the attempts with retry key %#v!",
+				retryKey,
+			)
+
+			k.Payload = err
+		})
+	}
+}
+
+func (transport *amqpTransport) healthy() error {
+	if transport.channel.IsClosed() {
+		return fmt.Errorf("AMQP channel is closed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisPingTimeout)
+
+	defer cancel()
+
+	if err := transport.redisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis isn't responding! %v", err)
+	}
+
+	return nil
+}
+
+func (transport *amqpTransport) close() error {
+	_ = transport.redisClient.Close()
+
+	return transport.channel.Close()
+}
+
+// stringHeaders from an AMQP table, leaving out any values that aren't
+// strings
+func stringHeaders(table amqp.Table) map[string]string {
+	if len(table) == 0 {
+		return nil
+	}
+
+	headers := make(map[string]string, len(table))
+
+	for key, value := range table {
+		if value, ok := value.(string); ok {
+			headers[key] = value
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+import (
+	"fmt"
+
+	"github.com/fluidity-money/fluidity-app/lib/queue/memory"
+)
+
+// memoryTransport sends and receives messages using a broker in the
+// current process, with retries being tracked by the broker
+type memoryTransport struct {
+	broker *memory.Broker
+}
+
+func newMemoryTransport(messageRetries int) *memoryTransport {
+	return &memoryTransport{
+		broker: memory.New(messageRetries),
+	}
+}
+
+func (transport *memoryTransport) consume(queueName, topic, consumerId string, deadLetterEnabled bool) (<-chan delivery, error) {
+	messages, err := transport.broker.Consume(queueName, consumerId, topic, deadLetterEnabled)
+
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make(chan delivery)
+
+	go func() {
+		defer close(deliveries)
+
+		for message := range messages {
+			message := message
+
+			deliveries <- delivery{
+				routingKey: message.Topic,
+				body:       message.Body,
+				headers:    message.Headers,
+				attempts:   message.Attempts,
+
+				ack: message.Ack,
+
+				nack: func(requeue bool) error {
+					if requeue {
+						return message.Retry()
+					}
+
+					return message.Reject()
+				},
+			}
+		}
+	}()
+
+	return deliveries, nil
+}
+
+func (transport *memoryTransport) cancel(consumerId string) error {
+	return transport.broker.Cancel(consumerId)
+}
+
+func (transport *memoryTransport) publish(topic string, content []byte, headers map[string]string) error {
+	return transport.broker.PublishWithHeaders(topic, content, headers)
+}
+
+func (transport *memoryTransport) countAttempt(_ string, message delivery) int {
+	return message.attempts
+}
+
+func (transport *memoryTransport) clearAttempts(_ string) {}
+
+func (transport *memoryTransport) healthy() error {
+	if transport.broker.Closed() {
+		return fmt.Errorf("in-memory broker is closed")
+	}
+
+	return nil
+}
+
+func (transport *memoryTransport) close() error {
+	return transport.broker.Close()
+}
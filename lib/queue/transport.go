@@ -0,0 +1,66 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package queue
+
+const (
+	// TransportAmqp to send and receive messages using RabbitMQ
+	TransportAmqp = "amqp"
+
+	// TransportMemory to send and receive messages using an in-process
+	// broker, useful for testing the entire pipeline in one process
+	TransportMemory = "memory"
+)
+
+type (
+	// transport abstracts the broker that messages are sent and received
+	// over, so the AMQP server can be swapped for an in-process broker
+	transport interface {
+		// consume messages from the queue given, declaring it (and its
+		// dead letter queue) and binding it to the topic
+		consume(queueName, topic, consumerId string, deadLetterEnabled bool) (<-chan delivery, error)
+
+		// cancel the consumer with the id given, closing the channel
+		// returned by consume once every delivery is received
+		cancel(consumerId string) error
+
+		// publish the content to the topic given with the headers,
+		// which can be nil
+		publish(topic string, content []byte, headers map[string]string) error
+
+		// countAttempt that was made to process a message, returning
+		// the number of attempts made including this one
+		countAttempt(retryKey string, message delivery) int
+
+		// clearAttempts recorded for the message
+		clearAttempts(retryKey string)
+
+		// healthy returns an error if the transport can't be used
+		healthy() error
+
+		// close the transport, stopping any consumers
+		close() error
+	}
+
+	// delivery of a message from the transport
+	delivery struct {
+		routingKey  string
+		body        []byte
+		deliveryTag uint64
+
+		// headers that were sent with the message, with values that
+		// aren't strings left out
+		headers map[string]string
+
+		// attempts that the transport made to deliver this message if
+		// it tracks it, otherwise 0
+		attempts int
+
+		// ack the message, removing it from the queue
+		ack func() error
+
+		// nack the message, with requeue set to retry it
+		nack func(requeue bool) error
+	}
+)
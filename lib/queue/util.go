@@ -26,3 +26,16 @@ func generateRandomConsumerId(workerId string) string {
 
 	return consumerId
 }
+
+// getRetryKey to track the number of times a message was retried with
+// in Redis, the risk of a collision is near 0 enough to be ignored.
+func getRetryKey(workerId, topic string, body []byte) string {
+	retryKey := fmt.Sprintf(
+		"worker.%#v.retry.%#v.%#v",
+		workerId,
+		topic,
+		util.GetB16Hash(body),
+	)
+
+	return retryKey
+}
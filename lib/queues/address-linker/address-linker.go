@@ -13,12 +13,13 @@ type (
 	LinkedAddresses = types.LinkedAddresses
 )
 
-func LinkedAddressesEthereum(f func(LinkedAddresses)) {
-	queue.GetMessages(TopicLinkedAddresses, func(message queue.Message) {
-		var linkedAddresses LinkedAddresses
-
-		message.Decode(&linkedAddresses)
+var (
+	// SchemaLinkedAddresses to encode and decode linked addresses with
+	SchemaLinkedAddresses = queue.NewSchema(`address_linker.linked_addresses`, 1, queue.JsonDecoder(LinkedAddresses{}))
+)
 
-		f(linkedAddresses)
+func LinkedAddressesEthereum(f func(LinkedAddresses)) {
+	queue.GetEnvelopes(TopicLinkedAddresses, SchemaLinkedAddresses, func(decoded interface{}) {
+		f(decoded.(LinkedAddresses))
 	})
 }
@@ -15,22 +15,22 @@ type (
 	PositionUpdate = amm.AmmEventPositionUpdate
 )
 
-func PositionMintsEthereum(f func(PositionMint)) {
-	queue.GetMessages(TopicPositionMint, func(message queue.Message) {
-		var mint PositionMint
+var (
+	// SchemaPositionMint to encode and decode minted AMM positions with
+	SchemaPositionMint = queue.NewSchema(`amm.position_mint`, 1, queue.JsonDecoder(PositionMint{}))
 
-		message.Decode(&mint)
+	// SchemaPositionUpdate to encode and decode updated AMM positions with
+	SchemaPositionUpdate = queue.NewSchema(`amm.position_update`, 1, queue.JsonDecoder(PositionUpdate{}))
+)
 
-		f(mint)
+func PositionMintsEthereum(f func(PositionMint)) {
+	queue.GetEnvelopes(TopicPositionMint, SchemaPositionMint, func(decoded interface{}) {
+		f(decoded.(PositionMint))
 	})
 }
 
 func PositionUpdatesEthereum(f func(PositionUpdate)) {
-	queue.GetMessages(TopicPositionUpdate, func(message queue.Message) {
-		var update PositionUpdate
-
-		message.Decode(&update)
-
-		f(update)
+	queue.GetEnvelopes(TopicPositionUpdate, SchemaPositionUpdate, func(decoded interface{}) {
+		f(decoded.(PositionUpdate))
 	})
 }
@@ -0,0 +1,53 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package dry_run
+
+// dry_run contains queue code to publish the results of services in dry
+// run mode, so a new release can be run beside the live one and the
+// results compared
+
+import (
+	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+	types "github.com/fluidity-money/fluidity-app/lib/types/dry-run"
+)
+
+// TopicDryRunResults to publish the results of dry runs to
+const TopicDryRunResults = `dry_run.results`
+
+type Result = types.Result
+
+var (
+	// SchemaResult to encode and decode dry run results with
+	SchemaResult = queue.NewSchema(`dry_run.result`, 1, queue.JsonDecoder(Result{}))
+)
+
+// Publish the result of a dry run, logging it and sending it down the
+// results topic
+func Publish(result Result) {
+	log.App(func(k *log.Log) {
+		k.Format(
+			"Dry run of a transaction from %v to %v with hash %v, success %v, gas estimate %v!",
+			result.Sender,
+			result.Recipient,
+			result.TransactionHash,
+			result.Success,
+			result.GasEstimate,
+		)
+
+		if result.Error != "" {
+			k.Payload = result.Error
+		}
+	})
+
+	queue.SendEnvelope(TopicDryRunResults, SchemaResult, result)
+}
+
+// Results of dry runs from every service
+func Results(f func(Result)) {
+	queue.GetEnvelopes(TopicDryRunResults, SchemaResult, func(decoded interface{}) {
+		f(decoded.(Result))
+	})
+}
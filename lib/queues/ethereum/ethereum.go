@@ -8,7 +8,11 @@ package ethereum
 // receipts from upstream, safely decoding it appropriately. Intended
 // to be used with a fanout exchange, so topic names are randomly chosen.
 
-import "github.com/fluidity-money/fluidity-app/lib/queue"
+import (
+	"context"
+
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+)
 
 const (
 	// TopicLogs follow to get every contract log that's confirmed
@@ -16,24 +20,63 @@ const (
 
 	// TopicBlockHeaders follow to get every block header seen
 	TopicBlockHeaders = "ethereum.block.header"
+
+	// TopicChainReorgs follow to get every chain reorganisation seen by
+	// the header connector, sent before the headers that replaced the
+	// orphaned blocks
+	TopicChainReorgs = "ethereum.chain.reorg"
+
+	// TopicBlockHeadersReplay follow to get the headers of old blocks
+	// sent again by microservice-ethereum-replay-blocks, kept apart from
+	// the live headers so services that only follow the chain never see
+	// them
+	TopicBlockHeadersReplay = "ethereum.block.header.replay"
 )
 
-func Logs(f func(Log)) {
-	queue.GetMessages(TopicLogs, func(message queue.Message) {
-		var log Log
+var (
+	// SchemaLog to encode and decode contract logs with
+	SchemaLog = queue.NewSchema(`ethereum.log`, 1, queue.JsonDecoder(Log{}))
+
+	// SchemaBlockHeader to encode and decode block headers with
+	SchemaBlockHeader = queue.NewSchema(`ethereum.block_header`, 1, queue.JsonDecoder(BlockHeader{}))
+
+	// SchemaChainReorg to encode and decode chain reorganisations with
+	SchemaChainReorg = queue.NewSchema(`ethereum.chain_reorg`, 1, queue.JsonDecoder(ChainReorg{}))
+)
 
-		message.Decode(&log)
+// Logs that are confirmed, skipping logs sent again by
+// microservice-ethereum-replay-blocks so consumers with side effects
+// don't see them twice
+func Logs(f func(Log)) {
+	queue.GetEnvelopesContext(TopicLogs, SchemaLog, queue.SkipReplayedEnvelopes(func(_ context.Context, decoded interface{}) {
+		f(decoded.(Log))
+	}))
+}
 
-		f(log)
+// LogsContext that are confirmed, including logs that were replayed, with
+// a context that tags anything sent with it as a replay if the log was
+func LogsContext(f func(context.Context, Log)) {
+	queue.GetEnvelopesContext(TopicLogs, SchemaLog, func(ctx context.Context, decoded interface{}) {
+		f(ctx, decoded.(Log))
 	})
 }
 
 func BlockHeaders(f func(BlockHeader)) {
-	queue.GetMessages(TopicBlockHeaders, func(message queue.Message) {
-		var header BlockHeader
+	queue.GetEnvelopes(TopicBlockHeaders, SchemaBlockHeader, func(decoded interface{}) {
+		f(decoded.(BlockHeader))
+	})
+}
 
-		message.Decode(&header)
+// ReplayedBlockHeaders that were sent again to backfill old blocks, with
+// a context that tags anything sent with it as a replay
+func ReplayedBlockHeaders(f func(context.Context, BlockHeader)) {
+	queue.GetEnvelopesContext(TopicBlockHeadersReplay, SchemaBlockHeader, func(ctx context.Context, decoded interface{}) {
+		f(queue.WithReplay(ctx), decoded.(BlockHeader))
+	})
+}
 
-		f(header)
+func ChainReorgs(f func(ChainReorg)) {
+	queue.GetEnvelopes(TopicChainReorgs, SchemaChainReorg, func(decoded interface{}) {
+		f(decoded.(ChainReorg))
 	})
 }
@@ -12,4 +12,5 @@ import "github.com/fluidity-money/fluidity-app/lib/types/ethereum"
 type (
 	Log         = ethereum.Log
 	BlockHeader = ethereum.BlockHeader
+	ChainReorg  = ethereum.ChainReorg
 )
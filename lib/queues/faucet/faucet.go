@@ -13,12 +13,13 @@ const TopicFaucetRequest = "faucet.requests"
 
 type FaucetRequest = faucet.FaucetRequest
 
-func FaucetRequests(f func(request FaucetRequest)) {
-	queue.GetMessages(TopicFaucetRequest, func(m queue.Message) {
-		var faucetRequest FaucetRequest
-
-		m.Decode(&faucetRequest)
+var (
+	// SchemaFaucetRequest to encode and decode faucet requests with
+	SchemaFaucetRequest = queue.NewSchema(`faucet.request`, 1, queue.JsonDecoder(FaucetRequest{}))
+)
 
-		f(faucetRequest)
+func FaucetRequests(f func(request FaucetRequest)) {
+	queue.GetEnvelopes(TopicFaucetRequest, SchemaFaucetRequest, func(decoded interface{}) {
+		f(decoded.(FaucetRequest))
 	})
 }
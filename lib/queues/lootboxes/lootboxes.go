@@ -16,12 +16,13 @@ const TopicLootboxes = `lootboxes`
 
 type Lootbox = types.Lootbox
 
-func LootboxesAll(f func(Lootbox)) {
-	queue.GetMessages(TopicLootboxes, func(message queue.Message) {
-		var lootbox Lootbox
-
-		message.Decode(&lootbox)
+var (
+	// SchemaLootbox to encode and decode lootboxes with
+	SchemaLootbox = queue.NewSchema(`lootboxes.lootbox`, 1, queue.JsonDecoder(Lootbox{}))
+)
 
-		f(lootbox)
+func LootboxesAll(f func(Lootbox)) {
+	queue.GetEnvelopes(TopicLootboxes, SchemaLootbox, func(decoded interface{}) {
+		f(decoded.(Lootbox))
 	})
 }
@@ -28,32 +28,31 @@ type (
 	BufferedApplicationTransactions = worker.SolanaBufferedApplicationTransactions
 )
 
-func Slots(f func(Slot)) {
-	queue.GetMessages(TopicSlots, func(message queue.Message) {
-		var log Slot
+var (
+	// SchemaSlot to encode and decode slots with
+	SchemaSlot = queue.NewSchema(`solana.slot`, 1, queue.JsonDecoder(Slot{}))
+
+	// SchemaBufferedTransactionLog to encode and decode buffered transaction logs with
+	SchemaBufferedTransactionLog = queue.NewSchema(`solana.buffered_transaction_log`, 1, queue.JsonDecoder(BufferedTransactionLog{}))
 
-		message.Decode(&log)
+	// SchemaBufferedApplicationTransactions to encode and decode buffered application transactions with
+	SchemaBufferedApplicationTransactions = queue.NewSchema(`solana.buffered_application_transactions`, 1, queue.JsonDecoder(BufferedApplicationTransactions{}))
+)
 
-		f(log)
+func Slots(f func(Slot)) {
+	queue.GetEnvelopes(TopicSlots, SchemaSlot, func(decoded interface{}) {
+		f(decoded.(Slot))
 	})
 }
 
 func BufferedTransactionLogs(f func(BufferedTransactionLog)) {
-	queue.GetMessages(TopicBufferedTransactionLogs, func(message queue.Message) {
-		var bufferedTransactionLogs BufferedTransactionLog
-
-		message.Decode(&bufferedTransactionLogs)
-
-		f(bufferedTransactionLogs)
+	queue.GetEnvelopes(TopicBufferedTransactionLogs, SchemaBufferedTransactionLog, func(decoded interface{}) {
+		f(decoded.(BufferedTransactionLog))
 	})
 }
 
 func BufferedTransactions(f func(BufferedApplicationTransactions)) {
-	queue.GetMessages(TopicBufferedTransactions, func(message queue.Message) {
-		var bufferedTransaction BufferedApplicationTransactions
-
-		message.Decode(&bufferedTransaction)
-
-		f(bufferedTransaction)
+	queue.GetEnvelopes(TopicBufferedTransactions, SchemaBufferedApplicationTransactions, func(decoded interface{}) {
+		f(decoded.(BufferedApplicationTransactions))
 	})
 }
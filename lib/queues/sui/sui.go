@@ -23,22 +23,22 @@ const (
 
 type SuiAppFees = worker.SuiAppFees
 
-func Checkpoints(f func(Checkpoint)) {
-	queue.GetMessages(TopicCheckpoints, func(message queue.Message) {
-		var checkpoint Checkpoint
+var (
+	// SchemaCheckpoint to encode and decode checkpoint summaries with
+	SchemaCheckpoint = queue.NewSchema(`sui.checkpoint`, 1, queue.JsonDecoder(Checkpoint{}))
 
-		message.Decode(&checkpoint)
+	// SchemaDecoratedTransfers to encode and decode lists of decorated transfers with
+	SchemaDecoratedTransfers = queue.NewSchema(`sui.decorated_transfers`, 1, queue.JsonDecoder([]DecoratedTransfer(nil)))
+)
 
-		f(checkpoint)
+func Checkpoints(f func(Checkpoint)) {
+	queue.GetEnvelopes(TopicCheckpoints, SchemaCheckpoint, func(decoded interface{}) {
+		f(decoded.(Checkpoint))
 	})
 }
 
 func DecoratedTransfers(f func([]DecoratedTransfer)) {
-	queue.GetMessages(TopicDecoratedTransfers, func(message queue.Message) {
-		var decoratedTransfers []DecoratedTransfer
-
-		message.Decode(&decoratedTransfers)
-
-		f(decoratedTransfers)
+	queue.GetEnvelopes(TopicDecoratedTransfers, SchemaDecoratedTransfers, func(decoded interface{}) {
+		f(decoded.([]DecoratedTransfer))
 	})
 }
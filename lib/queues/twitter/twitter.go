@@ -19,12 +19,13 @@ type (
 	User  = twitter.User
 )
 
-func Tweets(f func(tweet Tweet)) {
-	queue.GetMessages(TopicTweets, func(m queue.Message) {
-		var tweet Tweet
-
-		m.Decode(&tweet)
+var (
+	// SchemaTweet to encode and decode tweets with
+	SchemaTweet = queue.NewSchema(`twitter.tweet`, 1, queue.JsonDecoder(Tweet{}))
+)
 
-		f(tweet)
+func Tweets(f func(tweet Tweet)) {
+	queue.GetEnvelopes(TopicTweets, SchemaTweet, func(decoded interface{}) {
+		f(decoded.(Tweet))
 	})
 }
@@ -7,6 +7,7 @@ package user_actions
 // user_actions contains queue code that receives user actions
 
 import (
+	"context"
 	"math/big"
 
 	solApplications "github.com/fluidity-money/fluidity-app/common/solana/applications"
@@ -43,6 +44,14 @@ type (
 	BufferedUserAction = user_actions.BufferedUserAction
 )
 
+var (
+	// SchemaUserAction to encode and decode user actions with
+	SchemaUserAction = queue.NewSchema(`user_actions.user_action`, 1, queue.JsonDecoder(UserAction{}))
+
+	// SchemaBufferedUserAction to encode and decode buffered user actions with
+	SchemaBufferedUserAction = queue.NewSchema(`user_actions.buffered_user_action`, 1, queue.JsonDecoder(BufferedUserAction{}))
+)
+
 func NewSwapEthereum(network_ network.BlockchainNetwork, senderAddress ethereum.Address, transactionHash ethereum.Hash, amount misc.BigInt, swapIn bool, tokenShortName string, tokenDecimals int) UserAction {
 	return user_actions.NewSwapEthereum(
 		network_,
@@ -120,12 +129,8 @@ func NewSendSui(network_ network.BlockchainNetwork, senderAddress, recipientAddr
 }
 
 func userActions(topic string, f func(UserAction)) {
-	queue.GetMessages(topic, func(message queue.Message) {
-		var userAction UserAction
-
-		message.Decode(&userAction)
-
-		f(userAction)
+	queue.GetEnvelopes(topic, SchemaUserAction, func(decoded interface{}) {
+		f(decoded.(UserAction))
 	})
 }
 
@@ -133,6 +138,14 @@ func UserActionsEthereum(f func(UserAction)) {
 	userActions(TopicUserActionsEthereum, f)
 }
 
+// UserActionsEthereumContext, calling the function with the context of
+// the message so user actions sent again by a replay can be told apart
+func UserActionsEthereumContext(f func(context.Context, UserAction)) {
+	queue.GetEnvelopesContext(TopicUserActionsEthereum, SchemaUserAction, func(ctx context.Context, decoded interface{}) {
+		f(ctx, decoded.(UserAction))
+	})
+}
+
 func UserActionsSolana(f func(UserAction)) {
 	userActions(TopicUserActionsSolana, f)
 }
@@ -146,12 +159,8 @@ func UserActionsAll(f func(UserAction)) {
 }
 
 func bufferedUserActions(topic string, f func(BufferedUserAction)) {
-	queue.GetMessages(topic, func(message queue.Message) {
-		var bufferedUserAction BufferedUserAction
-
-		message.Decode(&bufferedUserAction)
-
-		f(bufferedUserAction)
+	queue.GetEnvelopes(topic, SchemaBufferedUserAction, func(decoded interface{}) {
+		f(decoded.(BufferedUserAction))
 	})
 }
 
@@ -52,13 +52,20 @@ type (
 	RewardData    = fluidity.RewardData
 )
 
-func winners(topic string, f func(Winner)) {
-	queue.GetMessages(topic, func(message queue.Message) {
-		var winner Winner
+var (
+	// SchemaWinner to encode and decode winners with
+	SchemaWinner = queue.NewSchema(`winners.winner`, 1, queue.JsonDecoder(Winner{}))
+
+	// SchemaBlockedWinner to encode and decode blocked winners with
+	SchemaBlockedWinner = queue.NewSchema(`winners.blocked_winner`, 1, queue.JsonDecoder(BlockedWinner{}))
 
-		message.Decode(&winner)
+	// SchemaPendingWinners to encode and decode lists of pending winners with
+	SchemaPendingWinners = queue.NewSchema(`winners.pending_winners`, 1, queue.JsonDecoder([]PendingWinner(nil)))
+)
 
-		f(winner)
+func winners(topic string, f func(Winner)) {
+	queue.GetEnvelopes(topic, SchemaWinner, func(decoded interface{}) {
+		f(decoded.(Winner))
 	})
 }
 
@@ -78,22 +85,21 @@ func WinnersAll(f func(Winner)) {
 	winners(subWinnersAll, f)
 }
 
-func BlockedWinnersAll(f func(BlockedWinner)) {
-	queue.GetMessages(subBlockedWinnersAll, func(message queue.Message) {
-		var blockedWinner BlockedWinner
-
-		message.Decode(&blockedWinner)
+// BlockedWinnersEthereum from Ethereum and the networks sharing its topic
+func BlockedWinnersEthereum(f func(BlockedWinner)) {
+	queue.GetEnvelopes(TopicBlockedWinnersEthereum, SchemaBlockedWinner, func(decoded interface{}) {
+		f(decoded.(BlockedWinner))
+	})
+}
 
-		f(blockedWinner)
+func BlockedWinnersAll(f func(BlockedWinner)) {
+	queue.GetEnvelopes(subBlockedWinnersAll, SchemaBlockedWinner, func(decoded interface{}) {
+		f(decoded.(BlockedWinner))
 	})
 }
 
 func PendingWinners(f func([]PendingWinner)) {
-	queue.GetMessages(TopicPendingWinners, func(message queue.Message) {
-		var pendingWinners []PendingWinner
-
-		message.Decode(&pendingWinners)
-
-		f(pendingWinners)
+	queue.GetEnvelopes(TopicPendingWinners, SchemaPendingWinners, func(decoded interface{}) {
+		f(decoded.([]PendingWinner))
 	})
 }
@@ -7,6 +7,8 @@ package worker
 // contains queue code specific to the current implementation of the worker
 
 import (
+	"context"
+
 	"github.com/fluidity-money/fluidity-app/lib/queue"
 	"github.com/fluidity-money/fluidity-app/lib/types/worker"
 )
@@ -25,6 +27,7 @@ const (
 
 type (
 	EthereumAnnouncement         = worker.EthereumAnnouncement
+	EthereumWinnerAnnouncement   = worker.EthereumWinnerAnnouncement
 	EthereumBlockLog             = worker.EthereumBlockLog
 	EthereumHintedBlock          = worker.EthereumHintedBlock
 	EthereumDecoratedTransfer    = worker.EthereumDecoratedTransfer
@@ -45,64 +48,81 @@ type (
 	SolanaAppFees   = worker.SolanaAppFees
 )
 
-func EthereumAnnouncements(f func(EthereumAnnouncement)) {
-	queue.GetMessages(TopicEthereumAnnouncements, func(message queue.Message) {
+var (
+	// SchemaEthereumAnnouncement to encode and decode Ethereum announcements with
+	SchemaEthereumAnnouncement = queue.NewSchema(`worker.ethereum_announcement`, 1, queue.JsonDecoder(EthereumAnnouncement{}))
 
-		var announcement EthereumAnnouncement
+	// SchemaEthereumBlockLog to encode and decode Ethereum block logs with
+	SchemaEthereumBlockLog = queue.NewSchema(`worker.ethereum_block_log`, 1, queue.JsonDecoder(EthereumBlockLog{}))
 
-		message.Decode(&announcement)
+	// SchemaEthereumHintedBlock to encode and decode Ethereum hinted blocks with
+	SchemaEthereumHintedBlock = queue.NewSchema(`worker.ethereum_hinted_block`, 1, queue.JsonDecoder(EthereumHintedBlock{}))
 
-		f(announcement)
-	})
-}
+	// SchemaEthereumAnnouncements to encode and decode every announcement in a block with
+	SchemaEthereumAnnouncements = queue.NewSchema(`worker.ethereum_announcements`, 1, queue.JsonDecoder([]EthereumAnnouncement{}))
 
-func GetEthereumBlockLogs(f func(EthereumBlockLog)) {
-	queue.GetMessages(TopicEthereumBlockLogs, func(message queue.Message) {
-		var blockLog EthereumBlockLog
+	// SchemaEthereumWinnerAnnouncements to encode and decode the winners in a block with
+	SchemaEthereumWinnerAnnouncements = queue.NewSchema(`worker.ethereum_winner_announcements`, 1, queue.JsonDecoder([]EthereumWinnerAnnouncement{}))
 
-		message.Decode(&blockLog)
+	// SchemaSolanaBufferedParsedTransactions to encode and decode buffered Solana parsed transactions with
+	SchemaSolanaBufferedParsedTransactions = queue.NewSchema(`worker.solana_buffered_parsed_transactions`, 1, queue.JsonDecoder(SolanaBufferedParsedTransactions{}))
 
-		f(blockLog)
-	})
-}
+	// SchemaSolanaBufferedTransfers to encode and decode buffered Solana transfers with
+	SchemaSolanaBufferedTransfers = queue.NewSchema(`worker.solana_buffered_transfers`, 1, queue.JsonDecoder(SolanaBufferedTransfers{}))
 
-func GetEthereumHintedBlocks(f func(EthereumHintedBlock)) {
-	queue.GetMessages(TopicEthereumServerWork, func(message queue.Message) {
-		var hintedBlock EthereumHintedBlock
+	// SchemaSolanaWork to encode and decode Solana transfers with their mint supply and tvl with
+	SchemaSolanaWork = queue.NewSchema(`worker.solana_work`, 1, queue.JsonDecoder(SolanaWork{}))
+
+	// SchemaSolanaWinnerAnnouncement to encode and decode Solana winner announcements with
+	SchemaSolanaWinnerAnnouncement = queue.NewSchema(`worker.solana_winner_announcement`, 1, queue.JsonDecoder(SolanaWinnerAnnouncement{}))
 
-		message.Decode(&hintedBlock)
+	// SchemaEmission to encode and decode emissions with
+	SchemaEmission = queue.NewSchema(`worker.emission`, 1, queue.JsonDecoder(Emission{}))
+)
 
-		f(hintedBlock)
+func EthereumAnnouncements(f func(EthereumAnnouncement)) {
+	queue.GetEnvelopes(TopicEthereumAnnouncements, SchemaEthereumAnnouncement, func(decoded interface{}) {
+		f(decoded.(EthereumAnnouncement))
 	})
 }
 
-func GetSolanaBufferedParsedTransactions(f func(SolanaBufferedParsedTransactions)) {
-	queue.GetMessages(TopicSolanaParsedTransactions, func(message queue.Message) {
-		var transactions SolanaBufferedParsedTransactions
+// GetEthereumBlockLogs, calling the function with the context of the
+// message so anything it sends is tagged as a replay if the block was
+func GetEthereumBlockLogs(f func(context.Context, EthereumBlockLog)) {
+	queue.GetEnvelopesContext(TopicEthereumBlockLogs, SchemaEthereumBlockLog, func(ctx context.Context, decoded interface{}) {
+		f(ctx, decoded.(EthereumBlockLog))
+	})
+}
 
-		message.Decode(&transactions)
+func GetEthereumHintedBlocks(f func(EthereumHintedBlock)) {
+	queue.GetEnvelopes(TopicEthereumServerWork, SchemaEthereumHintedBlock, func(decoded interface{}) {
+		f(decoded.(EthereumHintedBlock))
+	})
+}
 
-		f(transactions)
+func GetSolanaBufferedParsedTransactions(f func(SolanaBufferedParsedTransactions)) {
+	queue.GetEnvelopes(TopicSolanaParsedTransactions, SchemaSolanaBufferedParsedTransactions, func(decoded interface{}) {
+		f(decoded.(SolanaBufferedParsedTransactions))
 	})
 }
 
 func GetSolanaBufferedTransfers(f func(SolanaBufferedTransfers)) {
-	queue.GetMessages(TopicSolanaBufferedTransfers, func(message queue.Message) {
-		var transfers SolanaBufferedTransfers
-
-		message.Decode(&transfers)
-
-		f(transfers)
+	queue.GetEnvelopes(TopicSolanaBufferedTransfers, SchemaSolanaBufferedTransfers, func(decoded interface{}) {
+		f(decoded.(SolanaBufferedTransfers))
 	})
 }
 
 func Emissions(f func(Emission)) {
-	queue.GetMessages(TopicEmissions, func(message queue.Message) {
-		var emission Emission
-
-		message.Decode(&emission)
+	queue.GetEnvelopes(TopicEmissions, SchemaEmission, func(decoded interface{}) {
+		f(decoded.(Emission))
+	})
+}
 
-		f(emission)
+// EmissionsContext, calling the function with the context of the
+// message so emissions sent again by a replay can be told apart
+func EmissionsContext(f func(context.Context, Emission)) {
+	queue.GetEnvelopesContext(TopicEmissions, SchemaEmission, func(ctx context.Context, decoded interface{}) {
+		f(ctx, decoded.(Emission))
 	})
 }
 
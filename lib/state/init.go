@@ -7,12 +7,18 @@ package state
 import (
 	"context"
 	"os"
+	"time"
 
+	"github.com/fluidity-money/fluidity-app/lib/lifecycle"
 	"github.com/fluidity-money/fluidity-app/lib/log"
+	"github.com/fluidity-money/fluidity-app/lib/metrics"
 	"github.com/fluidity-money/fluidity-app/lib/util"
 	"github.com/go-redis/redis/v8"
 )
 
+// pingTimeout to wait for Redis to respond to the readiness probe
+const pingTimeout = 5 * time.Second
+
 func init() {
 	var (
 		redisAddr     = util.GetEnvOrFatal(EnvRedisAddr)
@@ -29,6 +35,10 @@ func init() {
 
 	_redisClient = redis.NewClient(&redisOptions)
 
+	if metrics.Enabled() {
+		_redisClient.AddHook(metricsHook{})
+	}
+
 	log.Debugf(
 		"Connecting to the Redis server!",
 	)
@@ -47,6 +57,14 @@ func init() {
 		"Connected to the Redis server!",
 	)
 
+	lifecycle.RegisterReadiness("redis", func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+
+		defer cancel()
+
+		return _redisClient.Ping(ctx).Err()
+	})
+
 	go func() {
 		for {
 			redisReadyChan <- true
@@ -0,0 +1,125 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/log"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/go-redsync/redsync/v4"
+)
+
+// fencingTokenSuffix to store the last fencing token for a lease or a
+// fenced key with
+const fencingTokenSuffix = `.fencing-token`
+
+// setFencedScript sets KEYS[1] to ARGV[2] unless a write with a larger
+// fencing token than ARGV[1] was made already, tracking the largest
+// token in KEYS[2]
+var setFencedScript = redis.NewScript(`
+local last = tonumber(redis.call("GET", KEYS[2]) or "0")
+
+if tonumber(ARGV[1]) < last then
+	return 0
+end
+
+redis.call("SET", KEYS[2], ARGV[1])
+redis.call("SET", KEYS[1], ARGV[2])
+
+return 1
+`)
+
+// LeaseStore of leases for lib/leader, locked with redsync
+type LeaseStore struct{}
+
+// Acquire the lease if it isn't held, using the holder as the lock's
+// value so it can be renewed and released by the holder only
+func (LeaseStore) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	mutex := NewRedsync().NewMutex(
+		name,
+		redsync.WithExpiry(ttl),
+		redsync.WithTries(1),
+		redsync.WithGenValueFunc(func() (string, error) {
+			return holder, nil
+		}),
+	)
+
+	err := mutex.LockContext(ctx)
+
+	if errors.Is(err, redsync.ErrFailed) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Renew the lease if the holder still holds it
+func (LeaseStore) Renew(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	mutex := NewRedsync().NewMutex(
+		name,
+		redsync.WithExpiry(ttl),
+		redsync.WithValue(holder),
+	)
+
+	renewed, err := mutex.ExtendContext(ctx)
+
+	if errors.Is(err, redsync.ErrExtendFailed) {
+		return false, nil
+	}
+
+	return renewed, err
+}
+
+// Release the lease if the holder still holds it
+func (LeaseStore) Release(ctx context.Context, name, holder string) (bool, error) {
+	mutex := NewRedsync().NewMutex(name, redsync.WithValue(holder))
+
+	return mutex.UnlockContext(ctx)
+}
+
+// NextToken for the lease, incremented every time it's acquired
+func (LeaseStore) NextToken(ctx context.Context, name string) (uint64, error) {
+	return client().Incr(ctx, name+fencingTokenSuffix).Uint64()
+}
+
+// SetFenced sets the key to the JSON-encoded content unless a write
+// with a larger fencing token was made already, returning false if the
+// write was rejected. Used by leaders to stop writing once another
+// replica took their lease
+func SetFenced(key string, token uint64, content interface{}) (didSet bool) {
+	redisClient := client()
+
+	contentBytes := serialiseToBytes(content)
+
+	log.Debugf(
+		"About to set this state to key %s with fencing token %v: %v",
+		key,
+		token,
+		contentBytes,
+	)
+
+	result, err := setFencedScript.Run(
+		context.Background(),
+		redisClient,
+		[]string{key, key + fencingTokenSuffix},
+		token,
+		contentBytes,
+	).Int()
+
+	if err != nil {
+		log.Fatal(func(k *log.Log) {
+			k.Context = Context
+			k.Message = "Failed to set fenced state!"
+			k.Payload = err
+		})
+	}
+
+	return result == 1
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/metrics"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// commandPipeline to label pipelined commands with
+const commandPipeline = "pipeline"
+
+var redisCallDuration = metrics.NewHistogramVec(
+	"redis_call_duration_seconds",
+	"Time taken by calls made to Redis, by command.",
+	metrics.DefaultBuckets,
+	"command",
+)
+
+// metricsHook times every command sent to Redis
+type metricsHook struct{}
+
+type startTimeKey struct{}
+
+func (metricsHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if start, ok := ctx.Value(startTimeKey{}).(time.Time); ok {
+		redisCallDuration.ObserveSince(start, cmd.Name())
+	}
+
+	return nil
+}
+
+func (metricsHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startTimeKey{}, time.Now()), nil
+}
+
+func (metricsHook) AfterProcessPipeline(ctx context.Context, _ []redis.Cmder) error {
+	if start, ok := ctx.Value(startTimeKey{}).(time.Time); ok {
+		redisCallDuration.ObserveSince(start, commandPipeline)
+	}
+
+	return nil
+}
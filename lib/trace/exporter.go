@@ -0,0 +1,211 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpTracesPath to post spans to on the OTLP endpoint
+const otlpTracesPath = "/v1/traces"
+
+// otlpTimeout to wait for the collector to accept spans
+const otlpTimeout = 10 * time.Second
+
+// Exporter of finished spans, called from a single goroutine
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+type (
+	// stdoutExporter writes every span as a line of JSON
+	stdoutExporter struct {
+		serviceName string
+		stream      io.Writer
+	}
+
+	// otlpExporter posts spans to an OTLP collector, using the JSON
+	// encoding of the OTLP HTTP protocol
+	otlpExporter struct {
+		serviceName string
+		url         string
+		client      *http.Client
+	}
+
+	stdoutSpan struct {
+		Service    string            `json:"service"`
+		TraceId    string            `json:"trace_id"`
+		SpanId     string            `json:"span_id"`
+		ParentId   string            `json:"parent_id,omitempty"`
+		Name       string            `json:"name"`
+		Kind       SpanKind          `json:"kind"`
+		Start      time.Time         `json:"start"`
+		End        time.Time         `json:"end"`
+		Failed     bool              `json:"failed"`
+		Attributes map[string]string `json:"attributes,omitempty"`
+	}
+)
+
+// NewStdoutExporter writing spans to the stream given
+func NewStdoutExporter(serviceName string, stream io.Writer) Exporter {
+	return &stdoutExporter{
+		serviceName: serviceName,
+		stream:      stream,
+	}
+}
+
+// NewOtlpExporter sending spans to the collector at the endpoint given,
+// eg http://localhost:4318
+func NewOtlpExporter(serviceName, endpoint string) Exporter {
+	url := strings.TrimSuffix(endpoint, "/") + otlpTracesPath
+
+	return &otlpExporter{
+		serviceName: serviceName,
+		url:         url,
+		client:      &http.Client{Timeout: otlpTimeout},
+	}
+}
+
+func (exporter *stdoutExporter) Export(spans []*Span) error {
+	encoder := json.NewEncoder(exporter.stream)
+
+	for _, span := range spans {
+		var parentId string
+
+		if span.ParentId.IsValid() {
+			parentId = span.ParentId.String()
+		}
+
+		err := encoder.Encode(stdoutSpan{
+			Service:    exporter.serviceName,
+			TraceId:    span.Context.TraceId.String(),
+			SpanId:     span.Context.SpanId.String(),
+			ParentId:   parentId,
+			Name:       span.Name,
+			Kind:       span.Kind,
+			Start:      span.Start,
+			End:        span.End,
+			Failed:     span.Failed,
+			Attributes: span.Attributes,
+		})
+
+		if err != nil {
+			return fmt.Errorf(
+				"failed to write span %v! %v",
+				span.Context.SpanId,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+func (exporter *otlpExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(otlpRequest(exporter.serviceName, spans))
+
+	if err != nil {
+		return fmt.Errorf(
+			"failed to encode %v spans for OTLP! %v",
+			len(spans),
+			err,
+		)
+	}
+
+	response, err := exporter.client.Post(
+		exporter.url,
+		"application/json",
+		bytes.NewReader(body),
+	)
+
+	if err != nil {
+		return fmt.Errorf(
+			"failed to post spans to %#v! %v",
+			exporter.url,
+			err,
+		)
+	}
+
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf(
+			"collector at %#v returned status %v!",
+			exporter.url,
+			response.Status,
+		)
+	}
+
+	return nil
+}
+
+// otlpRequest to send the spans in, following ExportTraceServiceRequest
+func otlpRequest(serviceName string, spans []*Span) map[string]interface{} {
+	otlpSpans := make([]map[string]interface{}, len(spans))
+
+	for i, span := range spans {
+		otlpSpan := map[string]interface{}{
+			"traceId":           span.Context.TraceId.String(),
+			"spanId":            span.Context.SpanId.String(),
+			"name":              span.Name,
+			"kind":              int(span.Kind),
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(span.Attributes),
+		}
+
+		if span.ParentId.IsValid() {
+			otlpSpan["parentSpanId"] = span.ParentId.String()
+		}
+
+		// STATUS_CODE_ERROR
+
+		if span.Failed {
+			otlpSpan["status"] = map[string]interface{}{"code": 2}
+		}
+
+		otlpSpans[i] = otlpSpan
+	}
+
+	resource := map[string]interface{}{
+		"attributes": otlpAttributes(map[string]string{
+			"service.name": serviceName,
+		}),
+	}
+
+	scopeSpans := []map[string]interface{}{{
+		"scope": map[string]interface{}{"name": "fluidity"},
+		"spans": otlpSpans,
+	}}
+
+	return map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource":   resource,
+			"scopeSpans": scopeSpans,
+		}},
+	}
+}
+
+func otlpAttributes(attributes map[string]string) []map[string]interface{} {
+	otlpAttributes := make([]map[string]interface{}, 0, len(attributes))
+
+	for key, value := range attributes {
+		otlpAttributes = append(otlpAttributes, map[string]interface{}{
+			"key":   key,
+			"value": map[string]interface{}{"stringValue": value},
+		})
+	}
+
+	return otlpAttributes
+}
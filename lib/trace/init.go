@@ -0,0 +1,172 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package trace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib"
+)
+
+const (
+	// exportBatchSize to export spans in once this many are waiting
+	exportBatchSize = 100
+
+	// exportInterval to export waiting spans after
+	exportInterval = time.Second
+
+	// exportBufferSize of spans that can be waiting before new ones are
+	// dropped
+	exportBufferSize = 1000
+)
+
+var (
+	// exporter that's in use, nil if tracing is disabled
+	exporter Exporter
+
+	spansChan = make(chan *Span, exportBufferSize)
+	flushChan = make(chan chan bool)
+)
+
+func init() {
+	var (
+		exporterName = os.Getenv(EnvExporter)
+		otlpEndpoint = os.Getenv(EnvOtlpEndpoint)
+		serviceName  = os.Getenv(microservice_lib.EnvWorkerId)
+	)
+
+	if serviceName == "" {
+		serviceName = filepath.Base(os.Args[0])
+	}
+
+	switch exporterName {
+	case "":
+		return
+
+	case ExporterStdout:
+		exporter = NewStdoutExporter(serviceName, os.Stdout)
+
+	case ExporterOtlp:
+		if otlpEndpoint == "" {
+			fmt.Fprintf(
+				os.Stderr,
+				"%v is %#v but %v isn't set, tracing is disabled!\n",
+				EnvExporter,
+				exporterName,
+				EnvOtlpEndpoint,
+			)
+
+			return
+		}
+
+		exporter = NewOtlpExporter(serviceName, otlpEndpoint)
+
+	default:
+		fmt.Fprintf(
+			os.Stderr,
+			"Unknown %v %#v, tracing is disabled!\n",
+			EnvExporter,
+			exporterName,
+		)
+
+		return
+	}
+
+	go startExportServer(exporter)
+}
+
+// Enabled if an exporter is set
+func Enabled() bool {
+	return exporter != nil
+}
+
+// Flush every span that's waiting to be exported, blocking until they're
+// sent. Called by lib/log before the process exits
+func Flush() {
+	if !Enabled() {
+		return
+	}
+
+	reply := make(chan bool)
+
+	flushChan <- reply
+
+	<-reply
+}
+
+// exportSpan by queueing it for the export server, dropping it if the
+// buffer is full so a slow collector can't block the service
+func exportSpan(span *Span) {
+	if !Enabled() || !span.Context.Sampled {
+		return
+	}
+
+	select {
+	case spansChan <- span:
+	default:
+	}
+}
+
+func startExportServer(exporter Exporter) {
+	var (
+		spans  = make([]*Span, 0, exportBatchSize)
+		ticker = time.NewTicker(exportInterval)
+	)
+
+	export := func() {
+		if len(spans) == 0 {
+			return
+		}
+
+		if err := exporter.Export(spans); err != nil {
+			// lib/log depends on this package so write directly
+
+			fmt.Fprintf(
+				os.Stderr,
+				"[%v] [%s] Failed to export %v spans! %v\n",
+				time.Now(),
+				Context,
+				len(spans),
+				err,
+			)
+		}
+
+		spans = make([]*Span, 0, exportBatchSize)
+	}
+
+	for {
+		select {
+		case span := <-spansChan:
+			spans = append(spans, span)
+
+			if len(spans) >= exportBatchSize {
+				export()
+			}
+
+		case <-ticker.C:
+			export()
+
+		case reply := <-flushChan:
+			// take anything that's still buffered before exporting
+
+		drain:
+			for {
+				select {
+				case span := <-spansChan:
+					spans = append(spans, span)
+				default:
+					break drain
+				}
+			}
+
+			export()
+
+			reply <- true
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship of a span to its remote parent
+// or children, following OTLP
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// Span of work done by a service as part of a trace
+type Span struct {
+	Name     string
+	Kind     SpanKind
+	Context  SpanContext
+	ParentId SpanId
+	Start    time.Time
+	End      time.Time
+	Failed   bool
+
+	Attributes map[string]string
+
+	mu    sync.Mutex
+	ended bool
+}
+
+// spanContextKey to store the span being handled in a context with
+type spanContextKey struct{}
+
+// StartSpan that's a child of the parent given, or the root of a new
+// trace if the parent isn't valid. Returns nil if tracing is disabled.
+func StartSpan(name string, kind SpanKind, parent SpanContext) *Span {
+	if !Enabled() {
+		return nil
+	}
+
+	span := &Span{
+		Name:       name,
+		Kind:       kind,
+		Start:      time.Now(),
+		Attributes: make(map[string]string),
+	}
+
+	if parent.IsValid() {
+		span.Context.TraceId = parent.TraceId
+		span.Context.Sampled = parent.Sampled
+		span.ParentId = parent.SpanId
+	} else {
+		span.Context.TraceId = newTraceId()
+		span.Context.Sampled = true
+	}
+
+	span.Context.SpanId = newSpanId()
+
+	return span
+}
+
+// StartChild of the span in the context given, or a new trace if there
+// isn't one
+func StartChild(ctx context.Context, name string, kind SpanKind) *Span {
+	var parent SpanContext
+
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.Context
+	}
+
+	return StartSpan(name, kind, parent)
+}
+
+// RemoteSpan that was started somewhere else (like a message that was
+// stored to be sent later), to start children of. It's never exported
+// here, so finishing it does nothing
+func RemoteSpan(spanContext SpanContext) *Span {
+	return &Span{
+		Context: spanContext,
+		ended:   true,
+	}
+}
+
+// SetAttribute on the span, doing nothing if the span is nil
+func (span *Span) SetAttribute(key, value string) {
+	if span == nil {
+		return
+	}
+
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.Attributes[key] = value
+}
+
+// Fail the span, recording the error as an attribute
+func (span *Span) Fail(err error) {
+	if span == nil {
+		return
+	}
+
+	span.mu.Lock()
+	defer span.mu.Unlock()
+
+	span.Failed = true
+
+	if err != nil {
+		span.Attributes["error"] = err.Error()
+	}
+}
+
+// Finish the span and queue it to be exported. Finishing a span twice
+// does nothing
+func (span *Span) Finish() {
+	if span == nil {
+		return
+	}
+
+	span.mu.Lock()
+
+	if span.ended {
+		span.mu.Unlock()
+		return
+	}
+
+	span.ended = true
+	span.End = time.Now()
+
+	span.mu.Unlock()
+
+	exportSpan(span)
+}
+
+// ContextWithSpan returns a copy of the context with the span, so logs
+// and messages sent with it are tagged with the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span in the context, or nil if there isn't
+// one
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+
+	return span
+}
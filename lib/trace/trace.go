@@ -0,0 +1,200 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package trace
+
+// trace follows work across microservices with W3C trace contexts,
+// passed between them in AMQP message headers. Spans are exported with
+// the exporter set with FLU_TRACE_EXPORTER. If no exporter is set,
+// tracing is disabled and everything here is a no-op.
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Context to use for logging
+	Context = `TRACE`
+
+	// EnvExporter to use to export spans, either ExporterStdout or
+	// ExporterOtlp. Tracing is disabled if it isn't set
+	EnvExporter = `FLU_TRACE_EXPORTER`
+
+	// EnvOtlpEndpoint to send spans to if the OTLP exporter is used,
+	// without the /v1/traces path
+	EnvOtlpEndpoint = `FLU_TRACE_OTLP_ENDPOINT`
+
+	// ExporterStdout to write every span to stdout as a line of JSON
+	ExporterStdout = `stdout`
+
+	// ExporterOtlp to send spans to an OTLP collector over HTTP
+	ExporterOtlp = `otlp`
+
+	// HeaderTraceparent to use to pass the trace context in message
+	// headers
+	HeaderTraceparent = `traceparent`
+)
+
+// traceparentVersion is the only version of the traceparent header
+// that's written
+const traceparentVersion = "00"
+
+// flagSampled is set in the traceparent flags if the trace is recorded
+const flagSampled = 0x01
+
+type (
+	// TraceId shared by every span in a trace
+	TraceId [16]byte
+
+	// SpanId unique to a span
+	SpanId [8]byte
+
+	// SpanContext that's passed between services to continue a trace
+	SpanContext struct {
+		TraceId TraceId
+		SpanId  SpanId
+		Sampled bool
+	}
+)
+
+func (id TraceId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid if the id isn't all zeroes
+func (id TraceId) IsValid() bool {
+	return id != TraceId{}
+}
+
+func (id SpanId) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid if the id isn't all zeroes
+func (id SpanId) IsValid() bool {
+	return id != SpanId{}
+}
+
+// IsValid if both the trace id and span id are set
+func (spanContext SpanContext) IsValid() bool {
+	return spanContext.TraceId.IsValid() && spanContext.SpanId.IsValid()
+}
+
+// Traceparent header containing the span context
+func (spanContext SpanContext) Traceparent() string {
+	flags := 0
+
+	if spanContext.Sampled {
+		flags |= flagSampled
+	}
+
+	return fmt.Sprintf(
+		"%s-%s-%s-%02x",
+		traceparentVersion,
+		spanContext.TraceId,
+		spanContext.SpanId,
+		flags,
+	)
+}
+
+// ParseTraceparent header into a span context, returning an error if
+// it's malformed
+func ParseTraceparent(traceparent string) (SpanContext, error) {
+	var spanContext SpanContext
+
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+
+	if len(parts) < 4 {
+		return spanContext, fmt.Errorf(
+			"traceparent %#v doesn't have 4 parts!",
+			traceparent,
+		)
+	}
+
+	var (
+		version = parts[0]
+		traceId = parts[1]
+		spanId  = parts[2]
+		flags   = parts[3]
+	)
+
+	// only version 00 is defined, future versions can have more parts
+
+	if version == "ff" || len(version) != 2 || (version == traceparentVersion && len(parts) != 4) {
+		return spanContext, fmt.Errorf(
+			"traceparent %#v has a bad version!",
+			traceparent,
+		)
+	}
+
+	if err := decodeHex(spanContext.TraceId[:], traceId); err != nil {
+		return spanContext, fmt.Errorf(
+			"traceparent %#v has a bad trace id! %v",
+			traceparent,
+			err,
+		)
+	}
+
+	if err := decodeHex(spanContext.SpanId[:], spanId); err != nil {
+		return spanContext, fmt.Errorf(
+			"traceparent %#v has a bad span id! %v",
+			traceparent,
+			err,
+		)
+	}
+
+	var flagsByte [1]byte
+
+	if err := decodeHex(flagsByte[:], flags); err != nil {
+		return spanContext, fmt.Errorf(
+			"traceparent %#v has bad flags! %v",
+			traceparent,
+			err,
+		)
+	}
+
+	if !spanContext.IsValid() {
+		return spanContext, fmt.Errorf(
+			"traceparent %#v has an empty trace or span id!",
+			traceparent,
+		)
+	}
+
+	spanContext.Sampled = flagsByte[0]&flagSampled != 0
+
+	return spanContext, nil
+}
+
+// decodeHex into the buffer given, which must be filled exactly. Only
+// lowercase hex is accepted by the spec
+func decodeHex(buf []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(buf)) {
+		return fmt.Errorf(
+			"expected %v hex characters, got %v",
+			hex.EncodedLen(len(buf)),
+			len(s),
+		)
+	}
+
+	if strings.ToLower(s) != s {
+		return fmt.Errorf("hex %#v isn't lowercase", s)
+	}
+
+	_, err := hex.Decode(buf, []byte(s))
+
+	return err
+}
+
+func newTraceId() (id TraceId) {
+	_, _ = rand.Read(id[:])
+	return
+}
+
+func newSpanId() (id SpanId) {
+	_, _ = rand.Read(id[:])
+	return
+}
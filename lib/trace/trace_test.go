@@ -0,0 +1,238 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingExporter keeps every span it's given
+type recordingExporter struct {
+	spans []*Span
+}
+
+func (exporter *recordingExporter) Export(spans []*Span) error {
+	exporter.spans = append(exporter.spans, spans...)
+	return nil
+}
+
+// enableTracing with an exporter that's never called, restoring the
+// previous exporter once the test finishes
+func enableTracing(t *testing.T) {
+	previous := exporter
+
+	exporter = new(recordingExporter)
+
+	t.Cleanup(func() {
+		exporter = previous
+	})
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	spanContext, err := ParseTraceparent(traceparent)
+
+	require.NoError(t, err)
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceId.String())
+	assert.Equal(t, "00f067aa0ba902b7", spanContext.SpanId.String())
+	assert.True(t, spanContext.Sampled)
+
+	assert.Equal(t, traceparent, spanContext.Traceparent())
+}
+
+func TestParseTraceparentInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+
+	for _, traceparent := range invalid {
+		_, err := ParseTraceparent(traceparent)
+
+		assert.Error(t, err, "traceparent %#v", traceparent)
+	}
+}
+
+func TestStartSpanDisabled(t *testing.T) {
+	span := StartChild(context.Background(), "test", SpanKindInternal)
+
+	assert.Nil(t, span)
+
+	// nil spans can be used without checking
+
+	span.SetAttribute("key", "value")
+	span.Fail(nil)
+	span.Finish()
+
+	ctx := ContextWithSpan(context.Background(), span)
+
+	assert.Nil(t, SpanFromContext(ctx))
+}
+
+func TestStartSpanChild(t *testing.T) {
+	enableTracing(t)
+
+	root := StartChild(context.Background(), "root", SpanKindProducer)
+
+	require.NotNil(t, root)
+
+	assert.True(t, root.Context.IsValid())
+	assert.False(t, root.ParentId.IsValid())
+
+	ctx := ContextWithSpan(context.Background(), root)
+
+	child := StartChild(ctx, "child", SpanKindConsumer)
+
+	assert.Equal(t, root.Context.TraceId, child.Context.TraceId)
+	assert.Equal(t, root.Context.SpanId, child.ParentId)
+	assert.NotEqual(t, root.Context.SpanId, child.Context.SpanId)
+}
+
+func TestSpanFromContext(t *testing.T) {
+	enableTracing(t)
+
+	span := StartChild(context.Background(), "test", SpanKindInternal)
+
+	ctx := ContextWithSpan(context.Background(), span)
+
+	assert.Equal(t, span, SpanFromContext(ctx))
+
+	// the span is carried with the context, even to other goroutines
+
+	inGoroutine := make(chan *Span)
+
+	go func() {
+		inGoroutine <- SpanFromContext(ctx)
+	}()
+
+	assert.Equal(t, span, <-inGoroutine)
+
+	assert.Nil(t, SpanFromContext(context.Background()))
+}
+
+func TestOtlpExporter(t *testing.T) {
+	enableTracing(t)
+
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, otlpTracesPath, r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		body, _ = io.ReadAll(r.Body)
+	}))
+
+	defer server.Close()
+
+	parent := StartChild(context.Background(), "parent", SpanKindProducer)
+	span := StartSpan("child", SpanKindConsumer, parent.Context)
+
+	span.SetAttribute("messaging.destination", "worker.server.work")
+	span.Fail(nil)
+
+	err := NewOtlpExporter("test-worker", server.URL+"/").Export([]*Span{span})
+
+	require.NoError(t, err)
+
+	var request struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []struct {
+					TraceId      string `json:"traceId"`
+					SpanId       string `json:"spanId"`
+					ParentSpanId string `json:"parentSpanId"`
+					Name         string `json:"name"`
+					Kind         int    `json:"kind"`
+					Status       struct {
+						Code int `json:"code"`
+					} `json:"status"`
+				} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+
+	require.NoError(t, json.Unmarshal(body, &request))
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+
+	require.Len(t, spans, 1)
+
+	assert.Equal(t, parent.Context.TraceId.String(), spans[0].TraceId)
+	assert.Equal(t, span.Context.SpanId.String(), spans[0].SpanId)
+	assert.Equal(t, parent.Context.SpanId.String(), spans[0].ParentSpanId)
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, int(SpanKindConsumer), spans[0].Kind)
+	assert.Equal(t, 2, spans[0].Status.Code)
+}
+
+func TestOtlpExporterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	defer server.Close()
+
+	err := NewOtlpExporter("test-worker", server.URL).Export(nil)
+
+	assert.Error(t, err)
+}
+
+func TestStdoutExporter(t *testing.T) {
+	enableTracing(t)
+
+	var buf bytes.Buffer
+
+	span := StartChild(context.Background(), "test", SpanKindInternal)
+
+	err := NewStdoutExporter("test-worker", &buf).Export([]*Span{span})
+
+	require.NoError(t, err)
+
+	var line stdoutSpan
+
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+
+	assert.Equal(t, "test-worker", line.Service)
+	assert.Equal(t, span.Context.TraceId.String(), line.TraceId)
+	assert.Empty(t, line.ParentId)
+}
+
+func TestRemoteSpanParent(t *testing.T) {
+	enableTracing(t)
+
+	spanContext, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	require.NoError(t, err)
+
+	remote := RemoteSpan(spanContext)
+
+	child := StartChild(ContextWithSpan(context.Background(), remote), "child", SpanKindProducer)
+
+	assert.Equal(t, spanContext.TraceId, child.Context.TraceId)
+	assert.Equal(t, spanContext.SpanId, child.ParentId)
+
+	// remote spans aren't exported
+
+	remote.Finish()
+
+	assert.Empty(t, exporter.(*recordingExporter).spans)
+}
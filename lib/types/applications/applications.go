@@ -18,8 +18,47 @@ import (
 // application-specific information like fees and senders/recipients.
 type Application int64
 
-// applications supported via the app. Note that this is also a
-// positional list.
+// Applications supported before the decoders registered themselves with
+// common/ethereum/applications/registry. The numbers are stored in the
+// database and sent between services, so they can never change, and
+// new applications should pick the next free number in their own
+// package instead of extending this list.
+const (
+	// ApplicationNone is the nil value representing a transfer.
+	ApplicationNone Application = iota
+	ApplicationUniswapV3
+	ApplicationUniswapV2
+	ApplicationBalancerV2
+	ApplicationOneInchLPV2
+	ApplicationOneInchLPV1
+	ApplicationMooniswap
+	ApplicationOneInchFixedRateSwap
+	ApplicationDodoV2
+	ApplicationCurve
+	ApplicationMultichain
+	ApplicationXyFinance
+	ApplicationApeswap
+	ApplicationSaddle
+	ApplicationGTradeV6_1
+	ApplicationMeson
+	ApplicationCamelot
+	ApplicationChronos
+	ApplicationSushiswap
+	ApplicationKyberClassic
+	ApplicationWombat
+	ApplicationSeawaterAmm
+	ApplicationTraderJoe
+	ApplicationRamses
+	ApplicationJumper
+	ApplicationCamelotV3
+	ApplicationLifi
+	ApplicationOdos
+	ApplicationBetSwirl
+	ApplicationParaswap
+)
+
+// applications supported via the app, positional with the constants
+// above. Names are stored in the database, so these can't change either
 var applicationNames = []string{
 	"none",
 	"uniswap_v3",
@@ -80,8 +119,61 @@ type ApplicationFeeData = struct {
 	Volume *big.Rat `json:"volume"`
 }
 
+// registeredNames of applications added with Register that aren't in
+// applicationNames
+var registeredNames = make(map[Application]string)
+
+// Register the name of an application, panicking if either the number or
+// the name is already used by another application. Should only be
+// called from init
+func Register(app Application, name string) {
+	if name == "" {
+		panic(fmt.Sprintf("application %d registered without a name", app))
+	}
+
+	if existing, found := lookupName(app); found {
+		if existing != name {
+			panic(fmt.Sprintf(
+				"application %d registered as %#v, but is already %#v",
+				app,
+				name,
+				existing,
+			))
+		}
+
+		return
+	}
+
+	if existing, err := ParseApplicationName(name); err == nil {
+		panic(fmt.Sprintf(
+			"application %#v registered as %d, but is already %d",
+			name,
+			app,
+			existing,
+		))
+	}
+
+	registeredNames[app] = name
+}
+
+func lookupName(app Application) (string, bool) {
+	if app >= 0 && int(app) < len(applicationNames) {
+		return applicationNames[app], true
+	}
+
+	name, found := registeredNames[app]
+
+	return name, found
+}
+
 func (app Application) String() string {
-	return applicationNames[app]
+	name, found := lookupName(app)
+
+	if !found {
+		return fmt.Sprintf("unknown_%d", app)
+	}
+
+	return name
 }
 
 func ParseApplicationName(name string) (Application, error) {
@@ -91,6 +183,12 @@ func ParseApplicationName(name string) (Application, error) {
 		}
 	}
 
+	for app, registeredName := range registeredNames {
+		if registeredName == name {
+			return app, nil
+		}
+	}
+
 	return 0, fmt.Errorf(
 		"unknown app name %s",
 		name,
@@ -3,3 +3,86 @@
 // LICENSE.md file.
 
 package applications
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestApplicationNumbers to make sure applications keep their numbers,
+// which are stored in the database and sent between services
+func TestApplicationNumbers(t *testing.T) {
+	expected := map[Application]string{
+		0:  "none",
+		1:  "uniswap_v3",
+		2:  "uniswap_v2",
+		3:  "balancer_v2",
+		4:  "oneinch_v2",
+		5:  "oneinch_v1",
+		6:  "mooniswap",
+		7:  "oneinch_fixedrate",
+		8:  "dodo_v2",
+		9:  "curve",
+		10: "multichain",
+		11: "xy_finance",
+		12: "apeswap",
+		13: "saddle",
+		14: "gtrade_v6_1",
+		15: "meson",
+		16: "camelot",
+		17: "chronos",
+		18: "sushiswap",
+		19: "kyber_classic",
+		20: "wombat",
+		21: "seawater_amm",
+		22: "trader_joe",
+		23: "ramses",
+		24: "jumper",
+		25: "camelot_v3",
+		26: "lifi",
+		27: "odos",
+		28: "betswirl",
+		29: "paraswap",
+	}
+
+	assert.Len(t, applicationNames, len(expected))
+
+	for app, name := range expected {
+		assert.Equal(t, name, app.String())
+
+		parsed, err := ParseApplicationName(name)
+
+		assert.NoError(t, err)
+		assert.Equal(t, app, parsed)
+	}
+
+	assert.Equal(t, Application(1), ApplicationUniswapV3)
+	assert.Equal(t, Application(21), ApplicationSeawaterAmm)
+	assert.Equal(t, Application(29), ApplicationParaswap)
+}
+
+func TestRegister(t *testing.T) {
+	defer delete(registeredNames, 1000)
+
+	Register(1000, "test_application")
+
+	assert.Equal(t, "test_application", Application(1000).String())
+
+	parsed, err := ParseApplicationName("test_application")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Application(1000), parsed)
+
+	// registering the same name again is fine, as is a built in one
+
+	Register(1000, "test_application")
+	Register(ApplicationCurve, "curve")
+
+	assert.Panics(t, func() { Register(1000, "other_application") })
+	assert.Panics(t, func() { Register(1001, "test_application") })
+	assert.Panics(t, func() { Register(ApplicationCurve, "not_curve") })
+	assert.Panics(t, func() { Register(1002, "") })
+
+	assert.Equal(t, "unknown_1003", Application(1003).String())
+}
@@ -0,0 +1,50 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package dry_run
+
+// dry_run contains the results of services that were run with
+// FLU_DRY_RUN set, so they can be compared with the live services
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+)
+
+// Result of simulating the transaction a service would have sent (or the
+// message it would have published) if it wasn't in dry run mode
+type Result struct {
+	Network network.BlockchainNetwork `json:"network"`
+
+	// ChainId of the EVM chain the transaction was simulated on
+	ChainId uint64 `json:"chain_id,omitempty"`
+
+	Description string `json:"description,omitempty"`
+
+	Sender    string `json:"sender"`
+	Recipient string `json:"recipient"`
+
+	// TransactionHash, signature or digest of the signed transaction
+	TransactionHash string `json:"transaction_hash,omitempty"`
+
+	// Transaction that was signed, encoded as hex on Ethereum and
+	// base64 on Solana and Sui
+	Transaction string `json:"transaction,omitempty"`
+
+	// Message that would have been published, for services that don't
+	// sign transactions themselves
+	Message json.RawMessage `json:"message,omitempty"`
+
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+
+	// GasEstimate of the transaction, or the compute units on Solana
+	GasEstimate uint64 `json:"gas_estimate"`
+
+	Logs []string `json:"logs,omitempty"`
+
+	Time time.Time `json:"time"`
+}
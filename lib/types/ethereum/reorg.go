@@ -0,0 +1,55 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package ethereum
+
+import (
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+)
+
+type (
+	// OrphanedBlock that was replaced by another block in a chain
+	// reorganisation, with the transactions that it contained
+	OrphanedBlock struct {
+		BlockHash         Hash        `json:"block_hash"`
+		BlockNumber       misc.BigInt `json:"block_number"`
+		TransactionHashes []Hash      `json:"transaction_hashes"`
+	}
+
+	// ChainReorg seen by the header connector, naming every block that
+	// was orphaned so anything recorded from them can be rolled back
+	ChainReorg struct {
+		Network        network.BlockchainNetwork `json:"network"`
+		OrphanedBlocks []OrphanedBlock           `json:"orphaned_blocks"`
+	}
+)
+
+// BlockHashes of every orphaned block, as strings to use when filtering
+// database rows that recorded the block they came from
+func (reorg ChainReorg) BlockHashes() []string {
+	blockHashes := make([]string, len(reorg.OrphanedBlocks))
+
+	for i, block := range reorg.OrphanedBlocks {
+		blockHashes[i] = block.BlockHash.String()
+	}
+
+	return blockHashes
+}
+
+// OrphanedTransactions in every orphaned block, as the block number and
+// transaction hash of each, to use when filtering database rows that
+// were recorded without the hash of their block
+func (reorg ChainReorg) OrphanedTransactions() (blockNumbers []string, transactionHashes []string) {
+	for _, block := range reorg.OrphanedBlocks {
+		blockNumber := block.BlockNumber.String()
+
+		for _, transactionHash := range block.TransactionHashes {
+			blockNumbers = append(blockNumbers, blockNumber)
+			transactionHashes = append(transactionHashes, transactionHash.String())
+		}
+	}
+
+	return blockNumbers, transactionHashes
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package ethereum
+
+import (
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrphanedTransactions(t *testing.T) {
+	reorg := ChainReorg{
+		OrphanedBlocks: []OrphanedBlock{
+			{
+				BlockHash:   HashFromString("0xa"),
+				BlockNumber: misc.BigIntFromInt64(10),
+				TransactionHashes: []Hash{
+					HashFromString("0x1"),
+					HashFromString("0x2"),
+				},
+			},
+			{
+				BlockHash:         HashFromString("0xb"),
+				BlockNumber:       misc.BigIntFromInt64(11),
+				TransactionHashes: []Hash{HashFromString("0x3")},
+			},
+		},
+	}
+
+	blockNumbers, transactionHashes := reorg.OrphanedTransactions()
+
+	assert.Equal(t, []string{"10", "10", "11"}, blockNumbers)
+	assert.Equal(t, []string{"0x1", "0x2", "0x3"}, transactionHashes)
+	assert.Equal(t, []string{"0xa", "0xb"}, reorg.BlockHashes())
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package ethereum
+
+import (
+	"time"
+
+	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+)
+
+// TransactionAttemptStatus of an attempt to get a transaction mined
+type TransactionAttemptStatus string
+
+const (
+	// TransactionAttemptPending for attempts that are waiting to be mined
+	TransactionAttemptPending TransactionAttemptStatus = "pending"
+
+	// TransactionAttemptMined for attempts that were mined successfully
+	TransactionAttemptMined TransactionAttemptStatus = "mined"
+
+	// TransactionAttemptReverted for attempts that were mined but reverted
+	TransactionAttemptReverted TransactionAttemptStatus = "reverted"
+
+	// TransactionAttemptReplaced for attempts replaced by a later attempt
+	// of ours that was mined
+	TransactionAttemptReplaced TransactionAttemptStatus = "replaced"
+
+	// TransactionAttemptReplacedExternally for attempts whose nonce was
+	// used by a transaction that we didn't send
+	TransactionAttemptReplacedExternally TransactionAttemptStatus = "replaced_externally"
+
+	// TransactionAttemptDropped for attempts the node dropped from its
+	// mempool
+	TransactionAttemptDropped TransactionAttemptStatus = "dropped"
+
+	// TransactionAttemptAbandoned for attempts that weren't mined before
+	// they were given up on
+	TransactionAttemptAbandoned TransactionAttemptStatus = "abandoned"
+)
+
+// TransactionAttempt to get a transaction mined, with one for each time
+// it was signed with different fees
+type TransactionAttempt struct {
+	ChainId         uint64                   `json:"chain_id"`
+	Address         Address                  `json:"address"`
+	Nonce           uint64                   `json:"nonce"`
+	Attempt         int                      `json:"attempt"`
+	TransactionHash Hash                     `json:"transaction_hash"`
+	GasTipCap       misc.BigInt              `json:"gas_tip_cap"`
+	GasFeeCap       misc.BigInt              `json:"gas_fee_cap"`
+	Status          TransactionAttemptStatus `json:"status"`
+	CreatedTime     time.Time                `json:"created_time"`
+}
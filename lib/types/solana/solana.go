@@ -106,6 +106,16 @@ type (
 		PostTokenBalances []TransactionTokenBalance     `json:"postTokenBalances"`
 		InnerInstructions []TransactionInnerInstruction `json:"innerInstructions"`
 		Logs              []string                      `json:"logMessages"`
+
+		// LoadedAddresses from address lookup tables, only present in v0
+		LoadedAddresses *TransactionLoadedAddresses `json:"loadedAddresses,omitempty"`
+	}
+
+	// TransactionLoadedAddresses resolved from the address table lookups
+	// of a v0 transaction, indexed after the account keys in the message
+	TransactionLoadedAddresses struct {
+		Writable []string `json:"writable"`
+		Readonly []string `json:"readonly"`
 	}
 
 	TransactionTokenBalance struct {
@@ -159,6 +169,32 @@ type (
 	}
 )
 
+// AccountKeys of the transaction that instructions and token balances
+// index, the account keys in the message followed by the writable then
+// the readonly addresses loaded from lookup tables
+func (result TransactionResult) AccountKeys() []string {
+	var (
+		accountKeys     = result.Transaction.Message.AccountKeys
+		loadedAddresses = result.Meta.LoadedAddresses
+	)
+
+	if loadedAddresses == nil {
+		return accountKeys
+	}
+
+	allAccountKeys := make(
+		[]string,
+		0,
+		len(accountKeys)+len(loadedAddresses.Writable)+len(loadedAddresses.Readonly),
+	)
+
+	allAccountKeys = append(allAccountKeys, accountKeys...)
+	allAccountKeys = append(allAccountKeys, loadedAddresses.Writable...)
+	allAccountKeys = append(allAccountKeys, loadedAddresses.Readonly...)
+
+	return allAccountKeys
+}
+
 // Slot is the type that logs the current slot as sent by the solana RPC
 type Slot struct {
 	Slot uint64 `json:"slot"`
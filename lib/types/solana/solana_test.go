@@ -46,3 +46,24 @@ func TestGetBinary(t *testing.T) {
 	assert.Error(t, err)
 	assert.Zero(t, b)
 }
+
+func TestAccountKeys(t *testing.T) {
+	var result TransactionResult
+
+	result.Transaction.Message.AccountKeys = []string{"payer", "program"}
+
+	// legacy transactions only have the keys in the message
+
+	assert.Equal(t, []string{"payer", "program"}, result.AccountKeys())
+
+	result.Meta.LoadedAddresses = &TransactionLoadedAddresses{
+		Writable: []string{"pool", "vault"},
+		Readonly: []string{"oracle"},
+	}
+
+	assert.Equal(
+		t,
+		[]string{"payer", "program", "pool", "vault", "oracle"},
+		result.AccountKeys(),
+	)
+}
@@ -207,4 +207,8 @@ type SuiDecoratedTransfer struct {
 	Data       models.SuiTransactionBlockData
 	Event      *models.SuiEventResponse
 	Checkpoint misc.BigInt
+
+	// BalanceChanges of the transaction, to tell if an application
+	// interaction involved the fluid coin
+	BalanceChanges []models.BalanceChanges
 }
@@ -45,6 +45,11 @@ type (
 		// For Sui, this is the index of the action in a PTB
 		LogIndex misc.BigInt `json:"log_index"`
 
+		// BlockHash of the block containing the transaction on Ethereum,
+		// used to roll the user action back if the block is orphaned.
+		// Empty string otherwise
+		BlockHash string `json:"block_hash"`
+
 		// SwapIn or swap out from a Fluid Asset. If true, then that would indicate
 		// that the transfer went from USDT to fUSDT for example.
 		SwapIn bool `json:"swap_in"`
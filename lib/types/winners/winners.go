@@ -51,6 +51,9 @@ type BlockedWinner struct {
 	WinningAmount           misc.BigInt                `json:"winning_amount"`
 	BatchFirstBlock         misc.BigInt                `json:"first_block"`
 	BatchLastBlock          misc.BigInt                `json:"last_block"`
+	// BlockHash of the block the reward was blocked in on Ethereum, empty
+	// string otherwise
+	BlockHash string `json:"block_hash"`
 }
 
 // PendingWinner is a winner that has been spooled but not sent
@@ -70,4 +73,7 @@ type PendingWinner struct {
 	// this is the stringified result of either an ethereum.Application or sui.Application
 	Application string `json:"application"`
 	RewardTier  int    `json:"reward_tier"`
+	// BlockHash of the block the win was in on Ethereum, empty string
+	// otherwise
+	BlockHash string `json:"block_hash"`
 }
@@ -31,6 +31,9 @@ type Emission struct {
 	SenderAddress    string                     `json:"sender_address"`
 
 	EthereumBlockNumber misc.BigInt `json:"ethereum_block_number"`
+	// EthereumBlockHash to roll the emission back with if the block is
+	// orphaned, empty string otherwise
+	EthereumBlockHash   string      `json:"ethereum_block_hash"`
 	SolanaSlotNumber    misc.BigInt `json:"solana_slot_number"`
 	SuiCheckpointNumber misc.BigInt `json:"sui_checkpoint_number"`
 
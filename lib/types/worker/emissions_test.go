@@ -36,6 +36,7 @@ func TestString(t *testing.T) {
 	"recipient_address":"",
 	"sender_address":"",
 	"ethereum_block_number":"0",
+	"ethereum_block_hash":"",
 	"solana_slot_number":"0",
 	"sui_checkpoint_number":"0",
 	"average_transfers_in_block":0,
@@ -110,7 +111,12 @@ func TestString(t *testing.T) {
 		"betswirl": 0,
 		"paraswap": 0
 	},
-	"sui_fees":{},
+	"sui_fees":{
+		"cetus":0,
+		"turbos":0,
+		"deepbook":0,
+		"aftermath":0
+	},
 	"calculate_n":{
 		"probability_m":0,
 		"factorial":0,
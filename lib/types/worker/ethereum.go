@@ -20,6 +20,7 @@ type (
 	EthereumAnnouncement struct {
 		TransactionHash ethereum.Hash                         `json:"transaction_hash"`
 		BlockNumber     *misc.BigInt                          `json:"block_number"`
+		BlockHash       ethereum.Hash                         `json:"block_hash"`
 		LogIndex        *misc.BigInt                          `json:"log_index"`
 		FromAddress     ethereum.Address                      `json:"from_address"`
 		ToAddress       ethereum.Address                      `json:"to_address"`
@@ -36,6 +37,7 @@ type (
 		TransactionHash ethereum.Hash                       `json:"transaction_hash"`
 		LogIndex        *misc.BigInt                        `json:"log_index"`
 		BlockNumber     *misc.BigInt                        `json:"block_number"`
+		BlockHash       ethereum.Hash                       `json:"block_hash"`
 		FromAddress     ethereum.Address                    `json:"from_address"`
 		ToAddress       ethereum.Address                    `json:"to_address"`
 		FromWinAmount   map[applications.UtilityName]Payout `json:"from_win_amount"`
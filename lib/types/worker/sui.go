@@ -3,9 +3,9 @@ package worker
 import (
 	"math/big"
 
-	user_actions "github.com/fluidity-money/fluidity-app/lib/databases/timescale/user-actions"
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
 	"github.com/fluidity-money/fluidity-app/lib/types/misc"
+	user_actions "github.com/fluidity-money/fluidity-app/lib/types/user-actions"
 	"github.com/fluidity-money/sui-go-sdk/models"
 )
 
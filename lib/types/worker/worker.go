@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/fluidity-money/fluidity-app/lib/types/applications"
@@ -88,7 +90,7 @@ type (
 
 	// app fees for ethereum transactions
 	EthereumAppFees struct {
-		UniswapV3        float64 `json:"uniswap_v3",`
+		UniswapV3        float64 `json:"uniswap_v3"`
 		UniswapV2        float64 `json:"uniswap_v2"`
 		BalancerV2       float64 `json:"balancer_v2"`
 		OneInchV2        float64 `json:"oneinch_v2"`
@@ -117,10 +119,18 @@ type (
 		Odos         float64 `json:"odos"`
 		BetSwirl     float64 `json:"betswirl"`
 		Paraswap     float64 `json:"paraswap"`
+
+		// Other fees tracked by applications without a field here,
+		// keyed by the emission key they registered with
+		Other map[string]float64 `json:"other,omitempty"`
 	}
 
 	// app fees for sui transactions
 	SuiAppFees struct {
+		Cetus     float64 `json:"cetus"`
+		Turbos    float64 `json:"turbos"`
+		DeepBook  float64 `json:"deepbook"`
+		Aftermath float64 `json:"aftermath"`
 	}
 
 	FeeSwitch struct {
@@ -171,6 +181,59 @@ func (emission Emission) String() string {
 	return string(bytes)
 }
 
+// ethereumAppFeesFields maps the json name of each field in
+// EthereumAppFees to its index
+var ethereumAppFeesFields = func() map[string]int {
+	var (
+		feesType = reflect.TypeOf(EthereumAppFees{})
+		fields   = make(map[string]int)
+	)
+
+	for i := 0; i < feesType.NumField(); i++ {
+		field := feesType.Field(i)
+
+		if field.Type.Kind() != reflect.Float64 {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		fields[name] = i
+	}
+
+	return fields
+}()
+
+// IsEthereumAppFeesField returns whether the key is the json name of a
+// field in EthereumAppFees
+func IsEthereumAppFeesField(key string) bool {
+	_, found := ethereumAppFeesFields[key]
+
+	return found
+}
+
+// Add a fee to the field with the json name given, or to Other if the
+// fee isn't tracked with its own field
+func (fees *EthereumAppFees) Add(key string, fee float64) {
+	if i, found := ethereumAppFeesFields[key]; found {
+		field := reflect.ValueOf(fees).Elem().Field(i)
+
+		field.SetFloat(field.Float() + fee)
+
+		return
+	}
+
+	if fee == 0 {
+		return
+	}
+
+	if fees.Other == nil {
+		fees.Other = make(map[string]float64)
+	}
+
+	fees.Other[key] += fee
+}
+
 // DebugString the UtilityVars, returning "name:pool size:token decimals
 // scale:exchange rate:delta weight" for logging purposes
 func (v UtilityVars) DebugString() string {
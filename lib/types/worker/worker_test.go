@@ -26,3 +26,22 @@ func TestUtilityVarsDebugString(t *testing.T) {
 
 	assert.Equal(t, s, expected)
 }
+
+func TestEthereumAppFeesAdd(t *testing.T) {
+	var fees EthereumAppFees
+
+	fees.Add("uniswap_v3", 1.5)
+	fees.Add("uniswap_v3", 1)
+	fees.Add("paraswap", 0)
+
+	assert.Equal(t, 2.5, fees.UniswapV3)
+	assert.Nil(t, fees.Other)
+
+	fees.Add("new_application", 3)
+
+	assert.Equal(t, map[string]float64{"new_application": 3}, fees.Other)
+
+	assert.True(t, IsEthereumAppFeesField("gtrade_v6_1"))
+	assert.False(t, IsEthereumAppFeesField("other"))
+	assert.False(t, IsEthereumAppFeesField("new_application"))
+}
@@ -0,0 +1,16 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package util
+
+import "os"
+
+// EnvDryRun to set to `true` to build, sign and simulate transactions
+// without broadcasting them, publishing the results instead
+const EnvDryRun = `FLU_DRY_RUN`
+
+// DryRunEnabled if FLU_DRY_RUN is set to true
+func DryRunEnabled() bool {
+	return os.Getenv(EnvDryRun) == "true"
+}
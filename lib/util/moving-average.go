@@ -0,0 +1,40 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package util
+
+// MovingAverageAndSumMaybePop of the values given, newest first, dropping
+// the oldest values that exceed the limit if the flag is set. The sum is
+// over the newest limit + 1 values, and the average divides that by the
+// number of values before any were dropped. Returns the values kept
+func MovingAverageAndSumMaybePop(values []int, limit int, shouldPopIfExcess bool) (remaining []int, average int, sum int) {
+	listLength := len(values)
+
+	remaining = values
+
+	// should only pop if there's excess and the argument is set
+
+	if listLength > limit && shouldPopIfExcess {
+		remaining = values[:limit]
+	}
+
+	// avoid dividing by zero if nothing is stored
+	if listLength == 0 {
+		return remaining, 0, 0
+	}
+
+	// the range is inclusive of the limit
+
+	end := limit + 1
+
+	if end > len(remaining) {
+		end = len(remaining)
+	}
+
+	for _, value := range remaining[:end] {
+		sum += value
+	}
+
+	return remaining, sum / listLength, sum
+}
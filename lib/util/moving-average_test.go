@@ -0,0 +1,37 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovingAverageAndSumMaybePop(t *testing.T) {
+	remaining, average, sum := MovingAverageAndSumMaybePop(nil, 3, true)
+
+	assert.Empty(t, remaining)
+	assert.Equal(t, 0, average)
+	assert.Equal(t, 0, sum)
+
+	values := []int{5, 4, 3, 2, 1}
+
+	// without popping, the newest limit + 1 are summed
+
+	remaining, average, sum = MovingAverageAndSumMaybePop(values, 2, false)
+
+	assert.Equal(t, values, remaining)
+	assert.Equal(t, 12, sum)
+	assert.Equal(t, 12/5, average)
+
+	// popping drops the oldest, but the average is over the old length
+
+	remaining, average, sum = MovingAverageAndSumMaybePop(values, 2, true)
+
+	assert.Equal(t, []int{5, 4}, remaining)
+	assert.Equal(t, 9, sum)
+	assert.Equal(t, 9/5, average)
+}
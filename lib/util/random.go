@@ -9,6 +9,7 @@ package util
 import (
 	"crypto/rand"
 	"fmt"
+	"io"
 	"math/big"
 
 	"github.com/fluidity-money/fluidity-app/lib/log"
@@ -34,12 +35,18 @@ func RandomString(length int) string {
 // doing some coercion internally to use crypto/rand and assuming that the
 // uint32 requirement in the arguments prevent any size-of-int issues
 func RandomIntegers(amount int, min, max uint32) (numbers []uint32) {
+	return RandomIntegersFrom(rand.Reader, amount, min, max)
+}
+
+// RandomIntegersFrom the source of randomness given, the same as
+// RandomIntegers. Used with a seeded source to replay the TRF
+func RandomIntegersFrom(source io.Reader, amount int, min, max uint32) (numbers []uint32) {
 	var (
 		maxBig = new(big.Int).SetInt64(int64(max))
 		minBig = new(big.Int).SetInt64(int64(min))
 	)
 
-	if int64(amount) > int64(max-min+1) {
+	if int64(amount) > int64(max)-int64(min)+1 {
 		log.Fatal(func(k *log.Log) {
 			k.Format(
 				"Can't generate %d non-repeating integers between %d and %d!",
@@ -59,7 +66,7 @@ func RandomIntegers(amount int, min, max uint32) (numbers []uint32) {
 
 	for i := 0; i < amount; i++ {
 		for {
-			no, err := rand.Int(rand.Reader, scaledMax)
+			no, err := rand.Int(source, scaledMax)
 
 			if err != nil {
 				log.Fatal(func(k *log.Log) {
@@ -88,7 +88,7 @@ const integrationTestLifi = `[
     "transaction": {
       "to": "0x1231deb6f5749ef6ce6943a275a1d3e7486f4eae",
       "from": "0xb04a0f6c86a20d0155907102e97824d915815feb",
-      "hash": "0x252443cbf245cab96f0d3b37c216138181b86e21b087da397a992500479016b3"
+      "hash": "0xce2a8a2d6d93dca969810443e1370bcdb6305c4d19d1cbb0460da86a1ce8e0a0"
     },
     "expected_sender": "0xb04a0f6c86a20d0155907102e97824d915815feb",
     "expected_recipient": "0x1231deb6f5749ef6ce6943a275a1d3e7486f4eae",
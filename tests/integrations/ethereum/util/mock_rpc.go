@@ -72,6 +72,12 @@ func marshalError(response serverResponse, rw http.ResponseWriter, err interface
 // rpcMethods [methodName]response to provide a list of Ethereum RPC methods and how they should be mocked.
 // callMethods[methodName]response to provide a list of eth_call contract methods and how they should respond.
 func MockRpcClient(rpcMethods_ map[string]interface{}, callMethods_ map[string]map[string]interface{}) (*ethclient.Client, error) {
+	return ethclient.Dial(MockRpcServer(rpcMethods_, callMethods_))
+}
+
+// MockRpcServer to start a server mocking the given methods like
+// MockRpcClient, returning its url
+func MockRpcServer(rpcMethods_ map[string]interface{}, callMethods_ map[string]map[string]interface{}) string {
 	var (
 		// generate a random endpoint for the client
 		endpoint = "/" + fmt.Sprint(rand.Uint64())
@@ -187,5 +193,6 @@ func MockRpcClient(rpcMethods_ map[string]interface{}, callMethods_ map[string]m
 	})
 
 	server := httptest.NewServer(nil)
-	return ethclient.Dial(server.URL + endpoint)
+
+	return server.URL + endpoint
 }
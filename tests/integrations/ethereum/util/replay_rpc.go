@@ -0,0 +1,283 @@
+// Copyright 2024 Fluidity Money. All rights reserved. Use of this
+// source code is governed by a GPL-style license that can be found in the
+// LICENSE.md file.
+
+package test_utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// RpcExchange recorded between a client and an Ethereum node, replayed
+// by ReplayRpcClient
+type RpcExchange struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// RpcRecorder stores the exchanges made with a node by a client
+// returned from RecordRpcClient
+type RpcRecorder struct {
+	mu        sync.Mutex
+	exchanges []RpcExchange
+}
+
+type rpcMessage struct {
+	Version string          `json:"jsonrpc"`
+	Id      json.RawMessage `json:"id"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+}
+
+// ReplayRpcClient to return an eth client connected to a server that
+// answers requests with the exchanges given, returning an error for
+// any request that wasn't recorded. The function returned closes the
+// client and the server
+func ReplayRpcClient(exchanges []RpcExchange) (*ethclient.Client, func(), error) {
+	responses := make(map[string]RpcExchange, len(exchanges))
+
+	for _, exchange := range exchanges {
+		key, err := exchangeKey(exchange.Method, exchange.Params)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		responses[key] = exchange
+	}
+
+	server := httptest.NewServer(rpcHandler(func(request rpcMessage) rpcMessage {
+		response := rpcMessage{
+			Version: "2.0",
+			Id:      request.Id,
+		}
+
+		key, err := exchangeKey(request.Method, request.Params)
+
+		if err != nil {
+			response.Error = rpcError(err.Error())
+			return response
+		}
+
+		exchange, found := responses[key]
+
+		if !found {
+			response.Error = rpcError(fmt.Sprintf(
+				"no recorded response for %v with params %s",
+				request.Method,
+				request.Params,
+			))
+
+			return response
+		}
+
+		response.Result = exchange.Result
+		response.Error = exchange.Error
+
+		// a null result has to be sent explicitly
+
+		if response.Result == nil && response.Error == nil {
+			response.Result = json.RawMessage("null")
+		}
+
+		return response
+	}))
+
+	return dialRpcServer(server)
+}
+
+// RecordRpcClient to return an eth client that forwards requests to the
+// node at the url given, recording each exchange. The function returned
+// closes the client and the server
+func RecordRpcClient(upstreamUrl string) (*ethclient.Client, *RpcRecorder, func(), error) {
+	recorder := new(RpcRecorder)
+
+	server := httptest.NewServer(rpcHandler(func(request rpcMessage) rpcMessage {
+		response := rpcMessage{
+			Version: "2.0",
+			Id:      request.Id,
+		}
+
+		requestBody, _ := json.Marshal(request)
+
+		upstreamResponse, err := http.Post(
+			upstreamUrl,
+			"application/json",
+			bytes.NewReader(requestBody),
+		)
+
+		if err != nil {
+			response.Error = rpcError(err.Error())
+			return response
+		}
+
+		defer upstreamResponse.Body.Close()
+
+		if err := json.NewDecoder(upstreamResponse.Body).Decode(&response); err != nil {
+			response.Error = rpcError(err.Error())
+			return response
+		}
+
+		recorder.mu.Lock()
+
+		recorder.exchanges = append(recorder.exchanges, RpcExchange{
+			Method: request.Method,
+			Params: request.Params,
+			Result: response.Result,
+			Error:  response.Error,
+		})
+
+		recorder.mu.Unlock()
+
+		return response
+	}))
+
+	client, closeClient, err := dialRpcServer(server)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return client, recorder, closeClient, nil
+}
+
+// dialRpcServer to connect a client to the server given, closing the
+// server if the client can't connect
+func dialRpcServer(server *httptest.Server) (*ethclient.Client, func(), error) {
+	client, err := ethclient.Dial(server.URL)
+
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+
+	closeClient := func() {
+		client.Close()
+		server.Close()
+	}
+
+	return client, closeClient, nil
+}
+
+// Exchanges recorded so far, without duplicates
+func (recorder *RpcRecorder) Exchanges() []RpcExchange {
+	recorder.mu.Lock()
+
+	defer recorder.mu.Unlock()
+
+	var (
+		exchanges = make([]RpcExchange, 0, len(recorder.exchanges))
+		seen      = make(map[string]bool)
+	)
+
+	for _, exchange := range recorder.exchanges {
+		key, _ := exchangeKey(exchange.Method, exchange.Params)
+
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		exchanges = append(exchanges, exchange)
+	}
+
+	return exchanges
+}
+
+// rpcHandler to serve JSON-RPC requests (and batches of requests) with
+// the function given
+func rpcHandler(handle func(request rpcMessage) rpcMessage) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		body = bytes.TrimSpace(body)
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		// batches of requests are sent as an array
+
+		if len(body) > 0 && body[0] == '[' {
+			var requests []rpcMessage
+
+			if err := json.Unmarshal(body, &requests); err != nil {
+				http.Error(rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			responses := make([]rpcMessage, len(requests))
+
+			for i, request := range requests {
+				responses[i] = handle(request)
+			}
+
+			_ = json.NewEncoder(rw).Encode(responses)
+
+			return
+		}
+
+		var request rpcMessage
+
+		if err := json.Unmarshal(body, &request); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_ = json.NewEncoder(rw).Encode(handle(request))
+	}
+}
+
+// exchangeKey to match requests with the same method and parameters,
+// ignoring the formatting of the parameters
+func exchangeKey(method string, params json.RawMessage) (string, error) {
+	var decoded interface{}
+
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &decoded); err != nil {
+			return "", fmt.Errorf(
+				"failed to decode the params of %v: %v",
+				method,
+				err,
+			)
+		}
+	}
+
+	// maps are marshalled with their keys sorted
+
+	canonical, err := json.Marshal(decoded)
+
+	if err != nil {
+		return "", err
+	}
+
+	return method + string(canonical), nil
+}
+
+func rpcError(message string) json.RawMessage {
+	encoded, _ := json.Marshal(struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{
+		Code:    -32000,
+		Message: message,
+	})
+
+	return encoded
+}
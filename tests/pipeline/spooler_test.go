@@ -16,6 +16,8 @@ import (
 	"github.com/fluidity-money/fluidity-app/lib/util"
 	"github.com/fluidity-money/fluidity-app/tests/pipeline/libtest"
 	"github.com/stretchr/testify/assert"
+
+	spooler "github.com/fluidity-money/fluidity-app/cmd/microservice-ethereum-worker-spooler/lib"
 )
 
 const (
@@ -42,11 +44,13 @@ func (w *winnerGenerator) generateWinnerAnnouncement(from, to ethTypes.Address,
 
 	blockNum := new(big.Int).Set(w.blockNumber)
 	blockNumInt := misc.NewBigIntFromInt(*blockNum)
+	logIndex := misc.BigIntFromInt64(0)
 
 	return workerTypes.EthereumWinnerAnnouncement{
 		Network:         w.network,
 		TransactionHash: libtest.RandomHash(),
 		BlockNumber:     &blockNumInt,
+		LogIndex:        &logIndex,
 		FromAddress:     from,
 		ToAddress:       to,
 		FromWinAmount:   fromWin,
@@ -88,6 +92,16 @@ func TestPipelineSpooler(t *testing.T) {
 
 	logger := libtest.LogMessages(spoolerPublishQueue)
 
+	// the spooler runs in this process, since the test uses the
+	// in-memory broker
+
+	go spooler.Spool(spooler.Config{
+		Network:             Network,
+		RewardsQueue:        spoolerInputQueue,
+		BatchedRewardsQueue: spoolerPublishQueue,
+		TokenDetails:        make(map[applications.UtilityName]token_details.TokenDetails),
+	})
+
 	var (
 		config = worker.GetWorkerConfigEthereum(Network)
 
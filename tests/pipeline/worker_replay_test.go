@@ -0,0 +1,102 @@
+package main_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fluidity-money/fluidity-app/lib/queue"
+	"github.com/fluidity-money/fluidity-app/lib/state"
+	"github.com/fluidity-money/fluidity-app/lib/types/applications"
+	"github.com/fluidity-money/fluidity-app/lib/types/network"
+	workerTypes "github.com/fluidity-money/fluidity-app/lib/types/worker"
+	"github.com/fluidity-money/fluidity-app/lib/util"
+	"github.com/fluidity-money/fluidity-app/tests/pipeline/libtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// EnvTokenDetails is the list of utility:shortname:decimals the worker
+// server uses, with the name of the fluid token it tracks the moving
+// average of transfers for
+const EnvTokenDetails = `FLU_ETHEREUM_UTILITY_TOKEN_DETAILS`
+
+// fluidTokenName in the token details given
+func fluidTokenName(tokenDetails string) string {
+	for _, details := range strings.Split(tokenDetails, ",") {
+		parts := strings.Split(details, ":")
+
+		if len(parts) == 3 && applications.UtilityName(parts[0]) == applications.UtilityFluid {
+			return parts[1]
+		}
+	}
+
+	return ""
+}
+
+func TestWorkerReplay(t *testing.T) {
+	var (
+		workerServerInTopic  = util.GetEnvOrFatal(EnvWorkerServerWorkQueue)
+		workerServerOutTopic = util.GetEnvOrFatal(EnvWorkerServerPublishQueue)
+		tokenDetails         = util.GetEnvOrFatal(EnvTokenDetails)
+	)
+
+	tokenName := fluidTokenName(tokenDetails)
+
+	require.NotEmpty(t, tokenName)
+
+	workerServerOut := libtest.LogMessages(workerServerOutTopic)
+
+	config := workerTypes.WorkerConfigEthereum{
+		Network:                      network.NetworkEthereum,
+		DefaultSecondsSinceLastBlock: 12,
+		CurrentAtxTransactionMargin:  0,
+		DefaultTransfersInBlock:      0,
+		AtxBufferSize:                2,
+		EpochBlocks:                  2,
+	}
+
+	updateWorkerConfigEthereum(config)
+
+	movingAverageKey := fmt.Sprintf(
+		"%v.%v.transfer-count",
+		network.NetworkEthereum,
+		tokenName,
+	)
+
+	generator := blockGenerator{100}
+
+	block := generator.get(2)
+
+	// process the block live first
+
+	queue.SendMessage(workerServerInTopic, block)
+
+	var announcements []workerTypes.EthereumAnnouncement
+
+	require.NoError(t, workerServerOut.GetMessage(&announcements))
+	assert.Len(t, announcements, 2)
+
+	liveTransferCounts := state.LRange(movingAverageKey, 0, -1)
+
+	// replaying the same block shouldn't hit the failsafe (which would
+	// crash the worker server) or touch the live moving average
+
+	replayCtx := queue.WithReplay(context.Background())
+
+	queue.SendMessageContext(replayCtx, workerServerInTopic, block)
+
+	require.NoError(t, workerServerOut.GetMessage(&announcements))
+	assert.Len(t, announcements, 2)
+
+	assert.Equal(t, liveTransferCounts, state.LRange(movingAverageKey, 0, -1))
+	assert.NotEmpty(t, state.LRange(movingAverageKey+".replay", 0, -1))
+
+	// the worker server is still running and processing live blocks
+
+	queue.SendMessage(workerServerInTopic, generator.get(1))
+
+	require.NoError(t, workerServerOut.GetMessage(&announcements))
+	assert.Len(t, announcements, 1)
+}